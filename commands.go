@@ -0,0 +1,711 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/anon"
+	"github.com/drcynic/dcmtagger/pkg/backup"
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/drcynic/dcmtagger/pkg/dump"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/hook"
+	"github.com/drcynic/dcmtagger/pkg/index"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// exit codes of the commands for scripts and CI pipelines, like the ones of diff and grep
+const (
+	exitOK       = 0
+	exitFindings = 1 // validation errors, differing values, attributes left to review or no match of grep
+	exitError    = 2 // invalid arguments, unreadable input or failed writes
+)
+
+// parses the arguments of the subcommand into dest, returns false if only the help was requested
+func parseCommandArgs(command string, dest interface{}, commandArgs []string) (*arg.Parser, bool) {
+	p, err := arg.NewParser(arg.Config{Program: "dcmtagger " + command}, dest)
+	if err != nil {
+		panic(err)
+	}
+	if err := p.Parse(commandArgs); err == arg.ErrHelp {
+		p.WriteHelp(os.Stdout)
+		return p, false
+	} else if err != nil {
+		failUsage(p, err.Error())
+	}
+	return p, true
+}
+
+// prints the usage and the message to stderr and exits with exitError, unlike p.Fail exiting with 255
+func failUsage(p *arg.Parser, message string) {
+	p.WriteUsage(os.Stderr)
+	fmt.Fprintln(os.Stderr, "error:", message)
+	os.Exit(exitError)
+}
+
+// loads the inputs completely like the UI would after expanding all files, exits on errors
+func loadAllInputs(inputs []string, pathFilter dicomtree.PathFilter) ([]dicomtree.Entry, func()) {
+	entries, cleanup, err := loadInputs(inputs, func(path string) ([]dicomtree.Entry, error) {
+		return dicomtree.ParseFilesFiltered(path, pathFilter)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+	return entries, cleanup
+}
+
+// writes the value as indented JSON to stdout
+func writeJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+type dumpArgs struct {
+	Include []string `arg:"--include" help:"Only dump the files of directories and archives matching one of the glob patterns, e.g. '*.dcm'"`
+	Exclude []string `arg:"--exclude" help:"Skip the files of directories and archives matching one of the glob patterns"`
+	Filter  string   `arg:"--filter" help:"Only dump the files matching the filter expression, e.g. 'Modality=CT and PatientName~doe'"`
+	Search  string   `arg:"--search" help:"Only dump the elements containing the text, case insensitive"`
+	JSON    bool     `arg:"--json" help:"Dump in the DICOM JSON model instead of text lines"`
+	Inputs  []string `arg:"positional,required" help:"The DICOM input files, directories or archives (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+}
+
+func (dumpArgs) Description() string {
+	return "Writes the datasets as one line per element or as DICOM JSON to stdout, like the piped output of view"
+}
+
+// runs 'dcmtagger dump [options] <input>...' without starting the UI, also if stdout is a terminal
+func runDumpCommand(commandArgs []string) {
+	var args dumpArgs
+	p, ok := parseCommandArgs("dump", &args, commandArgs)
+	if !ok {
+		return
+	}
+	pathFilter, err := dicomtree.NewPathFilter(args.Include, args.Exclude)
+	if err != nil {
+		failUsage(p, err.Error())
+	}
+	entries, cleanup := loadAllInputs(args.Inputs, pathFilter)
+	defer cleanup()
+	if err := dumpEntries(entries, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error dumping input: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+}
+
+// the changes of a file by edit or anon
+type fileResult struct {
+	File    string        `json:"file"`
+	Written string        `json:"written,omitempty"` // the path the changed file was written to
+	Changes []edit.Change `json:"changes"`
+	Risks   []auditRisk   `json:"risks,omitempty"` // attributes left to review after anon
+}
+
+// an attribute anon.Audit finds after de-identification
+type auditRisk struct {
+	Category string `json:"category"`
+	Path     string `json:"path"`
+	Value    string `json:"value"`
+}
+
+// applies change to the datasets of all entries and writes the changed files with the writer, the changes include the
+// ones of its save hooks
+func changeEntries(entries []dicomtree.Entry, writer *hook.Writer, change func(dataset *dicom.Dataset) error) ([]fileResult, error) {
+	results := make([]fileResult, 0, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		before := edit.TakeSnapshot(&entry.Dataset)
+		if err := change(&entry.Dataset); err != nil {
+			return results, fmt.Errorf("%s: %w", entry.Filename, err)
+		}
+		result := fileResult{File: entry.Filename, Changes: edit.Diff(entry.Filename, before, &entry.Dataset)}
+		if len(result.Changes) > 0 {
+			path, err := writer.Write(entry)
+			if err != nil {
+				return results, fmt.Errorf("%s: %w", entry.Filename, err)
+			}
+			result.Written = path
+			result.Changes = edit.Diff(entry.Filename, before, &entry.Dataset)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// writes the results as JSON array, else the changed values, the risks and a summary as text lines
+func writeResults(results []fileResult, output string, asJSON bool) error {
+	if asJSON {
+		return writeJSON(results)
+	}
+	changed := 0
+	for _, r := range results {
+		for _, c := range r.Changes {
+			fmt.Printf("%s  %s %s: '%s' -> '%s'\n", c.File, c.Tag, c.Name, c.OldValue, c.NewValue)
+		}
+		for _, risk := range r.Risks {
+			fmt.Printf("%s  %s %s, review: '%s'\n", r.File, risk.Path, risk.Category, risk.Value)
+		}
+		if r.Written != "" {
+			changed++
+		}
+	}
+	if output == "" {
+		fmt.Printf("Changed %d of %d files in place, previous versions in %s\n", changed, len(results), backup.DirName)
+	} else {
+		fmt.Printf("Wrote %d changed of %d files to %s\n", changed, len(results), output)
+	}
+	return nil
+}
+
+// an assignment tag=value of --set
+type assignment struct {
+	tag    tag.Tag
+	values []string
+}
+
+type editArgs struct {
+	Set       []string `arg:"--set" help:"Set the tag to the value in all files, tag=value with the tag as keyword or group,element, multiple values separated by \\"`
+	Delete    []string `arg:"--delete" help:"Delete the tag from all files"`
+	Unprotect []string `arg:"--unprotect" help:"Tags to remove from the default protected tags, which are not edited otherwise"`
+	Output    string   `arg:"--output" help:"Write the edited files to this directory, mirroring their paths relative to the input, instead of overwriting them"`
+	JSON      bool     `arg:"--json" help:"Write the changes of each file as JSON array instead of text lines"`
+	Inputs    []string `arg:"positional,required" help:"The DICOM input files, directories or archives (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin"`
+}
+
+func (editArgs) Description() string {
+	return "Sets and deletes top level elements in all files of the inputs and writes the changed files"
+}
+
+// runs 'dcmtagger edit --set <tag>=<value>... --delete <tag>... <input>...' without starting the UI
+func runEditCommand(commandArgs []string) {
+	var args editArgs
+	p, ok := parseCommandArgs("edit", &args, commandArgs)
+	if !ok {
+		return
+	}
+	protected, err := protectedTags(nil, args.Unprotect)
+	if err != nil {
+		failUsage(p, err.Error())
+	}
+	assignments, deletions, err := parseEdits(args.Set, args.Delete, protected)
+	if err != nil {
+		failUsage(p, err.Error())
+	}
+	if len(assignments) == 0 && len(deletions) == 0 {
+		failUsage(p, "Nothing to edit, use --set <tag>=<value> or --delete <tag>")
+	}
+	if args.Output == "" && slices.Contains(args.Inputs, dicomtree.StdinInput) {
+		failUsage(p, "The input is read from stdin, use --output to write the edited files")
+	}
+	writer, err := newWriter(args.Output, protected)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error editing input: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+	entries, cleanup := loadAllInputs(args.Inputs, dicomtree.PathFilter{})
+	defer cleanup()
+	results, err := changeEntries(entries, writer, func(dataset *dicom.Dataset) error {
+		for _, a := range assignments {
+			e, err := edit.Set(dataset, a.tag, a.values)
+			if err == nil {
+				err = edit.SetStrings(e, a.values, charset.FromDataset(dataset))
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", dicomtree.FormatTag(a.tag), err)
+			}
+		}
+		for _, t := range deletions {
+			edit.Delete(dataset, t)
+		}
+		return nil
+	})
+	if err == nil {
+		err = writeResults(results, args.Output, args.JSON)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error editing input: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+}
+
+// parses the tag=value assignments of --set and the tags of --delete, protected tags are refused
+func parseEdits(set, deletes []string, protected edit.ProtectedTags) ([]assignment, []tag.Tag, error) {
+	var assignments []assignment
+	for _, text := range set {
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid assignment '%s', use <tag>=<value>", text)
+		}
+		t, err := dicomtree.ParseTag(strings.TrimSpace(name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := protected.Check(t); err != nil {
+			return nil, nil, err
+		}
+		assignments = append(assignments, assignment{t, strings.Split(value, "\\")})
+	}
+	var deletions []tag.Tag
+	for _, text := range deletes {
+		t, err := dicomtree.ParseTag(text)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := protected.Check(t); err != nil {
+			return nil, nil, err
+		}
+		deletions = append(deletions, t)
+	}
+	return assignments, deletions, nil
+}
+
+type anonArgs struct {
+	Profile string   `arg:"--profile" default:"basic" help:"The de-identification profile: basic, a PS3.15 option like retain-device-identity, a profile file or <name>.json in the profiles directory of the config"`
+	Option  []string `arg:"--option" help:"Additional PS3.15 options applied with the profile"`
+	Output  string   `arg:"--output" help:"Write the de-identified files to this directory, mirroring their paths relative to the input, instead of overwriting them"`
+	JSON    bool     `arg:"--json" help:"Write the changes and the attributes left to review of each file as JSON array instead of text lines"`
+	Inputs  []string `arg:"positional,required" help:"The DICOM input files, directories or archives (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin"`
+}
+
+func (anonArgs) Description() string {
+	return "De-identifies all files of the inputs with a profile like :anonymize, writes the changed files and lists the attributes left to review, exits with 1 if there are any"
+}
+
+// runs 'dcmtagger anon [--profile <profile>] [--output <dir>] <input>...' without starting the UI
+func runAnonCommand(commandArgs []string) {
+	var args anonArgs
+	p, ok := parseCommandArgs("anon", &args, commandArgs)
+	if !ok {
+		return
+	}
+	dir, _ := config.ProfilesDir()
+	profile, err := anon.LoadProfile(args.Profile, dir)
+	if err != nil {
+		failUsage(p, err.Error())
+	}
+	profile.Options = append(slices.Clone(profile.Options), args.Option...)
+	if err := profile.Validate(); err != nil {
+		failUsage(p, fmt.Sprintf("Invalid profile %s: %s", profile.Name, err.Error()))
+	}
+	if args.Output == "" && slices.Contains(args.Inputs, dicomtree.StdinInput) {
+		failUsage(p, "The input is read from stdin, use --output to write the de-identified files")
+	}
+	writer, err := newWriter(args.Output, edit.NewProtectedTags(edit.DefaultProtectedTags))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error de-identifying input: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+	entries, cleanup := loadAllInputs(args.Inputs, dicomtree.PathFilter{})
+	defer cleanup()
+	uids := anon.UIDMap{}
+	results, err := changeEntries(entries, writer, func(dataset *dicom.Dataset) error {
+		_, err := profile.Apply(dataset, uids)
+		return err
+	})
+	risks := 0
+	for i := range results {
+		for _, risk := range anon.Audit(&entries[i].Dataset) {
+			results[i].Risks = append(results[i].Risks, auditRisk{string(risk.Category), risk.Path, risk.Value})
+			risks++
+		}
+	}
+	if err == nil {
+		err = writeResults(results, args.Output, args.JSON)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error de-identifying input: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	if risks > 0 {
+		cleanup()
+		os.Exit(exitFindings)
+	}
+}
+
+type diffArgs struct {
+	JSON   bool     `arg:"--json" help:"Write the differing tags as JSON array of objects with the value per file instead of text lines"`
+	Inputs []string `arg:"positional,required" help:"Two or more DICOM files, or directories and archives (.zip, .tar, .tar.gz) whose files are compared"`
+}
+
+func (diffArgs) Description() string {
+	return "Writes the top level tags whose values differ between the files with the value of each file, exits with 1 if there are any"
+}
+
+// runs 'dcmtagger diff [--json] <input>...' without starting the UI
+func runDiffCommand(commandArgs []string) {
+	var args diffArgs
+	p, ok := parseCommandArgs("diff", &args, commandArgs)
+	if !ok {
+		return
+	}
+	entries, cleanup := loadAllInputs(args.Inputs, dicomtree.PathFilter{})
+	defer cleanup()
+	if len(entries) < 2 {
+		failUsage(p, "Need at least two files to compare")
+	}
+	diffs := diffEntries(entries)
+	var err error
+	if args.JSON {
+		err = writeJSON(diffs)
+	} else {
+		err = writeDiffs(os.Stdout, diffs, entryNames(entries))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the differences: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	if len(diffs) > 0 {
+		cleanup()
+		os.Exit(exitFindings)
+	}
+}
+
+// a top level tag whose value differs between files
+type tagDiff struct {
+	Tag    string            `json:"tag"`
+	Name   string            `json:"name,omitempty"`
+	Values map[string]string `json:"values"` // by file, see entryNames, files without the tag are left out
+}
+
+// returns the filenames of the entries, their paths if filenames are ambiguous
+func entryNames(entries []dicomtree.Entry) []string {
+	names := make([]string, len(entries))
+	seen := make(map[string]bool)
+	for i, entry := range entries {
+		if seen[entry.Filename] {
+			for j := range entries {
+				names[j] = entries[j].Path
+			}
+			return names
+		}
+		seen[entry.Filename] = true
+		names[i] = entry.Filename
+	}
+	return names
+}
+
+// returns the top level tags with different values in the entries or missing in some of them, in tag order
+func diffEntries(entries []dicomtree.Entry) []tagDiff {
+	names := entryNames(entries)
+	values := make(map[tag.Tag]map[string]string)
+	tagNames := make(map[tag.Tag]string)
+	for i := range entries {
+		charsets := charset.FromDataset(&entries[i].Dataset)
+		for _, e := range entries[i].Dataset.Elements {
+			if values[e.Tag] == nil {
+				values[e.Tag] = make(map[string]string)
+				tagNames[e.Tag] = dicomtree.TagName(e)
+			}
+			values[e.Tag][names[i]] = dump.ValueText(e, charsets)
+		}
+	}
+	tags := make([]tag.Tag, 0, len(values))
+	for t := range values {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return dicomtree.TagOrder(tags[i]) < dicomtree.TagOrder(tags[j]) })
+	diffs := make([]tagDiff, 0)
+	for _, t := range tags {
+		distinct := make(map[string]bool)
+		for _, value := range values[t] {
+			distinct[value] = true
+		}
+		if len(distinct) > 1 || len(values[t]) < len(entries) {
+			diffs = append(diffs, tagDiff{dicomtree.FormatTag(t), tagNames[t], values[t]})
+		}
+	}
+	return diffs
+}
+
+// writes each differing tag followed by the value of each file, "<missing>" for files without the tag
+func writeDiffs(w io.Writer, diffs []tagDiff, names []string) error {
+	for _, d := range diffs {
+		if _, err := fmt.Fprintln(w, strings.TrimSpace(d.Tag+" "+d.Name)); err != nil {
+			return err
+		}
+		for _, name := range names {
+			value, ok := d.Values[name]
+			if !ok {
+				value = "<missing>"
+			}
+			if _, err := fmt.Fprintf(w, "  %s: %s\n", name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type validateArgs struct {
+	Strict bool     `arg:"--strict" help:"Also exit with 1 for warnings, not only for errors"`
+	JSON   bool     `arg:"--json" help:"Write the findings of each file as JSON array instead of text lines"`
+	Inputs []string `arg:"positional,required" help:"The DICOM input files, directories or archives (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+}
+
+func (validateArgs) Description() string {
+	return "Validates all files against their IOD like :validate, exits with 1 if there are errors"
+}
+
+// the validation of a file by validate
+type fileValidation struct {
+	File     string          `json:"file"`
+	IOD      string          `json:"iod"`
+	Findings []findingResult `json:"findings"`
+}
+
+type findingResult struct {
+	Severity string `json:"severity"`
+	Tag      string `json:"tag"`
+	Message  string `json:"message"`
+}
+
+// runs 'dcmtagger validate [--strict] [--json] <input>...' without starting the UI
+func runValidateCommand(commandArgs []string) {
+	var args validateArgs
+	if _, ok := parseCommandArgs("validate", &args, commandArgs); !ok {
+		return
+	}
+	entries, cleanup := loadAllInputs(args.Inputs, dicomtree.PathFilter{})
+	defer cleanup()
+	results := make([]fileValidation, 0, len(entries))
+	failed := false
+	for i := range entries {
+		iod, findings := validate.Dataset(&entries[i].Dataset)
+		result := fileValidation{File: entries[i].Filename, IOD: iod, Findings: make([]findingResult, 0, len(findings))}
+		for _, f := range findings {
+			result.Findings = append(result.Findings, findingResult{f.Severity.String(), dicomtree.FormatTag(f.Tag), f.Message})
+			failed = failed || f.Severity == validate.SeverityError || args.Strict
+		}
+		results = append(results, result)
+	}
+	var err error
+	if args.JSON {
+		err = writeJSON(results)
+	} else {
+		for _, r := range results {
+			fmt.Printf("%s: %s, %d findings\n", r.File, r.IOD, len(r.Findings))
+			for _, f := range r.Findings {
+				fmt.Printf("  %-7s %s\n", f.Severity, f.Message)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the findings: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	if failed {
+		cleanup()
+		os.Exit(exitFindings)
+	}
+}
+
+type grepArgs struct {
+	IgnoreCase bool     `arg:"-i,--ignore-case" help:"Match the pattern case insensitive"`
+	JSON       bool     `arg:"--json" help:"Write the matching elements as JSON array instead of text lines"`
+	Pattern    string   `arg:"positional,required" help:"The regular expression matched against the element lines of dump without the file: path of the tag, keyword, [VR] and value"`
+	Inputs     []string `arg:"positional,required" help:"The DICOM input files, directories or archives (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+}
+
+func (grepArgs) Description() string {
+	return "Writes the elements matching a regular expression, also nested ones, exits with 1 if none matches"
+}
+
+// runs 'dcmtagger grep [-i] [--json] <pattern> <input>...' without starting the UI
+func runGrepCommand(commandArgs []string) {
+	var args grepArgs
+	p, ok := parseCommandArgs("grep", &args, commandArgs)
+	if !ok {
+		return
+	}
+	pattern := args.Pattern
+	if args.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		failUsage(p, fmt.Sprintf("Invalid pattern: %s", err.Error()))
+	}
+	entries, cleanup := loadAllInputs(args.Inputs, dicomtree.PathFilter{})
+	defer cleanup()
+	matches := grepEntries(entries, re)
+	if args.JSON {
+		err = writeJSON(matches)
+	} else {
+		for _, line := range matches {
+			if _, err = fmt.Println(line); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the matches: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	if len(matches) == 0 {
+		cleanup()
+		os.Exit(exitFindings)
+	}
+}
+
+// returns the element lines of the entries matching the regular expression, which is matched without the file
+func grepEntries(entries []dicomtree.Entry, re *regexp.Regexp) []dump.Line {
+	matches := make([]dump.Line, 0)
+	for i := range entries {
+		for _, line := range dump.Lines(&entries[i]) {
+			file := line.File
+			line.File = ""
+			if re.MatchString(line.String()) {
+				line.File = file
+				matches = append(matches, line)
+			}
+		}
+	}
+	return matches
+}
+
+type sendArgs struct {
+	Node   string   `arg:"positional,required" help:"The name of a node of the config file, AET@host:port or a DICOMweb base URL"`
+	Inputs []string `arg:"positional,required" help:"The DICOM input files, directories or archives (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+}
+
+func (sendArgs) Description() string {
+	return "Sends all files of the inputs to a node with C-STORE, or with STOW-RS to a DICOMweb server"
+}
+
+// runs 'dcmtagger send <node> <input>...' without starting the UI
+func runSendCommand(commandArgs []string) {
+	var args sendArgs
+	p, ok := parseCommandArgs("send", &args, commandArgs)
+	if !ok {
+		return
+	}
+	cfg, _ := config.Load()
+	node, ok := cfg.Nodes[args.Node]
+	if !ok {
+		var err error
+		if node, err = dimse.ParseNode(args.Node); err != nil {
+			failUsage(p, fmt.Sprintf("Unknown node '%s', use the name of a configured node, AET@host:port or a DICOMweb URL", args.Node))
+		}
+	}
+	entries, cleanup, err := loadInputs(args.Inputs, dicomtree.ListFiles) // the files are sent as they are
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+	defer cleanup()
+	failed := 0
+	for _, entry := range entries {
+		file, err := entry.ReadFile()
+		if err == nil {
+			err = dimse.Store(node, file)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending %s: %s\n", entry.Filename, err.Error())
+			failed++
+		}
+	}
+	fmt.Printf("Sent %d of %d files to %s\n", len(entries)-failed, len(entries), node)
+	if failed > 0 {
+		cleanup()
+		os.Exit(exitError)
+	}
+}
+
+type listenArgs struct {
+	Port   int    `arg:"--port" default:"11112" help:"The TCP port to listen on"`
+	AET    string `arg:"--aet" help:"Only accept associations calling this AE title, any if empty"`
+	Output string `arg:"positional,required" help:"The output directory, received instances are written as <SOPInstanceUID>.dcm"`
+}
+
+func (listenArgs) Description() string {
+	return "Runs a storage SCP writing the instances received with C-STORE to a directory until interrupted"
+}
+
+// runs 'dcmtagger listen [--port <port>] [--aet <aet>] <output>' without starting the UI
+func runListenCommand(commandArgs []string) {
+	var args listenArgs
+	p, ok := parseCommandArgs("listen", &args, commandArgs)
+	if !ok {
+		return
+	}
+	if err := os.MkdirAll(args.Output, 0o755); err != nil {
+		failUsage(p, err.Error())
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", args.Port))
+	if err != nil {
+		failUsage(p, err.Error())
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		listener.Close()
+	}()
+	scp := &dimse.Listener{AET: args.AET, Store: func(instance dimse.Instance, file []byte) error {
+		path := filepath.Join(args.Output, filepath.Base(instance.SOPInstanceUID)+".dcm")
+		if err := os.WriteFile(path, file, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", path, err.Error())
+			return err
+		}
+		fmt.Printf("Received %s\n", path)
+		return nil
+	}}
+	fmt.Printf("Listening on port %d, interrupt with ctrl+c\n", args.Port)
+	if err := scp.Serve(listener); err != nil {
+		fmt.Fprintf(os.Stderr, "Error listening: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+}
+
+type indexArgs struct {
+	Include []string `arg:"--include" help:"Only index the files matching one of the glob patterns, e.g. '*.dcm'"`
+	Exclude []string `arg:"--exclude" help:"Skip the files matching one of the glob patterns"`
+	Dir     string   `arg:"positional,required" help:"The DICOM input directory"`
+}
+
+func (indexArgs) Description() string {
+	return "Builds or updates the index of a directory used by view --index, e.g. nightly for a large archive"
+}
+
+// runs 'dcmtagger index <dir>' without starting the UI
+func runIndexCommand(commandArgs []string) {
+	var args indexArgs
+	p, ok := parseCommandArgs("index", &args, commandArgs)
+	if !ok {
+		return
+	}
+	pathFilter, err := dicomtree.NewPathFilter(args.Include, args.Exclude)
+	if err != nil {
+		failUsage(p, err.Error())
+	}
+	ix, entries, err := openIndex(args.Dir, pathFilter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error indexing %s: %s\n", args.Dir, err.Error())
+		os.Exit(exitError)
+	}
+	ix.Close()
+	path, _ := index.Path(args.Dir)
+	fmt.Printf("Indexed %d files of %s in %s\n", len(entries), args.Dir, path)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// shells of 'dcmtagger completion'
+var completionShells = []string{"bash", "zsh", "fish"}
+
+type completionArgs struct {
+	Shell string `arg:"positional,required" help:"The shell to generate the completion for: bash, zsh or fish"`
+}
+
+func (completionArgs) Description() string {
+	return "Writes the completion script of the commands and their flags for bash, zsh or fish to stdout"
+}
+
+// runs 'dcmtagger completion <shell>', e.g. 'source <(dcmtagger completion bash)'
+func runCompletionCommand(commandArgs []string) {
+	var args completionArgs
+	p, ok := parseCommandArgs("completion", &args, commandArgs)
+	if !ok {
+		return
+	}
+	if err := writeCompletion(os.Stdout, args.Shell, subcommands()); err != nil {
+		failUsage(p, err.Error())
+	}
+}
+
+// a flag of the arguments of a subcommand
+type commandFlag struct {
+	name  string // with the dashes, e.g. "--include"
+	help  string
+	value bool // whether the flag is followed by a value
+}
+
+// returns the flags of the go-arg struct dest points to, with --help
+func commandFlags(dest interface{}) []commandFlag {
+	var flags []commandFlag
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				collect(field.Type)
+				continue
+			}
+			for _, part := range strings.Split(field.Tag.Get("arg"), ",") {
+				if strings.HasPrefix(part, "--") {
+					flags = append(flags, commandFlag{part, field.Tag.Get("help"), field.Type.Kind() != reflect.Bool})
+				}
+			}
+		}
+	}
+	collect(reflect.TypeOf(dest).Elem())
+	return append(flags, commandFlag{"--help", "Display this help and exit", false})
+}
+
+// returns the first clause of a help text, short enough for the completion menu
+func summary(help string) string {
+	if i := strings.IndexAny(help, ",."); i > 0 {
+		help = help[:i]
+	}
+	return strings.TrimSpace(help)
+}
+
+// writes the completion script for the shell, the view flags are also completed without a command
+func writeCompletion(w io.Writer, shell string, commands []subcommand) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, commands)
+	case "zsh":
+		writeZshCompletion(w, commands)
+	case "fish":
+		writeFishCompletion(w, commands)
+	default:
+		return fmt.Errorf("unknown shell '%s', use %s", shell, strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+func flagNames(flags []commandFlag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.name
+	}
+	return strings.Join(names, " ")
+}
+
+func writeBashCompletion(w io.Writer, commands []subcommand) {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	fmt.Fprintln(w, "# bash completion of dcmtagger, source <(dcmtagger completion bash)")
+	fmt.Fprintln(w, "_dcmtagger() {")
+	fmt.Fprintln(w, `    local cur=${COMP_WORDS[COMP_CWORD]} command=${COMP_WORDS[1]} flags words`)
+	fmt.Fprintln(w, `    if [ "$COMP_CWORD" -eq 1 ] && [[ $cur != -* ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\") $(compgen -f -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case $command in`)
+	for _, c := range commands {
+		fmt.Fprintf(w, "        %s) flags=\"%s\" words=\"%s\" ;;\n", c.name, flagNames(commandFlags(c.args)), strings.Join(c.words, " "))
+	}
+	fmt.Fprintf(w, "        *) flags=\"%s\" ;;\n", flagNames(commandFlags(&args{})))
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, `    if [[ $cur == -* ]]; then`)
+	fmt.Fprintln(w, `        COMPREPLY=($(compgen -W "$flags" -- "$cur"))`)
+	fmt.Fprintln(w, `    elif [ -n "$words" ]; then`)
+	fmt.Fprintln(w, `        COMPREPLY=($(compgen -W "$words" -- "$cur"))`)
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintln(w, `        COMPREPLY=($(compgen -f -- "$cur"))`)
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o filenames -F _dcmtagger dcmtagger")
+}
+
+// returns the _arguments specs of the flags and positionals
+func zshSpecs(c subcommand) string {
+	escape := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+	var specs []string
+	for _, f := range commandFlags(c.args) {
+		spec := fmt.Sprintf("'%s[%s]'", f.name, escape.Replace(summary(f.help)))
+		if f.value {
+			spec = fmt.Sprintf("'*%s[%s]:value:'", f.name, escape.Replace(summary(f.help)))
+		}
+		specs = append(specs, spec)
+	}
+	if len(c.words) > 0 {
+		return strings.Join(append(specs, fmt.Sprintf("'1:%s:(%s)'", c.name, strings.Join(c.words, " "))), " ")
+	}
+	return strings.Join(append(specs, "'*:file:_files'"), " ")
+}
+
+func writeZshCompletion(w io.Writer, commands []subcommand) {
+	escape := strings.NewReplacer("'", `'\''`, ":", `\:`)
+	fmt.Fprintln(w, "#compdef dcmtagger")
+	fmt.Fprintln(w, "# zsh completion of dcmtagger, source <(dcmtagger completion zsh)")
+	fmt.Fprintln(w, "_dcmtagger() {")
+	fmt.Fprintln(w, "    local -a commands")
+	fmt.Fprint(w, "    commands=(")
+	for _, c := range commands {
+		fmt.Fprintf(w, "\n        '%s:%s'", c.name, escape.Replace(summary(c.args.Description())))
+	}
+	fmt.Fprintln(w, "\n    )")
+	fmt.Fprintln(w, `    if (( CURRENT == 2 )) && [[ $words[2] != -* ]]; then`)
+	fmt.Fprintln(w, "        _describe command commands")
+	fmt.Fprintln(w, "        _files")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    case $words[2] in")
+	for _, c := range commands {
+		fmt.Fprintf(w, "        %s) shift words; (( CURRENT-- )); _arguments %s ;;\n", c.name, zshSpecs(c))
+	}
+	fmt.Fprintf(w, "        *) _arguments %s ;;\n", zshSpecs(subcommand{args: &args{}}))
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, `compdef _dcmtagger dcmtagger`)
+}
+
+func writeFishCompletion(w io.Writer, commands []subcommand) {
+	quote := func(text string) string { return "'" + strings.ReplaceAll(text, "'", `\'`) + "'" }
+	var others []string // all commands but view, whose flags are also completed without a command
+	for _, c := range commands {
+		if c.name != "view" {
+			others = append(others, c.name)
+		}
+	}
+	writeFlags := func(condition string, flags []commandFlag) {
+		for _, f := range flags {
+			required := ""
+			if f.value {
+				required = " -r"
+			}
+			fmt.Fprintf(w, "complete -c dcmtagger -n %s -l %s%s -d %s\n", quote(condition), strings.TrimPrefix(f.name, "--"),
+				required, quote(summary(f.help)))
+		}
+	}
+	fmt.Fprintln(w, "# fish completion of dcmtagger, dcmtagger completion fish | source")
+	for _, c := range commands {
+		fmt.Fprintf(w, "complete -c dcmtagger -n __fish_use_subcommand -a %s -d %s\n", c.name, quote(summary(c.args.Description())))
+	}
+	writeFlags("not __fish_seen_subcommand_from "+strings.Join(others, " "), commandFlags(&args{}))
+	for _, c := range commands {
+		if c.name == "view" {
+			continue
+		}
+		condition := "__fish_seen_subcommand_from " + c.name
+		writeFlags(condition, commandFlags(c.args))
+		if len(c.words) > 0 {
+			fmt.Fprintf(w, "complete -c dcmtagger -n %s -f -a %s\n", quote(condition), quote(strings.Join(c.words, " ")))
+		}
+	}
+}
@@ -5,9 +5,12 @@ go 1.22
 require (
 	github.com/alexflint/go-arg v1.4.3
 	github.com/gdamore/tcell/v2 v2.5.4
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/rivo/tview v0.0.0-20230104153304-892d1a2eb0da
 	github.com/stretchr/testify v1.8.1
 	github.com/suyashkumar/dicom v1.0.5
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
+	golang.org/x/text v0.6.0
 )
 
 require (
@@ -20,6 +23,5 @@ require (
 	github.com/rivo/uniseg v0.4.3 // indirect
 	golang.org/x/sys v0.4.0 // indirect
 	golang.org/x/term v0.4.0 // indirect
-	golang.org/x/text v0.6.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
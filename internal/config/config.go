@@ -0,0 +1,173 @@
+// Package config loads and saves the user settings of the viewer, stored as JSON in the user config directory.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/drcynic/dcmtagger/pkg/hook"
+)
+
+// size and state of a pane next to the tree, e.g. the details pane
+type Pane struct {
+	Size      int  `json:"size"` // width in columns including the border
+	Collapsed bool `json:"collapsed"`
+}
+
+// a saved view of :view, the zero value of each leaves the current state unchanged
+type View struct {
+	SortMode int      `json:"sortMode,omitempty"`
+	Filter   string   `json:"filter,omitempty"`  // :filter expression
+	Search   string   `json:"search,omitempty"`  // search text of /
+	Columns  []string `json:"columns,omitempty"` // tags of :table, shown as table when the view is recalled
+}
+
+// settings of the config file, the zero value of each is the built-in default. Command line flags override them.
+type Config struct {
+	Panes          map[string]Pane       `json:"panes,omitempty"`
+	SortMode       int                   `json:"sortMode,omitempty"`       // 1 by filename, 2 by tag, 3 by tag with different values only, 4 by patient, study and series, 5 by module
+	ExpandDepth    int                   `json:"expandDepth,omitempty"`    // tree levels below the root expanded at startup
+	Theme          string                `json:"theme,omitempty"`          // "dark" or "light"
+	Keymap         map[string]string     `json:"keymap,omitempty"`         // key typed to the key it acts as, e.g. "x": "q"
+	MaxValueLength int                   `json:"maxValueLength,omitempty"` // negative for no truncation
+	Workers        int                   `json:"workers,omitempty"`        // files parsed in parallel by the UI, the number of CPUs by default
+	Remotes        map[string]string     `json:"remotes,omitempty"`        // name to base URL, inputs "name:path" are fetched below it
+	Worklist       *dimse.Node           `json:"worklist,omitempty"`       // modality worklist SCP queried by :worklist
+	Nodes          map[string]dimse.Node `json:"nodes,omitempty"`          // name to remote AE or DICOMweb server, managed with :nodes
+	Views          map[string]View       `json:"views,omitempty"`          // name to saved view, managed with :view
+	Pins           []string              `json:"pins,omitempty"`           // tags shown in the pin bar, managed with :pin
+	Hooks          map[string][]string   `json:"hooks,omitempty"`          // event "load" or "save" to the commands run for each file, see pkg/hook
+	Tools          map[string]string     `json:"tools,omitempty"`          // name to command of :open, with the placeholders {file} and {tag}
+}
+
+// returns the keymap as runes, every key and target must be a single character
+func (cfg Config) Runes() (map[rune]rune, error) {
+	keymap := make(map[rune]rune, len(cfg.Keymap))
+	for key, target := range cfg.Keymap {
+		if utf8.RuneCountInString(key) != 1 || utf8.RuneCountInString(target) != 1 {
+			return nil, fmt.Errorf("keymap entry '%s': '%s' is not a single key", key, target)
+		}
+		k, _ := utf8.DecodeRuneInString(key)
+		t, _ := utf8.DecodeRuneInString(target)
+		keymap[k] = t
+	}
+	return keymap, nil
+}
+
+// returns the URL of an input "name:path" with a name of the remotes, other inputs unchanged
+func (cfg Config) ExpandInput(input string) string {
+	name, path, ok := strings.Cut(input, ":")
+	base, known := cfg.Remotes[name]
+	if !ok || !known || strings.HasPrefix(path, "//") {
+		return input
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (cfg Config) validate() error {
+	if cfg.SortMode < 0 || cfg.SortMode > 5 {
+		return fmt.Errorf("sortMode %d is not 1, 2, 3, 4 or 5", cfg.SortMode)
+	}
+	if cfg.ExpandDepth < 0 {
+		return fmt.Errorf("negative expandDepth %d", cfg.ExpandDepth)
+	}
+	if cfg.Workers < 0 {
+		return fmt.Errorf("negative workers %d", cfg.Workers)
+	}
+	switch cfg.Theme {
+	case "", "dark", "light":
+	default:
+		return fmt.Errorf("unknown theme '%s', use dark or light", cfg.Theme)
+	}
+	if w := cfg.Worklist; w != nil && (w.Host == "" || w.Port <= 0 || w.AET == "") {
+		return fmt.Errorf("worklist needs host, port and aet")
+	}
+	for name, view := range cfg.Views {
+		if view.SortMode < 0 || view.SortMode > 5 {
+			return fmt.Errorf("view '%s': sortMode %d is not 1, 2, 3, 4 or 5", name, view.SortMode)
+		}
+	}
+	for event := range cfg.Hooks {
+		if event != hook.Load && event != hook.Save {
+			return fmt.Errorf("unknown hook event '%s', use load or save", event)
+		}
+	}
+	for name, node := range cfg.Nodes {
+		if err := node.Validate(); err != nil {
+			return fmt.Errorf("node '%s': %w", name, err)
+		}
+	}
+	_, err := cfg.Runes()
+	return err
+}
+
+// returns the path of the config file, $XDG_CONFIG_HOME/dcmtagger/config.json on Linux
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dcmtagger", "config.json"), nil
+}
+
+// returns the path of the pseudonym mapping of :pseudonymize next to the config file
+func PseudonymsPath() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "pseudonyms.csv"), nil
+}
+
+// returns the directory of the own de-identification profiles of :anonymize next to the config file
+func ProfilesDir() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "profiles"), nil
+}
+
+// loads the config file, an empty config if there is none yet or it has invalid values
+func Load() (Config, error) {
+	var cfg Config
+	path, err := Path()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// writes the config file, creating its directory if needed
+func Save(cfg Config) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAndSave(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, err := Load()
+	assert.NoError(err, "missing file is no error")
+	assert.Empty(cfg.Panes)
+
+	cfg.Panes = map[string]Pane{"details": {Size: 50, Collapsed: true}}
+	require.NoError(t, Save(cfg))
+	loaded, err := Load()
+	assert.NoError(err)
+	assert.Equal(cfg, loaded)
+
+	path, err := Path()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err = Load()
+	assert.Error(err)
+}
+
+func TestLoadSettings(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path, err := Path()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"sortMode": 3, "expandDepth": 2, "theme": "light", "keymap": {"x": "q"},
+		"maxValueLength": -1, "workers": 4, "remotes": {"pacs": "https://pacs.example.com/dicom/"},
+		"worklist": {"host": "ris.example.com", "port": 104, "aet": "RIS"},
+		"views": {"ct-review": {"sortMode": 4, "filter": "Modality=CT", "columns": ["SeriesNumber"]}},
+		"hooks": {"save": ["dciodvfy-json"]}}`), 0o644))
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(3, cfg.SortMode)
+	assert.Equal(2, cfg.ExpandDepth)
+	assert.Equal("light", cfg.Theme)
+	assert.Equal(-1, cfg.MaxValueLength)
+	assert.Equal(4, cfg.Workers)
+	assert.Equal("RIS@ris.example.com:104", cfg.Worklist.String())
+	assert.Equal(View{SortMode: 4, Filter: "Modality=CT", Columns: []string{"SeriesNumber"}}, cfg.Views["ct-review"])
+	assert.Equal(map[string][]string{"save": {"dciodvfy-json"}}, cfg.Hooks)
+	keymap, err := cfg.Runes()
+	assert.NoError(err)
+	assert.Equal(map[rune]rune{'x': 'q'}, keymap)
+
+	assert.Equal("https://pacs.example.com/dicom/study1/", cfg.ExpandInput("pacs:study1/"))
+	assert.Equal("https://pacs.example.com/dicom/a.dcm", cfg.ExpandInput("pacs:/a.dcm"))
+	assert.Equal("other:a.dcm", cfg.ExpandInput("other:a.dcm"))
+	assert.Equal("dir/a.dcm", cfg.ExpandInput("dir/a.dcm"))
+
+	for _, invalid := range []string{`{"sortMode": 6}`, `{"theme": "pink"}`, `{"keymap": {"x": "ctrl+q"}}`, `{"workers": -1}`,
+		`{"worklist": {"host": "ris"}}`, `{"nodes": {"pacs": {"host": "pacs", "port": 104}}}`,
+		`{"views": {"ct": {"sortMode": 7}}}`, `{"hooks": {"open": ["true"]}}`} {
+		require.NoError(t, os.WriteFile(path, []byte(invalid), 0o644))
+		cfg, err = Load()
+		assert.Error(err, invalid)
+		assert.Equal(Config{}, cfg, "defaults for invalid values")
+	}
+}
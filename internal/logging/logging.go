@@ -0,0 +1,30 @@
+// Package logging configures the structured log of parse warnings, writes, network transactions and edits, which
+// the packages write with log/slog to the default logger.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// discards all log records, the default as the terminal UI would be overwritten by output on stderr
+func Disable() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// appends the records with at least the level (debug, info, warn or error) to the file, the returned function closes it
+func Open(path string, level string) (func() error, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return nil, fmt.Errorf("invalid log level '%s', use debug, info, warn or error", level)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(file, &slog.HandlerOptions{Level: minLevel})))
+	return file.Close, nil
+}
@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	assert := assert.New(t)
+	defer slog.SetDefault(slog.Default())
+
+	path := filepath.Join(t.TempDir(), "dcmtagger.log")
+	closeLog, err := Open(path, "info")
+	require.NoError(t, err)
+	slog.Debug("parsed file", "path", "a.dcm")
+	slog.Info("wrote file", "path", "b.dcm")
+	assert.NoError(closeLog())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.NotContains(string(data), "a.dcm", "below the level")
+	assert.Contains(string(data), `level=INFO msg="wrote file" path=b.dcm`)
+
+	_, err = Open(path, "verbose")
+	assert.Error(err)
+}
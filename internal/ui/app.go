@@ -0,0 +1,846 @@
+package ui
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/hook"
+	"github.com/drcynic/dcmtagger/pkg/index"
+	"github.com/drcynic/dcmtagger/pkg/notes"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// columns the tree is scrolled horizontally by < and >
+const horizontalScrollStep = 10
+
+type EditMode int
+
+const (
+	TreeMode EditMode = iota
+	CmdlineMode
+)
+
+// the viewer with its widgets and state, created with NewApp
+type App struct {
+	app        *tview.Application
+	screen     *cancelScreen // the terminal unless set by SetScreen
+	pages      *tview.Pages
+	tree       *treeModel
+	mainGrid   *tview.Grid     // the pin bar if elements are pinned, the body and the lines below it
+	body       *tview.Flex     // the tree and the panes right of it
+	pinBar     *tview.TextView // values of the pinned elements in the file of the current node
+	details    *pane
+	breadcrumb *tview.TextView // path and position of the current node
+	statusLine *tview.TextView
+	cmdline    *tview.InputField
+
+	rootDir        string
+	entries        []dicomtree.Entry
+	searchText     string
+	sortMode       int
+	fileOrder      dicomtree.FileOrder
+	reverseOrder   bool
+	displayOptions dicomtree.DisplayOptions
+	index          *index.Index    // optional, used for tag stats and filters
+	filterPaths    map[string]bool // paths of the entries matching the :filter expression, nil if no filter
+	filterText     string          // the :filter expression of filterPaths
+	tableColumns   []string        // tags of the last :table, saved with :view save
+	pins           []tag.Tag       // elements of :pin shown in the pin bar
+	changes        edit.Changelog
+	hooks          map[string][]string // event to the commands run for each file, see pkg/hook
+	protected      edit.ProtectedTags
+	config         *config.Config // saved on changes of the pane sizes, nil if not persisted
+	paneKey        bool           // ctrl+w was pressed, the next key changes a pane
+	foldKey        bool           // z was pressed, the next key sets the fold level
+	bracketKey     rune           // [ or ] was pressed outside of the series sort, f jumps to the file
+	showFileInfo   bool           // size, modification time, transfer syntax and SOP class next to the file nodes
+	workers        int            // files parsed in parallel when all files are needed
+	keymap         map[rune]rune  // typed key to the key it acts as in the tree
+	pseudonyms     string         // mapping file of :pseudonymize, pseudonyms.csv in the config directory if empty
+	notes          *notes.Notes   // notes of :note, nil without sidecar file
+	assumeYes      bool           // batch commands change the files without the preview of their changes
+	outputDir      string         // files are written to this directory mirroring the input instead of in place if set
+	trace          networkTrace   // events of the network operations shown by :trace
+
+	searcher          *searcher
+	appliedSearchText string            // search text the highlighted nodes match
+	searchStart       *searchView       // view before the search being typed, nil if none
+	confirmedSearch   string            // lowercase text of the last search confirmed with enter, used by n and N
+	searchHistory     []string          // confirmed searches of the session, the newest last
+	historyPosition   int               // position in the history recalled with up and down
+	highlightedNodes  []*tview.TreeNode // nodes matching the last search
+
+	// queries the worklist of :worklist, dimse.QueryWorklist unless replaced in tests
+	queryWorklist func(dimse.Node, dimse.WorklistQuery) ([]dicom.Dataset, error)
+	// retrieves the archive copy of :compare-pacs, retrieveInstance unless replaced in tests
+	retrieve func(dimse.Node, dimse.Instance) (dicom.Dataset, error)
+}
+
+// runs the viewer for the given entries until quit, rootDir is the text of the root node
+func Run(rootDir string, entries []dicomtree.Entry) error {
+	return NewApp(rootDir, entries).Run()
+}
+
+// creates the viewer with all widgets and key handlings, the tree is built sorted by filename
+func NewApp(rootDir string, entries []dicomtree.Entry) *App {
+	a := &App{
+		app:            tview.NewApplication(),
+		screen:         &cancelScreen{},
+		pages:          tview.NewPages(),
+		tree:           newTreeModel(),
+		mainGrid:       tview.NewGrid().SetColumns(-1).SetBorders(true),
+		body:           tview.NewFlex(),
+		pinBar:         tview.NewTextView().SetDynamicColors(true),
+		details:        newDetailsPane(),
+		breadcrumb:     tview.NewTextView().SetTextColor(tcell.ColorGray),
+		statusLine:     tview.NewTextView(),
+		cmdline:        tview.NewInputField().SetFieldBackgroundColor(tview.Styles.PrimitiveBackgroundColor),
+		rootDir:        rootDir,
+		entries:        entries,
+		sortMode:       1,
+		workers:        runtime.NumCPU(),
+		protected:      edit.NewProtectedTags(edit.DefaultProtectedTags),
+		displayOptions: dicomtree.DisplayOptions{MaxValueLength: dicomtree.DefaultMaxValueLength},
+		queryWorklist:  dimse.QueryWorklist,
+		retrieve:       retrieveInstance,
+	}
+	a.searcher = &searcher{
+		delay: searchDelay,
+		queue: func(f func()) { a.app.QueueUpdate(f) },
+		draw:  func() { a.app.ForceDraw() },
+	}
+
+	a.buildTree()
+	a.layoutPanes()
+	a.layoutMain()
+
+	a.app.SetInputCapture(a.handleGlobalKey)
+	a.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		a.breadcrumb.SetText(breadcrumbText(a.tree)) // follows every move, also those not done by keys
+		a.updatePinBar()
+		a.updateDetailsPane()
+		return false
+	})
+	a.cmdline.SetInputCapture(a.handleCmdlineKey)
+	a.cmdline.SetChangedFunc(func(text string) {
+		if strings.HasPrefix(text, "/") && len(text) > 1 {
+			a.scheduleSearch(text[1:])
+		}
+	})
+	a.tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		if !node.IsExpanded() {
+			a.loadFileNodes(node)
+		}
+		node.SetExpanded(!node.IsExpanded())
+		a.tree.invalidate()
+	})
+	a.tree.SetInputCapture(a.handleTreeKey)
+
+	a.pages.AddPage("main", a.mainGrid, true, true)
+	a.app.SetRoot(a.pages, true)
+	return a
+}
+
+// sets the screen to draw on instead of the terminal, e.g. a tcell.SimulationScreen in tests
+func (a *App) SetScreen(screen tcell.Screen) *App {
+	a.screen.Screen = screen
+	a.app.SetScreen(a.screen)
+	return a
+}
+
+// sets the index the entries were loaded from, sort by tag and :filter are then computed by the index
+func (a *App) SetIndex(ix *index.Index) *App {
+	a.index = ix
+	a.buildTree()
+	return a
+}
+
+// sets the tags that can only be edited with explicit override, edit.DefaultProtectedTags by default
+func (a *App) SetProtectedTags(protected edit.ProtectedTags) *App {
+	a.protected = protected
+	return a
+}
+
+// sets the config with the pane sizes, which is saved when they are changed
+func (a *App) SetConfig(cfg *config.Config) *App {
+	a.config = cfg
+	if p, ok := cfg.Panes[a.details.name]; ok && p.Size >= minPaneSize {
+		a.details.Pane = p
+	}
+	a.layoutPanes()
+	return a
+}
+
+// applies the startup settings of the config, e.g. with command line overrides, the panes are set by SetConfig
+func (a *App) ApplyConfig(cfg config.Config) *App {
+	if cfg.MaxValueLength != 0 {
+		a.SetMaxValueLength(cfg.MaxValueLength)
+	}
+	if cfg.Workers > 0 {
+		a.SetWorkers(cfg.Workers)
+	}
+	if keymap, err := cfg.Runes(); err == nil {
+		a.keymap = keymap
+	}
+	if cfg.SortMode != 0 {
+		a.SetSortMode(cfg.SortMode)
+	}
+	if cfg.ExpandDepth > 0 {
+		a.SetExpandDepth(cfg.ExpandDepth)
+	}
+	if pins, err := parseTags(cfg.Pins); err == nil && len(pins) > 0 {
+		a.setPins(pins)
+	}
+	if len(cfg.Hooks) > 0 {
+		a.SetHooks(cfg.Hooks)
+	}
+	return a
+}
+
+// sets the length values are truncated to, negative for no truncation
+func (a *App) SetMaxValueLength(length int) *App {
+	a.displayOptions.MaxValueLength = length
+	a.refreshTree()
+	return a
+}
+
+// sets the number of files parsed in parallel, e.g. before sorting by tag
+func (a *App) SetWorkers(workers int) *App {
+	a.workers = max(workers, 1)
+	return a
+}
+
+// sets the mapping file of :pseudonymize instead of pseudonyms.csv in the config directory
+func (a *App) SetPseudonymsFile(path string) *App {
+	a.pseudonyms = path
+	return a
+}
+
+// loads the notes of :note from the sidecar file, which is created with the first note
+func (a *App) SetNotesFile(path string) *App {
+	n, err := notes.Load(path)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error loading notes: %s", err.Error()))
+		return a
+	}
+	a.notes = n
+	a.updateAnnotations()
+	return a
+}
+
+// writes edited files to the directory, preserving their paths relative to the input, instead of overwriting them
+func (a *App) SetOutputDir(dir string) *App {
+	a.outputDir = dir
+	return a
+}
+
+// applies batch commands like :anonymize without showing their changes and asking first
+func (a *App) SetAssumeYes(yes bool) *App {
+	a.assumeYes = yes
+	return a
+}
+
+// sets the sort mode like the keys 1 to 5, parsing all files for the modes by tag and hierarchy
+func (a *App) SetSortMode(mode int) *App {
+	a.sortMode = mode
+	var err error
+	if a.sortMode != 1 && a.sortMode != 5 && a.index == nil {
+		err = a.parseAllEntries() // the values of all files are needed to sort by tag or patient, study and series
+	}
+	a.buildTree()
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+	}
+	return a
+}
+
+// expands the tree nodes down to the given number of levels below the root, parsing the files of expanded file nodes
+func (a *App) SetExpandDepth(depth int) *App {
+	a.foldToLevels(depth + 1)
+	return a
+}
+
+// shows only the files matching the filter expression, like :filter
+func (a *App) SetFilter(text string) *App {
+	a.applyFilter(text)
+	return a
+}
+
+// runs the event loop until quit
+func (a *App) Run() error {
+	if a.screen.Screen == nil {
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			return err
+		}
+		a.SetScreen(screen)
+	}
+	return a.app.Run()
+}
+
+func (a *App) buildTree() {
+	a.scanWarnings()
+	entries := dicomtree.SortedEntries(a.visibleEntries(), a.fileOrder, a.reverseOrder)
+	var model *dicomtree.Node
+	switch a.sortMode {
+	case 1:
+		model = dicomtree.BuildByFilename(a.rootDir, entries, a.displayOptions)
+		a.statusLine.SetText("Sort by filename")
+	case 2:
+		model = dicomtree.BuildByTagsWithStats(a.rootDir, entries, 0, a.tagStats(entries), a.displayOptions)
+		a.statusLine.SetText("Sort by tag")
+	case 3:
+		model = dicomtree.BuildByTagsWithStats(a.rootDir, entries, 1, a.tagStats(entries), a.displayOptions)
+		a.statusLine.SetText("Sort by tag, show only different tag values")
+	case 4:
+		model = dicomtree.BuildByHierarchy(a.rootDir, entries, a.displayOptions)
+		a.statusLine.SetText("Sort by patient, study and series")
+	case 5:
+		model = dicomtree.BuildByModule(a.rootDir, entries, a.displayOptions)
+		a.statusLine.SetText("Sort by module")
+	}
+	root := newTreeNode(model)
+	a.searcher.stop() // results would refer to the old nodes
+	highlighted := len(a.highlightedNodes) > 0
+	a.highlightedNodes, a.appliedSearchText = nil, ""
+	a.tree.SetRoot(root).SetCurrentNode(root)
+	switch a.sortMode {
+	case 1, 5:
+		collapseAllRecursive(root)
+	case 4:
+		collapseAllSeries(a.tree)
+	default:
+		collapseAllLeaves(root)
+	}
+	if highlighted {
+		a.highlightSearchMatches()
+	}
+	a.tree.colorize = nil
+	if a.sortMode == 3 {
+		diffs := computeValueDiffs(root, entries, a.displayOptions)
+		a.tree.colorize = func(node *tview.TreeNode) string {
+			if d, ok := diffs[node]; ok {
+				return d.coloredText(node.GetText())
+			}
+			return ""
+		}
+	}
+}
+
+// scans the parsed files for parse warnings, which are shown as badges on their nodes
+func (a *App) scanWarnings() {
+	for i := range a.entries {
+		if !a.entries[i].Partial {
+			a.entries[i].EnsureWarnings()
+		}
+	}
+	a.updateAnnotations()
+}
+
+// returns the status text of the current file order
+func (a *App) fileOrderText() string {
+	text := "Files sorted by " + a.fileOrder.String()
+	if a.reverseOrder {
+		text += ", reversed"
+	}
+	return text
+}
+
+// rebuilds the tree in the current sort mode, keeping expansion state, current node and the zoom of :root
+func (a *App) refreshTree() {
+	zoomPath := ""
+	if a.tree.fullRoot != nil {
+		zoomPath = a.tree.fullPath(a.tree.GetRoot())
+		a.tree.unzoom()
+	}
+	state := captureTreeState(a.tree)
+	a.buildTree()
+	restoreTreeState(a.tree, state)
+	if zoomPath != "" {
+		a.tree.zoomToPath(zoomPath)
+	}
+}
+
+// returns the entries matching the current filter, all entries without filter
+func (a *App) visibleEntries() []dicomtree.Entry {
+	if a.filterPaths == nil {
+		return a.entries
+	}
+	entries := make([]dicomtree.Entry, 0, len(a.filterPaths))
+	for _, entry := range a.entries {
+		if a.filterPaths[entry.Path] {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// returns the tag stats from the index as long as it represents the entries, computes them otherwise
+func (a *App) tagStats(entries []dicomtree.Entry) dicomtree.TagStats {
+	if a.index == nil || a.filterPaths != nil {
+		return dicomtree.ComputeTagStats(entries)
+	}
+	for _, entry := range entries {
+		if !entry.Partial {
+			return dicomtree.ComputeTagStats(entries) // loaded and maybe edited
+		}
+	}
+	stats, err := a.index.TagStats()
+	if err != nil {
+		return dicomtree.ComputeTagStats(entries)
+	}
+	return stats
+}
+
+// shows how often each value of the tag of the node occurs in the visible files and a histogram of numeric values,
+// only when sorted by tag
+func (a *App) showValueFrequencies(node *tview.TreeNode) {
+	if !isTagNode(node) {
+		return
+	}
+	if a.sortMode != 2 && a.sortMode != 3 {
+		a.statusLine.SetText("value frequencies are shown when sorted by tag (2, 3)")
+		return
+	}
+	t := node.GetReference().(*dicom.Element).Tag
+	frequencies, missing := dicomtree.ValueFrequencies(a.visibleEntries(), t)
+	title := strings.TrimSpace(dicomtree.FormatTag(t) + " " + dicomtree.TagNameByTag(t))
+	addAndShowValueFrequenciesPage(a.pages, title, frequencies, missing, dicomtree.NumericTagValues(a.visibleEntries(), t))
+}
+
+// parses the complete file of a partial entry from the index and rebuilds the tree with its elements
+func (a *App) loadEntry(entry *dicomtree.Entry) error {
+	if !entry.Partial {
+		return nil
+	}
+	if err := entry.Load(); err != nil {
+		return fmt.Errorf("error loading %s: %w", entry.Filename, err)
+	}
+	err := a.runHooks(hook.Load, entry)
+	a.refreshTree()
+	return err
+}
+
+// parses all partial entries with the workers in parallel without rebuilding the tree, showing the progress. The
+// error of the first entry failing is returned after trying all.
+func (a *App) parseAllEntries() error {
+	var partial []int
+	for i := range a.entries {
+		if a.entries[i].Partial {
+			partial = append(partial, i)
+		}
+	}
+	errs := make([]error, len(a.entries))
+	indices := make(chan int)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < max(a.workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = a.entries[i].Load()
+				done <- struct{}{}
+			}
+		}()
+	}
+	go func() {
+		defer close(indices)
+		for _, i := range partial {
+			select {
+			case indices <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	p := a.startProgress("loading", len(partial))
+	for range done {
+		if err := p.step(); err != nil && !isClosed(stop) {
+			close(stop)
+		}
+	}
+	if err := p.finish(); err != nil {
+		return err
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("error loading %s: %w", a.entries[i].Filename, err)
+		}
+	}
+	for _, i := range partial {
+		if err := a.runHooks(hook.Load, &a.entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// parses all partial entries and rebuilds the tree
+func (a *App) loadAllEntries() error {
+	err := a.parseAllEntries()
+	a.refreshTree()
+	return err
+}
+
+// parses the partial entries of the given file nodes and adds their group and tag nodes in place, called before expanding
+func (a *App) loadFileNodes(nodes ...*tview.TreeNode) {
+	if a.sortMode != 1 && a.sortMode != 4 && a.sortMode != 5 {
+		return
+	}
+	build := dicomtree.BuildByFilename
+	if a.sortMode == 5 {
+		build = dicomtree.BuildByModule
+	}
+	for _, node := range nodes {
+		if isTagNode(node) {
+			continue
+		}
+		entry := dicomtree.FindEntryByFilename(a.entries, node.GetText())
+		if entry == nil || !entry.Partial {
+			continue
+		}
+		if err := entry.Load(); err != nil {
+			a.statusLine.SetText(fmt.Sprintf("error loading %s: %s", entry.Filename, err.Error()))
+			continue
+		}
+		if err := a.runHooks(hook.Load, entry); err != nil {
+			a.statusLine.SetText(err.Error())
+		}
+		entry.EnsureWarnings()
+		a.updateAnnotations()
+		setFileNodeChildren(node, build(entry.Filename, []dicomtree.Entry{*entry}, a.displayOptions))
+		a.tree.invalidateStructure()
+	}
+}
+
+func (a *App) handleGlobalKey(event *tcell.EventKey) *tcell.EventKey {
+	if _, ok := a.app.GetFocus().(*tview.InputField); ok {
+		return event // typing into the commandline or a form, e.g. a path with '/'
+	}
+	if target, ok := a.keymap[event.Rune()]; ok && event.Key() == tcell.KeyRune {
+		event = tcell.NewEventKey(tcell.KeyRune, target, event.Modifiers())
+	}
+	switch event.Key() {
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case '/':
+			a.startSearch()
+			a.app.SetFocus(a.cmdline)
+			a.cmdline.SetText("/")
+			return nil
+		case ':':
+			a.app.SetFocus(a.cmdline)
+			a.cmdline.SetText(":")
+			return nil
+		case '?':
+			addAndShowHelpPage(a.pages)
+			return nil
+		}
+	}
+	return event
+}
+
+func (a *App) handleCmdlineKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEsc:
+		if strings.HasPrefix(a.cmdline.GetText(), "/") {
+			a.searcher.stop()
+			a.clearSearchHighlights()
+			a.cancelSearch()
+		}
+		a.cmdline.SetText("")
+		a.app.SetFocus(a.tree)
+		return nil
+	case tcell.KeyUp, tcell.KeyDown:
+		if strings.HasPrefix(a.cmdline.GetText(), "/") {
+			offset := -1
+			if event.Key() == tcell.KeyDown {
+				offset = 1
+			}
+			a.recallSearch(offset)
+			return nil
+		}
+	case tcell.KeyEnter:
+		cmdlineText := a.cmdline.GetText()
+		if cmdlineText == ":q" {
+			a.app.Stop()
+			return nil
+		}
+		if strings.HasPrefix(cmdlineText, ":") {
+			a.cmdline.SetText("")
+			a.app.SetFocus(a.tree)
+			a.executeCommand(cmdlineText)
+			return nil
+		}
+		if strings.HasPrefix(cmdlineText, "/") {
+			if len(cmdlineText) > 1 {
+				a.finishSearch()
+				a.confirmedSearch = a.searchText
+				a.addSearchHistory(cmdlineText[1:])
+			}
+			a.searchStart = nil
+			a.app.SetFocus(a.tree)
+			return nil
+		}
+	}
+
+	return event
+}
+
+func (a *App) handleTreeKey(event *tcell.EventKey) *tcell.EventKey {
+	tree := a.tree
+	currentNode := tree.GetCurrentNode()
+
+	if a.paneKey {
+		a.paneKey = false
+		if event.Key() == tcell.KeyRune {
+			a.handlePaneKey(event.Rune())
+		} else {
+			a.statusLine.SetText("")
+		}
+		return nil
+	}
+	if a.foldKey {
+		a.foldKey = false
+		if event.Key() == tcell.KeyRune {
+			a.handleFoldKey(event.Rune())
+		} else {
+			a.statusLine.SetText("")
+		}
+		return nil
+	}
+	if a.bracketKey != 0 {
+		offset := 1
+		if a.bracketKey == '[' {
+			offset = -1
+		}
+		a.bracketKey = 0
+		if event.Key() == tcell.KeyRune && event.Rune() == 'f' {
+			a.jumpToFile(offset)
+		} else {
+			a.statusLine.SetText("")
+		}
+		return nil
+	}
+
+	switch key := event.Key(); key {
+	case tcell.KeyCtrlW:
+		a.paneKey = true
+		a.statusLine.SetText("ctrl+w: +/- resize, c collapse, = default size")
+	case tcell.KeyCtrlSpace:
+		if isTagNode(currentNode) {
+			element := currentNode.GetReference().(*dicom.Element)
+			var charsets []string
+			onSave := func() {}
+			if entry := findEntryForNode(tree, currentNode, a.entries); entry != nil {
+				if entry.Partial {
+					if err := a.loadEntry(entry); err != nil {
+						a.statusLine.SetText(err.Error())
+						return nil
+					}
+					loaded, err := entry.Dataset.FindElementByTag(element.Tag)
+					if err != nil {
+						a.statusLine.SetText(fmt.Sprintf("element %s not found in %s", dicomtree.FormatTag(element.Tag), entry.Filename))
+						return nil
+					}
+					element = loaded
+					jumpToElementNode(tree, element)
+				}
+				charsets = charset.FromDataset(&entry.Dataset)
+				before := edit.TakeSnapshot(&entry.Dataset)
+				onSave = func() { a.changes.Record(entry.Filename, before, &entry.Dataset) }
+			}
+			addAndShowTagEditingPage(a.pages, element, charsets, a.protected.Check(element.Tag) != nil, onSave)
+		} else {
+			return event
+		}
+	case tcell.KeyCtrlD:
+		_, _, _, height := tree.GetInnerRect()
+		tree.Move(height / 2)
+	case tcell.KeyCtrlU:
+		_, _, _, height := tree.GetInnerRect()
+		tree.Move(-height / 2)
+	case tcell.KeyLeft:
+		if event.Modifiers() == tcell.ModShift {
+			moveToParent(tree)
+		} else {
+			collapseOrMoveToParent(tree)
+		}
+	case tcell.KeyRight:
+		if event.Modifiers() == tcell.ModShift {
+			moveToFirstChild(tree)
+		} else {
+			expandOrMoveToFirstChild(tree)
+		}
+	case tcell.KeyUp:
+		if event.Modifiers() == tcell.ModShift {
+			moveUpSameLevel(tree)
+		} else {
+			return event // not handled, pass on
+		}
+	case tcell.KeyDown:
+		if event.Modifiers() == tcell.ModShift {
+			moveDownSameLevel(tree)
+		} else {
+			return event // not handled, pass on
+		}
+	case tcell.KeyHome:
+		jumpToRoot(tree)
+	case tcell.KeyEnd:
+		jumpToLastVisibleNode(tree)
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case '1', '2', '3', '4', '5':
+			a.SetSortMode(int(event.Rune() - '0'))
+		case 'S':
+			a.collapseSeries()
+		case 'X':
+			a.expandOnlyCurrentSeries()
+		case '[', ']':
+			offset, direction := 1, "next"
+			if event.Rune() == '[' {
+				offset, direction = -1, "previous"
+			}
+			if a.sortMode == 4 {
+				a.jumpToSeries(offset)
+			} else {
+				a.bracketKey = event.Rune()
+				a.statusLine.SetText(fmt.Sprintf("%c: f jumps to the %s file keeping the tag", event.Rune(), direction))
+			}
+		case 'p':
+			a.displayOptions.PrettyValues = !a.displayOptions.PrettyValues
+			a.refreshTree()
+			if a.displayOptions.PrettyValues {
+				a.statusLine.SetText("Pretty values on")
+			} else {
+				a.statusLine.SetText("Pretty values off")
+			}
+		case '#':
+			a.tree.lineNumbers = (a.tree.lineNumbers + 1) % 3
+			a.statusLine.SetText("Line numbers " + a.tree.lineNumbers.String())
+		case '<', '>':
+			columns := horizontalScrollStep
+			if event.Rune() == '<' {
+				columns = -columns
+			}
+			tree.scrollHorizontally(columns)
+			a.statusLine.SetText(fmt.Sprintf("Scrolled %d columns to the right", tree.scrollColumns))
+		case 't':
+			a.displayOptions.TagStyle = (a.displayOptions.TagStyle + 1) % 3
+			a.refreshTree()
+			a.statusLine.SetText("Tag display: " + a.displayOptions.TagStyle.String())
+		case 's', 'r':
+			if event.Rune() == 's' {
+				a.fileOrder = (a.fileOrder + 1) % 3
+			} else {
+				a.reverseOrder = !a.reverseOrder
+			}
+			a.refreshTree()
+			a.statusLine.SetText(a.fileOrderText())
+		case 'm':
+			a.toggleFileInfo()
+		case 'z':
+			a.foldKey = true
+			a.statusLine.SetText("z: M fold to the files, R expand all, 1-9 levels below the root")
+		case 'Z':
+			if a.tree.fullRoot != nil {
+				a.zoomOut()
+			} else {
+				a.zoomToCurrentNode()
+			}
+		case 'o':
+			a.displayOptions.ShowOffsets = !a.displayOptions.ShowOffsets
+			errorText := ""
+			if a.displayOptions.ShowOffsets {
+				for i := range a.entries {
+					if err := a.entries[i].EnsureOffsets(); err != nil {
+						errorText = fmt.Sprintf(" (%s: %s)", a.entries[i].Filename, err.Error())
+					}
+				}
+			}
+			a.refreshTree()
+			if a.displayOptions.ShowOffsets {
+				a.statusLine.SetText("File offsets on" + errorText)
+			} else {
+				a.statusLine.SetText("File offsets off")
+			}
+		case 'q':
+			a.app.Stop()
+		case 'J':
+			moveDownSameLevel(tree)
+		case 'K':
+			moveUpSameLevel(tree)
+		case 'h':
+			collapseOrMoveToParent(tree)
+		case 'l':
+			a.loadFileNodes(currentNode)
+			expandOrMoveToFirstChild(tree)
+		case 'H':
+			moveToParent(tree)
+		case 'L':
+			a.loadFileNodes(currentNode)
+			moveToFirstChild(tree)
+		case '0', '^':
+			moveToFirstSibling(tree)
+		case '$':
+			moveToLastSibling(tree)
+		case 'e':
+			a.loadFileNodes(tree.siblings(currentNode)...)
+			expandCurrentAndAllSiblings(tree)
+		case 'c':
+			collapseCurrentAndAllSiblings(tree)
+		case 'E':
+			a.loadFileNodes(append([]*tview.TreeNode{currentNode}, currentNode.GetChildren()...)...)
+			currentNode.ExpandAll()
+			tree.invalidate()
+		case 'C':
+			currentNode.CollapseAll()
+			tree.invalidate()
+		case 'g':
+			jumpToRoot(tree)
+		case 'G':
+			jumpToLastVisibleNode(tree)
+		case 'n':
+			jumpToNextFoundNode(a.searchText, tree)
+		case 'N':
+			jumpToPrevFoundNode(a.searchText, tree)
+		case 'i':
+			a.showValueFrequencies(currentNode)
+		case 'f':
+			a.followReference(currentNode)
+
+		default:
+			return event // not handled, pass on
+		}
+	default:
+		return event // not handled, pass on
+	}
+
+	return nil
+}
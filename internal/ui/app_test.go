@@ -0,0 +1,2252 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/backup"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/hook"
+	"github.com/drcynic/dcmtagger/pkg/notes"
+	"github.com/drcynic/dcmtagger/pkg/overlay"
+	"github.com/drcynic/dcmtagger/pkg/rdsr"
+	"github.com/drcynic/dcmtagger/pkg/rt"
+	"github.com/drcynic/dcmtagger/pkg/seg"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newTestEntries(t *testing.T) []dicomtree.Entry {
+	return []dicomtree.Entry{
+		{Filename: "a.dcm", Path: "testdir/a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.Modality, []string{"CT"}),
+			mustElement(t, tag.PatientName, []string{"Doe^John"}),
+		}}},
+		{Filename: "b.dcm", Path: "testdir/b.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.Modality, []string{"CT"}),
+			mustElement(t, tag.PatientName, []string{"Doe^Jane"}),
+		}}},
+	}
+}
+
+func TestAppStartup(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	screen := h.snapshot()
+	assert.Contains(screen, "testdir")
+	assert.Contains(screen, "a.dcm")
+	assert.Contains(screen, "b.dcm")
+	assert.NotContains(screen, "Doe^John", "files are collapsed")
+	assert.Contains(screen, "Sort by filename")
+	assert.Equal("testdir", h.currentNodeText())
+}
+
+func TestAppNavigation(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("jll")
+	assert.Equal("0008", h.currentNodeText())
+	h.typeText("J")
+	assert.Equal("0010", h.currentNodeText())
+	h.typeText("ll")
+	assert.Contains(h.currentNodeText(), "Doe^John")
+	assert.Contains(h.snapshot(), "Doe^John")
+
+	h.typeText("H")
+	assert.Equal("0010", h.currentNodeText())
+	h.typeText("g")
+	assert.Equal("testdir", h.currentNodeText())
+	h.typeText("G")
+	assert.Equal("b.dcm", h.currentNodeText())
+}
+
+func TestAppJumpToFile(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("jllJll")
+	assert.Contains(h.currentNodeText(), "Doe^John")
+	h.typeText("]")
+	assert.Equal("]: f jumps to the next file keeping the tag", h.statusText())
+	h.typeText("f")
+	assert.Contains(h.currentNodeText(), "Doe^Jane", "the same tag in the next file")
+	assert.Equal("b.dcm", h.statusText())
+	h.typeText("]f")
+	assert.Equal("no further file", h.statusText())
+	h.typeText("[f")
+	assert.Contains(h.currentNodeText(), "Doe^John")
+
+	h.typeText("2jjl[f")
+	assert.Equal("no further file", h.statusText())
+	h.typeText("]f")
+	assert.Contains(h.currentNodeText(), "CT", "sorted by filename to jump")
+	assert.Equal("b.dcm", h.statusText())
+}
+
+func TestAppBreadcrumb(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	breadcrumb := func() string {
+		text := ""
+		h.inspect(func(a *App) { text = a.breadcrumb.GetText(true) })
+		return text
+	}
+	assert.Equal("node 1/3  testdir", breadcrumb())
+	h.typeText("jllJll")
+	assert.Equal("node 5/6  testdir ▸ a.dcm ▸ 0010 ▸ (0010,0010) PatientName", breadcrumb())
+	assert.Contains(h.snapshot(), "node 5/6  testdir ▸ a.dcm")
+
+	h.typeText("2jjll")
+	assert.Equal("node 4/7  testdir ▸ 0008 ▸ (0008,0060) Modality ▸ a.dcm", breadcrumb())
+}
+
+func TestAppDetailsPane(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) { a.SetConfig(&config.Config{}) })
+	h.typeText("jllJll")
+	assert.NotContains(h.snapshot(), "Details", "collapsed by default")
+	h.sendKey(tcell.KeyCtrlW, 0, tcell.ModCtrl)
+	h.typeText("c")
+	assert.Equal("details pane width 40", h.statusText())
+	assert.Contains(h.snapshot(), "Details")
+	assert.Contains(h.snapshot(), "1: Doe^John")
+
+	h.sendKey(tcell.KeyCtrlW, 0, tcell.ModCtrl)
+	h.typeText("-")
+	assert.Equal("details pane width 35", h.statusText())
+	cfg, err := config.Load()
+	assert.NoError(err)
+	assert.Equal(config.Pane{Size: 35}, cfg.Panes["details"])
+
+	h.sendKey(tcell.KeyCtrlW, 0, tcell.ModCtrl)
+	h.typeText("c")
+	assert.Equal("details pane collapsed", h.statusText())
+	assert.NotContains(h.snapshot(), "Details")
+	assert.Contains(h.currentNodeText(), "Doe^John", "pane keys don't move in the tree")
+}
+
+func TestAppApplyConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) {
+		a.ApplyConfig(config.Config{SortMode: 2, ExpandDepth: 2, Workers: 2, MaxValueLength: 5, Keymap: map[string]string{"x": "j"}})
+	})
+	assert.Equal("Sort by tag", h.statusText())
+	h.typeText("x")
+	assert.Equal("0008/", h.currentNodeText(), "x acts as j")
+	assert.Contains(h.snapshot(), "├── D...] (0) - a.dcm", "tag and value nodes expanded, values truncated")
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) { a.ApplyConfig(config.Config{ExpandDepth: 1}) })
+	h.typeText("j")
+	screen := h.snapshot()
+	assert.Contains(screen, "0010")
+	assert.NotContains(screen, "Doe^John", "groups stay collapsed")
+}
+
+func TestAppLongValues(t *testing.T) {
+	assert := assert.New(t)
+
+	uids := strings.Repeat("1.2.840.10008.", 5)
+	entries := []dicomtree.Entry{{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.StudyInstanceUID, []string{uids}),
+	}}}}
+	h := newTestHarness(t, "a.dcm", entries)
+	h.typeText("lll")
+	assert.Contains(h.currentNodeText(), "...]")
+	h.typeText(":set maxvaluelen 5")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("invalid maxvaluelen, use 0 for no truncation or at least 10", h.statusText())
+	h.typeText(":set maxvaluelen=0")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("maxvaluelen=0", h.statusText())
+	assert.Contains(h.currentNodeText(), uids, "current node is kept")
+	h.typeText(":set maxvaluelen")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("maxvaluelen=0", h.statusText())
+
+	assert.Contains(h.snapshot(), "│a.dcm")
+	h.typeText(">")
+	assert.Equal("Scrolled 10 columns to the right", h.statusText())
+	assert.NotContains(h.snapshot(), "│a.dcm")
+	assert.Contains(h.snapshot(), "│ StudyInstanceUID (UI, 0): 1.2.840.10008.1.2.840", "border left of the tree is kept")
+	h.typeText("<<")
+	assert.Contains(h.snapshot(), "│a.dcm")
+}
+
+func TestAppSortModes(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("2")
+	assert.Equal("Sort by tag", h.statusText())
+	assert.Contains(h.snapshot(), "0008/")
+
+	h.typeText("jj")
+	assert.Contains(h.currentNodeText(), "/ [1 value]")
+	h.typeText("i")
+	assert.Contains(h.snapshot(), "(0008,0060) Modality (1 values)")
+	assert.Contains(h.snapshot(), "     2  100.0%  CT")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	h.typeText("3")
+	assert.Equal("Sort by tag, show only different tag values", h.statusText())
+	h.typeText("jl")
+	assert.Equal("0008/", h.currentNodeText())
+	h.typeText("l")
+	assert.Equal("0008/", h.currentNodeText(), "modality is the same in all files, so no children")
+
+	h.typeText("jjl")
+	lines := strings.Split(h.snapshot(), "\n")
+	assert.Contains(lines[5], "Doe^John (0) - a.dcm")
+	h.inspect(func(a *App) {
+		x := strings.Index(lines[5], "Doe^John")
+		if !assert.GreaterOrEqual(x, 0) {
+			return
+		}
+		x = len([]rune(lines[5][:x]))
+		color := func(x int) tcell.Color {
+			_, _, style, _ := h.screen.GetContent(x, 5)
+			foreground, _, _ := style.Decompose()
+			return foreground
+		}
+		assert.Equal(tcell.ColorGray, color(x+1), "common prefix dimmed")
+		assert.Equal(tcell.ColorYellow, color(x+5), "differing part highlighted")
+	})
+
+	h.typeText("5")
+	assert.Equal("Sort by module", h.statusText())
+	h.typeText("jl")
+	assert.Equal("a.dcm", h.currentNodeText())
+	assert.Contains(h.snapshot(), "Patient (1)")
+	assert.Contains(h.snapshot(), "General Series (1)")
+}
+
+func TestAppFileOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	entries[0].Filename, entries[1].Filename = "IM10", "IM9"
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("j")
+	assert.Equal("IM9", h.currentNodeText(), "natural order")
+
+	h.typeText("r")
+	assert.Equal("Files sorted by name, reversed", h.statusText())
+	h.typeText("gj")
+	assert.Equal("IM10", h.currentNodeText())
+
+	h.typeText("s")
+	assert.Equal("Files sorted by modification time, reversed", h.statusText())
+	h.typeText("ss")
+	assert.Equal("Files sorted by name, reversed", h.statusText())
+}
+
+func TestAppFileInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	entries[0].Dataset.Elements = append(entries[0].Dataset.Elements,
+		mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.4.50"}),
+		mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.7"}))
+	h := newTestHarness(t, "testdir", entries)
+	lines := strings.Split(h.snapshot(), "\n")
+	assert.Contains(lines[2], "Secondary Capture Image Storage (CT)", "summary of the parsed file")
+	assert.True(strings.HasSuffix(strings.TrimRight(lines[3], " │"), " CT"))
+
+	h.typeText("m")
+	assert.Equal("File info on", h.statusText())
+	lines = strings.Split(h.snapshot(), "\n")
+	assert.Contains(lines[2], "a.dcm")
+	assert.Contains(lines[2], "JPEG Baseline       Secondary Capture")
+	assert.Contains(lines[3], "b.dcm")
+	assert.Contains(lines[3], "-                   -")
+	assert.NotContains(lines[1], "-", "root has no file info")
+
+	h.typeText("m")
+	assert.Equal("File info off", h.statusText())
+	assert.NotContains(h.snapshot(), "JPEG Baseline")
+}
+
+func TestAppParseWarnings(t *testing.T) {
+	assert := assert.New(t)
+
+	// patient name with odd value length after an explicit VR little endian meta group
+	data := append(make([]byte, 128), "DICM"...)
+	data = append(data, 0x02, 0x00, 0x10, 0x00, 'U', 'I', 20, 0)
+	data = append(data, "1.2.840.10008.1.2.1\x00"...)
+	data = append(data, 0x10, 0x00, 0x10, 0x00, 'P', 'N', 7, 0)
+	data = append(data, "Doe^Joh"...)
+	path := filepath.Join(t.TempDir(), "odd.dcm")
+	assert.NoError(os.WriteFile(path, data, 0o644))
+
+	entries := newTestEntries(t)
+	entries[0].Path = path
+	h := newTestHarness(t, "testdir", entries)
+	lines := strings.Split(h.snapshot(), "\n")
+	assert.Contains(lines[2], "a.dcm")
+	assert.Contains(lines[2], "[! 1 warning]")
+	assert.NotContains(lines[3], "[!")
+
+	h.typeText(":warnings")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Parse warnings (1)")
+	assert.Contains(h.snapshot(), "a.dcm  (0010,0010)")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.currentNodeText(), "Doe^John [! odd value length 7]")
+}
+
+func TestAppTagStyles(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("jlllj")
+	assert.True(strings.HasPrefix(h.currentNodeText(), "\t0060 "))
+	h.typeText("t")
+	assert.Equal("Tag display: keyword and tag", h.statusText())
+	assert.Contains(h.currentNodeText(), "(0008,0060)")
+	h.typeText("t")
+	assert.Equal("Tag display: keyword only", h.statusText())
+	h.typeText("t")
+	assert.Equal("Tag display: tag and keyword", h.statusText())
+	assert.True(strings.HasPrefix(h.currentNodeText(), "\t0060 "))
+}
+
+func TestAppLineNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("#")
+	assert.Equal("Line numbers absolute", h.statusText())
+	assert.Contains(h.snapshot(), "1 testdir")
+	assert.Contains(h.snapshot(), "3 ")
+	h.typeText(":3")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("b.dcm", h.currentNodeText())
+
+	h.typeText("#")
+	assert.Equal("Line numbers relative", h.statusText())
+	assert.Contains(h.snapshot(), "2 testdir")
+	h.typeText(":-2")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("testdir", h.currentNodeText())
+	h.typeText(":+5")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("line 6 out of range, the tree shows 3 lines", h.statusText())
+
+	h.typeText("#")
+	assert.Equal("Line numbers off", h.statusText())
+	assert.NotContains(h.snapshot(), "1 testdir")
+}
+
+func TestAppLazyLoading(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []dicomtree.Entry{
+		{Filename: "a.dcm", Path: "../../testdata/test.dcm", Partial: true},
+		{Filename: "b.dcm", Path: "../../testdata/test.dcm", Partial: true},
+	}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("jl")
+	h.inspect(func(a *App) {
+		assert.False(a.entries[0].Partial, "expanded file is parsed")
+		assert.True(a.entries[1].Partial)
+	})
+
+	h.typeText("2")
+	h.inspect(func(a *App) {
+		assert.False(a.entries[1].Partial, "sort by tag parses all files")
+	})
+}
+
+func TestAppZoom(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("Z")
+	assert.Equal("the current node is already the root, :root! shows the complete tree", h.statusText())
+	h.typeText("j:root")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("root at a.dcm, :root! shows the complete tree", h.statusText())
+	screen := h.snapshot()
+	assert.Equal("│a.dcm", strings.Fields(strings.Split(screen, "\n")[1])[0])
+	assert.NotContains(screen, "b.dcm")
+	assert.Contains(screen, "node 1/3  testdir ▸ a.dcm", "breadcrumb with the path above the root")
+
+	h.typeText("lp")
+	assert.Equal("0008", h.currentNodeText())
+	assert.NotContains(h.snapshot(), "b.dcm", "zoom kept on refresh")
+	h.typeText(":root!")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("0008", h.currentNodeText())
+	assert.Contains(h.snapshot(), "b.dcm")
+	h.typeText("Z")
+	assert.Equal("root at 0008, :root! shows the complete tree", h.statusText())
+	h.typeText("Z")
+	assert.Equal("complete tree", h.statusText())
+	h.typeText(":root!")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("the tree shows all nodes", h.statusText())
+}
+
+func TestAppFoldLevels(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("z2")
+	assert.Equal("folded to 2 levels", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "0010")
+	assert.NotContains(screen, "Doe^John", "tags stay folded")
+
+	h.typeText("GzR")
+	assert.Equal("expanded all levels", h.statusText())
+	assert.Contains(h.snapshot(), "Doe^Jane")
+	assert.Equal("0010", h.currentNodeText(), "cursor stays")
+	h.typeText("G")
+	assert.Contains(h.currentNodeText(), "Doe^Jane")
+
+	h.typeText("zM")
+	assert.Equal("b.dcm", h.currentNodeText(), "cursor on the visible ancestor")
+	assert.NotContains(h.snapshot(), "0010")
+	h.typeText("zx")
+	assert.Equal("", h.statusText())
+}
+
+func TestAppSeriesKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	instance := func(filename, series, seriesNumber string) dicomtree.Entry {
+		return dicomtree.Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.PatientName, []string{"Doe^John"}),
+			mustElement(t, tag.StudyInstanceUID, []string{"1.2"}),
+			mustElement(t, tag.SeriesInstanceUID, []string{series}),
+			mustElement(t, tag.SeriesNumber, []string{seriesNumber}),
+		}}}
+	}
+	entries := []dicomtree.Entry{instance("a.dcm", "1.2.1", "1"), instance("b.dcm", "1.2.1", "1"), instance("c.dcm", "1.2.2", "2")}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("S")
+	assert.Equal("series keys work when sorted by patient, study and series (4)", h.statusText())
+
+	h.typeText("4")
+	assert.Equal("Sort by patient, study and series", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "Patient Doe^John")
+	assert.Contains(screen, "Series 1 (2 files)")
+	assert.Contains(screen, "Series 2 (1 file)")
+	assert.NotContains(screen, "a.dcm", "series start collapsed")
+
+	h.typeText("]")
+	assert.Equal("Series 1 (2 files)", h.currentNodeText())
+	h.typeText("]")
+	assert.Equal("Series 2 (1 file)", h.currentNodeText())
+	h.typeText("]")
+	assert.Equal("no further series", h.statusText())
+	h.typeText("[")
+	assert.Equal("Series 1 (2 files)", h.currentNodeText())
+
+	h.typeText("Xj")
+	assert.Equal("a.dcm", h.currentNodeText())
+	h.typeText("]X")
+	assert.Equal("Series 2 (1 file)", h.currentNodeText())
+	screen = h.snapshot()
+	assert.Contains(screen, "c.dcm")
+	assert.NotContains(screen, "a.dcm", "other series collapsed")
+
+	h.typeText("jS")
+	assert.Equal("Series 2 (1 file)", h.currentNodeText())
+	assert.Equal("collapsed 2 series", h.statusText())
+	assert.NotContains(h.snapshot(), "c.dcm")
+}
+
+func TestAppSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("/jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.currentNodeText(), "Doe^Jane")
+	assert.Contains(h.snapshot(), "Doe^Jane", "path to the found node is expanded")
+
+	h.typeText("g/doe")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.currentNodeText(), "Doe^John")
+	h.typeText("n")
+	assert.Contains(h.currentNodeText(), "Doe^Jane")
+	h.typeText("N")
+	assert.Contains(h.currentNodeText(), "Doe^John")
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) { a.SetSearch("Jane") })
+	assert.Contains(h.currentNodeText(), "Doe^Jane", "startup search")
+	assert.Equal("found 1 nodes matching 'jane'", h.statusText())
+}
+
+func TestAppSearchInBackground(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) {
+		a.searcher.delay = 0
+		a.app.SetFocus(a.cmdline)
+		a.cmdline.SetText("/jane")
+	})
+	h.waitForDraw() // drawn when the results arrive
+	assert.Contains(h.currentNodeText(), "Doe^Jane")
+	assert.Equal("found 1 nodes matching 'jane'", h.statusText())
+	h.inspect(func(a *App) {
+		assert.Equal(searchHighlightColor, a.tree.GetCurrentNode().GetColor())
+	})
+
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	h.inspect(func(a *App) {
+		assert.Empty(a.highlightedNodes)
+		assert.NotEqual(searchHighlightColor, a.tree.GetCurrentNode().GetColor())
+	})
+}
+
+func TestAppSearchHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	cmdlineText := func() string {
+		text := ""
+		h.inspect(func(a *App) { text = a.cmdline.GetText() })
+		return text
+	}
+	for _, text := range []string{"jane", "ct", "John"} {
+		h.typeText("/" + text)
+		h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	}
+	assert.Contains(h.currentNodeText(), "Doe^John")
+
+	h.typeText("/")
+	h.sendKey(tcell.KeyUp, 0, tcell.ModNone)
+	assert.Equal("/John", cmdlineText())
+	h.sendKey(tcell.KeyUp, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyUp, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyUp, 0, tcell.ModNone)
+	assert.Equal("/jane", cmdlineText(), "oldest search")
+	h.sendKey(tcell.KeyDown, 0, tcell.ModNone)
+	assert.Equal("/ct", cmdlineText())
+	h.sendKey(tcell.KeyDown, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyDown, 0, tcell.ModNone)
+	assert.Equal("/", cmdlineText(), "empty after the newest")
+	h.typeText("doe^ja")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	h.typeText("gn")
+	assert.Contains(h.currentNodeText(), "Doe^John", "n continues the confirmed search after esc")
+	h.typeText("/john")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText("g1")
+	h.inspect(func(a *App) { assert.Len(a.highlightedNodes, 1, "highlights kept on rebuild") })
+	h.typeText("n")
+	assert.Contains(h.currentNodeText(), "Doe^John", "n works after a rebuild")
+}
+
+func TestAppIncrementalSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j")
+	h.inspect(func(a *App) { a.searcher.delay = 0 })
+	h.typeText("/")
+	h.inspect(func(a *App) { a.cmdline.SetText("/doe") })
+	h.waitForDraw()
+	assert.Contains(h.currentNodeText(), "Doe^John", "first match after the start node")
+	h.inspect(func(a *App) { a.cmdline.SetText("/doe^ja") })
+	h.waitForDraw()
+	assert.Contains(h.currentNodeText(), "Doe^Jane")
+	screen := h.snapshot()
+	assert.NotContains(screen, "Doe^John", "path of the previous match collapsed again")
+	assert.Contains(screen, "Doe^Jane")
+
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	assert.Equal("a.dcm", h.currentNodeText(), "view restored on cancel")
+	assert.NotContains(h.snapshot(), "Doe^Jane")
+
+	h.typeText("/")
+	h.inspect(func(a *App) { a.cmdline.SetText("/jane") })
+	h.waitForDraw()
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText("k")
+	assert.Equal("0010", h.currentNodeText(), "view kept on enter")
+}
+
+func TestAppCommandline(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":nope")
+	assert.Contains(h.snapshot(), ":nope")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("unknown command ':nope'", h.statusText())
+	assert.NotContains(h.snapshot(), ":nope'\n", "commandline is cleared")
+
+	h.typeText(":validate")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no dataset selected for validation", h.statusText(), "root node belongs to no file")
+
+	h.typeText(":verify-roundtrip")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no dataset selected for the round-trip", h.statusText())
+
+	h.typeText(":stats")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Statistics")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "Statistics")
+
+	h.typeText("?")
+	assert.Contains(h.snapshot(), "Help")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "Help")
+}
+
+func TestAppHash(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":hash")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no file selected to hash", h.statusText())
+
+	h.typeText("j:hash")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Checksums")
+	assert.Contains(h.snapshot(), "a.dcm")
+	assert.Contains(h.snapshot(), "no pixel data")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "Checksums")
+
+	manifest := filepath.Join(t.TempDir(), "manifest.txt")
+	h.typeText(":hash manifest " + manifest)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote checksums of 2 files to "+manifest, h.statusText())
+	data, err := os.ReadFile(manifest)
+	assert.NoError(err)
+	assert.Contains(string(data), "-  -  b.dcm\n")
+}
+
+func TestAppReport(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	dir := t.TempDir()
+	h.typeText(":report diff " + filepath.Join(dir, "diff.pdf"))
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "unknown report format")
+
+	h.typeText(":report diff " + filepath.Join(dir, "diff.md"))
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote Differences of 2 files to "+filepath.Join(dir, "diff.md"), h.statusText())
+	data, err := os.ReadFile(filepath.Join(dir, "diff.md"))
+	assert.NoError(err)
+	assert.Contains(string(data), "| Tag | Name | a.dcm | b.dcm |")
+
+	h.typeText("j:report dataset " + filepath.Join(dir, "a.html"))
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	data, err = os.ReadFile(filepath.Join(dir, "a.html"))
+	assert.NoError(err)
+	assert.Contains(string(data), "<h2>a.dcm</h2>")
+}
+
+func TestAppDeidAudit(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":deid-audit")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("2 attributes of 2 files to review before release", h.statusText())
+	assert.Contains(h.snapshot(), "De-identification audit (2 attributes)")
+	assert.Contains(h.snapshot(), "identifying  (0010,0010)")
+	assert.Contains(h.snapshot(), "Doe^Jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm", h.currentNodeText(), "the file line jumps to the file")
+
+	h.typeText(":anonymize")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyRune, 'y', tcell.ModNone)
+	assert.Equal("anonymized 2 elements of 2 files, 0 attributes of 0 files to review before release", h.statusText())
+	assert.Contains(h.snapshot(), "No attributes to review")
+}
+
+func TestAppBurnedIn(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	data := make([][]int, 64*64)
+	for i := range data {
+		data[i] = []int{0}
+		if x, y := i%64, i/64; y < 8 && x < 24 && x%3 == 0 {
+			data[i] = []int{255}
+		}
+	}
+	entries[1].Dataset.Elements = append(entries[1].Dataset.Elements, mustElement(t, tag.PixelData, dicom.PixelDataInfo{
+		Frames: []frame.Frame{{NativeData: frame.NativeFrame{Data: data, Rows: 64, Cols: 64, BitsPerSample: 8}}},
+	}))
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":burned-in")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 of 1 images may contain burned-in text", h.statusText())
+	assert.Contains(h.snapshot(), "b.dcm  1 text-like regions: frame 1 at 0,0 (24x8)")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("b.dcm", h.currentNodeText())
+}
+
+func TestAppPseudonymize(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "pseudonyms.csv")
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) { a.SetPseudonymsFile(path) })
+	h.typeText(":pseudonymize")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyRune, 'y', tcell.ModNone)
+	assert.Equal("pseudonymized 2 files, 2 new patients in "+path+", save with :w", h.statusText())
+	h.inspect(func(a *App) {
+		assert.Equal("PSEUDO^000002", dicomtree.UIDValue(&a.entries[1].Dataset, tag.PatientName))
+	})
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Contains(string(data), ",Doe^Jane,PSEUDO-000002,PSEUDO^000002\n")
+
+	h.typeText(":pseudonyms")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Pseudonyms (2)")
+	assert.Contains(h.snapshot(), "PSEUDO-000001  PSEUDO^000001    <-  Doe^John")
+}
+
+func TestAppBatchPreview(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":anonymize")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(":anonymize would make 8 changes in 2 files, y applies them, n cancels", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "Preview of :anonymize")
+	assert.Contains(screen, "(0010,0010) PatientName: 'Doe^Jane' -> 'ANONYMIZED'")
+	h.sendKey(tcell.KeyRune, 'n', tcell.ModNone)
+	assert.Equal("cancelled :anonymize, no file changed", h.statusText())
+	h.inspect(func(a *App) {
+		assert.Equal("Doe^Jane", dicomtree.UIDValue(&a.entries[1].Dataset, tag.PatientName))
+		assert.Empty(a.changes.Changes)
+	})
+
+	h.inspect(func(a *App) { a.SetAssumeYes(true) })
+	h.typeText(":anonymize")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "anonymized 2 elements of 2 files")
+}
+
+func TestAppWriteInPlace(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	entries := newTestEntries(t)[:1]
+	entries[0].Path = filepath.Join(dir, "a.dcm")
+	assert.NoError(dicomtree.WriteFile(entries[0].Dataset, entries[0].Path))
+	original, err := os.ReadFile(entries[0].Path)
+	assert.NoError(err)
+	h := newTestHarness(t, dir, entries)
+	h.typeText(":restore")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "error restoring a.dcm: no backup of a.dcm")
+
+	h.inspect(func(a *App) { edit.Delete(&a.entries[0].Dataset, tag.PatientName) })
+	h.typeText(":w!")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote a.dcm, previous version in .dcmtagger-backup, undo with :restore", h.statusText())
+	backups, err := backup.List(entries[0].Path)
+	assert.NoError(err)
+	assert.Len(backups, 1)
+
+	h.typeText(":restore")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("restored a.dcm from "+filepath.Base(backups[0]), h.statusText())
+	data, err := os.ReadFile(entries[0].Path)
+	assert.NoError(err)
+	assert.Equal(original, data)
+	h.inspect(func(a *App) { assert.False(a.entries[0].Partial, "reloaded") })
+}
+
+func TestAppOutputDir(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	entries := newTestEntries(t)
+	for i := range entries {
+		entries[i].Path = filepath.Join(dir, "in", entries[i].Filename)
+	}
+	entries[1].Filename = "sub/b.dcm"
+	out := filepath.Join(dir, "out")
+	h := newTestHarness(t, "in", entries)
+	h.typeText(":set outdir=" + out)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("outdir="+out, h.statusText())
+	h.inspect(func(a *App) { a.SetAssumeYes(true) })
+	h.typeText(":anonymize")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":wa")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote 2 edited files to "+out, h.statusText())
+	assert.FileExists(filepath.Join(out, "a.dcm"))
+	assert.FileExists(filepath.Join(out, "sub", "b.dcm"))
+	assert.NoDirExists(filepath.Join(dir, "in"), "sources are not touched")
+
+	scriptFile := filepath.Join(dir, "save.star")
+	require.NoError(t, os.WriteFile(scriptFile, []byte("datasets[0].set('0010,0010', 'Roe^Jim')\ndatasets[0].save()\n"), 0o644))
+	h.typeText(":source " + scriptFile)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	written, err := dicom.ParseFile(filepath.Join(out, "a.dcm"), nil)
+	if assert.NoError(err) {
+		assert.Equal("Roe^Jim", dicomtree.UIDValue(&written, tag.PatientName), "save() of scripts writes to the directory")
+	}
+	h.typeText(":w")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(":w writes a single file, use :w! for the current file or :wa for all edited files", h.statusText())
+	assert.NoDirExists(filepath.Join(dir, "in"), "sources are not touched")
+
+	h.typeText(":set outdir=" + filepath.Join(dir, "in"))
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":wa")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "is the input file")
+
+	h = newTestHarness(t, "in", entries[:1])
+	h.typeText(":set outdir=" + out)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":w")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote a.dcm to "+filepath.Join(out, "a.dcm"), h.statusText())
+	assert.NoDirExists(filepath.Join(dir, "in"), "sources are not touched")
+}
+
+func TestAppProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) {
+		p := a.startProgress("loading", 3)
+		p.start = time.Now().Add(-time.Second)
+		assert.NoError(p.step())
+		cells, width, _ := h.screen.GetContents()
+		var screen strings.Builder
+		for i, cell := range cells {
+			if i%width == 0 {
+				screen.WriteString("\n")
+			}
+			screen.WriteString(string(cell.Runes))
+		}
+		assert.Contains(screen.String(), "1 of 3 files")
+		assert.Contains(screen.String(), "escape cancels")
+
+		h.screen.InjectKey(tcell.KeyEsc, 0, tcell.ModNone) // handled by the screen while the event loop is busy
+		for start := time.Now(); !p.cancelled.Load() && time.Since(start) < harnessTimeout; {
+			time.Sleep(time.Millisecond)
+		}
+		assert.ErrorIs(p.step(), errCancelled)
+		assert.EqualError(p.finish(), "loading cancelled after 2 of 3 files")
+		assert.False(a.pages.HasPage("progress"))
+	})
+	assert.Contains(h.currentNodeText(), "testdir", "escape did not reach the tree")
+}
+
+func TestAppWorklist(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":worklist")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "no worklist configured")
+
+	var query dimse.WorklistQuery
+	h.inspect(func(a *App) {
+		a.SetConfig(&config.Config{Worklist: &dimse.Node{Host: "localhost", Port: 104, AET: "MWL"}})
+		a.queryWorklist = func(node dimse.Node, q dimse.WorklistQuery) ([]dicom.Dataset, error) {
+			query = q
+			step := []*dicom.Element{
+				mustElement(t, tag.Modality, []string{"MR"}),
+				mustElement(t, tag.ScheduledProcedureStepStartDate, []string{"20240102"}),
+			}
+			return []dicom.Dataset{{Elements: []*dicom.Element{
+				mustElement(t, tag.AccessionNumber, []string{"A123"}),
+				mustElement(t, tag.PatientName, []string{"Smith^Max"}),
+				mustElement(t, tag.PatientID, []string{"4711"}),
+				mustElement(t, tag.ScheduledProcedureStepSequence, [][]*dicom.Element{step}),
+			}}}, nil
+		}
+	})
+	h.typeText(":worklist mod=MR")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("unknown filter 'mod', use node, name, id, date, modality or aet", h.statusText())
+
+	h.typeText("j:worklist name=Smith* modality=mr")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(dimse.WorklistQuery{PatientName: "Smith*", Modality: "MR"}, query)
+	assert.Equal("1 worklist entries at MWL@localhost:104", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "Worklist of MWL@localhost:104")
+	assert.Contains(screen, "c: copy to a.dcm")
+	assert.Contains(screen, "1 20240102 Smith^Max MR")
+
+	h.typeText("c")
+	assert.Equal("copied 3 elements of worklist entry 1 20240102 Smith^Max MR into a.dcm, save with :w", h.statusText())
+	h.inspect(func(a *App) {
+		e, err := a.entries[0].Dataset.FindElementByTag(tag.PatientName)
+		assert.NoError(err)
+		assert.Equal([]string{"Smith^Max"}, e.Value.GetValue())
+		assert.Len(a.changes.Changes, 3)
+	})
+	assert.NotContains(h.snapshot(), "Worklist of")
+}
+
+func TestAppNodes(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) { a.SetConfig(&config.Config{}) })
+	h.typeText(":nodes add web " + server.URL)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("added node web "+server.URL, h.statusText())
+	h.typeText(fmt.Sprintf(":nodes add pacs PACS@127.0.0.1:%d echo find", closedPort))
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(fmt.Sprintf("added node pacs PACS@127.0.0.1:%d", closedPort), h.statusText())
+	h.typeText(":nodes add other OTHER@127.0.0.1:104 teleport")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "unknown capability 'teleport'")
+
+	h.typeText(":nodes echo web")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "web ok in ")
+	h.typeText(":nodes echo")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "pacs failed: ")
+
+	h.typeText(":nodes")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen := h.snapshot()
+	assert.Contains(screen, "Nodes (2)")
+	assert.Contains(screen, "pacs         PACS@127.0.0.1")
+	assert.Contains(screen, "not tested")
+	h.typeText("e")
+	h.waitForDraw() // of the result
+	assert.Contains(h.snapshot(), "failed: ")
+	h.typeText("d")
+	assert.Equal("deleted node pacs", h.statusText())
+	assert.Contains(h.snapshot(), "Nodes (1)")
+
+	cfg, err := config.Load()
+	assert.NoError(err)
+	assert.Equal(map[string]dimse.Node{"web": {URL: server.URL}}, cfg.Nodes)
+}
+
+func TestAppViews(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) { a.SetConfig(&config.Config{}) })
+	h.typeText(":view")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no views saved, save the current one with :view save <name>", h.statusText())
+	h.typeText(":filter 0010,0010~jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":table 0010,0010")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	h.typeText(":view save jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("saved view jane: sort mode 1, filter '0010,0010~jane', 1 columns", h.statusText())
+
+	h.typeText(":filter")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":view jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("view jane: 1 of 2 files", h.statusText())
+	assert.Contains(h.snapshot(), "Table of 1 files")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "a.dcm")
+
+	h.typeText(":view delete jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("deleted view jane", h.statusText())
+	h.typeText(":view jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("unknown view 'jane'", h.statusText())
+
+	cfg, err := config.Load()
+	assert.NoError(err)
+	assert.Empty(cfg.Views)
+}
+
+func TestAppTrace(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Cleanup(func() { dimse.Trace = nil })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) {
+		a.SetConfig(&config.Config{Nodes: map[string]dimse.Node{"pacs": {Host: "127.0.0.1", Port: closedPort, AET: "PACS"}}})
+	})
+	h.typeText(":trace")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no network trace, start it with :trace on", h.statusText())
+
+	h.typeText(":trace on")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":nodes echo pacs")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":trace")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen := h.snapshot()
+	assert.Contains(screen, "Network trace (2 events)")
+	assert.Contains(screen, fmt.Sprintf("PACS@127.0.0.1:%d connecting", closedPort))
+	assert.Contains(screen, "connection failed")
+
+	h.typeText("q:trace off")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("network trace stopped", h.statusText())
+	h.typeText(":nodes echo pacs")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":trace")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Network trace (2 events)")
+}
+
+func TestAppComparePACS(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:compare-pacs")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no nodes configured, add them with :nodes add", h.statusText())
+
+	var requested dimse.Instance
+	h.inspect(func(a *App) {
+		a.SetConfig(&config.Config{Nodes: map[string]dimse.Node{
+			"pacs": {Host: "localhost", Port: 104, AET: "PACS"},
+			"web":  {URL: "http://localhost/dicomweb"},
+			"mwl":  {Host: "localhost", Port: 105, AET: "MWL", Capabilities: []string{"worklist"}},
+		}})
+		a.retrieve = func(node dimse.Node, instance dimse.Instance) (dicom.Dataset, error) {
+			requested = instance
+			return dicom.Dataset{Elements: []*dicom.Element{
+				mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.4.90"}),
+				mustElement(t, tag.StudyInstanceUID, []string{"1.2"}),
+				mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"}),
+				mustElement(t, tag.SOPInstanceUID, []string{"1.2.3.4"}),
+				mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+				mustElement(t, tag.Modality, []string{"CT"}),
+				mustElement(t, tag.PatientName, []string{"DOE^JOHN"}),
+			}}, nil
+		}
+	})
+	h.typeText(":compare-pacs")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("several nodes retrieve instances, use :compare-pacs pacs|web", h.statusText())
+	h.typeText(":compare-pacs mwl")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("node 'mwl' retrieves neither with C-GET nor DICOMweb", h.statusText())
+	h.typeText(":compare-pacs pacs")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm: missing StudyInstanceUID, SeriesInstanceUID, SOPInstanceUID, SOPClassUID to retrieve the instance", h.statusText())
+
+	h.inspect(func(a *App) {
+		entry := &a.entries[0]
+		entry.Dataset.Elements = append(entry.Dataset.Elements,
+			mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+			mustElement(t, tag.StudyInstanceUID, []string{"1.2"}),
+			mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"}),
+			mustElement(t, tag.SOPInstanceUID, []string{"1.2.3.4"}),
+			mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}))
+	})
+	h.typeText(":compare-pacs pacs")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(dimse.Instance{StudyInstanceUID: "1.2", SeriesInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3.4",
+		SOPClassUID: "1.2.840.10008.5.1.4.1.1.2", TransferSyntaxUID: "1.2.840.10008.1.2.1"}, requested)
+	assert.Equal("a.dcm differs from the copy at PACS@localhost:104 in 2 elements, archived as JPEG 2000 Lossless instead of Explicit VR LE", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "archive copy at PACS@localhost:104 (2 findings)")
+	assert.Contains(screen, "changed value from 'Doe^John' to 'DOE^JOHN'")
+}
+
+func TestAppForkStudy(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	for i, sop := range []string{"1.3.1", "1.3.2"} {
+		for _, e := range []struct {
+			tag tag.Tag
+			uid string
+		}{{tag.SOPInstanceUID, sop}, {tag.StudyInstanceUID, "1.1"}, {tag.SeriesInstanceUID, "1.2"}} {
+			value, err := dicom.NewValue([]string{e.uid})
+			assert.NoError(err)
+			entries[i].Dataset.Elements = append(entries[i].Dataset.Elements, &dicom.Element{Tag: e.tag, RawValueRepresentation: "UI", Value: value})
+		}
+	}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":fork-study")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(":fork-study would make 6 changes in 2 files, y applies them, n cancels", h.statusText())
+	h.sendKey(tcell.KeyRune, 'y', tcell.ModNone)
+	assert.Equal("assigned 4 new UIDs in 6 elements of 2 files, save with :w", h.statusText())
+	h.inspect(func(a *App) {
+		study := dicomtree.UIDValue(&a.entries[0].Dataset, tag.StudyInstanceUID)
+		assert.NotEqual("1.1", study)
+		assert.Equal(study, dicomtree.UIDValue(&a.entries[1].Dataset, tag.StudyInstanceUID))
+		assert.NotEqual("1.3.1", dicomtree.UIDValue(&a.entries[0].Dataset, tag.SOPInstanceUID))
+		assert.Len(a.changes.Changes, 6)
+	})
+
+	h.inspect(func(a *App) { a.protected = edit.NewProtectedTags([]tag.Tag{tag.SeriesInstanceUID}) })
+	h.typeText(":fork-study")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "protected")
+}
+
+func TestAppTimezone(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	for _, e := range []struct {
+		tag   tag.Tag
+		vr    string
+		value string
+	}{{tag.StudyDate, "DA", "20240102"}, {tag.StudyTime, "TM", "0130"}, {tag.TimezoneOffsetFromUTC, "SH", "+0200"}} {
+		value, err := dicom.NewValue([]string{e.value})
+		assert.NoError(err)
+		entries[0].Dataset.Elements = append(entries[0].Dataset.Elements, &dicom.Element{Tag: e.tag, RawValueRepresentation: e.vr, Value: value})
+	}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":timezone")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :timezone <&HHMM>|utc, e.g. :timezone +0100", h.statusText())
+	h.typeText(":timezone CET")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("invalid timezone offset 'CET', use &HHMM", h.statusText())
+
+	h.typeText(":timezone utc")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(":timezone would make 3 changes in 1 files, y applies them, n cancels", h.statusText())
+	h.sendKey(tcell.KeyRune, 'y', tcell.ModNone)
+	assert.Equal("converted 3 elements of 2 files to +0000, 1 files without TimezoneOffsetFromUTC keep their dates and "+
+		"times without offset, save with :w", h.statusText())
+	h.inspect(func(a *App) {
+		assert.Equal("20240101", dicomtree.ItemValue(a.entries[0].Dataset.Elements, tag.StudyDate))
+		assert.Equal("2330", dicomtree.ItemValue(a.entries[0].Dataset.Elements, tag.StudyTime))
+		assert.Equal("+0000", dicomtree.ItemValue(a.entries[0].Dataset.Elements, tag.TimezoneOffsetFromUTC))
+	})
+}
+
+func TestAppHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) {
+		a.SetHooks(map[string][]string{
+			hook.Load: {`echo '{"00080080": {"vr": "LO", "Value": ["General Hospital"]}}'`},
+			hook.Save: {"echo 'missing PatientID' >&2; exit 1"},
+		})
+		assert.Equal("General Hospital", dicomtree.ItemValue(a.entries[1].Dataset.Elements, tag.InstitutionName))
+		assert.Len(a.changes.Changes, 2)
+	})
+	h.typeText("j:w!")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "error writing a.dcm: save hook 'echo 'missing PatientID' >&2; exit 1' failed for a.dcm: exit status 1: missing PatientID")
+
+	dir := t.TempDir()
+	h = newTestHarness(t, "testdir", newTestEntries(t)[:1])
+	h.inspect(func(a *App) {
+		a.SetHooks(map[string][]string{hook.Save: {"exit 1"}})
+		a.SetOutputDir(dir)
+		a.SetAssumeYes(true)
+	})
+	h.typeText(":w")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("error writing a.dcm: save hook 'exit 1' failed for a.dcm: exit status 1", h.statusText())
+	assert.NoFileExists(filepath.Join(dir, "a.dcm"), "the hook aborts :w")
+
+	scriptFile := filepath.Join(dir, "save.star")
+	require.NoError(t, os.WriteFile(scriptFile, []byte("datasets[0].save('"+filepath.Join(dir, "copy.dcm")+"')\n"), 0o644))
+	h.typeText(":source " + scriptFile)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "save hook 'exit 1' failed for a.dcm")
+	assert.NoFileExists(filepath.Join(dir, "copy.dcm"), "the hook aborts save() of scripts")
+}
+
+func TestAppTools(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("jllJll:! echo {file} {tag}")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.waitForDraw() // of the output
+	assert.Equal("ran echo 'testdir/a.dcm' 0010,0010", h.statusText())
+	assert.Contains(h.snapshot(), "Output of echo 'testdir/a.dcm' 0010,0010")
+	assert.Contains(h.snapshot(), "testdir/a.dcm 0010,0010")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	h.typeText(":open")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "no tools configured")
+	h.inspect(func(a *App) {
+		a.SetConfig(&config.Config{Tools: map[string]string{"dump": "echo dump {file}", "fail": "echo broken >&2; exit 4"}})
+	})
+	h.typeText("H:open")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Open with")
+	assert.Contains(h.snapshot(), "dump             echo dump {file}")
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.waitForDraw()
+	assert.Equal("echo broken >&2; exit 4 failed: exit status 4", h.statusText())
+	assert.Contains(h.snapshot(), "broken")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	h.typeText("gj:open dump")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.waitForDraw()
+	assert.Equal("ran echo dump 'testdir/a.dcm'", h.statusText())
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	h.typeText(":! sleep 0.2; echo late")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("running sleep 0.2; echo late", h.statusText(), "the UI is not blocked by the command")
+	h.waitForDraw()
+	assert.Equal("ran sleep 0.2; echo late", h.statusText())
+	assert.Contains(h.snapshot(), "late")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	h.typeText(":! cat {tag}")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no element selected for {tag}", h.statusText())
+}
+
+func TestAppPropagate(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := append(newTestEntries(t), dicomtree.Entry{Filename: "c.dcm", Path: "testdir/c.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientName, []string{"Roe^Richard"}),
+	}}})
+	for i, series := range []string{"1.2.1", "1.2.1", "1.2.2"} {
+		entries[i].Dataset.Elements = append(entries[i].Dataset.Elements, mustElement(t, tag.SeriesInstanceUID, []string{series}))
+	}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("jllJll:propagate")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "(0010,0010): pick the value")
+	screen := h.snapshot()
+	assert.Contains(screen, "'Doe^John' (current file)")
+	assert.Contains(screen, "'Roe^Richard'")
+	assert.Contains(screen, "type a value...")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen = h.snapshot()
+	assert.Contains(screen, "series of a.dcm (2 files)")
+	assert.Contains(screen, "study of a.dcm (1 files)", "without study UID only the current file")
+	assert.Contains(screen, "all files (3 files)")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(":propagate would make 1 changes in 1 files, y applies them, n cancels", h.statusText())
+	h.sendKey(tcell.KeyRune, 'y', tcell.ModNone)
+	assert.Contains(h.statusText(), "(0010,0010) to 'Doe^John' in 1 of 2 files of the series of a.dcm, save with :w")
+	h.inspect(func(a *App) {
+		assert.Equal("Doe^John", dicomtree.ItemValue(a.entries[1].Dataset.Elements, tag.PatientName))
+		assert.Equal("Roe^Richard", dicomtree.ItemValue(a.entries[2].Dataset.Elements, tag.PatientName))
+	})
+
+	h.typeText(":propagate (0010,0010)")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText("jj")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText("Smith^Ann")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText("jj")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(":propagate would make 3 changes in 3 files, y applies them, n cancels", h.statusText())
+	h.sendKey(tcell.KeyRune, 'n', tcell.ModNone)
+	assert.Equal("cancelled :propagate, no file changed", h.statusText())
+
+	h.typeText("gj:propagate")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :propagate <tag> or select a top level element", h.statusText())
+}
+
+func TestAppCoerce(t *testing.T) {
+	assert := assert.New(t)
+
+	rules := filepath.Join(t.TempDir(), "site.json")
+	assert.NoError(os.WriteFile(rules, []byte(`{"rules": [{"if": "(0010,0010)=Doe^Jane", "prefix": {"(0010,0010)": "A-"}}]}`), 0o644))
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":coerce " + rules)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("the rules of "+rules+" would change 1 of 2 files on ingestion, the files are unchanged", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "Coercion by")
+	assert.Contains(screen, "'Doe^Jane' -> 'A-Doe^Jane'")
+	h.inspect(func(a *App) {
+		assert.Equal("Doe^Jane", dicomtree.UIDValue(&a.entries[1].Dataset, tag.PatientName))
+		assert.Empty(a.changes.Changes)
+	})
+
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	h.typeText(":coerce")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :coerce <rules.json>", h.statusText())
+}
+
+func TestAppTable(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":table 0010,0010,(0008,0060)")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("2 files, s sorts by the selected column", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "Table of 2 files")
+	assert.Less(strings.Index(screen, "Doe^John"), strings.Index(screen, "Doe^Jane"))
+
+	h.sendKey(tcell.KeyRune, 's', tcell.ModNone)
+	screen = h.snapshot()
+	assert.Less(strings.Index(screen, "Doe^Jane"), strings.Index(screen, "Doe^John"), "sorted by name")
+	h.sendKey(tcell.KeyRune, 's', tcell.ModNone)
+	screen = h.snapshot()
+	assert.Less(strings.Index(screen, "Doe^John"), strings.Index(screen, "Doe^Jane"), "reversed")
+
+	h.sendKey(tcell.KeyDown, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("b.dcm", h.currentNodeText())
+
+	h.typeText(":table NoSuchTag")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("unknown tag 'NoSuchTag'", h.statusText())
+}
+
+func TestAppNotes(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":note check")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("notes need a sidecar file, start with --notes <file.json>", h.statusText())
+
+	sidecar := filepath.Join(t.TempDir(), "notes.json")
+	h.inspect(func(a *App) { a.SetNotesFile(sidecar) })
+	h.sendKey(tcell.KeyDown, 0, tcell.ModNone)
+	h.typeText(":note wrong patient")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("noted a.dcm", h.statusText())
+	assert.Contains(h.snapshot(), "[note]")
+	h.typeText(":note")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm: wrong patient", h.statusText())
+
+	h.inspect(func(a *App) {
+		n, err := notes.Load(sidecar)
+		assert.NoError(err)
+		assert.Len(n.All, 1)
+		assert.Equal("wrong patient", n.All[0].Text)
+	})
+	h.sendKey(tcell.KeyDown, 0, tcell.ModNone)
+	h.typeText(":notes")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Notes (1)")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm", h.currentNodeText())
+
+	export := filepath.Join(t.TempDir(), "notes.csv")
+	h.typeText(":notes export " + export)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("exported 1 notes to "+export, h.statusText())
+	data, err := os.ReadFile(export)
+	assert.NoError(err)
+	assert.Contains(string(data), "a.dcm,,,wrong patient")
+
+	h.typeText(":note!")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("removed the note on a.dcm", h.statusText())
+	assert.NotContains(h.snapshot(), "[note]")
+	_, err = os.Stat(sidecar)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestAppVRAudit(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	date := mustElement(t, tag.StudyDate, []string{"2024-01-31"})
+	date.RawValueRepresentation = "DA"
+	entries[0].Dataset.Elements = append(entries[0].Dataset.Elements, date)
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":vr-audit")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 values in 1 of 2 files violate their VR, fix the fixable ones with :vr-audit fix", h.statusText())
+	assert.Contains(h.snapshot(), "DA value '2024-01-31' has separators")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.currentNodeText(), "2024-01-31")
+
+	h.typeText(":vr-audit fix")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(":vr-audit fix would make 1 changes in 1 files, y applies them, n cancels", h.statusText())
+	h.sendKey(tcell.KeyRune, 'y', tcell.ModNone)
+	assert.Equal("fixed the values of 1 elements in 1 files, save with :w", h.statusText())
+	h.inspect(func(a *App) { assert.Equal([]string{"20240131"}, date.Value.GetValue()) })
+
+	h.typeText(":vr-audit all")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :vr-audit [fix]", h.statusText())
+}
+
+func TestAppUnknownVR(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":un")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no elements of 2 files with VR UN for tags of the dictionary", h.statusText())
+
+	h.typeText(":un fix")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("set the dictionary VR of 0 elements with VR UN in 0 files, save with :w", h.statusText())
+
+	h.typeText(":un all")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :un [fix]", h.statusText())
+}
+
+func TestAppPins(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.inspect(func(a *App) { a.SetConfig(&config.Config{}) })
+	h.typeText(":pin")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :pin <tag>[,<tag>...] or :pin on an element", h.statusText())
+
+	h.typeText("jllJll")
+	h.typeText(":pin")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 elements pinned: PatientName", h.statusText())
+	h.typeText(":pin 0008,0060 0010,0010")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("2 elements pinned: PatientName, Modality", h.statusText())
+	assert.Contains(h.snapshot(), "a.dcm  PatientName Doe^John │ Modality CT")
+	h.inspect(func(a *App) { assert.Equal([]string{"(0010,0010)", "(0008,0060)"}, a.config.Pins) })
+
+	h.typeText("hhj")
+	assert.Contains(h.snapshot(), "b.dcm  PatientName Doe^Jane", "the bar follows the cursor")
+	h.typeText(":unpin 0008,0060")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 elements pinned: PatientName", h.statusText())
+	assert.NotContains(h.snapshot(), "Modality CT")
+	h.typeText(":unpin")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no elements pinned", h.statusText())
+	assert.NotContains(h.snapshot(), "Doe^Jane │")
+	h.typeText("k")
+	assert.NotEqual("b.dcm", h.currentNodeText(), "the tree keeps the focus")
+}
+
+func TestAppTrend(t *testing.T) {
+	assert := assert.New(t)
+
+	slice := func(filename string, instance string, location float64) dicomtree.Entry {
+		return dicomtree.Entry{Filename: filename, Path: "testdir/" + filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.InstanceNumber, []string{instance}),
+			mustElement(t, tag.SliceLocation, []float64{location}),
+		}}}
+	}
+	h := newTestHarness(t, "testdir", []dicomtree.Entry{slice("a.dcm", "1", 0), slice("b.dcm", "2", 5), slice("c.dcm", "4", 2.5),
+		slice("d.dcm", "5", 10)})
+	h.typeText("jllll")
+	h.typeText(":trend")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("trends are shown when sorted by tag (2, 3)", h.statusText())
+
+	h.typeText("2gjjj")
+	h.typeText(":trend")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("4 files, 1 missing instances, 1 values not monotonic", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "(0020,1041) SliceLocation over InstanceNumber")
+	assert.Contains(screen, "4 files, instances 1..5, values 0..10")
+	assert.Contains(screen, "Missing instances: 3")
+	assert.Contains(screen, "instance 4: 2.5 after 5  c.dcm")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "Missing instances")
+}
+
+func TestAppWriteOptions(t *testing.T) {
+	h := newTestHarness(t, "a.dcm", newTestEntries(t)[:1])
+	h.typeText(":w nope")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(t, "unknown write option 'nope', use :w [clean|clean-empty|explicit-vr]", h.statusText())
+
+	h.typeText(":set outdir=testdir")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":w clean")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal(t, "error writing a.dcm: "+filepath.Join("testdir", "a.dcm")+" is the input file, choose another output directory", h.statusText())
+	assert.NoFileExists(t, "write_test_copy.dcm")
+}
+
+func TestAppFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":filter 0010,0010~jane")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("filter matches 1 of 2 files", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "b.dcm")
+	assert.NotContains(screen, "a.dcm")
+
+	h.typeText(":filter 0010,0010=")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "invalid filter")
+
+	h.typeText(":filter")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("filter cleared", h.statusText())
+	assert.Contains(h.snapshot(), "a.dcm")
+}
+
+func TestAppChanges(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	scriptFile := filepath.Join(dir, "edit.star")
+	assert.NoError(os.WriteFile(scriptFile, []byte(`datasets[1].set("0010,0010", "Roe^Jim")`), 0o644))
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":source " + scriptFile)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.sendKey(tcell.KeyRune, 'y', tcell.ModNone)
+	h.typeText(":changes")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen := h.snapshot()
+	assert.Contains(screen, "Changes (1)")
+	assert.Contains(screen, "b.dcm  (0010,0010)")
+	assert.Contains(screen, "'Doe^Jane' -> 'Roe^Jim'")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	csvFile := filepath.Join(dir, "changes.csv")
+	h.typeText(":changes export " + csvFile)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("exported 1 changes to "+csvFile, h.statusText())
+	data, err := os.ReadFile(csvFile)
+	assert.NoError(err)
+	assert.Contains(string(data), ",Doe^Jane,Roe^Jim\n")
+
+	h.typeText(":changes export changes.txt")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "use :changes export")
+}
+
+func TestAppProtectedTags(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("jllJll")
+	assert.Contains(h.currentNodeText(), "Doe^John")
+	h.sendKey(tcell.KeyCtrlSpace, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "Override protection")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	h.typeText(":protect 0010,0010")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("(0010,0010) is protected", h.statusText())
+	h.sendKey(tcell.KeyCtrlSpace, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Override protection")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	h.typeText(":unprotect 0010,0010")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	h.typeText(":protect")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.NotContains(h.statusText(), "(0010,0010)")
+	assert.Contains(h.statusText(), "(0002,0010)")
+}
+
+func TestAppImport(t *testing.T) {
+	assert := assert.New(t)
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":import")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("missing file, use :import <file> [tags]", h.statusText())
+
+	h.typeText("j:import ../../testdata/test.dcm 0002,0010")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "(0002,0010)")
+	assert.Contains(h.statusText(), "tag is protected")
+
+	h.typeText(":import ../../testdata/test.dcm study 0010,0010")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "of 8 elements from ../../testdata/test.dcm into a.dcm")
+}
+
+func TestAppExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	out := filepath.Join(t.TempDir(), "sample.dcm")
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:extract " + out)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("select an element or group, or use :extract <file> header|found|<tags>", h.statusText())
+	h.typeText(":extract " + out + " header")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote 2 of 2 elements of a.dcm to "+out, h.statusText())
+	extracted, err := dicom.ParseFile(out, nil)
+	if assert.NoError(err, "written with the default transfer syntax") {
+		_, err = extracted.FindElementByTag(tag.PatientName)
+		assert.NoError(err)
+	}
+
+	h.inspect(func(a *App) { jumpToElementNode(a.tree, a.entries[0].Dataset.Elements[1]) })
+	h.typeText(":extract " + out)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote 1 of 2 elements of a.dcm to "+out, h.statusText())
+	h.inspect(func(a *App) { a.tree.SetCurrentNode(a.tree.fullParent(a.tree.GetCurrentNode())) })
+	h.typeText(":extract " + out)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote 1 of 2 elements of a.dcm to "+out, h.statusText(), "the elements of the group")
+
+	h.typeText(":extract " + out + " found")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no element of a.dcm matches the last search", h.statusText())
+}
+
+func TestAppStripPixels(t *testing.T) {
+	assert := assert.New(t)
+
+	out := t.TempDir()
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText(":strip-pixels " + out + " blank")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :strip-pixels <dir> [zero]", h.statusText())
+	h.typeText(":strip-pixels " + out)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("wrote 2 files without pixel data to "+out, h.statusText())
+	written, err := dicom.ParseFile(filepath.Join(out, "b.dcm"), nil)
+	if assert.NoError(err) {
+		_, err = written.FindElementByTag(tag.PixelData)
+		assert.Error(err)
+	}
+}
+
+func TestAppPixelData(t *testing.T) {
+	assert := assert.New(t)
+
+	rawFile := filepath.Join(t.TempDir(), "image.raw")
+	assert.NoError(os.WriteFile(rawFile, make([]byte, 6), 0o644))
+
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:pixeldata " + rawFile)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.statusText(), "missing size of RAW image")
+
+	h.typeText(":pixeldata " + rawFile + " 3x2")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("replaced pixel data of a.dcm by 3x2 image "+rawFile, h.statusText())
+	h.inspect(func(a *App) {
+		e, err := a.entries[0].Dataset.FindElementByTag(tag.Rows)
+		assert.NoError(err)
+		assert.Equal([]int{2}, e.Value.GetValue())
+	})
+}
+
+func TestAppDuplicates(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	newEntry := func(filename, uid, modality string, age time.Duration) dicomtree.Entry {
+		path := filepath.Join(dir, filename)
+		assert.NoError(os.WriteFile(path, nil, 0o644))
+		assert.NoError(os.Chtimes(path, time.Now().Add(-age), time.Now().Add(-age)))
+		return dicomtree.Entry{Filename: filename, Path: path, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.Modality, []string{modality}),
+			mustElement(t, tag.SOPInstanceUID, []string{uid}),
+		}}}
+	}
+	entries := []dicomtree.Entry{
+		newEntry("a.dcm", "1.2.3", "CT", time.Hour),
+		newEntry("b.dcm", "1.2.3", "MR", time.Minute),
+		newEntry("c.dcm", "1.2.4", "CT", time.Hour),
+		newEntry("d.dcm", "1.2.4", "CT", time.Hour),
+	}
+	h := newTestHarness(t, dir, entries)
+	h.typeText(":duplicates")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen := h.snapshot()
+	assert.Contains(screen, "Duplicate SOP Instances (2)")
+	assert.Contains(screen, "1.2.3: a.dcm, b.dcm")
+	assert.Contains(screen, "differs in (0008,0060)")
+	assert.Contains(screen, "identical top level values")
+
+	h.typeText("n")
+	assert.Contains(h.snapshot(), "Keep b.dcm and delete a.", "b.dcm is newer")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("kept b.dcm, deleted 1 duplicates", h.statusText())
+	assert.NoFileExists(filepath.Join(dir, "a.dcm"))
+	assert.NotContains(h.snapshot(), "a.dcm")
+
+	h.typeText(":duplicates")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen = h.snapshot()
+	assert.Contains(screen, "Duplicate SOP Instances (1)", "the list is open for the remaining group")
+	assert.Contains(screen, "1.2.4: c.dcm, d.dcm")
+	h.typeText("u")
+	assert.Contains(h.snapshot(), "UIDs to d.dcm and")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("assigned new SOP instance UIDs to 1 files", h.statusText())
+	written, err := dicom.ParseFile(filepath.Join(dir, "d.dcm"), nil)
+	if assert.NoError(err) {
+		assert.NotEqual("1.2.4", dicomtree.UIDValue(&written, tag.SOPInstanceUID), "the file is overwritten with the new UID")
+		assert.NotEmpty(dicomtree.UIDValue(&written, tag.SOPInstanceUID))
+	}
+	h.inspect(func(a *App) {
+		assert.Len(a.entries, 3)
+		assert.Empty(dicomtree.FindDuplicates(a.entries))
+		assert.Len(a.changes.Changes, 1)
+	})
+}
+
+func TestAppMissingTags(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	entries = append(entries, dicomtree.Entry{Filename: "c.dcm", Path: "testdir/c.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.PatientName, []string{"Doe^Jim"}),
+	}}})
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("2:missing")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 tags are missing in some of 3 files", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "(0008,0060) Modality - present in 2 of 3 files, missing in:")
+	assert.Contains(screen, "    c.dcm")
+
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("c.dcm", h.currentNodeText())
+	assert.Equal("Sort by filename", h.statusText())
+}
+
+func TestAppSequenceItems(t *testing.T) {
+	assert := assert.New(t)
+
+	sequence := mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{
+		{mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"})},
+	})
+	entries := []dicomtree.Entry{
+		{Filename: "a.dcm", Path: "testdir/a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{sequence}}},
+	}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("jlllll")
+	assert.Equal("\tItem 1", h.currentNodeText())
+
+	h.typeText(":item dup")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("duplicated item 1 of (0008,1115)", h.statusText())
+	assert.Equal("\tItem 2", h.currentNodeText())
+
+	h.typeText(":item set 0020,000e 1.2.4")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("set (0020,000e) in item 2 of (0008,1115)", h.statusText())
+	assert.Equal("{(0020,000e)=1.2.3}{(0020,000e)=1.2.4}", dicomtree.ValueText(sequence, nil))
+
+	h.typeText(":item delete")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("\tItem 1", h.currentNodeText())
+	assert.Equal("{(0020,000e)=1.2.3}", dicomtree.ValueText(sequence, nil))
+	h.inspect(func(a *App) {
+		assert.Len(a.changes.Changes, 3)
+	})
+
+	h.typeText("g:item add")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("no sequence selected", h.statusText())
+}
+
+func TestAppStructureSet(t *testing.T) {
+	assert := assert.New(t)
+
+	roi := func(number, name string) []*dicom.Element {
+		return []*dicom.Element{mustElement(t, tag.ROINumber, []string{number}), mustElement(t, tag.ROIName, []string{name})}
+	}
+	entries := []dicomtree.Entry{{Filename: "rs.dcm", Path: "testdir/rs.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{rt.StructureSetSOPClassUID}),
+		mustElement(t, tag.StructureSetROISequence, [][]*dicom.Element{roi("1", "Body"), roi("2", "PTV")}),
+	}}}}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":rois")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("2 ROIs in rs.dcm referencing 0 series", h.statusText())
+	assert.Contains(h.snapshot(), "ROIs of rs.dcm (2)")
+	assert.Contains(h.snapshot(), "2  PTV")
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("\tItem 2", h.currentNodeText())
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:rois")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm is no RT structure set", h.statusText())
+}
+
+func TestAppRTSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	beams := mustElement(t, tag.BeamSequence, [][]*dicom.Element{
+		{mustElement(t, tag.BeamNumber, []string{"1"}), mustElement(t, tag.BeamName, []string{"AP"})},
+	})
+	entries := []dicomtree.Entry{{Filename: "rp.dcm", Path: "testdir/rp.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{rt.PlanSOPClassUID}),
+		mustElement(t, tag.RTPlanLabel, []string{"Prostate"}),
+		beams,
+	}}}}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":rt")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("RT plan rp.dcm", h.statusText())
+	assert.Contains(h.snapshot(), "Plan Prostate, prescribed dose - Gy")
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("\tItem 1", h.currentNodeText(), "beam line jumps to the beam item")
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:rt")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm is no RT plan, dose or structure set", h.statusText())
+}
+
+func TestAppDose(t *testing.T) {
+	assert := assert.New(t)
+
+	code := func(tg tag.Tag, value, meaning string) *dicom.Element {
+		return mustElement(t, tg, [][]*dicom.Element{{
+			mustElement(t, tag.CodeValue, []string{value}),
+			mustElement(t, tag.CodeMeaning, []string{meaning}),
+		}})
+	}
+	ctdi := []*dicom.Element{
+		mustElement(t, tag.ValueType, []string{"NUM"}),
+		code(tag.ConceptNameCodeSequence, "113830", "Mean CTDIvol"),
+		mustElement(t, tag.MeasuredValueSequence, [][]*dicom.Element{{
+			code(tag.MeasurementUnitsCodeSequence, "mGy", "mGy"),
+			mustElement(t, tag.NumericValue, []string{"10.2"}),
+		}}),
+	}
+	entries := []dicomtree.Entry{{Filename: "sr.dcm", Path: "testdir/sr.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{rdsr.SOPClassUID}),
+		mustElement(t, tag.ContentSequence, [][]*dicom.Element{{
+			mustElement(t, tag.ValueType, []string{"CONTAINER"}),
+			code(tag.ConceptNameCodeSequence, "113819", "CT Acquisition"),
+			mustElement(t, tag.ContentSequence, [][]*dicom.Element{ctdi}),
+		}}),
+	}}}}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":dose")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 irradiation events in sr.dcm", h.statusText())
+	assert.Contains(h.snapshot(), "Event  Protocol  Type  Target  CTDIvol [mGy]")
+	assert.Contains(h.snapshot(), "1                              10.2")
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("\tItem 1", h.currentNodeText(), "event jumps to its content item")
+
+	csvFile := filepath.Join(t.TempDir(), "dose.csv")
+	h.typeText(":dose export " + csvFile)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("exported 1 irradiation events of sr.dcm to "+csvFile, h.statusText())
+	data, err := os.ReadFile(csvFile)
+	assert.NoError(err)
+	assert.Equal("Event,Protocol,Type,Target,CTDIvol [mGy]\n1,,,,10.2\n", string(data))
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:dose")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm is no radiation dose SR", h.statusText())
+}
+
+func TestAppWaveform(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []dicomtree.Entry{{Filename: "ecg.dcm", Path: "testdir/ecg.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.WaveformSequence, [][]*dicom.Element{{
+			mustElement(t, tag.NumberOfWaveformChannels, []int{1}),
+			mustElement(t, tag.NumberOfWaveformSamples, []int{4}),
+			mustElement(t, tag.SamplingFrequency, []string{"2"}),
+			mustElement(t, tag.ChannelDefinitionSequence, [][]*dicom.Element{{mustElement(t, tag.ChannelLabel, []string{"Lead I"})}}),
+			mustElement(t, tag.WaveformBitsAllocated, []int{8}),
+			mustElement(t, tag.WaveformSampleInterpretation, []string{"SB"}),
+			mustElement(t, tag.WaveformData, []byte{0, 4, 0xfc, 0}),
+		}}),
+	}}}}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":waveform")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 multiplex groups in ecg.dcm", h.statusText())
+	assert.Contains(h.snapshot(), "Waveform of ecg.dcm, 1 samples per dot")
+	assert.Contains(h.snapshot(), "Group 1: 2 Hz, 1 channels")
+	assert.Contains(h.snapshot(), "Lead I  -4..4")
+	h.typeText("-")
+	assert.Contains(h.snapshot(), "Waveform of ecg.dcm, 2 samples per dot")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	path := filepath.Join(t.TempDir(), "ecg.csv")
+	h.typeText(":waveform export " + path)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("exported waveform of ecg.dcm to "+path, h.statusText())
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal("time,Lead I\n0,0\n0.5,4\n1,-4\n1.5,0\n", string(data))
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:waveform")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("error decoding waveform of a.dcm: no waveform sequence", h.statusText())
+}
+
+func TestAppOverlays(t *testing.T) {
+	assert := assert.New(t)
+
+	overlayElement := func(element uint16, data interface{}) *dicom.Element {
+		e, err := overlay.NewElement(tag.Tag{Group: 0x6000, Element: element}, data)
+		require.NoError(t, err)
+		return e
+	}
+	entries := []dicomtree.Entry{{Filename: "cr.dcm", Path: "testdir/cr.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		overlayElement(0x0010, []int{2}),
+		overlayElement(0x0011, []int{4}),
+		overlayElement(0x0040, []string{"G"}),
+		overlayElement(0x0050, []int{1, 1}),
+		overlayElement(0x0100, []int{1}),
+		overlayElement(0x1500, []string{"marker"}),
+		overlayElement(0x3000, []byte{0x0f}), // first row set
+	}}}}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":overlays")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 overlays in cr.dcm", h.statusText())
+	assert.Contains(h.snapshot(), "Overlays of cr.dcm (1)")
+	assert.Contains(h.snapshot(), "6000     4x2     origin 1,1  type G     1 frames        4 pixels  marker")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Overlay 6000, 4x2")
+	assert.Contains(h.snapshot(), "⠉⠉")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Overlays of cr.dcm (1)")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+
+	path := filepath.Join(t.TempDir(), "overlay.png")
+	h.typeText(":overlays export " + path)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("exported 1 overlays of cr.dcm to "+path, h.statusText())
+	assert.FileExists(path)
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:overlays export x.png")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm has no overlays", h.statusText())
+}
+
+func TestAppImage(t *testing.T) {
+	assert := assert.New(t)
+
+	pixelData := dicom.PixelDataInfo{Frames: []frame.Frame{
+		{NativeData: frame.NativeFrame{Data: [][]int{{255, 0, 0}, {0, 0, 255}}, Rows: 1, Cols: 2, BitsPerSample: 8}},
+	}}
+	entries := []dicomtree.Entry{{Filename: "vl.dcm", Path: "testdir/vl.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SamplesPerPixel, []int{3}),
+		mustElement(t, tag.PhotometricInterpretation, []string{"RGB"}),
+		mustElement(t, tag.PlanarConfiguration, []int{1}),
+		mustElement(t, tag.Rows, []int{1}),
+		mustElement(t, tag.Columns, []int{2}),
+		mustElement(t, tag.ICCProfile, []byte("profile")),
+		mustElement(t, tag.PixelData, pixelData),
+	}}}}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":image")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("vl.dcm: 2x1 RGB, color-by-plane, ICC profile of 7 bytes", h.statusText())
+
+	path := filepath.Join(t.TempDir(), "frame.png")
+	h.typeText(":image export " + path)
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("exported frame 1 of vl.dcm to "+path+" with its ICC profile", h.statusText())
+	assert.FileExists(path)
+
+	h.typeText(":image export " + path + " 2")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("error exporting frame 2 of vl.dcm: frame 2 out of range, 1 frames", h.statusText())
+	h.typeText(":image export")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("use :image or :image export <file.png> [<frame>]", h.statusText())
+}
+
+func TestAppSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	pixelData := func(frames ...[][]int) dicom.PixelDataInfo {
+		info := dicom.PixelDataInfo{}
+		for _, data := range frames {
+			info.Frames = append(info.Frames, frame.Frame{NativeData: frame.NativeFrame{Data: data, Rows: 2, Cols: 2, BitsPerSample: 8}})
+		}
+		return info
+	}
+	perFrame := []*dicom.Element{
+		mustElement(t, tag.DerivationImageSequence, [][]*dicom.Element{{
+			mustElement(t, tag.SourceImageSequence, [][]*dicom.Element{{mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3"})}}),
+		}}),
+		mustElement(t, tag.SegmentIdentificationSequence, [][]*dicom.Element{{mustElement(t, tag.ReferencedSegmentNumber, []int{1})}}),
+	}
+	entries := []dicomtree.Entry{
+		{Filename: "img.dcm", Path: "testdir/img.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPInstanceUID, []string{"1.2.3"}),
+			mustElement(t, tag.PixelData, pixelData([][]int{{0}, {200}, {100}, {100}})),
+		}}},
+		{Filename: "seg.dcm", Path: "testdir/seg.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPClassUID, []string{seg.SOPClassUID}),
+			mustElement(t, tag.SegmentSequence, [][]*dicom.Element{
+				{mustElement(t, tag.SegmentNumber, []int{1}), mustElement(t, tag.SegmentLabel, []string{"Liver"})},
+				{mustElement(t, tag.SegmentNumber, []int{2}), mustElement(t, tag.SegmentLabel, []string{"Tumor"})},
+			}),
+			mustElement(t, tag.PerFrameFunctionalGroupsSequence, [][]*dicom.Element{perFrame, perFrame}),
+			mustElement(t, tag.PixelData, pixelData([][]int{{1}, {0}, {0}, {1}}, [][]int{{0}, {0}, {0}, {0}})),
+		}}},
+	}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("jj:segments")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("2 segments in seg.dcm", h.statusText())
+	assert.Contains(h.snapshot(), "Segments of seg.dcm (2)")
+	assert.Contains(h.snapshot(), "Liver")
+	assert.Contains(h.snapshot(), "Tumor")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Segment 1 Liver, frame 1 of 2 on img.dcm")
+	assert.Contains(h.snapshot(), ":*")
+	h.typeText("n")
+	assert.Contains(h.snapshot(), "Segment 1 Liver, frame 2 of 2 on img.dcm")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "Segments of seg.dcm")
+	assert.Equal("\tItem 2", h.currentNodeText())
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:segments")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm is no segmentation", h.statusText())
+}
+
+func TestAppReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	source := mustElement(t, tag.SourceImageSequence, [][]*dicom.Element{
+		{mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+		{mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.9"})},
+	})
+	entries := []dicomtree.Entry{
+		{Filename: "a.dcm", Path: "testdir/a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPInstanceUID, []string{"1.2.3"}),
+		}}},
+		{Filename: "b.dcm", Path: "testdir/b.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPInstanceUID, []string{"1.2.4"}),
+			source,
+		}}},
+	}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText("f")
+	assert.Equal("no reference to another object at the cursor", h.statusText())
+	h.inspect(func(a *App) { jumpToElementNode(a.tree, source) })
+	h.typeText("f")
+	assert.Equal("followed referenced instance 1.2.3 to a.dcm", h.statusText())
+	assert.Contains(h.currentNodeText(), "1.2.3")
+	h.inspect(func(a *App) {
+		assert.Equal("a.dcm", findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries).Filename)
+	})
+
+	h.typeText(":references")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 of 2 references point to objects that are not loaded", h.statusText())
+	assert.Contains(h.snapshot(), "b.dcm  (0008,1155) -> instance 1.2.9")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.currentNodeText(), "1.2.9")
+	h.typeText("f")
+	assert.Equal("referenced instance 1.2.9 is not loaded", h.statusText())
+}
+
+func TestAppGeometry(t *testing.T) {
+	assert := assert.New(t)
+
+	slice := func(filename string, z string) dicomtree.Entry {
+		position := mustElement(t, tag.ImagePositionPatient, []string{"0", "0", z})
+		position.RawValueRepresentation = "DS"
+		orientation := mustElement(t, tag.ImageOrientationPatient, []string{"1", "0", "0", "0", "1", "0"})
+		orientation.RawValueRepresentation = "DS"
+		return dicomtree.Entry{Filename: filename, Path: "testdir/" + filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SeriesInstanceUID, []string{"1.2"}),
+			mustElement(t, tag.SeriesNumber, []string{"3"}),
+			position,
+			orientation,
+		}}}
+	}
+	h := newTestHarness(t, "testdir", []dicomtree.Entry{slice("a.dcm", "0"), slice("b.dcm", "1"), slice("c.dcm", "2"), slice("d.dcm", "5")})
+	h.typeText("2:geometry")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 of 1 series have an irregular geometry", h.statusText())
+	assert.Contains(h.snapshot(), "Series 3: 4 slices, spacing 1 mm (1..3), extent 0 x 0 x 5 mm, axial")
+	assert.Contains(h.snapshot(), "d.dcm: gap of 3 mm after c.dcm, about 2 missing slices")
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("d.dcm", h.currentNodeText())
+	h.inspect(func(a *App) { assert.Equal(1, a.sortMode, "sorted by file to show the file node") })
+}
+
+func TestAppSlices(t *testing.T) {
+	assert := assert.New(t)
+
+	slice := func(filename string, instance string, location string) dicomtree.Entry {
+		sliceLocation := mustElement(t, tag.SliceLocation, []string{location})
+		sliceLocation.RawValueRepresentation = "DS"
+		return dicomtree.Entry{Filename: filename, Path: "testdir/" + filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SeriesInstanceUID, []string{"1.2"}),
+			mustElement(t, tag.SeriesNumber, []string{"3"}),
+			mustElement(t, tag.InstanceNumber, []string{instance}),
+			sliceLocation,
+		}}}
+	}
+	h := newTestHarness(t, "testdir", []dicomtree.Entry{slice("a.dcm", "1", "0"), slice("b.dcm", "2", "1"), slice("c.dcm", "4", "2"),
+		slice("d.dcm", "5", "2")})
+	h.typeText("2:slices")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 of 1 series have suspect instances", h.statusText())
+	assert.Contains(h.snapshot(), "Series 3: 4 files, instances 1..5, 2 suspect instances")
+	assert.Contains(h.snapshot(), "c.dcm: instance number 3 missing before")
+	assert.Contains(h.snapshot(), "d.dcm: same slice location as c.dcm")
+	h.typeText("jj")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("d.dcm", h.currentNodeText())
+}
+
+func TestAppTimeSync(t *testing.T) {
+	assert := assert.New(t)
+
+	file := func(filename, station, acquired string) dicomtree.Entry {
+		return dicomtree.Entry{Filename: filename, Path: "testdir/" + filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.StudyInstanceUID, []string{"1.2"}),
+			mustElement(t, tag.StationName, []string{station}),
+			mustElement(t, tag.AcquisitionDateTime, []string{acquired}),
+		}}}
+	}
+	h := newTestHarness(t, "testdir", []dicomtree.Entry{file("ct.dcm", "CT1", "20240102100000"), file("pet.dcm", "PET1", "20240102101000"),
+		file("us.dcm", "US1", "20240102100200")})
+	h.typeText(":time-sync x")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("invalid threshold 'x', use e.g. 90s or 10m", h.statusText())
+	h.typeText(":time-sync")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("1 of 3 devices start more than 5m0s after the first device of their study", h.statusText())
+	screen := h.snapshot()
+	assert.Contains(screen, "Study 1.2")
+	assert.Contains(screen, "    US1: 1 files, 2024-01-02 10:02:00 .. 2024-01-02 10:02:00, +2m0s")
+	assert.Contains(screen, "[!] PET1: 1 files, 2024-01-02 10:10:00 .. 2024-01-02 10:10:00, +10m0s")
+	h.typeText("jjj")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("pet.dcm", h.currentNodeText())
+
+	h.typeText(":time-sync 1m")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("2 of 3 devices start more than 1m0s after the first device of their study", h.statusText())
+}
+
+func TestAppFrames(t *testing.T) {
+	assert := assert.New(t)
+
+	frame := func(position int) []*dicom.Element {
+		return []*dicom.Element{mustElement(t, tag.FrameContentSequence, [][]*dicom.Element{{
+			mustElement(t, tag.InStackPositionNumber, []int{position}),
+		}})}
+	}
+	entries := []dicomtree.Entry{{Filename: "ct.dcm", Path: "testdir/ct.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SharedFunctionalGroupsSequence, [][]*dicom.Element{{
+			mustElement(t, tag.PixelMeasuresSequence, [][]*dicom.Element{{mustElement(t, tag.SliceThickness, []string{"1.5"})}}),
+		}}),
+		mustElement(t, tag.PerFrameFunctionalGroupsSequence, [][]*dicom.Element{frame(7), frame(8)}),
+	}}}}
+	h := newTestHarness(t, "testdir", entries)
+	h.typeText(":frames")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("2 frames in ct.dcm", h.statusText())
+	assert.Contains(h.snapshot(), "Functional groups of ct.dcm (2 frames)")
+	assert.Contains(h.snapshot(), "Frame│InStackPositionNumber│SliceThickness")
+	assert.Contains(h.snapshot(), "    2│8                    │1.5")
+	h.typeText("j")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.NotContains(h.snapshot(), "Functional groups")
+	assert.Contains(h.currentNodeText(), "8")
+
+	h.typeText(":frames 0028,1050")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Contains(h.snapshot(), "Frame│WindowCenter")
+	h.sendKey(tcell.KeyEsc, 0, tcell.ModNone)
+	h.typeText(":frames Nonsense")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("unknown tag 'Nonsense'", h.statusText())
+
+	h = newTestHarness(t, "testdir", newTestEntries(t))
+	h.typeText("j:frames")
+	h.sendKey(tcell.KeyEnter, 0, tcell.ModNone)
+	assert.Equal("a.dcm has no per-frame functional groups", h.statusText())
+}
+
+func TestAppQuit(t *testing.T) {
+	h := newTestHarness(t, "testdir", newTestEntries(t))
+	assert.NoError(t, h.quit())
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
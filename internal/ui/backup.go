@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/drcynic/dcmtagger/pkg/backup"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+)
+
+// writes the dataset of the entry to the output directory if set, else overwrites the file keeping the previous
+// version in the backup directory, after the save hooks succeeded. Returns the written path.
+func (a *App) writeEntry(entry *dicomtree.Entry) (string, error) {
+	before := edit.TakeSnapshot(&entry.Dataset)
+	path, err := a.writer().Write(entry)
+	a.changes.Record(entry.Filename, before, &entry.Dataset)
+	return path, err
+}
+
+// writes the dataset of the entry for save() of scripts, see writeEntry, to the path of the script if it is another one
+// after the save hooks succeeded
+func (a *App) saveScriptEntry(entry *dicomtree.Entry, path string) (string, error) {
+	before := edit.TakeSnapshot(&entry.Dataset)
+	path, err := a.writer().Save(entry, path)
+	a.changes.Record(entry.Filename, before, &entry.Dataset)
+	return path, err
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// returns where written files end up for the status line
+func (a *App) writeTarget() string {
+	if a.outputDir == "" {
+		return "in place, previous versions in " + backup.DirName
+	}
+	return "to " + a.outputDir
+}
+
+// handles ':w!' writing the current file to the output directory or in place, see writeEntry
+func (a *App) writeInPlace() {
+	entry := a.currentRTEntry()
+	if entry == nil {
+		return
+	}
+	path, err := a.writeEntry(entry)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error writing %s: %s", entry.Filename, err.Error()))
+		return
+	}
+	a.statusLine.SetText(a.writtenText(entry, path))
+}
+
+// returns the status text of the entry written to the path by writeEntry
+func (a *App) writtenText(entry *dicomtree.Entry, path string) string {
+	if a.outputDir != "" {
+		return fmt.Sprintf("wrote %s to %s", entry.Filename, path)
+	}
+	return fmt.Sprintf("wrote %s, previous version in %s, undo with :restore", entry.Filename, backup.DirName)
+}
+
+// handles ':wa' writing all files edited in the session to the output directory or in place, see writeEntry
+func (a *App) writeChanged() {
+	edited := make(map[string]bool)
+	for _, c := range a.changes.Changes {
+		edited[c.File] = true
+	}
+	var entries []*dicomtree.Entry
+	for i := range a.entries {
+		if edited[a.entries[i].Filename] && !a.entries[i].Partial {
+			entries = append(entries, &a.entries[i])
+		}
+	}
+	written := 0
+	p := a.startProgress("writing", len(entries))
+	for _, entry := range entries {
+		if _, err := a.writeEntry(entry); err != nil {
+			p.finish()
+			a.statusLine.SetText(fmt.Sprintf("error writing %s after %d files: %s", entry.Filename, written, err.Error()))
+			return
+		}
+		written++
+		if p.step() != nil {
+			break
+		}
+	}
+	if err := p.finish(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("wrote %d edited files %s", written, a.writeTarget()))
+}
+
+// handles ':restore' replacing the current file by its newest backup and reloading it
+func (a *App) restoreBackup() {
+	entry := a.currentRTEntry()
+	if entry == nil {
+		return
+	}
+	restored, err := backup.Restore(entry.Path)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error restoring %s: %s", entry.Filename, err.Error()))
+		return
+	}
+	before := edit.TakeSnapshot(&entry.Dataset)
+	entry.Partial, entry.Offsets, entry.Warnings = true, nil, nil
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	a.changes.Record(entry.Filename, before, &entry.Dataset)
+	jumpToFileNode(a.tree, entry.Filename)
+	a.statusLine.SetText(fmt.Sprintf("restored %s from %s", entry.Filename, filepath.Base(restored)))
+}
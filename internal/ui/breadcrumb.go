@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+const breadcrumbSeparator = " ▸ "
+
+// returns the position of the current node among the visible nodes and the labels of the nodes from the root to it,
+// e.g. "node 4/12  testdir ▸ a.dcm ▸ 0010 ▸ (0010,0010) PatientName"
+func breadcrumbText(tree *treeModel) string {
+	node := tree.GetCurrentNode()
+	if node == nil {
+		return ""
+	}
+	tree.invalidate() // expansion may have changed without notice
+	var labels []string
+	for n := node; n != nil; n = tree.fullParent(n) {
+		labels = append([]string{breadcrumbLabel(n, tree.fullParent(n))}, labels...)
+	}
+	position, _ := tree.visiblePosition(node)
+	return fmt.Sprintf("node %d/%d  %s", position+1, len(tree.visibleNodes()), strings.Join(labels, breadcrumbSeparator))
+}
+
+// returns the tag of element nodes without value, the filename of the file nodes below a tag when sorted by tag
+func breadcrumbLabel(node, parent *tview.TreeNode) string {
+	e, ok := node.GetReference().(*dicom.Element)
+	if !ok {
+		return strings.TrimSuffix(strings.TrimSpace(node.GetText()), "/")
+	}
+	if parent != nil {
+		if parentElement, ok := parent.GetReference().(*dicom.Element); ok && parentElement.Tag == e.Tag {
+			text := node.GetText()
+			return text[strings.LastIndex(text, "\t - ")+len("\t - "):]
+		}
+	}
+	return strings.TrimSpace(dicomtree.FormatTag(e.Tag) + " " + dicomtree.TagName(e))
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/burnedin"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// image of the review pane of :burned-in with the reason it is listed
+type burnedInFile struct {
+	entry  *dicomtree.Entry
+	reason string
+}
+
+// scans the pixel data of all visible files for text-like regions near the edges and lists the files that likely
+// contain burned-in text or are flagged by BurnedInAnnotation, selecting one jumps to its file
+func (a *App) showBurnedIn() {
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	var files []burnedInFile
+	images := 0
+	for i := range a.entries {
+		entry := &a.entries[i]
+		if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+			continue
+		}
+		regions, err := burnedin.Detect(&entry.Dataset)
+		if errors.Is(err, burnedin.ErrNoPixelData) {
+			continue
+		}
+		images++
+		var reasons []string
+		if flag := dicomtree.UIDValue(&entry.Dataset, tag.BurnedInAnnotation); flag == "YES" {
+			reasons = append(reasons, "BurnedInAnnotation YES")
+		}
+		if len(regions) > 0 {
+			texts := make([]string, 0, len(regions))
+			for _, r := range regions {
+				texts = append(texts, r.String())
+			}
+			reasons = append(reasons, fmt.Sprintf("%d text-like regions: %s", len(regions), strings.Join(texts, ", ")))
+		}
+		if err != nil {
+			reasons = append(reasons, "not scanned, "+err.Error())
+		}
+		if len(reasons) > 0 {
+			files = append(files, burnedInFile{entry, strings.Join(reasons, "; ")})
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d of %d images may contain burned-in text", len(files), images))
+	addAndShowBurnedInPage(a.pages, files, func(file burnedInFile) {
+		a.sortByFileIfByTag()
+		jumpToFileNode(a.tree, file.entry.Filename)
+		a.app.SetFocus(a.tree)
+	})
+}
+
+func addAndShowBurnedInPage(pages *tview.Pages, files []burnedInFile, onSelect func(file burnedInFile)) {
+	viewName := "burned-in"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Possibly burned-in text (%d files)", len(files))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(files) == 0 {
+		list.AddItem("No images with text-like regions near the edges", "", 0, nil)
+	}
+	for _, file := range files {
+		file := file
+		list.AddItem(tview.Escape(file.entry.Filename+"  "+file.reason), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(file)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// returns the text of the changes page, one line per change
+func changesText(changes []edit.Change) string {
+	if len(changes) == 0 {
+		return "No changes"
+	}
+	var text strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&text, "%s  %s  %s %s: '%s' -> '%s'\n", c.Time.Format(time.TimeOnly), c.File, c.Tag, c.Name, c.OldValue, c.NewValue)
+	}
+	return text.String()
+}
+
+func addAndShowChangesPage(pages *tview.Pages, changes []edit.Change) {
+	viewName := "changes"
+	changesView := tview.NewTextView().SetText(changesText(changes))
+	changesView.
+		SetTitle(fmt.Sprintf("Changes (%d)", len(changes))).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	changesView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(changesView, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
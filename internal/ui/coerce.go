@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/coerce"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// handles ':coerce <rules.json>' simulating the attribute coercion of a PACS on copies of the visible files and
+// showing the values before and after, the files themselves stay unchanged
+func (a *App) simulateCoercion(args []string) {
+	if len(args) != 1 {
+		a.statusLine.SetText("use :coerce <rules.json>")
+		return
+	}
+	rules, err := coerce.Load(args[0])
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	changes, err := a.previewChanges(func(dataset *dicom.Dataset) error {
+		_, err := rules.Apply(dataset)
+		return err
+	})
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	files := len(a.visibleEntries())
+	if len(changes) == 0 {
+		a.statusLine.SetText(fmt.Sprintf("the rules of %s change none of the %d files", rules.Name, files))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("the rules of %s would change %d of %d files on ingestion, the files are unchanged",
+		rules.Name, changedFiles(changes), files))
+	title := fmt.Sprintf("Coercion by %s (%d changes in %d files)", rules.Name, len(changes), changedFiles(changes))
+	addAndShowCoercionPage(a.pages, title, previewText(changes))
+}
+
+func addAndShowCoercionPage(pages *tview.Pages, title, text string) {
+	viewName := "coercion"
+	view := tview.NewTextView().SetText(text)
+	view.
+		SetTitle(title).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(view, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,742 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/filter"
+	"github.com/drcynic/dcmtagger/pkg/script"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// line number commands like :37, :+5 or :-5
+var lineCommand = regexp.MustCompile(`^:[+-]?[0-9]+$`)
+
+// executes a ':' command of the commandline, except for :q which is handled directly
+func (a *App) executeCommand(cmdlineText string) {
+	tree, statusLine := a.tree, a.statusLine
+
+	if cmdlineText == ":w" || strings.HasPrefix(cmdlineText, ":w ") {
+		if len(a.entries) == 1 {
+			if err := a.loadEntry(&a.entries[0]); err != nil {
+				statusLine.SetText(err.Error())
+				return
+			}
+			cleanupText := ""
+			switch option := strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":w")); option {
+			case "":
+			case "clean", "clean-empty":
+				entry := &a.entries[0]
+				before := edit.TakeSnapshot(&entry.Dataset)
+				changed, err := edit.Cleanup(&entry.Dataset, edit.CleanupOptions{RemoveEmpty: option == "clean-empty"})
+				a.changes.Record(entry.Filename, before, &entry.Dataset)
+				a.refreshTree()
+				if err != nil {
+					statusLine.SetText(fmt.Sprintf("error cleaning up %s: %s", entry.Filename, err.Error()))
+					return
+				}
+				cleanupText = fmt.Sprintf(", %d elements cleaned up", changed)
+			case "explicit-vr":
+				entry := &a.entries[0]
+				before := edit.TakeSnapshot(&entry.Dataset)
+				changed, err := setUnknownVRs(entry)
+				a.changes.Record(entry.Filename, before, &entry.Dataset)
+				a.refreshTree()
+				if err != nil {
+					statusLine.SetText(err.Error())
+					return
+				}
+				cleanupText = fmt.Sprintf(", %d elements with VR UN written with their dictionary VR", changed)
+			default:
+				statusLine.SetText(fmt.Sprintf("unknown write option '%s', use :w [clean|clean-empty|explicit-vr]", option))
+				return
+			}
+			entry := &a.entries[0]
+			path, err := a.writeEntry(entry)
+			if err != nil {
+				statusLine.SetText(fmt.Sprintf("error writing %s: %s", entry.Filename, err.Error()))
+				return
+			}
+			statusLine.SetText(a.writtenText(entry, path) + cleanupText)
+		} else {
+			statusLine.SetText(":w writes a single file, use :w! for the current file or :wa for all edited files")
+		}
+	} else if cmdlineText == ":w!" {
+		a.writeInPlace()
+	} else if cmdlineText == ":wa" {
+		a.writeChanged()
+	} else if cmdlineText == ":restore" {
+		a.restoreBackup()
+	} else if cmdlineText == ":validate" {
+		entry := findEntryForNode(tree, tree.GetCurrentNode(), a.entries)
+		if entry == nil {
+			statusLine.SetText("no dataset selected for validation")
+			return
+		}
+		if err := a.loadEntry(entry); err != nil {
+			statusLine.SetText(err.Error())
+			return
+		}
+		iodName, findings := validate.Dataset(&entry.Dataset)
+		statusLine.SetText(fmt.Sprintf("validated %s against %s: %d findings", entry.Filename, iodName, len(findings)))
+		addAndShowValidationPage(a.pages, iodName, findings, func(finding validate.Finding) {
+			if finding.Element == nil || !jumpToElementNode(tree, finding.Element) {
+				statusLine.SetText(finding.Message)
+			}
+			a.app.SetFocus(tree)
+		})
+	} else if cmdlineText == ":verify-roundtrip" {
+		entry := findEntryForNode(tree, tree.GetCurrentNode(), a.entries)
+		if entry == nil {
+			statusLine.SetText("no dataset selected for the round-trip")
+			return
+		}
+		if err := a.loadEntry(entry); err != nil {
+			statusLine.SetText(err.Error())
+			return
+		}
+		findings, err := validate.RoundTrip(&entry.Dataset)
+		if err != nil {
+			statusLine.SetText(fmt.Sprintf("round-trip of %s failed: %s", entry.Filename, err.Error()))
+			return
+		}
+		statusLine.SetText(fmt.Sprintf("round-trip of %s: %d elements changed, dropped or added by writing", entry.Filename, len(findings)))
+		addAndShowValidationPage(a.pages, "write round-trip", findings, func(finding validate.Finding) {
+			if finding.Element == nil || !jumpToElementNode(tree, finding.Element) {
+				statusLine.SetText(finding.Message)
+			}
+			a.app.SetFocus(tree)
+		})
+	} else if cmdlineText == ":un" || strings.HasPrefix(cmdlineText, ":un ") {
+		a.unknownVRCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":un")))
+	} else if cmdlineText == ":vr-audit" || strings.HasPrefix(cmdlineText, ":vr-audit ") {
+		a.auditValues(strings.Fields(strings.TrimPrefix(cmdlineText, ":vr-audit")))
+	} else if strings.HasPrefix(cmdlineText, ":convert-charset") {
+		target := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":convert-charset")))
+		if target != "utf8" && target != "utf-8" {
+			statusLine.SetText(fmt.Sprintf("unsupported target character set '%s', only utf8 is supported", target))
+			return
+		}
+		entry := findEntryForNode(tree, tree.GetCurrentNode(), a.entries)
+		if entry == nil {
+			statusLine.SetText("no dataset selected for conversion")
+			return
+		}
+		if err := a.loadEntry(entry); err != nil {
+			statusLine.SetText(err.Error())
+			return
+		}
+		before := edit.TakeSnapshot(&entry.Dataset)
+		converted, err := edit.ConvertToUTF8(&entry.Dataset)
+		a.changes.Record(entry.Filename, before, &entry.Dataset)
+		if err != nil {
+			statusLine.SetText(fmt.Sprintf("error converting %s to UTF-8: %s", entry.Filename, err.Error()))
+			return
+		}
+		statusLine.SetText(fmt.Sprintf("converted %d values of %s to UTF-8, save with :w", converted, entry.Filename))
+	} else if strings.HasPrefix(cmdlineText, ":goto-offset") {
+		offset, err := dicomtree.ParseOffset(strings.TrimPrefix(cmdlineText, ":goto-offset"))
+		if err != nil {
+			statusLine.SetText("invalid offset, use decimal or 0x prefixed hex value")
+			return
+		}
+		entry := findEntryForNode(tree, tree.GetCurrentNode(), a.entries)
+		if entry == nil {
+			statusLine.SetText("no dataset selected for offset lookup")
+			return
+		}
+		if err := a.loadEntry(entry); err != nil {
+			statusLine.SetText(err.Error())
+			return
+		}
+		if err := entry.EnsureOffsets(); err != nil {
+			statusLine.SetText(fmt.Sprintf("error scanning %s: %s", entry.Filename, err.Error()))
+		}
+		element := entry.FindElementAtOffset(offset)
+		if element == nil || !jumpToElementNode(tree, element) {
+			statusLine.SetText(fmt.Sprintf("no element found at offset %d (0x%x) in %s", offset, offset, entry.Filename))
+			return
+		}
+		statusLine.SetText(fmt.Sprintf("element %s contains offset %d (0x%x)", dicomtree.FormatTag(element.Tag), offset, offset))
+	} else if strings.HasPrefix(cmdlineText, ":source") {
+		scriptFile := strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":source"))
+		if scriptFile == "" {
+			statusLine.SetText("missing script file, use :source <file>")
+			return
+		}
+		if err := a.loadAllEntries(); err != nil {
+			statusLine.SetText(err.Error())
+			return
+		}
+		preview := func() ([]edit.Change, error) {
+			changes, err := script.Preview(scriptFile, a.entries)
+			if err != nil {
+				return nil, fmt.Errorf("error running %s: %s", scriptFile, strings.ReplaceAll(err.Error(), "\n", " "))
+			}
+			return changes, nil
+		}
+		a.confirmBatch(":source", preview, func() { a.runScript(scriptFile) })
+	} else if cmdlineText == ":changes" {
+		addAndShowChangesPage(a.pages, a.changes.Changes)
+	} else if strings.HasPrefix(cmdlineText, ":changes export") {
+		a.exportChanges(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":changes export")))
+	} else if strings.HasPrefix(cmdlineText, ":protect") || strings.HasPrefix(cmdlineText, ":unprotect") {
+		a.changeProtection(cmdlineText)
+	} else if cmdlineText == ":trace" || strings.HasPrefix(cmdlineText, ":trace ") {
+		a.traceCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":trace")))
+	} else if cmdlineText == ":note" || cmdlineText == ":note!" || strings.HasPrefix(cmdlineText, ":note ") {
+		command, text, _ := strings.Cut(cmdlineText, " ")
+		a.noteCommand(command, strings.TrimSpace(text))
+	} else if cmdlineText == ":notes" || strings.HasPrefix(cmdlineText, ":notes ") {
+		a.notesCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":notes")))
+	} else if cmdlineText == ":view" || strings.HasPrefix(cmdlineText, ":view ") {
+		a.viewCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":view")))
+	} else if cmdlineText == ":nodes" || strings.HasPrefix(cmdlineText, ":nodes ") {
+		a.nodesCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":nodes")))
+	} else if cmdlineText == ":compare-pacs" || strings.HasPrefix(cmdlineText, ":compare-pacs ") {
+		a.compareWithArchive(strings.Fields(strings.TrimPrefix(cmdlineText, ":compare-pacs")))
+	} else if cmdlineText == ":worklist" || strings.HasPrefix(cmdlineText, ":worklist ") {
+		a.showWorklist(strings.Fields(strings.TrimPrefix(cmdlineText, ":worklist")))
+	} else if strings.HasPrefix(cmdlineText, ":import") {
+		a.importElements(strings.Fields(strings.TrimPrefix(cmdlineText, ":import")))
+	} else if cmdlineText == ":extract" || strings.HasPrefix(cmdlineText, ":extract ") {
+		a.extractElements(strings.Fields(strings.TrimPrefix(cmdlineText, ":extract")))
+	} else if cmdlineText == ":strip-pixels" || strings.HasPrefix(cmdlineText, ":strip-pixels ") {
+		a.stripPixels(strings.Fields(strings.TrimPrefix(cmdlineText, ":strip-pixels")))
+	} else if strings.HasPrefix(cmdlineText, ":pixeldata") {
+		a.replacePixelData(strings.Fields(strings.TrimPrefix(cmdlineText, ":pixeldata")))
+	} else if strings.HasPrefix(cmdlineText, ":!") {
+		if command := strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":!")); command != "" {
+			a.runTool(command)
+		} else {
+			a.statusLine.SetText("use :! <command>, e.g. :! dcmdump {file}")
+		}
+	} else if cmdlineText == ":open" || strings.HasPrefix(cmdlineText, ":open ") {
+		a.openCommand(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":open")))
+	} else if cmdlineText == ":propagate" || strings.HasPrefix(cmdlineText, ":propagate ") {
+		a.propagateCommand(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":propagate")))
+	} else if strings.HasPrefix(cmdlineText, ":item") {
+		a.editSequenceItem(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":item")))
+	} else if cmdlineText == ":duplicates" {
+		a.showDuplicates()
+	} else if cmdlineText == ":warnings" {
+		a.showWarnings()
+	} else if cmdlineText == ":inventory" {
+		a.showInventory()
+	} else if cmdlineText == ":missing" {
+		a.showMissingTags()
+	} else if cmdlineText == ":table" || strings.HasPrefix(cmdlineText, ":table ") {
+		a.showFileTable(strings.TrimPrefix(cmdlineText, ":table"))
+	} else if cmdlineText == ":trend" {
+		a.showTrend()
+	} else if cmdlineText == ":pin" || strings.HasPrefix(cmdlineText, ":pin ") {
+		a.pinCommand(strings.TrimPrefix(cmdlineText, ":pin"), true)
+	} else if cmdlineText == ":unpin" || strings.HasPrefix(cmdlineText, ":unpin ") {
+		a.pinCommand(strings.TrimPrefix(cmdlineText, ":unpin"), false)
+	} else if cmdlineText == ":frames" || strings.HasPrefix(cmdlineText, ":frames ") {
+		a.framesCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":frames")))
+	} else if cmdlineText == ":geometry" {
+		a.showGeometry()
+	} else if cmdlineText == ":slices" {
+		a.showSliceChecks()
+	} else if cmdlineText == ":time-sync" || strings.HasPrefix(cmdlineText, ":time-sync ") {
+		a.timeSyncCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":time-sync")))
+	} else if cmdlineText == ":timezone" || strings.HasPrefix(cmdlineText, ":timezone ") {
+		a.timezoneCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":timezone")))
+	} else if cmdlineText == ":anonymize" || strings.HasPrefix(cmdlineText, ":anonymize ") {
+		a.anonymize(strings.Fields(strings.TrimPrefix(cmdlineText, ":anonymize")))
+	} else if cmdlineText == ":pseudonymize" || cmdlineText == ":pseudonyms" || strings.HasPrefix(cmdlineText, ":pseudonyms ") {
+		fields := strings.Fields(cmdlineText)
+		a.pseudonymsCommand(fields[0], fields[1:])
+	} else if cmdlineText == ":coerce" || strings.HasPrefix(cmdlineText, ":coerce ") {
+		a.simulateCoercion(strings.Fields(strings.TrimPrefix(cmdlineText, ":coerce")))
+	} else if cmdlineText == ":fork-study" {
+		a.forkStudy()
+	} else if cmdlineText == ":deid-audit" {
+		a.showDeidAudit("")
+	} else if cmdlineText == ":burned-in" {
+		a.showBurnedIn()
+	} else if cmdlineText == ":references" {
+		a.showDanglingReferences()
+	} else if cmdlineText == ":rois" {
+		a.showStructureSet()
+	} else if cmdlineText == ":segments" {
+		a.showSegments()
+	} else if cmdlineText == ":rt" {
+		a.showRTSummary()
+	} else if cmdlineText == ":dose" || strings.HasPrefix(cmdlineText, ":dose ") {
+		a.doseCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":dose")))
+	} else if cmdlineText == ":waveform" || strings.HasPrefix(cmdlineText, ":waveform ") {
+		a.waveformCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":waveform")))
+	} else if cmdlineText == ":overlays" || strings.HasPrefix(cmdlineText, ":overlays ") {
+		a.overlaysCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":overlays")))
+	} else if cmdlineText == ":image" || strings.HasPrefix(cmdlineText, ":image ") {
+		a.imageCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":image")))
+	} else if cmdlineText == ":report" || strings.HasPrefix(cmdlineText, ":report ") {
+		a.reportCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":report")))
+	} else if cmdlineText == ":fhir" || strings.HasPrefix(cmdlineText, ":fhir ") {
+		a.exportFHIR(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":fhir")))
+	} else if cmdlineText == ":hash" || strings.HasPrefix(cmdlineText, ":hash ") {
+		a.hashCommand(strings.Fields(strings.TrimPrefix(cmdlineText, ":hash")))
+	} else if cmdlineText == ":root" {
+		a.zoomToCurrentNode()
+	} else if cmdlineText == ":root!" {
+		a.zoomOut()
+	} else if cmdlineText == ":stats" {
+		addAndShowStatsPage(a.pages, a.entries)
+	} else if strings.HasPrefix(cmdlineText, ":filter") {
+		a.applyFilter(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":filter")))
+	} else if cmdlineText == ":set" || strings.HasPrefix(cmdlineText, ":set ") {
+		a.setOption(strings.TrimSpace(strings.TrimPrefix(cmdlineText, ":set")))
+	} else if lineCommand.MatchString(cmdlineText) {
+		a.gotoLine(cmdlineText[1:])
+	} else if cmdlineText != ":" {
+		statusLine.SetText(fmt.Sprintf("unknown command '%s'", cmdlineText))
+	}
+}
+
+// runs the script against all loaded datasets, recording its changes, and shows the last printed line
+func (a *App) runScript(scriptFile string) {
+	before := make([]edit.Snapshot, len(a.entries))
+	for i := range a.entries {
+		before[i] = edit.TakeSnapshot(&a.entries[i].Dataset)
+	}
+	var output strings.Builder
+	err := script.Run(scriptFile, a.entries, &output, a.saveScriptEntry)
+	for i := range a.entries {
+		a.changes.Record(a.entries[i].Filename, before[i], &a.entries[i].Dataset)
+	}
+	a.refreshTree()
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error running %s: %s", scriptFile, strings.ReplaceAll(err.Error(), "\n", " ")))
+	} else if lines[len(lines)-1] != "" {
+		a.statusLine.SetText(lines[len(lines)-1])
+	} else {
+		a.statusLine.SetText(fmt.Sprintf("ran %s", scriptFile))
+	}
+}
+
+// sets a display option given as 'name=value' or 'name value', shows its value if given without value
+func (a *App) setOption(text string) {
+	name, value, hasValue := strings.Cut(strings.Replace(text, " ", "=", 1), "=")
+	switch strings.TrimSpace(name) {
+	case "maxvaluelen":
+		if !hasValue {
+			a.statusLine.SetText(fmt.Sprintf("maxvaluelen=%d", max(a.displayOptions.MaxValueLength, 0)))
+			return
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || (length != 0 && length < 10) {
+			a.statusLine.SetText("invalid maxvaluelen, use 0 for no truncation or at least 10")
+			return
+		}
+		a.displayOptions.MaxValueLength = length
+		if length == 0 {
+			a.displayOptions.MaxValueLength = -1
+		}
+		a.refreshTree()
+		a.statusLine.SetText(fmt.Sprintf("maxvaluelen=%d", length))
+	case "outdir":
+		if hasValue {
+			a.outputDir = strings.TrimSpace(value)
+		}
+		if a.outputDir == "" {
+			a.statusLine.SetText("outdir not set, files are written in place")
+			return
+		}
+		a.statusLine.SetText("outdir=" + a.outputDir)
+	default:
+		a.statusLine.SetText(fmt.Sprintf("unknown option '%s', use :set maxvaluelen=<length> or :set outdir=<dir>", name))
+	}
+}
+
+// moves to the visible line with the given number, or by the given number of lines for a leading + or -
+func (a *App) gotoLine(text string) {
+	number, _ := strconv.Atoi(text)
+	a.tree.invalidate()
+	visible := a.tree.visibleNodes()
+	position := number - 1
+	if strings.HasPrefix(text, "+") || strings.HasPrefix(text, "-") {
+		current, _ := a.tree.visiblePosition(a.tree.GetCurrentNode())
+		position = current + number
+	}
+	if position < 0 || position >= len(visible) {
+		a.statusLine.SetText(fmt.Sprintf("line %d out of range, the tree shows %d lines", position+1, len(visible)))
+		return
+	}
+	a.tree.SetCurrentNode(visible[position])
+}
+
+// shows only the files matching the filter expression, queried from the index if present, an empty expression clears the filter
+func (a *App) applyFilter(text string) {
+	if text == "" {
+		a.filterPaths, a.filterText = nil, ""
+		a.buildTree()
+		a.statusLine.SetText("filter cleared")
+		return
+	}
+	expr, err := filter.Parse(text)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("invalid filter: %s", err.Error()))
+		return
+	}
+
+	var loadErr error
+	paths := make(map[string]bool)
+	if a.index != nil {
+		matches, err := a.index.Filter(expr)
+		if err != nil {
+			a.statusLine.SetText(fmt.Sprintf("error querying index: %s", err.Error()))
+			return
+		}
+		for _, path := range matches {
+			paths[path] = true
+		}
+	} else {
+		loadErr = a.parseAllEntries()
+		for _, entry := range a.entries {
+			if expr.Match(&entry.Dataset) {
+				paths[entry.Path] = true
+			}
+		}
+	}
+	a.filterPaths, a.filterText = paths, text
+	a.buildTree()
+	statusText := fmt.Sprintf("filter matches %d of %d files", len(a.visibleEntries()), len(a.entries))
+	if loadErr != nil {
+		statusText += " (" + loadErr.Error() + ")"
+	}
+	a.statusLine.SetText(statusText)
+}
+
+// writes the changelog as JSON or CSV depending on the extension of the file
+func (a *App) exportChanges(filename string) {
+	var write func(w io.Writer) error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		write = a.changes.WriteJSON
+	case ".csv":
+		write = a.changes.WriteCSV
+	default:
+		a.statusLine.SetText("use :changes export <file>.json or <file>.csv")
+		return
+	}
+	file, err := os.Create(filename)
+	if err == nil {
+		err = write(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error exporting changes: %s", err.Error()))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("exported %d changes to %s", len(a.changes.Changes), filename))
+}
+
+// handles ':protect' to list the protected tags and ':protect <tag>' and ':unprotect <tag>' to change them
+func (a *App) changeProtection(cmdlineText string) {
+	command, tagText, _ := strings.Cut(strings.TrimSpace(cmdlineText), " ")
+	tagText = strings.TrimSpace(tagText)
+	if tagText == "" {
+		if command != ":protect" {
+			a.statusLine.SetText("missing tag, use :unprotect <tag>")
+			return
+		}
+		names := make([]string, 0, len(a.protected))
+		for _, t := range a.protected.Tags() {
+			names = append(names, dicomtree.FormatTag(t))
+		}
+		a.statusLine.SetText("protected: " + strings.Join(names, " "))
+		return
+	}
+	t, err := dicomtree.ParseTag(tagText)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	switch command {
+	case ":protect":
+		a.protected[t] = true
+		a.statusLine.SetText(fmt.Sprintf("%s is protected", dicomtree.FormatTag(t)))
+	case ":unprotect":
+		delete(a.protected, t)
+		a.statusLine.SetText(fmt.Sprintf("%s is not protected anymore", dicomtree.FormatTag(t)))
+	default:
+		a.statusLine.SetText(fmt.Sprintf("unknown command '%s'", cmdlineText))
+	}
+}
+
+// handles ':import <file> [tags]' copying the given tags and modules from the file into the current dataset
+func (a *App) importElements(args []string) {
+	if len(args) == 0 {
+		a.statusLine.SetText("missing file, use :import <file> [tags]")
+		return
+	}
+	tags, err := edit.ImportTags(args[1:])
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	for _, t := range tags {
+		if err := a.protected.Check(t); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+	}
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected for import")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	source := dicomtree.Entry{Path: args[0], Partial: true}
+	if err := source.Load(); err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error reading %s: %s", args[0], err.Error()))
+		return
+	}
+	before := edit.TakeSnapshot(&entry.Dataset)
+	imported, err := edit.Import(&entry.Dataset, &source.Dataset, tags)
+	a.changes.Record(entry.Filename, before, &entry.Dataset)
+	a.refreshTree()
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error importing from %s: %s", args[0], err.Error()))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("imported %d of %d elements from %s into %s", len(imported), len(tags), args[0], entry.Filename))
+}
+
+// handles ':pixeldata <file> [<columns>x<rows>]' installing the image as pixel data of the current dataset,
+// the pixel attributes are updated regardless of their protection
+func (a *App) replacePixelData(args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		a.statusLine.SetText("use :pixeldata <file> [<columns>x<rows>], the size is needed for RAW files only")
+		return
+	}
+	var columns, rows int
+	if len(args) == 2 {
+		if _, err := fmt.Sscanf(args[1], "%dx%d", &columns, &rows); err != nil {
+			a.statusLine.SetText(fmt.Sprintf("invalid size '%s', use <columns>x<rows>", args[1]))
+			return
+		}
+	}
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected for pixel data")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	img, err := edit.LoadImage(args[0], columns, rows)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error loading %s: %s", args[0], err.Error()))
+		return
+	}
+	before := edit.TakeSnapshot(&entry.Dataset)
+	err = edit.ReplacePixelData(&entry.Dataset, img)
+	a.changes.Record(entry.Filename, before, &entry.Dataset)
+	a.refreshTree()
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error replacing pixel data of %s: %s", entry.Filename, err.Error()))
+		return
+	}
+	bounds := img.Bounds()
+	a.statusLine.SetText(fmt.Sprintf("replaced pixel data of %s by %dx%d image %s", entry.Filename, bounds.Dx(), bounds.Dy(), args[0]))
+}
+
+// shows the groups of files with identical SOP instance UID, parsing all files first
+func (a *App) showDuplicates() {
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	groups := dicomtree.FindDuplicates(a.entries)
+	a.statusLine.SetText(fmt.Sprintf("found %d SOP instance UIDs used by multiple files", len(groups)))
+	addAndShowDuplicatesPage(a.pages, groups, func(group dicomtree.DuplicateGroup) {
+		if e, err := group.Entries[0].Dataset.FindElementByTag(tag.SOPInstanceUID); err == nil {
+			jumpToElementNode(a.tree, e)
+		}
+		a.app.SetFocus(a.tree)
+	}, a.resolveDuplicates)
+}
+
+// keeps the newest file or the first file of the group and deletes the others, or assigns new SOP instance UIDs
+// to all but the first file and overwrites them, after confirmation
+func (a *App) resolveDuplicates(group dicomtree.DuplicateGroup, action rune) {
+	if action == regenerateUIDsAction {
+		duplicates := group.Entries[1:]
+		addAndShowConfirmPage(a.pages, fmt.Sprintf("Assign new SOP instance UIDs to %s and overwrite the files?", entryFilenames(duplicates)), func() {
+			for _, entry := range duplicates {
+				before := edit.TakeSnapshot(&entry.Dataset)
+				err := edit.SetSOPInstanceUID(&entry.Dataset, edit.NewUID())
+				a.changes.Record(entry.Filename, before, &entry.Dataset)
+				if err == nil {
+					_, err = a.writeEntry(entry)
+				}
+				if err != nil {
+					a.statusLine.SetText(fmt.Sprintf("error regenerating UID of %s: %s", entry.Filename, err.Error()))
+					a.refreshTree()
+					return
+				}
+			}
+			a.refreshTree()
+			a.statusLine.SetText(fmt.Sprintf("assigned new SOP instance UIDs to %d files", len(duplicates)))
+		})
+		return
+	}
+
+	keep := group.Entries[0]
+	if action == keepNewestAction {
+		var newest time.Time
+		for _, entry := range group.Entries {
+			if info, err := os.Stat(entry.Path); err == nil && info.ModTime().After(newest) {
+				keep, newest = entry, info.ModTime()
+			}
+		}
+	}
+	var remove []*dicomtree.Entry
+	for _, entry := range group.Entries {
+		if entry != keep {
+			remove = append(remove, entry)
+		}
+	}
+	addAndShowConfirmPage(a.pages, fmt.Sprintf("Keep %s and delete %s?", keep.Filename, entryFilenames(remove)), func() {
+		removed := make(map[string]bool)
+		for _, entry := range remove {
+			slog.Info("deleting duplicate", "path", entry.Path, "kept", keep.Path)
+			if err := os.Remove(entry.Path); err != nil {
+				a.statusLine.SetText(fmt.Sprintf("error deleting %s: %s", entry.Filename, err.Error()))
+				break
+			}
+			removed[entry.Path] = true
+		}
+		entries := make([]dicomtree.Entry, 0, len(a.entries))
+		for _, entry := range a.entries {
+			if !removed[entry.Path] {
+				entries = append(entries, entry)
+			}
+		}
+		a.entries = entries
+		a.refreshTree()
+		if len(removed) == len(remove) {
+			a.statusLine.SetText(fmt.Sprintf("kept %s, deleted %d duplicates", keep.Filename, len(removed)))
+		}
+	})
+}
+
+// shows the tags present in some of the visible files but not in all, selecting a file jumps to it sorted by filename
+func (a *App) showMissingTags() {
+	if a.index == nil {
+		if err := a.parseAllEntries(); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+	}
+	entries := a.visibleEntries()
+	missingTags := dicomtree.FindMissingTags(entries)
+	a.statusLine.SetText(fmt.Sprintf("%d tags are missing in some of %d files", len(missingTags), len(entries)))
+	addAndShowMissingTagsPage(a.pages, len(entries), missingTags, func(entry *dicomtree.Entry) {
+		if a.sortMode != 1 {
+			a.sortMode = 1
+			a.buildTree()
+		}
+		jumpToFileNode(a.tree, entry.Filename)
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// handles ':item add|dup|delete|set <tag> <value>|remove <tag>' for the sequence item of the current node,
+// add also works on the node of the sequence itself
+func (a *App) editSequenceItem(args string) {
+	tree := a.tree
+	action, rest, _ := strings.Cut(args, " ")
+	var sequence *dicom.Element
+	index := -1
+	for n := tree.GetCurrentNode(); n != nil && sequence == nil; n = tree.parent(n) {
+		switch ref := n.GetReference().(type) {
+		case *dicomtree.SequenceItem:
+			sequence, index = ref.Sequence, ref.Index
+		case *dicom.Element:
+			if dicomtree.IsSequence(ref) {
+				sequence = ref
+			}
+		}
+	}
+	entry := findEntryForNode(tree, tree.GetCurrentNode(), a.entries)
+	if sequence == nil || entry == nil {
+		a.statusLine.SetText("no sequence selected")
+		return
+	}
+	if index < 0 && action != "add" {
+		a.statusLine.SetText("no sequence item selected")
+		return
+	}
+
+	before := edit.TakeSnapshot(&entry.Dataset)
+	var status string
+	var err error
+	switch action {
+	case "add":
+		index, err = edit.AddItem(sequence)
+		status = fmt.Sprintf("added item %d to %s", index+1, dicomtree.FormatTag(sequence.Tag))
+	case "dup":
+		source := index
+		index, err = edit.DuplicateItem(sequence, index)
+		status = fmt.Sprintf("duplicated item %d of %s", source+1, dicomtree.FormatTag(sequence.Tag))
+	case "delete":
+		err = edit.DeleteItem(sequence, index)
+		status = fmt.Sprintf("deleted item %d of %s", index+1, dicomtree.FormatTag(sequence.Tag))
+		index = min(index, len(dicomtree.SequenceItems(sequence))-1)
+	case "set", "remove":
+		tagText, value, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		t, parseErr := dicomtree.ParseTag(tagText)
+		if parseErr != nil {
+			a.statusLine.SetText(fmt.Sprintf("%s, use :item set <tag> <value> or :item remove <tag>", parseErr.Error()))
+			return
+		}
+		if err := a.protected.Check(t); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+		if action == "set" {
+			_, err = edit.SetInItem(sequence, index, t, strings.Split(strings.TrimSpace(value), "\\"))
+			status = fmt.Sprintf("set %s in item %d of %s", dicomtree.FormatTag(t), index+1, dicomtree.FormatTag(sequence.Tag))
+		} else {
+			var deleted bool
+			deleted, err = edit.DeleteFromItem(sequence, index, t)
+			status = fmt.Sprintf("removed %s from item %d of %s", dicomtree.FormatTag(t), index+1, dicomtree.FormatTag(sequence.Tag))
+			if err == nil && !deleted {
+				status = fmt.Sprintf("%s not found in item %d of %s", dicomtree.FormatTag(t), index+1, dicomtree.FormatTag(sequence.Tag))
+			}
+		}
+	default:
+		a.statusLine.SetText(fmt.Sprintf("unknown item action '%s', use :item add|dup|delete|set <tag> <value>|remove <tag>", action))
+		return
+	}
+	a.changes.Record(entry.Filename, before, &entry.Dataset)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	a.refreshTree()
+	jumpToItemNode(tree, sequence, index)
+	a.statusLine.SetText(status)
+}
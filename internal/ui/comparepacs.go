@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// retrieves the instance from the node and parses it
+func retrieveInstance(node dimse.Node, instance dimse.Instance) (dicom.Dataset, error) {
+	file, err := dimse.Retrieve(node, instance)
+	if err != nil {
+		return dicom.Dataset{}, err
+	}
+	dataset, err := dicom.Parse(bytes.NewReader(file), int64(len(file)), nil)
+	if err != nil {
+		return dicom.Dataset{}, fmt.Errorf("error parsing the retrieved instance: %w", err)
+	}
+	return dataset, nil
+}
+
+// returns the node with the name, without name the only node retrieving instances with C-GET or WADO-RS
+func (a *App) retrieveNode(name string) (dimse.Node, error) {
+	if a.config == nil || len(a.config.Nodes) == 0 {
+		return dimse.Node{}, errors.New("no nodes configured, add them with :nodes add")
+	}
+	canRetrieve := func(node dimse.Node) bool {
+		return node.Host != "" && node.Can("get") || node.URL != "" && node.Can("dicomweb")
+	}
+	if name != "" {
+		node, ok := a.config.Nodes[name]
+		if !ok {
+			return node, fmt.Errorf("unknown node '%s'", name)
+		}
+		if !canRetrieve(node) {
+			return node, fmt.Errorf("node '%s' retrieves neither with C-GET nor DICOMweb", name)
+		}
+		return node, nil
+	}
+	var names []string
+	for _, name := range a.nodeNames() {
+		if canRetrieve(a.config.Nodes[name]) {
+			names = append(names, name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return dimse.Node{}, errors.New("no node retrieves with C-GET or DICOMweb")
+	case 1:
+		return a.config.Nodes[names[0]], nil
+	}
+	return dimse.Node{}, fmt.Errorf("several nodes retrieve instances, use :compare-pacs %s", strings.Join(names, "|"))
+}
+
+// returns the UIDs identifying the instance of the dataset at the archive
+func archiveInstance(dataset *dicom.Dataset) (dimse.Instance, error) {
+	instance := dimse.Instance{
+		StudyInstanceUID:  dicomtree.UIDValue(dataset, tag.StudyInstanceUID),
+		SeriesInstanceUID: dicomtree.UIDValue(dataset, tag.SeriesInstanceUID),
+		SOPInstanceUID:    dicomtree.UIDValue(dataset, tag.SOPInstanceUID),
+		SOPClassUID:       dicomtree.UIDValue(dataset, tag.SOPClassUID),
+		TransferSyntaxUID: dicomtree.UIDValue(dataset, tag.TransferSyntaxUID),
+	}
+	var missing []string
+	names := []string{"StudyInstanceUID", "SeriesInstanceUID", "SOPInstanceUID", "SOPClassUID"}
+	for i, uid := range []string{instance.StudyInstanceUID, instance.SeriesInstanceUID, instance.SOPInstanceUID, instance.SOPClassUID} {
+		if uid == "" {
+			missing = append(missing, names[i])
+		}
+	}
+	if len(missing) > 0 {
+		return instance, fmt.Errorf("missing %s to retrieve the instance", strings.Join(missing, ", "))
+	}
+	return instance, nil
+}
+
+// handles ':compare-pacs [node]' retrieving the archive copy of the current instance and listing the elements the
+// archive changed, dropped or added, e.g. by coercing patient attributes or compressing on ingest
+func (a *App) compareWithArchive(args []string) {
+	if len(args) > 1 {
+		a.statusLine.SetText("use :compare-pacs [node]")
+		return
+	}
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected to compare")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	node, err := a.retrieveNode(strings.Join(args, ""))
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	instance, err := archiveInstance(&entry.Dataset)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("%s: %s", entry.Filename, err.Error()))
+		return
+	}
+	archived, err := a.retrieve(node, instance)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error retrieving %s from %s: %s", entry.Filename, node, err.Error()))
+		return
+	}
+	findings := validate.CompareElements(entry.Dataset.Elements, archived.Elements)
+	status := fmt.Sprintf("%s differs from the copy at %s in %d elements", entry.Filename, node, len(findings))
+	archivedSyntax := dicomtree.UIDValue(&archived, tag.TransferSyntaxUID)
+	if archivedSyntax != "" && instance.TransferSyntaxUID != "" && archivedSyntax != instance.TransferSyntaxUID {
+		status += fmt.Sprintf(", archived as %s instead of %s", dicomtree.UIDShortName(archivedSyntax),
+			dicomtree.UIDShortName(instance.TransferSyntaxUID))
+	}
+	a.statusLine.SetText(status)
+	addAndShowValidationPage(a.pages, "archive copy at "+node.String(), findings, func(finding validate.Finding) {
+		if finding.Element == nil || !jumpToElementNode(a.tree, finding.Element) {
+			a.statusLine.SetText(finding.Message)
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
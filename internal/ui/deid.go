@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/anon"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// attribute of a file a reviewer has to check before release
+type fileRisk struct {
+	entry *dicomtree.Entry
+	risk  anon.Risk
+}
+
+// handles ':anonymize [profile] [option...]' applying the de-identification profile, the basic profile by default,
+// with the PS3.15 options to all visible files and showing the audit of what remains, save with :w
+func (a *App) anonymize(args []string) {
+	profile := anon.Profile{Name: "basic"}
+	if len(args) > 0 {
+		if _, isOption := anon.Options[args[0]]; !isOption {
+			dir, _ := config.ProfilesDir()
+			var err error
+			if profile, err = anon.LoadProfile(args[0], dir); err != nil {
+				a.statusLine.SetText(err.Error())
+				return
+			}
+			args = args[1:]
+		}
+	}
+	profile.Options = append(slices.Clone(profile.Options), args...)
+	if err := profile.Validate(); err != nil {
+		a.statusLine.SetText(fmt.Sprintf("invalid profile %s: %s", profile.Name, err.Error()))
+		return
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	uids := anon.UIDMap{} // the preview shows the UIDs that are applied
+	preview := func() ([]edit.Change, error) {
+		return a.previewChanges(func(dataset *dicom.Dataset) error {
+			_, err := profile.Apply(dataset, uids)
+			return err
+		})
+	}
+	a.confirmBatch(":anonymize", preview, func() {
+		changed := 0
+		p := a.startProgress("anonymizing", len(a.visibleEntries()))
+		for i := range a.entries {
+			entry := &a.entries[i]
+			if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+				continue
+			}
+			before := edit.TakeSnapshot(&entry.Dataset)
+			n, err := profile.Apply(&entry.Dataset, uids)
+			a.changes.Record(entry.Filename, before, &entry.Dataset)
+			changed += n
+			if err != nil {
+				p.finish()
+				a.refreshTree()
+				a.statusLine.SetText(fmt.Sprintf("error anonymizing %s: %s", entry.Filename, err.Error()))
+				return
+			}
+			if p.step() != nil {
+				break
+			}
+		}
+		if err := p.finish(); err != nil {
+			a.refreshTree()
+			a.statusLine.SetText(fmt.Sprintf("%s, the anonymized files keep their changes", err.Error()))
+			return
+		}
+		a.refreshTree()
+		a.showDeidAudit(fmt.Sprintf("anonymized %d elements of %d files, ", changed, len(a.visibleEntries())))
+	})
+}
+
+// lists the attributes of all visible files that may still contain PHI, selecting one jumps to its element
+func (a *App) showDeidAudit(statusPrefix string) {
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	var risks []fileRisk
+	files := 0
+	for i := range a.entries {
+		entry := &a.entries[i]
+		if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+			continue
+		}
+		fileRisks := anon.Audit(&entry.Dataset)
+		if len(fileRisks) > 0 {
+			files++
+		}
+		for _, risk := range fileRisks {
+			risks = append(risks, fileRisk{entry, risk})
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("%s%d attributes of %d files to review before release", statusPrefix, len(risks), files))
+	addAndShowDeidAuditPage(a.pages, risks, func(r fileRisk) {
+		a.sortByFileIfByTag()
+		if r.risk.Element == nil || !jumpToElementNode(a.tree, r.risk.Element) {
+			jumpToFileNode(a.tree, r.entry.Filename)
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// returns the line of an attribute to review, e.g. "free text  (0008,1030) StudyDescription  Head CT"
+func fileRiskText(r fileRisk) string {
+	name := ""
+	if r.risk.Element != nil {
+		name = dicomtree.TagName(r.risk.Element)
+	}
+	return fmt.Sprintf("    %-11s  %s  %s", r.risk.Category, strings.TrimSpace(r.risk.Path+" "+name), r.risk.Value)
+}
+
+func addAndShowDeidAuditPage(pages *tview.Pages, risks []fileRisk, onSelect func(r fileRisk)) {
+	viewName := "deid-audit"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("De-identification audit (%d attributes)", len(risks))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(risks) == 0 {
+		list.AddItem("No attributes to review", "", 0, nil)
+	}
+	var file *dicomtree.Entry
+	for _, r := range risks {
+		r := r
+		selectRisk := func() {
+			pages.RemovePage(viewName)
+			onSelect(r)
+		}
+		if r.entry != file {
+			file = r.entry
+			list.AddItem(tview.Escape(file.Filename), "", 0, func() {
+				pages.RemovePage(viewName)
+				onSelect(fileRisk{entry: r.entry})
+			})
+		}
+		list.AddItem(tview.Escape(fileRiskText(r)), "", 0, selectRisk)
+	}
+	showListPage(pages, viewName, list)
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// colors of the part of a value common to all files and of the differing rest, when sorted by different values
+const (
+	diffCommonColor  = "gray"
+	diffChangedColor = "yellow"
+)
+
+// value of a value node and the length of its prefix common to all values of the tag
+type valueDiff struct {
+	value  string
+	common int
+}
+
+// computes the common prefixes of the values below each tag node of a tree sorted by tag
+func computeValueDiffs(root *tview.TreeNode, entries []dicomtree.Entry, opts dicomtree.DisplayOptions) map[*tview.TreeNode]valueDiff {
+	diffs := make(map[*tview.TreeNode]valueDiff)
+	root.Walk(func(node, parent *tview.TreeNode) bool {
+		if !isTagNode(node) {
+			return true
+		}
+		var values []string
+		var valueNodes []*tview.TreeNode
+		for _, child := range node.GetChildren() {
+			e, ok := child.GetReference().(*dicom.Element)
+			if !ok {
+				continue
+			}
+			var charsets []string
+			if entry := dicomtree.FindEntryByElement(entries, e); entry != nil {
+				charsets = charset.FromDataset(&entry.Dataset)
+			}
+			value := dicomtree.ValueString(e, charsets, opts)
+			if !strings.HasPrefix(child.GetText(), "\t "+value) {
+				continue
+			}
+			values = append(values, value)
+			valueNodes = append(valueNodes, child)
+		}
+		common := commonPrefixLength(values)
+		for i, child := range valueNodes {
+			diffs[child] = valueDiff{values[i], common}
+		}
+		return false
+	})
+	return diffs
+}
+
+// returns the length in bytes of the prefix all values start with, on a rune boundary
+func commonPrefixLength(values []string) int {
+	if len(values) == 0 {
+		return 0
+	}
+	common := len(values[0])
+	for _, value := range values[1:] {
+		n := 0
+		for n < common && n < len(value) && value[n] == values[0][n] {
+			n++
+		}
+		common = n
+	}
+	for common > 0 && common < len(values[0]) && !utf8.RuneStart(values[0][common]) {
+		common--
+	}
+	return common
+}
+
+// returns the text of the value node with the common prefix of the value dimmed and the rest highlighted
+func (d valueDiff) coloredText(text string) string {
+	if !strings.HasPrefix(text, "\t "+d.value) {
+		return "" // changed since the diffs were computed
+	}
+	rest := text[len("\t ")+len(d.value):]
+	return "\t [" + diffCommonColor + "]" + tview.Escape(d.value[:d.common]) +
+		"[" + diffChangedColor + "]" + tview.Escape(d.value[d.common:]) + "[-]" + rest
+}
@@ -0,0 +1,20 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommonPrefixLength(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, commonPrefixLength(nil))
+	assert.Equal(len("[120000.5"), commonPrefixLength([]string{"[120000.5]", "[120000.51]"}))
+	assert.Equal(len("[Doe^J"), commonPrefixLength([]string{"[Doe^John]", "[Doe^Jane]", "[Doe^Jim]"}))
+	assert.Equal(len("[M"), commonPrefixLength([]string{"[Mü]", "[Mö]"}), "on a rune boundary")
+
+	d := valueDiff{"[12]", 2}
+	assert.Equal("\t [gray][1[yellow]2][-] (2)\t - a.dcm", d.coloredText("\t [12] (2)\t - a.dcm"))
+	assert.Equal("", d.coloredText("\t [13] (2)\t - a.dcm"), "outdated")
+}
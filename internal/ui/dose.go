@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drcynic/dcmtagger/pkg/rdsr"
+	"github.com/rivo/tview"
+)
+
+// handles ':dose' showing the irradiation events of the current radiation dose SR with their dose metrics as table
+// and ':dose export <file.csv>' writing the table as CSV
+func (a *App) doseCommand(args []string) {
+	entry := a.currentRTEntry()
+	if entry == nil {
+		return
+	}
+	if !rdsr.IsDoseReport(&entry.Dataset) {
+		a.statusLine.SetText(fmt.Sprintf("%s is no radiation dose SR", entry.Filename))
+		return
+	}
+	report := rdsr.Extract(&entry.Dataset)
+	switch {
+	case len(args) == 0:
+		a.statusLine.SetText(fmt.Sprintf("%d irradiation events in %s", len(report.Events), entry.Filename))
+		addAndShowDosePage(a.pages, entry.Filename, report, func(event rdsr.Event) {
+			jumpToItemNode(a.tree, report.Content, event.Item)
+			a.app.SetFocus(a.tree)
+		})
+	case len(args) == 2 && args[0] == "export":
+		if err := exportDoseReport(args[1], report); err != nil {
+			a.statusLine.SetText(fmt.Sprintf("error exporting dose report: %s", err.Error()))
+			return
+		}
+		a.statusLine.SetText(fmt.Sprintf("exported %d irradiation events of %s to %s", len(report.Events), entry.Filename, args[1]))
+	default:
+		a.statusLine.SetText("use :dose or :dose export <file.csv>")
+	}
+}
+
+func exportDoseReport(filename string, report rdsr.Report) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = rdsr.WriteCSV(file, report)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// returns the header and rows with the columns padded to the widest cell
+func doseTableLines(report rdsr.Report) []string {
+	table := append([][]string{report.Header()}, report.Rows()...)
+	widths := make([]int, len(table[0]))
+	for _, row := range table {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	lines := make([]string, 0, len(table))
+	for _, row := range table {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+	return lines
+}
+
+// lists the events as table below its header and the accumulated doses, selecting an event jumps to its content item
+func addAndShowDosePage(pages *tview.Pages, filename string, report rdsr.Report, onSelect func(event rdsr.Event)) {
+	viewName := "dose"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Dose report %s (%d events)", filename, len(report.Events))).
+		SetTitleAlign(tview.AlignCenter)
+	lines := doseTableLines(report)
+	list.AddItem(tview.Escape(lines[0]), "", 0, nil)
+	for i, event := range report.Events {
+		event := event
+		list.AddItem(tview.Escape(lines[i+1]), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(event)
+		})
+	}
+	for _, total := range report.Totals {
+		list.AddItem(tview.Escape(fmt.Sprintf("%s: %s %s", total.Name, total.Value, total.Unit)), "", 0, nil)
+	}
+	showListPage(pages, viewName, list)
+}
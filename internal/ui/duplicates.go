@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// actions on the selected group of the duplicates page
+const (
+	keepNewestAction       = 'n'
+	deleteDuplicatesAction = 'd'
+	regenerateUIDsAction   = 'u'
+)
+
+// returns the main text with UID and files and the secondary text with the differing tags of a duplicate group
+func duplicateGroupText(group dicomtree.DuplicateGroup) (string, string) {
+	mainText := group.SOPInstanceUID + ": " + entryFilenames(group.Entries)
+	if len(group.DifferingTags) == 0 {
+		return mainText, "  identical top level values"
+	}
+	tags := make([]string, 0, len(group.DifferingTags))
+	for _, t := range group.DifferingTags {
+		tags = append(tags, strings.TrimSpace(dicomtree.FormatTag(t)+" "+dicomtree.TagNameByTag(t)))
+	}
+	return mainText, "  differs in " + strings.Join(tags, ", ")
+}
+
+func addAndShowDuplicatesPage(pages *tview.Pages, groups []dicomtree.DuplicateGroup, onSelect func(group dicomtree.DuplicateGroup), onAction func(group dicomtree.DuplicateGroup, action rune)) {
+	viewName := "duplicates"
+	list := tview.NewList()
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Duplicate SOP Instances (%d) - n: keep newest, d: keep first, u: new UIDs", len(groups))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(groups) == 0 {
+		list.AddItem("No duplicates", "", 0, nil)
+	}
+	for _, group := range groups {
+		group := group
+		mainText, secondaryText := duplicateGroupText(group)
+		list.AddItem(mainText, secondaryText, 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(group)
+		})
+	}
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			case keepNewestAction, deleteDuplicatesAction, regenerateUIDsAction:
+				if len(groups) > 0 {
+					pages.RemovePage(viewName)
+					onAction(groups[list.GetCurrentItem()], event.Rune())
+				}
+				return nil
+			}
+		}
+		return event
+	})
+	width, height := 120, 30
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(list, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
+
+// asks for confirmation of a file changing action, onConfirm is only called for yes
+func addAndShowConfirmPage(pages *tview.Pages, text string, onConfirm func()) {
+	viewName := "confirm"
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			pages.RemovePage(viewName)
+			if buttonLabel == "Yes" {
+				onConfirm()
+			}
+		})
+	pages.AddAndSwitchToPage(viewName, modal, true).ShowPage("main")
+}
+
+func entryFilenames(entries []*dicomtree.Entry) string {
+	filenames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		filenames = append(filenames, entry.Filename)
+	}
+	return strings.Join(filenames, ", ")
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// shows the form to edit the value of the element, onSave is called after the new value is set.
+// A protected element is only saved if the override is checked.
+func addAndShowTagEditingPage(pages *tview.Pages, element *dicom.Element, charsets []string, protected bool, onSave func()) {
+	viewName := "TagEditView"
+
+	newValue := ""
+	override := false
+	form := tview.NewForm()
+	form.
+		SetItemPadding(0).
+		SetFieldBackgroundColor(tcell.ColorDarkBlue).
+		SetButtonBackgroundColor(tcell.ColorDarkBlue).
+		AddTextView("Tag", fmt.Sprintf("%04x | %04x", element.Tag.Group, element.Tag.Element), 0, 1, false, false).
+		AddTextView("Name", dicomtree.TagName(element), 0, 1, false, false).
+		AddTextView("VR", element.RawValueRepresentation, 0, 1, false, false).
+		AddTextView("Length", fmt.Sprint(element.ValueLength), 0, 1, false, false)
+	height := 11
+	value := dicomtree.ValueString(element, charsets, dicomtree.DisplayOptions{MaxValueLength: -1})
+	if element.RawValueRepresentation == "PN" && len(dicomtree.ValueStrings(element)) <= 1 {
+		// a field per component group, so ideographic and phonetic names are edited apart from the alphabetic one
+		groups := dicomtree.SplitPersonName(value)
+		for i, name := range dicomtree.PersonNameGroups {
+			i := i
+			form.AddInputField(name, groups[i], 0, nil, func(text string) {
+				groups[i] = text
+				newValue = dicomtree.JoinPersonName(groups)
+			})
+		}
+		height += 2
+	} else {
+		form.AddInputField("Value", value, 0, nil, func(text string) {
+			newValue = text
+		})
+	}
+	form.
+		AddButton("Save", func() {
+			if protected && !override {
+				form.SetTitle("Protected Tag - check override to save")
+				return
+			}
+			edit.SetStrings(element, []string{newValue}, charsets)
+			onSave()
+			pages.RemovePage(viewName)
+		}).
+		AddButton("Cancel", func() {
+			pages.RemovePage(viewName)
+		})
+	if protected {
+		form.AddCheckbox("Override protection", false, func(checked bool) {
+			override = checked
+		})
+		height++
+	}
+	form.SetBorder(true).
+		SetTitle("Edit Tag Value").
+		SetTitleAlign(tview.AlignCenter)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		}
+		return event
+	})
+
+	modal := func(p tview.Primitive, width, height int) tview.Primitive {
+		return tview.NewGrid().
+			SetColumns(0, width, 0).
+			SetRows(0, height, 0).
+			AddItem(p, 1, 1, 1, 1, 0, 0, true)
+	}
+	pages.AddAndSwitchToPage(viewName, modal(form, 64, height), true).ShowPage("main")
+}
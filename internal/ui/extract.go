@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// handles ':extract <file> [header|found|tags]' writing a new file with only some top level elements of the current
+// dataset: the given tags, all but the pixel data, the elements matching the last search or by default the current
+// element or group. The file meta information and the UIDs are always kept.
+func (a *App) extractElements(args []string) {
+	if len(args) == 0 {
+		a.statusLine.SetText("use :extract <file> [header|found|tags]")
+		return
+	}
+	node := a.tree.GetCurrentNode()
+	entry := findEntryForNode(a.tree, node, a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected to extract from")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if sameFile(args[0], entry.Path) {
+		a.statusLine.SetText(fmt.Sprintf("%s is the input file, choose another file", args[0]))
+		return
+	}
+	var tags []tag.Tag
+	switch {
+	case len(args) == 2 && args[1] == "header":
+		tags = edit.HeaderTags(&entry.Dataset)
+	case len(args) == 2 && args[1] == "found":
+		for _, n := range a.highlightedNodes {
+			tags = appendTopLevelTag(tags, entry, n)
+		}
+		if len(tags) == 0 {
+			a.statusLine.SetText(fmt.Sprintf("no element of %s matches the last search", entry.Filename))
+			return
+		}
+	case len(args) > 1:
+		for _, name := range args[1:] {
+			t, err := dicomtree.ParseTag(name)
+			if err != nil {
+				a.statusLine.SetText(err.Error())
+				return
+			}
+			tags = append(tags, t)
+		}
+	default:
+		tags = a.selectedTags(entry, node)
+		if len(tags) == 0 {
+			a.statusLine.SetText("select an element or group, or use :extract <file> header|found|<tags>")
+			return
+		}
+	}
+	dataset, err := edit.Extract(&entry.Dataset, tags)
+	if err == nil {
+		err = dicomtree.WriteFile(dataset, args[0])
+	}
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error extracting to %s: %s", args[0], err.Error()))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("wrote %d of %d elements of %s to %s", len(dataset.Elements), len(entry.Dataset.Elements),
+		entry.Filename, args[0]))
+}
+
+// returns the tags of the top level elements of the entry at or below the node: the element of an element node or
+// its enclosing top level element, the elements of a group node
+func (a *App) selectedTags(entry *dicomtree.Entry, node *tview.TreeNode) []tag.Tag {
+	if !isTagNode(node) {
+		var tags []tag.Tag
+		for _, child := range node.GetChildren() {
+			if isTagNode(child) {
+				tags = appendTopLevelTag(tags, entry, child)
+			}
+		}
+		return tags
+	}
+	for n := node; n != nil; n = a.tree.fullParent(n) {
+		if tags := appendTopLevelTag(nil, entry, n); len(tags) > 0 {
+			return tags
+		}
+	}
+	return nil
+}
+
+// appends the tag of the node if it is a top level element of the entry and not in the tags yet
+func appendTopLevelTag(tags []tag.Tag, entry *dicomtree.Entry, node *tview.TreeNode) []tag.Tag {
+	e, ok := node.GetReference().(*dicom.Element)
+	if !ok || !slices.Contains(entry.Dataset.Elements, e) || slices.Contains(tags, e.Tag) {
+		return tags
+	}
+	return append(tags, e.Tag)
+}
+
+// handles ':strip-pixels <dir> [zero]' writing copies of the visible files without their pixel data, or zeroed, to
+// the directory, the edits of the session included
+func (a *App) stripPixels(args []string) {
+	if len(args) == 0 || len(args) > 2 || len(args) == 2 && args[1] != "zero" {
+		a.statusLine.SetText("use :strip-pixels <dir> [zero]")
+		return
+	}
+	entries := a.visibleEntries()
+	for i := range entries {
+		if sameFile(dicomtree.OutputPath(args[0], &entries[i]), entries[i].Path) {
+			a.statusLine.SetText(fmt.Sprintf("%s contains the input files, choose another directory", args[0]))
+			return
+		}
+	}
+	paths, err := edit.WriteStripped(entries, args[0], len(args) == 2)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error stripping pixel data after %d files: %s", len(paths), err.Error()))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("wrote %d files without pixel data to %s", len(paths), args[0]))
+}
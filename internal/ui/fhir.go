@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/drcynic/dcmtagger/pkg/fhir"
+)
+
+// writes the FHIR bundle of the visible files to the file, the files of a directory are parsed before
+func (a *App) exportFHIR(path string) {
+	if path == "" {
+		a.statusLine.SetText("usage: :fhir <file.json>")
+		return
+	}
+	loadErr := a.loadAllEntries()
+	entries := a.visibleEntries()
+	file, err := os.Create(path)
+	if err == nil {
+		err = fhir.Write(file, entries)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error writing %s: %s", path, err.Error()))
+		return
+	}
+	studies := 0
+	for _, entry := range fhir.NewBundle(entries).Entry {
+		if _, ok := entry.Resource.(*fhir.ImagingStudy); ok {
+			studies++
+		}
+	}
+	statusText := fmt.Sprintf("wrote %d imaging studies of %d files to %s", studies, len(entries), path)
+	if loadErr != nil {
+		statusText += " (" + loadErr.Error() + ")"
+	}
+	a.statusLine.SetText(statusText)
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// returns the columns shown next to a file node: size, modification time, transfer syntax and SOP class,
+// "-" for unknown values, e.g. of archive members or files not parsed yet
+func fileInfoText(entry *dicomtree.Entry) string {
+	size, modTime := "-", "-"
+	if info, err := os.Stat(entry.Path); err == nil {
+		size, modTime = formatBytes(uint64(info.Size())), info.ModTime().Format("2006-01-02 15:04")
+	}
+	uidName := func(t tag.Tag) string {
+		if uid := dicomtree.UIDValue(&entry.Dataset, t); uid != "" {
+			return dicomtree.UIDShortName(uid)
+		}
+		return "-"
+	}
+	return fmt.Sprintf("%9s  %16s  %-18s  %-20s", size, modTime, uidName(tag.TransferSyntaxUID), uidName(tag.SOPClassUID))
+}
+
+// returns the parse warning and note badges and the file info, if shown, else the summary of SOP class and modality
+// of file nodes, the note badge of element nodes and "" for other nodes
+func (a *App) nodeAnnotation(node *tview.TreeNode) string {
+	if isTagNode(node) {
+		if a.notes == nil {
+			return ""
+		}
+		return a.elementNoteAnnotation(node.GetReference().(*dicom.Element))
+	}
+	entry := dicomtree.FindEntryByFilename(a.entries, node.GetText())
+	if entry == nil {
+		return ""
+	}
+	var parts []string
+	if count := len(entry.Warnings); count == 1 {
+		parts = append(parts, "[! 1 warning]")
+	} else if count > 1 {
+		parts = append(parts, fmt.Sprintf("[! %d warnings]", count))
+	}
+	if a.notes != nil && a.notes.HasFile(entry.Filename) {
+		parts = append(parts, noteBadge)
+	}
+	if a.showFileInfo {
+		parts = append(parts, fileInfoText(entry)) // contains the SOP class already
+	} else if summary := entry.Summary(); summary != "" {
+		parts = append(parts, summary)
+	}
+	return strings.Join(parts, " ")
+}
+
+// annotates the nodes if the file info is shown, there are notes or any file has parse warnings or a summary
+func (a *App) updateAnnotations() {
+	a.tree.annotate = nil
+	if a.notes != nil && len(a.notes.All) > 0 {
+		a.tree.annotate = a.nodeAnnotation
+		return
+	}
+	for i := range a.entries {
+		if a.showFileInfo || len(a.entries[i].Warnings) > 0 || a.entries[i].Summary() != "" {
+			a.tree.annotate = a.nodeAnnotation
+			return
+		}
+	}
+}
+
+// shows or hides the file info columns, the files are parsed for transfer syntax and SOP class
+func (a *App) toggleFileInfo() {
+	a.showFileInfo = !a.showFileInfo
+	if !a.showFileInfo {
+		a.updateAnnotations()
+		a.statusLine.SetText("File info off")
+		return
+	}
+	var err error
+	if a.index == nil {
+		err = a.loadAllEntries()
+	}
+	a.updateAnnotations()
+	if err != nil {
+		a.statusLine.SetText("File info on (" + err.Error() + ")")
+	} else {
+		a.statusLine.SetText("File info on")
+	}
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// selects the element with the tag of the current element in the next or previous file, the file node if it lacks
+// the tag, e.g. to compare a value slice by slice
+func (a *App) jumpToFile(offset int) {
+	current := a.tree.GetCurrentNode()
+	entry := findEntryForNode(a.tree, current, a.entries)
+	var t *tag.Tag
+	for n := current; n != nil; n = a.tree.fullParent(n) {
+		if isTagNode(n) {
+			t = &n.GetReference().(*dicom.Element).Tag // the top level element of nested ones
+		}
+	}
+	if entry != nil && t != nil {
+		a.jumpToEntryElement(entry, *t) // below its file node if sorted by tag
+	} else {
+		a.sortByFileIfByTag()
+	}
+
+	nodes := fileNodes(a.tree, a.entries)
+	index := -1
+	for i, node := range nodes {
+		if entry != nil && node.GetText() == entry.Filename {
+			index = i
+		}
+	}
+	if index < 0 && offset < 0 {
+		index = len(nodes)
+	}
+	if index+offset < 0 || index+offset >= len(nodes) {
+		a.statusLine.SetText("no further file")
+		return
+	}
+	target := nodes[index+offset]
+	a.loadFileNodes(target)
+	targetEntry := dicomtree.FindEntryByFilename(a.entries, target.GetText())
+	if t == nil {
+		jumpToFileNode(a.tree, targetEntry.Filename)
+		a.statusLine.SetText(targetEntry.Filename)
+		return
+	}
+	if e, err := targetEntry.Dataset.FindElementByTag(*t); err == nil && jumpToElementNode(a.tree, e) {
+		a.statusLine.SetText(targetEntry.Filename)
+		return
+	}
+	jumpToFileNode(a.tree, targetEntry.Filename)
+	a.statusLine.SetText(fmt.Sprintf("%s has no %s", targetEntry.Filename, dicomtree.FormatTag(*t)))
+}
+
+// returns the file nodes of the tree in display order, below the root, a source directory or a series
+func fileNodes(tree *treeModel, entries []dicomtree.Entry) []*tview.TreeNode {
+	filenames := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		filenames[entry.Filename] = true
+	}
+	root := tree.GetRoot()
+	var nodes []*tview.TreeNode
+	root.Walk(func(node, parent *tview.TreeNode) bool {
+		if node.GetReference() == nil && filenames[node.GetText()] {
+			nodes = append(nodes, node)
+			return false
+		}
+		return node == root || parent == root && node.GetReference() == nil || isHierarchyNode(node)
+	})
+	return nodes
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+)
+
+// number of tree levels shown by zR, deeper than any tree
+const foldLevelsAll = 1 << 16
+
+// shows the given number of tree levels below the root: the nodes above are expanded, the others collapsed. The
+// files of expanded file nodes are parsed. If the current node gets hidden, the cursor moves to its visible ancestor.
+func (a *App) foldToLevels(levels int) {
+	var fold func(node *tview.TreeNode, level int)
+	fold = func(node *tview.TreeNode, level int) {
+		if level >= levels {
+			node.CollapseAll()
+			return
+		}
+		a.loadFileNodes(node)
+		node.Expand()
+		for _, child := range node.GetChildren() {
+			fold(child, level+1)
+		}
+	}
+	fold(a.tree.GetRoot(), 0)
+	a.tree.invalidateStructure()
+
+	visible := a.tree.GetCurrentNode()
+	for n := a.tree.parent(visible); n != nil; n = a.tree.parent(n) {
+		if !n.IsExpanded() {
+			visible = n
+		}
+	}
+	a.tree.SetCurrentNode(visible)
+}
+
+// handles the key after z: M folds to the files, R expands everything and 1 to 9 show as many levels below the root
+func (a *App) handleFoldKey(r rune) {
+	switch {
+	case r == 'M':
+		a.foldToLevels(1)
+		a.statusLine.SetText("folded to 1 level")
+	case r == 'R':
+		a.foldToLevels(foldLevelsAll)
+		a.statusLine.SetText("expanded all levels")
+	case r >= '1' && r <= '9':
+		levels := int(r - '0')
+		a.foldToLevels(levels)
+		a.statusLine.SetText(fmt.Sprintf("folded to %d levels", levels))
+	default:
+		a.statusLine.SetText("")
+	}
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+)
+
+// handles ':fork-study' assigning new study, series and SOP instance UIDs to the visible files after a preview, the
+// same UID everywhere by the same new one so the references between the files are kept
+func (a *App) forkStudy() {
+	for _, t := range edit.ForkedUIDTags {
+		if err := a.protected.Check(t); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	entries := a.visibleEntries()
+	datasets := make([]*dicom.Dataset, 0, len(entries))
+	for i := range entries {
+		datasets = append(datasets, &entries[i].Dataset)
+	}
+	fork := edit.NewUIDFork(datasets)
+	if len(fork.Mapping) == 0 {
+		a.statusLine.SetText("no study, series or SOP instance UIDs to fork")
+		return
+	}
+	apply := func(dataset *dicom.Dataset) error {
+		_, err := fork.Apply(dataset)
+		return err
+	}
+	preview := func() ([]edit.Change, error) { return a.previewChanges(apply) }
+	a.confirmBatch(":fork-study", preview, func() {
+		changed := 0
+		p := a.startProgress("forking", len(entries))
+		for i := range a.entries {
+			entry := &a.entries[i]
+			if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+				continue
+			}
+			before := edit.TakeSnapshot(&entry.Dataset)
+			n, err := fork.Apply(&entry.Dataset)
+			a.changes.Record(entry.Filename, before, &entry.Dataset)
+			changed += n
+			if err != nil {
+				p.finish()
+				a.refreshTree()
+				a.statusLine.SetText(fmt.Sprintf("error forking %s: %s", entry.Filename, err.Error()))
+				return
+			}
+			if p.step() != nil {
+				break
+			}
+		}
+		if err := p.finish(); err != nil {
+			a.refreshTree()
+			a.statusLine.SetText(fmt.Sprintf("%s, the forked files keep their new UIDs and the others are inconsistent now", err.Error()))
+			return
+		}
+		a.refreshTree()
+		a.statusLine.SetText(fmt.Sprintf("assigned %d new UIDs in %d elements of %d files, save with :w", len(fork.Mapping), changed, len(entries)))
+	})
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/multiframe"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const cellMaxValueLen = 40 // characters of a value in a cell of the frames and file tables
+
+// handles ':frames [tags]' showing the functional groups of the current enhanced multi-frame dataset as table with a
+// row per frame and a column per tag, the default tags without those no frame has if none are given
+func (a *App) framesCommand(args []string) {
+	entry := a.currentRTEntry()
+	if entry == nil {
+		return
+	}
+	if !multiframe.IsMultiFrame(&entry.Dataset) {
+		a.statusLine.SetText(fmt.Sprintf("%s has no per-frame functional groups", entry.Filename))
+		return
+	}
+	var table multiframe.Table
+	if len(args) == 0 {
+		table = multiframe.FunctionalGroupsTable(&entry.Dataset, multiframe.DefaultTags).WithoutEmptyColumns()
+	} else {
+		tags := make([]tag.Tag, 0, len(args))
+		for _, arg := range args {
+			t, err := dicomtree.ParseTag(arg)
+			if err != nil {
+				a.statusLine.SetText(err.Error())
+				return
+			}
+			tags = append(tags, t)
+		}
+		table = multiframe.FunctionalGroupsTable(&entry.Dataset, tags)
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d frames in %s", len(table.Rows), entry.Filename))
+	addAndShowFramesPage(a.pages, entry.Filename, table, func(cell multiframe.Cell) {
+		jumpToElementNode(a.tree, cell.Element)
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// returns the header of a column, the keyword of the tag or the tag if it has none
+func columnHeader(t tag.Tag) string {
+	if name := dicomtree.TagNameByTag(t); name != "" {
+		return name
+	}
+	return dicomtree.FormatTag(t)
+}
+
+// shows the table with the frame numbers and header fixed, values of the shared functional groups are dimmed.
+// h, j, k and l move, enter jumps to the element of the selected cell.
+func addAndShowFramesPage(pages *tview.Pages, filename string, table multiframe.Table, onSelect func(cell multiframe.Cell)) {
+	viewName := "frames"
+	view := tview.NewTable().SetFixed(1, 1).SetSelectable(true, true).SetSeparator(tview.Borders.Vertical)
+	view.SetBorder(true).
+		SetTitle(fmt.Sprintf("Functional groups of %s (%d frames)", filename, len(table.Rows))).
+		SetTitleAlign(tview.AlignCenter)
+	view.SetCell(0, 0, tview.NewTableCell("Frame").SetSelectable(false).SetAttributes(tcell.AttrBold))
+	for c, t := range table.Tags {
+		view.SetCell(0, c+1, tview.NewTableCell(columnHeader(t)).SetSelectable(false).SetAttributes(tcell.AttrBold))
+	}
+	for r, row := range table.Rows {
+		view.SetCell(r+1, 0, tview.NewTableCell(strconv.Itoa(r+1)).SetSelectable(false).SetAlign(tview.AlignRight))
+		for c, cell := range row {
+			value := cell.Value
+			if len(value) > cellMaxValueLen {
+				value = value[:cellMaxValueLen-3] + "..."
+			}
+			tableCell := tview.NewTableCell(tview.Escape(value)).SetReference(cell)
+			if cell.Shared {
+				tableCell.SetTextColor(tview.Styles.GraphicsColor)
+			}
+			view.SetCell(r+1, c+1, tableCell)
+		}
+	}
+	if len(table.Rows) > 0 && len(table.Tags) > 0 {
+		view.Select(1, 1)
+	}
+	view.SetSelectedFunc(func(row, column int) {
+		if cell, ok := view.GetCell(row, column).GetReference().(multiframe.Cell); ok && cell.Element != nil {
+			pages.RemovePage(viewName)
+			onSelect(cell)
+		}
+	})
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'h':
+				return tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone)
+			case 'l':
+				return tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	pages.AddAndSwitchToPage(viewName, view, true).ShowPage("main")
+}
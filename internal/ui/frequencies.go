@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	histogramBins     = 10
+	histogramBarWidth = 50 // characters of the bar of the most frequent bin
+)
+
+// returns the text of the value frequencies page, one line per value with the number and percentage of files
+func valueFrequenciesText(frequencies []dicomtree.ValueFrequency, missing int) string {
+	total := missing
+	for _, f := range frequencies {
+		total += f.Count
+	}
+	var text strings.Builder
+	line := func(count int, value string) {
+		fmt.Fprintf(&text, "%6d  %5.1f%%  %s\n", count, 100*float64(count)/float64(total), value)
+	}
+	for _, f := range frequencies {
+		line(f.Count, f.Value)
+	}
+	if missing > 0 {
+		line(missing, "<missing>")
+	}
+	return text.String()
+}
+
+// returns a histogram of the values with the bins between minimum and maximum, one line per bin with the range, the
+// number of values and a bar, empty for less than two different values
+func histogramText(values []float64, bins int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := slices.Min(values), slices.Max(values)
+	if lo == hi {
+		return ""
+	}
+	counts := make([]int, bins)
+	for _, v := range values {
+		counts[min(int((v-lo)/(hi-lo)*float64(bins)), bins-1)]++
+	}
+	most := slices.Max(counts)
+	var text strings.Builder
+	for i, count := range counts {
+		from, to := lo+(hi-lo)*float64(i)/float64(bins), lo+(hi-lo)*float64(i+1)/float64(bins)
+		bar := strings.Repeat("#", (count*histogramBarWidth+most-1)/most)
+		fmt.Fprintf(&text, "%10.4g .. %-10.4g %6d  %s\n", from, to, count, bar)
+	}
+	return text.String()
+}
+
+// shows the value frequencies and below them the histogram of numeric values, if any
+func addAndShowValueFrequenciesPage(pages *tview.Pages, title string, frequencies []dicomtree.ValueFrequency, missing int, values []float64) {
+	viewName := "frequencies"
+	text := valueFrequenciesText(frequencies, missing)
+	if histogram := histogramText(values, histogramBins); histogram != "" {
+		text += "\nHistogram\n\n" + histogram
+	}
+	frequenciesView := tview.NewTextView().SetText(text)
+	frequenciesView.
+		SetTitle(fmt.Sprintf("%s (%d values)", title, len(frequencies))).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	frequenciesView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(frequenciesView, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/geometry"
+	"github.com/rivo/tview"
+)
+
+// shows the slice geometry of each series with its problems, selecting a line jumps to its file
+func (a *App) showGeometry() {
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	series := geometry.Analyze(a.visibleEntries())
+	irregular := 0
+	for _, s := range series {
+		if !s.Regular() {
+			irregular++
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d of %d series have an irregular geometry", irregular, len(series)))
+	addAndShowGeometryPage(a.pages, series, func(entry *dicomtree.Entry) {
+		a.sortByFileIfByTag()
+		jumpToFileNode(a.tree, entry.Filename)
+		a.app.SetFocus(a.tree)
+	})
+}
+
+func addAndShowGeometryPage(pages *tview.Pages, series []geometry.Series, onSelect func(entry *dicomtree.Entry)) {
+	viewName := "geometry"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Geometry of %d series", len(series))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(series) == 0 {
+		list.AddItem("No series with image positions", "", 0, nil)
+	}
+	selectEntry := func(entry *dicomtree.Entry) func() {
+		return func() {
+			pages.RemovePage(viewName)
+			onSelect(entry)
+		}
+	}
+	for _, s := range series {
+		var first *dicomtree.Entry
+		if len(s.Slices) > 0 {
+			first = s.Slices[0].Entry
+		} else if len(s.Problems) > 0 {
+			first = s.Problems[0].Entry
+		}
+		list.AddItem(tview.Escape(s.Summary()), "", 0, selectEntry(first))
+		for _, problem := range s.Problems {
+			list.AddItem(tview.Escape(fmt.Sprintf("    %s: %s", problem.Entry.Filename, problem.Text)), "", 0, selectEntry(problem.Entry))
+		}
+	}
+	showListPage(pages, viewName, list)
+}
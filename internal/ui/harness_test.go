@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+)
+
+const harnessTimeout = 5 * time.Second
+
+// drives the complete viewer with its event loop on a simulation screen
+type testHarness struct {
+	t      *testing.T
+	app    *App
+	screen tcell.SimulationScreen
+	drawn  chan struct{}
+	done   chan error
+}
+
+// starts the viewer for the entries on a 80x25 simulation screen and waits for the first draw
+func newTestHarness(t *testing.T, rootDir string, entries []dicomtree.Entry) *testHarness {
+	h := &testHarness{
+		t:      t,
+		screen: tcell.NewSimulationScreen("UTF-8"),
+		drawn:  make(chan struct{}, 16),
+		done:   make(chan error, 1),
+	}
+	h.app = NewApp(rootDir, entries).SetScreen(h.screen)
+	h.app.searcher.delay = time.Hour // searches only run on enter, background results would cause extra draws
+	h.app.app.SetAfterDrawFunc(func(screen tcell.Screen) {
+		h.drawn <- struct{}{}
+	})
+	go func() {
+		h.done <- h.app.Run()
+	}()
+	t.Cleanup(func() {
+		h.app.app.Stop()
+		select {
+		case <-h.done:
+		case <-time.After(harnessTimeout):
+			t.Error("event loop did not stop")
+		}
+	})
+	h.waitForDraw()
+	return h
+}
+
+func (h *testHarness) waitForDraw() {
+	h.t.Helper()
+	select {
+	case <-h.drawn:
+	case <-time.After(harnessTimeout):
+		h.t.Fatal("timeout waiting for draw")
+	}
+}
+
+// injects the key and waits until the event loop has handled it, every handled key event causes one draw
+func (h *testHarness) sendKey(key tcell.Key, r rune, mod tcell.ModMask) {
+	h.t.Helper()
+	h.screen.InjectKey(key, r, mod)
+	h.waitForDraw()
+}
+
+// types the runes one by one, e.g. "jjl" or ":validate"
+func (h *testHarness) typeText(text string) {
+	h.t.Helper()
+	for _, r := range text {
+		h.sendKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+}
+
+// injects 'q' and waits for the event loop to end, quitting doesn't draw
+func (h *testHarness) quit() error {
+	h.t.Helper()
+	h.screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	select {
+	case err := <-h.done:
+		h.done <- err // for cleanup
+		return err
+	case <-time.After(harnessTimeout):
+		h.t.Fatal("timeout waiting for quit")
+		return nil
+	}
+}
+
+// runs f in the event loop, so the widgets can be inspected without races
+func (h *testHarness) inspect(f func(a *App)) {
+	h.app.app.QueueUpdate(func() { f(h.app) })
+}
+
+// returns the screen content as lines with trailing spaces removed
+func (h *testHarness) snapshot() string {
+	var lines []string
+	h.inspect(func(a *App) {
+		cells, width, height := h.screen.GetContents()
+		for y := 0; y < height; y++ {
+			var sb strings.Builder
+			for x := 0; x < width; x++ {
+				if runes := cells[y*width+x].Runes; len(runes) > 0 {
+					sb.WriteString(string(runes))
+				} else {
+					sb.WriteRune(' ')
+				}
+			}
+			lines = append(lines, strings.TrimRight(sb.String(), " "))
+		}
+	})
+	return strings.Join(lines, "\n")
+}
+
+func (h *testHarness) currentNodeText() string {
+	text := ""
+	h.inspect(func(a *App) { text = a.tree.GetCurrentNode().GetText() })
+	return text
+}
+
+func (h *testHarness) statusText() string {
+	text := ""
+	h.inspect(func(a *App) { text = a.statusLine.GetText(true) })
+	return text
+}
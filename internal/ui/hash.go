@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/checksum"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// shows the digests of the current file for ':hash' or writes the manifest of all files for ':hash manifest <file>'
+func (a *App) hashCommand(args []string) {
+	if len(args) == 2 && args[0] == "manifest" {
+		a.writeChecksumManifest(args[1])
+		return
+	}
+	if len(args) > 0 {
+		a.statusLine.SetText("usage: :hash or :hash manifest <file>")
+		return
+	}
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no file selected to hash")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	addAndShowHashPage(a.pages, hashText(entry))
+}
+
+// returns the digests of the file and of its pixel data in the dataset, which may be edited and not saved yet
+func hashText(entry *dicomtree.Entry) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "File:       %s\n\n", entry.Filename)
+	if digest, err := checksum.File(entry.Path); err == nil {
+		fmt.Fprintf(&text, "SHA-256 of the file:\n  %s\n\n", digest)
+	} else {
+		fmt.Fprintf(&text, "SHA-256 of the file:\n  %s\n\n", err.Error())
+	}
+	if digest, err := checksum.PixelData(&entry.Dataset); err == nil {
+		fmt.Fprintf(&text, "SHA-256 of the decoded pixel data:\n  %s\n", digest)
+	} else {
+		fmt.Fprintf(&text, "SHA-256 of the decoded pixel data:\n  %s\n", err.Error())
+	}
+	return text.String()
+}
+
+// writes the digests of all files, the files of a directory are parsed before
+func (a *App) writeChecksumManifest(path string) {
+	loadErr := a.loadAllEntries()
+	if errors.Is(loadErr, errCancelled) {
+		a.statusLine.SetText(loadErr.Error())
+		return
+	}
+	file, err := os.Create(path)
+	if err == nil {
+		_, err = fmt.Fprintln(file, checksum.ManifestHeader)
+		p := a.startProgress("hashing", len(a.entries))
+		for i := 0; i < len(a.entries) && err == nil; i++ {
+			if _, err = fmt.Fprintln(file, checksum.ManifestLine(&a.entries[i])); err == nil {
+				err = p.step()
+			}
+		}
+		if finishErr := p.finish(); finishErr != nil {
+			err = finishErr
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error writing %s: %s", path, err.Error()))
+		return
+	}
+	statusText := fmt.Sprintf("wrote checksums of %d files to %s", len(a.entries), path)
+	if loadErr != nil {
+		statusText += " (" + loadErr.Error() + ")"
+	}
+	a.statusLine.SetText(statusText)
+}
+
+func addAndShowHashPage(pages *tview.Pages, text string) {
+	viewName := "hash"
+	hashView := tview.NewTextView().SetText(text)
+	hashView.
+		SetTitle("Checksums").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	hashView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc || (event.Key() == tcell.KeyRune && event.Rune() == 'q') {
+			pages.RemovePage(viewName)
+			return nil
+		}
+		return event
+	})
+	width, height := 74, 13
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(hashView, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
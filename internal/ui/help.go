@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var helpText = `Navigation
+
+The line below the tree shows the path of the current node from the root, e.g. file ▸ group ▸ tag, and its position among the visible nodes.
+
+File nodes show what their object is right of the filename, the SOP class and modality, e.g. CT Image Storage (CT), once the file is parsed and while the file info is hidden.
+
+Global
+
+- q - quit
+- escape - cancel an operation over many files while its progress is shown, e.g. parsing all files or :anonymize
+- 1 - sort tree by filenames - under each filename entry the corresponding tags are located, private elements below a node per block with its private creator, e.g. 10xx SIEMENS CSA HEADER
+- 2 - sort tree by tags - under each tag the corresponding filenames are located with its values (parses all files of a directory), each tag shows the number of different values, the range of numeric values and the number of files missing it, private tags below a node per block and private creator, so blocks of different creators are kept apart
+- 3 - sort tree by tags and show only the tags which contains different tag values per file, the common prefix of the values is dimmed and the differing part highlighted
+- 4 - sort tree by patient, study and series - under each series its files with their tags (parses all files of a directory), series start collapsed
+- 5 - sort tree by module - under each file its elements grouped by the modules of the IOD of its SOP class (Patient, General Study, General Series, Image Pixel, ...) instead of the tag groups, elements of no module of the IOD below Other
+- shift + s - collapse all series, shift + x - expand only the series of the current node and collapse all others, [ / ] - jump to the previous / next series (sorted by patient, study and series)
+- [f / ]f - jump to the previous / next file and select the element with the tag of the current one there, the file node if the file lacks it, e.g. to compare a value slice by slice (sorted by filename or tags, which switches to sorting by filename)
+- s - cycle the order of the files between natural filename order (IM9 before IM10), modification time and size
+- r - reverse the order of the files
+- p - toggle pretty values - dates, times, person names, ages and numbers are shown formatted instead of raw
+- person names with ideographic or phonetic component groups (Yamada^Tarou=山田^太郎=やまだ^たろう) show each group on its own line below the name, the edit form (ctrl + space) has a field per group
+- date times and times of files with Timezone Offset From UTC, and date times with their own offset, show the time in UTC and in the local timezone next to the value, e.g. 143015 [UTC 2023-01-15 13:30:15, local 2023-01-15 14:30:15 +0100]
+- t - cycle tag display between "0010 PatientName", keyword with tag "PatientName (0010,0010)" and keyword only for a denser tree
+- # - cycle line numbers in the tree gutter between absolute, relative (distance to the current line, which shows its number) and off
+- ctrl + w, c - show or collapse the details pane right of the tree with the full values of the current element
+- ctrl + w, + / - (or > / <) - widen or narrow the details pane, ctrl + w, = restores its default width, width and state are kept in dcmtagger/config.json in the user config directory (~/.config on Linux)
+- <, > - scroll the tree 10 columns left or right, e.g. to read long values (see also :set maxvaluelen)
+- m - toggle file info right of the file nodes: size, modification time, transfer syntax and SOP class (parses all files of a directory)
+- z, 1 to 9 - fold the whole tree to the given number of levels below the root, e.g. z1 only the files, z2 files and groups (parses the files shown expanded)
+- z, shift + m - fold the tree to the files like z1, z, shift + r - expand all levels (parses all files of a directory)
+- shift + z - show only the current node and its descendants like :root, or the complete tree again like :root!
+- o - toggle display of byte offset and encoded length of each element within its file
+- / - enter command line with search - matches are searched in the background while typing and highlighted, the path to the first match at or after the cursor is expanded and the match centered, esc clears the highlights and restores the view from before the search, up and down recall the searches confirmed with enter in this session
+- : - enter command line with command
+- ? - help view
+
+Treeview
+
+- j,↓ - move down in visible tree structure over all hierarchy levels
+- k, ↑ - move up in visible tree structure over all hierarchy levels
+- shift + j, shift + ↓ - move down in current hierarchy level - skips other hierarchy levels
+- shift + k, shift + ↑ - move up in current hierarchy level - skips other hierarchy levels
+- h, ← - if branch node and expanded: collapse, if leaf or collapsed: move to parent if possible
+- l, → - if branch node and collapsed: expand node, if branch node and expanded: move to first child
+- shift + H, shift + ← - move to next parent
+- shift + l, shift + → - move to next child - if current node is collapsed it will be expanded
+- 0, ^ - move to first sibling in current hierachy level
+- $ - move to last sibling in current hierachy level
+
+- space, enter - toggle collapse state of current node
+- c - collapse current node and all its siblings
+- e - expand current node and all its siblings
+- shift + c - collapse current node recursively
+- shift + e - expand current node recursively
+
+- g, home - go to first node (root)
+- shift + g, end - go to last visible node
+- ctrl + u - half screen up
+- ctrl + d - half screen down
+- ctrl + f, page-down - one screen down
+- ctrl + b, page-up - one screen up
+
+- n - search for next occurence of the last search confirmed with enter, also after rebuilding the tree, e.g. by sorting
+- N - search for prev occurence of the last search confirmed with enter
+- i - when sorted by tag: show how often each value of the current tag occurs and for numeric tags a histogram, e.g. to spot the one slice with a different KVP
+- f - follow the reference of the current element or sequence item to the referenced file (Referenced SOP Instance UID, Frame of Reference UID, series and study UIDs in sequences like Source Image Sequence), parses all files
+
+Commandline
+
+- :q - quit
+- :w - write the dataset (single file only) like :w!
+- :w clean - like :w, but removes the retired group length elements (gggg,0000) and trailing padding of values before
+- :w clean-empty - like :w clean, but also removes empty attributes that are not Type 1 or 2 of the IOD (only for known SOP classes)
+- :w explicit-vr - like :w, but elements read with VR UN get the VR of the dictionary and their value decoded with it first, see :un
+- :w! - write the current file to the output directory if set, else overwrite it with its edited dataset, the previous version is moved to '.dcmtagger-backup' next to the file
+- :wa - write all files edited in the session like :w!
+- :restore - replace the current file by its newest backup and reload it, undoing the last :w!
+- :validate - check the current dataset against its SOP class IOD and the CodeMeaning and CodingSchemeDesignator of its code sequence items and show a report, select a finding to jump to its element
+- :verify-roundtrip - write the current dataset to a temporary file, parse it again and list the elements the writer changed, dropped or added, select one to jump to its element
+- :vr-audit [fix] - list the values of all files that are longer than their VR allows, have the wrong length for it, keep padding inside or have an odd length, select one to jump to its element; fix removes padding and date or time separators and shortens too long decimal strings and fractions of seconds after a preview, save with :w
+- :convert-charset utf8 - re-encode all text values of the current dataset to UTF-8 and set specific character set to ISO_IR 192, save with :w
+- :<line> - jump to the visible line with the number, :+<n> and :-<n> move n lines down or up (see relative line numbers)
+- :set maxvaluelen=<length> - truncate values in the tree after length characters (default 50), 0 shows values completely, :set maxvaluelen shows the current length
+- :set outdir=<dir> - write edited files to the directory mirroring their paths relative to the input instead of in place (see also --output), :set outdir= writes in place again
+- :goto-offset <offset> - jump to the element of the current file containing the byte offset, decimal or 0x prefixed hex
+- :source <file> - run a Starlark script against all loaded datasets after a preview of its changes and refresh the tree, the last printed line is shown in the status line
+- :changes - show all edits of the session with file, tag, old and new value and time
+- :changes export <file> - write the edits as JSON or CSV, depending on the extension .json or .csv
+- :note <text> - note the current file or top level element, noted nodes are marked with [note] in the tree, :note shows the note and :note! removes it. Notes are kept in dcmtagger-notes.json in the input directory, <file>.notes.json next to an input file or the file of --notes
+- :notes - list all notes, enter jumps to the noted file or element, :notes export <file> writes them as JSON or CSV, depending on the extension .json or .csv
+- :protect [tag] - list the protected tags or protect another one, protected tags can only be edited with override
+- :unprotect <tag> - remove the protection of a tag
+- :import <file> [tags] - copy elements from another file into the current dataset, tags are given as keyword, group,element or module name (patient, study), default is the patient module
+- :extract <file> [header|found|tags] - write a new file with only some top level elements of the current dataset: by default the current element (or the top level element around it) or the elements of the current group, with header all but the pixel data, with found the elements matching the last search, or the given tags. The file meta information, character set and UIDs are always kept, e.g. for a header-only stub or a redacted sample for a bug report
+- :strip-pixels <dir> [zero] - write copies of all files without their pixel data, or with zero the pixel data replaced by zeros of the same size, to the directory, e.g. to share a large study for metadata debugging, see also dcmtagger strip-pixels
+- :nodes - list the configured network nodes with address, TLS and capabilities, e tests the connectivity of the current node (C-ECHO, or a QIDO-RS query for DICOMweb), a tests all nodes, d deletes the current node
+- :nodes add <name> <AET@host:port|url> [tls] [capabilities] - add or replace a node in the config file, capabilities are echo, find, move, get, store, worklist and dicomweb (all services if none given)
+- :nodes delete <name> - remove a node from the config file, :nodes echo [name] tests the named or all nodes and shows the results in the status line
+- :compare-pacs [node] - retrieve the archive copy of the current instance by its UIDs from the node (C-GET, or WADO-RS for DICOMweb), without name the only node with the get or dicomweb capability, and list the elements the archive changed, dropped or added on ingest, e.g. coerced patient attributes or another transfer syntax from compression, select one to jump to its element
+- :trace [on|off|clear] - capture the network operations: the associations with their proposed and accepted presentation contexts, the DIMSE messages and the release or abort, :trace shows the captured events to debug interoperability problems
+- :worklist [node=<name>] [name=<name>] [id=<id>] [date=<YYYYMMDD>|today] [modality=<modality>] [aet=<aet>] - query the worklist of the config file or the node with the worklist capability for scheduled procedure steps matching the filters (names and IDs with wildcards * and ?, date ranges as YYYYMMDD-YYYYMMDD) and browse them as tree, c copies the patient attributes, accession number, referring physician and study instance UID of the current entry into the current dataset, save with :w
+- :pixeldata <file> [<columns>x<rows>] - replace the pixel data of the current dataset by a PNG, JPEG or RAW image (8/16 bit gray or RGB, size needed), rows, columns, bits and photometric interpretation are updated
+- :item add|dup|delete - add an empty item to the current sequence, duplicate or delete the current sequence item
+- :item set <tag> <value>|remove <tag> - set or remove an element of the current sequence item, multiple values are separated by \
+- :propagate [<tag>] - guided fix of a value over many files for the tag, by default the current element: pick the value of the current file, another file or type one (multiple values separated by \), pick the series or study of the current file, the files matching the filter or all files, and apply it after a preview of the changes, save with :w
+- :duplicates - list the files sharing a SOP instance UID with their differing tags, on the selected group n keeps the newest file and deletes the others, d keeps the first file and deletes the others, u assigns new UIDs to all but the first file and overwrites them (after confirmation), enter jumps to the first file
+- :warnings - list the parse warnings of the parsed files (odd value lengths, unknown VRs, VR UN for known tags, values truncated by the end of the file), select one to jump to its element. Files and elements with warnings are marked with [!] in the tree
+- :un [fix] - list the elements of all files read with VR UN whose tag the dictionary knows, with their values decoded with the VR of the dictionary, select one to jump to its element; fix sets that VR and the decoded values after a preview, so they are shown and written with it, save with :w
+- :inventory - profile the files: the number of files per modality, SOP class, transfer syntax, photometric interpretation and bit depth (stored/allocated), e.g. to see what a vendor actually sent (parses all files)
+- :missing - list the tags present in some files but missing in others with the affected files, select a file to jump to it
+- :table <tags> - show the files as table with a row per file and the tags (keyword or group,element, separated by commas) as columns, e.g. :table PatientName,StudyDate,SeriesNumber,InstanceNumber, h, j, k and l move, s sorts by the selected column (numbers numerically, again reverses), enter jumps to the file (parses all files)
+- :trend - when sorted by tag: plot the numeric values of the current tag against InstanceNumber across the files, e.g. SliceLocation or ExposureTime, and list missing instance numbers and values not monotonic like a slice out of order
+- :pin [<tags>] - pin the current element or the given tags (keyword or group,element, separated by commas), a bar above the tree shows their values in the file of the current node while navigating, :unpin [<tags>] removes the given or all pins, kept in the config file
+- :frames [tags] - show the functional groups of the current enhanced multi-frame dataset as table with a row per frame, by default the per-frame attributes like in-stack position, image position, spacing, window and rescale, or the given tags (keyword or group,element), values of the shared functional groups are dimmed, h, j, k and l move, enter jumps to the element
+- :geometry - check the slice geometry of each series from image position and orientation: number of slices, spacing, thickness, extent and plane, flagging gaps, duplicate positions, irregular spacing, differing orientations and tilted stacks that break a 3D reconstruction, select a line to jump to its file (parses all files)
+- :slices - check the slices of each series for missing and duplicate instance numbers, duplicate image positions and gaps or irregular spacing of the slice locations, listing the suspect instances, select one to jump to its file (parses all files)
+- :time-sync [threshold] - compare the acquisition times (Acquisition DateTime, else acquisition or content date and time, in the timezone of Timezone Offset From UTC) of the devices (station name, else manufacturer and model, with serial number) per study and mark the devices starting more than the threshold (default 5m, e.g. 90s or 1h) after the first device of their study, e.g. a modality clock off in a multi-modality study, select a device to jump to its first file (parses all files)
+- :timezone <&HHMM>|utc - rewrite the date times and the study, series, acquisition, content, instance creation and performed procedure step dates and times of all files, also in sequence items, into the timezone after a preview of the changes and set Timezone Offset From UTC to it, values without offset of files without Timezone Offset From UTC are kept as their timezone is unknown, save with :w
+- :references - list the references of all files to instances, series, studies and frames of reference that are not loaded, select one to jump to the referencing element
+- :anonymize [profile] [option...] - remove or replace the identifying patient, study and institution attributes and the instance UIDs of all files by the basic profile or the given one after a preview of the changes and show the de-identification audit, save with :w
+- :pseudonymize - replace PatientID and PatientName of all files with pseudonyms, the same patient always gets the same pseudonym as the mapping is kept in pseudonyms.csv in the config directory (or --pseudonyms), after a preview of the changes, save with :w
+- :pseudonyms - list the mapping of patients to pseudonyms, :pseudonyms import <file.csv> adds the rows PatientID,PatientName,PseudonymID[,PseudonymName] of an existing mapping
+- :coerce <rules.json> - simulate the attribute coercion of a PACS on ingestion by the rules file (see the README) and show the values of all files before and after, e.g. to validate routing rules before sending, the files stay unchanged
+- :fork-study - assign new study, series and SOP instance UIDs to all files after a preview of the changes, each UID everywhere by the same new one, so references between the files, e.g. of a report to its images, are kept: an independent copy of the study for repeated PACS import tests, save with :w
+- :deid-audit - list the attributes of all files that may still contain PHI before release: identifying attributes and free text with values, private attributes and images without burned-in annotation NO
+- :burned-in - scan the pixel data of all files for high-contrast text-like regions near the image edges and list the images that likely contain burned-in PHI or have BurnedInAnnotation YES, select one to jump to it
+- :rois - list the ROIs of the current RT structure set with number, name, display color, interpreted type, number of contours and points and the referenced image series, select one to jump to its item
+- :segments - list the segments of the current segmentation (SEG) with label, category and type, algorithm and number of frames, enter previews the frames of a segment in red on its referenced image if that is loaded, n and p step through the frames
+- :rt - summarize the current RT plan (label, prescribed dose, fraction groups, beams with type, energy, control points and MU) or RT dose (dose grid geometry and max dose), select a line to jump to its element, for a structure set like :rois
+- :dose - show the irradiation events of the current radiation dose SR (TID 10011 for CT, 10001 for projection X-ray) as table with protocol, acquisition type, target region and the dose metrics like CTDIvol, DLP and dose area product, below them the accumulated doses, select an event to jump to its content item
+- :dose export <file.csv> - write the event table of the current radiation dose SR as CSV
+- :waveform - plot the channels of the current waveform object, e.g. a 12-lead ECG, h and l scroll in time, + and - zoom in and out
+- :waveform export <file> - write the samples of the waveform as CSV with a time column and a column per channel, several multiplex groups to one file each
+- :overlays - list the overlay planes (60xx) of the current dataset with size, origin, type and number of set pixels, enter shows a preview of the overlay
+- :overlays export <file.png> - write the first frame with all overlays composited onto it in white as PNG
+- :image - show the pixel format of the current dataset: size, photometric interpretation, frames, planar configuration and ICC profile
+- :image export <file.png> [<frame>] - write a frame, by default the first, as PNG: YBR and color-by-plane pixel data are converted to RGB, MONOCHROME1 is inverted and the ICC profile of the dataset or its optical path is embedded
+- :hash - show the SHA-256 of the current file and of its decoded pixel data, which stays the same when only the header is edited
+- :hash manifest <file> - write the file and pixel data digests of all files to a checksum manifest, see also 'dcmtagger hash'
+- :report <dataset|diff|validation|audit> <file.html|file.md> [template] - write the dataset or the validation findings of the current file, the differing values of all files or their de-identification audit, as HTML or Markdown document, optionally with an own Go template
+- :fhir <file.json> - write the studies of all files as FHIR R4 bundle with ImagingStudy and Patient resources, see also 'dcmtagger fhir'
+- :! <command> - run the command with the shell on the current file in the background and show its output when it exits, {file} is replaced by the quoted path of the file, which is also in DCMTAGGER_FILE, and {tag} by the tag of the current element like 0010,0010, e.g. :! dcmdump +P {tag} {file}; the command sees the file on disk without unsaved edits
+- :open [<name>] - list the tools of the config file to run on the current file like :!, e.g. dcmdump, storescu or a viewer, enter runs one, with a name it runs right away
+- :root - make the current node the root of the tree, e.g. a single file or group, the breadcrumb still shows the full path
+- :root! - show the complete tree again, the cursor stays on the current node
+- :stats - show number of files and elements, heap usage and parse durations per file with the slowest files
+- :filter <expr> - show only the files matching the expression, e.g. 'Modality=CT and PatientName~doe', clear without expression
+- :view save <name> - save the sort mode, filter, last confirmed search and the columns of the last :table as named view in the config file
+- :view <name> - recall a saved view: restore its sort mode, filter and search and show its table, :view lists the saved views, :view delete <name> removes one
+`
+
+func addAndShowHelpPage(pages *tview.Pages) {
+	viewName := "help"
+	helpView := tview.NewTextView().SetText(string(helpText))
+	helpView.
+		SetTitle("Help").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	helpView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(helpView, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+)
+
+// returns whether the node is a series node of the hierarchy sort mode
+func isSeriesNode(node *tview.TreeNode) bool {
+	level, ok := node.GetReference().(dicomtree.HierarchyLevel)
+	return ok && level == dicomtree.HierarchySeries
+}
+
+// returns the series nodes of the tree in display order
+func seriesNodes(tree *treeModel) []*tview.TreeNode {
+	var nodes []*tview.TreeNode
+	tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		if isSeriesNode(node) {
+			nodes = append(nodes, node)
+			return false
+		}
+		return isHierarchyNode(node) || parent == nil
+	})
+	return nodes
+}
+
+// collapses all series nodes and expands the patients and studies above them, so every series is one line
+func collapseAllSeries(tree *treeModel) {
+	tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		if isSeriesNode(node) {
+			node.CollapseAll()
+			return false
+		}
+		node.Expand()
+		return true
+	})
+	tree.invalidate()
+}
+
+// returns the series node the node belongs to, nil if it is above or outside of a series
+func seriesOfNode(tree *treeModel, node *tview.TreeNode) *tview.TreeNode {
+	for n := node; n != nil; n = tree.parent(n) {
+		if isSeriesNode(n) {
+			return n
+		}
+	}
+	return nil
+}
+
+// returns false with a hint in the status line if the tree is not sorted by patient, study and series
+func (a *App) checkHierarchyMode() bool {
+	if a.sortMode != 4 {
+		a.statusLine.SetText("series keys work when sorted by patient, study and series (4)")
+		return false
+	}
+	return true
+}
+
+// collapses all series, the cursor moves to the series of the current node, S
+func (a *App) collapseSeries() {
+	if !a.checkHierarchyMode() {
+		return
+	}
+	series := seriesOfNode(a.tree, a.tree.GetCurrentNode())
+	collapseAllSeries(a.tree)
+	if series != nil {
+		a.tree.SetCurrentNode(series)
+	}
+	a.statusLine.SetText(fmt.Sprintf("collapsed %d series", len(seriesNodes(a.tree))))
+}
+
+// expands the series of the current node and collapses all others, X
+func (a *App) expandOnlyCurrentSeries() {
+	if !a.checkHierarchyMode() {
+		return
+	}
+	series := seriesOfNode(a.tree, a.tree.GetCurrentNode())
+	if series == nil {
+		a.statusLine.SetText("the current node is not in a series")
+		return
+	}
+	collapseAllSeries(a.tree)
+	a.loadFileNodes(series.GetChildren()...)
+	series.Expand()
+	a.tree.invalidate()
+	a.statusLine.SetText("expanded " + series.GetText())
+}
+
+// moves to the next series node after the current node for a positive offset, the previous one before the current
+// series otherwise, ] and [
+func (a *App) jumpToSeries(offset int) {
+	if !a.checkHierarchyMode() {
+		return
+	}
+	nodes := seriesNodes(a.tree)
+	order := a.tree.searchOrder()
+	current := a.tree.GetCurrentNode()
+	if series := seriesOfNode(a.tree, current); series != nil {
+		current = series
+	}
+	var target *tview.TreeNode
+	for _, node := range nodes {
+		if offset > 0 && order[node] > order[current] {
+			target = node
+			break
+		}
+		if offset < 0 && order[node] < order[current] {
+			target = node
+		}
+	}
+	if target == nil {
+		a.statusLine.SetText("no further series")
+		return
+	}
+	expandPathToNode(a.tree, a.tree.parent(target))
+	a.tree.SetCurrentNode(target)
+	a.statusLine.SetText(target.GetText())
+}
@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/hook"
+)
+
+// sets the commands run for the events of files, see pkg/hook, and runs the load hooks of the files already parsed
+func (a *App) SetHooks(hooks map[string][]string) *App {
+	a.hooks = hooks
+	for i := range a.entries {
+		if a.entries[i].Partial {
+			continue
+		}
+		if err := a.runHooks(hook.Load, &a.entries[i]); err != nil {
+			a.statusLine.SetText(err.Error())
+			break
+		}
+	}
+	a.refreshTree()
+	return a
+}
+
+// runs the commands of the event for the entry in their order, the changes they make are recorded like edits
+func (a *App) runHooks(event string, entry *dicomtree.Entry) error {
+	before := edit.TakeSnapshot(&entry.Dataset)
+	err := hook.RunAll(a.hooks[event], event, entry, a.protected)
+	a.changes.Record(entry.Filename, before, &entry.Dataset)
+	return err
+}
+
+// returns the writer of :w and save() of scripts with the save hooks, see hook.Writer
+func (a *App) writer() *hook.Writer {
+	return &hook.Writer{Commands: a.hooks[hook.Save], Protected: a.protected, OutputDir: a.outputDir}
+}
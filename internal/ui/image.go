@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+
+	"github.com/drcynic/dcmtagger/pkg/render"
+)
+
+// handles ':image' showing the pixel format of the current dataset and ':image export <file.png> [<frame>]' writing a
+// frame as PNG in its colors with the ICC profile of the dataset
+func (a *App) imageCommand(args []string) {
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	switch {
+	case len(args) == 0:
+		a.statusLine.SetText(fmt.Sprintf("%s: %s", entry.Filename, render.ReadFormat(&entry.Dataset)))
+	case (len(args) == 2 || len(args) == 3) && args[0] == "export":
+		number := 1
+		if len(args) == 3 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 1 {
+				a.statusLine.SetText(fmt.Sprintf("invalid frame number '%s'", args[2]))
+				return
+			}
+			number = n
+		}
+		profile := render.ICCProfile(&entry.Dataset)
+		img, err := render.Frame(&entry.Dataset, number-1)
+		if err == nil {
+			err = writeImagePNG(args[1], img, profile)
+		}
+		if err != nil {
+			a.statusLine.SetText(fmt.Sprintf("error exporting frame %d of %s: %s", number, entry.Filename, err.Error()))
+			return
+		}
+		text := fmt.Sprintf("exported frame %d of %s to %s", number, entry.Filename, args[1])
+		if profile != nil {
+			text += " with its ICC profile"
+		}
+		a.statusLine.SetText(text)
+	default:
+		a.statusLine.SetText("use :image or :image export <file.png> [<frame>]")
+	}
+}
+
+func writeImagePNG(filename string, img image.Image, profile []byte) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = render.EncodePNG(file, img, profile)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// handles ':inventory' profiling the visible files by modality, SOP class, transfer syntax, photometric interpretation
+// and bit depth
+func (a *App) showInventory() {
+	if a.index == nil {
+		if err := a.parseAllEntries(); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+	}
+	entries := a.visibleEntries()
+	inventory := dicomtree.Inventory(entries)
+	a.statusLine.SetText(fmt.Sprintf("inventory of %d files", len(entries)))
+	addAndShowInventoryPage(a.pages, inventoryText(inventory), len(entries))
+}
+
+// returns the text of the inventory page, per category the values with the number and percentage of files
+func inventoryText(inventory []dicomtree.InventoryCategory) string {
+	var text strings.Builder
+	for i, category := range inventory {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		fmt.Fprintf(&text, "%s:\n%s", category.Name, valueFrequenciesText(category.Frequencies, 0))
+	}
+	return text.String()
+}
+
+func addAndShowInventoryPage(pages *tview.Pages, text string, fileCount int) {
+	viewName := "inventory"
+	view := tview.NewTextView().SetText(text)
+	view.
+		SetTitle(fmt.Sprintf("Inventory of %d files", fileCount)).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(view, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// lists the tags missing in some files, under each tag the files of the smaller side (missing or present) to jump to
+func addAndShowMissingTagsPage(pages *tview.Pages, fileCount int, missingTags []dicomtree.MissingTag, onSelect func(entry *dicomtree.Entry)) {
+	viewName := "missing"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Tags missing in some files (%d)", len(missingTags))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(missingTags) == 0 {
+		list.AddItem("No missing tags", "", 0, nil)
+	}
+	selectEntry := func(entry *dicomtree.Entry) func() {
+		return func() {
+			pages.RemovePage(viewName)
+			onSelect(entry)
+		}
+	}
+	for _, missingTag := range missingTags {
+		label, entries := "missing in", missingTag.Missing
+		if len(missingTag.Present) < len(missingTag.Missing) {
+			label, entries = "only in", missingTag.Present
+		}
+		tagText := strings.TrimSpace(dicomtree.FormatTag(missingTag.Tag) + " " + dicomtree.TagNameByTag(missingTag.Tag))
+		list.AddItem(fmt.Sprintf("%s - present in %d of %d files, %s:", tagText, len(missingTag.Present), fileCount, label), "", 0, selectEntry(entries[0]))
+		for _, entry := range entries {
+			list.AddItem("    "+entry.Filename, "", 0, selectEntry(entry))
+		}
+	}
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 30
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(list, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
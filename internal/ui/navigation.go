@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+func findNodeRecursive(tree *treeModel, searchText string) ([]*tview.TreeNode, int) {
+	findPred := func(node *tview.TreeNode) bool {
+		return strings.Contains(strings.ToLower(node.GetText()), searchText)
+	}
+
+	foundNodes := make([]*tview.TreeNode, 0)
+	foundIndex := -1
+	tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		if findPred(node) {
+			foundNodes = append(foundNodes, node)
+		}
+		if tree.GetCurrentNode() == node {
+			if len(foundNodes) > 0 {
+				foundIndex = len(foundNodes) - 1
+			} else {
+				foundIndex = 0
+			}
+		}
+		return true
+	})
+
+	return foundNodes, foundIndex
+}
+
+func expandPathToNode(tree *treeModel, node *tview.TreeNode) {
+	for n := node; n != nil; n = tree.parent(n) {
+		n.Expand()
+	}
+	tree.invalidate()
+}
+
+func expandCurrentAndAllSiblings(tree *treeModel) {
+	siblings := tree.siblings(tree.GetCurrentNode())
+	for _, sibling := range siblings {
+		sibling.Expand()
+	}
+	tree.invalidate()
+}
+
+func collapseCurrentAndAllSiblings(tree *treeModel) {
+	siblings := tree.siblings(tree.GetCurrentNode())
+	for _, sibling := range siblings {
+		sibling.Collapse()
+	}
+	tree.invalidate()
+}
+
+func expandOrMoveToFirstChild(tree *treeModel) {
+	currentNode := tree.GetCurrentNode()
+	if len(currentNode.GetChildren()) > 0 {
+		if currentNode.IsExpanded() {
+			tree.SetCurrentNode(currentNode.GetChildren()[0])
+		} else {
+			currentNode.Expand()
+			tree.invalidate()
+		}
+	}
+}
+
+func collapseOrMoveToParent(tree *treeModel) {
+	currentNode := tree.GetCurrentNode()
+	if len(currentNode.GetChildren()) > 0 && currentNode.IsExpanded() {
+		currentNode.Collapse()
+		tree.invalidate()
+	} else {
+		moveToParent(tree)
+	}
+}
+
+func moveToFirstChild(tree *treeModel) {
+	currentNode := tree.GetCurrentNode()
+	if len(currentNode.GetChildren()) > 0 {
+		currentNode.SetExpanded(true)
+		tree.invalidate()
+		tree.SetCurrentNode(currentNode.GetChildren()[0])
+	}
+}
+
+func moveToParent(tree *treeModel) {
+	parent := tree.parent(tree.GetCurrentNode())
+	if parent != nil {
+		tree.SetCurrentNode(parent)
+	}
+}
+
+func moveToFirstSibling(tree *treeModel) {
+	siblings := tree.siblings(tree.GetCurrentNode())
+	if len(siblings) > 0 {
+		tree.SetCurrentNode(siblings[0])
+	}
+}
+
+func moveToLastSibling(tree *treeModel) {
+	siblings := tree.siblings(tree.GetCurrentNode())
+	if len(siblings) > 0 {
+		tree.SetCurrentNode(siblings[len(siblings)-1])
+	}
+}
+
+func moveUpSameLevel(tree *treeModel) {
+	if node := tree.sameLevelNode(tree.GetCurrentNode(), -1); node != nil {
+		tree.SetCurrentNode(node)
+	}
+}
+
+func moveDownSameLevel(tree *treeModel) {
+	if node := tree.sameLevelNode(tree.GetCurrentNode(), 1); node != nil {
+		tree.SetCurrentNode(node)
+	}
+}
+
+func jumpToRoot(tree *treeModel) {
+	tree.SetCurrentNode(tree.GetRoot())
+}
+
+func jumpToLastVisibleNode(tree *treeModel) {
+	nodes := tree.visibleNodes()
+	tree.SetCurrentNode(nodes[len(nodes)-1])
+}
+
+func jumpToNextFoundNode(searchText string, tree *treeModel) {
+	jumpToNthFoundNode(searchText, 1, tree)
+}
+
+func jumpToPrevFoundNode(searchText string, tree *treeModel) {
+	jumpToNthFoundNode(searchText, -1, tree)
+}
+
+func jumpToNthFoundNode(searchText string, offset int, tree *treeModel) {
+	if len(searchText) > 1 {
+		foundNodes, currentIdx := findNodeRecursive(tree, searchText)
+		len := len(foundNodes)
+		if len > 0 {
+			newNode := foundNodes[(currentIdx+len+offset)%len]
+			if newNode != tree.GetCurrentNode() {
+				tree.SetCurrentNode(newNode)
+				expandPathToNode(tree, newNode)
+			}
+		}
+	}
+}
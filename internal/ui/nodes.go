@@ -0,0 +1,201 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// handles ':nodes', ':nodes add <name> <AET@host:port|url> [tls] [capabilities]', ':nodes delete <name>' and
+// ':nodes echo [name]'
+func (a *App) nodesCommand(args []string) {
+	if a.config == nil {
+		a.statusLine.SetText("nodes are kept in the config file, which is not loaded")
+		return
+	}
+	if len(args) == 0 {
+		a.addAndShowNodesPage()
+		return
+	}
+	switch args[0] {
+	case "add":
+		a.addNode(args[1:])
+	case "delete":
+		if len(args) != 2 {
+			a.statusLine.SetText("use :nodes delete <name>")
+			return
+		}
+		a.deleteNode(args[1])
+	case "echo":
+		names := args[1:]
+		if len(names) == 0 {
+			names = a.nodeNames()
+		}
+		var results []string
+		for _, name := range names {
+			node, ok := a.config.Nodes[name]
+			if !ok {
+				a.statusLine.SetText(fmt.Sprintf("unknown node '%s'", name))
+				return
+			}
+			results = append(results, name+" "+pingText(node))
+		}
+		if len(results) == 0 {
+			a.statusLine.SetText("no nodes configured, add them with :nodes add")
+			return
+		}
+		a.statusLine.SetText(strings.Join(results, ", "))
+	default:
+		a.statusLine.SetText("use :nodes [add <name> <AET@host:port|url> [tls] [capabilities]|delete <name>|echo [name]]")
+	}
+}
+
+// returns the names of the configured nodes sorted
+func (a *App) nodeNames() []string {
+	names := make([]string, 0, len(a.config.Nodes))
+	for name := range a.config.Nodes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (a *App) addNode(args []string) {
+	if len(args) < 2 {
+		a.statusLine.SetText("use :nodes add <name> <AET@host:port|url> [tls] [capabilities]")
+		return
+	}
+	node, err := dimse.ParseNode(args[1])
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	for _, option := range args[2:] {
+		if option == "tls" {
+			node.TLS = true
+		} else {
+			node.Capabilities = append(node.Capabilities, option)
+		}
+	}
+	if err := node.Validate(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	_, replaced := a.config.Nodes[args[0]]
+	if a.config.Nodes == nil {
+		a.config.Nodes = make(map[string]dimse.Node)
+	}
+	a.config.Nodes[args[0]] = node
+	status := fmt.Sprintf("added node %s %s", args[0], node)
+	if replaced {
+		status = fmt.Sprintf("replaced node %s by %s", args[0], node)
+	}
+	a.statusLine.SetText(a.saveConfig(status))
+}
+
+func (a *App) deleteNode(name string) {
+	if _, ok := a.config.Nodes[name]; !ok {
+		a.statusLine.SetText(fmt.Sprintf("unknown node '%s'", name))
+		return
+	}
+	delete(a.config.Nodes, name)
+	a.statusLine.SetText(a.saveConfig("deleted node " + name))
+}
+
+// saves the config and returns the status with the error if saving failed
+func (a *App) saveConfig(status string) string {
+	if err := config.Save(*a.config); err != nil {
+		return fmt.Sprintf("%s (error saving config: %s)", status, err.Error())
+	}
+	return status
+}
+
+// tests the connectivity of the node, returns e.g. "ok in 12ms" or "failed: connection refused"
+func pingText(node dimse.Node) string {
+	duration, err := dimse.Ping(node)
+	if err != nil {
+		return "failed: " + err.Error()
+	}
+	return fmt.Sprintf("ok in %s", duration.Round(time.Millisecond))
+}
+
+// returns the line of the node in the nodes page, e.g. "pacs  PACS@pacs:104  echo, find"
+func nodeText(name string, node dimse.Node) string {
+	capabilities := "all services"
+	if len(node.Capabilities) > 0 {
+		capabilities = strings.Join(node.Capabilities, ", ")
+	}
+	return tview.Escape(fmt.Sprintf("%-12s %-40s %s", name, node, capabilities))
+}
+
+// lists the nodes, e tests the current node and a all nodes in the background, d deletes the current node
+func (a *App) addAndShowNodesPage() {
+	viewName := "nodes"
+	names := a.nodeNames()
+	list := tview.NewList()
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Nodes (%d) - e: test, a: test all, d: delete", len(names))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(names) == 0 {
+		list.AddItem("No nodes, add them with :nodes add <name> <AET@host:port|url> [tls] [capabilities]", "", 0, nil)
+	}
+	for _, name := range names {
+		list.AddItem(nodeText(name, a.config.Nodes[name]), "  not tested", 0, nil)
+	}
+	test := func(index int) {
+		name := names[index]
+		node := a.config.Nodes[name]
+		list.SetItemText(index, nodeText(name, node), "  testing")
+		go func() {
+			result := pingText(node)
+			a.app.QueueUpdateDraw(func() { list.SetItemText(index, nodeText(name, node), "  "+tview.Escape(result)) })
+		}()
+	}
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			a.pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				a.pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			case 'e':
+				if len(names) > 0 {
+					test(list.GetCurrentItem())
+				}
+				return nil
+			case 'a':
+				for i := range names {
+					test(i)
+				}
+				return nil
+			case 'd':
+				if len(names) > 0 {
+					a.pages.RemovePage(viewName)
+					a.deleteNode(names[list.GetCurrentItem()])
+					a.addAndShowNodesPage()
+				}
+				return nil
+			}
+		}
+		return event
+	})
+	width, height := 120, 30
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(list, 1, 1, 1, 1, 0, 0, true)
+	a.pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
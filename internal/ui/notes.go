@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/notes"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// badge of noted nodes in the tree, "[note]" escaped as tview would read it as style tag
+const noteBadge = "[note[]"
+
+// returns the file and the tag of the note on the node, tag "" for a file node
+func (a *App) noteTarget(node *tview.TreeNode) (file, tagText, name string, err error) {
+	if isTagNode(node) {
+		e := node.GetReference().(*dicom.Element)
+		entry := dicomtree.FindEntryByElement(a.entries, e)
+		if entry == nil {
+			return "", "", "", fmt.Errorf("notes are kept on files and top level elements")
+		}
+		return entry.Filename, dicomtree.FormatTag(e.Tag), dicomtree.TagNameByTag(e.Tag), nil
+	}
+	if entry := dicomtree.FindEntryByFilename(a.entries, node.GetText()); entry != nil {
+		return entry.Filename, "", "", nil
+	}
+	return "", "", "", fmt.Errorf("select a file or an element to note")
+}
+
+// handles ':note <text>' noting the current file or element, ':note' showing its note and ':note!' removing it
+func (a *App) noteCommand(command, text string) {
+	if a.notes == nil {
+		a.statusLine.SetText("notes need a sidecar file, start with --notes <file.json>")
+		return
+	}
+	file, tagText, name, err := a.noteTarget(a.tree.GetCurrentNode())
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	target := strings.TrimSpace(file + " " + tagText + " " + name)
+	if command == ":note" && text == "" {
+		if note, ok := a.notes.Find(file, tagText); ok {
+			a.statusLine.SetText(fmt.Sprintf("%s: %s", target, note.Text))
+		} else {
+			a.statusLine.SetText(fmt.Sprintf("no note on %s, add one with :note <text>", target))
+		}
+		return
+	}
+	note := notes.Note{File: file, Tag: tagText, Name: name, Text: text, Time: time.Now()}
+	if command == ":note!" {
+		note.Text = ""
+	}
+	if !a.notes.Set(note) {
+		a.statusLine.SetText(fmt.Sprintf("no note on %s", target))
+		return
+	}
+	a.updateAnnotations()
+	status := "noted " + target
+	if note.Text == "" {
+		status = "removed the note on " + target
+	}
+	if err := a.notes.Save(); err != nil {
+		status += fmt.Sprintf(" (error saving %s: %s)", a.notes.Path(), err.Error())
+	}
+	a.statusLine.SetText(status)
+}
+
+// handles ':notes' listing all notes and ':notes export <file>' writing them as JSON or CSV
+func (a *App) notesCommand(args []string) {
+	if a.notes == nil {
+		a.statusLine.SetText("notes need a sidecar file, start with --notes <file.json>")
+		return
+	}
+	switch {
+	case len(args) == 0:
+		a.statusLine.SetText(fmt.Sprintf("%d notes in %s", len(a.notes.All), a.notes.Path()))
+		addAndShowNotesPage(a.pages, a.notes.All, func(note notes.Note) {
+			a.jumpToNote(note)
+			a.app.SetFocus(a.tree)
+		})
+	case len(args) == 2 && args[0] == "export":
+		a.exportNotes(args[1])
+	default:
+		a.statusLine.SetText("use :notes or :notes export <file>.json|<file>.csv")
+	}
+}
+
+// selects the node of the noted file or element
+func (a *App) jumpToNote(note notes.Note) {
+	entry := dicomtree.FindEntryByFilename(a.entries, note.File)
+	if entry == nil {
+		a.statusLine.SetText(fmt.Sprintf("%s is not loaded", note.File))
+		return
+	}
+	if note.Tag == "" {
+		a.sortByFileIfByTag()
+		jumpToFileNode(a.tree, entry.Filename)
+		return
+	}
+	t, err := dicomtree.ParseTag(note.Tag)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	a.jumpToEntryElement(entry, t)
+}
+
+func (a *App) exportNotes(filename string) {
+	var write func(w io.Writer) error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		write = a.notes.WriteJSON
+	case ".csv":
+		write = a.notes.WriteCSV
+	default:
+		a.statusLine.SetText("use :notes export <file>.json or <file>.csv")
+		return
+	}
+	file, err := os.Create(filename)
+	if err == nil {
+		err = write(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error exporting notes: %s", err.Error()))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("exported %d notes to %s", len(a.notes.All), filename))
+}
+
+// returns the note badge of a top level element node, "" if it has no note
+func (a *App) elementNoteAnnotation(e *dicom.Element) string {
+	tagText := dicomtree.FormatTag(e.Tag)
+	if !slices.ContainsFunc(a.notes.All, func(note notes.Note) bool { return note.Tag == tagText }) {
+		return "" // saves looking up the file of the element
+	}
+	if entry := dicomtree.FindEntryByElement(a.entries, e); entry != nil {
+		if _, ok := a.notes.Find(entry.Filename, tagText); ok {
+			return noteBadge
+		}
+	}
+	return ""
+}
+
+// lists the notes by file, enter jumps to the noted file or element
+func addAndShowNotesPage(pages *tview.Pages, all []notes.Note, onSelect func(note notes.Note)) {
+	viewName := "notes"
+	list := tview.NewList()
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Notes (%d)", len(all))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(all) == 0 {
+		list.AddItem("No notes, add them with :note <text> on a file or element", "", 0, nil)
+	}
+	for _, note := range all {
+		target := strings.TrimSpace(note.File + " " + note.Tag + " " + note.Name)
+		list.AddItem(tview.Escape(target), "  "+tview.Escape(note.Text), 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(note)
+		})
+	}
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 30
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(list, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
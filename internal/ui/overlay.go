@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/overlay"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const overlayPreviewWidth = 110 // braille characters per line of the preview
+
+// handles ':overlays' listing the overlay planes of the current dataset and ':overlays export <file.png>' writing them
+// composited onto the first frame
+func (a *App) overlaysCommand(args []string) {
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	overlays, err := overlay.Decode(&entry.Dataset)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error decoding overlays of %s: %s", entry.Filename, err.Error()))
+		return
+	}
+	switch {
+	case len(args) == 0:
+		a.statusLine.SetText(fmt.Sprintf("%d overlays in %s", len(overlays), entry.Filename))
+		addAndShowOverlaysPage(a.pages, entry.Filename, overlays)
+	case len(args) == 2 && args[0] == "export":
+		if len(overlays) == 0 {
+			a.statusLine.SetText(fmt.Sprintf("%s has no overlays", entry.Filename))
+			return
+		}
+		img, err := overlay.Composite(&entry.Dataset, overlays)
+		if err == nil {
+			err = writePNG(args[1], img)
+		}
+		if err != nil {
+			a.statusLine.SetText(fmt.Sprintf("error exporting overlays: %s", err.Error()))
+			return
+		}
+		a.statusLine.SetText(fmt.Sprintf("exported %d overlays of %s to %s", len(overlays), entry.Filename, args[1]))
+	default:
+		a.statusLine.SetText("use :overlays or :overlays export <file.png>")
+	}
+}
+
+func writePNG(filename string, img *image.Gray) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, img)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// returns the line of an overlay with group, size, origin, type and the number of set pixels
+func overlayText(o overlay.Overlay) string {
+	name := strings.TrimSpace(o.Label + " " + o.Description)
+	if o.Embedded {
+		name = strings.TrimSpace(name + " (embedded)")
+	}
+	return fmt.Sprintf("%04x  %4dx%-4d  origin %d,%d  type %-2s  %3d frames  %7d pixels  %s", o.Group, o.Columns, o.Rows, o.Origin[0],
+		o.Origin[1], tview.Escape(o.Type), o.Frames, o.Count(), tview.Escape(name))
+}
+
+func addAndShowOverlaysPage(pages *tview.Pages, filename string, overlays []overlay.Overlay) {
+	viewName := "overlays"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Overlays of %s (%d)", filename, len(overlays))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(overlays) == 0 {
+		list.AddItem("No overlays", "", 0, nil)
+	}
+	for _, o := range overlays {
+		o := o
+		list.AddItem(overlayText(o), "", 0, func() {
+			addAndShowOverlayPreviewPage(pages, o)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
+
+// shows the braille preview of the first frame of the overlay on top of the list, scrollable with h, j, k and l
+func addAndShowOverlayPreviewPage(pages *tview.Pages, o overlay.Overlay) {
+	viewName := "overlay"
+	view := tview.NewTextView().SetWrap(false).SetText(strings.Join(o.Preview(overlayPreviewWidth), "\n"))
+	view.SetBorder(true).
+		SetTitle(fmt.Sprintf("Overlay %04x, %dx%d", o.Group, o.Columns, o.Rows)).
+		SetTitleAlign(tview.AlignCenter)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'h':
+				return tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone)
+			case 'l':
+				return tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	pages.AddPage(viewName, view, true, true)
+}
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+const (
+	detailsPane        = "details"
+	defaultPaneSize    = 40
+	minPaneSize        = 10
+	paneResizeStepSize = 5
+)
+
+// a pane right of the tree, resized and collapsed with the ctrl+w keys
+type pane struct {
+	name string
+	view *tview.TextView
+	config.Pane
+}
+
+func newDetailsPane() *pane {
+	view := tview.NewTextView().SetWrap(true)
+	view.SetBorder(true).SetTitle("Details")
+	return &pane{name: detailsPane, view: view, Pane: config.Pane{Size: defaultPaneSize, Collapsed: true}}
+}
+
+// returns the full values of the element one per line with tag, VR, value multiplicity and length
+func detailsText(e *dicom.Element, entry *dicomtree.Entry) string {
+	var charsets []string
+	header := fmt.Sprintf("%s %s\nVR %s, length %d", dicomtree.FormatTag(e.Tag), dicomtree.TagName(e), e.RawValueRepresentation, e.ValueLength)
+	if entry != nil {
+		charsets = charset.FromDataset(&entry.Dataset)
+		header += "\n" + entry.Filename
+	}
+	if dicomtree.IsSequence(e) {
+		return fmt.Sprintf("%s\n\n%d items", header, len(dicomtree.SequenceItems(e)))
+	}
+	values := dicomtree.ValueStrings(e)
+	if values == nil {
+		if e.Value == nil {
+			return header
+		}
+		return header + "\n\n" + e.Value.String()
+	}
+	lines := make([]string, 0, len(values))
+	for i, v := range values {
+		if charset.IsText(e) {
+			v = charset.Decode(v, charsets)
+		}
+		lines = append(lines, fmt.Sprintf("%d: %s", i+1, v))
+	}
+	return fmt.Sprintf("%s, VM %d\n\n%s", header, len(values), strings.Join(lines, "\n"))
+}
+
+// shows the details of the element of the current node, if the pane is shown
+func (a *App) updateDetailsPane() {
+	if a.details.Collapsed {
+		return
+	}
+	node := a.tree.GetCurrentNode()
+	if node == nil || !isTagNode(node) {
+		a.details.view.SetText("no element selected")
+		return
+	}
+	e := node.GetReference().(*dicom.Element)
+	a.details.view.SetText(detailsText(e, dicomtree.FindEntryByElement(a.entries, e))).ScrollToBeginning()
+}
+
+// lays out the tree and the shown panes
+func (a *App) layoutPanes() {
+	a.body.Clear().AddItem(a.tree, 0, 1, true)
+	if !a.details.Collapsed {
+		a.body.AddItem(a.details.view, a.details.Size, 0, false)
+	}
+}
+
+// handles the key following ctrl+w: + and > grow, - and < shrink the pane, = restores the default size and
+// c collapses or restores it. The sizes are saved in the config.
+func (a *App) handlePaneKey(r rune) {
+	p := a.details
+	switch r {
+	case '+', '>':
+		p.Size += paneResizeStepSize
+		p.Collapsed = false
+	case '-', '<':
+		p.Size = max(p.Size-paneResizeStepSize, minPaneSize)
+		p.Collapsed = false
+	case '=':
+		p.Size = defaultPaneSize
+		p.Collapsed = false
+	case 'c':
+		p.Collapsed = !p.Collapsed
+	default:
+		a.statusLine.SetText(fmt.Sprintf("unknown pane key '%c', use +, -, <, >, = or c", r))
+		return
+	}
+	a.layoutPanes()
+	status := fmt.Sprintf("%s pane width %d", p.name, p.Size)
+	if p.Collapsed {
+		status = p.name + " pane collapsed"
+	}
+	if a.config != nil {
+		if a.config.Panes == nil {
+			a.config.Panes = make(map[string]config.Pane)
+		}
+		a.config.Panes[p.name] = p.Pane
+		if err := config.Save(*a.config); err != nil {
+			status += fmt.Sprintf(" (error saving config: %s)", err.Error())
+		}
+	}
+	a.statusLine.SetText(status)
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// handles ':pin' pinning the element of the current node, ':pin <tags>' pinning the given tags, separated by commas or
+// spaces, and ':unpin [<tags>]' removing the given or all pins. The pin bar above the tree shows the values of the
+// pinned elements in the file of the current node.
+func (a *App) pinCommand(args string, pin bool) {
+	names := splitTagList(args)
+	if pin && len(names) == 0 {
+		node := a.tree.GetCurrentNode()
+		if node == nil || !isTagNode(node) {
+			a.statusLine.SetText("use :pin <tag>[,<tag>...] or :pin on an element")
+			return
+		}
+		names = []string{dicomtree.FormatTag(node.GetReference().(*dicom.Element).Tag)}
+	}
+	tags, err := parseTags(names)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	pins := slices.Clone(a.pins)
+	switch {
+	case pin:
+		for _, t := range tags {
+			if !slices.Contains(pins, t) {
+				pins = append(pins, t)
+			}
+		}
+	case len(tags) == 0:
+		pins = nil
+	default:
+		pins = slices.DeleteFunc(pins, func(t tag.Tag) bool { return slices.Contains(tags, t) })
+	}
+	a.setPins(pins)
+
+	status := "no elements pinned"
+	if len(pins) > 0 {
+		status = fmt.Sprintf("%d elements pinned: %s", len(pins), pinNames(pins))
+	}
+	if a.config != nil {
+		a.config.Pins = nil
+		for _, t := range pins {
+			a.config.Pins = append(a.config.Pins, dicomtree.FormatTag(t))
+		}
+		status = a.saveConfig(status)
+	}
+	a.statusLine.SetText(status)
+}
+
+// parses the tags given as keywords or "(gggg,eeee)"
+func parseTags(names []string) ([]tag.Tag, error) {
+	tags := make([]tag.Tag, 0, len(names))
+	for _, name := range names {
+		t, err := dicomtree.ParseTag(name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// sets the pinned elements, showing the pin bar only if there are any
+func (a *App) setPins(pins []tag.Tag) {
+	a.pins = pins
+	a.layoutMain()
+}
+
+// lays out the pin bar if elements are pinned, the body, the breadcrumb, the status line and the command line
+func (a *App) layoutMain() {
+	a.mainGrid.Clear()
+	row := 0
+	if len(a.pins) > 0 {
+		a.mainGrid.SetRows(1, -1, 1, 1, 1)
+		a.mainGrid.AddItem(a.pinBar, 0, 0, 1, 1, 0, 0, false)
+		row = 1
+	} else {
+		a.mainGrid.SetRows(-1, 1, 1, 1)
+	}
+	a.mainGrid.
+		AddItem(a.body, row, 0, 1, 1, 0, 0, true).
+		AddItem(a.breadcrumb, row+1, 0, 1, 1, 0, 0, false).
+		AddItem(a.statusLine, row+2, 0, 1, 1, 0, 0, false).
+		AddItem(a.cmdline, row+3, 0, 1, 1, 0, 0, false)
+}
+
+// shows the values of the pinned elements in the file of the current node, e.g. "a.dcm  SliceLocation 12.5 │ ..."
+func (a *App) updatePinBar() {
+	if len(a.pins) == 0 {
+		return
+	}
+	var entry *dicomtree.Entry
+	if node := a.tree.GetCurrentNode(); node != nil && node != a.tree.GetRoot() {
+		entry = findEntryForNode(a.tree, node, a.entries)
+	}
+	if entry == nil {
+		a.pinBar.SetText("[gray]pinned:[-] " + tview.Escape(pinNames(a.pins)))
+		return
+	}
+	charsets := charset.FromDataset(&entry.Dataset)
+	values := make([]string, 0, len(a.pins))
+	for _, t := range a.pins {
+		value := "-"
+		if e, err := entry.Dataset.FindElementByTag(t); err == nil {
+			value = dicomtree.ValueString(e, charsets, a.displayOptions)
+		}
+		values = append(values, fmt.Sprintf("[gray]%s[-] %s", tview.Escape(pinName(t)), tview.Escape(value)))
+	}
+	a.pinBar.SetText(tview.Escape(entry.Filename) + "  " + strings.Join(values, " │ "))
+}
+
+// returns the keyword of the tag, the tag if the dictionary doesn't know it
+func pinName(t tag.Tag) string {
+	if name := dicomtree.TagNameByTag(t); name != "" {
+		return name
+	}
+	return dicomtree.FormatTag(t)
+}
+
+func pinNames(pins []tag.Tag) string {
+	names := make([]string, 0, len(pins))
+	for _, t := range pins {
+		names = append(names, pinName(t))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// returns the changes the operation would make to the visible files, applied to copies of their datasets
+func (a *App) previewChanges(operation func(dataset *dicom.Dataset) error) ([]edit.Change, error) {
+	return a.previewChangesOf(a.visibleEntries(), operation)
+}
+
+// returns the changes the operation would make to the entries, applied to copies of their datasets
+func (a *App) previewChangesOf(entries []dicomtree.Entry, operation func(dataset *dicom.Dataset) error) ([]edit.Change, error) {
+	var changes []edit.Change
+	p := a.startProgress("previewing", len(entries))
+	for _, entry := range entries {
+		dataset, err := edit.CopyDataset(&entry.Dataset)
+		if err != nil {
+			p.finish()
+			return nil, fmt.Errorf("error copying %s: %w", entry.Filename, err)
+		}
+		if err := operation(&dataset); err != nil {
+			p.finish()
+			return nil, fmt.Errorf("error in %s: %w", entry.Filename, err)
+		}
+		changes = append(changes, edit.Diff(entry.Filename, edit.TakeSnapshot(&entry.Dataset), &dataset)...)
+		if err := p.step(); err != nil {
+			break
+		}
+	}
+	return changes, p.finish()
+}
+
+// shows the changes of the batch command before calling apply, which only happens after confirmation with y. With
+// --yes or without changes apply is called right away.
+func (a *App) confirmBatch(command string, preview func() ([]edit.Change, error), apply func()) {
+	if a.assumeYes {
+		apply()
+		return
+	}
+	changes, err := preview()
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if len(changes) == 0 {
+		apply()
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("%s would make %d changes in %d files, y applies them, n cancels", command, len(changes), changedFiles(changes)))
+	addAndShowPreviewPage(a.pages, command, changes, func(confirmed bool) {
+		a.app.SetFocus(a.tree)
+		if !confirmed {
+			a.statusLine.SetText(fmt.Sprintf("cancelled %s, no file changed", command))
+			return
+		}
+		apply()
+	})
+}
+
+func changedFiles(changes []edit.Change) int {
+	files := 0
+	for i, c := range changes {
+		if i == 0 || c.File != changes[i-1].File {
+			files++
+		}
+	}
+	return files
+}
+
+// returns the text of the preview page, the changes grouped by file
+func previewText(changes []edit.Change) string {
+	var text strings.Builder
+	for i, c := range changes {
+		if i == 0 || c.File != changes[i-1].File {
+			if i > 0 {
+				text.WriteString("\n")
+			}
+			fmt.Fprintf(&text, "%s\n", c.File)
+		}
+		fmt.Fprintf(&text, "    %s %s: '%s' -> '%s'\n", c.Tag, c.Name, c.OldValue, c.NewValue)
+	}
+	return text.String()
+}
+
+// shows the scrollable preview of the changes of a batch command, y confirms, n, q and escape cancel
+func addAndShowPreviewPage(pages *tview.Pages, command string, changes []edit.Change, onDone func(confirmed bool)) {
+	viewName := "preview"
+	view := tview.NewTextView().SetText(previewText(changes))
+	view.
+		SetTitle(fmt.Sprintf("Preview of %s (%d changes in %d files) - y: apply, n: cancel", command, len(changes), changedFiles(changes))).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	done := func(confirmed bool) {
+		pages.RemovePage(viewName)
+		onDone(confirmed)
+	}
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			done(false)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'y':
+				done(true)
+				return nil
+			case 'n', 'q':
+				done(false)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(view, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
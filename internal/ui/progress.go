@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	progressDelay    = 300 * time.Millisecond // operations finishing faster show no progress
+	progressInterval = 100 * time.Millisecond // minimum time between redraws of the progress
+)
+
+// returned by operations cancelled with escape while showing their progress
+var errCancelled = errors.New("cancelled")
+
+// screen of the viewer, swallows escape while a long operation runs in the event loop and cancels it instead. The
+// event loop can't handle keys until the operation returns, but tview polls the screen in its own goroutine.
+type cancelScreen struct {
+	tcell.Screen
+	running atomic.Pointer[progress]
+}
+
+func (s *cancelScreen) PollEvent() tcell.Event {
+	for {
+		event := s.Screen.PollEvent()
+		if key, ok := event.(*tcell.EventKey); ok && key.Key() == tcell.KeyEsc {
+			if p := s.running.Load(); p != nil {
+				p.cancelled.Store(true)
+				continue
+			}
+		}
+		return event
+	}
+}
+
+// progress of an operation over several files running in the event loop. After progressDelay a modal shows the
+// files done, the estimated time left and that escape cancels.
+type progress struct {
+	a         *App
+	name      string
+	total     int
+	done      int
+	start     time.Time
+	lastDraw  time.Time
+	shown     bool
+	cancelled atomic.Bool
+}
+
+// starts the progress of the operation over total files, finish has to be called when it ends
+func (a *App) startProgress(name string, total int) *progress {
+	p := &progress{a: a, name: name, total: total, start: time.Now()}
+	a.screen.running.Store(p)
+	return p
+}
+
+// counts a file as done and redraws the progress if due, returns errCancelled if escape was pressed
+func (p *progress) step() error {
+	p.done++
+	if p.isCancelled() {
+		return errCancelled
+	}
+	now := time.Now()
+	if now.Sub(p.start) < progressDelay || now.Sub(p.lastDraw) < progressInterval {
+		return nil
+	}
+	p.lastDraw = now
+	modal := tview.NewModal().SetText(progressText(p.name, p.done, p.total, now.Sub(p.start)))
+	p.a.pages.AddPage("progress", modal, true, true)
+	p.shown = true
+	p.a.app.ForceDraw()
+	return nil
+}
+
+// ends the progress and hides it, returns the error for a cancelled operation, e.g. "anonymizing cancelled after 3 of
+// 10 files"
+func (p *progress) finish() error {
+	p.a.screen.running.CompareAndSwap(p, nil)
+	if p.shown {
+		p.a.pages.RemovePage("progress")
+	}
+	if p.isCancelled() {
+		return fmt.Errorf("%s %w after %d of %d files", p.name, errCancelled, p.done, p.total)
+	}
+	return nil
+}
+
+// an operation is only cancelled if files were left when escape was pressed
+func (p *progress) isCancelled() bool {
+	return p.cancelled.Load() && p.done < p.total
+}
+
+// returns the text of the progress modal, e.g. "loading\n\n40 of 100 files\nabout 12s left\n\nescape cancels"
+func progressText(name string, done, total int, elapsed time.Duration) string {
+	left := "estimating time left"
+	if done > 0 {
+		remaining := elapsed * time.Duration(total-done) / time.Duration(done)
+		left = fmt.Sprintf("about %s left", remaining.Round(time.Second))
+	}
+	return fmt.Sprintf("%s\n\n%d of %d files\n%s\n\nescape cancels", name, done, total, left)
+}
@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// the files a value is propagated to
+type propagationScope struct {
+	name  string
+	paths map[string]bool
+}
+
+// handles ':propagate [<tag>]' guiding through copying a value of the tag, the current element without one, to other
+// files: pick the source value of the current file, another file or a typed one, pick the series, study, filtered or
+// all files and apply it after a preview
+func (a *App) propagateCommand(args string) {
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected")
+		return
+	}
+	var t tag.Tag
+	if args != "" {
+		parsed, err := dicomtree.ParseTag(args)
+		if err != nil {
+			a.statusLine.SetText(fmt.Sprintf("%s, use :propagate <tag>", err.Error()))
+			return
+		}
+		t = parsed
+	} else if e, ok := a.tree.GetCurrentNode().GetReference().(*dicom.Element); ok && dicomtree.FindItemElement(entry.Dataset.Elements, e.Tag) == e {
+		t = e.Tag
+	} else {
+		a.statusLine.SetText("use :propagate <tag> or select a top level element")
+		return
+	}
+	if err := a.protected.Check(t); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if e := dicomtree.FindItemElement(entry.Dataset.Elements, t); e != nil && dicomtree.IsSequence(e) {
+		a.statusLine.SetText(fmt.Sprintf("%s is a sequence, only values can be propagated", dicomtree.FormatTag(t)))
+		return
+	}
+	name := strings.TrimSpace(dicomtree.TagNameByTag(t) + " " + dicomtree.FormatTag(t))
+	a.statusLine.SetText(fmt.Sprintf("propagate %s: pick the value", name))
+	addAndShowPropagationValuePage(a.pages, name, a.propagationValues(entry, t), func(value string) {
+		scopes := a.propagationScopes(entry)
+		a.statusLine.SetText(fmt.Sprintf("propagate %s '%s': pick the files", name, value))
+		addAndShowPropagationScopePage(a.pages, name, value, scopes, func(scope propagationScope) {
+			a.app.SetFocus(a.tree)
+			a.propagate(t, name, value, scope)
+		})
+	})
+}
+
+// returns the values of the tag to pick from: the one of the current file first, then the others by frequency
+func (a *App) propagationValues(entry *dicomtree.Entry, t tag.Tag) []string {
+	var values []string
+	if e := dicomtree.FindItemElement(entry.Dataset.Elements, t); e != nil {
+		values = append(values, dicomtree.ValueText(e, charset.FromDataset(&entry.Dataset)))
+	}
+	frequencies, _ := dicomtree.ValueFrequencies(a.visibleEntries(), t)
+	for _, f := range frequencies {
+		if len(values) == 0 || f.Value != values[0] {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// returns the series and study of the entry, the filtered files while a filter is active and all files
+func (a *App) propagationScopes(entry *dicomtree.Entry) []propagationScope {
+	sameValue := func(t tag.Tag, scope string) propagationScope {
+		uid := dicomtree.UIDValue(&entry.Dataset, t)
+		paths := make(map[string]bool)
+		for i := range a.entries {
+			if uid != "" && dicomtree.UIDValue(&a.entries[i].Dataset, t) == uid || a.entries[i].Path == entry.Path {
+				paths[a.entries[i].Path] = true
+			}
+		}
+		return propagationScope{fmt.Sprintf("%s of %s", scope, entry.Filename), paths}
+	}
+	scopes := []propagationScope{sameValue(tag.SeriesInstanceUID, "series"), sameValue(tag.StudyInstanceUID, "study")}
+	if a.filterPaths != nil {
+		scopes = append(scopes, propagationScope{"files matching the filter", a.filterPaths})
+	}
+	all := make(map[string]bool, len(a.entries))
+	for _, e := range a.entries {
+		all[e.Path] = true
+	}
+	return append(scopes, propagationScope{"all files", all})
+}
+
+// sets the value in the files of the scope after a preview of the changes
+func (a *App) propagate(t tag.Tag, name, value string, scope propagationScope) {
+	set := func(dataset *dicom.Dataset) error {
+		values := strings.Split(value, "\\")
+		e, err := edit.Set(dataset, t, values)
+		if err != nil {
+			return err
+		}
+		return edit.SetStrings(e, values, charset.FromDataset(dataset))
+	}
+	var entries []dicomtree.Entry
+	for _, entry := range a.entries {
+		if scope.paths[entry.Path] {
+			entries = append(entries, entry)
+		}
+	}
+	preview := func() ([]edit.Change, error) { return a.previewChangesOf(entries, set) }
+	a.confirmBatch(":propagate", preview, func() {
+		changed := 0
+		for i := range a.entries {
+			entry := &a.entries[i]
+			if !scope.paths[entry.Path] {
+				continue
+			}
+			before := edit.TakeSnapshot(&entry.Dataset)
+			err := set(&entry.Dataset)
+			if a.changes.Record(entry.Filename, before, &entry.Dataset) > 0 {
+				changed++
+			}
+			if err != nil {
+				a.refreshTree()
+				a.statusLine.SetText(fmt.Sprintf("error setting %s in %s: %s", name, entry.Filename, err.Error()))
+				return
+			}
+		}
+		a.refreshTree()
+		a.statusLine.SetText(fmt.Sprintf("set %s to '%s' in %d of %d files of the %s, save with :w", name, value, changed,
+			len(scope.paths), scope.name))
+	})
+}
+
+// shows the values to propagate and an entry to type one, onPick is called with the picked value
+func addAndShowPropagationValuePage(pages *tview.Pages, name string, values []string, onPick func(value string)) {
+	viewName := "propagate"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Propagate %s - 1/3: value", name)).
+		SetTitleAlign(tview.AlignCenter)
+	for i, value := range values {
+		value := value
+		text := "'" + tview.Escape(value) + "'"
+		if i == 0 {
+			text += " (current file)"
+		}
+		list.AddItem(text, "", 0, func() {
+			pages.RemovePage(viewName)
+			onPick(value)
+		})
+	}
+	list.AddItem("type a value...", "", 0, func() {
+		pages.RemovePage(viewName)
+		addAndShowPropagationInputPage(pages, name, onPick)
+	})
+	showListPage(pages, viewName, list)
+}
+
+// shows an input for the value, enter picks it, escape cancels
+func addAndShowPropagationInputPage(pages *tview.Pages, name string, onPick func(value string)) {
+	viewName := "propagate-input"
+	input := tview.NewInputField().
+		SetLabel("Value: ").
+		SetFieldBackgroundColor(tcell.ColorDarkBlue)
+	input.SetBorder(true).
+		SetTitle(fmt.Sprintf("Propagate %s - 1/3: value, multiple values separated by \\", name)).
+		SetTitleAlign(tview.AlignCenter)
+	input.SetDoneFunc(func(key tcell.Key) {
+		pages.RemovePage(viewName)
+		if key == tcell.KeyEnter {
+			onPick(input.GetText())
+		}
+	})
+	grid := tview.NewGrid().
+		SetColumns(0, 80, 0).
+		SetRows(0, 3, 0).
+		AddItem(input, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
+
+// shows the scopes with their number of files, onPick is called with the picked one
+func addAndShowPropagationScopePage(pages *tview.Pages, name, value string, scopes []propagationScope, onPick func(scope propagationScope)) {
+	viewName := "propagate"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Propagate %s '%s' - 2/3: files, 3/3 previews the changes", name, tview.Escape(value))).
+		SetTitleAlign(tview.AlignCenter)
+	for _, scope := range scopes {
+		scope := scope
+		list.AddItem(fmt.Sprintf("%s (%d files)", scope.name, len(scope.paths)), "", 0, func() {
+			pages.RemovePage(viewName)
+			onPick(scope)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
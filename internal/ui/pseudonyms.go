@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/anon"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// handles ':pseudonymize', ':pseudonyms' and ':pseudonyms import <file.csv>'
+func (a *App) pseudonymsCommand(command string, args []string) {
+	pseudonyms, err := a.loadPseudonyms()
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error loading pseudonyms: %s", err.Error()))
+		return
+	}
+	switch {
+	case command == ":pseudonymize" && len(args) == 0:
+		a.pseudonymize(pseudonyms)
+	case command == ":pseudonyms" && len(args) == 0:
+		a.statusLine.SetText(fmt.Sprintf("%d pseudonyms in %s", len(pseudonyms.All), pseudonyms.Path()))
+		addAndShowPseudonymsPage(a.pages, pseudonyms.All)
+	case command == ":pseudonyms" && len(args) == 2 && args[0] == "import":
+		file, err := os.Open(args[1])
+		if err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+		defer file.Close()
+		added, err := pseudonyms.Import(file)
+		if err == nil {
+			err = pseudonyms.Save()
+		}
+		if err != nil {
+			a.statusLine.SetText(fmt.Sprintf("error importing %s: %s", args[1], err.Error()))
+			return
+		}
+		a.statusLine.SetText(fmt.Sprintf("imported %d pseudonyms to %s", added, pseudonyms.Path()))
+	default:
+		a.statusLine.SetText("usage: :pseudonymize, :pseudonyms or :pseudonyms import <file.csv>")
+	}
+}
+
+func (a *App) loadPseudonyms() (*anon.Pseudonyms, error) {
+	path := a.pseudonyms
+	if path == "" {
+		var err error
+		if path, err = config.PseudonymsPath(); err != nil {
+			return nil, err
+		}
+	}
+	return anon.LoadPseudonyms(path)
+}
+
+// replaces PatientID and PatientName of all visible files with their pseudonyms after the preview and saves new
+// pseudonyms to the mapping file right away, so they are kept even if the files are not written
+func (a *App) pseudonymize(pseudonyms *anon.Pseudonyms) {
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	before := len(pseudonyms.All)
+	preview := func() ([]edit.Change, error) {
+		return a.previewChanges(func(dataset *dicom.Dataset) error {
+			_, err := pseudonyms.Apply(dataset) // assigns the same pseudonyms as applying them after confirmation
+			return err
+		})
+	}
+	a.confirmBatch(":pseudonymize", preview, func() {
+		files := 0
+		var applyErr error
+		p := a.startProgress("pseudonymizing", len(a.visibleEntries()))
+		for i := range a.entries {
+			entry := &a.entries[i]
+			if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+				continue
+			}
+			snapshot := edit.TakeSnapshot(&entry.Dataset)
+			changed, err := pseudonyms.Apply(&entry.Dataset)
+			a.changes.Record(entry.Filename, snapshot, &entry.Dataset)
+			if err != nil {
+				applyErr = fmt.Errorf("error pseudonymizing %s: %w", entry.Filename, err)
+				break
+			}
+			if changed {
+				files++
+			}
+			if p.step() != nil {
+				break
+			}
+		}
+		if err := p.finish(); err != nil && applyErr == nil {
+			applyErr = err
+		}
+		if err := pseudonyms.Save(); err != nil && applyErr == nil {
+			applyErr = fmt.Errorf("error saving %s: %w", pseudonyms.Path(), err)
+		}
+		a.refreshTree()
+		if applyErr != nil {
+			a.statusLine.SetText(applyErr.Error())
+			return
+		}
+		a.statusLine.SetText(fmt.Sprintf("pseudonymized %d files, %d new patients in %s, save with :w", files, len(pseudonyms.All)-before, pseudonyms.Path()))
+	})
+}
+
+func addAndShowPseudonymsPage(pages *tview.Pages, pseudonyms []anon.Pseudonym) {
+	viewName := "pseudonyms"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Pseudonyms (%d)", len(pseudonyms))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(pseudonyms) == 0 {
+		list.AddItem("No pseudonyms yet, assign them with :pseudonymize", "", 0, nil)
+	}
+	for _, p := range pseudonyms {
+		list.AddItem(tview.Escape(fmt.Sprintf("%-14s %-16s <- %s %s", p.ID, p.Name, p.OriginalID, p.OriginalName)), "", 0, nil)
+	}
+	showListPage(pages, viewName, list)
+}
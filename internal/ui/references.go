@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// returns the entry the reference resolves to after loading all entries, nil if there is none
+func (a *App) resolveReference(r dicomtree.Reference, referencing *dicomtree.Entry) *dicomtree.Entry {
+	if r.UID == "" {
+		return nil
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+	}
+	return dicomtree.ResolveReference(a.entries, r, referencing)
+}
+
+// jumps to the element identifying the object referenced by the current element or sequence item, e.g. the
+// SOP instance UID of the file a referenced SOP instance UID refers to
+func (a *App) followReference(node *tview.TreeNode) {
+	var r dicomtree.Reference
+	var ok bool
+	switch reference := node.GetReference().(type) {
+	case *dicom.Element:
+		r, ok = dicomtree.ElementReference(reference)
+	case *dicomtree.SequenceItem:
+		if references := dicomtree.References(reference.Elements()); len(references) > 0 {
+			r, ok = references[0], true
+		}
+	}
+	if !ok {
+		a.statusLine.SetText("no reference to another object at the cursor")
+		return
+	}
+	referencing := findEntryForNode(a.tree, node, a.entries)
+	target := a.resolveReference(r, referencing)
+	if target == nil {
+		a.statusLine.SetText(fmt.Sprintf("referenced %s %s is not loaded", r.Kind(), r.UID))
+		return
+	}
+	a.jumpToEntryElement(target, r.Target)
+	a.statusLine.SetText(fmt.Sprintf("followed referenced %s %s to %s", r.Kind(), r.UID, target.Filename))
+}
+
+// selects the node of the top level element with the tag of the entry, the file node if the tree doesn't show it
+func (a *App) jumpToEntryElement(entry *dicomtree.Entry, t tag.Tag) {
+	a.sortByFileIfByTag()
+	if e, err := entry.Dataset.FindElementByTag(t); err == nil && jumpToElementNode(a.tree, e) {
+		return
+	}
+	jumpToFileNode(a.tree, entry.Filename)
+}
+
+// rebuilds the tree sorted by filename if sorted by tag, so the elements of a file are below its file node
+func (a *App) sortByFileIfByTag() {
+	if a.sortMode == 2 || a.sortMode == 3 {
+		a.sortMode = 1
+		a.buildTree()
+	}
+}
+
+// lists the references of all files to objects that are not loaded, selecting one jumps to the referencing element
+func (a *App) showDanglingReferences() {
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	dangling, total := dicomtree.DanglingReferences(a.entries)
+	a.statusLine.SetText(fmt.Sprintf("%d of %d references point to objects that are not loaded", len(dangling), total))
+	addAndShowDanglingReferencesPage(a.pages, dangling, func(d dicomtree.DanglingReference) {
+		a.sortByFileIfByTag()
+		if !jumpToElementNode(a.tree, d.Element) {
+			jumpToFileNode(a.tree, d.Entry.Filename)
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
+
+func addAndShowDanglingReferencesPage(pages *tview.Pages, dangling []dicomtree.DanglingReference, onSelect func(d dicomtree.DanglingReference)) {
+	viewName := "references"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Dangling references (%d)", len(dangling))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(dangling) == 0 {
+		list.AddItem("No dangling references", "", 0, nil)
+	}
+	for _, d := range dangling {
+		d := d
+		text := fmt.Sprintf("%s  %s -> %s %s", d.Entry.Filename, dicomtree.FormatTag(d.Element.Tag), d.Kind(), d.UID)
+		list.AddItem(tview.Escape(text), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(d)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
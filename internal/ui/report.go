@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/report"
+)
+
+// handles ':report <dataset|diff|validation|audit> <file.html|file.md> [template]' writing the dataset or the
+// validation of the current file, the differences between all visible files or their de-identification audit, with
+// the built-in or the given template
+func (a *App) reportCommand(args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		a.statusLine.SetText("usage: :report <dataset|diff|validation|audit> <file.html|file.md> [template]")
+		return
+	}
+	format, err := report.FormatOf(args[1])
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	templateFile := ""
+	if len(args) == 3 {
+		templateFile = args[2]
+	}
+	var r report.Report
+	switch report.Kind(args[0]) {
+	case report.KindDataset:
+		entry := a.currentRTEntry()
+		if entry == nil {
+			return
+		}
+		r = report.Datasets([]dicomtree.Entry{*entry})
+	case report.KindValidation:
+		entry := a.currentRTEntry()
+		if entry == nil {
+			return
+		}
+		r = report.Validations([]dicomtree.Entry{*entry})
+	case report.KindAudit:
+		if err := a.loadAllEntries(); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+		r = report.Audits(a.visibleEntries())
+	case report.KindDiff:
+		if err := a.loadAllEntries(); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+		entries := a.visibleEntries()
+		if len(entries) < 2 {
+			a.statusLine.SetText("the diff report needs at least two files")
+			return
+		}
+		r = report.Diff(entries)
+	default:
+		a.statusLine.SetText(fmt.Sprintf("unknown report '%s', use dataset, diff, validation or audit", args[0]))
+		return
+	}
+
+	file, err := os.Create(args[1])
+	if err == nil {
+		err = report.Write(file, r, format, templateFile)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error writing %s: %s", args[1], err.Error()))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("wrote %s to %s", r.Title, args[1]))
+}
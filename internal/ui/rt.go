@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/rt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// returns the parsed entry of the current node, nil with the error in the status line if there is none
+func (a *App) currentRTEntry() *dicomtree.Entry {
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected")
+		return nil
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return nil
+	}
+	return entry
+}
+
+// shows the summary of the current RT plan or dose, the ROIs of a structure set, selecting a line jumps to its element
+func (a *App) showRTSummary() {
+	entry := a.currentRTEntry()
+	if entry == nil {
+		return
+	}
+	var title string
+	var lines []rt.Line
+	switch {
+	case rt.IsStructureSet(&entry.Dataset):
+		a.showStructureSet()
+		return
+	case rt.IsPlan(&entry.Dataset):
+		title, lines = "RT plan "+entry.Filename, rt.PlanSummary(&entry.Dataset).Lines()
+	case rt.IsDose(&entry.Dataset):
+		title, lines = "RT dose "+entry.Filename, rt.DoseSummary(&entry.Dataset).Lines()
+	default:
+		a.statusLine.SetText(fmt.Sprintf("%s is no RT plan, dose or structure set", entry.Filename))
+		return
+	}
+	a.statusLine.SetText(title)
+	addAndShowRTSummaryPage(a.pages, title, lines, func(line rt.Line) {
+		if line.Item >= 0 {
+			jumpToItemNode(a.tree, line.Element, line.Item)
+		} else if line.Element != nil {
+			jumpToElementNode(a.tree, line.Element)
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// lists the ROIs of the current RT structure set, selecting one jumps to its item of the structure set ROI sequence
+func (a *App) showStructureSet() {
+	entry := a.currentRTEntry()
+	if entry == nil {
+		return
+	}
+	if !rt.IsStructureSet(&entry.Dataset) {
+		a.statusLine.SetText(fmt.Sprintf("%s is no RT structure set", entry.Filename))
+		return
+	}
+	rois := rt.StructureSetROIs(&entry.Dataset)
+	a.statusLine.SetText(fmt.Sprintf("%d ROIs in %s referencing %d series", len(rois), entry.Filename, len(rt.ReferencedSeries(rois))))
+	addAndShowROIsPage(a.pages, entry.Filename, rois, func(roi rt.ROI) {
+		if e, err := entry.Dataset.FindElementByTag(tag.StructureSetROISequence); err == nil {
+			jumpToItemNode(a.tree, e, roi.Item)
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// returns the line of an ROI with a swatch of its display color
+func roiText(roi rt.ROI) string {
+	swatch := " "
+	if roi.Color != "" {
+		swatch = "[" + roi.Color + "]■[-]"
+	}
+	series := "no referenced series"
+	if len(roi.ReferencedSeries) > 0 {
+		series = "series " + strings.Join(roi.ReferencedSeries, ", ")
+	}
+	return fmt.Sprintf("%s %4s  %-24s  %-10s  %4d contours  %6d points  %s", swatch, tview.Escape(roi.Number), tview.Escape(roi.Name),
+		tview.Escape(roi.Type), roi.ContourCount, roi.PointCount, series)
+}
+
+func addAndShowRTSummaryPage(pages *tview.Pages, title string, lines []rt.Line, onSelect func(line rt.Line)) {
+	viewName := "rt"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignCenter)
+	for _, line := range lines {
+		line := line
+		list.AddItem(tview.Escape(line.Text), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(line)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
+
+func addAndShowROIsPage(pages *tview.Pages, filename string, rois []rt.ROI, onSelect func(roi rt.ROI)) {
+	viewName := "rois"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("ROIs of %s (%d)", filename, len(rois))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(rois) == 0 {
+		list.AddItem("No ROIs", "", 0, nil)
+	}
+	for _, roi := range rois {
+		roi := roi
+		list.AddItem(roiText(roi), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(roi)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
+
+// shows the list centered over the main page, esc and q close it, j and k move like in the tree
+func showListPage(pages *tview.Pages, viewName string, list *tview.List) {
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 30
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(list, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
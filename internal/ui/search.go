@@ -0,0 +1,220 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// delay after the last change of the search text before matching starts
+const searchDelay = 150 * time.Millisecond
+
+// number of confirmed searches kept for recalling them with up and down
+const maxSearchHistory = 100
+
+// color of the nodes matching the current search text
+const searchHighlightColor = tcell.ColorYellow
+
+// node of the tree with its lowercase text, see treeModel.searchItems
+type searchItem struct {
+	node *tview.TreeNode
+	text string
+}
+
+// matches the search text in a background goroutine against a snapshot of the node texts. Fast consecutive
+// changes are debounced and a new search cancels the running one. All methods have to be called from the
+// event loop, results are applied there as well.
+type searcher struct {
+	delay      time.Duration
+	queue      func(func()) // runs a function in the event loop
+	draw       func()       // redraws from within the event loop
+	timer      *time.Timer
+	cancel     context.CancelFunc
+	generation int // incremented per search, results of older searches are dropped
+}
+
+// starts matching after the delay unless another search is started before, apply is called with the matches
+func (s *searcher) schedule(items []searchItem, text string, apply func(matches []*tview.TreeNode)) {
+	s.stop()
+	generation := s.generation
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.timer = time.AfterFunc(s.delay, func() {
+		matches, ok := matchSearchItems(ctx, items, text)
+		if !ok {
+			return
+		}
+		s.queue(func() {
+			if generation != s.generation {
+				return // outdated
+			}
+			apply(matches)
+			s.draw()
+		})
+	})
+}
+
+// matches immediately in the event loop, e.g. when the search is confirmed before the results arrived
+func (s *searcher) run(items []searchItem, text string, apply func(matches []*tview.TreeNode)) {
+	s.stop()
+	matches, _ := matchSearchItems(context.Background(), items, text)
+	apply(matches)
+}
+
+// cancels the pending or running search and drops its results
+func (s *searcher) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.generation++
+}
+
+// returns the nodes whose text contains the lowercase text, false if canceled
+func matchSearchItems(ctx context.Context, items []searchItem, text string) ([]*tview.TreeNode, bool) {
+	matches := make([]*tview.TreeNode, 0)
+	for i, item := range items {
+		if i%1024 == 0 && ctx.Err() != nil {
+			return nil, false
+		}
+		if strings.Contains(item.text, text) {
+			matches = append(matches, item.node)
+		}
+	}
+	return matches, true
+}
+
+// view of the tree when an incremental search started, restored when it is canceled
+type searchView struct {
+	state  TreeState
+	offset int // vertical scroll offset
+}
+
+// remembers the view of the tree before typing a search
+func (a *App) startSearch() {
+	a.searchStart = &searchView{captureTreeState(a.tree), a.tree.GetScrollOffset()}
+	a.historyPosition = len(a.searchHistory)
+}
+
+// restores the view of the tree before the search when it is canceled with esc, n and N continue with the last
+// confirmed search
+func (a *App) cancelSearch() {
+	a.searchText = a.confirmedSearch
+	if a.searchStart != nil {
+		restoreTreeState(a.tree, a.searchStart.state)
+		a.tree.scrollTo(a.searchStart.offset)
+		a.searchStart = nil
+	}
+}
+
+// schedules the search for the text of the commandline
+func (a *App) scheduleSearch(text string) {
+	a.searchText = strings.ToLower(text)
+	a.searcher.schedule(a.tree.searchItems(), a.searchText, a.applySearchMatches)
+}
+
+// searches the text like / after loading all files, so the cursor is on the first matching element
+func (a *App) SetSearch(text string) *App {
+	err := a.loadAllEntries()
+	a.searchText = strings.ToLower(text)
+	a.confirmedSearch = a.searchText
+	a.searcher.run(a.tree.searchItems(), a.searchText, a.applySearchMatches)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+	}
+	return a
+}
+
+// runs the search for the current search text now if its results are not applied yet
+func (a *App) finishSearch() {
+	if a.appliedSearchText != a.searchText {
+		a.searcher.run(a.tree.searchItems(), a.searchText, a.applySearchMatches)
+	}
+}
+
+// highlights the matches, expands the path to the first match at or after the node the search started on, or the
+// first match, and centers it. While typing, each search starts from the view before the search, so only the path
+// to the current match is expanded.
+func (a *App) applySearchMatches(matches []*tview.TreeNode) {
+	a.clearSearchHighlights()
+	a.appliedSearchText = a.searchText
+	a.highlightedNodes = matches
+	for _, node := range matches {
+		node.SetColor(searchHighlightColor)
+	}
+	a.statusLine.SetText(fmt.Sprintf("found %d nodes matching '%s'", len(matches), a.searchText))
+	if a.searchStart != nil {
+		restoreTreeState(a.tree, a.searchStart.state)
+		a.tree.scrollTo(a.searchStart.offset)
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	order := a.tree.searchOrder()
+	current := order[a.tree.GetCurrentNode()]
+	target := matches[0]
+	for _, node := range matches {
+		if order[node] >= current {
+			target = node
+			break
+		}
+	}
+	expandPathToNode(a.tree, a.tree.parent(target))
+	a.tree.SetCurrentNode(target)
+	a.tree.centerNode(target)
+}
+
+// highlights the matches of the search text in a rebuilt tree without moving the cursor
+func (a *App) highlightSearchMatches() {
+	matches, _ := matchSearchItems(context.Background(), a.tree.searchItems(), a.searchText)
+	for _, node := range matches {
+		node.SetColor(searchHighlightColor)
+	}
+	a.highlightedNodes, a.appliedSearchText = matches, a.searchText
+}
+
+// adds the confirmed search to the end of the history, a repeated search is moved there
+func (a *App) addSearchHistory(text string) {
+	for i, previous := range a.searchHistory {
+		if previous == text {
+			a.searchHistory = append(a.searchHistory[:i], a.searchHistory[i+1:]...)
+			break
+		}
+	}
+	a.searchHistory = append(a.searchHistory, text)
+	if len(a.searchHistory) > maxSearchHistory {
+		a.searchHistory = a.searchHistory[len(a.searchHistory)-maxSearchHistory:]
+	}
+	a.historyPosition = len(a.searchHistory)
+}
+
+// shows an older (negative offset) or newer search of the history in the search prompt, after the newest the prompt
+// is empty again
+func (a *App) recallSearch(offset int) {
+	position := a.historyPosition + offset
+	if position < 0 || position > len(a.searchHistory) {
+		return
+	}
+	a.historyPosition = position
+	if position == len(a.searchHistory) {
+		a.cmdline.SetText("/")
+	} else {
+		a.cmdline.SetText("/" + a.searchHistory[position])
+	}
+}
+
+// resets the color of the nodes highlighted by the last search
+func (a *App) clearSearchHighlights() {
+	for _, node := range a.highlightedNodes {
+		node.SetColor(tview.Styles.PrimaryTextColor)
+	}
+	a.highlightedNodes = nil
+	a.appliedSearchText = ""
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchSearchItems(t *testing.T) {
+	assert := assert.New(t)
+
+	a, b := tview.NewTreeNode("A"), tview.NewTreeNode("B")
+	items := []searchItem{{a, "doe^john"}, {b, "doe^jane"}}
+	matches, ok := matchSearchItems(context.Background(), items, "jane")
+	assert.True(ok)
+	assert.Equal([]*tview.TreeNode{b}, matches)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok = matchSearchItems(ctx, items, "doe")
+	assert.False(ok, "canceled")
+}
+
+func TestSearcherDebounce(t *testing.T) {
+	assert := assert.New(t)
+
+	queued := make(chan func(), 4)
+	s := &searcher{delay: 20 * time.Millisecond, queue: func(f func()) { queued <- f }, draw: func() {}}
+	node := tview.NewTreeNode("doe^jane")
+	items := []searchItem{{node, "doe^jane"}}
+
+	var applied [][]*tview.TreeNode
+	apply := func(matches []*tview.TreeNode) { applied = append(applied, matches) }
+	s.schedule(items, "doe", apply)
+	s.schedule(items, "john", apply)
+	s.schedule(items, "jane", apply)
+
+	for len(applied) == 0 {
+		select {
+		case f := <-queued:
+			f() // results of the earlier searches are dropped if they ran at all
+		case <-time.After(harnessTimeout):
+			t.Fatal("timeout waiting for search")
+		}
+	}
+	assert.Equal([][]*tview.TreeNode{{node}}, applied, "only the last search runs")
+
+	s.schedule(items, "doe", apply)
+	time.Sleep(50 * time.Millisecond)
+	s.stop()
+	for len(queued) > 0 {
+		(<-queued)()
+	}
+	assert.Len(applied, 1, "results of stopped searches are dropped")
+}
@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/seg"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const (
+	segmentPreviewWidth = 110          // characters per line of the segment preview
+	segmentShades       = " .:-=+*#%@" // from dark to bright
+)
+
+// shows the segments of the current segmentation, selecting one previews its frames or jumps to its item if it has none
+func (a *App) showSegments() {
+	entry := a.currentRTEntry()
+	if entry == nil {
+		return
+	}
+	if !seg.IsSegmentation(&entry.Dataset) {
+		a.statusLine.SetText(fmt.Sprintf("%s is no segmentation", entry.Filename))
+		return
+	}
+	segments := seg.Segments(&entry.Dataset)
+	a.statusLine.SetText(fmt.Sprintf("%d segments in %s", len(segments), entry.Filename))
+	addAndShowSegmentsPage(a.pages, entry.Filename, segments, func(segment seg.Segment) {
+		if len(segment.Frames) == 0 {
+			a.pages.RemovePage("segments")
+			if e, err := entry.Dataset.FindElementByTag(tag.SegmentSequence); err == nil {
+				jumpToItemNode(a.tree, e, segment.Item)
+			}
+			a.app.SetFocus(a.tree)
+			return
+		}
+		addAndShowSegmentPreviewPage(a.pages, segment, func(frame int) (string, string) {
+			return a.segmentFrameText(entry, segment, frame)
+		})
+	})
+}
+
+// returns the title and preview of the frame of the segment, on the referenced image if it is among the entries
+func (a *App) segmentFrameText(entry *dicomtree.Entry, segment seg.Segment, frame int) (string, string) {
+	title := fmt.Sprintf("Segment %s %s, frame %d of %d", segment.Number, segment.Label, frame+1, len(segment.Frames))
+	mask, columns, rows, err := seg.Mask(&entry.Dataset, segment.Frames[frame])
+	if err != nil {
+		return title, "error reading frame: " + tview.Escape(err.Error())
+	}
+	var image []int
+	if source := a.resolveReference(dicomtree.Reference{Target: tag.SOPInstanceUID, UID: segment.Instances[frame]}, entry); source != nil {
+		samples, sourceColumns, sourceRows, err := seg.Frame(&source.Dataset, 0)
+		if err == nil && sourceColumns == columns && sourceRows == rows {
+			image = samples
+			title += " on " + source.Filename
+		}
+	}
+	return title, segmentPreview(mask, image, columns, rows, segmentPreviewWidth)
+}
+
+// renders the mask with a character per block of pixels of at most the given width, blocks are twice as high as wide
+// to keep the aspect ratio. Blocks of the mask are red, the others are shaded by the mean of the image if given.
+func segmentPreview(mask []bool, image []int, columns, rows, width int) string {
+	if columns <= 0 || rows <= 0 || width <= 0 {
+		return ""
+	}
+	scale := (columns + width - 1) / width
+	low, high := 0, 0
+	for i, s := range image {
+		if i == 0 || s < low {
+			low = s
+		}
+		if i == 0 || s > high {
+			high = s
+		}
+	}
+	var text strings.Builder
+	for y := 0; y < rows; y += 2 * scale {
+		for x := 0; x < columns; x += scale {
+			set, sum, count := false, 0, 0
+			for by := y; by < min(y+2*scale, rows); by++ {
+				for bx := x; bx < min(x+scale, columns); bx++ {
+					set = set || mask[by*columns+bx]
+					if image != nil {
+						sum += image[by*columns+bx]
+					}
+					count++
+				}
+			}
+			shade := byte(' ')
+			if image != nil && high > low {
+				shade = segmentShades[(sum/count-low)*(len(segmentShades)-1)/(high-low)]
+			}
+			switch {
+			case set && image == nil:
+				text.WriteString("[red]█[-]")
+			case set:
+				text.WriteString("[:red]" + tview.Escape(string(shade)) + "[:-]")
+			default:
+				text.WriteString(tview.Escape(string(shade)))
+			}
+		}
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+// returns the line of a segment with its codes, algorithm and number of frames
+func segmentText(segment seg.Segment) string {
+	algorithm := strings.TrimSpace(segment.AlgorithmType + " " + segment.AlgorithmName)
+	return fmt.Sprintf("%4s  %-20s  %-16s  %-20s  %-20s  %4d frames", tview.Escape(segment.Number), tview.Escape(segment.Label),
+		tview.Escape(segment.Category), tview.Escape(segment.Type), tview.Escape(algorithm), len(segment.Frames))
+}
+
+func addAndShowSegmentsPage(pages *tview.Pages, filename string, segments []seg.Segment, onSelect func(segment seg.Segment)) {
+	viewName := "segments"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Segments of %s (%d)", filename, len(segments))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(segments) == 0 {
+		list.AddItem("No segments", "", 0, nil)
+	}
+	for _, segment := range segments {
+		segment := segment
+		list.AddItem(segmentText(segment), "", 0, func() {
+			onSelect(segment)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
+
+// shows the frames of the segment on top of the list, n and p step through the frames, h, j, k and l scroll
+func addAndShowSegmentPreviewPage(pages *tview.Pages, segment seg.Segment, frameText func(frame int) (string, string)) {
+	viewName := "segment"
+	frame := 0
+	view := tview.NewTextView().SetWrap(false).SetDynamicColors(true)
+	view.SetBorder(true).SetTitleAlign(tview.AlignCenter)
+	update := func() {
+		title, text := frameText(frame)
+		view.SetText(text).ScrollToBeginning().SetTitle(tview.Escape(title))
+	}
+	update()
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'n':
+				frame = min(frame+1, len(segment.Frames)-1)
+				update()
+				return nil
+			case 'p':
+				frame = max(frame-1, 0)
+				update()
+				return nil
+			case 'h':
+				return tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone)
+			case 'l':
+				return tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	pages.AddPage(viewName, view, true, true)
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/geometry"
+	"github.com/rivo/tview"
+)
+
+// shows the suspect instances of each series: missing and duplicate instance numbers, duplicate positions and gaps or
+// irregular spacing of the slice locations, selecting a line jumps to its file
+func (a *App) showSliceChecks() {
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	checks := geometry.CheckSlices(a.visibleEntries())
+	suspect := 0
+	for _, c := range checks {
+		if len(c.Problems) > 0 {
+			suspect++
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d of %d series have suspect instances", suspect, len(checks)))
+	addAndShowSliceChecksPage(a.pages, checks, func(entry *dicomtree.Entry) {
+		a.sortByFileIfByTag()
+		jumpToFileNode(a.tree, entry.Filename)
+		a.app.SetFocus(a.tree)
+	})
+}
+
+func addAndShowSliceChecksPage(pages *tview.Pages, checks []geometry.SliceCheck, onSelect func(entry *dicomtree.Entry)) {
+	viewName := "slices"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Slices of %d series", len(checks))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(checks) == 0 {
+		list.AddItem("No series with instance numbers, image positions or slice locations", "", 0, nil)
+	}
+	for _, c := range checks {
+		list.AddItem(tview.Escape(c.Summary()), "", 0, nil)
+		for _, problem := range c.Problems {
+			entry := problem.Entry
+			list.AddItem(tview.Escape(fmt.Sprintf("    %s: %s", entry.Filename, problem.Text)), "", 0, func() {
+				pages.RemovePage(viewName)
+				onSelect(entry)
+			})
+		}
+	}
+	showListPage(pages, viewName, list)
+}
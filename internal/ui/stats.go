@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// number of files listed as slowest on the stats page
+const slowestFileCount = 10
+
+// returns the text of the stats page for the entries and the memory statistics of the runtime
+func statsText(entries []dicomtree.Entry, mem *runtime.MemStats) string {
+	parsed, elements := 0, 0
+	var totalDuration time.Duration
+	for i := range entries {
+		elements += dicomtree.CountElements(&entries[i].Dataset)
+		if !entries[i].Partial {
+			parsed++
+			totalDuration += entries[i].ParseDuration
+		}
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Files:          %d (%d parsed, %d not parsed or from index)\n", len(entries), parsed, len(entries)-parsed)
+	fmt.Fprintf(&text, "Elements:       %d (including sequence items)\n", elements)
+	fmt.Fprintf(&text, "Heap in use:    %s (%d objects)\n", formatBytes(mem.HeapInuse), mem.HeapObjects)
+	fmt.Fprintf(&text, "Heap allocated: %s total, %d GC cycles\n", formatBytes(mem.TotalAlloc), mem.NumGC)
+	fmt.Fprintf(&text, "Parse time:     %s total", totalDuration.Round(time.Microsecond))
+	if parsed > 0 {
+		fmt.Fprintf(&text, ", %s per file", (totalDuration / time.Duration(parsed)).Round(time.Microsecond))
+	}
+	text.WriteString("\n")
+
+	byDuration := make([]*dicomtree.Entry, 0, len(entries))
+	for i := range entries {
+		if !entries[i].Partial {
+			byDuration = append(byDuration, &entries[i])
+		}
+	}
+	sort.SliceStable(byDuration, func(i, j int) bool { return byDuration[i].ParseDuration > byDuration[j].ParseDuration })
+	if len(byDuration) > slowestFileCount {
+		byDuration = byDuration[:slowestFileCount]
+	}
+	if len(byDuration) > 0 {
+		text.WriteString("\nSlowest files:\n")
+		for _, entry := range byDuration {
+			fmt.Fprintf(&text, "  %12s  %s\n", entry.ParseDuration.Round(time.Microsecond), entry.Filename)
+		}
+	}
+
+	text.WriteString("\nParse time per file:\n")
+	for _, entry := range entries {
+		duration := "-"
+		if !entry.Partial {
+			duration = entry.ParseDuration.Round(time.Microsecond).String()
+		}
+		fmt.Fprintf(&text, "  %12s  %6d elements  %s\n", duration, dicomtree.CountElements(&entry.Dataset), entry.Filename)
+	}
+	return text.String()
+}
+
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
+
+func addAndShowStatsPage(pages *tview.Pages, entries []dicomtree.Entry) {
+	viewName := "stats"
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	statsView := tview.NewTextView().SetText(statsText(entries, &mem))
+	statsView.
+		SetTitle("Statistics").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	statsView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(statsView, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsText(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	entries[0].ParseDuration = 2 * time.Millisecond
+	entries[1].ParseDuration = 5 * time.Millisecond
+	entries = append(entries, dicomtree.Entry{Filename: "c.dcm", Partial: true})
+	text := statsText(entries, &runtime.MemStats{HeapInuse: 3 * 1024 * 1024, HeapObjects: 42})
+
+	assert.Contains(text, "Files:          3 (2 parsed, 1 not parsed or from index)")
+	assert.Contains(text, "Elements:       4 ")
+	assert.Contains(text, "Heap in use:    3.0 MiB (42 objects)")
+	assert.Contains(text, "Parse time:     7ms total, 3.5ms per file")
+	assert.Regexp(`Slowest files:\n +5ms  b.dcm\n +2ms  a.dcm\n`, text)
+	assert.Regexp(`  +- +0 elements  c.dcm`, text)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "2.0 GiB", formatBytes(2*1024*1024*1024))
+}
+
+func TestValueFrequenciesText(t *testing.T) {
+	text := valueFrequenciesText([]dicomtree.ValueFrequency{{Value: "CT", Count: 3}}, 1)
+	assert.Equal(t, "     3   75.0%  CT\n     1   25.0%  <missing>\n", text)
+}
+
+func TestInventoryText(t *testing.T) {
+	text := inventoryText([]dicomtree.InventoryCategory{
+		{Name: "Modality", Frequencies: []dicomtree.ValueFrequency{{Value: "CT", Count: 3}, {Value: "SR", Count: 1}}},
+		{Name: "Transfer syntax", Frequencies: []dicomtree.ValueFrequency{{Value: dicomtree.InventoryMissing, Count: 4}}},
+	})
+	assert.Equal(t, "Modality:\n     3   75.0%  CT\n     1   25.0%  SR\n\nTransfer syntax:\n     4  100.0%  <missing>\n", text)
+}
+
+func TestHistogramText(t *testing.T) {
+	assert := assert.New(t)
+
+	text := histogramText([]float64{120, 120, 120, 140}, 2)
+	assert.Equal("       120 .. 130             3  "+strings.Repeat("#", 50)+"\n       130 .. 140             1  "+strings.Repeat("#", 17)+"\n", text)
+	assert.Empty(histogramText([]float64{120, 120}, 2), "a single value")
+	assert.Empty(histogramText(nil, 2))
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// handles ':table <tags>' showing the visible files as table with a row per file and the given tags, separated by
+// commas or spaces, as columns, selecting a file jumps to it sorted by filename
+func (a *App) showFileTable(args string) {
+	names := splitTagList(args)
+	if len(names) == 0 {
+		a.statusLine.SetText("use :table <tag>[,<tag>...], e.g. :table PatientName,StudyDate,SeriesNumber,InstanceNumber")
+		return
+	}
+	tags, err := parseTags(names)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if a.index == nil {
+		if err := a.parseAllEntries(); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+	}
+	a.tableColumns = names
+	rows := dicomtree.FileTable(a.visibleEntries(), tags)
+	a.statusLine.SetText(fmt.Sprintf("%d files, s sorts by the selected column", len(rows)))
+	addAndShowFileTablePage(a.pages, tags, rows, func(filename string) {
+		if a.sortMode != 1 {
+			a.sortMode = 1
+			a.buildTree()
+		}
+		jumpToFileNode(a.tree, filename)
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// splits a list of tags separated by commas or spaces, keeping tags given as group,element together
+func splitTagList(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' })
+	isHex := func(s string) bool {
+		_, err := strconv.ParseUint(s, 16, 16)
+		return len(s) == 4 && err == nil
+	}
+	var names []string
+	for i := 0; i < len(fields); i++ {
+		group := strings.TrimPrefix(fields[i], "(")
+		if i+1 < len(fields) && isHex(group) && isHex(strings.TrimSuffix(fields[i+1], ")")) {
+			names = append(names, fields[i]+","+fields[i+1])
+			i++
+			continue
+		}
+		names = append(names, fields[i])
+	}
+	return names
+}
+
+// shows the rows with the filenames and header fixed. h, j, k and l move, s sorts by the selected column and reverses
+// the order if it is sorted by it already, enter jumps to the file of the selected row.
+func addAndShowFileTablePage(pages *tview.Pages, tags []tag.Tag, rows []dicomtree.TableRow, onSelect func(filename string)) {
+	viewName := "table"
+	view := tview.NewTable().SetFixed(1, 1).SetSelectable(true, true).SetSeparator(tview.Borders.Vertical)
+	view.SetBorder(true).
+		SetTitle(fmt.Sprintf("Table of %d files", len(rows))).
+		SetTitleAlign(tview.AlignCenter)
+	sortColumn, descending := -1, false
+	fill := func() {
+		view.SetCell(0, 0, tview.NewTableCell("File").SetSelectable(false).SetAttributes(tcell.AttrBold))
+		for c, t := range tags {
+			header := columnHeader(t)
+			if c == sortColumn {
+				header += map[bool]string{false: " ▲", true: " ▼"}[descending]
+			}
+			view.SetCell(0, c+1, tview.NewTableCell(header).SetSelectable(false).SetAttributes(tcell.AttrBold))
+		}
+		for r, row := range rows {
+			view.SetCell(r+1, 0, tview.NewTableCell(tview.Escape(row.Filename)).SetSelectable(false))
+			for c, value := range row.Values {
+				if len(value) > cellMaxValueLen {
+					value = value[:cellMaxValueLen-3] + "..."
+				}
+				view.SetCell(r+1, c+1, tview.NewTableCell(tview.Escape(value)))
+			}
+		}
+	}
+	fill()
+	if len(rows) > 0 {
+		view.Select(1, 1)
+	}
+	view.SetSelectedFunc(func(row, column int) {
+		if row > 0 && row <= len(rows) {
+			pages.RemovePage(viewName)
+			onSelect(rows[row-1].Filename)
+		}
+	})
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 's':
+				_, column := view.GetSelection()
+				if column < 1 {
+					return nil
+				}
+				descending = column-1 == sortColumn && !descending
+				sortColumn = column - 1
+				dicomtree.SortTableRows(rows, sortColumn, descending)
+				fill()
+				return nil
+			case 'h':
+				return tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone)
+			case 'l':
+				return tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	pages.AddAndSwitchToPage(viewName, view, true).ShowPage("main")
+}
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// the tview default styles, white text on black
+var darkTheme = tview.Styles
+
+var lightTheme = tview.Theme{
+	PrimitiveBackgroundColor:    tcell.ColorWhite,
+	ContrastBackgroundColor:     tcell.ColorLightGray,
+	MoreContrastBackgroundColor: tcell.ColorSilver,
+	BorderColor:                 tcell.ColorBlack,
+	TitleColor:                  tcell.ColorBlack,
+	GraphicsColor:               tcell.ColorDimGray,
+	PrimaryTextColor:            tcell.ColorBlack,
+	SecondaryTextColor:          tcell.ColorNavy,
+	TertiaryTextColor:           tcell.ColorGreen,
+	InverseTextColor:            tcell.ColorWhite,
+	ContrastSecondaryTextColor:  tcell.ColorDarkCyan,
+}
+
+// selects the colors by theme name, "dark" (or "") or "light", must be called before NewApp
+func SetTheme(name string) error {
+	switch name {
+	case "", "dark":
+		tview.Styles = darkTheme
+	case "light":
+		tview.Styles = lightTheme
+	default:
+		return fmt.Errorf("unknown theme '%s', use dark or light", name)
+	}
+	return nil
+}
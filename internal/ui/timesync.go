@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+)
+
+// skew of a device from the first one of its study reported by :time-sync without threshold
+const defaultClockSkewThreshold = 5 * time.Minute
+
+// handles ':time-sync [threshold]' comparing the acquisition times of the devices per study, flagging devices whose
+// first acquisition is more than the threshold, e.g. 90s or 10m, after the first one of the study
+func (a *App) timeSyncCommand(args []string) {
+	threshold := defaultClockSkewThreshold
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		if threshold, err = time.ParseDuration(args[0]); err != nil || threshold < 0 {
+			a.statusLine.SetText(fmt.Sprintf("invalid threshold '%s', use e.g. 90s or 10m", args[0]))
+			return
+		}
+	default:
+		a.statusLine.SetText("use :time-sync [threshold], e.g. :time-sync 10m")
+		return
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	clocks := dicomtree.ClockSkews(a.visibleEntries())
+	skewed := 0
+	for _, clock := range clocks {
+		if clock.Skew > threshold {
+			skewed++
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d of %d devices start more than %s after the first device of their study", skewed,
+		len(clocks), threshold))
+	addAndShowTimeSyncPage(a.pages, clocks, threshold, func(entry *dicomtree.Entry) {
+		a.sortByFileIfByTag()
+		jumpToFileNode(a.tree, entry.Filename)
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// lists the devices below their study with files, time range and skew, devices over the threshold marked with [!],
+// selecting a device jumps to its first file
+func addAndShowTimeSyncPage(pages *tview.Pages, clocks []dicomtree.DeviceClock, threshold time.Duration,
+	onSelect func(entry *dicomtree.Entry)) {
+	viewName := "timesync"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Acquisition times of %d devices, skews over %s marked", len(clocks), threshold)).
+		SetTitleAlign(tview.AlignCenter)
+	if len(clocks) == 0 {
+		list.AddItem("No files with acquisition or content date and time", "", 0, nil)
+	}
+	const layout = "2006-01-02 15:04:05"
+	for i, clock := range clocks {
+		if i == 0 || clock.StudyUID != clocks[i-1].StudyUID {
+			list.AddItem(tview.Escape("Study "+clock.Study), "", 0, nil)
+		}
+		mark := "   "
+		if clock.Skew > threshold {
+			mark = "[!]"
+		}
+		entry := clock.Entry
+		list.AddItem(tview.Escape(fmt.Sprintf("  %s %s: %d files, %s .. %s, +%s", mark, clock.Device, clock.Files,
+			clock.First.Format(layout), clock.Last.Format(layout), clock.Skew)), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(entry)
+		})
+	}
+	showListPage(pages, viewName, list)
+}
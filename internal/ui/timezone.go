@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+)
+
+// handles ':timezone <&HHMM>|utc' rewriting the date times and the dates and times of all visible files into the
+// timezone after a preview of the changes, save with :w
+func (a *App) timezoneCommand(args []string) {
+	if len(args) != 1 {
+		a.statusLine.SetText("use :timezone <&HHMM>|utc, e.g. :timezone +0100")
+		return
+	}
+	offset := args[0]
+	if strings.EqualFold(offset, "utc") {
+		offset = "+0000"
+	}
+	if _, err := dicomtree.ParseTimezoneOffset(offset); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	preview := func() ([]edit.Change, error) {
+		return a.previewChanges(func(dataset *dicom.Dataset) error {
+			_, err := edit.ConvertTimezone(dataset, offset)
+			return err
+		})
+	}
+	a.confirmBatch(":timezone", preview, func() {
+		changed, unknown := 0, 0
+		for i := range a.entries {
+			entry := &a.entries[i]
+			if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+				continue
+			}
+			if _, ok := dicomtree.DatasetTimezone(&entry.Dataset); !ok {
+				unknown++
+			}
+			before := edit.TakeSnapshot(&entry.Dataset)
+			n, err := edit.ConvertTimezone(&entry.Dataset, offset)
+			a.changes.Record(entry.Filename, before, &entry.Dataset)
+			changed += n
+			if err != nil {
+				a.refreshTree()
+				a.statusLine.SetText(fmt.Sprintf("error converting %s: %s", entry.Filename, err.Error()))
+				return
+			}
+		}
+		a.refreshTree()
+		text := fmt.Sprintf("converted %d elements of %d files to %s", changed, len(a.visibleEntries()), offset)
+		if unknown > 0 {
+			text += fmt.Sprintf(", %d files without TimezoneOffsetFromUTC keep their dates and times without offset", unknown)
+		}
+		a.statusLine.SetText(text + ", save with :w")
+	})
+}
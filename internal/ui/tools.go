@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/drcynic/dcmtagger/pkg/tool"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// handles ':open [<name>]' listing the tools of the config file to run on the current file, or running the named one
+func (a *App) openCommand(name string) {
+	var tools map[string]string
+	if a.config != nil {
+		tools = a.config.Tools
+	}
+	if len(tools) == 0 {
+		a.statusLine.SetText("no tools configured, add them to the config file or use :! <command>")
+		return
+	}
+	if name != "" {
+		command, ok := tools[name]
+		if !ok {
+			a.statusLine.SetText(fmt.Sprintf("unknown tool '%s'", name))
+			return
+		}
+		a.runTool(command)
+		return
+	}
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	viewName := "tools"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle("Open with").
+		SetTitleAlign(tview.AlignCenter)
+	for _, name := range names {
+		command := tools[name]
+		list.AddItem(fmt.Sprintf("%-16s %s", tview.Escape(name), tview.Escape(command)), "", 0, func() {
+			a.pages.RemovePage(viewName)
+			a.app.SetFocus(a.tree)
+			a.runTool(command)
+		})
+	}
+	showListPage(a.pages, viewName, list)
+}
+
+// runs the command with the shell on the current file in the background, {file} is replaced by its path and {tag} by
+// the tag of the current element, and shows the output when it exits
+func (a *App) runTool(command string) {
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected")
+		return
+	}
+	t := ""
+	if e, ok := a.tree.GetCurrentNode().GetReference().(*dicom.Element); ok {
+		t = fmt.Sprintf("%04x,%04x", e.Tag.Group, e.Tag.Element)
+	}
+	line, err := tool.Expand(command, entry.Path, t)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	unsaved := ""
+	for _, c := range a.changes.Changes {
+		if c.File == entry.Filename {
+			unsaved = fmt.Sprintf(", the command sees %s without the unsaved edits", entry.Filename)
+			break
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("running %s%s", line, unsaved))
+	go func() {
+		output, err := tool.Run(line, entry.Path)
+		a.app.QueueUpdateDraw(func() {
+			status := fmt.Sprintf("ran %s", line)
+			if err != nil {
+				status = fmt.Sprintf("%s failed: %s", line, err.Error())
+			}
+			a.statusLine.SetText(status + unsaved)
+			addAndShowToolOutputPage(a.pages, line, output)
+		})
+	}()
+}
+
+func addAndShowToolOutputPage(pages *tview.Pages, line, output string) {
+	viewName := "tool-output"
+	if output == "" {
+		output = "no output"
+	}
+	view := tview.NewTextView().SetText(output)
+	view.
+		SetTitle(fmt.Sprintf("Output of %s", tview.Escape(line))).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(view, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// events kept by the network trace, older ones are dropped
+const maxTraceEvents = 5000
+
+// the events of the network operations captured while tracing is on, recorded from several goroutines
+type networkTrace struct {
+	mu      sync.Mutex
+	enabled bool
+	events  []dimse.Event
+}
+
+func (t *networkTrace) record(event dimse.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.events) == maxTraceEvents {
+		t.events = t.events[1:]
+	}
+	t.events = append(t.events, event)
+}
+
+// returns the events as lines
+func (t *networkTrace) text() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var text strings.Builder
+	for _, event := range t.events {
+		text.WriteString(event.String() + "\n")
+	}
+	return text.String()
+}
+
+// handles ':trace [on|off|clear]' capturing the associations, PDUs and messages of network operations
+func (a *App) traceCommand(args []string) {
+	switch strings.Join(args, " ") {
+	case "":
+		a.trace.mu.Lock()
+		count := len(a.trace.events)
+		a.trace.mu.Unlock()
+		if count == 0 && !a.trace.enabled {
+			a.statusLine.SetText("no network trace, start it with :trace on")
+			return
+		}
+		addAndShowTracePage(a.pages, a.trace.text(), count)
+	case "on":
+		a.trace.enabled = true
+		dimse.Trace = a.trace.record
+		a.statusLine.SetText("tracing network operations, :trace shows them")
+	case "off":
+		a.trace.enabled = false
+		dimse.Trace = nil
+		a.statusLine.SetText("network trace stopped")
+	case "clear":
+		a.trace.mu.Lock()
+		a.trace.events = nil
+		a.trace.mu.Unlock()
+		a.statusLine.SetText("network trace cleared")
+	default:
+		a.statusLine.SetText("use :trace [on|off|clear]")
+	}
+}
+
+// shows the traced events scrolled to the newest one at the bottom
+func addAndShowTracePage(pages *tview.Pages, text string, count int) {
+	viewName := "trace"
+	view := tview.NewTextView().SetText(text).ScrollToEnd()
+	view.
+		SetTitle(fmt.Sprintf("Network trace (%d events) - -> sent, <- received", count)).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(view, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+// expansion state and current node of a tree, identified by the path of node keys
+type TreeState struct {
+	expanded map[string]bool
+	current  string
+}
+
+// creates the tview nodes for the given model node and all its descendants
+func newTreeNode(node *dicomtree.Node) *tview.TreeNode {
+	treeNode := tview.NewTreeNode(node.Text).SetSelectable(true)
+	if node.Element != nil {
+		treeNode.SetReference(node.Element)
+	} else if node.Item != nil {
+		treeNode.SetReference(node.Item)
+	} else if node.Level != dicomtree.HierarchyNone {
+		treeNode.SetReference(node.Level)
+	}
+	for _, child := range node.Children {
+		treeNode.AddChild(newTreeNode(child))
+	}
+	return treeNode
+}
+
+// replaces the children of a file node by the collapsed group or module nodes of the model of the single entry
+func setFileNodeChildren(node *tview.TreeNode, model *dicomtree.Node) {
+	node.ClearChildren()
+	for _, child := range model.Children {
+		groupNode := newTreeNode(child)
+		groupNode.CollapseAll()
+		node.AddChild(groupNode)
+	}
+}
+
+// returns whether the node is a patient, study or series node of the hierarchy sort mode
+func isHierarchyNode(node *tview.TreeNode) bool {
+	_, ok := node.GetReference().(dicomtree.HierarchyLevel)
+	return ok
+}
+
+func isTagNode(node *tview.TreeNode) bool {
+	_, ok := node.GetReference().(*dicom.Element)
+	return ok
+}
+
+func updateTagValue(node *tview.TreeNode, newValue string) {
+	if isTagNode(node) {
+		e := node.GetReference().(*dicom.Element)
+		stringArray := []string{newValue}
+		e.Value, _ = dicom.NewValue(stringArray)
+	}
+}
+
+// returns the dataset entry the given node belongs to, by checking the referenced elements and filenames on the path to the root
+func findEntryForNode(tree *treeModel, node *tview.TreeNode, entries []dicomtree.Entry) *dicomtree.Entry {
+	if len(entries) == 1 {
+		return &entries[0]
+	}
+	for n := node; n != nil; n = tree.fullParent(n) {
+		var entry *dicomtree.Entry
+		if isTagNode(n) {
+			entry = dicomtree.FindEntryByElement(entries, n.GetReference().(*dicom.Element))
+		} else {
+			entry = dicomtree.FindEntryByFilename(entries, n.GetText())
+		}
+		if entry != nil {
+			return entry
+		}
+	}
+	return nil
+}
+
+func getNodeKey(node *tview.TreeNode) string {
+	if isTagNode(node) {
+		return fmt.Sprintf("%p", node.GetReference())
+	}
+	return node.GetText()
+}
+
+func captureTreeState(tree *treeModel) TreeState {
+	state := TreeState{expanded: make(map[string]bool)}
+	paths := make(map[*tview.TreeNode]string)
+	tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		paths[node] = paths[parent] + "/" + getNodeKey(node)
+		state.expanded[paths[node]] = node.IsExpanded()
+		if node == tree.GetCurrentNode() {
+			state.current = paths[node]
+		}
+		return true
+	})
+	return state
+}
+
+// restores expansion state and current node for all nodes that can be found in the given state
+func restoreTreeState(tree *treeModel, state TreeState) {
+	paths := make(map[*tview.TreeNode]string)
+	tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		paths[node] = paths[parent] + "/" + getNodeKey(node)
+		if expanded, ok := state.expanded[paths[node]]; ok {
+			node.SetExpanded(expanded)
+		}
+		if paths[node] == state.current {
+			tree.SetCurrentNode(node)
+		}
+		return true
+	})
+	tree.invalidate()
+}
+
+func jumpToElementNode(tree *treeModel, element *dicom.Element) bool {
+	var foundNode *tview.TreeNode
+	tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		if foundNode == nil && node.GetReference() == element {
+			foundNode = node
+		}
+		return foundNode == nil
+	})
+	if foundNode == nil {
+		return false
+	}
+	expandPathToNode(tree, foundNode)
+	tree.SetCurrentNode(foundNode)
+	return true
+}
+
+// selects the node of the file, which is the root node for a single file
+func jumpToFileNode(tree *treeModel, filename string) bool {
+	root := tree.GetRoot()
+	var found *tview.TreeNode
+	root.Walk(func(node, parent *tview.TreeNode) bool {
+		if found == nil && node.GetReference() == nil && node.GetText() == filename {
+			found = node
+		}
+		// files are below the root, a source directory of merged inputs or a series
+		return found == nil && (node == root || parent == root && node.GetReference() == nil || isHierarchyNode(node))
+	})
+	if found == nil {
+		return false
+	}
+	for n := tree.parent(found); n != nil; n = tree.parent(n) {
+		n.Expand()
+	}
+	tree.invalidate()
+	tree.SetCurrentNode(found)
+	return true
+}
+
+// selects the node of the sequence item with the given index, the node of the sequence if there is no such item
+func jumpToItemNode(tree *treeModel, sequence *dicom.Element, index int) bool {
+	var foundNode *tview.TreeNode
+	tree.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		if item, ok := node.GetReference().(*dicomtree.SequenceItem); ok && item.Sequence == sequence && item.Index == index {
+			foundNode = node
+		}
+		return foundNode == nil
+	})
+	if foundNode == nil {
+		return jumpToElementNode(tree, sequence)
+	}
+	expandPathToNode(tree, foundNode)
+	tree.SetCurrentNode(foundNode)
+	return true
+}
+
+func collapseAllChildren(node *tview.TreeNode) {
+	for _, child := range node.GetChildren() {
+		child.CollapseAll()
+	}
+}
+
+func collapseAllRecursive(node *tview.TreeNode) {
+	for _, child := range node.GetChildren() {
+		child.CollapseAll()
+		collapseAllRecursive(child)
+	}
+}
+
+func collapseAllLeaves(node *tview.TreeNode) {
+	canCollapse := true
+	for _, child := range node.GetChildren() {
+		if len(child.GetChildren()) > 0 {
+			collapseAllLeaves(child)
+			canCollapse = false
+		}
+	}
+	if canCollapse {
+		node.CollapseAll()
+	}
+}
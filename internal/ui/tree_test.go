@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newTestTree(t *testing.T) (*treeModel, []dicomtree.Entry) {
+	entries := []dicomtree.Entry{
+		{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.PatientName, []string{"Doe^John"})}}},
+		{Filename: "b.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.PatientName, []string{"Doe^Jane"})}}},
+	}
+	root := newTreeNode(dicomtree.BuildByFilename("dir", entries, dicomtree.DisplayOptions{}))
+	tree := newTreeModel()
+	tree.SetRoot(root).SetCurrentNode(root)
+	return tree, entries
+}
+
+func TestNewTreeNode(t *testing.T) {
+	assert := assert.New(t)
+
+	tree, entries := newTestTree(t)
+	root := tree.GetRoot()
+	assert.False(isTagNode(root), "no reference for nodes without element")
+	fileNode := root.GetChildren()[1]
+	assert.Equal("b.dcm", fileNode.GetText())
+	elementNode := fileNode.GetChildren()[0].GetChildren()[0]
+	assert.True(isTagNode(elementNode))
+	assert.Same(entries[1].Dataset.Elements[0], elementNode.GetReference())
+}
+
+func TestFindEntryForNode(t *testing.T) {
+	assert := assert.New(t)
+
+	tree, entries := newTestTree(t)
+	fileNode := tree.GetRoot().GetChildren()[1]
+	assert.Same(&entries[1], findEntryForNode(tree, fileNode, entries))
+	assert.Same(&entries[1], findEntryForNode(tree, fileNode.GetChildren()[0].GetChildren()[0], entries))
+	assert.Nil(findEntryForNode(tree, tree.GetRoot(), entries))
+}
+
+func TestTreeState(t *testing.T) {
+	assert := assert.New(t)
+
+	tree, entries := newTestTree(t)
+	collapseAllRecursive(tree.GetRoot())
+	assert.True(jumpToElementNode(tree, entries[1].Dataset.Elements[0]))
+	state := captureTreeState(tree)
+
+	root := newTreeNode(dicomtree.BuildByFilename("dir", entries, dicomtree.DisplayOptions{PrettyValues: true}))
+	tree.SetRoot(root).SetCurrentNode(root)
+	collapseAllRecursive(root)
+	restoreTreeState(tree, state)
+	assert.Same(entries[1].Dataset.Elements[0], tree.GetCurrentNode().GetReference())
+	assert.False(root.GetChildren()[0].IsExpanded())
+	assert.True(root.GetChildren()[1].IsExpanded())
+}
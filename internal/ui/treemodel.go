@@ -0,0 +1,317 @@
+package ui
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// line numbers shown in the gutter left of the tree
+type lineNumberMode int
+
+const (
+	lineNumbersOff      lineNumberMode = iota
+	lineNumbersAbsolute                // number of each visible line, starting with 1 for the root
+	lineNumbersRelative                // distance to the current line, the current line shows its number
+)
+
+func (mode lineNumberMode) String() string {
+	switch mode {
+	case lineNumbersAbsolute:
+		return "absolute"
+	case lineNumbersRelative:
+		return "relative"
+	}
+	return "off"
+}
+
+// tree view with parent pointers and a flattened index of the visible nodes, so that navigation doesn't have to
+// walk the whole tree on every key. Changes of the expansion state have to be reported with invalidate,
+// changes of the children with invalidateStructure. A new root is detected automatically.
+type treeModel struct {
+	*tview.TreeView
+
+	root      *tview.TreeNode                     // root the index was built for
+	parents   map[*tview.TreeNode]*tview.TreeNode // nil if outdated
+	depths    map[*tview.TreeNode]int
+	visible   []*tview.TreeNode       // visible nodes in display order, nil if outdated
+	positions map[*tview.TreeNode]int // position of each visible node in visible
+	levels    map[int][]int           // ascending positions of the visible nodes per depth
+	items     []searchItem            // all nodes in display order for searching, nil if outdated
+	order     map[*tview.TreeNode]int // position of each node in items
+
+	lineNumbers   lineNumberMode
+	scrollColumns int                               // columns the tree is scrolled to the right
+	annotate      func(node *tview.TreeNode) string // text shown right aligned in the line of the node, nil for none
+	colorize      func(node *tview.TreeNode) string // text with color tags drawn instead of the node text, "" or nil for none
+
+	fullRoot     *tview.TreeNode                     // root of the complete tree while zoomed into a node, nil otherwise
+	outerParents map[*tview.TreeNode]*tview.TreeNode // parents of the nodes above the root while zoomed
+}
+
+func newTreeModel() *treeModel {
+	return &treeModel{TreeView: tview.NewTreeView()}
+}
+
+// marks the visible nodes as outdated, e.g. after expanding or collapsing nodes
+func (m *treeModel) invalidate() {
+	m.visible = nil
+}
+
+// marks parents and visible nodes as outdated, e.g. after adding or removing children
+func (m *treeModel) invalidateStructure() {
+	m.parents = nil
+	m.visible = nil
+	m.items = nil
+}
+
+func (m *treeModel) updateParents() {
+	if m.root != m.GetRoot() {
+		m.root = m.GetRoot()
+		m.invalidateStructure()
+	}
+	if m.parents != nil || m.root == nil {
+		return
+	}
+	m.parents = make(map[*tview.TreeNode]*tview.TreeNode)
+	m.depths = make(map[*tview.TreeNode]int)
+	m.root.Walk(func(node, parent *tview.TreeNode) bool {
+		if parent != nil {
+			m.parents[node] = parent
+			m.depths[node] = m.depths[parent] + 1
+		}
+		return true
+	})
+}
+
+func (m *treeModel) updateVisible() {
+	m.updateParents()
+	if m.visible != nil || m.root == nil {
+		return
+	}
+	m.visible = make([]*tview.TreeNode, 0)
+	m.positions = make(map[*tview.TreeNode]int)
+	m.levels = make(map[int][]int)
+	m.root.Walk(func(node, parent *tview.TreeNode) bool {
+		position := len(m.visible)
+		m.positions[node] = position
+		m.levels[m.depths[node]] = append(m.levels[m.depths[node]], position)
+		m.visible = append(m.visible, node)
+		return node.IsExpanded()
+	})
+}
+
+// returns the parent of the node, nil for the root or nodes not in the tree
+func (m *treeModel) parent(node *tview.TreeNode) *tview.TreeNode {
+	m.updateParents()
+	if parent, ok := m.parents[node]; ok || node == m.root {
+		return parent
+	}
+	m.invalidateStructure() // children changed without notice
+	m.updateParents()
+	return m.parents[node]
+}
+
+// returns the children of the parent of the node including the node itself, only the node itself for the root
+func (m *treeModel) siblings(node *tview.TreeNode) []*tview.TreeNode {
+	if parent := m.parent(node); parent != nil {
+		return parent.GetChildren()
+	}
+	if node == m.GetRoot() {
+		return []*tview.TreeNode{node}
+	}
+	return nil
+}
+
+// returns all visible nodes in display order
+func (m *treeModel) visibleNodes() []*tview.TreeNode {
+	m.updateVisible()
+	return m.visible
+}
+
+func (m *treeModel) visiblePosition(node *tview.TreeNode) (int, bool) {
+	m.updateVisible()
+	position, ok := m.positions[node]
+	if !ok {
+		m.invalidate() // expansion changed without notice
+		m.updateVisible()
+		position, ok = m.positions[node]
+	}
+	return position, ok
+}
+
+// returns the visible node at the given offset from the node counted only over nodes of the same depth,
+// nil if there is none
+func (m *treeModel) sameLevelNode(node *tview.TreeNode, offset int) *tview.TreeNode {
+	position, ok := m.visiblePosition(node)
+	if !ok {
+		return nil
+	}
+	positions := m.levels[m.depths[node]]
+	i := sort.SearchInts(positions, position) + offset
+	if i < 0 || i >= len(positions) {
+		return nil
+	}
+	return m.visible[positions[i]]
+}
+
+// draws the tree scrolled horizontally by scrollColumns, with line numbers in a gutter left of it if enabled
+func (m *treeModel) Draw(screen tcell.Screen) {
+	if m.colorize != nil {
+		defer m.drawColored()()
+	}
+	if m.lineNumbers == lineNumbersOff && m.scrollColumns == 0 && m.annotate == nil {
+		m.TreeView.Draw(screen)
+		return
+	}
+	x, y, width, height := m.GetRect()
+	var visible []*tview.TreeNode
+	gutter := 0
+	if m.lineNumbers != lineNumbersOff || m.annotate != nil {
+		m.invalidate() // expansion may have changed without notice, e.g. by the tree view itself
+		visible = m.visibleNodes()
+	}
+	if m.lineNumbers != lineNumbersOff {
+		gutter = len(strconv.Itoa(len(visible))) + 1
+	}
+
+	// the tree view can't scroll horizontally, so it is drawn shifted left and the cells it overwrote left of
+	// the tree are restored
+	left := x + gutter - m.scrollColumns
+	type cell struct {
+		mainc rune
+		combc []rune
+		style tcell.Style
+	}
+	var covered []cell
+	for row := 0; row < height; row++ {
+		for column := max(left, 0); column < x; column++ {
+			mainc, combc, style, _ := screen.GetContent(column, y+row)
+			covered = append(covered, cell{mainc, combc, style})
+		}
+	}
+	m.TreeView.SetRect(left, y, width-gutter+m.scrollColumns, height)
+	m.TreeView.Draw(screen)
+	m.TreeView.SetRect(x, y, width, height)
+	for row := 0; row < height; row++ {
+		for column := max(left, 0); column < x; column++ {
+			c := covered[0]
+			covered = covered[1:]
+			screen.SetContent(column, y+row, c.mainc, c.combc, c.style)
+		}
+	}
+	offset := m.GetScrollOffset()
+	if m.annotate != nil {
+		for row := 0; row < height && offset+row < len(visible); row++ {
+			if text := m.annotate(visible[offset+row]); text != "" {
+				tview.Print(screen, " "+text, x+gutter, y+row, width-gutter, tview.AlignRight, tcell.ColorGray)
+			}
+		}
+	}
+	if gutter == 0 {
+		return
+	}
+
+	current, _ := m.visiblePosition(m.GetCurrentNode())
+	for row := 0; row < height; row++ {
+		position := offset + row
+		text := ""
+		if position < len(visible) {
+			number := position + 1
+			if m.lineNumbers == lineNumbersRelative && position != current {
+				number = position - current
+				if number < 0 {
+					number = -number
+				}
+			}
+			text = strconv.Itoa(number)
+		}
+		for column := 0; column < gutter; column++ {
+			screen.SetContent(x+column, y+row, ' ', nil, tcell.StyleDefault)
+		}
+		tview.Print(screen, text, x, y+row, gutter-1, tview.AlignRight, tcell.ColorGray)
+	}
+}
+
+// replaces the texts of the nodes around the viewport by their colored texts for drawing, the returned function
+// restores them. The node texts stay plain for searching and the breadcrumb.
+func (m *treeModel) drawColored() func() {
+	m.invalidate()
+	visible := m.visibleNodes()
+	_, _, _, height := m.GetInnerRect()
+	// the tree view may still scroll by up to a page to follow the current node
+	first, last := max(m.GetScrollOffset()-height, 0), min(m.GetScrollOffset()+2*height, len(visible))
+	plain := make(map[*tview.TreeNode]string)
+	for _, node := range visible[first:last] {
+		if text := m.colorize(node); text != "" {
+			plain[node] = node.GetText()
+			node.SetText(text)
+		}
+	}
+	return func() {
+		for node, text := range plain {
+			node.SetText(text)
+		}
+	}
+}
+
+// scrolls vertically so that the visible node at the given position is in the first line, the current node stays.
+// The tree view has no offset setter, but scrolls minimally to a moved selection, so the selection is moved across
+// the edge of the viewport first.
+func (m *treeModel) scrollTo(offset int) {
+	visible := m.visibleNodes()
+	_, _, _, height := m.GetInnerRect()
+	if len(visible) < 2 || height <= 0 {
+		return
+	}
+	current := m.GetCurrentNode()
+	if scroll := m.GetScrollOffset(); offset > scroll {
+		bottom := min(offset+height-1, len(visible)-1)
+		m.SetCurrentNode(visible[max(bottom-1, 0)])
+		m.Move(1)
+	} else if offset < scroll {
+		m.SetCurrentNode(visible[min(offset+1, len(visible)-1)])
+		m.Move(-1)
+	}
+	m.SetCurrentNode(current)
+}
+
+// scrolls vertically so that the node is in the middle of the viewport, as far as possible
+func (m *treeModel) centerNode(node *tview.TreeNode) {
+	position, ok := m.visiblePosition(node)
+	if !ok {
+		return
+	}
+	_, _, _, height := m.GetInnerRect()
+	m.scrollTo(max(position-height/2, 0))
+}
+
+// scrolls the tree horizontally by the number of columns, not before the first column
+func (m *treeModel) scrollHorizontally(columns int) {
+	m.scrollColumns = max(m.scrollColumns+columns, 0)
+}
+
+// returns all nodes with their lowercase text in display order, the snapshot can be read from other goroutines
+func (m *treeModel) searchItems() []searchItem {
+	m.updateParents()
+	if m.items != nil || m.root == nil {
+		return m.items
+	}
+	m.items = make([]searchItem, 0, len(m.parents)+1)
+	m.order = make(map[*tview.TreeNode]int)
+	m.root.Walk(func(node, parent *tview.TreeNode) bool {
+		m.order[node] = len(m.items)
+		m.items = append(m.items, searchItem{node, strings.ToLower(node.GetText())})
+		return true
+	})
+	return m.items
+}
+
+// returns the position of each node in the search items
+func (m *treeModel) searchOrder() map[*tview.TreeNode]int {
+	m.searchItems()
+	return m.order
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeModelParentsAndSiblings(t *testing.T) {
+	assert := assert.New(t)
+
+	tree, _ := newTestTree(t)
+	root := tree.GetRoot()
+	fileNode := root.GetChildren()[1]
+	groupNode := fileNode.GetChildren()[0]
+	assert.Nil(tree.parent(root))
+	assert.Same(root, tree.parent(fileNode))
+	assert.Same(fileNode, tree.parent(groupNode))
+	assert.Equal([]*tview.TreeNode{root}, tree.siblings(root))
+	assert.Equal(root.GetChildren(), tree.siblings(fileNode))
+
+	newChild := tview.NewTreeNode("new")
+	groupNode.AddChild(newChild)
+	assert.Same(groupNode, tree.parent(newChild), "unknown nodes trigger a rebuild of the parents")
+	assert.Nil(tree.parent(tview.NewTreeNode("detached")))
+
+	newRoot := tview.NewTreeNode("root").AddChild(fileNode)
+	tree.SetRoot(newRoot)
+	assert.Same(newRoot, tree.parent(fileNode), "a new root is detected")
+}
+
+func TestTreeModelVisibleNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	tree, _ := newTestTree(t)
+	root := tree.GetRoot()
+	collapseAllRecursive(root)
+	fileA, fileB := root.GetChildren()[0], root.GetChildren()[1]
+	assert.Equal([]*tview.TreeNode{root, fileA, fileB}, tree.visibleNodes())
+	assert.Same(fileB, tree.sameLevelNode(fileA, 1))
+	assert.Nil(tree.sameLevelNode(fileB, 1))
+
+	fileA.Expand()
+	fileB.Expand()
+	tree.invalidate()
+	assert.Len(tree.visibleNodes(), 5)
+	groupA, groupB := fileA.GetChildren()[0], fileB.GetChildren()[0]
+	assert.Same(groupB, tree.sameLevelNode(groupA, 1), "same level across different parents")
+	assert.Same(groupA, tree.sameLevelNode(groupB, -1))
+	assert.Nil(tree.sameLevelNode(groupA, -1))
+
+	groupA.Expand()
+	tagNode := groupA.GetChildren()[0]
+	position, ok := tree.visiblePosition(tagNode)
+	assert.True(ok, "nodes becoming visible without notice trigger a rebuild")
+	assert.Equal(3, position)
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/waveform"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+)
+
+const (
+	trendPlotLines   = 8
+	trendPlotColumns = 200 // dot columns, two per character
+)
+
+// handles ':trend' plotting the numeric values of the current tag against InstanceNumber across the visible files,
+// listing missing instance numbers and values breaking the direction of the others, only when sorted by tag
+func (a *App) showTrend() {
+	node := a.tree.GetCurrentNode()
+	if node == nil || !isTagNode(node) {
+		a.statusLine.SetText("use :trend on a tag, e.g. SliceLocation")
+		return
+	}
+	if a.sortMode != 2 && a.sortMode != 3 {
+		a.statusLine.SetText("trends are shown when sorted by tag (2, 3)")
+		return
+	}
+	t := node.GetReference().(*dicom.Element).Tag
+	title := strings.TrimSpace(dicomtree.FormatTag(t) + " " + dicomtree.TagNameByTag(t))
+	points, skipped := dicomtree.Trend(a.visibleEntries(), t)
+	if len(points) < 2 {
+		a.statusLine.SetText(fmt.Sprintf("%s needs numeric values in at least 2 files with InstanceNumber", title))
+		return
+	}
+	missing, breaks := dicomtree.MissingInstances(points), dicomtree.NonMonotonic(points)
+	a.statusLine.SetText(fmt.Sprintf("%d files, %d missing instances, %d values not monotonic", len(points), len(missing),
+		len(breaks)))
+	addAndShowTrendPage(a.pages, title, trendText(points, skipped))
+}
+
+// returns the plot of the values over the instance numbers with gaps for missing ones, below it the missing instance
+// numbers and the values breaking the direction
+func trendText(points []dicomtree.TrendPoint, skipped int) string {
+	samples := []float64{points[0].Value}
+	for i := 1; i < len(points); i++ {
+		for instance := points[i-1].Instance + 1; instance < points[i].Instance; instance++ {
+			samples = append(samples, math.NaN())
+		}
+		samples = append(samples, points[i].Value)
+	}
+	values := make([]float64, 0, len(points))
+	for _, p := range points {
+		values = append(values, p.Value)
+	}
+	low, high := slices.Min(values), slices.Max(values)
+	first, last := points[0].Instance, points[len(points)-1].Instance
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d files, instances %d..%d, values %g..%g\n\n", len(points), first, last, low, high)
+	step := (len(samples) + trendPlotColumns - 1) / trendPlotColumns
+	plot := waveform.Plot(samples, trendPlotLines, step)
+	for i, line := range plot {
+		label := ""
+		switch i {
+		case 0:
+			label = strconv.FormatFloat(high, 'g', 6, 64)
+		case len(plot) - 1:
+			label = strconv.FormatFloat(low, 'g', 6, 64)
+		}
+		fmt.Fprintf(&text, "%10s │%s\n", label, line)
+	}
+	width := len([]rune(plot[0]))
+	lastLabel := strconv.Itoa(last)
+	fmt.Fprintf(&text, "%10s  %-*s%s\n", "instance", max(width-len(lastLabel), 1), strconv.Itoa(first), lastLabel)
+
+	text.WriteString("\nMissing instances: ")
+	if missing := dicomtree.MissingInstances(points); len(missing) > 0 {
+		text.WriteString(instanceRanges(missing))
+	} else {
+		text.WriteString("none")
+	}
+	text.WriteString("\nNot monotonic:")
+	breaks := dicomtree.NonMonotonic(points)
+	if len(breaks) == 0 {
+		text.WriteString(" none")
+	}
+	text.WriteString("\n")
+	for _, p := range breaks {
+		i := slices.Index(points, p)
+		fmt.Fprintf(&text, "  instance %d: %g after %g  %s\n", p.Instance, p.Value, points[i-1].Value, p.Filename)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(&text, "\n%d files without InstanceNumber or numeric value\n", skipped)
+	}
+	return text.String()
+}
+
+// returns the sorted numbers with consecutive ones as range, e.g. "3, 5-7"
+func instanceRanges(numbers []int) string {
+	var ranges []string
+	for i := 0; i < len(numbers); {
+		j := i
+		for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(numbers[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// shows the trend scrollable with j and k
+func addAndShowTrendPage(pages *tview.Pages, title string, text string) {
+	viewName := "trend"
+	view := tview.NewTextView().SetWrap(false).SetText(text)
+	view.SetTitle(fmt.Sprintf("%s over InstanceNumber", title)).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	pages.AddAndSwitchToPage(viewName, view, true).ShowPage("main")
+}
@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/suyashkumar/dicom"
+)
+
+// handles ':un [fix]' listing the elements of all visible files read with VR UN whose VR the dictionary knows, with
+// their values decoded with that VR. fix sets the VR and the decoded values after the preview, so they are displayed
+// and written with it.
+func (a *App) unknownVRCommand(args []string) {
+	if len(args) > 1 || len(args) == 1 && args[0] != "fix" {
+		a.statusLine.SetText("use :un [fix]")
+		return
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if len(args) == 1 {
+		a.reinterpretUnknownVRs()
+		return
+	}
+	entries := a.visibleEntries()
+	var findings []validate.Finding
+	entriesByElement := make(map[*dicom.Element]*dicomtree.Entry)
+	for i := range entries {
+		entry := &entries[i]
+		charsets := charset.FromDataset(&entry.Dataset)
+		for _, unknown := range edit.FindUnknownVRs(&entry.Dataset) {
+			e := unknown.Element
+			finding := validate.Finding{Severity: validate.SeverityWarning, Tag: e.Tag, Element: e}
+			value, err := unknownValueBytes(entry, e)
+			var decoded dicom.Value
+			if err == nil {
+				decoded, err = edit.DecodeUnknownValue(value, unknown.VR)
+			}
+			tagText := fmt.Sprintf("%s %s", dicomtree.FormatTag(e.Tag), dicomtree.TagName(e))
+			if err != nil {
+				finding.Severity = validate.SeverityError
+				finding.Message = fmt.Sprintf("%s has VR UN, not decoded as %s: %s", tagText, unknown.VR, err.Error())
+			} else {
+				reinterpreted := &dicom.Element{Tag: e.Tag, RawValueRepresentation: unknown.VR, Value: decoded}
+				finding.Message = fmt.Sprintf("%s has VR UN, as %s: %s", tagText, unknown.VR,
+					dicomtree.ValueString(reinterpreted, charsets, dicomtree.DisplayOptions{}))
+			}
+			if len(entries) > 1 {
+				finding.Message = entry.Filename + ": " + finding.Message
+			}
+			entriesByElement[e] = entry
+			findings = append(findings, finding)
+		}
+	}
+	if len(findings) == 0 {
+		a.statusLine.SetText(fmt.Sprintf("no elements of %d files with VR UN for tags of the dictionary", len(entries)))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d elements with VR UN for tags of the dictionary, set their VR with :un fix", len(findings)))
+	addAndShowValidationPage(a.pages, "elements with VR UN", findings, func(finding validate.Finding) {
+		a.sortByFileIfByTag()
+		if !jumpToElementNode(a.tree, finding.Element) {
+			jumpToFileNode(a.tree, entriesByElement[finding.Element].Filename)
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// returns the value bytes of the element read with VR UN, read from the file if the parser trimmed them
+func unknownValueBytes(entry *dicomtree.Entry, e *dicom.Element) ([]byte, error) {
+	if value := edit.UnknownValueBytes(e); value != nil || e.ValueLength == 0 {
+		return value, nil
+	}
+	return entry.ReadValueBytes(e)
+}
+
+// sets the dictionary VR and the decoded values of the elements of the entry read with VR UN, returns their number
+func setUnknownVRs(entry *dicomtree.Entry) (int, error) {
+	changed := 0
+	for _, unknown := range edit.FindUnknownVRs(&entry.Dataset) {
+		value, err := unknownValueBytes(entry, unknown.Element)
+		if err == nil {
+			err = edit.ReinterpretUnknown(unknown, value)
+		}
+		if err != nil {
+			return changed, fmt.Errorf("error setting the VR in %s: %w", entry.Filename, err)
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// sets the dictionary VR of the elements with VR UN of all visible files after the preview of the changed values
+func (a *App) reinterpretUnknownVRs() {
+	preview := func() ([]edit.Change, error) {
+		var changes []edit.Change
+		for _, entry := range a.visibleEntries() {
+			dataset, err := edit.CopyDataset(&entry.Dataset)
+			if err != nil {
+				return nil, fmt.Errorf("error copying %s: %w", entry.Filename, err)
+			}
+			before := edit.TakeSnapshot(&entry.Dataset)
+			entry.Dataset = dataset
+			if _, err := setUnknownVRs(&entry); err != nil {
+				return nil, err
+			}
+			changes = append(changes, edit.Diff(entry.Filename, before, &entry.Dataset)...)
+		}
+		return changes, nil
+	}
+	a.confirmBatch(":un fix", preview, func() {
+		elements, files := 0, 0
+		var applyErr error
+		for i := range a.entries {
+			entry := &a.entries[i]
+			if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+				continue
+			}
+			snapshot := edit.TakeSnapshot(&entry.Dataset)
+			n, err := setUnknownVRs(entry)
+			a.changes.Record(entry.Filename, snapshot, &entry.Dataset)
+			elements += n
+			if n > 0 {
+				files++
+			}
+			if err != nil {
+				applyErr = err
+				break
+			}
+		}
+		a.refreshTree()
+		if applyErr != nil {
+			a.statusLine.SetText(applyErr.Error())
+			return
+		}
+		a.statusLine.SetText(fmt.Sprintf("set the dictionary VR of %d elements with VR UN in %d files, save with :w", elements, files))
+	})
+}
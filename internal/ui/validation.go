@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func addAndShowValidationPage(pages *tview.Pages, iodName string, findings []validate.Finding, onSelect func(finding validate.Finding)) {
+	viewName := "validation"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Validation Report - %s (%d findings)", iodName, len(findings))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(findings) == 0 {
+		list.AddItem("No findings", "", 0, nil)
+	}
+	for _, finding := range findings {
+		finding := finding
+		list.AddItem(fmt.Sprintf("%-7s %s", finding.Severity, finding.Message), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(finding)
+		})
+	}
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 30
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(list, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
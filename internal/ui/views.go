@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/internal/config"
+)
+
+// handles ':view' listing the saved views, ':view save <name>' saving sort mode, filter, search and table columns
+// to the config file, ':view delete <name>' and ':view <name>' recalling a saved view
+func (a *App) viewCommand(args []string) {
+	if a.config == nil {
+		a.statusLine.SetText("views are kept in the config file, which is not loaded")
+		return
+	}
+	switch {
+	case len(args) == 0:
+		names := make([]string, 0, len(a.config.Views))
+		for name := range a.config.Views {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			a.statusLine.SetText("no views saved, save the current one with :view save <name>")
+			return
+		}
+		slices.Sort(names)
+		a.statusLine.SetText("views: " + strings.Join(names, ", "))
+	case args[0] == "save" && len(args) == 2:
+		view := config.View{SortMode: a.sortMode, Filter: a.filterText, Search: a.confirmedSearch, Columns: a.tableColumns}
+		if a.config.Views == nil {
+			a.config.Views = make(map[string]config.View)
+		}
+		a.config.Views[args[1]] = view
+		a.statusLine.SetText(a.saveConfig(fmt.Sprintf("saved view %s: %s", args[1], viewText(view))))
+	case args[0] == "delete" && len(args) == 2:
+		if _, ok := a.config.Views[args[1]]; !ok {
+			a.statusLine.SetText(fmt.Sprintf("unknown view '%s'", args[1]))
+			return
+		}
+		delete(a.config.Views, args[1])
+		a.statusLine.SetText(a.saveConfig("deleted view " + args[1]))
+	case len(args) == 1:
+		view, ok := a.config.Views[args[0]]
+		if !ok {
+			a.statusLine.SetText(fmt.Sprintf("unknown view '%s'", args[0]))
+			return
+		}
+		a.applyView(args[0], view)
+	default:
+		a.statusLine.SetText("use :view [<name>|save <name>|delete <name>]")
+	}
+}
+
+// restores sort mode, filter and search of the view and shows its table if it has columns
+func (a *App) applyView(name string, view config.View) {
+	if view.SortMode != 0 && view.SortMode != a.sortMode {
+		a.SetSortMode(view.SortMode)
+	}
+	a.applyFilter(view.Filter)
+	if view.Search != "" {
+		a.searchText = strings.ToLower(view.Search)
+		a.confirmedSearch = a.searchText
+		a.searcher.run(a.tree.searchItems(), a.searchText, a.applySearchMatches)
+	}
+	if len(view.Columns) > 0 {
+		a.showFileTable(strings.Join(view.Columns, ","))
+	}
+	a.statusLine.SetText(fmt.Sprintf("view %s: %d of %d files", name, len(a.visibleEntries()), len(a.entries)))
+}
+
+// returns the settings of the view for the status line, e.g. "sort mode 4, filter 'Modality=CT', 3 columns"
+func viewText(view config.View) string {
+	parts := []string{fmt.Sprintf("sort mode %d", view.SortMode)}
+	if view.Filter != "" {
+		parts = append(parts, fmt.Sprintf("filter '%s'", view.Filter))
+	}
+	if view.Search != "" {
+		parts = append(parts, fmt.Sprintf("search '%s'", view.Search))
+	}
+	if len(view.Columns) > 0 {
+		parts = append(parts, fmt.Sprintf("%d columns", len(view.Columns)))
+	}
+	return strings.Join(parts, ", ")
+}
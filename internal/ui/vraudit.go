@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/suyashkumar/dicom"
+)
+
+// handles ':vr-audit [fix]' listing the values of all visible files that violate the length rules of their VR or
+// keep padding inside, fix repairs the fixable ones after the preview
+func (a *App) auditValues(args []string) {
+	if len(args) > 1 || len(args) == 1 && args[0] != "fix" {
+		a.statusLine.SetText("use :vr-audit [fix]")
+		return
+	}
+	if err := a.loadAllEntries(); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	if len(args) == 1 {
+		a.fixValues()
+		return
+	}
+	entries := a.visibleEntries()
+	var findings []validate.Finding
+	entriesByElement := make(map[*dicom.Element]*dicomtree.Entry)
+	files := 0
+	for i := range entries {
+		entry := &entries[i]
+		entryFindings := validate.Values(&entry.Dataset)
+		if len(entryFindings) > 0 {
+			files++
+		}
+		for _, finding := range entryFindings {
+			if len(entries) > 1 {
+				finding.Message = entry.Filename + ": " + finding.Message
+			}
+			entriesByElement[finding.Element] = entry
+			findings = append(findings, finding)
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d values in %d of %d files violate their VR, fix the fixable ones with :vr-audit fix",
+		len(findings), files, len(entries)))
+	addAndShowValidationPage(a.pages, "values and their VR", findings, func(finding validate.Finding) {
+		a.sortByFileIfByTag()
+		if !jumpToElementNode(a.tree, finding.Element) {
+			jumpToFileNode(a.tree, entriesByElement[finding.Element].Filename)
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
+
+// repairs the fixable values of the top level elements of all visible files after the preview
+func (a *App) fixValues() {
+	preview := func() ([]edit.Change, error) {
+		return a.previewChanges(func(dataset *dicom.Dataset) error {
+			validate.FixValues(dataset)
+			return nil
+		})
+	}
+	a.confirmBatch(":vr-audit fix", preview, func() {
+		elements, files := 0, 0
+		for i := range a.entries {
+			entry := &a.entries[i]
+			if a.filterPaths != nil && !a.filterPaths[entry.Path] {
+				continue
+			}
+			snapshot := edit.TakeSnapshot(&entry.Dataset)
+			if n := validate.FixValues(&entry.Dataset); n > 0 {
+				a.changes.Record(entry.Filename, snapshot, &entry.Dataset)
+				elements += n
+				files++
+			}
+		}
+		a.refreshTree()
+		a.statusLine.SetText(fmt.Sprintf("fixed the values of %d elements in %d files, save with :w", elements, files))
+	})
+}
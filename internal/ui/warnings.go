@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// a parse warning with the file it was found in
+type fileWarning struct {
+	entry   *dicomtree.Entry
+	warning dicomtree.ParseWarning
+}
+
+// lists the parse warnings of all parsed files, e.g. after ':warnings'
+func (a *App) showWarnings() {
+	warnings := make([]fileWarning, 0)
+	for i := range a.entries {
+		for _, w := range a.entries[i].Warnings {
+			warnings = append(warnings, fileWarning{&a.entries[i], w})
+		}
+	}
+	a.statusLine.SetText(fmt.Sprintf("%d parse warnings in the parsed files", len(warnings)))
+	addAndShowWarningsPage(a.pages, warnings, func(w fileWarning) {
+		e, err := w.entry.Dataset.FindElementByTag(w.warning.Tag)
+		if err != nil || !jumpToElementNode(a.tree, e) {
+			a.statusLine.SetText(warningText(w))
+		}
+		a.app.SetFocus(a.tree)
+	})
+}
+
+func warningText(w fileWarning) string {
+	tagText := strings.TrimSpace(dicomtree.FormatTag(w.warning.Tag) + " " + dicomtree.TagNameByTag(w.warning.Tag))
+	return fmt.Sprintf("%s  %s @0x%08x: %s", w.entry.Filename, tagText, w.warning.Offset, w.warning.Message)
+}
+
+func addAndShowWarningsPage(pages *tview.Pages, warnings []fileWarning, onSelect func(w fileWarning)) {
+	viewName := "warnings"
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Parse warnings (%d)", len(warnings))).
+		SetTitleAlign(tview.AlignCenter)
+	if len(warnings) == 0 {
+		list.AddItem("No parse warnings", "", 0, nil)
+	}
+	for _, w := range warnings {
+		w := w
+		list.AddItem(warningText(w), "", 0, func() {
+			pages.RemovePage(viewName)
+			onSelect(w)
+		})
+	}
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	width, height := 120, 30
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(list, 1, 1, 1, 1, 0, 0, true)
+	pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/waveform"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const waveformPlotLines = 3 // text lines per channel, each with four dot rows
+
+// handles ':waveform' showing the plot of the current waveform object and ':waveform export <file>' writing its samples
+func (a *App) waveformCommand(args []string) {
+	entry := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	if entry == nil {
+		a.statusLine.SetText("no dataset selected")
+		return
+	}
+	if err := a.loadEntry(entry); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	groups, err := waveform.Decode(&entry.Dataset)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error decoding waveform of %s: %s", entry.Filename, err.Error()))
+		return
+	}
+	switch {
+	case len(args) == 0:
+		a.statusLine.SetText(fmt.Sprintf("%d multiplex groups in %s", len(groups), entry.Filename))
+		addAndShowWaveformPage(a.pages, entry.Filename, groups)
+	case len(args) == 2 && args[0] == "export":
+		files, err := exportWaveform(args[1], groups)
+		if err != nil {
+			a.statusLine.SetText(fmt.Sprintf("error exporting waveform: %s", err.Error()))
+			return
+		}
+		a.statusLine.SetText(fmt.Sprintf("exported waveform of %s to %s", entry.Filename, strings.Join(files, ", ")))
+	default:
+		a.statusLine.SetText("use :waveform or :waveform export <file>")
+	}
+}
+
+// writes the groups as CSV, for several groups to one file per group with the group number appended to the name
+func exportWaveform(filename string, groups []waveform.Group) ([]string, error) {
+	files := make([]string, 0, len(groups))
+	for i, group := range groups {
+		path := filename
+		if len(groups) > 1 {
+			extension := filepath.Ext(filename)
+			path = strings.TrimSuffix(filename, extension) + "_" + strconv.Itoa(i+1) + extension
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return files, err
+		}
+		err = waveform.WriteCSV(file, group)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// returns the plots of all channels, each below a line with label, range and unit
+func waveformText(groups []waveform.Group, step int) string {
+	var text strings.Builder
+	for i, group := range groups {
+		fmt.Fprintf(&text, "%s: %g Hz, %d channels\n", strings.TrimSpace(fmt.Sprintf("Group %d %s", i+1, group.Label)), group.Frequency,
+			len(group.Channels))
+		for _, channel := range group.Channels {
+			low, high := 0.0, 0.0
+			for j, v := range channel.Samples {
+				if j == 0 || v < low {
+					low = v
+				}
+				if j == 0 || v > high {
+					high = v
+				}
+			}
+			fmt.Fprintf(&text, "%s  %g..%g %s\n", channel.Label, low, high, channel.Unit)
+			for _, line := range waveform.Plot(channel.Samples, waveformPlotLines, step) {
+				text.WriteString(line + "\n")
+			}
+		}
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+// shows the plots scrollable with h, j, k and l, + and - zoom in and out in time
+func addAndShowWaveformPage(pages *tview.Pages, filename string, groups []waveform.Group) {
+	viewName := "waveform"
+	step := 1
+	view := tview.NewTextView().SetWrap(false)
+	view.SetBorder(true).SetTitleAlign(tview.AlignCenter)
+	update := func() {
+		view.SetText(waveformText(groups, step)).SetTitle(fmt.Sprintf("Waveform of %s, %d samples per dot", filename, step))
+	}
+	update()
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(viewName)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				pages.RemovePage(viewName)
+				return nil
+			case '+':
+				step = max(step/2, 1)
+				update()
+				return nil
+			case '-':
+				step *= 2
+				update()
+				return nil
+			case 'h':
+				return tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone)
+			case 'l':
+				return tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+		}
+		return event
+	})
+	pages.AddAndSwitchToPage(viewName, view, true).ShowPage("main")
+}
@@ -0,0 +1,230 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dimse"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// tags copied from a worklist entry into the current dataset with c: the patient module and the study attributes the
+// worklist assigns
+var worklistCopyTags = append(slices.Clone(edit.ImportModules["patient"]),
+	tag.AccessionNumber, tag.ReferringPhysicianName, tag.StudyInstanceUID)
+
+// parses the filters of ':worklist [name=<name>] [id=<id>] [date=<YYYYMMDD>|today] [modality=<modality>] [aet=<aet>]'
+func parseWorklistQuery(args []string, now time.Time) (dimse.WorklistQuery, error) {
+	var query dimse.WorklistQuery
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return query, fmt.Errorf("invalid filter '%s', use <filter>=<value>", arg)
+		}
+		switch strings.ToLower(key) {
+		case "name":
+			query.PatientName = value
+		case "id":
+			query.PatientID = value
+		case "date":
+			if strings.EqualFold(value, "today") {
+				value = now.Format("20060102")
+			}
+			query.Date = value
+		case "modality":
+			query.Modality = strings.ToUpper(value)
+		case "aet":
+			query.StationAET = value
+		default:
+			return query, fmt.Errorf("unknown filter '%s', use node, name, id, date, modality or aet", key)
+		}
+	}
+	return query, nil
+}
+
+// returns the worklist SCP with the name of the nodes, without name the worklist of the config or the only node with
+// the worklist capability
+func (a *App) worklistNode(name string) (dimse.Node, error) {
+	if a.config == nil {
+		return dimse.Node{}, errors.New("no worklist configured")
+	}
+	if name != "" {
+		node, ok := a.config.Nodes[name]
+		if !ok {
+			return node, fmt.Errorf("unknown node '%s'", name)
+		}
+		if !node.Can("worklist") || node.Host == "" {
+			return node, fmt.Errorf("node '%s' has no worklist", name)
+		}
+		return node, nil
+	}
+	if a.config.Worklist != nil {
+		return *a.config.Worklist, nil
+	}
+	var worklists []string
+	for _, name := range a.nodeNames() {
+		if slices.Contains(a.config.Nodes[name].Capabilities, "worklist") {
+			worklists = append(worklists, name)
+		}
+	}
+	switch len(worklists) {
+	case 0:
+		return dimse.Node{}, errors.New("no worklist configured, add a node with the worklist capability")
+	case 1:
+		return a.config.Nodes[worklists[0]], nil
+	}
+	return dimse.Node{}, fmt.Errorf("several worklists, select one with node=%s", strings.Join(worklists, "|"))
+}
+
+// handles ':worklist [node=<name>] [filters]' querying the worklist SCP and showing the scheduled procedure steps
+func (a *App) showWorklist(args []string) {
+	var nodeName string
+	args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool {
+		name, ok := strings.CutPrefix(arg, "node=")
+		if ok {
+			nodeName = name
+		}
+		return ok
+	})
+	node, err := a.worklistNode(nodeName)
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	query, err := parseWorklistQuery(args, time.Now())
+	if err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	matches, err := a.queryWorklist(node, query)
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error querying the worklist: %s", err.Error()))
+		return
+	}
+	if len(matches) == 0 {
+		a.statusLine.SetText(fmt.Sprintf("no worklist entries at %s match", node))
+		return
+	}
+	target := findEntryForNode(a.tree, a.tree.GetCurrentNode(), a.entries)
+	a.addAndShowWorklistPage(node, worklistEntries(matches), target)
+	a.statusLine.SetText(fmt.Sprintf("%d worklist entries at %s", len(matches), node))
+}
+
+// returns the matches as entries named by number, scheduled date and time, patient, modality and description
+func worklistEntries(matches []dicom.Dataset) []dicomtree.Entry {
+	entries := make([]dicomtree.Entry, 0, len(matches))
+	for i := range matches {
+		dataset := &matches[i]
+		var step []*dicom.Element
+		if e, err := dataset.FindElementByTag(tag.ScheduledProcedureStepSequence); err == nil {
+			if items := dicomtree.SequenceItems(e); len(items) > 0 {
+				step = items[0]
+			}
+		}
+		stepDataset := &dicom.Dataset{Elements: step}
+		parts := []string{fmt.Sprintf("%d", i+1)}
+		for _, value := range []string{
+			worklistValue(stepDataset, tag.ScheduledProcedureStepStartDate),
+			worklistValue(stepDataset, tag.ScheduledProcedureStepStartTime),
+			worklistValue(dataset, tag.PatientName),
+			worklistValue(stepDataset, tag.Modality),
+			worklistValue(stepDataset, tag.ScheduledProcedureStepDescription),
+		} {
+			if value != "" {
+				parts = append(parts, value)
+			}
+		}
+		entries = append(entries, dicomtree.Entry{Filename: strings.Join(parts, " "), Dataset: *dataset})
+	}
+	return entries
+}
+
+func worklistValue(dataset *dicom.Dataset, t tag.Tag) string {
+	e, err := dataset.FindElementByTag(t)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(dicomtree.ValueText(e, nil))
+}
+
+// shows the worklist entries as tree, c copies the demographics of the current entry into the target dataset,
+// q and escape close it
+func (a *App) addAndShowWorklistPage(node dimse.Node, entries []dicomtree.Entry, target *dicomtree.Entry) {
+	viewName := "worklist"
+	tree := newTreeModel()
+	root := newTreeNode(dicomtree.BuildByFilename("Worklist "+node.String(), entries, a.displayOptions))
+	root.CollapseAll()
+	root.Expand()
+	tree.SetRoot(root).SetCurrentNode(root)
+	tree.SetSelectedFunc(func(n *tview.TreeNode) {
+		n.SetExpanded(!n.IsExpanded())
+		tree.invalidate()
+	})
+	targetText := "no dataset selected"
+	if target != nil {
+		targetText = "c: copy to " + target.Filename
+	}
+	tree.
+		SetTitle(fmt.Sprintf("Worklist of %s (%d entries) - %s, q: close", node, len(entries), targetText)).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true).
+		SetBorderPadding(1, 1, 1, 1)
+	tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEsc || event.Key() == tcell.KeyRune && event.Rune() == 'q':
+			a.pages.RemovePage(viewName)
+			return nil
+		case event.Key() == tcell.KeyRune && event.Rune() == 'c':
+			source := findEntryForNode(tree, tree.GetCurrentNode(), entries)
+			if source == nil {
+				a.statusLine.SetText("select a worklist entry to copy")
+				return nil
+			}
+			a.pages.RemovePage(viewName)
+			a.copyWorklistEntry(source, target)
+			return nil
+		}
+		return event
+	})
+	width, height := 120, 40
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(tree, 1, 1, 1, 1, 0, 0, true)
+	a.pages.AddAndSwitchToPage(viewName, grid, true).ShowPage("main")
+}
+
+// copies the demographics of the worklist entry into the target dataset and records the changes
+func (a *App) copyWorklistEntry(source *dicomtree.Entry, target *dicomtree.Entry) {
+	if target == nil {
+		a.statusLine.SetText("no dataset selected to copy the worklist entry into")
+		return
+	}
+	for _, t := range worklistCopyTags {
+		if err := a.protected.Check(t); err != nil {
+			a.statusLine.SetText(err.Error())
+			return
+		}
+	}
+	if err := a.loadEntry(target); err != nil {
+		a.statusLine.SetText(err.Error())
+		return
+	}
+	before := edit.TakeSnapshot(&target.Dataset)
+	copied, err := edit.Import(&target.Dataset, &source.Dataset, worklistCopyTags)
+	a.changes.Record(target.Filename, before, &target.Dataset)
+	a.refreshTree()
+	if err != nil {
+		a.statusLine.SetText(fmt.Sprintf("error copying the worklist entry: %s", err.Error()))
+		return
+	}
+	a.statusLine.SetText(fmt.Sprintf("copied %d elements of worklist entry %s into %s, save with :w", len(copied), source.Filename, target.Filename))
+}
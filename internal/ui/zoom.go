@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// sets a new tree, ending a zoom into the old one
+func (m *treeModel) SetRoot(root *tview.TreeNode) *tview.TreeView {
+	m.fullRoot, m.outerParents = nil, nil
+	return m.TreeView.SetRoot(root)
+}
+
+// shows only the node and its descendants, the nodes above it are kept for lookups like the file of a node
+func (m *treeModel) zoom(node *tview.TreeNode) {
+	if m.fullRoot == nil {
+		m.fullRoot = m.GetRoot()
+		m.outerParents = make(map[*tview.TreeNode]*tview.TreeNode)
+	}
+	for n := node; m.parent(n) != nil; n = m.parent(n) {
+		m.outerParents[n] = m.parent(n)
+	}
+	m.TreeView.SetRoot(node)
+	m.invalidateStructure()
+}
+
+// shows the complete tree again, returns false if not zoomed
+func (m *treeModel) unzoom() bool {
+	if m.fullRoot == nil {
+		return false
+	}
+	root := m.fullRoot
+	m.fullRoot, m.outerParents = nil, nil
+	m.TreeView.SetRoot(root)
+	m.invalidateStructure()
+	return true
+}
+
+// returns the parent of the node in the complete tree, also above the root while zoomed
+func (m *treeModel) fullParent(node *tview.TreeNode) *tview.TreeNode {
+	if parent := m.parent(node); parent != nil {
+		return parent
+	}
+	return m.outerParents[node]
+}
+
+// returns the path of the node in the complete tree like captureTreeState
+func (m *treeModel) fullPath(node *tview.TreeNode) string {
+	path := ""
+	for n := node; n != nil; n = m.fullParent(n) {
+		path = "/" + getNodeKey(n) + path
+	}
+	return path
+}
+
+// zooms into the node with the given path of the complete tree, false if there is none
+func (m *treeModel) zoomToPath(path string) bool {
+	var found *tview.TreeNode
+	paths := make(map[*tview.TreeNode]string)
+	m.GetRoot().Walk(func(node, parent *tview.TreeNode) bool {
+		paths[node] = paths[parent] + "/" + getNodeKey(node)
+		if paths[node] == path {
+			found = node
+		}
+		return found == nil && strings.HasPrefix(path, paths[node])
+	})
+	if found == nil {
+		return false
+	}
+	current := m.GetCurrentNode()
+	m.zoom(found)
+	for n := current; n != found; n = m.parent(n) {
+		if n == nil {
+			m.SetCurrentNode(found) // the current node is not below the new root
+			break
+		}
+	}
+	return true
+}
+
+// makes the current node the root of the tree, :root
+func (a *App) zoomToCurrentNode() {
+	node := a.tree.GetCurrentNode()
+	if node == nil || node == a.tree.GetRoot() {
+		a.statusLine.SetText("the current node is already the root, :root! shows the complete tree")
+		return
+	}
+	a.loadFileNodes(node)
+	node.Expand()
+	a.tree.zoom(node)
+	a.tree.SetCurrentNode(node)
+	a.statusLine.SetText(fmt.Sprintf("root at %s, :root! shows the complete tree", breadcrumbLabel(node, a.tree.fullParent(node))))
+}
+
+// shows the complete tree again with the cursor on the current node, :root!
+func (a *App) zoomOut() {
+	node := a.tree.GetCurrentNode()
+	if !a.tree.unzoom() {
+		a.statusLine.SetText("the tree shows all nodes")
+		return
+	}
+	expandPathToNode(a.tree, node)
+	a.tree.SetCurrentNode(node)
+	a.statusLine.SetText("complete tree")
+}
@@ -1,242 +1,710 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/alexflint/go-arg"
-	"github.com/gdamore/tcell/v2"
-	"github.com/rivo/tview"
-	"github.com/suyashkumar/dicom"
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/internal/logging"
+	"github.com/drcynic/dcmtagger/internal/ui"
+	"github.com/drcynic/dcmtagger/pkg/checksum"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dump"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/fhir"
+	"github.com/drcynic/dcmtagger/pkg/filter"
+	"github.com/drcynic/dcmtagger/pkg/hook"
+	"github.com/drcynic/dcmtagger/pkg/index"
+	"github.com/drcynic/dcmtagger/pkg/merge"
+	"github.com/drcynic/dcmtagger/pkg/multiframe"
+	"github.com/drcynic/dcmtagger/pkg/notes"
+	"github.com/drcynic/dcmtagger/pkg/remote"
+	"github.com/drcynic/dcmtagger/pkg/script"
+	"github.com/drcynic/dcmtagger/pkg/synth"
 )
 
 var version = "unknown"
 
 type args struct {
-	Input string `arg:"positional" help:"The DICOM input file or directory"`
+	Inputs     []string `arg:"positional" help:"The DICOM input files, directories or archives (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded. Several inputs are shown below a common root"`
+	Include    []string `arg:"--include" help:"Only load the files of directories and archives matching one of the glob patterns, e.g. '*.dcm'"`
+	Exclude    []string `arg:"--exclude" help:"Skip the files of directories and archives matching one of the glob patterns, e.g. '*/derived/*'"`
+	Index      bool     `arg:"--index" help:"Cache the tags of the input directory in an index, later starts only parse new and changed files"`
+	Protect    []string `arg:"--protect" help:"Additional tags that can only be edited with explicit override, keyword or group,element"`
+	Unprotect  []string `arg:"--unprotect" help:"Tags to remove from the default protected tags (SOP class, transfer syntax and pixel data structure)"`
+	Filter     string   `arg:"--filter" help:"Only show the files matching the filter expression, e.g. 'Modality=CT and PatientName~doe'"`
+	Search     string   `arg:"--search" help:"Start with the cursor on the first node containing the text like /, when the output is piped: only dump the elements containing the text, case insensitive"`
+	Sort       string   `arg:"--sort" help:"Start sorted by filename, tag, diff (by tag, only tags with different values) hierarchy (by patient, study and series) or module (by module of the IOD), overrides sortMode of the config file"`
+	Expand     *int     `arg:"--expand-depth" help:"Number of tree levels below the root expanded at startup, overrides expandDepth of the config file"`
+	ExpandAll  bool     `arg:"--expand-all" help:"Start with all tree nodes expanded"`
+	JSON       bool     `arg:"--json" help:"When the output is piped: dump in the DICOM JSON model instead of text lines"`
+	Log        string   `arg:"--log" help:"Append parse warnings, writes, network requests and edits to the log file"`
+	LogLevel   string   `arg:"--log-level" default:"info" help:"Minimum level of the logged records: debug, info, warn or error"`
+	Theme      string   `arg:"--theme" help:"Colors of the UI, dark or light, overrides the theme of the config file"`
+	MaxLength  *int     `arg:"--max-value-len" help:"Truncate longer values in the tree, negative for no truncation, overrides maxValueLength of the config file"`
+	Workers    *int     `arg:"--workers" help:"Number of files the viewer parses in parallel, the number of CPUs by default, overrides workers of the config file"`
+	Pseudonyms string   `arg:"--pseudonyms" help:"CSV file mapping patients to the pseudonyms of :pseudonymize, pseudonyms.csv in the config directory by default"`
+	Notes      string   `arg:"--notes" help:"JSON sidecar file of the notes of :note, dcmtagger-notes.json in the input directory or <file>.notes.json next to the input file by default"`
+	Yes        bool     `arg:"--yes" help:"Apply batch commands like :anonymize, :pseudonymize and :source without the preview of their changes"`
+	Output     string   `arg:"--output" help:"Write edited files to this directory, mirroring their paths relative to the input, instead of overwriting them"`
 }
 
 func (args) Version() string { return "Version " + version }
 
-type EditMode int
+func (args) Description() string {
+	return "Shows the inputs in the tree UI, the default without a command, dumps them like dump if the output is piped"
+}
 
-const (
-	TreeMode EditMode = iota
-	CmdlineMode
-)
+type scriptArgs struct {
+	Script string `arg:"positional,required" help:"The Starlark script to run"`
+	Input  string `arg:"positional,required" help:"The DICOM input file, directory or archive (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+	Yes    bool   `arg:"--yes" help:"Run the script without showing the changes it makes and asking first"`
+}
 
-func main() {
-	var args args
-	p := arg.MustParse(&args)
-	if args.Input == "" {
-		p.Fail("Missing DICOM input file or directory")
+func (scriptArgs) Description() string {
+	return "Runs a Starlark script headless against all datasets of the input, the datasets are available as 'datasets'"
+}
+
+// runs 'dcmtagger script <script> <input>' without starting the UI
+func runScriptCommand(commandArgs []string) {
+	var args scriptArgs
+	if _, ok := parseCommandArgs("script", &args, commandArgs); !ok {
+		return
+	}
+	input, cleanup, err := inputPath(args.Input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: '%s'\n", err.Error())
+		os.Exit(exitError)
+	}
+	defer cleanup()
+	entries, err := dicomtree.ParseFiles(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: '%s'\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	if !args.Yes {
+		changes, err := script.Preview(args.Script, entries)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running script: %s\n", err.Error())
+			cleanup()
+			os.Exit(exitError)
+		}
+		if len(changes) > 0 && !confirmChanges(changes, args.Input == "-") {
+			fmt.Println("Cancelled, no file changed")
+			return
+		}
+	}
+	writer, err := newWriter("", edit.NewProtectedTags(edit.DefaultProtectedTags))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running script: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	if err := script.Run(args.Script, entries, os.Stdout, writer.Save); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running script: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+}
+
+// prints the changes a script would make and asks on stdin whether to make them, refuses if stdin is the input
+func confirmChanges(changes []edit.Change, stdinIsInput bool) bool {
+	for _, c := range changes {
+		fmt.Printf("%s  %s %s: '%s' -> '%s'\n", c.File, c.Tag, c.Name, c.OldValue, c.NewValue)
+	}
+	if stdinIsInput {
+		fmt.Fprintln(os.Stderr, "The input is read from stdin, use --yes to make the changes")
+		return false
+	}
+	fmt.Printf("Make %d changes? [y/N] ", len(changes))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// returns the writer of the headless commands with the save hooks of the config file, see hook.Writer
+func newWriter(output string, protected edit.ProtectedTags) (*hook.Writer, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	return &hook.Writer{Commands: cfg.Hooks[hook.Save], Protected: protected, OutputDir: output}, nil
+}
+
+type hashArgs struct {
+	Input string `arg:"positional,required" help:"The DICOM input file, directory or archive (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+}
+
+func (hashArgs) Description() string {
+	return "Writes a checksum manifest with the SHA-256 of each file and of its decoded pixel data to stdout"
+}
+
+// runs 'dcmtagger hash <input>' without starting the UI
+func runHashCommand(commandArgs []string) {
+	var args hashArgs
+	if _, ok := parseCommandArgs("hash", &args, commandArgs); !ok {
+		return
+	}
+	input, cleanup, err := inputPath(args.Input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: '%s'\n", err.Error())
+		os.Exit(exitError)
+	}
+	defer cleanup()
+	entries, err := dicomtree.ParseFiles(input)
+	if err == nil {
+		err = checksum.WriteManifest(os.Stdout, entries)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error hashing input: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+}
+
+type fhirArgs struct {
+	Input string `arg:"positional,required" help:"The DICOM input file, directory or archive (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+}
+
+func (fhirArgs) Description() string {
+	return "Writes the studies as FHIR R4 bundle with ImagingStudy and Patient resources in JSON to stdout"
+}
+
+// runs 'dcmtagger fhir <input>' without starting the UI
+func runFHIRCommand(commandArgs []string) {
+	var args fhirArgs
+	if _, ok := parseCommandArgs("fhir", &args, commandArgs); !ok {
+		return
+	}
+	input, cleanup, err := inputPath(args.Input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: '%s'\n", err.Error())
+		os.Exit(exitError)
+	}
+	defer cleanup()
+	entries, err := dicomtree.ParseFiles(input)
+	if err == nil {
+		err = fhir.Write(os.Stdout, entries)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting input: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+}
+
+type synthArgs struct {
+	Modality  string `arg:"--modality" default:"CT" help:"Modality of the study: CT, MR, PT, CR, US or OT"`
+	Series    int    `arg:"--series" default:"1" help:"Number of series"`
+	Instances int    `arg:"--instances" default:"10" help:"Number of instances per series"`
+	Rows      int    `arg:"--rows" default:"256" help:"Rows of the pixel data"`
+	Columns   int    `arg:"--columns" default:"256" help:"Columns of the pixel data"`
+	Output    string `arg:"positional,required" help:"The output directory, created if needed"`
+}
+
+func (synthArgs) Description() string {
+	return "Generates a synthetic study with coherent UIDs and gradient pixel data, e.g. as fixture for PACS tests"
+}
+
+// runs 'dcmtagger synth [options] <output>' without starting the UI
+func runSynthCommand(commandArgs []string) {
+	var args synthArgs
+	if _, ok := parseCommandArgs("synth", &args, commandArgs); !ok {
+		return
+	}
+	entries, err := synth.Write(args.Output, synth.Options{
+		Modality: args.Modality, Series: args.Series, Instances: args.Instances, Rows: args.Rows, Columns: args.Columns,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating study: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+	fmt.Printf("Wrote %d instances to %s\n", len(entries), args.Output)
+}
+
+type convertArgs struct {
+	Input  string `arg:"positional,required" help:"The DICOM input file, directory or archive (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+	Output string `arg:"positional,required" help:"The output directory, created if needed"`
+}
+
+type splitArgs struct {
+	convertArgs
+}
+
+func (splitArgs) Description() string {
+	return "Splits enhanced multi-frame CT, MR and PET objects into classic single-frame instances"
+}
+
+type assembleArgs struct {
+	convertArgs
+}
+
+func (assembleArgs) Description() string {
+	return "Assembles the single-frame CT, MR and PET instances of each series into a legacy converted enhanced multi-frame object"
+}
+
+// runs 'dcmtagger split <input> <output>' or 'dcmtagger assemble <input> <output>' without starting the UI
+func runConvertCommand(command string, commandArgs []string) {
+	var split splitArgs
+	var assemble assembleArgs
+	var dest interface{} = &split
+	if command == "assemble" {
+		dest = &assemble
+	}
+	if _, ok := parseCommandArgs(command, dest, commandArgs); !ok {
+		return
+	}
+	args := split.convertArgs
+	if command == "assemble" {
+		args = assemble.convertArgs
+	}
+
+	input, cleanup, err := inputPath(args.Input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: '%s'\n", err.Error())
+		os.Exit(exitError)
 	}
+	defer cleanup()
+	entries, err := dicomtree.ParseFiles(input)
+	var paths []string
+	if err == nil && command == "split" {
+		paths, err = multiframe.WriteSplit(entries, args.Output)
+	} else if err == nil {
+		paths, err = multiframe.WriteAssembled(entries, args.Output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error converting input: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	fmt.Printf("Wrote %d files to %s\n", len(paths), args.Output)
+}
+
+type stripPixelsArgs struct {
+	Zero   bool   `arg:"--zero" help:"Replace the pixel data by zeros of the same size instead of removing it, so viewers still open the files"`
+	Input  string `arg:"positional,required" help:"The DICOM input file, directory or archive (.zip, .tar, .tar.gz), - reads a DICOM stream from stdin, http(s):// and s3:// URLs are downloaded"`
+	Output string `arg:"positional,required" help:"The output directory, created if needed"`
+}
+
+func (stripPixelsArgs) Description() string {
+	return "Writes copies of the files without their pixel data, e.g. to share a large study for metadata debugging"
+}
 
-	datasetsWithFilename, err := parseDicomFiles(args.Input)
+// runs 'dcmtagger strip-pixels [--zero] <input> <output>' without starting the UI
+func runStripPixelsCommand(commandArgs []string) {
+	var args stripPixelsArgs
+	if _, ok := parseCommandArgs("strip-pixels", &args, commandArgs); !ok {
+		return
+	}
+	input, cleanup, err := inputPath(args.Input)
 	if err != nil {
-		fmt.Printf("Error reading input: '%s'\n", err.Error())
+		fmt.Fprintf(os.Stderr, "Error reading input: '%s'\n", err.Error())
+		os.Exit(exitError)
+	}
+	defer cleanup()
+	entries, err := dicomtree.ListFiles(input) // parsed one at a time without the pixel data
+	var paths []string
+	if err == nil {
+		paths, err = edit.WriteStripped(entries, args.Output, args.Zero)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error stripping pixel data: %s\n", err.Error())
+		cleanup()
+		os.Exit(exitError)
+	}
+	fmt.Printf("Wrote %d files to %s\n", len(paths), args.Output)
+}
+
+type mergeArgs struct {
+	Paths []string `arg:"positional,required" help:"Two or more DICOM input directories, read recursively, followed by the output directory, created if needed"`
+}
+
+func (mergeArgs) Description() string {
+	return "Merges several exports of the same studies into one directory, copying each instance only once"
+}
+
+// runs 'dcmtagger merge <input>... <output>' without starting the UI
+func runMergeCommand(commandArgs []string) {
+	var args mergeArgs
+	p, ok := parseCommandArgs("merge", &args, commandArgs)
+	if !ok {
 		return
 	}
+	if len(args.Paths) < 3 {
+		failUsage(p, "Need at least two inputs and the output directory")
+	}
 
-	// global state
-	searchText := ""
-
-	// create tree nodes with dicom tags
-	app := tview.NewApplication()
-
-	rootDir := args.Input
-
-	pages := tview.NewPages()
-
-	statusLine := tview.NewTextView()
-
-	tree := tview.NewTreeView()
-	tree, root := sortTreeByFilename(rootDir, tree, datasetsWithFilename[:])
-	collapseAllRecursive(root)
-	statusLine.SetText("Sort by filename")
-	cmdline := tview.NewInputField().SetFieldBackgroundColor(tcell.ColorBlack)
-	mainGrid := tview.NewGrid().
-		SetRows(-1, 1, 1).
-		SetColumns(-1).
-		SetBorders(true).
-		AddItem(tree, 0, 0, 1, 1, 0, 0, true).
-		AddItem(statusLine, 1, 0, 1, 1, 0, 0, false).
-		AddItem(cmdline, 2, 0, 1, 1, 0, 0, false)
-
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyRune:
-			switch event.Rune() {
-			case '/':
-				app.SetFocus(cmdline)
-				cmdline.SetText("/")
-				return nil
-			case ':':
-				app.SetFocus(cmdline)
-				cmdline.SetText(":")
-				return nil
-			case '?':
-				addAndShowHelpPage(pages)
-				return nil
+	inputs, output := args.Paths[:len(args.Paths)-1], args.Paths[len(args.Paths)-1]
+	entries := make([][]dicomtree.Entry, 0, len(inputs))
+	for _, input := range inputs {
+		loaded, skipped, err := merge.Load(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %s\n", input, err.Error())
+			os.Exit(exitError)
+		}
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "Skipped %s: %s\n", s.Path, s.Reason)
+		}
+		entries = append(entries, loaded)
+	}
+	result, err := merge.Write(entries, output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error merging inputs: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+	for _, conflict := range result.Conflicts {
+		fmt.Fprintf(os.Stderr, "Instance %s differs between %s and %s, kept the first\n", conflict.SOPInstanceUID, conflict.Kept, conflict.Skipped)
+	}
+	fmt.Printf("Wrote %d files to %s, skipped %d duplicates and %d conflicting files\n", len(result.Written), output,
+		result.Duplicates, len(result.Conflicts))
+}
+
+// returns the default protected tags with the tags of --protect added and the ones of --unprotect removed
+func protectedTags(protect, unprotect []string) (edit.ProtectedTags, error) {
+	protected := edit.NewProtectedTags(edit.DefaultProtectedTags)
+	for _, text := range protect {
+		t, err := dicomtree.ParseTag(text)
+		if err != nil {
+			return nil, err
+		}
+		protected[t] = true
+	}
+	for _, text := range unprotect {
+		t, err := dicomtree.ParseTag(text)
+		if err != nil {
+			return nil, err
+		}
+		delete(protected, t)
+	}
+	return protected, nil
+}
+
+// returns the path to read the input from, a DICOM stream on stdin ('-') is buffered to a temporary file
+// removed by the returned cleanup, URLs are downloaded to the cache directory
+func inputPath(input string) (string, func(), error) {
+	if input == dicomtree.StdinInput {
+		return dicomtree.BufferStream(os.Stdin)
+	}
+	if remote.IsURL(input) {
+		path, err := remote.FetchWithProgress(input, func(done, total int) {
+			if total > 1 {
+				fmt.Fprintf(os.Stderr, "\rdownloading %d of %d files", done, total)
 			}
+			if total > 1 && done == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		})
+		return path, func() {}, err
+	}
+	return input, func() {}, nil
+}
+
+// sort modes of --sort
+var sortModes = map[string]int{"filename": 1, "tag": 2, "diff": 3, "hierarchy": 4, "module": 5}
+
+// expand depth of --expand-all, deeper than any tree
+const expandAllDepth = 1 << 16
+
+// returns the settings of the config file with the values given on the command line, the config file itself
+// keeps its values
+func startupConfig(cfg config.Config, args args) (config.Config, error) {
+	if args.Theme != "" {
+		cfg.Theme = args.Theme
+	}
+	if args.Sort != "" {
+		mode, ok := sortModes[args.Sort]
+		if !ok {
+			return cfg, fmt.Errorf("unknown sort '%s', use filename, tag, diff, hierarchy or module", args.Sort)
 		}
-		return event
-	})
+		cfg.SortMode = mode
+	}
+	if args.Expand != nil {
+		if *args.Expand < 0 {
+			return cfg, fmt.Errorf("negative expand depth %d", *args.Expand)
+		}
+		cfg.ExpandDepth = *args.Expand
+	}
+	if args.ExpandAll {
+		cfg.ExpandDepth = expandAllDepth
+	}
+	if args.MaxLength != nil {
+		cfg.MaxValueLength = *args.MaxLength
+	}
+	if args.Workers != nil {
+		cfg.Workers = *args.Workers
+	}
+	return cfg, nil
+}
 
-	cmdline.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEsc:
-			cmdline.SetText("")
-			app.SetFocus(tree)
-			return nil
-		case tcell.KeyEnter:
-			cmdlineText := cmdline.GetText()
-			if strings.HasPrefix(cmdlineText, ":") {
-				if cmdlineText == ":q" {
-					app.Stop()
-					return nil
-				} else if cmdlineText == ":w" {
-					if len(datasetsWithFilename) == 1 {
-						writeDatasetToFile(datasetsWithFilename[0].dataset, "write_test_copy.dcm")
-						statusLine.SetText("saved to write_test_copy.dcm")
-					}
-					cmdline.SetText("")
-					app.SetFocus(tree)
-				}
-				if cmdlineText == ":" {
-					cmdline.SetText("")
-					app.SetFocus(tree)
-					return nil
+// returns whether the file is a terminal, false e.g. if the output is redirected to a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// loads the entries of all inputs with load, the entries of directories and archives are grouped by their input if
+// there are several inputs. Archive members failing to parse are skipped with a warning on stderr. The returned
+// cleanup removes buffered stdin.
+func loadInputs(inputs []string, load func(path string) ([]dicomtree.Entry, error)) ([]dicomtree.Entry, func(), error) {
+	var entries []dicomtree.Entry
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+	for _, input := range inputs {
+		path, c, err := inputPath(input)
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("%s: %w", input, err)
+		}
+		cleanups = append(cleanups, c)
+		inputEntries, err := load(path)
+		var skipped *dicomtree.SkippedMembersError
+		if errors.As(err, &skipped) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err.Error())
+			err = nil
+		}
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("%s: %w", input, err)
+		}
+		if len(inputs) > 1 {
+			if info, err := os.Stat(path); err == nil && (info.IsDir() || dicomtree.IsArchive(path)) {
+				dicomtree.SetSource(inputEntries, strings.TrimSuffix(input, "/"))
+			} else if input != dicomtree.StdinInput {
+				for i := range inputEntries {
+					inputEntries[i].Filename = input // unique, unlike the names of files from different directories
 				}
 			}
-			if strings.HasPrefix(cmdlineText, "/") {
-				app.SetFocus(tree)
-				return nil
+		}
+		entries = append(entries, inputEntries...)
+	}
+	return entries, cleanup, nil
+}
+
+// writes all datasets matching the filter as text or JSON to stdout instead of starting the UI
+func dumpEntries(entries []dicomtree.Entry, args dumpArgs) error {
+	if args.Filter != "" {
+		expr, err := filter.Parse(args.Filter)
+		if err != nil {
+			return err
+		}
+		matching := make([]dicomtree.Entry, 0, len(entries))
+		for _, entry := range entries {
+			if expr.Match(&entry.Dataset) {
+				matching = append(matching, entry)
 			}
 		}
+		entries = matching
+	}
+	if args.JSON {
+		return dump.JSON(os.Stdout, entries, args.Search)
+	}
+	return dump.Text(os.Stdout, entries, args.Search)
+}
 
-		return event
-	})
+// opens and updates the index of the directory with the files matching the filter and returns its partial entries,
+// files failing to parse are skipped with a warning on stderr
+func openIndex(dir string, filter dicomtree.PathFilter) (*index.Index, []dicomtree.Entry, error) {
+	ix, err := index.Open(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	ix.SetPathFilter(filter)
+	_, skipped, err := ix.Update()
+	if err != nil {
+		ix.Close()
+		return nil, nil, err
+	}
+	for _, err := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %s\n", err.Error())
+	}
+	entries, err := ix.Entries()
+	if err != nil {
+		ix.Close()
+		return nil, nil, err
+	}
+	return ix, entries, nil
+}
 
-	cmdline.SetChangedFunc(func(text string) {
-		cmdlineText := text
-		if strings.HasPrefix(cmdlineText, "/") && len(cmdlineText) > 1 {
-			searchText = strings.ToLower(cmdlineText[1:])
-			jumpToNthFoundNode(searchText, 0, tree)
-		}
-	})
+// a subcommand 'dcmtagger <name> ...' with the go-arg struct of its arguments
+type subcommand struct {
+	name  string
+	args  interface{ Description() string } // the zero arguments for the help and the completion
+	words []string                          // fixed words completed for the positional arguments, files if empty
+	run   func(commandArgs []string)
+}
 
-	tree.SetSelectedFunc(func(node *tview.TreeNode) {
-		node.SetExpanded(!node.IsExpanded())
-	})
+// returns the subcommands, 'dcmtagger <input>...' without one runs view
+func subcommands() []subcommand {
+	convert := func(command string) func([]string) {
+		return func(commandArgs []string) { runConvertCommand(command, commandArgs) }
+	}
+	return []subcommand{
+		{name: "view", args: &args{}, run: func(commandArgs []string) { runViewCommand("dcmtagger view", commandArgs) }},
+		{name: "dump", args: &dumpArgs{}, run: runDumpCommand},
+		{name: "edit", args: &editArgs{}, run: runEditCommand},
+		{name: "anon", args: &anonArgs{}, run: runAnonCommand},
+		{name: "diff", args: &diffArgs{}, run: runDiffCommand},
+		{name: "validate", args: &validateArgs{}, run: runValidateCommand},
+		{name: "grep", args: &grepArgs{}, run: runGrepCommand},
+		{name: "send", args: &sendArgs{}, run: runSendCommand},
+		{name: "listen", args: &listenArgs{}, run: runListenCommand},
+		{name: "index", args: &indexArgs{}, run: runIndexCommand},
+		{name: "script", args: &scriptArgs{}, run: runScriptCommand},
+		{name: "hash", args: &hashArgs{}, run: runHashCommand},
+		{name: "fhir", args: &fhirArgs{}, run: runFHIRCommand},
+		{name: "synth", args: &synthArgs{}, run: runSynthCommand},
+		{name: "merge", args: &mergeArgs{}, run: runMergeCommand},
+		{name: "strip-pixels", args: &stripPixelsArgs{}, run: runStripPixelsCommand},
+		{name: "split", args: &splitArgs{}, run: convert("split")},
+		{name: "assemble", args: &assembleArgs{}, run: convert("assemble")},
+		{name: "completion", args: &completionArgs{}, words: completionShells, run: runCompletionCommand},
+	}
+}
 
-	// key handlings
-	tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		currentNode := tree.GetCurrentNode()
+// writes the subcommands with their descriptions, below the help of 'dcmtagger --help'
+func writeSubcommands(w io.Writer) {
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range subcommands() {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.args.Description())
+	}
+}
 
-		switch key := event.Key(); key {
-		case tcell.KeyCtrlSpace:
-			if isTagNode(currentNode) {
-				addAndShowTagEditingPage(pages, currentNode.GetReference().(*dicom.Element))
-			} else {
-				return event
-			}
-		case tcell.KeyCtrlD:
-			_, _, _, height := tree.GetInnerRect()
-			tree.Move(height / 2)
-		case tcell.KeyCtrlU:
-			_, _, _, height := tree.GetInnerRect()
-			tree.Move(-height / 2)
-		case tcell.KeyLeft:
-			if event.Modifiers() == tcell.ModShift {
-				moveToParent(tree)
-			} else {
-				collapseOrMoveToParent(tree)
-			}
-		case tcell.KeyRight:
-			if event.Modifiers() == tcell.ModShift {
-				moveToFirstChild(tree)
-			} else {
-				expandOrMoveToFirstChild(tree)
-			}
-		case tcell.KeyUp:
-			if event.Modifiers() == tcell.ModShift {
-				moveUpSameLevel(tree)
-			} else {
-				return event // not handled, pass on
-			}
-		case tcell.KeyDown:
-			if event.Modifiers() == tcell.ModShift {
-				moveDownSameLevel(tree)
-			} else {
-				return event // not handled, pass on
-			}
-		case tcell.KeyHome:
-			jumpToRoot(tree)
-		case tcell.KeyEnd:
-			jumpToLastVisibleNode(tree)
-		case tcell.KeyRune:
-			switch event.Rune() {
-			case '1':
-				tree, root = sortTreeByFilename(rootDir, tree, datasetsWithFilename[:])
-				collapseAllRecursive(root)
-				statusLine.SetText("Sort by filename")
-			case '2':
-				tree, root = sortTreeByTags(rootDir, tree, datasetsWithFilename[:], 0)
-				collapseAllLeaves(root)
-				statusLine.SetText("Sort by tag")
-			case '3':
-				tree, root = sortTreeByTags(rootDir, tree, datasetsWithFilename[:], 1)
-				collapseAllLeaves(root)
-				statusLine.SetText("Sort by tag, show only different tag values")
-			case 'q':
-				app.Stop()
-			case 'J':
-				moveDownSameLevel(tree)
-			case 'K':
-				moveUpSameLevel(tree)
-			case 'h':
-				collapseOrMoveToParent(tree)
-			case 'l':
-				expandOrMoveToFirstChild(tree)
-			case 'H':
-				moveToParent(tree)
-			case 'L':
-				moveToFirstChild(tree)
-			case '0', '^':
-				moveToFirstSibling(tree)
-			case '$':
-				moveToLastSibling(tree)
-			case 'e':
-				expandCurrentAndAllSiblings(tree)
-			case 'c':
-				collapseCurrentAndAllSiblings(tree)
-			case 'E':
-				currentNode.ExpandAll()
-			case 'C':
-				currentNode.CollapseAll()
-			case 'g':
-				jumpToRoot(tree)
-			case 'G':
-				jumpToLastVisibleNode(tree)
-			case 'n':
-				jumpToNextFoundNode(searchText, tree)
-			case 'N':
-				jumpToPrevFoundNode(searchText, tree)
-
-			default:
-				return event // not handled, pass on
+func main() {
+	logging.Disable() // the records would mix with the UI and the output of the subcommands, see --log
+	// go-arg doesn't allow positionals next to subcommands, so the subcommands are handled separately
+	if len(os.Args) > 1 {
+		for _, c := range subcommands() {
+			if c.name == os.Args[1] {
+				c.run(os.Args[2:])
+				return
 			}
-		default:
-			return event // not handled, pass on
 		}
+	}
+	runViewCommand("dcmtagger", os.Args[1:])
+}
 
-		return nil
-	})
+// runs 'dcmtagger [view] [options] <input>...' starting the UI, or dumping the inputs if the output is piped
+func runViewCommand(program string, commandArgs []string) {
+	var args args
+	p, err := arg.NewParser(arg.Config{Program: program}, &args)
+	if err != nil {
+		panic(err)
+	}
+	if err := p.Parse(commandArgs); err == arg.ErrHelp {
+		p.WriteHelp(os.Stdout)
+		if program == "dcmtagger" {
+			writeSubcommands(os.Stdout)
+		}
+		return
+	} else if err == arg.ErrVersion {
+		fmt.Println(args.Version())
+		return
+	} else if err != nil {
+		p.Fail(err.Error())
+	}
+	if len(args.Inputs) == 0 {
+		p.Fail("Missing DICOM input file or directory")
+	}
+	if args.Log != "" {
+		closeLog, err := logging.Open(args.Log, args.LogLevel)
+		if err != nil {
+			p.Fail(err.Error())
+		}
+		defer closeLog()
+	}
+	protected, err := protectedTags(args.Protect, args.Unprotect)
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	pathFilter, err := dicomtree.NewPathFilter(args.Include, args.Exclude)
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error reading config, using defaults: %s\n", err.Error())
+	}
+	inputs := make([]string, len(args.Inputs))
+	for i, input := range args.Inputs {
+		inputs[i] = cfg.ExpandInput(input)
+	}
+	if !isTerminal(os.Stdout) {
+		entries, cleanup, err := loadInputs(inputs, func(path string) ([]dicomtree.Entry, error) {
+			return dicomtree.ParseFilesFiltered(path, pathFilter)
+		})
+		if err == nil {
+			err = dumpEntries(entries, dumpArgs{Filter: args.Filter, Search: args.Search, JSON: args.JSON})
+			cleanup()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error dumping input: %s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	startup, err := startupConfig(cfg, args)
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	if err := ui.SetTheme(startup.Theme); err != nil {
+		p.Fail(err.Error())
+	}
 
-	pages.AddPage("main", mainGrid, true, true)
+	var ix *index.Index
+	entries, cleanup, err := loadInputs(inputs, func(path string) ([]dicomtree.Entry, error) {
+		if info, statErr := os.Stat(path); args.Index && len(inputs) == 1 && statErr == nil && info.IsDir() {
+			var entries []dicomtree.Entry
+			var err error
+			ix, entries, err = openIndex(path, pathFilter)
+			return entries, err
+		}
+		return dicomtree.ListFilesFiltered(path, pathFilter) // files of a directory are parsed when expanded
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %s\n", err.Error())
+		os.Exit(1)
+	}
+	defer cleanup()
 
-	if err := app.SetRoot(pages, true).Run(); err != nil {
+	rootText := args.Inputs[0]
+	if len(args.Inputs) > 1 {
+		rootText = fmt.Sprintf("%d inputs", len(args.Inputs))
+	}
+	app := ui.NewApp(rootText, entries).SetProtectedTags(protected)
+	if ix != nil {
+		defer ix.Close()
+		app.SetIndex(ix)
+	}
+	if args.Filter != "" {
+		app.SetFilter(args.Filter)
+	}
+	if args.Pseudonyms != "" {
+		app.SetPseudonymsFile(args.Pseudonyms)
+	}
+	notesFile := args.Notes
+	if notesFile == "" && len(args.Inputs) == 1 {
+		notesFile = notes.DefaultPath(args.Inputs[0])
+	}
+	if notesFile != "" {
+		app.SetNotesFile(notesFile)
+	}
+	app.SetAssumeYes(args.Yes).SetOutputDir(args.Output)
+	app.SetConfig(&cfg).ApplyConfig(startup) // after index and filter, so their tree is sorted and expanded
+	if args.Search != "" {
+		app.SetSearch(args.Search)
+	}
+	if err := app.Run(); err != nil {
 		panic(err)
 	}
 }
@@ -1,9 +1,20 @@
 package main
 
 import (
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/drcynic/dcmtagger/internal/config"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/hook"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
 func TestSomething(t *testing.T) {
@@ -13,3 +24,172 @@ func TestSomething(t *testing.T) {
 	input := 16
 	assert.Equal(16, input, "just a test test")
 }
+
+func TestProtectedTags(t *testing.T) {
+	assert := assert.New(t)
+
+	protected, err := protectedTags([]string{"0010,0010"}, []string{"(7fe0,0010)"})
+	assert.NoError(err)
+	assert.True(protected[tag.PatientName])
+	assert.True(protected[tag.TransferSyntaxUID])
+	assert.False(protected[tag.PixelData])
+
+	_, err = protectedTags([]string{"nope"}, nil)
+	assert.Error(err)
+}
+
+func TestStartupConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := config.Config{SortMode: 2, ExpandDepth: 1, Theme: "light", Workers: 4}
+	startup, err := startupConfig(cfg, args{})
+	assert.NoError(err)
+	assert.Equal(cfg, startup)
+
+	depth, length := 3, -1
+	startup, err = startupConfig(cfg, args{Sort: "diff", Expand: &depth, Theme: "dark", MaxLength: &length})
+	assert.NoError(err)
+	assert.Equal(config.Config{SortMode: 3, ExpandDepth: 3, Theme: "dark", MaxValueLength: -1, Workers: 4}, startup)
+	assert.Equal(2, cfg.SortMode, "config file values unchanged")
+
+	startup, err = startupConfig(cfg, args{Sort: "filename", ExpandAll: true})
+	assert.NoError(err)
+	assert.Equal(1, startup.SortMode)
+	assert.Equal(expandAllDepth, startup.ExpandDepth)
+
+	_, err = startupConfig(cfg, args{Sort: "size"})
+	assert.Error(err)
+	depth = -1
+	_, err = startupConfig(cfg, args{Expand: &depth})
+	assert.Error(err)
+}
+
+func TestParseEdits(t *testing.T) {
+	assert := assert.New(t)
+
+	protected, err := protectedTags(nil, nil)
+	assert.NoError(err)
+	assignments, deletions, err := parseEdits([]string{"0010,0010=Doe^John", "0008,0060 = CT\\MR"}, []string{"0010,0020"}, protected)
+	assert.NoError(err)
+	assert.Equal([]assignment{{tag.PatientName, []string{"Doe^John"}}, {tag.Modality, []string{" CT", "MR"}}}, assignments)
+	assert.Equal([]tag.Tag{tag.PatientID}, deletions)
+
+	_, _, err = parseEdits([]string{"0010,0010"}, nil, protected)
+	assert.ErrorContains(err, "use <tag>=<value>")
+	_, _, err = parseEdits(nil, []string{"7fe0,0010"}, protected)
+	assert.ErrorContains(err, "protected")
+}
+
+func TestWriteCompletion(t *testing.T) {
+	assert := assert.New(t)
+
+	flags := commandFlags(&splitArgs{})
+	assert.Equal([]commandFlag{{"--help", "Display this help and exit", false}}, flags, "positionals of the embedded struct are no flags")
+	flags = commandFlags(&dumpArgs{})
+	assert.Contains(flags, commandFlag{"--json", "Dump in the DICOM JSON model instead of text lines", false})
+	assert.Contains(flags, commandFlag{"--filter", "Only dump the files matching the filter expression, e.g. 'Modality=CT and PatientName~doe'", true})
+
+	var bash, zsh, fish strings.Builder
+	assert.NoError(writeCompletion(&bash, "bash", subcommands()))
+	assert.Contains(bash.String(), `COMPREPLY=($(compgen -W "view dump edit anon diff validate grep send listen index`)
+	assert.Contains(bash.String(), `dump) flags="--include --exclude --filter --search --json --help" words="" ;;`)
+	assert.Contains(bash.String(), `completion) flags="--help" words="bash zsh fish" ;;`)
+	assert.Contains(bash.String(), `*) flags="--include --exclude --index`, "view flags without a command")
+
+	assert.NoError(writeCompletion(&zsh, "zsh", subcommands()))
+	assert.Contains(zsh.String(), "'dump:Writes the datasets as one line per element or as DICOM JSON to stdout'")
+	assert.Contains(zsh.String(), "'*--filter[Only dump the files matching the filter expression]:value:'")
+	assert.Contains(zsh.String(), "'--json[Dump in the DICOM JSON model instead of text lines]'")
+	assert.Contains(zsh.String(), "'1:completion:(bash zsh fish)'")
+
+	assert.NoError(writeCompletion(&fish, "fish", subcommands()))
+	assert.Contains(fish.String(), "complete -c dcmtagger -n __fish_use_subcommand -a send -d 'Sends all files of the inputs to a node with C-STORE'")
+	assert.Contains(fish.String(), "complete -c dcmtagger -n '__fish_seen_subcommand_from listen' -l port -r -d 'The TCP port to listen on'")
+	assert.Contains(fish.String(), "complete -c dcmtagger -n 'not __fish_seen_subcommand_from dump edit")
+
+	assert.ErrorContains(writeCompletion(&bash, "tcsh", subcommands()), "unknown shell 'tcsh', use bash, zsh, fish")
+}
+
+func newTestEntry(t *testing.T, filename, path, patientName string, extra ...*dicom.Element) dicomtree.Entry {
+	elements := append([]*dicom.Element{mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientName, []string{patientName})}, extra...)
+	return dicomtree.Entry{Filename: filename, Path: path, Dataset: dicom.Dataset{Elements: elements}}
+}
+
+func TestDiffEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []dicomtree.Entry{
+		newTestEntry(t, "a.dcm", "x/a.dcm", "Doe^John", mustElement(t, tag.PatientID, []string{"1"})),
+		newTestEntry(t, "b.dcm", "x/b.dcm", "Doe^Jane"),
+	}
+	diffs := diffEntries(entries)
+	require.Len(t, diffs, 2, "modality is the same in both files")
+	assert.Equal("(0010,0010)", diffs[0].Tag)
+	assert.Equal(map[string]string{"a.dcm": "Doe^John", "b.dcm": "Doe^Jane"}, diffs[0].Values)
+	assert.Equal(map[string]string{"a.dcm": "1"}, diffs[1].Values, "missing in b.dcm")
+
+	var out strings.Builder
+	assert.NoError(writeDiffs(&out, diffs[1:], entryNames(entries)))
+	assert.True(strings.HasPrefix(out.String(), "(0010,0020)"))
+	assert.Contains(out.String(), "  a.dcm: 1\n  b.dcm: <missing>\n")
+
+	entries[1].Filename = "a.dcm"
+	assert.Equal([]string{"x/a.dcm", "x/b.dcm"}, entryNames(entries), "paths for ambiguous filenames")
+	assert.Empty(diffEntries(entries[:1]))
+}
+
+func TestChangeEntries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh")
+	}
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	entries := []dicomtree.Entry{
+		newTestEntry(t, "a.dcm", filepath.Join(dir, "a.dcm"), "Doe^John"),
+		newTestEntry(t, "b.dcm", filepath.Join(dir, "b.dcm"), "Roe^Jane"),
+	}
+	writer := &hook.Writer{Commands: []string{`echo '{"00080080": {"vr": "LO", "Value": ["Clinic"]}}'`}, OutputDir: filepath.Join(dir, "out")}
+	results, err := changeEntries(entries, writer, func(dataset *dicom.Dataset) error {
+		if dicomtree.ItemValue(dataset.Elements, tag.PatientName) == "Doe^John" {
+			_, err := edit.Set(dataset, tag.PatientName, []string{"Anonymous"})
+			return err
+		}
+		return nil
+	})
+	assert.NoError(err)
+	require.Len(t, results, 2)
+	assert.Equal(filepath.Join(dir, "out", "a.dcm"), results[0].Written)
+	assert.Len(results[0].Changes, 2, "the change of the save hook is listed")
+	assert.Empty(results[1].Written, "unchanged files are not written")
+	assert.FileExists(filepath.Join(dir, "out", "a.dcm"))
+	assert.NoFileExists(filepath.Join(dir, "out", "b.dcm"))
+
+	writer.Commands = []string{"exit 1"}
+	_, err = changeEntries(entries, writer, func(dataset *dicom.Dataset) error {
+		_, err := edit.Set(dataset, tag.PatientID, []string{"1"})
+		return err
+	})
+	assert.EqualError(err, "a.dcm: save hook 'exit 1' failed for a.dcm: exit status 1")
+}
+
+func TestGrepEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []dicomtree.Entry{newTestEntry(t, "a.dcm", "a.dcm", "Doe^John"), newTestEntry(t, "doe.dcm", "doe.dcm", "Roe^Jane")}
+	matches := grepEntries(entries, regexp.MustCompile(`(?i)doe\^`))
+	require.Len(t, matches, 1, "the filename is not matched")
+	assert.Equal("a.dcm", matches[0].File)
+	assert.Equal("(0010,0010)", matches[0].Path)
+	assert.Empty(grepEntries(entries, regexp.MustCompile(`doe\^`)))
+	assert.Len(grepEntries(entries, regexp.MustCompile(`^\(0008,0060\) .*CT$`)), 2)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
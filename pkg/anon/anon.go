@@ -0,0 +1,74 @@
+// Package anon removes or replaces identifying attributes of datasets.
+package anon
+
+import (
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+type Action int
+
+const (
+	Remove  Action = iota // remove the element
+	Empty                 // keep the element with an empty value
+	Replace               // replace the value with the rule value
+)
+
+type Rule struct {
+	Tag    tag.Tag
+	Action Action
+	Value  string // used by Replace
+}
+
+// rules for the most common identifying attributes of patient, study and institution
+var BasicRules = []Rule{
+	{tag.PatientName, Replace, "ANONYMIZED"},
+	{tag.PatientID, Replace, "ANONYMIZED"},
+	{tag.PatientBirthDate, Empty, ""},
+	{tag.PatientAddress, Remove, ""},
+	{tag.PatientTelephoneNumbers, Remove, ""},
+	{tag.PatientMotherBirthName, Remove, ""},
+	{tag.OtherPatientIDs, Remove, ""},
+	{tag.OtherPatientNames, Remove, ""},
+	{tag.MilitaryRank, Remove, ""},
+	{tag.EthnicGroup, Remove, ""},
+	{tag.PatientComments, Remove, ""},
+	{tag.InstitutionName, Remove, ""},
+	{tag.InstitutionAddress, Remove, ""},
+	{tag.ReferringPhysicianName, Empty, ""},
+	{tag.PhysiciansOfRecord, Remove, ""},
+	{tag.PerformingPhysicianName, Remove, ""},
+	{tag.OperatorsName, Remove, ""},
+	{tag.AccessionNumber, Empty, ""},
+	{tag.StudyID, Empty, ""},
+}
+
+// applies the rules to the top level elements of the dataset and marks the patient identity as removed,
+// returns the number of changed elements
+func Apply(dataset *dicom.Dataset, rules []Rule) (int, error) {
+	changed := 0
+	for _, rule := range rules {
+		if _, err := dataset.FindElementByTag(rule.Tag); err != nil {
+			continue // only existing elements are touched
+		}
+		switch rule.Action {
+		case Remove:
+			edit.Delete(dataset, rule.Tag)
+		case Empty:
+			if _, err := edit.Set(dataset, rule.Tag, []string{""}); err != nil {
+				return changed, err
+			}
+		case Replace:
+			if _, err := edit.Set(dataset, rule.Tag, []string{rule.Value}); err != nil {
+				return changed, err
+			}
+		}
+		changed++
+	}
+
+	if _, err := edit.Set(dataset, tag.PatientIdentityRemoved, []string{"YES"}); err != nil {
+		return changed, err
+	}
+	return changed, nil
+}
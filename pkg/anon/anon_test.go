@@ -0,0 +1,280 @@
+package anon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestApply(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.InstitutionName, []string{"Hospital"}),
+		mustElement(t, tag.PatientName, []string{"Doe^John"}),
+		mustElement(t, tag.PatientBirthDate, []string{"19700101"}),
+	}}
+
+	changed, err := Apply(&dataset, BasicRules)
+	assert.NoError(err)
+	assert.Equal(3, changed)
+
+	_, err = dataset.FindElementByTag(tag.InstitutionName)
+	assert.Error(err, "removed")
+	name, err := dataset.FindElementByTag(tag.PatientName)
+	assert.NoError(err)
+	assert.Equal([]string{"ANONYMIZED"}, name.Value.GetValue())
+	birthDate, err := dataset.FindElementByTag(tag.PatientBirthDate)
+	assert.NoError(err)
+	assert.Equal([]string{""}, birthDate.Value.GetValue())
+	_, err = dataset.FindElementByTag(tag.PatientID)
+	assert.Error(err, "missing elements are not added")
+	removed, err := dataset.FindElementByTag(tag.PatientIdentityRemoved)
+	assert.NoError(err)
+	assert.Equal([]string{"YES"}, removed.Value.GetValue())
+}
+
+func TestAudit(t *testing.T) {
+	assert := assert.New(t)
+
+	privateElement := func(t tag.Tag, value string) *dicom.Element {
+		v, err := dicom.NewValue([]string{value})
+		assert.NoError(err)
+		return &dicom.Element{Tag: t, RawValueRepresentation: "LO", Value: v} // not in the dictionary of NewElement
+	}
+	comments := mustElement(t, tag.ImageComments, []string{"John's follow-up"})
+	private := privateElement(tag.Tag{Group: 0x0009, Element: 0x1001}, "Doe")
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.PatientName, []string{"ANONYMIZED"}),
+		mustElement(t, tag.PatientID, []string{"12345"}),
+		mustElement(t, tag.StudyDescription, []string{""}),
+		mustElement(t, tag.ReferencedImageSequence, [][]*dicom.Element{{comments}}),
+		privateElement(tag.Tag{Group: 0x0009, Element: 0x0010}, "ACME"),
+		private,
+		mustElement(t, tag.Modality, []string{"CT"}),
+	}}
+	risks := Audit(&dataset)
+	assert.Len(risks, 3)
+	assert.Equal(Risk{RiskIdentifying, "(0010,0020)", dataset.Elements[1], "12345"}, risks[0])
+	assert.Equal(Risk{RiskFreeText, "(0008,1140)/1/(0020,4000)", comments, "John's follow-up"}, risks[1])
+	assert.Equal(Risk{RiskPrivate, "(0009,1001)", private, "Doe"}, risks[2])
+
+	dataset.Elements = append(dataset.Elements, mustElement(t, tag.PixelData, []byte{0}))
+	risks = Audit(&dataset)
+	assert.Equal(RiskBurnedIn, risks[len(risks)-1].Category, "burned-in annotation not specified")
+	assert.Nil(risks[len(risks)-1].Element)
+	dataset.Elements = append(dataset.Elements, mustElement(t, tag.BurnedInAnnotation, []string{"NO"}))
+	assert.Len(Audit(&dataset), 3)
+}
+
+func TestPseudonyms(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "dcmtagger", "pseudonyms.csv")
+	pseudonyms, err := LoadPseudonyms(path)
+	require.NoError(t, err)
+	added, err := pseudonyms.Import(strings.NewReader("PatientID,PatientName,PseudonymID\n7,Roe^Richard,STUDY-7\n"))
+	assert.NoError(err)
+	assert.Equal(1, added)
+
+	patient := func(id, name string) *dicom.Dataset {
+		return &dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.PatientName, []string{name}),
+			mustElement(t, tag.PatientID, []string{id}),
+		}}
+	}
+	john := patient("42", "Doe^John")
+	changed, err := pseudonyms.Apply(john)
+	assert.NoError(err)
+	assert.True(changed)
+	assert.Equal([]string{"PSEUDO-000002"}, john.Elements[1].Value.GetValue())
+	assert.Equal([]string{"PSEUDO^000002"}, john.Elements[0].Value.GetValue())
+	changed, err = pseudonyms.Apply(john)
+	assert.NoError(err)
+	assert.False(changed, "already pseudonymized")
+	require.NoError(t, pseudonyms.Save())
+
+	pseudonyms, err = LoadPseudonyms(path)
+	require.NoError(t, err)
+	assert.Equal(Pseudonym{"42", "Doe^John", "PSEUDO-000002", "PSEUDO^000002"}, pseudonyms.Get("42", "Doe J"), "same patient ID in the next run")
+	assert.Equal("STUDY-7", pseudonyms.Get("7", "").ID)
+	assert.Equal("PSEUDO-000003", pseudonyms.Get("", "Doe^Jane").ID, "identified by name without ID")
+	assert.True(pseudonyms.Changed)
+
+	_, err = pseudonyms.Import(strings.NewReader("1,Doe\n"))
+	assert.Error(err)
+}
+
+func TestProfile(t *testing.T) {
+	assert := assert.New(t)
+
+	newDataset := func() *dicom.Dataset {
+		date := mustElement(t, tag.StudyDate, []string{"20240301"})
+		date.RawValueRepresentation = "DA"
+		private, err := dicom.NewValue([]string{"ACME"})
+		require.NoError(t, err)
+		return &dicom.Dataset{Elements: []*dicom.Element{
+			date,
+			mustElement(t, tag.InstitutionName, []string{"Hospital"}),
+			mustElement(t, tag.StudyDescription, []string{"Head"}),
+			{Tag: tag.Tag{Group: 0x0009, Element: 0x0010}, RawValueRepresentation: "LO", Value: private},
+			mustElement(t, tag.PatientSex, []string{"F"}),
+			mustElement(t, tag.PatientName, []string{"Doe^Jane"}),
+		}}
+	}
+	value := func(dataset *dicom.Dataset, t tag.Tag) string {
+		if _, err := dataset.FindElementByTag(t); err != nil {
+			return "<removed>"
+		}
+		return dicomtree.UIDValue(dataset, t)
+	}
+
+	dataset := newDataset()
+	changed, err := Profile{}.Apply(dataset, nil)
+	assert.NoError(err)
+	assert.Equal(6, changed)
+	for _, removed := range []tag.Tag{tag.InstitutionName, tag.StudyDescription, tag.PatientSex, {Group: 0x0009, Element: 0x0010}} {
+		assert.Equal("<removed>", value(dataset, removed))
+	}
+	assert.Equal("", value(dataset, tag.StudyDate))
+	assert.Equal("REMOVED", value(dataset, tag.LongitudinalTemporalInformationModified))
+	assert.Equal("dcmtagger basic", value(dataset, tag.DeidentificationMethod))
+
+	dataset = newDataset()
+	profile := Profile{
+		Name:          "site",
+		Options:       []string{"retain-patient-characteristics", "retain-longitudinal-modified-dates", "retain-safe-private"},
+		Keep:          []string{"(0008,1030)"},
+		Replace:       map[string]string{"(0008,0080)": "SITE 01"},
+		DateShiftDays: -31,
+	}
+	_, err = profile.Apply(dataset, nil)
+	assert.NoError(err)
+	assert.Equal("20240130", value(dataset, tag.StudyDate))
+	assert.Equal("SITE 01", value(dataset, tag.InstitutionName))
+	assert.Equal("Head", value(dataset, tag.StudyDescription))
+	assert.Equal("F", value(dataset, tag.PatientSex))
+	assert.Equal("<removed>", value(dataset, tag.Tag{Group: 0x0009, Element: 0x0010}), "not a known safe private tag")
+	assert.Equal("MODIFIED", value(dataset, tag.LongitudinalTemporalInformationModified))
+	assert.Equal("dcmtagger site, retain-patient-characteristics, retain-longitudinal-modified-dates, retain-safe-private", value(dataset, tag.DeidentificationMethod))
+
+	dates := mustElement(t, tag.CalibrationDate, []string{"20240301", "20240302"})
+	dates.RawValueRepresentation = "DA"
+	dataset = &dicom.Dataset{Elements: []*dicom.Element{dates}}
+	_, err = profile.Apply(dataset, nil)
+	assert.NoError(err)
+	assert.Equal([]string{"20240130", "20240131"}, dicomtree.ValueStrings(dates), "every value is shifted")
+
+	assert.Error(Profile{Options: []string{"retain-everything"}}.Validate())
+	assert.Error(Profile{UIDs: "new"}.Validate())
+	assert.Error(Profile{Dates: DatesShift}.Validate(), "no shift days")
+	assert.Error(Profile{Remove: []string{"NoSuchTag"}}.Validate())
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "research.json"), []byte(`{"options": ["retain-device-identity"], "dates": "keep"}`), 0o644))
+	profile, err = LoadProfile("research", dir)
+	assert.NoError(err)
+	assert.Equal(Profile{Name: "research", Options: []string{"retain-device-identity"}, Dates: DatesKeep}, profile)
+	profile, err = LoadProfile("clean-descriptors", dir)
+	assert.NoError(err)
+	assert.Equal([]string{"clean-descriptors"}, profile.Options)
+	_, err = LoadProfile("missing", dir)
+	assert.Error(err)
+}
+
+func TestProfileSequencesAndUIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	newDataset := func(sopInstanceUID string) *dicom.Dataset {
+		time := mustElement(t, tag.StudyTime, []string{"101500"})
+		time.RawValueRepresentation = "TM"
+		referenced, err := dicom.NewValue([][]*dicom.Element{{
+			mustElement(t, tag.ReferencedSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+			mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3.1"}),
+			mustElement(t, tag.PatientName, []string{"Doe^Jane"}),
+		}})
+		require.NoError(t, err)
+		return &dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+			mustElement(t, tag.SOPInstanceUID, []string{sopInstanceUID}),
+			time,
+			mustElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+			{Tag: tag.ReferencedImageSequence, ValueRepresentation: tag.VRSequence, RawValueRepresentation: "SQ", Value: referenced},
+		}}
+	}
+	item := func(dataset *dicom.Dataset) []*dicom.Element {
+		e, err := dataset.FindElementByTag(tag.ReferencedImageSequence)
+		require.NoError(t, err)
+		return dicomtree.SequenceItems(e)[0]
+	}
+
+	uids := UIDMap{}
+	first, second := newDataset("1.2.3.1"), newDataset("1.2.3.2")
+	_, err := Profile{}.Apply(first, uids)
+	assert.NoError(err)
+	_, err = Profile{}.Apply(second, uids)
+	assert.NoError(err)
+	assert.Equal("1.2.840.10008.5.1.4.1.1.2", dicomtree.UIDValue(first, tag.SOPClassUID), "standard UIDs are kept")
+	assert.NotEqual("1.2.3", dicomtree.UIDValue(first, tag.StudyInstanceUID))
+	assert.Equal(dicomtree.UIDValue(first, tag.StudyInstanceUID), dicomtree.UIDValue(second, tag.StudyInstanceUID), "same study")
+	assert.NotEqual(dicomtree.UIDValue(first, tag.SOPInstanceUID), dicomtree.UIDValue(second, tag.SOPInstanceUID))
+	assert.Equal("", dicomtree.UIDValue(first, tag.StudyTime))
+	referenced := item(second)
+	assert.Equal(dicomtree.UIDValue(first, tag.SOPInstanceUID), dicomtree.ValueText(dicomtree.FindItemElement(referenced, tag.ReferencedSOPInstanceUID), nil),
+		"the reference follows the referenced instance")
+	assert.Equal("ANONYMIZED", dicomtree.ValueText(dicomtree.FindItemElement(referenced, tag.PatientName), nil), "rules apply to items")
+
+	dataset := newDataset("1.2.3.1")
+	_, err = Profile{DateShiftDays: 10, Options: []string{"retain-uids", "retain-longitudinal-modified-dates"}}.Apply(dataset, nil)
+	assert.NoError(err)
+	assert.Equal("1.2.3", dicomtree.UIDValue(dataset, tag.StudyInstanceUID))
+	assert.Equal("1.2.3.1", dicomtree.ValueText(dicomtree.FindItemElement(item(dataset), tag.ReferencedSOPInstanceUID), nil))
+	assert.Equal("101500", dicomtree.UIDValue(dataset, tag.StudyTime), "times are kept when shifting")
+}
+
+func TestProfileSafePrivateAndDescriptors(t *testing.T) {
+	assert := assert.New(t)
+
+	private := func(element uint16, vr string, data []string) *dicom.Element {
+		value, err := dicom.NewValue(data)
+		require.NoError(t, err)
+		return &dicom.Element{Tag: tag.Tag{Group: 0x0019, Element: element}, RawValueRepresentation: vr, Value: value}
+	}
+	dataset := &dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.PatientName, []string{"Doe^Jane"}),
+		mustElement(t, tag.PatientID, []string{"P-4711"}),
+		mustElement(t, tag.StudyDescription, []string{"Head DOE p-4711 follow-up"}),
+		mustElement(t, tag.SeriesDescription, []string{"T1 axial"}),
+		private(0x0010, "LO", []string{"SIEMENS MR HEADER"}),
+		private(0x100c, "IS", []string{"1000"}),
+		private(0x1010, "LO", []string{"Jane"}),
+		private(0x0011, "LO", []string{"ACME"}),
+		private(0x110c, "LO", []string{"Doe"}),
+	}}
+	_, err := Profile{Options: []string{"retain-safe-private", "clean-descriptors"}}.Apply(dataset, nil)
+	assert.NoError(err)
+	var kept []tag.Tag
+	for _, e := range dataset.Elements {
+		if e.Tag.Group == 0x0019 {
+			kept = append(kept, e.Tag)
+		}
+	}
+	assert.Equal([]tag.Tag{{Group: 0x0019, Element: 0x0010}, {Group: 0x0019, Element: 0x100c}}, kept, "the B_value with its creator")
+	assert.Equal("Head follow-up", dicomtree.UIDValue(dataset, tag.StudyDescription))
+	assert.Equal("T1 axial", dicomtree.UIDValue(dataset, tag.SeriesDescription))
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
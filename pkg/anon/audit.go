@@ -0,0 +1,135 @@
+package anon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+type RiskCategory string
+
+const (
+	RiskIdentifying RiskCategory = "identifying"
+	RiskFreeText    RiskCategory = "free text"
+	RiskPrivate     RiskCategory = "private"
+	RiskBurnedIn    RiskCategory = "burned-in"
+)
+
+// attribute that may still contain protected health information
+type Risk struct {
+	Category RiskCategory
+	Path     string         // tag with the path of its sequence and item, e.g. "(0040,0275)/1/(0032,1060)"
+	Element  *dicom.Element // nil for a missing BurnedInAnnotation
+	Value    string
+}
+
+// attributes identifying patient, staff or institution, listed if they have a value other than the replacement of
+// BasicRules
+var identifyingTags = map[tag.Tag]bool{
+	tag.PatientName:                      true,
+	tag.PatientID:                        true,
+	tag.IssuerOfPatientID:                true,
+	tag.OtherPatientIDs:                  true,
+	tag.OtherPatientNames:                true,
+	tag.PatientBirthName:                 true,
+	tag.PatientBirthDate:                 true,
+	tag.PatientAddress:                   true,
+	tag.PatientTelephoneNumbers:          true,
+	tag.PatientMotherBirthName:           true,
+	tag.AccessionNumber:                  true,
+	tag.StudyID:                          true,
+	tag.InstitutionName:                  true,
+	tag.InstitutionAddress:               true,
+	tag.InstitutionalDepartmentName:      true,
+	tag.StationName:                      true,
+	tag.DeviceSerialNumber:               true,
+	tag.ReferringPhysicianName:           true,
+	tag.PerformingPhysicianName:          true,
+	tag.NameOfPhysiciansReadingStudy:     true,
+	tag.RequestingPhysician:              true,
+	tag.ScheduledPerformingPhysicianName: true,
+	tag.OperatorsName:                    true,
+	tag.ContentCreatorName:               true,
+	tag.PersonName:                       true,
+}
+
+// descriptions and comments in which operators often type names, dates or diagnoses
+var freeTextTags = map[tag.Tag]bool{
+	tag.StudyDescription:                  true,
+	tag.SeriesDescription:                 true,
+	tag.ImageComments:                     true,
+	tag.PatientComments:                   true,
+	tag.AdditionalPatientHistory:          true,
+	tag.AdmittingDiagnosesDescription:     true,
+	tag.RequestedProcedureDescription:     true,
+	tag.PerformedProcedureStepDescription: true,
+	tag.ReasonForTheRequestedProcedure:    true,
+	tag.ProtocolName:                      true,
+	tag.DerivationDescription:             true,
+	tag.MedicalAlerts:                     true,
+	tag.Allergies:                         true,
+	tag.Occupation:                        true,
+	tag.PatientState:                      true,
+	tag.TextValue:                         true,
+}
+
+// VRs of free text, other attributes with them are listed as free text too
+var freeTextVRs = map[string]bool{"LT": true, "ST": true, "UT": true}
+
+// returns the attributes of the dataset a reviewer has to check before release: identifying attributes and free
+// text with values, private attributes and a burned-in annotation flag other than NO on images
+func Audit(dataset *dicom.Dataset) []Risk {
+	risks := auditElements("", dataset.Elements, charset.FromDataset(dataset))
+	if _, err := dataset.FindElementByTag(tag.PixelData); err == nil {
+		e, err := dataset.FindElementByTag(tag.BurnedInAnnotation)
+		if err != nil {
+			risks = append(risks, Risk{RiskBurnedIn, dicomtree.FormatTag(tag.BurnedInAnnotation), nil, "missing, the pixel data may contain burned-in text"})
+		} else if value := strings.TrimSpace(dicomtree.ValueText(e, nil)); value != "NO" {
+			risks = append(risks, Risk{RiskBurnedIn, dicomtree.FormatTag(e.Tag), e, value})
+		}
+	}
+	return risks
+}
+
+func auditElements(prefix string, elements []*dicom.Element, charsets []string) []Risk {
+	var risks []Risk
+	for _, e := range elements {
+		path := prefix + dicomtree.FormatTag(e.Tag)
+		for i, item := range dicomtree.SequenceItems(e) {
+			risks = append(risks, auditElements(fmt.Sprintf("%s/%d/", path, i+1), item, charsets)...)
+		}
+		if dicomtree.IsSequence(e) || e.Tag.Group == 0x0002 {
+			continue
+		}
+		value := strings.TrimRight(dicomtree.ValueText(e, charsets), "\x00 ")
+		if e.Value != nil && (e.Value.ValueType() == dicom.Bytes || e.Value.ValueType() == dicom.PixelData) {
+			value = fmt.Sprintf("<%d bytes>", e.ValueLength)
+		}
+		if value == "" {
+			continue
+		}
+		switch {
+		case e.Tag.Group%2 == 1 && e.Tag.Element >= 0x1000:
+			risks = append(risks, Risk{RiskPrivate, path, e, value})
+		case identifyingTags[e.Tag] && !isReplacement(e.Tag, value):
+			risks = append(risks, Risk{RiskIdentifying, path, e, value})
+		case freeTextTags[e.Tag] || freeTextVRs[e.RawValueRepresentation]:
+			risks = append(risks, Risk{RiskFreeText, path, e, value})
+		}
+	}
+	return risks
+}
+
+// returns whether the value is the replacement of the tag by BasicRules, e.g. "ANONYMIZED"
+func isReplacement(t tag.Tag, value string) bool {
+	for _, rule := range BasicRules {
+		if rule.Tag == t && rule.Action == Replace && rule.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,510 @@
+package anon
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// handling of the DA, DT and TM attributes by a profile
+const (
+	DatesRemove = "remove" // empty the dates, the default
+	DatesKeep   = "keep"
+	DatesShift  = "shift" // move the dates by DateShiftDays, keeping the intervals between them and the times
+)
+
+// handling of private attributes by a profile
+const (
+	PrivateRemove = "remove" // the default
+	PrivateKeep   = "keep"
+	PrivateSafe   = "safe" // keep the safePrivateTags with their private creators
+)
+
+// handling of the instance UIDs by a profile
+const (
+	UIDsReplace = "replace" // new UIDs of the same UIDMap, the default
+	UIDsKeep    = "keep"
+)
+
+// root of the UIDs defined by the standard, e.g. SOP classes and transfer syntaxes, kept when replacing UIDs
+const standardUIDRoot = "1.2.840.10008."
+
+// de-identification profile, the basic profile with the options applied, then the own rules. Tags are keywords or
+// "(gggg,eeee)".
+type Profile struct {
+	Name          string            `json:"name,omitempty"`
+	Options       []string          `json:"options,omitempty"` // names of the PS3.15 options, see Options
+	Keep          []string          `json:"keep,omitempty"`    // attributes kept although a rule would change them
+	Remove        []string          `json:"remove,omitempty"`
+	Empty         []string          `json:"empty,omitempty"`
+	Replace       map[string]string `json:"replace,omitempty"` // tag to replacement value
+	Dates         string            `json:"dates,omitempty"`   // DatesRemove, DatesKeep or DatesShift
+	DateShiftDays int               `json:"dateShiftDays,omitempty"`
+	PrivateTags   string            `json:"privateTags,omitempty"` // PrivateRemove, PrivateKeep or PrivateSafe
+	UIDs          string            `json:"uids,omitempty"`        // UIDsReplace or UIDsKeep
+}
+
+// attributes removed by the basic profile in addition to BasicRules, grouped by the option retaining them
+var (
+	patientCharacteristics = []tag.Tag{tag.PatientSex, tag.PatientAge, tag.PatientSize, tag.PatientWeight, tag.SmokingStatus, tag.PregnancyStatus}
+	deviceIdentity         = []tag.Tag{tag.StationName, tag.DeviceSerialNumber, tag.DeviceUID, tag.GantryID, tag.DetectorID}
+	institutionIdentity    = []tag.Tag{tag.InstitutionName, tag.InstitutionAddress, tag.InstitutionalDepartmentName}
+	descriptors            = []tag.Tag{tag.StudyDescription, tag.SeriesDescription, tag.ImageComments, tag.PerformedProcedureStepDescription, tag.RequestedProcedureDescription, tag.ProtocolName}
+)
+
+// the options of the de-identification profiles of PS3.15 annex E that are supported, by name
+var Options = map[string]func(p *Profile){
+	"retain-longitudinal-full-dates":     func(p *Profile) { p.Dates = DatesKeep },
+	"retain-longitudinal-modified-dates": func(p *Profile) { p.Dates = DatesShift },
+	"retain-patient-characteristics":     func(p *Profile) { p.keep(patientCharacteristics) },
+	"retain-device-identity":             func(p *Profile) { p.keep(deviceIdentity) },
+	"retain-institution-identity":        func(p *Profile) { p.keep(institutionIdentity) },
+	"retain-safe-private":                func(p *Profile) { p.PrivateTags = PrivateSafe },
+	"retain-uids":                        func(p *Profile) { p.UIDs = UIDsKeep },
+	"clean-descriptors":                  func(p *Profile) { p.keep(descriptors) }, // cleaned of the identifying values
+}
+
+func (p *Profile) keep(tags []tag.Tag) {
+	for _, t := range tags {
+		p.Keep = append(p.Keep, dicomtree.FormatTag(t))
+	}
+}
+
+// returns the profile of the built-in name, "basic" or an option name, else loads the profile from the file or from
+// <name>.json in the profiles directory
+func LoadProfile(name, profilesDir string) (Profile, error) {
+	if name == "basic" {
+		return Profile{Name: name}, nil
+	}
+	if _, ok := Options[name]; ok {
+		return Profile{Name: name, Options: []string{name}}, nil
+	}
+	path := name
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && !strings.ContainsRune(name, os.PathSeparator) && profilesDir != "" {
+		path = filepath.Join(profilesDir, name+".json")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, fmt.Errorf("unknown profile '%s': %w", name, err)
+	}
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if profile.Name == "" {
+		profile.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return profile, nil
+}
+
+// returns the profile with the options applied and the rules to apply, checking tags, options and date handling
+func (p Profile) resolve() (Profile, []Rule, map[tag.Tag]bool, error) {
+	resolved := p
+	resolved.Keep = slices.Clone(p.Keep)
+	for _, option := range p.Options {
+		apply, ok := Options[option]
+		if !ok {
+			return p, nil, nil, fmt.Errorf("unknown option '%s'", option)
+		}
+		apply(&resolved)
+	}
+	switch resolved.Dates {
+	case "", DatesRemove, DatesKeep:
+	case DatesShift:
+		if resolved.DateShiftDays == 0 {
+			return p, nil, nil, errors.New("shifting dates needs dateShiftDays")
+		}
+	default:
+		return p, nil, nil, fmt.Errorf("unknown dates '%s', use remove, keep or shift", resolved.Dates)
+	}
+	switch resolved.PrivateTags {
+	case "", PrivateRemove, PrivateKeep, PrivateSafe:
+	default:
+		return p, nil, nil, fmt.Errorf("unknown privateTags '%s', use remove, keep or safe", resolved.PrivateTags)
+	}
+	switch resolved.UIDs {
+	case "", UIDsReplace, UIDsKeep:
+	default:
+		return p, nil, nil, fmt.Errorf("unknown uids '%s', use replace or keep", resolved.UIDs)
+	}
+
+	parse := func(texts []string) ([]tag.Tag, error) {
+		tags := make([]tag.Tag, 0, len(texts))
+		for _, text := range texts {
+			t, err := dicomtree.ParseTag(text)
+			if err != nil {
+				return nil, err
+			}
+			tags = append(tags, t)
+		}
+		return tags, nil
+	}
+	keepTags, err := parse(resolved.Keep)
+	if err != nil {
+		return p, nil, nil, err
+	}
+	kept := make(map[tag.Tag]bool, len(keepTags))
+	for _, t := range keepTags {
+		kept[t] = true
+	}
+
+	byTag := make(map[tag.Tag]Rule)
+	var order []tag.Tag
+	set := func(rule Rule) {
+		if _, ok := byTag[rule.Tag]; !ok {
+			order = append(order, rule.Tag)
+		}
+		byTag[rule.Tag] = rule
+	}
+	for _, rule := range BasicRules {
+		set(rule)
+	}
+	for _, group := range [][]tag.Tag{patientCharacteristics, deviceIdentity, institutionIdentity, descriptors} {
+		for _, t := range group {
+			set(Rule{t, Remove, ""})
+		}
+	}
+	for _, own := range []struct {
+		action Action
+		texts  []string
+	}{{Remove, resolved.Remove}, {Empty, resolved.Empty}} {
+		tags, err := parse(own.texts)
+		if err != nil {
+			return p, nil, nil, err
+		}
+		for _, t := range tags {
+			set(Rule{t, own.action, ""})
+		}
+	}
+	replaceTexts := make([]string, 0, len(resolved.Replace))
+	for text := range resolved.Replace {
+		replaceTexts = append(replaceTexts, text)
+	}
+	slices.Sort(replaceTexts)
+	for _, text := range replaceTexts {
+		t, err := dicomtree.ParseTag(text)
+		if err != nil {
+			return p, nil, nil, err
+		}
+		set(Rule{t, Replace, resolved.Replace[text]})
+	}
+
+	rules := make([]Rule, 0, len(order))
+	for _, t := range order {
+		if !kept[t] {
+			rules = append(rules, byTag[t])
+		}
+	}
+	return resolved, rules, kept, nil
+}
+
+// checks the profile, e.g. for unknown tags and options
+func (p Profile) Validate() error {
+	_, _, _, err := p.resolve()
+	return err
+}
+
+// new UIDs by original UID, shared by the datasets of a batch, so an original UID gets the same new UID in all of
+// them and studies, series and references between the files stay together
+type UIDMap map[string]string
+
+func (m UIDMap) replace(uid string) string {
+	if _, ok := m[uid]; !ok {
+		m[uid] = edit.NewUID()
+	}
+	return m[uid]
+}
+
+// the state of applying a resolved profile to a dataset
+type application struct {
+	profile     Profile
+	rules       map[tag.Tag]Rule
+	kept        map[tag.Tag]bool
+	uids        UIDMap
+	identifying *regexp.Regexp // values of the identifying attributes cleaned from descriptors, nil if not cleaned
+}
+
+// applies the profile to the dataset and the items of its sequences: the rules, the date handling to DA, DT and TM
+// values, new instance UIDs of uids unless retained, the private tag policy, and records the method in
+// DeidentificationMethod. A nil uids replaces the UIDs consistently within the dataset only. Returns the number of
+// changed elements.
+func (p Profile) Apply(dataset *dicom.Dataset, uids UIDMap) (int, error) {
+	resolved, rules, kept, err := p.resolve()
+	if err != nil {
+		return 0, err
+	}
+	if uids == nil {
+		uids = UIDMap{}
+	}
+	a := application{profile: resolved, rules: make(map[tag.Tag]Rule, len(rules)), kept: kept, uids: uids}
+	for _, rule := range rules {
+		a.rules[rule.Tag] = rule
+	}
+	if slices.Contains(resolved.Options, "clean-descriptors") {
+		a.identifying = identifyingValues(dataset.Elements)
+	}
+	elements, changed, err := a.apply(dataset.Elements)
+	dataset.Elements = elements
+	if err != nil {
+		return changed, err
+	}
+
+	temporal := map[string]string{"": "REMOVED", DatesRemove: "REMOVED", DatesKeep: "UNMODIFIED", DatesShift: "MODIFIED"}[resolved.Dates]
+	method := strings.Join(append([]string{"dcmtagger " + cmp.Or(resolved.Name, "basic")}, resolved.Options...), ", ")
+	for t, value := range map[tag.Tag]string{
+		tag.PatientIdentityRemoved:                  "YES",
+		tag.LongitudinalTemporalInformationModified: temporal,
+		tag.DeidentificationMethod:                  method,
+	} {
+		if _, err := edit.Set(dataset, t, []string{value}); err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// de-identifies the elements of the dataset or of a sequence item, returns the remaining ones and the number of
+// changed elements
+func (a application) apply(elements []*dicom.Element) ([]*dicom.Element, int, error) {
+	creators := privateCreators(elements)
+	changed := 0
+	remaining := make([]*dicom.Element, 0, len(elements))
+	for _, e := range elements {
+		n, keep, err := a.applyElement(e, creators)
+		changed += n
+		if err != nil {
+			return elements, changed, fmt.Errorf("%s: %w", dicomtree.FormatTag(e.Tag), err)
+		}
+		if keep {
+			remaining = append(remaining, e)
+		}
+	}
+	return remaining, changed, nil
+}
+
+// de-identifies the element, returns the number of changed elements and whether the element is kept
+func (a application) applyElement(e *dicom.Element, creators map[privateBlock]string) (int, bool, error) {
+	if a.identifying != nil && slices.Contains(descriptors, e.Tag) {
+		return a.clean(e)
+	}
+	if a.kept[e.Tag] {
+		return 0, true, nil
+	}
+	if e.Tag.Group%2 == 1 {
+		switch a.profile.PrivateTags {
+		case PrivateKeep:
+			return 0, true, nil
+		case PrivateSafe:
+			if isSafePrivate(e.Tag, creators) {
+				return 0, true, nil
+			}
+		}
+		return 1, false, nil
+	}
+	if rule, ok := a.rules[e.Tag]; ok {
+		switch rule.Action {
+		case Remove:
+			return 1, false, nil
+		case Empty:
+			if dicomtree.IsSequence(e) {
+				value, err := dicom.NewValue([][]*dicom.Element{})
+				e.Value = value
+				return 1, true, err
+			}
+			return 1, true, edit.SetStrings(e, []string{""}, nil)
+		case Replace:
+			return 1, true, edit.SetStrings(e, []string{rule.Value}, nil)
+		}
+	}
+	if dicomtree.IsSequence(e) {
+		return a.applyItems(e)
+	}
+	switch e.RawValueRepresentation {
+	case "UI":
+		return a.replaceUIDs(e)
+	case "DA", "DT", "TM":
+		return a.changeDates(e)
+	}
+	return 0, true, nil
+}
+
+func (a application) applyItems(e *dicom.Element) (int, bool, error) {
+	items := dicomtree.SequenceItems(e)
+	changed := 0
+	for i, item := range items {
+		elements, n, err := a.apply(item)
+		items[i], changed = elements, changed+n
+		if err != nil {
+			return changed, true, err
+		}
+	}
+	if changed == 0 {
+		return 0, true, nil
+	}
+	value, err := dicom.NewValue(items)
+	if err == nil {
+		e.Value = value
+	}
+	return changed, true, err
+}
+
+// replaces the instance UIDs, all but the well-known ones of the standard like SOP classes and those of the file meta
+// information other than MediaStorageSOPInstanceUID
+func (a application) replaceUIDs(e *dicom.Element) (int, bool, error) {
+	if a.profile.UIDs == UIDsKeep || e.Tag.Group == 0x0002 && e.Tag != tag.MediaStorageSOPInstanceUID {
+		return 0, true, nil
+	}
+	values := dicomtree.ValueStrings(e)
+	replaced := make([]string, len(values))
+	found := false
+	for i, uid := range values {
+		uid = strings.TrimRight(uid, "\x00 ")
+		replaced[i] = uid
+		if uid != "" && !strings.HasPrefix(uid, standardUIDRoot) {
+			replaced[i], found = a.uids.replace(uid), true
+		}
+	}
+	if !found {
+		return 0, true, nil
+	}
+	return 1, true, edit.SetStrings(e, replaced, nil)
+}
+
+// empties or shifts the dates, date times and times with values as the profile says, each of multiple values, times
+// are kept when shifting
+func (a application) changeDates(e *dicom.Element) (int, bool, error) {
+	values := strings.Split(strings.TrimRight(dicomtree.ValueText(e, nil), "\x00 "), "\\")
+	if values[0] == "" && len(values) == 1 || a.profile.Dates == DatesKeep || a.profile.Dates == DatesShift && e.RawValueRepresentation == "TM" {
+		return 0, true, nil
+	}
+	if a.profile.Dates != DatesShift {
+		return 1, true, edit.SetStrings(e, []string{""}, nil)
+	}
+	for i, value := range values {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		shifted, err := shiftDate(value, a.profile.DateShiftDays)
+		if err != nil {
+			return 0, true, err
+		}
+		values[i] = shifted
+	}
+	return 1, true, edit.SetStrings(e, values, nil)
+}
+
+// removes the values of the identifying attributes from the values of the descriptor
+func (a application) clean(e *dicom.Element) (int, bool, error) {
+	values := dicomtree.ValueStrings(e)
+	cleaned := make([]string, len(values))
+	differs := false
+	for i, v := range values {
+		cleaned[i] = strings.Join(strings.Fields(a.identifying.ReplaceAllString(v, " ")), " ")
+		differs = differs || cleaned[i] != strings.TrimRight(v, "\x00 ")
+	}
+	if !differs {
+		return 0, true, nil
+	}
+	return 1, true, edit.SetStrings(e, cleaned, nil)
+}
+
+// returns a case insensitive pattern of the values of the identifying attributes of the elements, names also by their
+// components, nil without values
+func identifyingValues(elements []*dicom.Element) *regexp.Regexp {
+	var values []string
+	for _, e := range elements {
+		if !identifyingTags[e.Tag] {
+			continue
+		}
+		for _, v := range dicomtree.ValueStrings(e) {
+			parts := []string{strings.TrimRight(v, "\x00 ")}
+			if e.RawValueRepresentation == "PN" {
+				parts = strings.FieldsFunc(parts[0], func(r rune) bool { return r == '^' || r == '=' || r == ' ' })
+			}
+			for _, part := range parts {
+				if len(part) > 1 {
+					values = append(values, regexp.QuoteMeta(part))
+				}
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) }) // longest first
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(values, "|") + `)\b`)
+}
+
+// private attribute by its private creator, group and element number within the block of the creator
+type privateTag struct {
+	creator string
+	group   uint16
+	element uint8
+}
+
+// private attributes without identifying information kept by retain-safe-private, from the safe private attributes
+// of PS3.15 table E.3.10-1
+var safePrivateTags = map[privateTag]bool{
+	{"SIEMENS MR HEADER", 0x0019, 0x0c}:      true, // B_value
+	{"SIEMENS MR HEADER", 0x0019, 0x0d}:      true, // DiffusionDirectionality
+	{"SIEMENS MR HEADER", 0x0019, 0x0e}:      true, // DiffusionGradientDirection
+	{"SIEMENS MR HEADER", 0x0019, 0x27}:      true, // B_matrix
+	{"GEMS_PARM_01", 0x0043, 0x39}:           true, // b value
+	{"Philips Imaging DD 001", 0x2001, 0x03}: true, // Diffusion B-Factor
+	{"Philips Imaging DD 001", 0x2001, 0x04}: true, // Diffusion Direction
+}
+
+// private block by group and block number, the element number of its private creator element
+type privateBlock struct {
+	group, block uint16
+}
+
+// returns the private creators of the elements by block
+func privateCreators(elements []*dicom.Element) map[privateBlock]string {
+	creators := make(map[privateBlock]string)
+	for _, e := range elements {
+		if e.Tag.Group%2 == 1 && e.Tag.Element >= 0x0010 && e.Tag.Element <= 0x00ff {
+			creators[privateBlock{e.Tag.Group, e.Tag.Element}] = strings.Trim(dicomtree.ValueText(e, nil), "\x00 ")
+		}
+	}
+	return creators
+}
+
+// whether the private attribute is one of safePrivateTags or the creator of a block with one of them
+func isSafePrivate(t tag.Tag, creators map[privateBlock]string) bool {
+	if t.Element >= 0x0010 && t.Element <= 0x00ff {
+		creator := creators[privateBlock{t.Group, t.Element}]
+		for safe := range safePrivateTags {
+			if safe.creator == creator && safe.group == t.Group {
+				return true
+			}
+		}
+		return false
+	}
+	creator, ok := creators[privateBlock{t.Group, t.Element >> 8}]
+	return ok && t.Element >= 0x1000 && safePrivateTags[privateTag{creator, t.Group, uint8(t.Element)}]
+}
+
+// returns the DA or DT value moved by the days, the time of a DT unchanged
+func shiftDate(value string, days int) (string, error) {
+	if len(value) < 8 {
+		return "", fmt.Errorf("invalid date '%s'", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return "", fmt.Errorf("invalid date '%s'", value)
+	}
+	return date.AddDate(0, 0, days).Format("20060102") + value[8:], nil
+}
@@ -0,0 +1,178 @@
+package anon
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// header of the mapping file, the import also accepts files without it
+var pseudonymsHeader = []string{"PatientID", "PatientName", "PseudonymID", "PseudonymName"}
+
+// pseudonym of a patient, identified by PatientID or by PatientName if it has no ID
+type Pseudonym struct {
+	OriginalID   string
+	OriginalName string
+	ID           string
+	Name         string
+}
+
+// mapping of patients to pseudonyms kept in a CSV file, so a patient gets the same pseudonym in every run
+type Pseudonyms struct {
+	path    string
+	byKey   map[string]int // key of the original patient to the index in All
+	ids     map[string]bool
+	All     []Pseudonym // in the order they were assigned or imported
+	Changed bool        // not saved yet
+}
+
+// loads the mapping file, an empty mapping if it doesn't exist yet
+func LoadPseudonyms(path string) (*Pseudonyms, error) {
+	p := &Pseudonyms{path: path, byKey: make(map[string]int), ids: make(map[string]bool)}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := p.Import(file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	p.Changed = false
+	return p, nil
+}
+
+// adds the rows "PatientID,PatientName,PseudonymID[,PseudonymName]" of a CSV mapping, e.g. of another tool, existing
+// patients keep their pseudonym. Returns the number of added patients.
+func (p *Pseudonyms) Import(r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	added := 0
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return added, nil
+		} else if err != nil {
+			return added, err
+		}
+		if line == 1 && strings.EqualFold(record[0], pseudonymsHeader[0]) {
+			continue
+		}
+		if len(record) < 3 || record[2] == "" || (record[0] == "" && record[1] == "") {
+			return added, fmt.Errorf("line %d: expected PatientID,PatientName,PseudonymID[,PseudonymName]", line)
+		}
+		if _, ok := p.byKey[pseudonymKey(record[0], record[1])]; ok {
+			continue
+		}
+		pseudonym := Pseudonym{OriginalID: record[0], OriginalName: record[1], ID: record[2], Name: record[2]}
+		if len(record) > 3 && record[3] != "" {
+			pseudonym.Name = record[3]
+		}
+		p.add(pseudonym)
+		added++
+	}
+}
+
+// returns the pseudonym of the patient, a new one "PSEUDO-<n>" if the patient has none yet
+func (p *Pseudonyms) Get(id, name string) Pseudonym {
+	if i, ok := p.byKey[pseudonymKey(id, name)]; ok {
+		return p.All[i]
+	}
+	n := len(p.All) + 1
+	for p.ids[fmt.Sprintf("PSEUDO-%06d", n)] {
+		n++
+	}
+	pseudonym := Pseudonym{OriginalID: id, OriginalName: name, ID: fmt.Sprintf("PSEUDO-%06d", n), Name: fmt.Sprintf("PSEUDO^%06d", n)}
+	p.add(pseudonym)
+	return pseudonym
+}
+
+func (p *Pseudonyms) add(pseudonym Pseudonym) {
+	p.byKey[pseudonymKey(pseudonym.OriginalID, pseudonym.OriginalName)] = len(p.All)
+	p.ids[pseudonym.ID] = true
+	p.All = append(p.All, pseudonym)
+	p.Changed = true
+}
+
+func pseudonymKey(id, name string) string {
+	if id != "" {
+		return "id\x00" + id
+	}
+	return "name\x00" + name
+}
+
+// replaces PatientID and PatientName of the dataset with the pseudonym of the patient and marks the identity as
+// removed, returns false for datasets without both or with a pseudonym as PatientID
+func (p *Pseudonyms) Apply(dataset *dicom.Dataset) (bool, error) {
+	value := func(t tag.Tag) string {
+		e, err := dataset.FindElementByTag(t)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimRight(dicomtree.ValueText(e, charset.FromDataset(dataset)), "\x00 ")
+	}
+	id, name := value(tag.PatientID), value(tag.PatientName)
+	if (id == "" && name == "") || p.ids[id] {
+		return false, nil // no patient or already pseudonymized
+	}
+	pseudonym := p.Get(id, name)
+	for _, v := range []struct {
+		tag   tag.Tag
+		value string
+	}{{tag.PatientID, pseudonym.ID}, {tag.PatientName, pseudonym.Name}, {tag.PatientIdentityRemoved, "YES"}} {
+		if _, err := edit.Set(dataset, v.tag, []string{v.value}); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// writes the mapping file if it changed, creating its directory if needed
+func (p *Pseudonyms) Save() error {
+	if !p.Changed {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) // the originals are PHI
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	writer.Write(pseudonymsHeader)
+	for _, pseudonym := range p.All {
+		writer.Write([]string{pseudonym.OriginalID, pseudonym.OriginalName, pseudonym.ID, pseudonym.Name})
+	}
+	writer.Flush()
+	err = writer.Error()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return err
+	}
+	p.Changed = false
+	return nil
+}
+
+// returns the path of the mapping file
+func (p *Pseudonyms) Path() string {
+	return p.path
+}
@@ -0,0 +1,99 @@
+// Package backup keeps the previous versions of files overwritten in place, so that the writes can be undone.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+)
+
+// directory next to the overwritten files holding their previous versions
+const DirName = ".dcmtagger-backup"
+
+// suffix format of the backups, sorting in the order they were made
+const timeFormat = "20060102-150405.000000000"
+
+var ErrNoBackup = errors.New("no backup")
+
+// returns the backup directory of the file
+func Dir(path string) string {
+	return filepath.Join(filepath.Dir(path), DirName)
+}
+
+// moves the file into the backup directory as '<name>.<time>', returns the path of the backup
+func Save(path string) (string, error) {
+	dir := Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	backupPath := filepath.Join(dir, filepath.Base(path)+"."+time.Now().Format(timeFormat))
+	if err := os.Rename(path, backupPath); err != nil {
+		return "", err
+	}
+	slog.Info("backed up file", "path", path, "backup", backupPath)
+	return backupPath, nil
+}
+
+// returns the backups of the file, the newest first
+func List(path string) ([]string, error) {
+	files, err := os.ReadDir(Dir(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(path) + "."
+	var backups []string
+	for _, f := range files {
+		suffix, isBackup := strings.CutPrefix(f.Name(), prefix)
+		if _, err := time.Parse(timeFormat, suffix); isBackup && err == nil && !f.IsDir() {
+			backups = append(backups, filepath.Join(Dir(path), f.Name()))
+		}
+	}
+	slices.Sort(backups)
+	slices.Reverse(backups)
+	return backups, nil
+}
+
+// writes the dataset to the file, moving an existing file into the backup directory first. The backup is moved
+// back if writing fails.
+func WriteFile(dataset dicom.Dataset, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return dicomtree.WriteFile(dataset, path)
+	}
+	backupPath, err := Save(path)
+	if err != nil {
+		return fmt.Errorf("error backing up %s: %w", path, err)
+	}
+	if err := dicomtree.WriteFile(dataset, path); err != nil {
+		if restoreErr := os.Rename(backupPath, path); restoreErr != nil {
+			return fmt.Errorf("%w, previous version kept in %s", err, backupPath)
+		}
+		return err
+	}
+	return nil
+}
+
+// replaces the file by its newest backup, returns the path of the restored backup or ErrNoBackup
+func Restore(path string) (string, error) {
+	backups, err := List(path)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", fmt.Errorf("%w of %s in %s", ErrNoBackup, filepath.Base(path), Dir(path))
+	}
+	if err := os.Rename(backups[0], path); err != nil {
+		return "", err
+	}
+	slog.Info("restored file", "path", path, "backup", backups[0])
+	return backups[0], nil
+}
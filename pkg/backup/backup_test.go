@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestBackup(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "a.dcm")
+	_, err := Restore(path)
+	assert.ErrorIs(err, ErrNoBackup)
+
+	require.NoError(t, os.WriteFile(path, []byte("first"), 0o644))
+	dataset := dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.PatientName, []string{"Doe^Jane"})}}
+	assert.NoError(WriteFile(dataset, path))
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0o644))
+	assert.NoError(WriteFile(dataset, path))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(path), "a.dcm.notes"), nil, 0o644))
+
+	backups, err := List(path)
+	assert.NoError(err)
+	require.Len(t, backups, 2)
+	assert.Equal(Dir(path), filepath.Dir(backups[0]))
+
+	restored, err := Restore(path)
+	assert.NoError(err)
+	assert.Equal(backups[0], restored)
+	data, _ := os.ReadFile(path)
+	assert.Equal("second", string(data), "the newest backup is restored first")
+	_, err = Restore(path)
+	assert.NoError(err)
+	data, _ = os.ReadFile(path)
+	assert.Equal("first", string(data))
+	_, err = Restore(path)
+	assert.ErrorIs(err, ErrNoBackup)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
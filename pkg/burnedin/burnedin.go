@@ -0,0 +1,150 @@
+// Package burnedin detects text burned into the pixel data, e.g. patient names in the corners of ultrasound or
+// secondary capture images, with a heuristic looking for high-contrast strokes near the image edges.
+package burnedin
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"slices"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+var ErrNoPixelData = errors.New("no pixel data")
+
+const (
+	borderFraction  = 0.2 // part of width and height at each edge that is scanned, text in the center is not PHI
+	contrast        = 0.5 // difference of neighboring pixels relative to the value range that counts as stroke edge
+	strokesPerRow   = 2.0 // mean stroke edges per row of a text block, an object outline has about one
+	minTextRows     = 3   // rows of a block with stroke edges
+	minBlocksInLine = 2   // adjacent text blocks of a region, text lines are wider than one block
+	maxFrames       = 3   // first, middle and last frame are scanned
+)
+
+// area of a frame that looks like a line of text
+type Region struct {
+	Frame               int // 0-based
+	X, Y, Width, Height int
+}
+
+func (r Region) String() string {
+	return fmt.Sprintf("frame %d at %d,%d (%dx%d)", r.Frame+1, r.X, r.Y, r.Width, r.Height)
+}
+
+// returns the text-like regions near the edges of the first, middle and last frame, none if the pixel data looks
+// free of burned-in text. Frames that can't be decoded return an error.
+func Detect(dataset *dicom.Dataset) ([]Region, error) {
+	e, err := dataset.FindElementByTag(tag.PixelData)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+		return nil, ErrNoPixelData
+	}
+	info := dicom.MustGetPixelDataInfo(e.Value)
+	if len(info.Frames) == 0 {
+		return nil, ErrNoPixelData
+	}
+	indices := []int{0, len(info.Frames) / 2, len(info.Frames) - 1}
+	indices = slices.Compact(indices[:min(maxFrames, len(info.Frames))])
+	var regions []Region
+	for _, i := range indices {
+		gray, width, height, err := grayFrame(&info.Frames[i])
+		if err != nil {
+			return regions, fmt.Errorf("frame %d: %w", i+1, err)
+		}
+		for _, r := range detectInFrame(gray, width, height) {
+			r.Frame = i
+			regions = append(regions, r)
+		}
+	}
+	return regions, nil
+}
+
+// returns the samples of the frame as gray values, the mean of the samples of a pixel for color
+func grayFrame(f *frame.Frame) ([]float64, int, int, error) {
+	if !f.IsEncapsulated() {
+		native, err := f.GetNativeFrame()
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		gray := make([]float64, len(native.Data))
+		for i, pixel := range native.Data {
+			for _, sample := range pixel {
+				gray[i] += float64(sample) / float64(len(pixel))
+			}
+		}
+		return gray, native.Cols, native.Rows, nil
+	}
+	img, err := f.GetImage()
+	if err == nil && img == nil {
+		err = errors.New("no decoder")
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	bounds := img.Bounds()
+	gray := make([]float64, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray = append(gray, float64(color.Gray16Model.Convert(img.At(x, y)).(color.Gray16).Y))
+		}
+	}
+	return gray, bounds.Dx(), bounds.Dy(), nil
+}
+
+// returns the regions of adjacent text blocks in a row of blocks within the border of the frame. A text block has
+// several stroke edges, steps of at least half the value range between horizontal neighbors, in most of its rows.
+func detectInFrame(gray []float64, width, height int) []Region {
+	if len(gray) != width*height || width == 0 {
+		return nil
+	}
+	lo, hi := slices.Min(gray), slices.Max(gray)
+	if hi == lo {
+		return nil
+	}
+	step := contrast * (hi - lo)
+	block := max(8, min(width, height)/32)
+	borderX, borderY := int(float64(width)*borderFraction), int(float64(height)*borderFraction)
+	isText := func(bx, by int) bool {
+		x0, y0 := bx*block, by*block
+		if x0+block > width || y0+block > height {
+			return false
+		}
+		inBorder := x0 < borderX || x0+block > width-borderX || y0 < borderY || y0+block > height-borderY
+		if !inBorder {
+			return false
+		}
+		edges, rows := 0, 0
+		for y := y0; y < y0+block; y++ {
+			rowEdges := 0
+			for x := x0; x < x0+block-1; x++ {
+				d := gray[y*width+x+1] - gray[y*width+x]
+				if d >= step || -d >= step {
+					rowEdges++
+				}
+			}
+			if rowEdges > 0 {
+				rows++
+				edges += rowEdges
+			}
+		}
+		return rows >= minTextRows && float64(edges)/float64(rows) >= strokesPerRow
+	}
+
+	var regions []Region
+	for by := 0; by < height/block; by++ {
+		run := 0
+		for bx := 0; bx <= width/block; bx++ {
+			if bx < width/block && isText(bx, by) {
+				run++
+				continue
+			}
+			if run >= minBlocksInLine {
+				regions = append(regions, Region{X: (bx - run) * block, Y: by * block, Width: run * block, Height: block})
+			}
+			run = 0
+		}
+	}
+	return regions
+}
@@ -0,0 +1,53 @@
+package burnedin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// returns a 128x128 frame with a bright disc reaching into the border, with text-like strokes at the top left if text
+func newFrame(text bool) frame.Frame {
+	const size = 128
+	data := make([][]int, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			value := 0
+			if (x-64)*(x-64)+(y-64)*(y-64) < 55*55 {
+				value = 200
+			}
+			if text && y >= 8 && y < 16 && x >= 8 && x < 40 && x%3 == 0 {
+				value = 255
+			}
+			data[y*size+x] = []int{value}
+		}
+	}
+	return frame.Frame{NativeData: frame.NativeFrame{Data: data, Rows: size, Cols: size, BitsPerSample: 8}}
+}
+
+func TestDetect(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.PixelData, dicom.PixelDataInfo{Frames: []frame.Frame{newFrame(false), newFrame(true)}}),
+	}}
+	regions, err := Detect(&dataset)
+	require.NoError(t, err)
+	assert.Equal([]Region{{Frame: 1, X: 8, Y: 8, Width: 32, Height: 8}}, regions, "only the strokes, not the outline of the disc")
+	assert.Equal("frame 2 at 8,8 (32x8)", regions[0].String())
+
+	_, err = Detect(&dicom.Dataset{})
+	assert.ErrorIs(err, ErrNoPixelData)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
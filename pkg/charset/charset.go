@@ -0,0 +1,217 @@
+// Package charset decodes and encodes DICOM text values according to the specific character set (0008,0005).
+package charset
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// defined term of specific character set for UTF-8
+const UTF8 = "ISO_IR 192"
+
+// value representations whose values are affected by the specific character set
+var textVRs = map[string]bool{"SH": true, "LO": true, "ST": true, "LT": true, "UT": true, "UC": true, "PN": true}
+
+// encodings by defined term of specific character set (0008,0005), nil means ASCII or UTF-8 compatible
+var encodingsByCharacterSet = map[string]encoding.Encoding{
+	"":                nil,
+	"ISO_IR 6":        nil,
+	"ISO 2022 IR 6":   nil,
+	"ISO_IR 192":      nil,
+	"ISO_IR 100":      charmap.ISO8859_1,
+	"ISO 2022 IR 100": charmap.ISO8859_1,
+	"ISO_IR 101":      charmap.ISO8859_2,
+	"ISO 2022 IR 101": charmap.ISO8859_2,
+	"ISO_IR 109":      charmap.ISO8859_3,
+	"ISO 2022 IR 109": charmap.ISO8859_3,
+	"ISO_IR 110":      charmap.ISO8859_4,
+	"ISO 2022 IR 110": charmap.ISO8859_4,
+	"ISO_IR 144":      charmap.ISO8859_5,
+	"ISO 2022 IR 144": charmap.ISO8859_5,
+	"ISO_IR 127":      charmap.ISO8859_6,
+	"ISO 2022 IR 127": charmap.ISO8859_6,
+	"ISO_IR 126":      charmap.ISO8859_7,
+	"ISO 2022 IR 126": charmap.ISO8859_7,
+	"ISO_IR 138":      charmap.ISO8859_8,
+	"ISO 2022 IR 138": charmap.ISO8859_8,
+	"ISO_IR 148":      charmap.ISO8859_9,
+	"ISO 2022 IR 148": charmap.ISO8859_9,
+	"ISO_IR 203":      charmap.ISO8859_15,
+	"ISO 2022 IR 203": charmap.ISO8859_15,
+	"ISO_IR 166":      charmap.Windows874,
+	"ISO 2022 IR 166": charmap.Windows874,
+	"ISO_IR 13":       japanese.ShiftJIS,
+	"ISO 2022 IR 13":  japanese.ShiftJIS,
+	"ISO 2022 IR 87":  japanese.ISO2022JP,
+	"ISO 2022 IR 159": japanese.ISO2022JP,
+	"ISO 2022 IR 149": korean.EUCKR,
+	"ISO 2022 IR 58":  simplifiedchinese.GBK,
+	"GB18030":         simplifiedchinese.GB18030,
+	"GBK":             simplifiedchinese.GBK,
+}
+
+// ISO 2022 escape sequences used by DICOM code extensions and the encoding of the designated character set
+var encodingsByEscapeSequence = map[string]encoding.Encoding{
+	"\x1b(B":  nil,
+	"\x1b(J":  nil,
+	"\x1b)I":  japanese.ShiftJIS,
+	"\x1b$B":  japanese.ISO2022JP,
+	"\x1b$(D": japanese.ISO2022JP,
+	"\x1b$)C": korean.EUCKR,
+	"\x1b$)A": simplifiedchinese.GBK,
+	"\x1b-A":  charmap.ISO8859_1,
+	"\x1b-B":  charmap.ISO8859_2,
+	"\x1b-C":  charmap.ISO8859_3,
+	"\x1b-D":  charmap.ISO8859_4,
+	"\x1b-L":  charmap.ISO8859_5,
+	"\x1b-G":  charmap.ISO8859_6,
+	"\x1b-F":  charmap.ISO8859_7,
+	"\x1b-H":  charmap.ISO8859_8,
+	"\x1b-M":  charmap.ISO8859_9,
+	"\x1b-T":  charmap.Windows874,
+	"\x1b-b":  charmap.ISO8859_15,
+}
+
+// returns the trimmed values of the specific character set of the dataset, nil if not present
+func FromDataset(dataset *dicom.Dataset) []string {
+	e, err := dataset.FindElementByTag(tag.SpecificCharacterSet)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.Strings {
+		return nil
+	}
+	charsets := make([]string, 0)
+	for _, v := range e.Value.GetValue().([]string) {
+		charsets = append(charsets, strings.TrimSpace(v))
+	}
+	return charsets
+}
+
+// reports whether the element holds string values that are affected by the specific character set
+func IsText(e *dicom.Element) bool {
+	return textVRs[e.RawValueRepresentation] && e.Value != nil && e.Value.ValueType() == dicom.Strings
+}
+
+// decodes a raw string value with the given specific character set terms, honoring ISO 2022 escape sequences.
+// Values that are already valid UTF-8 without escape sequences are returned unchanged.
+func Decode(raw string, charsets []string) string {
+	if len(charsets) == 0 || (utf8.ValidString(raw) && !strings.Contains(raw, "\x1b")) {
+		return raw
+	}
+
+	var sb strings.Builder
+	current := encodingsByCharacterSet[charsets[0]]
+	rest := raw
+	for len(rest) > 0 {
+		escIdx := strings.IndexByte(rest, 0x1b)
+		if escIdx != 0 {
+			segment := rest
+			if escIdx > 0 {
+				segment = rest[:escIdx]
+			}
+			sb.WriteString(decodeWithEncoding(segment, current))
+			if escIdx < 0 {
+				break
+			}
+			rest = rest[escIdx:]
+		}
+
+		sequence, enc := findEscapeSequence(rest)
+		if sequence == "" {
+			sb.WriteByte(rest[0]) // unknown escape sequence, keep it
+			rest = rest[1:]
+			continue
+		}
+		if enc == japanese.ISO2022JP {
+			// the ISO 2022 JP decoder handles the escape sequences itself
+			end := strings.IndexByte(rest[len(sequence):], 0x1b)
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += len(sequence)
+			}
+			sb.WriteString(decodeWithEncoding(rest[:end], enc))
+			rest = rest[end:]
+			current = nil
+			continue
+		}
+		current = enc
+		rest = rest[len(sequence):]
+	}
+
+	return sb.String()
+}
+
+func findEscapeSequence(s string) (string, encoding.Encoding) {
+	for sequence, enc := range encodingsByEscapeSequence {
+		if strings.HasPrefix(s, sequence) {
+			return sequence, enc
+		}
+	}
+	return "", nil
+}
+
+func decodeWithEncoding(s string, enc encoding.Encoding) string {
+	if enc == nil {
+		return s
+	}
+	decoded, err := enc.NewDecoder().String(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// encodes an UTF-8 string for storing in a dataset with the given specific character set, fails for characters the
+// character set can't represent
+func Encode(s string, charsets []string) (string, error) {
+	if len(charsets) == 0 || isASCII(s) {
+		return s, nil
+	}
+	if len(charsets) == 1 {
+		enc := encodingsByCharacterSet[charsets[0]]
+		if enc == nil {
+			return s, nil
+		}
+		encoded, err := enc.NewEncoder().String(s)
+		if err != nil {
+			return s, fmt.Errorf("'%s' can't be encoded in %s", s, charsets[0])
+		}
+		return encoded, nil
+	}
+
+	// code extensions, try the multi-byte character sets that are designated by escape sequences
+	for _, charset := range charsets[1:] {
+		switch charset {
+		case "ISO 2022 IR 87", "ISO 2022 IR 159":
+			if encoded, err := japanese.ISO2022JP.NewEncoder().String(s); err == nil {
+				return encoded, nil
+			}
+		case "ISO 2022 IR 149":
+			if encoded, err := korean.EUCKR.NewEncoder().String(s); err == nil {
+				return "\x1b$)C" + encoded, nil
+			}
+		case "ISO 2022 IR 58":
+			if encoded, err := simplifiedchinese.GBK.NewEncoder().String(s); err == nil {
+				return "\x1b$)A" + encoded, nil
+			}
+		}
+	}
+	return s, fmt.Errorf("'%s' can't be encoded in %s", s, strings.Join(charsets, "\\"))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
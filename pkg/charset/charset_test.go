@@ -0,0 +1,36 @@
+package charset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Buc^Jérôme", Decode("Buc^J\xe9r\xf4me", []string{"ISO_IR 100"}))
+	assert.Equal("Люкceмбypг", Decode("\xbb\xee\xda\x63\x65\xdc\xd1\x79\x70\xd3", []string{"ISO_IR 144"}))
+	assert.Equal("Yamada^Tarou=山田^太郎",
+		Decode("Yamada^Tarou=\x1b$B;3ED\x1b(B^\x1b$BB@O:\x1b(B", []string{"", "ISO 2022 IR 87"}))
+	assert.Equal("already utf8 ü", Decode("already utf8 ü", []string{"ISO_IR 100"}))
+	assert.Equal("no charset", Decode("no charset", nil))
+}
+
+func TestEncode(t *testing.T) {
+	assert := assert.New(t)
+
+	encode := func(s string, charsets []string) string {
+		encoded, err := Encode(s, charsets)
+		assert.NoError(err)
+		return encoded
+	}
+	assert.Equal("J\xe9r\xf4me", encode("Jérôme", []string{"ISO_IR 100"}))
+	assert.Equal("ascii", encode("ascii", []string{"ISO_IR 100"}))
+	assert.Equal("Jérôme", encode("Jérôme", []string{"ISO_IR 192"}))
+
+	_, err := Encode("5 €", []string{"ISO_IR 100"})
+	assert.EqualError(err, "'5 €' can't be encoded in ISO_IR 100")
+	_, err = Encode("Jérôme", []string{"", "ISO 2022 IR 87"})
+	assert.Error(err)
+}
@@ -0,0 +1,125 @@
+// Package checksum computes SHA-256 digests of DICOM files and of their decoded pixel data, e.g. to verify that edits
+// of the header didn't touch the image content.
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"image"
+	"io"
+	"os"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+var ErrNoPixelData = errors.New("no pixel data")
+
+// returns the hex encoded SHA-256 of the file content
+func File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// returns the hex encoded SHA-256 of the decoded pixel data. Each sample of all frames is hashed as 32 bit little
+// endian integer, so the digest doesn't depend on byte order, VR or value length of the encoding. Encapsulated frames
+// are decoded first, which fails for compressions without decoder, e.g. JPEG 2000.
+func PixelData(dataset *dicom.Dataset) (string, error) {
+	e, err := dataset.FindElementByTag(tag.PixelData)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+		return "", ErrNoPixelData
+	}
+	info := dicom.MustGetPixelDataInfo(e.Value)
+	h := sha256.New()
+	for i := range info.Frames {
+		f := &info.Frames[i]
+		if !f.IsEncapsulated() {
+			native, err := f.GetNativeFrame()
+			if err != nil {
+				return "", fmt.Errorf("frame %d: %w", i+1, err)
+			}
+			for _, pixel := range native.Data {
+				writeSamples(h, pixel...)
+			}
+			continue
+		}
+		img, err := f.GetImage()
+		if err == nil && img == nil {
+			err = errors.New("no decoder")
+		}
+		if err != nil {
+			return "", fmt.Errorf("frame %d can't be decoded: %w", i+1, err)
+		}
+		writeImage(h, img)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeSamples(h hash.Hash, samples ...int) {
+	var buf [4]byte
+	for _, sample := range samples {
+		binary.LittleEndian.PutUint32(buf[:], uint32(int32(sample)))
+		h.Write(buf[:])
+	}
+}
+
+// writes the samples of a decoded frame like the native samples: the gray value, or 8 bit red, green and blue
+func writeImage(h hash.Hash, img image.Image) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			switch gray := img.(type) {
+			case *image.Gray:
+				writeSamples(h, int(gray.GrayAt(x, y).Y))
+			case *image.Gray16:
+				writeSamples(h, int(gray.Gray16At(x, y).Y))
+			default:
+				r, g, b, _ := img.At(x, y).RGBA()
+				writeSamples(h, int(r>>8), int(g>>8), int(b>>8))
+			}
+		}
+	}
+}
+
+// first line of a manifest
+const ManifestHeader = "# sha256 of file  sha256 of decoded pixel data  filename"
+
+// writes a ManifestLine per entry after the ManifestHeader
+func WriteManifest(w io.Writer, entries []dicomtree.Entry) error {
+	if _, err := fmt.Fprintln(w, ManifestHeader); err != nil {
+		return err
+	}
+	for i := range entries {
+		if _, err := fmt.Fprintln(w, ManifestLine(&entries[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the manifest line "<file digest>  <pixel data digest>  <filename>" of the entry, "-" for digests that can't
+// be computed, e.g. of files without pixel data or archive members
+func ManifestLine(entry *dicomtree.Entry) string {
+	fileDigest, err := File(entry.Path)
+	if err != nil {
+		fileDigest = "-"
+	}
+	pixelDigest, err := PixelData(&entry.Dataset)
+	if err != nil {
+		pixelDigest = "-"
+	}
+	return fmt.Sprintf("%s  %s  %s", fileDigest, pixelDigest, entry.Filename)
+}
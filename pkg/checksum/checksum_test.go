@@ -0,0 +1,88 @@
+package checksum
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newDataset(t *testing.T, patientName string, pixels ...int) dicom.Dataset {
+	data := make([][]int, 0, len(pixels))
+	for _, pixel := range pixels {
+		data = append(data, []int{pixel})
+	}
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.PatientName, []string{patientName}),
+		mustElement(t, tag.PixelData, dicom.PixelDataInfo{Frames: []frame.Frame{{
+			NativeData: frame.NativeFrame{Data: data, Rows: 1, Cols: len(pixels), BitsPerSample: 16},
+		}}}),
+	}}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "abc")
+	require.NoError(t, os.WriteFile(path, []byte("abc"), 0o644))
+	digest, err := File(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", digest)
+}
+
+func TestPixelData(t *testing.T) {
+	assert := assert.New(t)
+
+	original := newDataset(t, "Doe^John", 1, 2, 300)
+	digest, err := PixelData(&original)
+	assert.NoError(err)
+	assert.Len(digest, 64)
+
+	edited := newDataset(t, "ANONYMIZED", 1, 2, 300)
+	editedDigest, err := PixelData(&edited)
+	assert.NoError(err)
+	assert.Equal(digest, editedDigest, "header edits don't change the digest")
+
+	changed := newDataset(t, "Doe^John", 1, 2, 301)
+	changedDigest, err := PixelData(&changed)
+	assert.NoError(err)
+	assert.NotEqual(digest, changedDigest)
+
+	_, err = PixelData(&dicom.Dataset{})
+	assert.ErrorIs(err, ErrNoPixelData)
+}
+
+func TestWriteManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "a.dcm")
+	require.NoError(t, os.WriteFile(path, []byte("abc"), 0o644))
+	entries := []dicomtree.Entry{
+		{Filename: "a.dcm", Path: path, Dataset: newDataset(t, "Doe^John", 1)},
+		{Filename: "b.dcm", Path: filepath.Join(t.TempDir(), "missing"), Dataset: dicom.Dataset{}},
+	}
+	var out bytes.Buffer
+	assert.NoError(WriteManifest(&out, entries))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(lines, 3)
+	assert.True(strings.HasPrefix(lines[0], "#"))
+	fields := strings.Fields(lines[1])
+	assert.Equal("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", fields[0])
+	assert.Len(fields[1], 64)
+	assert.Equal("a.dcm", fields[2])
+	assert.Equal("-  -  b.dcm", lines[2])
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
@@ -0,0 +1,235 @@
+// Package coerce applies attribute coercion rules like a PACS does on ingestion, e.g. to see how an archive would
+// change the headers of a delivery before routing it there.
+package coerce
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/filter"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// a coercion rule applied to the datasets matching If. Its actions see the values before the rule and run in the
+// order copy, map, prefix, set and remove. Tags are keywords or "(gggg,eeee)".
+type Rule struct {
+	If     string                       `json:"if,omitempty"`     // filter expression, e.g. "Modality=CT", all datasets if empty
+	Copy   map[string]string            `json:"copy,omitempty"`   // target tag to source tag
+	Map    map[string]map[string]string `json:"map,omitempty"`    // tag to a table of values and their replacement
+	Prefix map[string]string            `json:"prefix,omitempty"` // tag to a prefix added unless the value starts with it
+	Set    map[string]string            `json:"set,omitempty"`    // tag to value
+	Remove []string                     `json:"remove,omitempty"`
+}
+
+// the rules of a coercion file, applied in order
+type Rules struct {
+	Name  string `json:"-"` // the path of the file
+	Rules []Rule `json:"rules"`
+	rules []rule
+}
+
+// a rule with parsed condition and tags, the actions sorted by tag
+type rule struct {
+	condition *filter.Expr
+	copy      []tagPair
+	mapping   []tagMapping
+	prefix    []tagValue
+	set       []tagValue
+	remove    []tag.Tag
+}
+
+type tagPair struct{ target, source tag.Tag }
+type tagValue struct {
+	tag   tag.Tag
+	value string
+}
+type tagMapping struct {
+	tag    tag.Tag
+	values map[string]string
+}
+
+// loads a JSON coercion file '{"rules": [...]}' and checks its conditions and tags
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := &Rules{Name: path}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := rules.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func (r *Rules) compile() error {
+	r.rules = make([]rule, 0, len(r.Rules))
+	for i, spec := range r.Rules {
+		compiled, err := spec.compile()
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return nil
+}
+
+func (spec Rule) compile() (rule, error) {
+	var compiled rule
+	var err error
+	if spec.If != "" {
+		if compiled.condition, err = filter.Parse(spec.If); err != nil {
+			return compiled, err
+		}
+	}
+	for _, target := range sortedKeys(spec.Copy) {
+		targetTag, err := dicomtree.ParseTag(target)
+		if err != nil {
+			return compiled, err
+		}
+		sourceTag, err := dicomtree.ParseTag(spec.Copy[target])
+		if err != nil {
+			return compiled, err
+		}
+		compiled.copy = append(compiled.copy, tagPair{targetTag, sourceTag})
+	}
+	for _, text := range sortedKeys(spec.Map) {
+		t, err := dicomtree.ParseTag(text)
+		if err != nil {
+			return compiled, err
+		}
+		compiled.mapping = append(compiled.mapping, tagMapping{t, spec.Map[text]})
+	}
+	if compiled.prefix, err = tagValues(spec.Prefix); err != nil {
+		return compiled, err
+	}
+	if compiled.set, err = tagValues(spec.Set); err != nil {
+		return compiled, err
+	}
+	for _, text := range spec.Remove {
+		t, err := dicomtree.ParseTag(text)
+		if err != nil {
+			return compiled, err
+		}
+		compiled.remove = append(compiled.remove, t)
+	}
+	return compiled, nil
+}
+
+func tagValues(values map[string]string) ([]tagValue, error) {
+	var parsed []tagValue
+	for _, text := range sortedKeys(values) {
+		t, err := dicomtree.ParseTag(text)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, tagValue{t, values[text]})
+	}
+	return parsed, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// applies the rules to the top level elements of the dataset and returns the number of actions that changed it
+func (r *Rules) Apply(dataset *dicom.Dataset) (int, error) {
+	if r.rules == nil {
+		if err := r.compile(); err != nil {
+			return 0, err
+		}
+	}
+	changed := 0
+	for i, rule := range r.rules {
+		n, err := rule.apply(dataset)
+		changed += n
+		if err != nil {
+			return changed, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return changed, nil
+}
+
+func (r rule) apply(dataset *dicom.Dataset) (int, error) {
+	if r.condition != nil && !r.condition.Match(dataset) {
+		return 0, nil
+	}
+	charsets := charset.FromDataset(dataset)
+	values := make(map[tag.Tag]string) // the decoded values, read before the rule changes them
+	value := func(t tag.Tag) (string, bool) {
+		if v, ok := values[t]; ok {
+			return v, true
+		}
+		e, err := dataset.FindElementByTag(t)
+		if err != nil {
+			return "", false
+		}
+		values[t] = dicomtree.ValueText(e, charsets)
+		return values[t], true
+	}
+	var updates []tagValue
+	for _, pair := range r.copy {
+		if v, ok := value(pair.source); ok {
+			updates = append(updates, tagValue{pair.target, v})
+		}
+	}
+	for _, mapping := range r.mapping {
+		if v, ok := value(mapping.tag); ok {
+			if replacement, ok := mapping.values[v]; ok {
+				updates = append(updates, tagValue{mapping.tag, replacement})
+			}
+		}
+	}
+	for _, prefix := range r.prefix {
+		if v, ok := value(prefix.tag); ok && !strings.HasPrefix(v, prefix.value) {
+			updates = append(updates, tagValue{prefix.tag, prefix.value + v})
+		}
+	}
+	for _, t := range r.set {
+		value(t.tag)
+	}
+	updates = append(updates, r.set...)
+
+	changed := 0
+	for _, update := range updates {
+		if v, ok := value(update.tag); ok && v == update.value {
+			continue
+		}
+		if err := set(dataset, update.tag, update.value, charsets); err != nil {
+			return changed, err
+		}
+		values[update.tag] = update.value
+		changed++
+	}
+	for _, t := range r.remove {
+		if edit.Delete(dataset, t) {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// sets the backslash separated values of the top level element, encoded with the character set of the dataset, the
+// element is created if missing
+func set(dataset *dicom.Dataset, t tag.Tag, value string, charsets []string) error {
+	values := strings.Split(value, "\\")
+	e, err := edit.Set(dataset, t, values)
+	if err != nil {
+		return err
+	}
+	return edit.SetStrings(e, values, charsets)
+}
@@ -0,0 +1,87 @@
+package coerce
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestApply(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "site.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": [
+		{"if": "(0008,0060)=CT",
+		 "copy": {"(0010,1000)": "(0010,0020)"},
+		 "map": {"(0008,0080)": {"Hospital": "HOSPITAL A"}},
+		 "prefix": {"(0010,0020)": "A-", "(0008,0050)": "A-"},
+		 "set": {"(0010,0021)": "SITE A", "(0008,1010)": "CT1"},
+		 "remove": ["(0008,1070)", "(0008,1050)"]},
+		{"if": "(0008,0060)=MR", "set": {"(0008,0080)": "MR"}}
+	]}`), 0o644))
+	rules, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(rules.Rules, 2)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.AccessionNumber, []string{"A-1"}),
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.InstitutionName, []string{"Hospital"}),
+		mustElement(t, tag.StationName, []string{"CT1"}),
+		mustElement(t, tag.OperatorsName, []string{"Doe^Jane"}),
+		mustElement(t, tag.PatientID, []string{"123"}),
+	}}
+	changed, err := rules.Apply(&dataset)
+	assert.NoError(err)
+	assert.Equal(5, changed, "copy, map, prefix, set of the issuer and remove of the operator")
+	value := func(t tag.Tag) string {
+		if _, err := dataset.FindElementByTag(t); err != nil {
+			return "<missing>"
+		}
+		return dicomtree.UIDValue(&dataset, t)
+	}
+	assert.Equal("123", value(tag.OtherPatientIDs), "copied before the prefix")
+	assert.Equal("A-123", value(tag.PatientID))
+	assert.Equal("A-1", value(tag.AccessionNumber), "already prefixed")
+	assert.Equal("HOSPITAL A", value(tag.InstitutionName))
+	assert.Equal("SITE A", value(tag.IssuerOfPatientID))
+	assert.Equal("CT1", value(tag.StationName))
+	assert.Equal("<missing>", value(tag.OperatorsName))
+
+	changed, err = rules.Apply(&dataset)
+	assert.NoError(err)
+	assert.Equal(1, changed, "only the copy of the prefixed ID")
+	assert.Equal("A-123", value(tag.PatientID))
+
+	mr := dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.Modality, []string{"MR"})}}
+	changed, err = rules.Apply(&mr)
+	assert.NoError(err)
+	assert.Equal(1, changed)
+	assert.Equal("MR", dicomtree.UIDValue(&mr, tag.InstitutionName))
+
+	for _, invalid := range []string{
+		`{"rules": [{"if": "(0008,0060)="}]}`,
+		`{"rules": [{"set": {"NoSuchTag": "x"}}]}`,
+		`{"rules": [{"remove": ["(0008,0080)", "(0008"]}]}`,
+		`{"rules": {}}`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(invalid), 0o644))
+		_, err := Load(path)
+		assert.ErrorContains(err, path, invalid)
+	}
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(err)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
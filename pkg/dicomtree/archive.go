@@ -0,0 +1,179 @@
+package dicomtree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/suyashkumar/dicom"
+)
+
+// returns whether the path is an archive read by ParseArchive, by its extension
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// members of an archive that failed to parse, returned by ParseArchive with the entries of the other members
+type SkippedMembersError struct {
+	Archive string
+	Members []string // in archive order
+	Errs    []error  // parse error of each member
+}
+
+func (e *SkippedMembersError) Error() string {
+	skipped := make([]string, 0, len(e.Members))
+	for i, name := range e.Members {
+		skipped = append(skipped, fmt.Sprintf("%s (%s)", name, e.Errs[i].Error()))
+	}
+	return fmt.Sprintf("skipped %d members of %s that failed to parse: %s", len(e.Members), e.Archive, strings.Join(skipped, ", "))
+}
+
+// parses the DICOM files of a .zip, .tar, .tar.gz or .tgz archive in memory, without extracting it. Members without
+// the DICM prefix, e.g. a README, are skipped, members failing to parse are skipped and reported by a
+// *SkippedMembersError returned with the other entries. The filename of an entry is its path in the archive, its path the
+// archive path joined with it, so the entries can't be reloaded or written in place, see CheckOverwrite.
+func ParseArchive(path string) ([]Entry, error) {
+	return parseArchive(path, PathFilter{})
+}
+
+func parseArchive(path string, filter PathFilter) ([]Entry, error) {
+	var entries []Entry
+	skipped := &SkippedMembersError{Archive: path}
+	add := func(name string, r io.Reader) error {
+		if !filter.Match(filepath.Join(path, name)) {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("error reading %s from %s: %w", name, path, err)
+		}
+		if len(data) < 132 || string(data[128:132]) != "DICM" {
+			return nil
+		}
+		start := time.Now()
+		dataset, err := dicom.Parse(bytes.NewReader(data), int64(len(data)), nil)
+		if err != nil {
+			slog.Warn("error parsing archive member", "archive", path, "member", name, "error", err)
+			skipped.Members, skipped.Errs = append(skipped.Members, name), append(skipped.Errs, err)
+			return nil
+		}
+		entries = append(entries, Entry{Filename: name, Path: filepath.Join(path, name), Archive: path, Dataset: dataset, ParseDuration: time.Since(start)})
+		return nil
+	}
+
+	if err := readArchive(path, add); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Filename < entries[j].Filename })
+	if len(skipped.Members) > 0 {
+		return entries, skipped
+	}
+	return entries, nil
+}
+
+// returned by the add function of ReadFile to stop reading the archive at the member
+var errMemberRead = errors.New("member read")
+
+// returns the encoded file of the entry, the member of its archive for archive entries
+func (entry *Entry) ReadFile() ([]byte, error) {
+	if entry.Archive == "" {
+		return os.ReadFile(entry.Path)
+	}
+	var data []byte
+	err := readArchive(entry.Archive, func(name string, r io.Reader) error {
+		if filepath.Join(entry.Archive, name) != entry.Path {
+			return nil
+		}
+		var err error
+		if data, err = io.ReadAll(r); err != nil {
+			return err
+		}
+		return errMemberRead
+	})
+	if err == nil {
+		return nil, fmt.Errorf("%s not found in %s", entry.Filename, entry.Archive)
+	} else if err != errMemberRead {
+		return nil, err
+	}
+	return data, nil
+}
+
+// calls add for each regular file of the zip or tar archive in archive order, an error of add stops reading
+func readArchive(path string, add func(name string, r io.Reader) error) error {
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".zip") {
+		return readZip(path, add)
+	}
+	return readTar(path, strings.HasSuffix(lower, "gz"), add)
+}
+
+func readZip(path string, add func(name string, r io.Reader) error) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = add(f.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTar(path string, compressed bool, add func(name string, r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := add(header.Name, archive); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dicomtree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArchive(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := os.ReadFile("../../testdata/test.dcm")
+	require.NoError(t, err)
+	members := map[string][]byte{"series/b.dcm": data, "series/a.dcm": data, "README.txt": []byte("no DICOM"),
+		"series/bad.dcm": append(data[:132:132], 0x02, 0x00, 0x10, 0x00, 'U', 'I', 0xff)}
+	dir := t.TempDir()
+
+	zipPath := filepath.Join(dir, "study.zip")
+	f, err := os.Create(zipPath)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for name, content := range members {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	tarPath := filepath.Join(dir, "study.tar.gz")
+	f, err = os.Create(tarPath)
+	require.NoError(t, err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range members {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	require.NoError(t, f.Close())
+
+	for _, path := range []string{zipPath, tarPath} {
+		assert.True(IsArchive(path))
+		entries, err := ListFiles(path)
+		var skipped *SkippedMembersError
+		if assert.ErrorAs(err, &skipped, path) {
+			assert.Equal([]string{"series/bad.dcm"}, skipped.Members)
+			assert.Contains(err.Error(), "skipped 1 members of "+path+" that failed to parse: series/bad.dcm (")
+		}
+		assert.Len(entries, 2, "README and the bad member are skipped")
+		assert.Equal("series/a.dcm", entries[0].Filename)
+		assert.Equal(filepath.Join(path, "series/a.dcm"), entries[0].Path)
+		assert.False(entries[0].Partial)
+		assert.Equal(path, entries[0].Archive)
+		member, err := entries[0].ReadFile()
+		assert.NoError(err)
+		assert.Equal(data, member)
+		assert.ErrorContains(entries[0].CheckOverwrite(), "series/a.dcm is a member of the archive "+path+" and can't be written in place")
+	}
+	assert.NoError((&Entry{Filename: "a.dcm", Path: "a.dcm"}).CheckOverwrite())
+	assert.False(IsArchive("../../testdata/test.dcm"))
+}
@@ -0,0 +1,82 @@
+package dicomtree
+
+import (
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// code values too long for CodeValue, not in the dictionary of the parser
+var (
+	LongCodeValue = tag.Tag{Group: 0x0008, Element: 0x0119}
+	URNCodeValue  = tag.Tag{Group: 0x0008, Element: 0x0120}
+)
+
+// meanings of frequent codes by scheme and value, used when an item lacks its CodeMeaning
+var knownCodes = map[string]string{
+	"DCM 113701":    "X-Ray Radiation Dose Report",
+	"DCM 113702":    "Accumulated X-Ray Dose Data",
+	"DCM 113706":    "Irradiation Event X-Ray Data",
+	"DCM 113811":    "CT Accumulated Dose Data",
+	"DCM 113812":    "Total Number of Irradiation Events",
+	"DCM 113813":    "CT Dose Length Product Total",
+	"DCM 113819":    "CT Acquisition",
+	"DCM 113820":    "CT Acquisition Type",
+	"DCM 113829":    "CT Dose",
+	"DCM 113830":    "Mean CTDIvol",
+	"DCM 113838":    "DLP",
+	"DCM 121058":    "Procedure reported",
+	"DCM 121071":    "Finding",
+	"DCM 121106":    "Comment",
+	"DCM 122130":    "Dose Area Product",
+	"DCM 125203":    "Acquisition Protocol",
+	"SCT 12738006":  "Brain",
+	"SCT 39607008":  "Lung",
+	"SCT 51185008":  "Chest",
+	"SCT 69536005":  "Head",
+	"SCT 80891009":  "Heart",
+	"SCT 818981001": "Abdomen",
+	"UCUM mGy":      "mGy",
+	"UCUM mGy.cm":   "mGy.cm",
+	"UCUM mm":       "mm",
+	"UCUM s":        "s",
+}
+
+// a coded entry of a code sequence item, PS3.3 8.8
+type Code struct {
+	Value   string // CodeValue, LongCodeValue or URNCodeValue
+	Scheme  string // CodingSchemeDesignator, "" for URN codes
+	Meaning string // CodeMeaning, else the one of known codes
+	Known   bool   // whether the meaning is the one of known codes as the item lacks it
+}
+
+// returns the code of the elements of a sequence item, false if the item has no code value
+func ItemCode(elements []*dicom.Element) (Code, bool) {
+	code := Code{Scheme: ItemValue(elements, tag.CodingSchemeDesignator), Meaning: ItemValue(elements, tag.CodeMeaning)}
+	for _, t := range []tag.Tag{tag.CodeValue, LongCodeValue, URNCodeValue} {
+		if code.Value = ItemValue(elements, t); code.Value != "" {
+			break
+		}
+	}
+	if code.Value == "" {
+		return code, false
+	}
+	if code.Meaning == "" {
+		code.Meaning, code.Known = knownCodes[code.Scheme+" "+code.Value]
+	}
+	return code, true
+}
+
+// returns the code like "SCT 818981001 — Abdomen", a meaning of known codes followed by "(known code)"
+func (c Code) String() string {
+	text := c.Value
+	if c.Scheme != "" {
+		text = c.Scheme + " " + c.Value
+	}
+	switch {
+	case c.Known:
+		return text + " — " + c.Meaning + " (known code)"
+	case c.Meaning == "":
+		return text + " — no CodeMeaning"
+	}
+	return text + " — " + c.Meaning
+}
@@ -0,0 +1,43 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestItemCode(t *testing.T) {
+	assert := assert.New(t)
+
+	code, ok := ItemCode([]*dicom.Element{
+		mustElement(t, tag.CodeValue, []string{"818981001"}),
+		mustElement(t, tag.CodingSchemeDesignator, []string{"SCT"}),
+		mustElement(t, tag.CodeMeaning, []string{"Abdomen "}),
+	})
+	assert.True(ok)
+	assert.Equal("SCT 818981001 — Abdomen", code.String())
+
+	code, _ = ItemCode([]*dicom.Element{
+		mustElement(t, tag.CodeValue, []string{"113830"}),
+		mustElement(t, tag.CodingSchemeDesignator, []string{"DCM"}),
+	})
+	assert.Equal(Code{"113830", "DCM", "Mean CTDIvol", true}, code)
+	assert.Equal("DCM 113830 — Mean CTDIvol (known code)", code.String())
+	code, _ = ItemCode([]*dicom.Element{vrElement(t, URNCodeValue, "UR", []string{"urn:example:1"})})
+	assert.Equal("urn:example:1 — no CodeMeaning", code.String())
+
+	_, ok = ItemCode([]*dicom.Element{mustElement(t, tag.CodeMeaning, []string{"Abdomen"})})
+	assert.False(ok)
+
+	entry := Entry{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.ProcedureCodeSequence, [][]*dicom.Element{{
+			mustElement(t, tag.CodeValue, []string{"818981001"}),
+			mustElement(t, tag.CodingSchemeDesignator, []string{"SCT"}),
+			mustElement(t, tag.CodeMeaning, []string{"Abdomen"}),
+		}}),
+	}}}
+	root := BuildByFilename("root", []Entry{entry}, DisplayOptions{})
+	assert.Equal("\tItem 1: SCT 818981001 — Abdomen", root.Children[0].Children[0].Children[0].Text)
+}
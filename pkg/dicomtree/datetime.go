@@ -0,0 +1,157 @@
+package dicomtree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// parses a DT value "YYYYMMDDHHMMSS.FFFFFF&ZZXX", the components after the year may be omitted, in the location if it
+// has no offset
+func ParseDateTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexAny(value, "+-"); i >= 0 {
+		zone, err := ParseTimezoneOffset(value[i:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		value, loc = value[:i], zone
+	}
+	fraction := ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value, fraction = value[:i], value[i+1:]
+	}
+	if len(value) < 4 || len(value) > 14 || len(value)%2 != 0 || !isDigits(value) || (fraction != "" && !isDigits(fraction)) {
+		return time.Time{}, fmt.Errorf("invalid date time '%s'", value)
+	}
+	components := [6]int{0, 1, 1, 0, 0, 0} // year, month, day, hour, minute and second
+	for i := 0; i < 6 && 4+2*i <= len(value); i++ {
+		from, to := 2+2*i, 4+2*i
+		if i == 0 {
+			from = 0
+		}
+		components[i], _ = strconv.Atoi(value[from:to])
+	}
+	nanoseconds := 0
+	if fraction != "" {
+		nanoseconds, _ = strconv.Atoi((fraction + "000000000")[:9])
+	}
+	t := time.Date(components[0], time.Month(components[1]), components[2], components[3], components[4], components[5],
+		nanoseconds, loc)
+	if t.Month() != time.Month(components[1]) || t.Day() != components[2] || components[3] > 23 || components[4] > 59 ||
+		components[5] > 60 {
+		return time.Time{}, fmt.Errorf("invalid date time '%s'", value)
+	}
+	return t, nil
+}
+
+// parses an offset from UTC "&ZZXX" like the one of TimezoneOffsetFromUTC
+func ParseTimezoneOffset(value string) (*time.Location, error) {
+	value = strings.TrimSpace(value)
+	if len(value) != 5 || (value[0] != '+' && value[0] != '-') || !isDigits(value[1:]) {
+		return nil, fmt.Errorf("invalid timezone offset '%s', use &HHMM", value)
+	}
+	hours, _ := strconv.Atoi(value[1:3])
+	minutes, _ := strconv.Atoi(value[3:])
+	offset := hours*3600 + minutes*60
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(value, offset), nil
+}
+
+// returns the time the dataset was acquired: AcquisitionDateTime, else AcquisitionDate and AcquisitionTime, else
+// ContentDate and ContentTime, in the timezone of TimezoneOffsetFromUTC, UTC without one
+func AcquisitionDateTime(dataset *dicom.Dataset) (time.Time, bool) {
+	loc, ok := DatasetTimezone(dataset)
+	if !ok {
+		loc = time.UTC
+	}
+	if value := firstValue(dataset, tag.AcquisitionDateTime); value != "" {
+		if t, err := ParseDateTime(value, loc); err == nil {
+			return t, true
+		}
+	}
+	for _, tags := range [][2]tag.Tag{{tag.AcquisitionDate, tag.AcquisitionTime}, {tag.ContentDate, tag.ContentTime}} {
+		date, tm := firstValue(dataset, tags[0]), firstValue(dataset, tags[1])
+		if date == "" || tm == "" {
+			continue
+		}
+		if t, err := ParseDateTime(strings.TrimSpace(date)+strings.TrimSpace(tm), loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// returns the timezone of TimezoneOffsetFromUTC of the dataset, false without a valid one
+func DatasetTimezone(dataset *dicom.Dataset) (*time.Location, bool) {
+	zone, err := ParseTimezoneOffset(firstValue(dataset, tag.TimezoneOffsetFromUTC))
+	return zone, err == nil
+}
+
+// the DA and TM attributes together giving a point in time
+var DateTimePairs = [][2]tag.Tag{
+	{tag.StudyDate, tag.StudyTime},
+	{tag.SeriesDate, tag.SeriesTime},
+	{tag.AcquisitionDate, tag.AcquisitionTime},
+	{tag.ContentDate, tag.ContentTime},
+	{tag.InstanceCreationDate, tag.InstanceCreationTime},
+	{tag.PerformedProcedureStepStartDate, tag.PerformedProcedureStepStartTime},
+	{tag.PerformedProcedureStepEndDate, tag.PerformedProcedureStepEndTime},
+}
+
+// returns the point in time of a DT element or a TM element of DateTimePairs with its date among the elements, false
+// for other elements, values without hour and values without own offset if loc is nil
+func ElementDateTime(elements []*dicom.Element, e *dicom.Element, loc *time.Location) (time.Time, bool) {
+	value := ""
+	switch e.RawValueRepresentation {
+	case "DT":
+		value = firstString(e)
+	case "TM":
+		for _, pair := range DateTimePairs {
+			if pair[1] == e.Tag {
+				if date := FindItemElement(elements, pair[0]); date != nil && len(firstString(date)) == 8 {
+					value = firstString(date) + firstString(e)
+				}
+				break
+			}
+		}
+	}
+	digits, hasOffset := value, strings.ContainsAny(value, "+-")
+	if i := strings.IndexAny(value, ".+-"); i >= 0 {
+		digits = value[:i]
+	}
+	if len(digits) < 10 || loc == nil && !hasOffset {
+		return time.Time{}, false
+	}
+	if loc == nil {
+		loc = time.UTC // not used, the value has its own offset
+	}
+	t, err := ParseDateTime(value, loc)
+	return t, err == nil
+}
+
+// returns the annotation of a DT or TM element with the time in UTC and in the local timezone, e.g.
+// ' [UTC 2023-01-15 13:30:15, local 2023-01-15 14:30:15 +0100]', empty if the timezone of its value is unknown
+func (entry *Entry) DateTimeText(e *dicom.Element) string {
+	if e.RawValueRepresentation != "DT" && e.RawValueRepresentation != "TM" {
+		return ""
+	}
+	loc, _ := DatasetTimezone(&entry.Dataset)
+	t, ok := ElementDateTime(entry.Dataset.Elements, e, loc)
+	if !ok {
+		return ""
+	}
+	return NormalizedTimeText(t, time.Local)
+}
+
+// returns ' [UTC <time>, local <time> <offset>]' of the time
+func NormalizedTimeText(t time.Time, local *time.Location) string {
+	const layout = "2006-01-02 15:04:05"
+	return fmt.Sprintf(" [UTC %s, local %s]", t.UTC().Format(layout), t.In(local).Format(layout+" -0700"))
+}
@@ -0,0 +1,79 @@
+package dicomtree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParseDateTime(t *testing.T) {
+	assert := assert.New(t)
+
+	dt, err := ParseDateTime("20240102103005.25+0130", time.UTC)
+	assert.NoError(err)
+	assert.Equal(time.Date(2024, 1, 2, 9, 0, 5, 250000000, time.UTC), dt.UTC())
+	dt, err = ParseDateTime("202402", time.UTC)
+	assert.NoError(err)
+	assert.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), dt, "omitted components")
+	_, err = ParseDateTime("20240230", time.UTC)
+	assert.Error(err)
+	_, err = ParseDateTime("2024010", time.UTC)
+	assert.Error(err)
+	_, err = ParseDateTime("20240102+01", time.UTC)
+	assert.Error(err)
+}
+
+func TestAcquisitionDateTime(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := &dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.ContentDate, []string{"20240102"}),
+		mustElement(t, tag.ContentTime, []string{"1030"}),
+		mustElement(t, tag.TimezoneOffsetFromUTC, []string{"-0500"}),
+	}}
+	acquired, ok := AcquisitionDateTime(dataset)
+	assert.True(ok)
+	assert.Equal(time.Date(2024, 1, 2, 15, 30, 0, 0, time.UTC), acquired.UTC(), "content time in the timezone of the offset")
+
+	dataset.Elements = append(dataset.Elements, mustElement(t, tag.AcquisitionDateTime, []string{"20240102101500"}))
+	acquired, _ = AcquisitionDateTime(dataset)
+	assert.Equal(time.Date(2024, 1, 2, 15, 15, 0, 0, time.UTC), acquired.UTC(), "acquisition date time first")
+
+	_, ok = AcquisitionDateTime(&dicom.Dataset{})
+	assert.False(ok)
+}
+
+func TestElementDateTime(t *testing.T) {
+	assert := assert.New(t)
+
+	element := func(tg tag.Tag, vr, value string) *dicom.Element {
+		e := mustElement(t, tg, []string{value})
+		e.RawValueRepresentation = vr
+		return e
+	}
+	zone, _ := ParseTimezoneOffset("+0100")
+	elements := []*dicom.Element{
+		element(tag.StudyDate, "DA", "20240102"),
+		element(tag.StudyTime, "TM", "103005.5"),
+		element(tag.SeriesTime, "TM", "1100"),
+		element(tag.AcquisitionDateTime, "DT", "20240102231500-0500"),
+		element(tag.ContentTime, "DT", "20240102"),
+	}
+	studied, ok := ElementDateTime(elements, elements[1], zone)
+	assert.True(ok)
+	assert.Equal(time.Date(2024, 1, 2, 9, 30, 5, 500000000, time.UTC), studied.UTC(), "time with the date of its pair")
+	_, ok = ElementDateTime(elements, elements[1], nil)
+	assert.False(ok, "unknown timezone")
+	_, ok = ElementDateTime(elements, elements[2], zone)
+	assert.False(ok, "time without date")
+	acquired, ok := ElementDateTime(elements, elements[3], nil)
+	assert.True(ok, "own offset")
+	assert.Equal(time.Date(2024, 1, 3, 4, 15, 0, 0, time.UTC), acquired.UTC())
+	_, ok = ElementDateTime(elements, elements[4], zone)
+	assert.False(ok, "without hour")
+
+	assert.Equal(" [UTC 2024-01-03 04:15:00, local 2024-01-03 05:15:00 +0100]", NormalizedTimeText(acquired, zone))
+}
@@ -0,0 +1,63 @@
+package dicomtree
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// files sharing the same SOP instance UID
+type DuplicateGroup struct {
+	SOPInstanceUID string
+	Entries        []*Entry  // in the order of the entries
+	DifferingTags  []tag.Tag // top level tags with different values or missing in some of the files
+}
+
+// returns the groups of entries with identical SOP instance UID sorted by UID, entries without UID are skipped
+func FindDuplicates(entries []Entry) []DuplicateGroup {
+	entriesByUID := make(map[string][]*Entry)
+	for i := range entries {
+		e, err := entries[i].Dataset.FindElementByTag(tag.SOPInstanceUID)
+		if err != nil {
+			continue
+		}
+		uid := strings.TrimRight(ValueText(e, nil), "\x00 ")
+		if uid != "" {
+			entriesByUID[uid] = append(entriesByUID[uid], &entries[i])
+		}
+	}
+
+	groups := make([]DuplicateGroup, 0)
+	for uid, group := range entriesByUID {
+		if len(group) > 1 {
+			groups = append(groups, DuplicateGroup{uid, group, differingTags(group)})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].SOPInstanceUID < groups[j].SOPInstanceUID })
+	return groups
+}
+
+func differingTags(entries []*Entry) []tag.Tag {
+	valuesByTag := make(map[tag.Tag]map[string]bool)
+	countsByTag := make(map[tag.Tag]int)
+	for _, entry := range entries {
+		charsets := charset.FromDataset(&entry.Dataset)
+		for _, e := range entry.Dataset.Elements {
+			if valuesByTag[e.Tag] == nil {
+				valuesByTag[e.Tag] = make(map[string]bool)
+			}
+			valuesByTag[e.Tag][ValueText(e, charsets)] = true
+			countsByTag[e.Tag]++
+		}
+	}
+	tags := make([]tag.Tag, 0)
+	for t, values := range valuesByTag {
+		if len(values) > 1 || countsByTag[t] != len(entries) {
+			tags = append(tags, t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return TagOrder(tags[i]) < TagOrder(tags[j]) })
+	return tags
+}
@@ -0,0 +1,36 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	assert := assert.New(t)
+
+	newEntry := func(filename, uid, modality string) Entry {
+		return Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPInstanceUID, []string{uid}),
+			mustElement(t, tag.Modality, []string{modality}),
+		}}}
+	}
+	entries := []Entry{
+		newEntry("a.dcm", "1.2.3", "CT"),
+		newEntry("b.dcm", "1.2.4", "CT"),
+		newEntry("c.dcm", "1.2.3\x00", "MR"),
+		newEntry("d.dcm", "1.2.4", "CT"),
+		newEntry("e.dcm", "1.2.5", "CT"),
+	}
+	entries[3].Dataset.Elements = append(entries[3].Dataset.Elements, mustElement(t, tag.PatientName, []string{"Doe"}))
+
+	groups := FindDuplicates(entries)
+	assert.Len(groups, 2)
+	assert.Equal("1.2.3", groups[0].SOPInstanceUID)
+	assert.Equal([]*Entry{&entries[0], &entries[2]}, groups[0].Entries)
+	assert.Equal([]tag.Tag{tag.Modality}, groups[0].DifferingTags)
+	assert.Equal([]*Entry{&entries[1], &entries[3]}, groups[1].Entries)
+	assert.Equal([]tag.Tag{tag.PatientName}, groups[1].DifferingTags, "missing in one file")
+}
@@ -0,0 +1,239 @@
+// Package dicomtree loads DICOM datasets and builds the display tree model used by the viewer,
+// either sorted by filename or by tag with the values of all files per tag.
+package dicomtree
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+type Entry struct {
+	Filename string
+	Path     string
+	Dataset  dicom.Dataset
+	Offsets  map[tag.Tag]ElementOffset // nil until computed with EnsureOffsets
+	Partial  bool                      // dataset is incomplete, i.e. empty after ListFiles or the values of an index, see Load
+	Source   string                    // input directory or archive of the file if several inputs are merged, see SetSource
+	Archive  string                    // path of the archive the file is a member of, "" for files, see ParseArchive
+	Warnings []ParseWarning            // nil until scanned with EnsureWarnings or EnsureOffsets
+
+	ParseDuration time.Duration // duration of the complete parse, 0 if not parsed yet
+}
+
+// parses the complete file if the dataset is partial, elements of the partial dataset must not be used afterwards
+func (entry *Entry) Load() error {
+	if !entry.Partial {
+		return nil
+	}
+	start := time.Now()
+	dataset, err := dicom.ParseFile(entry.Path, nil)
+	if err != nil {
+		slog.Warn("error parsing file", "path", entry.Path, "error", err)
+		return err
+	}
+	entry.Dataset = dataset
+	entry.Partial = false
+	entry.ParseDuration = time.Since(start)
+	slog.Debug("parsed file", "path", entry.Path, "duration", entry.ParseDuration)
+	return nil
+}
+
+// returns an error for entries whose file can't be overwritten in place, i.e. members of archives
+func (entry *Entry) CheckOverwrite() error {
+	if entry.Archive != "" {
+		return fmt.Errorf("%s is a member of the archive %s and can't be written in place, write it to an output directory", entry.Filename, entry.Archive)
+	}
+	return nil
+}
+
+// parses the given file or all files of the given directory (not recursive), archives with ParseArchive
+func ParseFiles(path string) ([]Entry, error) {
+	return ParseFilesFiltered(path, PathFilter{})
+}
+
+// like ParseFiles, but only the files of a directory or archive matching the filter are parsed
+func ParseFilesFiltered(path string, filter PathFilter) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return entries, err
+	}
+	if !pathInfo.IsDir() && IsArchive(path) {
+		return parseArchive(path, filter)
+	}
+
+	if pathInfo.IsDir() {
+		dir := path
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return entries, err
+		}
+
+		for _, f := range files {
+			filePath := filepath.Join(dir, f.Name())
+			if f.IsDir() || !filter.Match(filePath) {
+				continue
+			}
+			start := time.Now()
+			dataset, err := dicom.ParseFile(filePath, nil)
+			if err != nil {
+				slog.Warn("error parsing file", "path", filePath, "error", err)
+				return entries, err
+			}
+			slog.Debug("parsed file", "path", filePath, "duration", time.Since(start))
+			entries = append(entries, Entry{Filename: f.Name(), Path: filePath, Dataset: dataset, ParseDuration: time.Since(start)})
+		}
+	} else {
+		start := time.Now()
+		dataset, err := dicom.ParseFile(path, nil)
+		if err != nil {
+			slog.Warn("error parsing file", "path", path, "error", err)
+			return entries, err
+		}
+		slog.Debug("parsed file", "path", path, "duration", time.Since(start))
+		entries = append(entries, Entry{Filename: pathInfo.Name(), Path: path, Dataset: dataset, ParseDuration: time.Since(start)})
+	}
+
+	return entries, err
+}
+
+// like ParseFiles, but the files of a directory are only listed as partial entries with empty datasets,
+// each file is parsed on demand with Load
+func ListFiles(path string) ([]Entry, error) {
+	return ListFilesFiltered(path, PathFilter{})
+}
+
+// like ListFiles, but only the files of a directory or archive matching the filter are listed
+func ListFilesFiltered(path string, filter PathFilter) ([]Entry, error) {
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return make([]Entry, 0), err
+	}
+	if !pathInfo.IsDir() {
+		return ParseFilesFiltered(path, filter)
+	}
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return make([]Entry, 0), err
+	}
+	entries := make([]Entry, 0, len(files))
+	for _, f := range files {
+		filePath := filepath.Join(path, f.Name())
+		if !f.IsDir() && filter.Match(filePath) {
+			entries = append(entries, Entry{Filename: f.Name(), Path: filePath, Partial: true})
+		}
+	}
+	return entries, nil
+}
+
+// sets the source of the entries of an input directory or archive and prefixes their filenames with it, so that
+// the filenames stay unique when the entries of several inputs are merged
+func SetSource(entries []Entry, source string) {
+	for i := range entries {
+		entries[i].Source = source
+		entries[i].Filename = source + "/" + entries[i].Filename
+	}
+}
+
+// input argument for reading a DICOM stream from stdin
+const StdinInput = "-"
+
+// copies the stream, e.g. stdin, to stdin.dcm in a new temporary directory, so that it can be parsed, scanned for
+// offsets and reloaded like any file. The returned cleanup removes the directory.
+func BufferStream(r io.Reader) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "dcmtagger-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	path := filepath.Join(dir, "stdin.dcm")
+	file, err := os.Create(path)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+// writes the dataset to the file, datasets without TransferSyntaxUID, e.g. without meta group, in implicit VR little
+// endian
+func WriteFile(dataset dicom.Dataset, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = dicom.Write(file, dataset, dicom.DefaultMissingTransferSyntax()); err != nil {
+		slog.Error("error writing dataset", "path", filename, "error", err)
+		return err
+	}
+	slog.Info("wrote dataset", "path", filename, "elements", len(dataset.Elements))
+	return nil
+}
+
+// returns the path of the entry in the output directory mirroring the input, its filename relative to the output
+// directory. Leading '..' and volume names are dropped, so the path never leaves the directory.
+func OutputPath(outputDir string, entry *Entry) string {
+	name := filepath.ToSlash(filepath.Clean(strings.TrimPrefix(entry.Filename, filepath.VolumeName(entry.Filename))))
+	for strings.HasPrefix(name, "../") || strings.HasPrefix(name, "/") {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "/"), "../")
+	}
+	return filepath.Join(outputDir, filepath.FromSlash(name))
+}
+
+// returns the number of elements of the dataset including the elements of all sequence items
+func CountElements(dataset *dicom.Dataset) int {
+	return countElements(dataset.Elements)
+}
+
+func countElements(elements []*dicom.Element) int {
+	count := len(elements)
+	for _, e := range elements {
+		if e.Value == nil || e.Value.ValueType() != dicom.Sequences {
+			continue
+		}
+		for _, item := range e.Value.GetValue().([]*dicom.SequenceItemValue) {
+			count += countElements(item.GetValue().([]*dicom.Element))
+		}
+	}
+	return count
+}
+
+// returns the entry whose dataset contains the given top level element, nil if none
+func FindEntryByElement(entries []Entry, element *dicom.Element) *Entry {
+	for i, entry := range entries {
+		for _, e := range entry.Dataset.Elements {
+			if e == element {
+				return &entries[i]
+			}
+		}
+	}
+	return nil
+}
+
+// returns the entry with the given filename, nil if none
+func FindEntryByFilename(entries []Entry, filename string) *Entry {
+	for i, entry := range entries {
+		if entry.Filename == filename {
+			return &entries[i]
+		}
+	}
+	return nil
+}
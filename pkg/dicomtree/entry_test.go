@@ -0,0 +1,99 @@
+package dicomtree
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+	"github.com/suyashkumar/dicom/pkg/uid"
+)
+
+func TestListFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	data, err := os.ReadFile("../../testdata/test.dcm")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.dcm"), data, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.dcm"), data, 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+
+	entries, err := ListFiles(dir)
+	assert.NoError(err)
+	assert.Len(entries, 2)
+	assert.Equal("a.dcm", entries[0].Filename)
+	assert.Equal(filepath.Join(dir, "a.dcm"), entries[0].Path)
+	assert.True(entries[0].Partial)
+	assert.Empty(entries[0].Dataset.Elements)
+
+	assert.NoError(entries[0].Load())
+	assert.False(entries[0].Partial)
+
+	filter, err := NewPathFilter(nil, []string{"b.*"})
+	require.NoError(t, err)
+	entries, err = ListFilesFiltered(dir, filter)
+	assert.NoError(err)
+	assert.Len(entries, 1)
+	assert.Equal("a.dcm", entries[0].Filename)
+	entries, err = ParseFilesFiltered(dir, filter)
+	assert.NoError(err)
+	assert.Len(entries, 1)
+
+	entries, err = ListFiles(filepath.Join(dir, "a.dcm"))
+	assert.NoError(err)
+	assert.Len(entries, 1)
+	assert.False(entries[0].Partial, "a single file is parsed directly")
+
+	_, err = ListFiles(filepath.Join(dir, "missing"))
+	assert.Error(err)
+}
+
+func TestBufferStream(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := os.ReadFile("../../testdata/test.dcm")
+	require.NoError(t, err)
+	path, cleanup, err := BufferStream(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal("stdin.dcm", filepath.Base(path))
+	buffered, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal(data, buffered)
+
+	cleanup()
+	_, err = os.Stat(filepath.Dir(path))
+	assert.True(os.IsNotExist(err), "directory is removed")
+}
+
+func TestOutputPath(t *testing.T) {
+	assert := assert.New(t)
+
+	out := filepath.Join("out", "put")
+	assert.Equal(filepath.Join(out, "a.dcm"), OutputPath(out, &Entry{Filename: "a.dcm"}))
+	assert.Equal(filepath.Join(out, "study", "series", "a.dcm"), OutputPath(out, &Entry{Filename: "study/series/a.dcm"}))
+	assert.Equal(filepath.Join(out, "data", "a.dcm"), OutputPath(out, &Entry{Filename: "/data/a.dcm"}))
+	assert.Equal(filepath.Join(out, "x", "a.dcm"), OutputPath(out, &Entry{Filename: "../../x/a.dcm"}))
+}
+
+func TestWriteFileWithoutTransferSyntax(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "a.dcm")
+	dataset := dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.PatientName, []string{"Doe^John"})}}
+	require.NoError(t, WriteFile(dataset, path))
+	written, err := dicom.ParseFile(path, nil)
+	require.NoError(t, err)
+	e, err := written.FindElementByTag(tag.TransferSyntaxUID)
+	if assert.NoError(err) {
+		assert.Equal([]string{uid.ImplicitVRLittleEndian}, ValueStrings(e))
+	}
+	e, err = written.FindElementByTag(tag.PatientName)
+	if assert.NoError(err) {
+		assert.Equal([]string{"Doe^John"}, ValueStrings(e))
+	}
+}
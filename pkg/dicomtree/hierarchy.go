@@ -0,0 +1,90 @@
+package dicomtree
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// level of the patient, study and series nodes of BuildByHierarchy
+type HierarchyLevel int
+
+const (
+	HierarchyNone HierarchyLevel = iota // file, group, tag and item nodes
+	HierarchyPatient
+	HierarchyStudy
+	HierarchySeries
+)
+
+// builds a tree with a node per patient, below it a node per study and per series, under each series the file nodes
+// like BuildByFilename. Patients, studies and series are identified by PatientID and PatientName,
+// StudyInstanceUID and SeriesInstanceUID, in the order of their first file.
+func BuildByHierarchy(rootText string, entries []Entry, opts DisplayOptions) *Node {
+	root := NewNode(rootText, nil)
+	nodes := make(map[string]*Node)
+	child := func(parent *Node, key, text string, level HierarchyLevel) *Node {
+		node, ok := nodes[key]
+		if !ok {
+			node = NewNode(text, nil)
+			node.Level = level
+			parent.AddChild(node)
+			nodes[key] = node
+		}
+		return node
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		value := func(t tag.Tag) string {
+			e, err := entry.Dataset.FindElementByTag(t)
+			if err != nil {
+				return ""
+			}
+			return ValueText(e, charset.FromDataset(&entry.Dataset))
+		}
+
+		patientKey := value(tag.PatientID) + "\x00" + value(tag.PatientName)
+		patientNode := child(root, patientKey, hierarchyText("Patient", "(no patient)", value(tag.PatientName), bracketed(value(tag.PatientID))), HierarchyPatient)
+		studyKey := patientKey + "\x00" + value(tag.StudyInstanceUID)
+		studyNode := child(patientNode, studyKey, hierarchyText("Study", cmp.Or(value(tag.StudyInstanceUID), "(no study)"), value(tag.StudyDate), value(tag.StudyDescription)), HierarchyStudy)
+		seriesKey := studyKey + "\x00" + value(tag.SeriesInstanceUID)
+		seriesNode := child(studyNode, seriesKey, hierarchyText("Series", cmp.Or(value(tag.SeriesInstanceUID), "(no series)"), value(tag.SeriesNumber), value(tag.Modality), value(tag.SeriesDescription)), HierarchySeries)
+
+		fileNode := BuildByFilename(entry.Filename, []Entry{*entry}, opts)
+		seriesNode.AddChild(fileNode)
+	}
+
+	root.Walk(func(node, parent *Node) bool {
+		if node.Level == HierarchySeries && len(node.Children) == 1 {
+			node.Text += " (1 file)"
+		} else if node.Level == HierarchySeries {
+			node.Text += fmt.Sprintf(" (%d files)", len(node.Children))
+		}
+		return node.Level != HierarchySeries
+	})
+	return root
+}
+
+// returns the text of a hierarchy node from the non-empty parts, the fallback if all are empty
+func hierarchyText(level, fallback string, parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		nonEmpty = append(nonEmpty, fallback)
+	}
+	return level + " " + strings.Join(nonEmpty, " ")
+}
+
+func bracketed(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "[" + text + "]"
+}
@@ -0,0 +1,96 @@
+package dicomtree
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// value of the files missing the attributes of an inventory category
+const InventoryMissing = "<missing>"
+
+// an attribute of the inventory with the number of files per value, most frequent first
+type InventoryCategory struct {
+	Name        string
+	Frequencies []ValueFrequency
+}
+
+// categories of the inventory with the value of a dataset, "" if it lacks the attributes
+var inventoryCategories = []struct {
+	name  string
+	value func(dataset *dicom.Dataset) string
+}{
+	{"Modality", func(dataset *dicom.Dataset) string { return firstValue(dataset, tag.Modality) }},
+	{"SOP class", func(dataset *dicom.Dataset) string { return uidText(UIDValue(dataset, tag.SOPClassUID)) }},
+	{"Transfer syntax", func(dataset *dicom.Dataset) string { return uidText(UIDValue(dataset, tag.TransferSyntaxUID)) }},
+	{"Photometric interpretation", func(dataset *dicom.Dataset) string {
+		return firstValue(dataset, tag.PhotometricInterpretation)
+	}},
+	{"Bit depth (stored/allocated)", func(dataset *dicom.Dataset) string {
+		stored, allocated := firstValue(dataset, tag.BitsStored), firstValue(dataset, tag.BitsAllocated)
+		if stored == "" && allocated == "" {
+			return ""
+		}
+		return stored + "/" + allocated
+	}},
+}
+
+// returns a profile of the entries: the number of files per modality, SOP class, transfer syntax, photometric
+// interpretation and bit depth
+func Inventory(entries []Entry) []InventoryCategory {
+	categories := make([]InventoryCategory, 0, len(inventoryCategories))
+	for _, category := range inventoryCategories {
+		counts := make(map[string]int)
+		for i := range entries {
+			value := category.value(&entries[i].Dataset)
+			if value == "" {
+				value = InventoryMissing
+			}
+			counts[value]++
+		}
+		categories = append(categories, InventoryCategory{category.name, sortedFrequencies(counts)})
+	}
+	return categories
+}
+
+// returns the first value of the top level element, "" if the dataset doesn't contain it
+func firstValue(dataset *dicom.Dataset, t tag.Tag) string {
+	e, err := dataset.FindElementByTag(t)
+	if err != nil {
+		return ""
+	}
+	return firstString(e)
+}
+
+// returns the first string value of the element without padding
+func firstString(e *dicom.Element) string {
+	if values := ValueStrings(e); len(values) > 0 {
+		return strings.TrimRight(values[0], "\x00 ")
+	}
+	return ""
+}
+
+// returns the UID with its short name, e.g. "CT Image (1.2.840.10008.5.1.4.1.1.2)"
+func uidText(uid string) string {
+	if name := UIDShortName(uid); name != uid {
+		return name + " (" + uid + ")"
+	}
+	return uid
+}
+
+// returns the values with their counts, most frequent first and equally frequent ones sorted by value
+func sortedFrequencies(counts map[string]int) []ValueFrequency {
+	frequencies := make([]ValueFrequency, 0, len(counts))
+	for value, count := range counts {
+		frequencies = append(frequencies, ValueFrequency{value, count})
+	}
+	sort.Slice(frequencies, func(i, j int) bool {
+		if frequencies[i].Count != frequencies[j].Count {
+			return frequencies[i].Count > frequencies[j].Count
+		}
+		return frequencies[i].Value < frequencies[j].Value
+	})
+	return frequencies
+}
@@ -0,0 +1,30 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestInventory(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT"), newTestEntry(t, "b.dcm", "Doe^Jane", "CT"), newTestEntry(t, "c.dcm", "Doe^Jane", "SR")}
+	for i := range entries[:2] {
+		entries[i].Dataset.Elements = append(entries[i].Dataset.Elements,
+			mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+			mustElement(t, tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+			mustElement(t, tag.BitsAllocated, []int{16}),
+			mustElement(t, tag.BitsStored, []int{12}))
+	}
+	entries[1].Dataset.Elements = append(entries[1].Dataset.Elements, mustElement(t, tag.TransferSyntaxUID, []string{"1.2.3"}))
+
+	inventory := Inventory(entries)
+	assert.Len(inventory, 5)
+	assert.Equal(InventoryCategory{"Modality", []ValueFrequency{{"CT", 2}, {"SR", 1}}}, inventory[0])
+	assert.Equal([]ValueFrequency{{"CT Image (1.2.840.10008.5.1.4.1.1.2)", 2}, {InventoryMissing, 1}}, inventory[1].Frequencies)
+	assert.Equal([]ValueFrequency{{InventoryMissing, 2}, {"1.2.3", 1}}, inventory[2].Frequencies)
+	assert.Equal([]ValueFrequency{{"MONOCHROME2", 2}, {InventoryMissing, 1}}, inventory[3].Frequencies)
+	assert.Equal(InventoryCategory{"Bit depth (stored/allocated)", []ValueFrequency{{"12/16", 2}, {InventoryMissing, 1}}}, inventory[4])
+}
@@ -0,0 +1,45 @@
+package dicomtree
+
+import (
+	"sort"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// top level tag present in some files and absent in others
+type MissingTag struct {
+	Tag     tag.Tag
+	Present []*Entry
+	Missing []*Entry
+}
+
+// returns the tags that are not present in all entries, sorted by tag
+func FindMissingTags(entries []Entry) []MissingTag {
+	presentByTag := make(map[tag.Tag]map[*Entry]bool)
+	for i := range entries {
+		for _, e := range entries[i].Dataset.Elements {
+			if presentByTag[e.Tag] == nil {
+				presentByTag[e.Tag] = make(map[*Entry]bool)
+			}
+			presentByTag[e.Tag][&entries[i]] = true
+		}
+	}
+
+	missingTags := make([]MissingTag, 0)
+	for t, present := range presentByTag {
+		if len(present) == len(entries) {
+			continue
+		}
+		missingTag := MissingTag{Tag: t}
+		for i := range entries {
+			if present[&entries[i]] {
+				missingTag.Present = append(missingTag.Present, &entries[i])
+			} else {
+				missingTag.Missing = append(missingTag.Missing, &entries[i])
+			}
+		}
+		missingTags = append(missingTags, missingTag)
+	}
+	sort.Slice(missingTags, func(i, j int) bool { return TagOrder(missingTags[i].Tag) < TagOrder(missingTags[j].Tag) })
+	return missingTags
+}
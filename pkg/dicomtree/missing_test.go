@@ -0,0 +1,25 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestFindMissingTags(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT"), newTestEntry(t, "b.dcm", "Doe^Jane", "CT"), newTestEntry(t, "c.dcm", "Doe^Jim", "CT")}
+	entries[1].Dataset.Elements = append(entries[1].Dataset.Elements, mustElement(t, tag.SliceLocation, []string{"1.5"}))
+	entries[2].Dataset.Elements = entries[2].Dataset.Elements[1:]
+
+	missingTags := FindMissingTags(entries)
+	assert.Len(missingTags, 2)
+	assert.Equal(tag.Modality, missingTags[0].Tag)
+	assert.Equal([]*Entry{&entries[0], &entries[1]}, missingTags[0].Present)
+	assert.Equal([]*Entry{&entries[2]}, missingTags[0].Missing)
+	assert.Equal(tag.SliceLocation, missingTags[1].Tag)
+	assert.Equal([]*Entry{&entries[1]}, missingTags[1].Present)
+	assert.Empty(FindMissingTags(entries[:1]))
+}
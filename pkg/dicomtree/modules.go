@@ -0,0 +1,134 @@
+package dicomtree
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// module of the standard, a named set of attributes shared by composite IODs
+type Module struct {
+	Name string
+	Tags []tag.Tag
+}
+
+// the common modules of the composite IODs with their most used attributes
+var standardModules = []Module{
+	{"Patient", []tag.Tag{tag.PatientName, tag.PatientID, tag.IssuerOfPatientID, tag.PatientBirthDate, tag.PatientBirthTime,
+		tag.PatientSex, tag.OtherPatientIDs, tag.OtherPatientNames, tag.EthnicGroup, tag.PatientComments,
+		tag.PatientIdentityRemoved, tag.DeidentificationMethod}},
+	{"General Study", []tag.Tag{tag.StudyInstanceUID, tag.StudyDate, tag.StudyTime, tag.ReferringPhysicianName, tag.StudyID,
+		tag.AccessionNumber, tag.StudyDescription, tag.PhysiciansOfRecord}},
+	{"Patient Study", []tag.Tag{tag.PatientAge, tag.PatientSize, tag.PatientWeight, tag.PatientAddress,
+		tag.PatientTelephoneNumbers, tag.PatientMotherBirthName, tag.MilitaryRank}},
+	{"General Series", []tag.Tag{tag.Modality, tag.SeriesInstanceUID, tag.SeriesNumber, tag.SeriesDate, tag.SeriesTime,
+		tag.SeriesDescription, tag.PerformingPhysicianName, tag.OperatorsName, tag.ProtocolName, tag.BodyPartExamined}},
+	{"CR Series", []tag.Tag{tag.ViewPosition}},
+	{"Frame of Reference", []tag.Tag{tag.FrameOfReferenceUID, tag.PositionReferenceIndicator}},
+	{"General Equipment", []tag.Tag{tag.Manufacturer, tag.InstitutionName, tag.InstitutionAddress, tag.StationName,
+		tag.InstitutionalDepartmentName, tag.ManufacturerModelName, tag.DeviceSerialNumber, tag.SoftwareVersions}},
+	{"SC Equipment", []tag.Tag{tag.ConversionType}},
+	{"General Acquisition", []tag.Tag{tag.AcquisitionNumber, tag.AcquisitionDate, tag.AcquisitionTime, tag.AcquisitionDateTime}},
+	{"General Image", []tag.Tag{tag.InstanceNumber, tag.PatientOrientation, tag.ContentDate, tag.ContentTime, tag.ImageType,
+		tag.DerivationDescription, tag.ReferencedImageSequence, tag.BurnedInAnnotation, tag.LossyImageCompression}},
+	{"Image Plane", []tag.Tag{tag.PixelSpacing, tag.ImageOrientationPatient, tag.ImagePositionPatient, tag.SliceThickness,
+		tag.SliceLocation}},
+	{"Image Pixel", []tag.Tag{tag.SamplesPerPixel, tag.PhotometricInterpretation, tag.Rows, tag.Columns, tag.BitsAllocated,
+		tag.BitsStored, tag.HighBit, tag.PixelRepresentation, tag.PlanarConfiguration, tag.PixelData}},
+	{"Multi-frame", []tag.Tag{tag.NumberOfFrames}},
+	{"CT Image", []tag.Tag{tag.KVP, tag.RescaleIntercept, tag.RescaleSlope}},
+	{"MR Image", []tag.Tag{tag.ScanningSequence, tag.SequenceVariant, tag.ScanOptions, tag.MRAcquisitionType, tag.EchoTime,
+		tag.EchoTrainLength}},
+	{"Modality LUT", []tag.Tag{tag.RescaleIntercept, tag.RescaleSlope}},
+	{"VOI LUT", []tag.Tag{tag.WindowCenter, tag.WindowWidth}},
+	{"SOP Common", []tag.Tag{tag.SOPClassUID, tag.SOPInstanceUID, tag.SpecificCharacterSet, tag.InstanceCreationDate,
+		tag.InstanceCreationTime, tag.TimezoneOffsetFromUTC}},
+}
+
+// modules of the composite IODs by SOP class UID, in the order of the IOD definitions
+var iodModules = map[string][]string{
+	"1.2.840.10008.5.1.4.1.1.1": {"Patient", "General Study", "Patient Study", "General Series", "CR Series", "General Equipment",
+		"General Acquisition", "General Image", "Image Pixel", "Modality LUT", "VOI LUT", "SOP Common"},
+	"1.2.840.10008.5.1.4.1.1.2": {"Patient", "General Study", "Patient Study", "General Series", "Frame of Reference",
+		"General Equipment", "General Acquisition", "General Image", "Image Plane", "Image Pixel", "CT Image", "VOI LUT", "SOP Common"},
+	"1.2.840.10008.5.1.4.1.1.4": {"Patient", "General Study", "Patient Study", "General Series", "Frame of Reference",
+		"General Equipment", "General Acquisition", "General Image", "Image Plane", "Image Pixel", "MR Image", "VOI LUT", "SOP Common"},
+	"1.2.840.10008.5.1.4.1.1.7": {"Patient", "General Study", "Patient Study", "General Series", "General Equipment",
+		"SC Equipment", "General Acquisition", "General Image", "Image Pixel", "Modality LUT", "VOI LUT", "SOP Common"},
+}
+
+const (
+	fileMetaModule = "File Meta Information"
+	otherModule    = "Other"
+)
+
+// returns the modules of the IOD of the SOP class in order, all known modules for an unknown SOP class
+func modulesOfIOD(sopClassUID string) []Module {
+	names, ok := iodModules[sopClassUID]
+	if !ok {
+		return standardModules
+	}
+	modules := make([]Module, 0, len(names))
+	for _, name := range names {
+		for _, module := range standardModules {
+			if module.Name == name {
+				modules = append(modules, module)
+			}
+		}
+	}
+	return modules
+}
+
+// builds a tree with a node per file, under each file the elements grouped by the modules of the file's IOD instead
+// of the tag groups. Group 0002 is shown as file meta information, elements of no module of the IOD below "Other".
+// For a single file the file node is the root.
+func BuildByModule(rootText string, entries []Entry, opts DisplayOptions) *Node {
+	root := NewNode(rootText, nil)
+	for i := range entries {
+		entry := &entries[i]
+		fileNode := NewNode(entry.Filename, nil)
+		if len(entries) == 1 {
+			root = fileNode
+		} else {
+			root.AddChild(fileNode)
+		}
+
+		modules := modulesOfIOD(UIDValue(&entry.Dataset, tag.SOPClassUID))
+		moduleByTag := make(map[tag.Tag]string)
+		for j := len(modules) - 1; j >= 0; j-- {
+			for _, t := range modules[j].Tags {
+				moduleByTag[t] = modules[j].Name // the first module of the IOD wins for attributes of several modules
+			}
+		}
+
+		charsets := charset.FromDataset(&entry.Dataset)
+		moduleNodes := make(map[string]*Node)
+		for _, e := range entry.Dataset.Elements {
+			name, ok := moduleByTag[e.Tag]
+			if e.Tag.Group == 0x0002 {
+				name = fileMetaModule
+			} else if !ok {
+				name = otherModule
+			}
+			node, ok := moduleNodes[name]
+			if !ok {
+				node = NewNode(name, nil)
+				moduleNodes[name] = node
+			}
+			node.AddChild(newElementNode(entry, e, charsets, opts, false))
+		}
+
+		names := []string{fileMetaModule}
+		for _, module := range modules {
+			names = append(names, module.Name)
+		}
+		for _, name := range append(names, otherModule) {
+			if node, ok := moduleNodes[name]; ok {
+				node.Text = fmt.Sprintf("%s (%d)", name, len(node.Children))
+				fileNode.AddChild(node)
+			}
+		}
+	}
+	return root
+}
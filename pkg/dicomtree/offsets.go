@@ -0,0 +1,392 @@
+package dicomtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const undefinedLength = 0xffffffff
+
+// maximum length of a UI value, a longer TransferSyntaxUID is skipped instead of read
+const maxUIDLength = 64
+
+// value representations that use a 4 byte length field (with 2 reserved bytes) in explicit VR encoding
+var longLengthVRs = map[string]bool{
+	"OB": true, "OD": true, "OF": true, "OL": true, "OV": true, "OW": true,
+	"SQ": true, "SV": true, "UC": true, "UN": true, "UR": true, "UT": true, "UV": true,
+}
+
+// all value representations of the standard, others are reported as parse warning
+var knownVRs = map[string]bool{
+	"AE": true, "AS": true, "AT": true, "CS": true, "DA": true, "DS": true, "DT": true, "FL": true, "FD": true,
+	"IS": true, "LO": true, "LT": true, "OB": true, "OD": true, "OF": true, "OL": true, "OV": true, "OW": true,
+	"PN": true, "SH": true, "SL": true, "SQ": true, "SS": true, "ST": true, "SV": true, "TM": true, "UC": true,
+	"UI": true, "UL": true, "UN": true, "UR": true, "US": true, "UT": true, "UV": true,
+}
+
+// an irregularity of the encoding of a top level element the dicom parser tolerates silently
+type ParseWarning struct {
+	Tag     tag.Tag
+	Offset  int64 // position of the element tag in the file
+	Message string
+}
+
+type ElementOffset struct {
+	Offset int64 // position of the element tag in the file
+	Length int64 // encoded length including tag, VR and length fields
+}
+
+// scans the raw file without interpreting values to find the byte positions of the top level elements,
+// the dicom parser doesn't expose these
+type offsetScanner struct {
+	r        *bufio.Reader
+	pos      int64
+	order    binary.ByteOrder
+	explicit bool
+	warnings []ParseWarning
+	stopAt   tag.Tag // the scan ends at this top level element if set, its offset is recorded with length 0
+}
+
+// scans the file for the byte positions and encoded lengths of its top level elements
+func ScanElementOffsets(path string) (map[tag.Tag]ElementOffset, error) {
+	offsets, _, err := scanFile(path)
+	return offsets, err
+}
+
+// scans the file for odd value lengths, unknown VRs, VR UN for known tags and values truncated by the end of the
+// file, which the dicom parser doesn't report
+func ScanParseWarnings(path string) ([]ParseWarning, error) {
+	_, warnings, err := scanFile(path)
+	return warnings, err
+}
+
+func scanFile(path string) (map[tag.Tag]ElementOffset, []ParseWarning, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	s := &offsetScanner{r: bufio.NewReader(file), order: binary.LittleEndian, explicit: true, warnings: make([]ParseWarning, 0)}
+	offsets, err := s.scan()
+	return offsets, s.warnings, err
+}
+
+// parses the file up to its top level pixel data, e.g. to read the headers of many files without their frames.
+// Elements after the pixel data, like trailing padding, are not read. Returns whether the file has pixel data.
+func ParseFileWithoutPixelData(path string) (dicom.Dataset, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return dicom.Dataset{}, false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return dicom.Dataset{}, false, err
+	}
+	s := &offsetScanner{r: bufio.NewReader(file), order: binary.LittleEndian, explicit: true, stopAt: tag.PixelData}
+	offsets, err := s.scan()
+	if err != nil {
+		return dicom.Dataset{}, false, err
+	}
+	size := info.Size()
+	pixelData, hasPixelData := offsets[tag.PixelData]
+	if hasPixelData {
+		size = pixelData.Offset
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return dicom.Dataset{}, false, err
+	}
+	dataset, err := dicom.Parse(file, size, nil)
+	return dataset, hasPixelData, err
+}
+
+func (s *offsetScanner) scan() (map[tag.Tag]ElementOffset, error) {
+	offsets := make(map[tag.Tag]ElementOffset)
+	if header, err := s.r.Peek(132); err == nil && string(header[128:132]) == "DICM" {
+		if err := s.skip(132); err != nil {
+			return offsets, err
+		}
+	}
+
+	inMetaGroup := true
+	transferSyntax := ""
+	for {
+		if inMetaGroup {
+			group, err := s.r.Peek(2)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return offsets, err
+			}
+			if binary.LittleEndian.Uint16(group) != 0x0002 {
+				inMetaGroup = false
+				if err := s.setTransferSyntax(transferSyntax); err != nil {
+					return offsets, err
+				}
+			}
+		}
+
+		start := s.pos
+		t, vr, length, err := s.readHeader()
+		if err == io.EOF && s.pos == start {
+			break
+		} else if err == io.EOF || err == io.ErrUnexpectedEOF {
+			s.warn(t, start, "element header truncated by the end of the file")
+			break
+		} else if err != nil {
+			return offsets, err
+		}
+		if s.stopAt != (tag.Tag{}) && t == s.stopAt {
+			offsets[t] = ElementOffset{start, 0}
+			break
+		}
+		s.checkHeader(t, vr, length, start)
+
+		if t == tag.TransferSyntaxUID && length <= maxUIDLength {
+			value := make([]byte, length)
+			err = s.read(value)
+			transferSyntax = strings.TrimRight(string(value), "\x00 ")
+		} else {
+			err = s.skipValue(vr, length)
+		}
+		offsets[t] = ElementOffset{start, s.pos - start}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			s.warn(t, start, fmt.Sprintf("value truncated by the end of the file after %d bytes", s.pos-start))
+			break
+		} else if err != nil {
+			return offsets, err
+		}
+	}
+
+	return offsets, nil
+}
+
+func (s *offsetScanner) warn(t tag.Tag, offset int64, message string) {
+	s.warnings = append(s.warnings, ParseWarning{t, offset, message})
+}
+
+// adds the warnings about the VR and length of a top level element
+func (s *offsetScanner) checkHeader(t tag.Tag, vr string, length uint32, offset int64) {
+	if vr != "" && !knownVRs[vr] {
+		s.warn(t, offset, fmt.Sprintf("unknown VR '%s'", strings.ToValidUTF8(vr, "?")))
+	} else if info, err := tag.Find(t); err == nil && vr == "UN" && info.VR != "UN" {
+		s.warn(t, offset, fmt.Sprintf("VR UN instead of %s", info.VR))
+	}
+	if length != undefinedLength && length%2 == 1 {
+		s.warn(t, offset, fmt.Sprintf("odd value length %d", length))
+	}
+	if t == tag.TransferSyntaxUID && length > maxUIDLength && length != undefinedLength {
+		s.warn(t, offset, fmt.Sprintf("value length %d exceeds the %d bytes of a UID", length, maxUIDLength))
+	}
+}
+
+func (s *offsetScanner) setTransferSyntax(transferSyntax string) error {
+	switch transferSyntax {
+	case "1.2.840.10008.1.2":
+		s.order, s.explicit = binary.LittleEndian, false
+	case "1.2.840.10008.1.2.2":
+		s.order, s.explicit = binary.BigEndian, true
+	case "1.2.840.10008.1.2.1.99":
+		return errors.New("deflated transfer syntax is not supported for offset scanning")
+	default:
+		s.order, s.explicit = binary.LittleEndian, true
+	}
+	return nil
+}
+
+func (s *offsetScanner) read(buf []byte) error {
+	n, err := io.ReadFull(s.r, buf)
+	s.pos += int64(n)
+	return err
+}
+
+func (s *offsetScanner) skip(n int64) error {
+	for n > 0 {
+		chunk := n
+		if chunk > 1<<30 {
+			chunk = 1 << 30
+		}
+		discarded, err := s.r.Discard(int(chunk))
+		s.pos += int64(discarded)
+		if err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+func (s *offsetScanner) readHeader() (tag.Tag, string, uint32, error) {
+	buf := make([]byte, 8)
+	if err := s.read(buf[:4]); err != nil {
+		return tag.Tag{}, "", 0, err
+	}
+	t := tag.Tag{Group: s.order.Uint16(buf[0:2]), Element: s.order.Uint16(buf[2:4])}
+
+	// item and delimitation tags never have a VR
+	if t.Group == 0xfffe || !s.explicit {
+		if err := s.read(buf[4:8]); err != nil {
+			return t, "", 0, err
+		}
+		return t, "", s.order.Uint32(buf[4:8]), nil
+	}
+
+	if err := s.read(buf[:2]); err != nil {
+		return t, "", 0, err
+	}
+	vr := string(buf[:2])
+	if longLengthVRs[vr] {
+		if err := s.read(buf[:6]); err != nil {
+			return t, vr, 0, err
+		}
+		return t, vr, s.order.Uint32(buf[2:6]), nil
+	}
+	if err := s.read(buf[:2]); err != nil {
+		return t, vr, 0, err
+	}
+	return t, vr, uint32(s.order.Uint16(buf[:2])), nil
+}
+
+func (s *offsetScanner) skipValue(vr string, length uint32) error {
+	if length != undefinedLength {
+		return s.skip(int64(length))
+	}
+	// undefined length is only allowed for sequences and encapsulated pixel data, both consist of items
+	return s.skipItems()
+}
+
+func (s *offsetScanner) skipItems() error {
+	for {
+		t, _, length, err := s.readHeader()
+		if err != nil {
+			return err
+		}
+		switch {
+		case t == tag.SequenceDelimitationItem:
+			return nil
+		case t == tag.Item && length == undefinedLength:
+			if err := s.skipElementsUntil(tag.ItemDelimitationItem); err != nil {
+				return err
+			}
+		case t == tag.Item:
+			if err := s.skip(int64(length)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected tag %s at offset %d while reading items", FormatTag(t), s.pos)
+		}
+	}
+}
+
+func (s *offsetScanner) skipElementsUntil(delimiter tag.Tag) error {
+	for {
+		t, vr, length, err := s.readHeader()
+		if err != nil {
+			return err
+		}
+		if t == delimiter {
+			return nil
+		}
+		if err := s.skipValue(vr, length); err != nil {
+			return err
+		}
+	}
+}
+
+// computes the element offsets of the entry if not already done
+func (entry *Entry) EnsureOffsets() error {
+	if entry.Offsets != nil {
+		return nil
+	}
+	offsets, warnings, err := scanFile(entry.Path)
+	entry.Offsets = offsets
+	if entry.Warnings == nil {
+		entry.Warnings = warnings
+	}
+	return err
+}
+
+// scans the file of the entry for parse warnings if not already done, files that can't be read, e.g. archive
+// members, have no warnings
+func (entry *Entry) EnsureWarnings() {
+	if entry.Warnings != nil {
+		return
+	}
+	offsets, warnings, _ := scanFile(entry.Path)
+	if entry.Offsets == nil {
+		entry.Offsets = offsets
+	}
+	entry.Warnings = warnings
+	if entry.Warnings == nil {
+		entry.Warnings = make([]ParseWarning, 0)
+	}
+}
+
+// returns the parse warnings of the top level element as badge, e.g. " [! odd value length 7]", "" if there are none
+func (entry *Entry) WarningText(e *dicom.Element) string {
+	var messages []string
+	for _, w := range entry.Warnings {
+		if w.Tag == e.Tag {
+			messages = append(messages, w.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+	return " [! " + strings.Join(messages, ", ") + "]"
+}
+
+// returns the top level element whose encoding contains the given file offset, nil if none
+func (entry *Entry) FindElementAtOffset(offset int64) *dicom.Element {
+	for _, e := range entry.Dataset.Elements {
+		if elementOffset, ok := entry.Offsets[e.Tag]; ok && offset >= elementOffset.Offset && offset < elementOffset.Offset+elementOffset.Length {
+			return e
+		}
+	}
+	return nil
+}
+
+// reads the encoded value of the top level element from the file of the entry, e.g. for values with VR UN the parser
+// trimmed as strings
+func (entry *Entry) ReadValueBytes(e *dicom.Element) ([]byte, error) {
+	if err := entry.EnsureOffsets(); err != nil {
+		return nil, err
+	}
+	elementOffset, ok := entry.Offsets[e.Tag]
+	if !ok || e.ValueLength == undefinedLength || int64(e.ValueLength) > elementOffset.Length {
+		return nil, fmt.Errorf("no value of %s found in %s", FormatTag(e.Tag), entry.Filename)
+	}
+	file, err := os.Open(entry.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	value := make([]byte, e.ValueLength)
+	if _, err := file.ReadAt(value, elementOffset.Offset+elementOffset.Length-int64(e.ValueLength)); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// parses decimal or 0x prefixed hexadecimal offsets
+func ParseOffset(text string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(text), 0, 64)
+}
+
+func (entry *Entry) OffsetText(e *dicom.Element) string {
+	elementOffset, ok := entry.Offsets[e.Tag]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" [@0x%08x, %d bytes]", elementOffset.Offset, elementOffset.Length)
+}
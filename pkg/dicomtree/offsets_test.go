@@ -0,0 +1,148 @@
+package dicomtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestScanElementOffsets(t *testing.T) {
+	assert := assert.New(t)
+
+	offsets, err := ScanElementOffsets("../../testdata/test.dcm")
+	assert.NoError(err)
+
+	// the meta information group starts directly after preamble and prefix
+	groupLength, ok := offsets[tag.FileMetaInformationGroupLength]
+	assert.True(ok)
+	assert.Equal(int64(132), groupLength.Offset)
+	assert.Equal(int64(12), groupLength.Length)
+
+	// the pixel data is the last element and ends with the file
+	end := int64(0)
+	for _, elementOffset := range offsets {
+		if elementOffset.Offset+elementOffset.Length > end {
+			end = elementOffset.Offset + elementOffset.Length
+		}
+	}
+	pixelData, ok := offsets[tag.PixelData]
+	assert.True(ok)
+	assert.Equal(end, pixelData.Offset+pixelData.Length)
+	assert.Equal(int64(528384), end)
+}
+
+func TestParseFileWithoutPixelData(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset, hasPixelData, err := ParseFileWithoutPixelData("../../testdata/test.dcm")
+	assert.NoError(err)
+	assert.True(hasPixelData)
+	full, err := dicom.ParseFile("../../testdata/test.dcm", nil)
+	require.NoError(t, err)
+	assert.Len(dataset.Elements, len(full.Elements)-1)
+	_, err = dataset.FindElementByTag(tag.PixelData)
+	assert.Error(err)
+	assert.Equal(UIDValue(&full, tag.SOPInstanceUID), UIDValue(&dataset, tag.SOPInstanceUID))
+
+	_, _, err = ParseFileWithoutPixelData("missing.dcm")
+	assert.Error(err)
+}
+
+func TestParseOffset(t *testing.T) {
+	assert := assert.New(t)
+
+	offset, err := ParseOffset(" 0x1f ")
+	assert.NoError(err)
+	assert.Equal(int64(31), offset)
+	offset, err = ParseOffset("132")
+	assert.NoError(err)
+	assert.Equal(int64(132), offset)
+	_, err = ParseOffset("abc")
+	assert.Error(err)
+}
+
+func TestReadValueBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	entry := Entry{Filename: "irregular.dcm", Path: newIrregularFile(t)}
+	patientName := mustElement(t, tag.PatientName, []string{"Doe^Joh"})
+	patientName.ValueLength = 7
+	value, err := entry.ReadValueBytes(patientName)
+	assert.NoError(err)
+	assert.Equal("Doe^Joh", string(value))
+
+	_, err = entry.ReadValueBytes(mustElement(t, tag.Modality, []string{"CT"}))
+	assert.EqualError(err, "no value of (0008,0060) found in irregular.dcm")
+}
+
+// returns a file with explicit VR little endian elements, the last one truncated
+func newIrregularFile(t *testing.T) string {
+	var data bytes.Buffer
+	data.Write(make([]byte, 128))
+	data.WriteString("DICM")
+	element := func(group, element uint16, vr string, value string, length int) {
+		binary.Write(&data, binary.LittleEndian, []uint16{group, element})
+		data.WriteString(vr)
+		if vr == "OW" {
+			binary.Write(&data, binary.LittleEndian, []uint16{0, uint16(length), uint16(length >> 16)})
+		} else {
+			binary.Write(&data, binary.LittleEndian, uint16(length))
+		}
+		data.WriteString(value)
+	}
+	element(0x0002, 0x0010, "UI", "1.2.840.10008.1.2.1\x00", 20)
+	element(0x0010, 0x0010, "PN", "Doe^Joh", 7)
+	element(0x0010, 0x0020, "XY", "12", 2)
+	element(0x7fe0, 0x0010, "OW", "0123456789", 100)
+	path := filepath.Join(t.TempDir(), "irregular.dcm")
+	require.NoError(t, os.WriteFile(path, data.Bytes(), 0o644))
+	return path
+}
+
+func TestScanParseWarnings(t *testing.T) {
+	assert := assert.New(t)
+
+	warnings, err := ScanParseWarnings(newIrregularFile(t))
+	assert.NoError(err)
+	if assert.Len(warnings, 3) {
+		assert.Equal(ParseWarning{tag.PatientName, 160, "odd value length 7"}, warnings[0])
+		assert.Equal(ParseWarning{tag.PatientID, 175, "unknown VR 'XY'"}, warnings[1])
+		assert.Equal(tag.PixelData, warnings[2].Tag)
+		assert.Equal("value truncated by the end of the file after 22 bytes", warnings[2].Message)
+	}
+
+	warnings, err = ScanParseWarnings("../../testdata/test.dcm")
+	assert.NoError(err)
+	assert.Empty(warnings)
+
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []uint16{0x0002, 0x0010})
+	data.WriteString("UN")
+	binary.Write(&data, binary.LittleEndian, []uint16{0, 0xfffe, 0x7fff})
+	data.WriteString("1.2.840.10008.1.2.1")
+	path := filepath.Join(t.TempDir(), "long.dcm")
+	require.NoError(t, os.WriteFile(path, data.Bytes(), 0o644))
+	warnings, err = ScanParseWarnings(path)
+	assert.NoError(err)
+	if assert.Len(warnings, 3) {
+		assert.Equal(ParseWarning{tag.TransferSyntaxUID, 0, "value length 2147483646 exceeds the 64 bytes of a UID"}, warnings[1])
+		assert.Equal("value truncated by the end of the file after 31 bytes", warnings[2].Message)
+	}
+
+	entry := Entry{Path: newIrregularFile(t)}
+	entry.EnsureWarnings()
+	assert.Len(entry.Warnings, 3)
+	assert.Equal(" [! odd value length 7]", entry.WarningText(mustElement(t, tag.PatientName, []string{"Doe^Joh"})))
+	assert.Equal("", entry.WarningText(mustElement(t, tag.Modality, []string{"CT"})))
+
+	entry = Entry{Path: "missing.dcm"}
+	entry.EnsureWarnings()
+	assert.NotNil(entry.Warnings, "scanned without warnings")
+}
@@ -0,0 +1,97 @@
+package dicomtree
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// order of the file nodes
+type FileOrder int
+
+const (
+	FileOrderName    FileOrder = iota // natural order of the filenames, IM9 before IM10
+	FileOrderModTime                  // oldest file first
+	FileOrderSize                     // smallest file first
+)
+
+func (o FileOrder) String() string {
+	switch o {
+	case FileOrderModTime:
+		return "modification time"
+	case FileOrderSize:
+		return "size"
+	default:
+		return "name"
+	}
+}
+
+// returns whether a is before b in natural order, which compares runs of digits by their numeric value, e.g. IM9 < IM10
+func NaturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aChunk, aRest := nextNaturalChunk(a)
+		bChunk, bRest := nextNaturalChunk(b)
+		if aChunk != bChunk {
+			if isDigit(aChunk[0]) && isDigit(bChunk[0]) {
+				aNumber, bNumber := strings.TrimLeft(aChunk, "0"), strings.TrimLeft(bChunk, "0")
+				if len(aNumber) != len(bNumber) {
+					return len(aNumber) < len(bNumber)
+				}
+				if aNumber != bNumber {
+					return aNumber < bNumber
+				}
+				return len(aChunk) < len(bChunk) // same value, fewer leading zeros first
+			}
+			return aChunk < bChunk
+		}
+		a, b = aRest, bRest
+	}
+	return a == "" && b != ""
+}
+
+// splits the text after its leading run of digits or non-digits
+func nextNaturalChunk(text string) (string, string) {
+	digits := isDigit(text[0])
+	i := 1
+	for i < len(text) && isDigit(text[i]) == digits {
+		i++
+	}
+	return text[:i], text[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// returns a copy of the entries sorted in the order, ties and files that can't be stat'ed, e.g. archive members, are
+// sorted by name
+func SortedEntries(entries []Entry, order FileOrder, reverse bool) []Entry {
+	sorted := append([]Entry{}, entries...)
+	infos := make(map[string]os.FileInfo)
+	if order != FileOrderName {
+		for _, entry := range entries {
+			if info, err := os.Stat(entry.Path); err == nil {
+				infos[entry.Path] = info
+			}
+		}
+	}
+	less := func(a, b *Entry) bool {
+		aInfo, bInfo := infos[a.Path], infos[b.Path]
+		if aInfo != nil && bInfo != nil {
+			switch {
+			case order == FileOrderModTime && !aInfo.ModTime().Equal(bInfo.ModTime()):
+				return aInfo.ModTime().Before(bInfo.ModTime())
+			case order == FileOrderSize && aInfo.Size() != bInfo.Size():
+				return aInfo.Size() < bInfo.Size()
+			}
+		}
+		return NaturalLess(a.Filename, b.Filename)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if reverse {
+			return less(&sorted[j], &sorted[i])
+		}
+		return less(&sorted[i], &sorted[j])
+	})
+	return sorted
+}
@@ -0,0 +1,50 @@
+package dicomtree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNaturalLess(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(NaturalLess("IM9", "IM10"))
+	assert.False(NaturalLess("IM10", "IM9"))
+	assert.True(NaturalLess("IM1", "IM9"))
+	assert.True(NaturalLess("IM2.dcm", "IM02.dcm"), "same value, fewer leading zeros first")
+	assert.True(NaturalLess("IM", "IM1"))
+	assert.True(NaturalLess("a10b2", "a10b10"))
+	assert.True(NaturalLess("CT", "MR"))
+	assert.False(NaturalLess("IM1", "IM1"))
+}
+
+func TestSortedEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	entries := []Entry{{Filename: "IM10"}, {Filename: "IM1"}, {Filename: "IM9"}}
+	for i, size := range []int{20, 30, 10} {
+		entries[i].Path = filepath.Join(dir, entries[i].Filename)
+		require.NoError(t, os.WriteFile(entries[i].Path, make([]byte, size), 0o644))
+		mtime := time.Date(2024, 1, 1, 0, 0, 10-i, 0, time.UTC)
+		require.NoError(t, os.Chtimes(entries[i].Path, mtime, mtime))
+	}
+	filenames := func(entries []Entry) []string {
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Filename
+		}
+		return names
+	}
+
+	assert.Equal([]string{"IM1", "IM9", "IM10"}, filenames(SortedEntries(entries, FileOrderName, false)))
+	assert.Equal([]string{"IM10", "IM9", "IM1"}, filenames(SortedEntries(entries, FileOrderName, true)))
+	assert.Equal([]string{"IM9", "IM1", "IM10"}, filenames(SortedEntries(entries, FileOrderModTime, false)))
+	assert.Equal([]string{"IM9", "IM10", "IM1"}, filenames(SortedEntries(entries, FileOrderSize, false)))
+	assert.Equal("IM10", entries[0].Filename, "entries are not changed")
+}
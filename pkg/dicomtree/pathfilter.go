@@ -0,0 +1,47 @@
+package dicomtree
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// include and exclude glob patterns selecting the files of directories and archives, the zero value selects all files
+type PathFilter struct {
+	include []string
+	exclude []string
+}
+
+// returns the filter for the glob patterns, e.g. "*.dcm" or "*/derived/*". A pattern is matched against as many
+// trailing path elements as it has, so "*.dcm" matches the filename and "*/derived/*" the files in any derived directory.
+func NewPathFilter(include, exclude []string) (PathFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return PathFilter{}, fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+		}
+	}
+	return PathFilter{include: include, exclude: exclude}, nil
+}
+
+// returns whether the file matches one of the include patterns, if any, and none of the exclude patterns
+func (f PathFilter) Match(filePath string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, filePath) {
+		return false
+	}
+	return !matchAny(f.exclude, filePath)
+}
+
+func matchAny(patterns []string, filePath string) bool {
+	elements := strings.Split(filepath.ToSlash(filePath), "/")
+	for _, pattern := range patterns {
+		n := strings.Count(pattern, "/") + 1
+		if n > len(elements) {
+			continue
+		}
+		if matched, _ := path.Match(pattern, strings.Join(elements[len(elements)-n:], "/")); matched {
+			return true
+		}
+	}
+	return false
+}
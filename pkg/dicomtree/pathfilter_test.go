@@ -0,0 +1,29 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(PathFilter{}.Match("/data/study/README"), "zero value selects all")
+
+	filter, err := NewPathFilter([]string{"*.dcm"}, []string{"*/derived/*"})
+	assert.NoError(err)
+	assert.True(filter.Match("/data/study/ct/1.dcm"))
+	assert.True(filter.Match("1.dcm"))
+	assert.False(filter.Match("/data/study/README"), "not included")
+	assert.False(filter.Match("/data/study/derived/1.dcm"), "excluded")
+	assert.False(filter.Match("study.zip/derived/1.dcm"), "archive member")
+
+	filter, err = NewPathFilter(nil, []string{"derived/*"})
+	assert.NoError(err)
+	assert.True(filter.Match("/data/study/README"), "no include pattern selects all")
+	assert.False(filter.Match("/data/derived/1.dcm"))
+
+	_, err = NewPathFilter([]string{"[a-"}, nil)
+	assert.Error(err)
+}
@@ -0,0 +1,47 @@
+package dicomtree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/suyashkumar/dicom"
+)
+
+// names of the component groups of a person name, PS3.5 6.2.1.1
+var PersonNameGroups = [3]string{"Alphabetic", "Ideographic", "Phonetic"}
+
+// splits a person name value like 'Yamada^Tarou=山田^太郎=やまだ^たろう' into its alphabetic, ideographic and phonetic
+// component groups, missing groups are empty
+func SplitPersonName(value string) [3]string {
+	var groups [3]string
+	copy(groups[:], strings.SplitN(value, "=", 3))
+	return groups
+}
+
+// joins the component groups to a person name value, without the delimiters of trailing empty groups
+func JoinPersonName(groups [3]string) string {
+	return strings.TrimRight(strings.Join(groups[:], "="), "=")
+}
+
+// adds a node per component group below the node of a PN element with ideographic or phonetic groups, each
+// referencing the element, so they are edited with it
+func addPersonNameNodes(node *Node, e *dicom.Element, charsets []string) {
+	if e.RawValueRepresentation != "PN" {
+		return
+	}
+	values := ValueStrings(e)
+	for i, value := range values {
+		value = charset.Decode(strings.TrimSpace(value), charsets)
+		if !strings.Contains(value, "=") {
+			continue
+		}
+		for j, group := range SplitPersonName(value) {
+			name := PersonNameGroups[j]
+			if len(values) > 1 {
+				name += fmt.Sprintf(" %d", i+1)
+			}
+			node.AddChild(NewNode(fmt.Sprintf("\t%s: %s", name, group), e))
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestSplitPersonName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([3]string{"Yamada^Tarou", "山田^太郎", "やまだ^たろう"}, SplitPersonName("Yamada^Tarou=山田^太郎=やまだ^たろう"))
+	assert.Equal([3]string{"Doe^John", "", ""}, SplitPersonName("Doe^John"))
+	assert.Equal([3]string{"", "", "やまだ^たろう"}, SplitPersonName("==やまだ^たろう"))
+
+	assert.Equal("Yamada^Tarou=山田^太郎", JoinPersonName([3]string{"Yamada^Tarou", "山田^太郎", ""}))
+	assert.Equal("Doe^John", JoinPersonName([3]string{"Doe^John", "", ""}))
+	assert.Equal("==やまだ^たろう", JoinPersonName([3]string{"", "", "やまだ^たろう"}))
+}
+
+func TestBuildWithPersonNameGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []Entry{newTestEntry(t, "a.dcm", "Yamada^Tarou=山田^太郎=やまだ^たろう", "CT")}
+	entries[0].Dataset.Elements = append(entries[0].Dataset.Elements,
+		mustElement(t, tag.ReferringPhysicianName, []string{"Doe^John"}))
+	for _, e := range entries[0].Dataset.Elements[1:] {
+		e.RawValueRepresentation = "PN"
+	}
+	root := BuildByFilename("a.dcm", entries, DisplayOptions{})
+	nameNode := root.Children[1].Children[0]
+	if assert.Len(nameNode.Children, 3) {
+		assert.Equal("\tAlphabetic: Yamada^Tarou", nameNode.Children[0].Text)
+		assert.Equal("\tIdeographic: 山田^太郎", nameNode.Children[1].Text)
+		assert.Equal("\tPhonetic: やまだ^たろう", nameNode.Children[2].Text)
+		assert.Same(nameNode.Element, nameNode.Children[2].Element, "groups are edited with the element")
+	}
+	assert.Empty(root.Children[2].Children[0].Children, "no groups without ideographic or phonetic ones")
+}
@@ -0,0 +1,146 @@
+package dicomtree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// formats a single raw value of the given value representation in a human-readable way,
+// values that do not match the expected format are returned unchanged
+func FormatPrettyValue(vr string, value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return value
+	}
+
+	switch vr {
+	case "DA":
+		return formatDate(trimmed)
+	case "TM":
+		return formatTime(trimmed)
+	case "DT":
+		return formatDateTime(trimmed)
+	case "PN":
+		return formatPersonName(trimmed)
+	case "AS":
+		return formatAge(trimmed)
+	case "DS", "IS":
+		return formatNumber(trimmed)
+	}
+	return value
+}
+
+func formatDate(value string) string {
+	value = strings.ReplaceAll(value, ".", "") // old ACR-NEMA format YYYY.MM.DD
+	if len(value) != 8 || !isDigits(value) {
+		return value
+	}
+	return value[:4] + "-" + value[4:6] + "-" + value[6:]
+}
+
+func formatTime(value string) string {
+	value = strings.ReplaceAll(value, ":", "") // old ACR-NEMA format HH:MM:SS.frac
+	fraction := ""
+	if idx := strings.IndexByte(value, '.'); idx >= 0 {
+		fraction = value[idx:]
+		value = value[:idx]
+	}
+	if len(value)%2 != 0 || len(value) > 6 || !isDigits(value) || (fraction != "" && !isDigits(fraction[1:])) {
+		return value + fraction
+	}
+	parts := make([]string, 0, 3)
+	for i := 0; i < len(value); i += 2 {
+		parts = append(parts, value[i:i+2])
+	}
+	return strings.Join(parts, ":") + fraction
+}
+
+func formatDateTime(value string) string {
+	offset := ""
+	if idx := strings.IndexAny(value, "+-"); idx >= 0 {
+		offset = " " + value[idx:]
+		value = value[:idx]
+	}
+	if len(value) < 8 {
+		return value + offset
+	}
+	date := formatDate(value[:8])
+	if len(value) == 8 {
+		return date + offset
+	}
+	return date + " " + formatTime(value[8:]) + offset
+}
+
+var personNameComponents = []string{"Family", "Given", "Middle", "Prefix", "Suffix"}
+
+func formatPersonName(value string) string {
+	groups := strings.Split(value, "=")
+	formattedGroups := make([]string, 0, len(groups))
+	for _, group := range groups {
+		components := strings.Split(group, "^")
+		formattedComponents := make([]string, 0, len(components))
+		for i, component := range components {
+			if i >= len(personNameComponents) || strings.TrimSpace(component) == "" {
+				continue
+			}
+			formattedComponents = append(formattedComponents, fmt.Sprintf("%s: %s", personNameComponents[i], strings.TrimSpace(component)))
+		}
+		if len(formattedComponents) > 0 {
+			formattedGroups = append(formattedGroups, strings.Join(formattedComponents, ", "))
+		}
+	}
+	if len(formattedGroups) == 0 {
+		return value
+	}
+	return strings.Join(formattedGroups, " = ")
+}
+
+var ageUnits = map[byte]string{'D': "day", 'W': "week", 'M': "month", 'Y': "year"}
+
+func formatAge(value string) string {
+	if len(value) != 4 || !isDigits(value[:3]) {
+		return value
+	}
+	unit, ok := ageUnits[value[3]]
+	if !ok {
+		return value
+	}
+	count, _ := strconv.Atoi(value[:3])
+	if count != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", count, unit)
+}
+
+// adds thousands separators to the integer part of a decimal or integer string
+func formatNumber(value string) string {
+	sign := ""
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		sign, value = value[:1], value[1:]
+	}
+	integer, fraction := value, ""
+	if idx := strings.IndexByte(value, '.'); idx >= 0 {
+		integer, fraction = value[:idx], value[idx:]
+	}
+	if !isDigits(integer) || (fraction != "" && !isDigits(fraction[1:])) {
+		return sign + value // e.g. exponential notation, leave as is
+	}
+	var sb strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	return sign + sb.String() + fraction
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
@@ -0,0 +1,23 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPrettyValue(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("2023-01-15", FormatPrettyValue("DA", "20230115"))
+	assert.Equal("14:30:15.1234", FormatPrettyValue("TM", "143015.1234"))
+	assert.Equal("14:30", FormatPrettyValue("TM", "1430"))
+	assert.Equal("2023-01-15 14:30:15 +0100", FormatPrettyValue("DT", "20230115143015+0100"))
+	assert.Equal("Family: Doe, Given: John, Prefix: Dr", FormatPrettyValue("PN", "Doe^John^^Dr"))
+	assert.Equal("45 years", FormatPrettyValue("AS", "045Y"))
+	assert.Equal("1 month", FormatPrettyValue("AS", "001M"))
+	assert.Equal("-1,234,567.89", FormatPrettyValue("DS", "-1234567.89"))
+	assert.Equal("123", FormatPrettyValue("IS", "123"))
+	assert.Equal("1.5e3", FormatPrettyValue("DS", "1.5e3"))
+	assert.Equal("not a date", FormatPrettyValue("DA", "not a date"))
+}
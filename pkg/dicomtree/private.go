@@ -0,0 +1,45 @@
+package dicomtree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// a block of 256 private data elements reserved by a private creator element (gggg,00bb), PS3.5 7.8.1
+type PrivateBlock struct {
+	Group uint16
+	Block uint16 // bb, the high byte of the elements of the block
+}
+
+// returns the block of a private data element, false for other tags including the private creator elements
+func PrivateBlockOf(t tag.Tag) (PrivateBlock, bool) {
+	if t.Group%2 == 0 || t.Group <= 0x0007 || t.Group == 0xffff || t.Element < 0x1000 {
+		return PrivateBlock{}, false
+	}
+	return PrivateBlock{t.Group, t.Element >> 8}, true
+}
+
+// returns the private creators of the blocks of the elements, e.g. "SIEMENS CSA HEADER" for (0029,10xx)
+func PrivateCreators(elements []*dicom.Element) map[PrivateBlock]string {
+	creators := make(map[PrivateBlock]string)
+	for _, e := range elements {
+		if e.Tag.Group%2 == 0 || e.Tag.Group <= 0x0007 || e.Tag.Element < 0x0010 || e.Tag.Element > 0x00ff {
+			continue
+		}
+		if values := ValueStrings(e); len(values) > 0 {
+			creators[PrivateBlock{e.Tag.Group, e.Tag.Element}] = strings.TrimRight(values[0], "\x00 ")
+		}
+	}
+	return creators
+}
+
+// returns the text of the node grouping the elements of a private block, e.g. "10xx SIEMENS CSA HEADER"
+func privateBlockText(block PrivateBlock, creator string) string {
+	if creator == "" {
+		creator = "<no private creator>"
+	}
+	return fmt.Sprintf("%02xxx %s", block.Block, creator)
+}
@@ -0,0 +1,114 @@
+package dicomtree
+
+import (
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// UID an element refers to another instance, series, study or frame of reference by
+type Reference struct {
+	Element *dicom.Element
+	Target  tag.Tag // tag identifying the referenced object in its own dataset, e.g. SOPInstanceUID
+	UID     string
+}
+
+// tags of the UIDs referring to other objects with the tag identifying the referenced object. Study, series and
+// frame of reference UIDs only refer to others within sequences, at the top level they identify the dataset itself.
+var referenceTargets = map[tag.Tag]tag.Tag{
+	tag.ReferencedSOPInstanceUID:      tag.SOPInstanceUID,
+	tag.ReferencedFrameOfReferenceUID: tag.FrameOfReferenceUID,
+	tag.FrameOfReferenceUID:           tag.FrameOfReferenceUID,
+	tag.SeriesInstanceUID:             tag.SeriesInstanceUID,
+	tag.StudyInstanceUID:              tag.StudyInstanceUID,
+}
+
+// returns what the reference refers to, e.g. "instance" or "frame of reference"
+func (r Reference) Kind() string {
+	switch r.Target {
+	case tag.SOPInstanceUID:
+		return "instance"
+	case tag.SeriesInstanceUID:
+		return "series"
+	case tag.StudyInstanceUID:
+		return "study"
+	}
+	return "frame of reference"
+}
+
+// returns the reference of an element, for sequences the first reference within its items
+func ElementReference(e *dicom.Element) (Reference, bool) {
+	if IsSequence(e) {
+		for _, item := range SequenceItems(e) {
+			if references := References(item); len(references) > 0 {
+				return references[0], true
+			}
+		}
+		return Reference{}, false
+	}
+	target, ok := referenceTargets[e.Tag]
+	if !ok {
+		return Reference{}, false
+	}
+	uid := strings.TrimRight(ValueText(e, nil), "\x00 ")
+	return Reference{Element: e, Target: target, UID: uid}, uid != ""
+}
+
+// returns the references of the elements of a sequence item including those of nested sequences, in element order
+func References(elements []*dicom.Element) []Reference {
+	references := make([]Reference, 0)
+	for _, e := range elements {
+		if IsSequence(e) {
+			for _, item := range SequenceItems(e) {
+				references = append(references, References(item)...)
+			}
+		} else if r, ok := ElementReference(e); ok {
+			references = append(references, r)
+		}
+	}
+	return references
+}
+
+// returns the references of a dataset to other objects, the top level UIDs identifying the dataset itself excluded
+func DatasetReferences(dataset *dicom.Dataset) []Reference {
+	references := make([]Reference, 0)
+	for _, e := range dataset.Elements {
+		if IsSequence(e) || e.Tag == tag.ReferencedSOPInstanceUID || e.Tag == tag.ReferencedFrameOfReferenceUID {
+			references = append(references, References([]*dicom.Element{e})...)
+		}
+	}
+	return references
+}
+
+// returns the first entry other than the referencing one identified by the UID of the reference, nil if none
+func ResolveReference(entries []Entry, r Reference, referencing *Entry) *Entry {
+	for i := range entries {
+		if &entries[i] != referencing && UIDValue(&entries[i].Dataset, r.Target) == r.UID {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// reference of an entry that doesn't resolve to any of the entries
+type DanglingReference struct {
+	Entry *Entry
+	Reference
+}
+
+// returns the references of all entries that resolve to no other entry in the order of the entries, with the total
+// number of references
+func DanglingReferences(entries []Entry) ([]DanglingReference, int) {
+	dangling := make([]DanglingReference, 0)
+	total := 0
+	for i := range entries {
+		for _, r := range DatasetReferences(&entries[i].Dataset) {
+			total++
+			if ResolveReference(entries, r, &entries[i]) == nil {
+				dangling = append(dangling, DanglingReference{Entry: &entries[i], Reference: r})
+			}
+		}
+	}
+	return dangling, total
+}
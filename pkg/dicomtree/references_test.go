@@ -0,0 +1,49 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	source := mustElement(t, tag.SourceImageSequence, [][]*dicom.Element{
+		{mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+		{mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.9"})},
+	})
+	entries := []Entry{
+		{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPInstanceUID, []string{"1.2.3"}),
+			mustElement(t, tag.FrameOfReferenceUID, []string{"1.5"}),
+		}}},
+		{Filename: "b.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.SOPInstanceUID, []string{"1.2.4"}),
+			mustElement(t, tag.FrameOfReferenceUID, []string{"1.5"}),
+			source,
+		}}},
+	}
+
+	r, ok := ElementReference(source)
+	assert.True(ok)
+	assert.Equal(Reference{Element: SequenceItems(source)[0][0], Target: tag.SOPInstanceUID, UID: "1.2.3"}, r)
+	assert.Equal("instance", r.Kind())
+	assert.Equal(&entries[0], ResolveReference(entries, r, &entries[1]))
+
+	r, ok = ElementReference(entries[1].Dataset.Elements[1])
+	assert.True(ok, "top level frame of reference refers to the others sharing it")
+	assert.Equal("frame of reference", r.Kind())
+	assert.Equal(&entries[0], ResolveReference(entries, r, &entries[1]))
+	assert.Nil(ResolveReference(entries[1:], r, &entries[1]), "not the referencing entry itself")
+	_, ok = ElementReference(entries[1].Dataset.Elements[0])
+	assert.False(ok)
+
+	assert.Len(DatasetReferences(&entries[1].Dataset), 2, "only the references within the sequence")
+	dangling, total := DanglingReferences(entries)
+	assert.Equal(2, total)
+	assert.Equal([]DanglingReference{{Entry: &entries[1], Reference: Reference{Element: SequenceItems(source)[1][0], Target: tag.SOPInstanceUID,
+		UID: "1.2.9"}}}, dangling)
+}
@@ -0,0 +1,94 @@
+package dicomtree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// item of a sequence element, referenced by the item nodes of the tree
+type SequenceItem struct {
+	Sequence *dicom.Element
+	Index    int
+}
+
+// returns the elements of the element's value
+func (item SequenceItem) Elements() []*dicom.Element {
+	items := SequenceItems(item.Sequence)
+	if item.Index < 0 || item.Index >= len(items) {
+		return nil
+	}
+	return items[item.Index]
+}
+
+func IsSequence(e *dicom.Element) bool {
+	return e.Value != nil && e.Value.ValueType() == dicom.Sequences
+}
+
+// returns the elements of each item of the sequence element, nil if the element is no sequence
+func SequenceItems(e *dicom.Element) [][]*dicom.Element {
+	if !IsSequence(e) {
+		return nil
+	}
+	values := e.Value.GetValue().([]*dicom.SequenceItemValue)
+	items := make([][]*dicom.Element, 0, len(values))
+	for _, item := range values {
+		items = append(items, item.GetValue().([]*dicom.Element))
+	}
+	return items
+}
+
+// returns the element with the tag among the elements of a sequence item, nil if there is none
+func FindItemElement(elements []*dicom.Element, t tag.Tag) *dicom.Element {
+	for _, e := range elements {
+		if e.Tag == t {
+			return e
+		}
+	}
+	return nil
+}
+
+// returns the value of the element with the tag among the elements of a sequence item without padding, "" if missing
+func ItemValue(elements []*dicom.Element, t tag.Tag) string {
+	if e := FindItemElement(elements, t); e != nil {
+		return strings.TrimRight(ValueText(e, nil), "\x00 ")
+	}
+	return ""
+}
+
+// adds a node per item below the node of the sequence element, under each item its elements. Items of code sequences
+// show their code, e.g. "Item 1: SCT 818981001 — Abdomen".
+func addSequenceItemNodes(node *Node, e *dicom.Element, charsets []string, opts DisplayOptions) {
+	for i, elements := range SequenceItems(e) {
+		text := fmt.Sprintf("\tItem %d", i+1)
+		if code, ok := ItemCode(elements); ok {
+			text += ": " + code.String()
+		}
+		itemNode := NewNode(text, nil)
+		itemNode.Item = &SequenceItem{e, i}
+		for _, itemElement := range elements {
+			text := fmt.Sprintf("\t%s (%s, %d): %s", opts.tagText(itemElement.Tag, false),
+				itemElement.RawValueRepresentation, itemElement.ValueLength, ValueString(itemElement, charsets, opts))
+			elementNode := NewNode(text, itemElement)
+			addPersonNameNodes(elementNode, itemElement, charsets)
+			addSequenceItemNodes(elementNode, itemElement, charsets, opts)
+			itemNode.AddChild(elementNode)
+		}
+		node.AddChild(itemNode)
+	}
+}
+
+// returns the values of the items like '{(0020,000e)=1.2.3, (0008,1150)=1.2.4}{...}', used by ValueText
+func sequenceText(e *dicom.Element, charsets []string) string {
+	var text strings.Builder
+	for _, elements := range SequenceItems(e) {
+		values := make([]string, 0, len(elements))
+		for _, itemElement := range elements {
+			values = append(values, FormatTag(itemElement.Tag)+"="+ValueText(itemElement, charsets))
+		}
+		text.WriteString("{" + strings.Join(values, ", ") + "}")
+	}
+	return text.String()
+}
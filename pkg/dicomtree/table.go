@@ -0,0 +1,63 @@
+package dicomtree
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// a row of a file table, the decoded values of the top level elements per column, "" if the file lacks the element
+type TableRow struct {
+	Filename string
+	Values   []string
+}
+
+// returns a row per entry with the values of the tags as columns, in the order of the entries
+func FileTable(entries []Entry, tags []tag.Tag) []TableRow {
+	rows := make([]TableRow, 0, len(entries))
+	for i := range entries {
+		dataset := &entries[i].Dataset
+		charsets := charset.FromDataset(dataset)
+		values := make([]string, len(tags))
+		for c, t := range tags {
+			if e, err := dataset.FindElementByTag(t); err == nil {
+				values[c] = ValueText(e, charsets)
+			}
+		}
+		rows = append(rows, TableRow{Filename: entries[i].Filename, Values: values})
+	}
+	return rows
+}
+
+// sorts the rows stably by the values of the column, numerically if both values are numbers, e.g. instance numbers.
+// Empty values come last in both directions.
+func SortTableRows(rows []TableRow, column int, descending bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].Values[column], rows[j].Values[column]
+		if a == "" || b == "" {
+			return a != "" && b == ""
+		}
+		if descending {
+			a, b = b, a
+		}
+		return compareValues(a, b) < 0
+	})
+}
+
+func compareValues(a, b string) int {
+	x, errX := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if errX == nil && errY == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,45 @@
+package dicomtree
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestFileTable(t *testing.T) {
+	assert := assert.New(t)
+
+	entry := func(filename, name string, number int) Entry {
+		elements := []*dicom.Element{mustElement(t, tag.InstanceNumber, []string{strconv.Itoa(number)})}
+		if name != "" {
+			elements = append(elements, mustElement(t, tag.PatientName, []string{name}))
+		}
+		return Entry{Filename: filename, Dataset: dicom.Dataset{Elements: elements}}
+	}
+	entries := []Entry{entry("a.dcm", "Doe^Jane", 10), entry("b.dcm", "", 9), entry("c.dcm", "Doe^John", 100)}
+	rows := FileTable(entries, []tag.Tag{tag.PatientName, tag.InstanceNumber})
+	assert.Equal([]TableRow{
+		{Filename: "a.dcm", Values: []string{"Doe^Jane", "10"}},
+		{Filename: "b.dcm", Values: []string{"", "9"}},
+		{Filename: "c.dcm", Values: []string{"Doe^John", "100"}},
+	}, rows)
+
+	filenames := func() []string {
+		var names []string
+		for _, row := range rows {
+			names = append(names, row.Filename)
+		}
+		return names
+	}
+	SortTableRows(rows, 1, false)
+	assert.Equal([]string{"b.dcm", "a.dcm", "c.dcm"}, filenames(), "numerically")
+	SortTableRows(rows, 1, true)
+	assert.Equal([]string{"c.dcm", "a.dcm", "b.dcm"}, filenames())
+	SortTableRows(rows, 0, true)
+	assert.Equal([]string{"c.dcm", "a.dcm", "b.dcm"}, filenames(), "empty values last")
+	SortTableRows(rows, 0, false)
+	assert.Equal([]string{"a.dcm", "c.dcm", "b.dcm"}, filenames())
+}
@@ -0,0 +1,169 @@
+package dicomtree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TagName(e *dicom.Element) string {
+	return TagNameByTag(e.Tag)
+}
+
+// returns the dictionary keyword of the tag, empty if unknown
+func TagNameByTag(t tag.Tag) string {
+	var tagName string
+	if tagInfo, err := tag.Find(t); err == nil {
+		tagName = tagInfo.Name
+	}
+	return tagName
+}
+
+// returns a sort key for the tag, ordering by group and element
+func TagOrder(t tag.Tag) uint32 {
+	return uint32(t.Group)<<16 | uint32(t.Element)
+}
+
+func FormatTag(t tag.Tag) string {
+	return fmt.Sprintf("(%04x,%04x)", t.Group, t.Element)
+}
+
+// parses a tag given as keyword (PatientName) or as group and element, e.g. "0010,0010", "(0010,0010)" or "00100010"
+func ParseTag(text string) (tag.Tag, error) {
+	text = strings.TrimSpace(text)
+	hex := strings.NewReplacer("(", "", ")", "", ",", "", " ", "").Replace(text)
+	if len(hex) == 8 {
+		if value, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return tag.Tag{Group: uint16(value >> 16), Element: uint16(value)}, nil
+		}
+	}
+	info, err := tag.FindByName(text)
+	if err != nil {
+		return tag.Tag{}, fmt.Errorf("unknown tag '%s'", text)
+	}
+	return info.Tag, nil
+}
+
+// returns all values of the element as strings, or nil if the value type has no string representation
+func ValueStrings(e *dicom.Element) []string {
+	if e.Value == nil {
+		return nil
+	}
+	switch e.Value.ValueType() {
+	case dicom.Strings:
+		return e.Value.GetValue().([]string)
+	case dicom.Ints:
+		ints := e.Value.GetValue().([]int)
+		values := make([]string, 0, len(ints))
+		for _, i := range ints {
+			values = append(values, fmt.Sprint(i))
+		}
+		return values
+	case dicom.Floats:
+		floats := e.Value.GetValue().([]float64)
+		values := make([]string, 0, len(floats))
+		for _, f := range floats {
+			values = append(values, fmt.Sprint(f))
+		}
+		return values
+	}
+	return nil
+}
+
+// value representations with numeric values, DS and IS as strings
+var numericVRs = map[string]bool{
+	"DS": true, "IS": true, "US": true, "SS": true, "UL": true, "SL": true, "UV": true, "SV": true, "FL": true, "FD": true,
+}
+
+// returns the values of elements with numeric value, nil for other elements or values that are no numbers
+func NumericValues(e *dicom.Element) []float64 {
+	if e.Value == nil {
+		return nil
+	}
+	switch e.Value.ValueType() {
+	case dicom.Ints, dicom.Floats:
+	case dicom.Strings:
+		if !numericVRs[e.RawValueRepresentation] {
+			return nil
+		}
+	default:
+		return nil
+	}
+	var values []float64
+	for _, v := range ValueStrings(e) {
+		v = strings.TrimSpace(strings.TrimRight(v, "\x00"))
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil
+		}
+		values = append(values, f)
+	}
+	return values
+}
+
+// returns the value of the element for display, decoded with the given character sets and truncated to the
+// maximum length of the options
+func ValueString(e *dicom.Element, charsets []string, opts DisplayOptions) string {
+	if IsSequence(e) {
+		return fmt.Sprintf("%d items", len(SequenceItems(e)))
+	}
+	value := e.Value.String()
+	if e.Value.ValueType() == dicom.Strings {
+		valueList := e.Value.GetValue().([]string)
+		if charset.IsText(e) || opts.PrettyValues {
+			formattedList := make([]string, 0, len(valueList))
+			for _, v := range valueList {
+				if charset.IsText(e) {
+					v = charset.Decode(v, charsets)
+				}
+				if opts.PrettyValues {
+					v = FormatPrettyValue(e.RawValueRepresentation, v)
+				}
+				formattedList = append(formattedList, v)
+			}
+			valueList = formattedList
+			value = fmt.Sprint(valueList)
+		}
+		if len(valueList) == 1 {
+			value = valueList[0]
+		}
+	}
+	maxLength := opts.MaxValueLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxValueLength
+	}
+	if maxLength > 0 && len(value) > maxLength {
+		value = value[:maxLength-4] + "...]"
+	}
+
+	return value
+}
+
+// returns the decoded values of the element joined by backslash like in the file, used for filtering and indexing
+func ValueText(e *dicom.Element, charsets []string) string {
+	values := ValueStrings(e)
+	if values == nil {
+		if e.Value == nil {
+			return ""
+		}
+		if IsSequence(e) {
+			return sequenceText(e, charsets)
+		}
+		return e.Value.String()
+	}
+	decoded := make([]string, 0, len(values))
+	for _, v := range values {
+		if charset.IsText(e) {
+			v = charset.Decode(v, charsets)
+		}
+		decoded = append(decoded, strings.TrimRight(v, "\x00 "))
+	}
+	return strings.Join(decoded, "\\")
+}
@@ -0,0 +1,58 @@
+package dicomtree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestParseTag(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, text := range []string{"0010,0010", "(0010,0010)", "00100010", " (0010, 0010) "} {
+		parsed, err := ParseTag(text)
+		assert.NoError(err, text)
+		assert.Equal(tag.Tag{Group: 0x0010, Element: 0x0010}, parsed, text)
+	}
+	parsed, err := ParseTag("7fe0,0010")
+	assert.NoError(err)
+	assert.Equal(tag.Tag{Group: 0x7fe0, Element: 0x0010}, parsed)
+
+	_, err = ParseTag("NoSuchKeyword")
+	assert.Error(err)
+}
+
+func TestValueStringTruncation(t *testing.T) {
+	assert := assert.New(t)
+
+	value := strings.Repeat("1.2.3.", 10)
+	e := mustElement(t, tag.StudyInstanceUID, []string{value})
+	assert.Equal(value[:46]+"...]", ValueString(e, nil, DisplayOptions{}), "default length")
+	assert.Equal(value[:16]+"...]", ValueString(e, nil, DisplayOptions{MaxValueLength: 20}))
+	assert.Equal(value, ValueString(e, nil, DisplayOptions{MaxValueLength: -1}))
+}
+
+func TestUIDShortName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("JPEG Baseline", UIDShortName("1.2.840.10008.1.2.4.50"))
+	assert.Equal("CT Image", UIDShortName("1.2.840.10008.5.1.4.1.1.2"))
+	assert.Equal("1.2.3", UIDShortName("1.2.3"), "unknown UID")
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.7\x00"})}}
+	assert.Equal("1.2.840.10008.5.1.4.1.1.7", UIDValue(&dataset, tag.SOPClassUID))
+	assert.Equal("", UIDValue(&dataset, tag.TransferSyntaxUID))
+}
+
+func TestEntrySummary(t *testing.T) {
+	assert := assert.New(t)
+
+	entry := newTestEntry(t, "a.dcm", "Doe^John", "RTSTRUCT")
+	assert.Equal("RTSTRUCT", entry.Summary())
+	entry.Dataset.Elements = append(entry.Dataset.Elements, mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.481.3"}))
+	assert.Equal("RT Structure Set Storage (RTSTRUCT)", entry.Summary())
+	assert.Equal("", (&Entry{Filename: "b.dcm", Partial: true}).Summary(), "not parsed yet")
+}
@@ -0,0 +1,91 @@
+package dicomtree
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// the acquisition times of the files of a device in a study
+type DeviceClock struct {
+	StudyUID string
+	Study    string // StudyDescription, the StudyInstanceUID without
+	Device   string // StationName, else manufacturer and model, with the DeviceSerialNumber if any
+	Files    int
+	First    time.Time
+	Last     time.Time
+	Skew     time.Duration // of First from the first acquisition of all devices of the study
+	Entry    *Entry        // the file acquired first
+}
+
+// returns the acquisition times of each device per study of the entries with one, see AcquisitionDateTime, in the order
+// of the studies and by first acquisition within a study. The skew of the devices of a study with a single device is 0.
+func ClockSkews(entries []Entry) []DeviceClock {
+	type key struct{ study, device string }
+	var clocks []DeviceClock
+	indexByKey := make(map[key]int)
+	studyOrder := make(map[string]int)
+	for i := range entries {
+		dataset := &entries[i].Dataset
+		acquired, ok := AcquisitionDateTime(dataset)
+		if !ok {
+			continue
+		}
+		uid := UIDValue(dataset, tag.StudyInstanceUID)
+		k := key{uid, deviceName(&entries[i])}
+		index, ok := indexByKey[k]
+		if !ok {
+			study := firstValue(dataset, tag.StudyDescription)
+			if study == "" {
+				study = uid
+			}
+			if _, ok := studyOrder[uid]; !ok {
+				studyOrder[uid] = len(studyOrder)
+			}
+			index = len(clocks)
+			indexByKey[k] = index
+			clocks = append(clocks, DeviceClock{StudyUID: uid, Study: study, Device: k.device, First: acquired, Last: acquired,
+				Entry: &entries[i]})
+		}
+		clock := &clocks[index]
+		clock.Files++
+		if acquired.Before(clock.First) {
+			clock.First, clock.Entry = acquired, &entries[i]
+		}
+		if acquired.After(clock.Last) {
+			clock.Last = acquired
+		}
+	}
+	firstOfStudy := make(map[string]time.Time)
+	for _, clock := range clocks {
+		if first, ok := firstOfStudy[clock.StudyUID]; !ok || clock.First.Before(first) {
+			firstOfStudy[clock.StudyUID] = clock.First
+		}
+	}
+	for i := range clocks {
+		clocks[i].Skew = clocks[i].First.Sub(firstOfStudy[clocks[i].StudyUID])
+	}
+	slices.SortStableFunc(clocks, func(a, b DeviceClock) int {
+		return cmp.Or(cmp.Compare(studyOrder[a.StudyUID], studyOrder[b.StudyUID]), a.First.Compare(b.First))
+	})
+	return clocks
+}
+
+// returns the station name of the file, else manufacturer and model, followed by the device serial number if any
+func deviceName(entry *Entry) string {
+	dataset := &entry.Dataset
+	name := strings.TrimSpace(firstValue(dataset, tag.StationName))
+	if name == "" {
+		name = strings.TrimSpace(firstValue(dataset, tag.Manufacturer) + " " + firstValue(dataset, tag.ManufacturerModelName))
+	}
+	if serial := strings.TrimSpace(firstValue(dataset, tag.DeviceSerialNumber)); serial != "" {
+		name = strings.TrimSpace(name + " #" + serial)
+	}
+	if name == "" {
+		return "unknown device"
+	}
+	return name
+}
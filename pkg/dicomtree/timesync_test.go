@@ -0,0 +1,39 @@
+package dicomtree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestClockSkews(t *testing.T) {
+	assert := assert.New(t)
+
+	file := func(filename, study, station, acquired string) Entry {
+		return Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.StudyInstanceUID, []string{study}),
+			mustElement(t, tag.StationName, []string{station}),
+			mustElement(t, tag.AcquisitionDateTime, []string{acquired}),
+		}}}
+	}
+	entries := []Entry{
+		file("pet1.dcm", "1.1", "PET1", "20240102103000"),
+		file("ct1.dcm", "1.1", "CT1", "20240102100500"),
+		file("ct2.dcm", "1.1", "CT1", "20240102100000"),
+		file("mr.dcm", "1.2", "MR1", "20240103080000"),
+		{Filename: "report.dcm"},
+	}
+	entries[3].Dataset.Elements = append(entries[3].Dataset.Elements, mustElement(t, tag.DeviceSerialNumber, []string{"42"}))
+
+	clocks := ClockSkews(entries)
+	assert.Len(clocks, 3)
+	assert.Equal(DeviceClock{StudyUID: "1.1", Study: "1.1", Device: "CT1", Files: 2, First: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+		Last: time.Date(2024, 1, 2, 10, 5, 0, 0, time.UTC), Entry: &entries[2]}, clocks[0])
+	assert.Equal("PET1", clocks[1].Device)
+	assert.Equal(30*time.Minute, clocks[1].Skew)
+	assert.Equal("MR1 #42", clocks[2].Device)
+	assert.Equal(time.Duration(0), clocks[2].Skew, "single device of its study")
+}
@@ -0,0 +1,343 @@
+package dicomtree
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// options that influence how the tag nodes are rendered
+type DisplayOptions struct {
+	PrettyValues   bool
+	ShowOffsets    bool
+	TagStyle       TagStyle
+	MaxValueLength int // longer values are truncated, 0 for DefaultMaxValueLength, negative for no truncation
+}
+
+const DefaultMaxValueLength = 50
+
+// how the tag of an element line is shown
+type TagStyle int
+
+const (
+	TagStyleNumeric     TagStyle = iota // "0010 PatientName", only the element number below the group node
+	TagStyleKeyword                     // "PatientName (0010,0010)"
+	TagStyleKeywordOnly                 // "PatientName", the number for unknown tags
+)
+
+func (s TagStyle) String() string {
+	switch s {
+	case TagStyleKeyword:
+		return "keyword and tag"
+	case TagStyleKeywordOnly:
+		return "keyword only"
+	}
+	return "tag and keyword"
+}
+
+// returns the tag part of an element line, the numeric style shows only the element number below a group node
+func (opts DisplayOptions) tagText(t tag.Tag, belowGroup bool) string {
+	keyword := TagNameByTag(t)
+	switch {
+	case opts.TagStyle == TagStyleKeyword && keyword != "":
+		return keyword + " " + FormatTag(t)
+	case opts.TagStyle == TagStyleKeywordOnly && keyword != "":
+		return keyword
+	case opts.TagStyle != TagStyleNumeric:
+		return FormatTag(t)
+	case belowGroup:
+		return fmt.Sprintf("%04x %s", t.Element, keyword)
+	}
+	return FormatTag(t) + " " + keyword
+}
+
+// node of the display tree, tag nodes reference the element they show, item nodes the sequence item
+type Node struct {
+	Text     string
+	Element  *dicom.Element // nil for root, file, group and item nodes
+	Item     *SequenceItem  // only set for item nodes
+	Level    HierarchyLevel // only set for patient, study and series nodes
+	Children []*Node
+}
+
+func NewNode(text string, element *dicom.Element) *Node {
+	return &Node{Text: text, Element: element}
+}
+
+func (n *Node) AddChild(child *Node) *Node {
+	n.Children = append(n.Children, child)
+	return n
+}
+
+// visits the node and all its descendants depth first, children are skipped if the callback returns false
+func (n *Node) Walk(callback func(node, parent *Node) bool) {
+	n.walk(nil, callback)
+}
+
+func (n *Node) walk(parent *Node, callback func(node, parent *Node) bool) {
+	if !callback(n, parent) {
+		return
+	}
+	for _, child := range n.Children {
+		child.walk(n, callback)
+	}
+}
+
+// builds a tree with a node per file, under each file the elements grouped by tag group. Files with a source are
+// placed below a node per source. For a single file the file node is the root.
+func BuildByFilename(rootText string, entries []Entry, opts DisplayOptions) *Node {
+	root := NewNode(rootText, nil)
+	sourceNodes := make(map[string]*Node)
+
+	for i := range entries {
+		entry := &entries[i]
+		fileNode := NewNode(entry.Filename, nil)
+		if len(entries) == 1 {
+			root = fileNode // only one file, so this name is root then
+		} else if entry.Source != "" {
+			sourceNode, ok := sourceNodes[entry.Source]
+			if !ok {
+				sourceNode = NewNode(entry.Source, nil)
+				root.AddChild(sourceNode)
+				sourceNodes[entry.Source] = sourceNode
+			}
+			sourceNode.AddChild(fileNode)
+		} else {
+			root.AddChild(fileNode)
+		}
+
+		charsets := charset.FromDataset(&entry.Dataset)
+		creators := PrivateCreators(entry.Dataset.Elements)
+		blockNodes := make(map[PrivateBlock]*Node)
+		var currentGroupNode *Node
+		var currentGroup uint16
+		for _, e := range entry.Dataset.Elements {
+			if currentGroupNode == nil || currentGroup != e.Tag.Group {
+				currentGroup = e.Tag.Group
+				currentGroupNode = NewNode(fmt.Sprintf("%04x", e.Tag.Group), nil)
+				fileNode.AddChild(currentGroupNode)
+			}
+
+			parent := currentGroupNode
+			if block, ok := PrivateBlockOf(e.Tag); ok {
+				// private elements below the node of their creator, so blocks of different vendors don't mix
+				if parent, ok = blockNodes[block]; !ok {
+					parent = NewNode(privateBlockText(block, creators[block]), nil)
+					currentGroupNode.AddChild(parent)
+					blockNodes[block] = parent
+				}
+			}
+			parent.AddChild(newElementNode(entry, e, charsets, opts, true))
+		}
+	}
+
+	return root
+}
+
+// returns the node of an element line with the nodes of its sequence items
+func newElementNode(entry *Entry, e *dicom.Element, charsets []string, opts DisplayOptions, belowGroup bool) *Node {
+	value := ValueString(e, charsets, opts)
+	offsetText := ""
+	if opts.ShowOffsets {
+		offsetText = entry.OffsetText(e)
+	}
+	elementText := fmt.Sprintf("\t%s (%s, %d)%s: %s%s%s", opts.tagText(e.Tag, belowGroup), e.RawValueRepresentation, e.ValueLength,
+		offsetText, value, entry.DateTimeText(e), entry.WarningText(e))
+	elementNode := NewNode(elementText, e)
+	addPersonNameNodes(elementNode, e, charsets)
+	addSequenceItemNodes(elementNode, e, charsets, opts)
+	return elementNode
+}
+
+// number of different values and value lengths per tag over a set of files
+type TagStats struct {
+	FileCount    int // number of files the stats are computed of, 0 if unknown
+	ValueCounts  map[tag.Tag]int
+	LengthCounts map[tag.Tag]int
+	FileCounts   map[tag.Tag]int        // number of files containing the tag
+	Ranges       map[tag.Tag]ValueRange // minimum and maximum of the numeric values, only for numeric VRs
+}
+
+type ValueRange struct {
+	Min, Max float64
+}
+
+func NewTagStats() TagStats {
+	return TagStats{
+		ValueCounts:  make(map[tag.Tag]int),
+		LengthCounts: make(map[tag.Tag]int),
+		FileCounts:   make(map[tag.Tag]int),
+		Ranges:       make(map[tag.Tag]ValueRange),
+	}
+}
+
+func ComputeTagStats(entries []Entry) TagStats {
+	valuesByTag := make(map[tag.Tag]map[string]bool)
+	valueLengthsByTag := make(map[tag.Tag]map[uint32]bool)
+	stats := NewTagStats()
+	stats.FileCount = len(entries)
+	for _, entry := range entries {
+		for _, e := range entry.Dataset.Elements {
+			_, ok := valuesByTag[e.Tag]
+			if !ok {
+				valuesByTag[e.Tag] = make(map[string]bool)
+			}
+			valuesByTag[e.Tag][e.Value.String()] = true
+
+			_, ok = valueLengthsByTag[e.Tag]
+			if !ok {
+				valueLengthsByTag[e.Tag] = make(map[uint32]bool)
+			}
+			valueLengthsByTag[e.Tag][e.ValueLength] = true
+
+			stats.FileCounts[e.Tag]++
+			for _, v := range NumericValues(e) {
+				r, ok := stats.Ranges[e.Tag]
+				if !ok {
+					r = ValueRange{v, v}
+				}
+				stats.Ranges[e.Tag] = ValueRange{min(r.Min, v), max(r.Max, v)}
+			}
+		}
+	}
+
+	for t, values := range valuesByTag {
+		stats.ValueCounts[t] = len(values)
+	}
+	for t, lengths := range valueLengthsByTag {
+		stats.LengthCounts[t] = len(lengths)
+	}
+	return stats
+}
+
+// returns the annotation of a tag node, e.g. ' [3 values, 1..5, missing in 2 files]'
+func (stats TagStats) Annotation(t tag.Tag) string {
+	text := fmt.Sprintf("%d values", stats.ValueCounts[t])
+	if stats.ValueCounts[t] == 1 {
+		text = "1 value"
+	}
+	if r, ok := stats.Ranges[t]; ok {
+		text += fmt.Sprintf(", %s..%s", strconv.FormatFloat(r.Min, 'g', -1, 64), strconv.FormatFloat(r.Max, 'g', -1, 64))
+	}
+	if missing := stats.FileCount - stats.FileCounts[t]; stats.FileCount > 0 && missing > 0 {
+		text += fmt.Sprintf(", missing in %d files", missing)
+	}
+	return " [" + text + "]"
+}
+
+// number of files with a value of a tag
+type ValueFrequency struct {
+	Value string
+	Count int
+}
+
+// returns the values of the tag over all entries, most frequent first, and the number of files missing the tag
+func ValueFrequencies(entries []Entry, t tag.Tag) ([]ValueFrequency, int) {
+	counts := make(map[string]int)
+	missing := 0
+	for i := range entries {
+		e, err := entries[i].Dataset.FindElementByTag(t)
+		if err != nil {
+			missing++
+			continue
+		}
+		counts[ValueText(e, charset.FromDataset(&entries[i].Dataset))]++
+	}
+	return sortedFrequencies(counts), missing
+}
+
+// returns the first value of the tag of each entry having it if all values are numeric, nil otherwise
+func NumericTagValues(entries []Entry, t tag.Tag) []float64 {
+	var values []float64
+	for i := range entries {
+		e, err := entries[i].Dataset.FindElementByTag(t)
+		if err != nil {
+			continue
+		}
+		numbers := NumericValues(e)
+		if len(numbers) == 0 {
+			return nil
+		}
+		values = append(values, numbers[0])
+	}
+	return values
+}
+
+// builds a tree with a node per tag group, under each group the tags and under each tag the values of all files.
+// Only tags with more than minDiffValuesPerTag different values are included.
+func BuildByTags(rootText string, entries []Entry, minDiffValuesPerTag int, opts DisplayOptions) *Node {
+	if len(entries) == 1 {
+		return BuildByFilename(rootText, entries, opts) // sorting by tag doesn't make sense for single file
+	}
+	return BuildByTagsWithStats(rootText, entries, minDiffValuesPerTag, ComputeTagStats(entries), opts)
+}
+
+// like BuildByTags, but with precomputed stats of the entries, e.g. from an index
+func BuildByTagsWithStats(rootText string, entries []Entry, minDiffValuesPerTag int, stats TagStats, opts DisplayOptions) *Node {
+	if len(entries) == 1 {
+		return BuildByFilename(rootText, entries, opts)
+	}
+
+	root := NewNode(rootText, nil)
+
+	// private blocks and their elements are told apart by creator, as files may reserve a block for different ones
+	type creatorBlock struct {
+		PrivateBlock
+		creator string
+	}
+	type creatorTag struct {
+		tag.Tag
+		creator string
+	}
+	groupNodesByGroupTag := make(map[uint16]*Node)
+	blockNodesByBlock := make(map[creatorBlock]*Node)
+	tagNodesByTag := make(map[creatorTag]*Node)
+	for i := range entries {
+		entry := &entries[i]
+		charsets := charset.FromDataset(&entry.Dataset)
+		creators := PrivateCreators(entry.Dataset.Elements)
+		for _, e := range entry.Dataset.Elements {
+			currentGroupNode, ok := groupNodesByGroupTag[e.Tag.Group]
+			if !ok {
+				currentGroupNode = NewNode(fmt.Sprintf("%04x/", e.Tag.Group), nil)
+				root.AddChild(currentGroupNode)
+				groupNodesByGroupTag[e.Tag.Group] = currentGroupNode
+			}
+
+			if stats.ValueCounts[e.Tag] > minDiffValuesPerTag {
+				parent, key := currentGroupNode, creatorTag{Tag: e.Tag}
+				if block, ok := PrivateBlockOf(e.Tag); ok {
+					key.creator = creators[block]
+					if parent, ok = blockNodesByBlock[creatorBlock{block, key.creator}]; !ok {
+						parent = NewNode(privateBlockText(block, key.creator)+"/", nil)
+						currentGroupNode.AddChild(parent)
+						blockNodesByBlock[creatorBlock{block, key.creator}] = parent
+					}
+				}
+				tagNode, ok := tagNodesByTag[key]
+				if !ok {
+					valueLengthText := ""
+					if stats.LengthCounts[e.Tag] == 1 {
+						valueLengthText = fmt.Sprintf(", %d", e.ValueLength)
+					}
+					elementText := fmt.Sprintf("\t%s (%s%s)/%s", opts.tagText(e.Tag, true), e.RawValueRepresentation, valueLengthText, stats.Annotation(e.Tag))
+					tagNode = NewNode(elementText, e)
+					parent.AddChild(tagNode)
+					tagNodesByTag[key] = tagNode
+				}
+
+				value := ValueString(e, charsets, opts)
+				offsetText := ""
+				if opts.ShowOffsets {
+					offsetText = entry.OffsetText(e)
+				}
+				elementText := fmt.Sprintf("\t %s%s (%d)%s\t - %s", value, entry.DateTimeText(e), e.ValueLength, offsetText, entry.Filename)
+				tagNode.AddChild(NewNode(elementText, e))
+			}
+		}
+	}
+	return root
+}
@@ -0,0 +1,289 @@
+package dicomtree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newTestEntry(t *testing.T, filename string, patientName string, modality string) Entry {
+	return Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{modality}),
+		mustElement(t, tag.PatientName, []string{patientName}),
+	}}}
+}
+
+func TestBuildByFilename(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT"), newTestEntry(t, "b.dcm", "Doe^Jane", "CT")}
+	root := BuildByFilename("dir", entries, DisplayOptions{})
+	assert.Equal("dir", root.Text)
+	assert.Len(root.Children, 2)
+	fileNode := root.Children[1]
+	assert.Equal("b.dcm", fileNode.Text)
+	assert.Nil(fileNode.Element)
+	assert.Len(fileNode.Children, 2, "one node per group")
+	assert.Equal("0010", fileNode.Children[1].Text)
+	assert.Same(entries[1].Dataset.Elements[1], fileNode.Children[1].Children[0].Element)
+	assert.Contains(fileNode.Children[1].Children[0].Text, "Doe^Jane")
+
+	root = BuildByFilename("dir", entries[:1], DisplayOptions{})
+	assert.Equal("a.dcm", root.Text, "single file is root")
+}
+
+func TestBuildByFilenameWithSources(t *testing.T) {
+	assert := assert.New(t)
+
+	dirEntries := []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT"), newTestEntry(t, "b.dcm", "Doe^Jane", "CT")}
+	SetSource(dirEntries, "dirB")
+	assert.Equal("dirB", dirEntries[0].Source)
+	assert.Equal("dirB/a.dcm", dirEntries[0].Filename)
+
+	entries := append([]Entry{newTestEntry(t, "fileA.dcm", "Doe^John", "MR")}, dirEntries...)
+	entries = append(entries, newTestEntry(t, "fileC.dcm", "Doe^Jim", "MR"))
+	root := BuildByFilename("3 inputs", entries, DisplayOptions{})
+	assert.Len(root.Children, 3)
+	assert.Equal("fileA.dcm", root.Children[0].Text)
+	assert.Equal("dirB", root.Children[1].Text)
+	assert.Nil(root.Children[1].Element)
+	assert.Len(root.Children[1].Children, 2)
+	assert.Equal("dirB/b.dcm", root.Children[1].Children[1].Text, "file nodes keep the unique filename")
+	assert.Len(root.Children[1].Children[1].Children, 2, "one node per group")
+	assert.Equal("fileC.dcm", root.Children[2].Text)
+}
+
+func TestBuildByHierarchy(t *testing.T) {
+	assert := assert.New(t)
+
+	instance := func(filename, patientID, study, series, seriesNumber string) Entry {
+		return Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.StudyDate, []string{"20240101"}),
+			mustElement(t, tag.Modality, []string{"CT"}),
+			mustElement(t, tag.PatientName, []string{"Doe^John"}),
+			mustElement(t, tag.PatientID, []string{patientID}),
+			mustElement(t, tag.StudyInstanceUID, []string{study}),
+			mustElement(t, tag.SeriesInstanceUID, []string{series}),
+			mustElement(t, tag.SeriesNumber, []string{seriesNumber}),
+		}}}
+	}
+	entries := []Entry{
+		instance("a.dcm", "42", "1.2", "1.2.1", "1"),
+		instance("b.dcm", "42", "1.2", "1.2.2", "2"),
+		instance("c.dcm", "42", "1.2", "1.2.1", "1"),
+		instance("d.dcm", "43", "1.3", "1.3.1", "1"),
+		newTestEntry(t, "e.dcm", "", "OT"),
+	}
+	root := BuildByHierarchy("dir", entries, DisplayOptions{})
+	assert.Equal("dir", root.Text)
+	assert.Len(root.Children, 3, "patients")
+	patient := root.Children[0]
+	assert.Equal("Patient Doe^John [42]", patient.Text)
+	assert.Equal(HierarchyPatient, patient.Level)
+	assert.Len(patient.Children, 1)
+	study := patient.Children[0]
+	assert.Equal("Study 20240101", study.Text)
+	assert.Equal(HierarchyStudy, study.Level)
+	assert.Len(study.Children, 2)
+	series := study.Children[0]
+	assert.Equal("Series 1 CT (2 files)", series.Text)
+	assert.Equal(HierarchySeries, series.Level)
+	assert.Equal("a.dcm", series.Children[0].Text)
+	assert.Equal("c.dcm", series.Children[1].Text)
+	assert.Equal(HierarchyNone, series.Children[0].Level)
+	assert.Len(series.Children[0].Children, 3, "groups of the file")
+
+	assert.Equal("Patient Doe^John [43]", root.Children[1].Text)
+	other := root.Children[2]
+	assert.Equal("Patient (no patient)", other.Text)
+	assert.Equal("Study (no study)", other.Children[0].Text)
+	assert.Equal("Series OT (1 file)", other.Children[0].Children[0].Text)
+}
+
+func TestTagStyles(t *testing.T) {
+	assert := assert.New(t)
+
+	private := tag.Tag{Group: 0x0009, Element: 0x1001}
+	numeric := DisplayOptions{}
+	assert.Equal("0010 "+TagNameByTag(tag.PatientName), numeric.tagText(tag.PatientName, true))
+	assert.Equal("(0010,0010) "+TagNameByTag(tag.PatientName), numeric.tagText(tag.PatientName, false))
+	keyword := DisplayOptions{TagStyle: TagStyleKeyword}
+	assert.True(strings.HasSuffix(keyword.tagText(tag.PatientName, true), "(0010,0010)"))
+	assert.Equal("(0009,1001)", keyword.tagText(private, true), "unknown tags show only the number")
+	keywordOnly := DisplayOptions{TagStyle: TagStyleKeywordOnly}
+	assert.True(strings.HasPrefix(keyword.tagText(tag.PatientName, true), keywordOnly.tagText(tag.PatientName, true)))
+	assert.Equal("(0009,1001)", keywordOnly.tagText(private, false))
+
+	root := BuildByFilename("dir", []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT")}, keyword)
+	assert.True(strings.HasPrefix(root.Children[1].Children[0].Text, "\t"+keyword.tagText(tag.PatientName, true)+" ("))
+}
+
+func TestBuildByFilenameWithSequence(t *testing.T) {
+	assert := assert.New(t)
+
+	sequence := mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{
+		{mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"})},
+		{mustElement(t, tag.SeriesInstanceUID, []string{"1.2.4"})},
+	})
+	entries := []Entry{{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{sequence}}}}
+	root := BuildByFilename("dir", entries, DisplayOptions{})
+	sequenceNode := root.Children[0].Children[0]
+	assert.Same(sequence, sequenceNode.Element)
+	assert.Contains(sequenceNode.Text, ": 2 items")
+	assert.Len(sequenceNode.Children, 2)
+	itemNode := sequenceNode.Children[1]
+	assert.Equal("\tItem 2", itemNode.Text)
+	assert.Nil(itemNode.Element)
+	assert.Equal(&SequenceItem{sequence, 1}, itemNode.Item)
+	assert.Contains(itemNode.Children[0].Text, "(0020,000e)")
+	assert.Contains(itemNode.Children[0].Text, ": 1.2.4")
+	assert.Same(itemNode.Item.Elements()[0], itemNode.Children[0].Element)
+	assert.Equal("{(0020,000e)=1.2.3}{(0020,000e)=1.2.4}", ValueText(sequence, nil))
+}
+
+func TestBuildByTags(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT"), newTestEntry(t, "b.dcm", "Doe^Jane", "CT")}
+	root := BuildByTags("dir", entries, 0, DisplayOptions{})
+	assert.Len(root.Children, 2)
+	assert.Equal("0008/", root.Children[0].Text)
+	assert.Len(root.Children[0].Children, 1)
+	assert.Len(root.Children[0].Children[0].Children, 2, "one value node per file")
+
+	root = BuildByTags("dir", entries, 1, DisplayOptions{})
+	assert.Len(root.Children[0].Children, 0, "modality is the same in all files")
+	assert.Len(root.Children[1].Children, 1, "patient name differs")
+
+	assert.Contains(root.Children[1].Children[0].Text, "/ [2 values]")
+
+	elements := 0
+	root.Walk(func(node, parent *Node) bool {
+		if node.Element != nil {
+			elements++
+		}
+		return true
+	})
+	assert.Equal(3, elements)
+}
+
+func TestBuildWithPrivateBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	private := func(element uint16, vr string, value string) *dicom.Element {
+		v, _ := dicom.NewValue([]string{value})
+		return &dicom.Element{Tag: tag.Tag{Group: 0x0029, Element: element}, RawValueRepresentation: vr, Value: v}
+	}
+	entries := []Entry{
+		{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			private(0x0010, "LO", "SIEMENS CSA HEADER"), private(0x0011, "LO", "GEMS_IMAG_01"),
+			private(0x1008, "CS", "IMAGE NUM 4"), private(0x1108, "SL", "7"), private(0x2001, "LO", "orphan"),
+		}}},
+		{Filename: "b.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			private(0x0010, "LO", "GEMS_IMAG_01"), private(0x1008, "SL", "8"),
+		}}},
+	}
+	root := BuildByFilename("dir", entries, DisplayOptions{})
+	groupNode := root.Children[0].Children[0]
+	assert.Len(groupNode.Children, 5, "the creators and a node per block")
+	assert.Equal("10xx SIEMENS CSA HEADER", groupNode.Children[2].Text)
+	assert.Nil(groupNode.Children[2].Element)
+	assert.Same(entries[0].Dataset.Elements[2], groupNode.Children[2].Children[0].Element)
+	assert.Equal("11xx GEMS_IMAG_01", groupNode.Children[3].Text)
+	assert.Equal("20xx <no private creator>", groupNode.Children[4].Text)
+
+	root = BuildByTags("dir", entries, 0, DisplayOptions{})
+	groupNode = root.Children[0]
+	texts := make([]string, 0, len(groupNode.Children))
+	for _, child := range groupNode.Children {
+		texts = append(texts, child.Text)
+	}
+	assert.Contains(texts, "10xx SIEMENS CSA HEADER/")
+	assert.Contains(texts, "10xx GEMS_IMAG_01/", "the same block of another creator is a separate node")
+	assert.Contains(texts, "11xx GEMS_IMAG_01/")
+}
+
+func TestComputeTagStats(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT"), newTestEntry(t, "b.dcm", "Doe^Jane", "CT"), newTestEntry(t, "c.dcm", "Doe^Jane", "CT")}
+	entries[0].Dataset.Elements = append(entries[0].Dataset.Elements, mustElement(t, tag.Rows, []int{512}))
+	entries[1].Dataset.Elements = append(entries[1].Dataset.Elements, mustElement(t, tag.Rows, []int{256}))
+	stats := ComputeTagStats(entries)
+	assert.Equal(3, stats.FileCount)
+	assert.Equal(2, stats.ValueCounts[tag.PatientName])
+	assert.Equal(2, stats.FileCounts[tag.Rows])
+	assert.Equal(ValueRange{256, 512}, stats.Ranges[tag.Rows])
+	assert.NotContains(stats.Ranges, tag.PatientName)
+	assert.Equal(" [2 values, 256..512, missing in 1 files]", stats.Annotation(tag.Rows))
+	assert.Equal(" [1 value]", stats.Annotation(tag.Modality))
+
+	frequencies, missing := ValueFrequencies(entries, tag.PatientName)
+	assert.Equal([]ValueFrequency{{"Doe^Jane", 2}, {"Doe^John", 1}}, frequencies)
+	assert.Equal(0, missing)
+	_, missing = ValueFrequencies(entries, tag.Rows)
+	assert.Equal(1, missing)
+
+	assert.Equal([]float64{512, 256}, NumericTagValues(entries, tag.Rows))
+	assert.Nil(NumericTagValues(entries, tag.PatientName))
+}
+
+func TestFindEntryByElement(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []Entry{newTestEntry(t, "a.dcm", "Doe^John", "CT"), newTestEntry(t, "b.dcm", "Doe^Jane", "CT")}
+	assert.Same(&entries[1], FindEntryByElement(entries, entries[1].Dataset.Elements[0]))
+	assert.Nil(FindEntryByElement(entries, mustElement(t, tag.Modality, []string{"CT"})))
+	assert.Same(&entries[0], FindEntryByFilename(entries, "a.dcm"))
+}
+
+func TestBuildByModule(t *testing.T) {
+	assert := assert.New(t)
+
+	entry := Entry{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientName, []string{"Doe^John"}),
+		mustElement(t, tag.PatientID, []string{"42"}),
+		mustElement(t, tag.ViewPosition, []string{"AP"}),
+		mustElement(t, tag.RescaleSlope, []string{"1"}),
+	}}}
+	root := BuildByModule("dir", []Entry{entry}, DisplayOptions{})
+	assert.Equal("a.dcm", root.Text, "single file is root")
+	var texts []string
+	for _, child := range root.Children {
+		texts = append(texts, child.Text)
+	}
+	assert.Equal([]string{"File Meta Information (1)", "Patient (2)", "General Series (1)", "CT Image (1)", "SOP Common (1)", "Other (1)"}, texts,
+		"modules in the order of the CT image IOD, view position is no attribute of it")
+	assert.Same(entry.Dataset.Elements[3], root.Children[1].Children[0].Element)
+	assert.Contains(root.Children[1].Children[0].Text, "(0010,0010)")
+
+	other := newTestEntry(t, "b.dcm", "Doe^Jane", "OT")
+	other.Dataset.Elements = append(other.Dataset.Elements, mustElement(t, tag.RescaleSlope, []string{"1"}))
+	root = BuildByModule("dir", []Entry{entry, other}, DisplayOptions{})
+	assert.Len(root.Children, 2)
+	assert.Equal("b.dcm", root.Children[1].Text)
+	assert.Equal("CT Image (1)", root.Children[1].Children[2].Text, "unknown SOP class uses all modules in order")
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
+
+// returns a new element with the VR, for tags the dictionary doesn't know
+func vrElement(t *testing.T, tg tag.Tag, vr string, data interface{}) *dicom.Element {
+	t.Helper()
+	value, err := dicom.NewValue(data)
+	require.NoError(t, err)
+	return &dicom.Element{Tag: tg, ValueRepresentation: tag.GetVRKind(tg, vr), RawValueRepresentation: vr, Value: value}
+}
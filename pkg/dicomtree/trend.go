@@ -0,0 +1,68 @@
+package dicomtree
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// the numeric value of a tag in a file with the InstanceNumber of the file
+type TrendPoint struct {
+	Instance int
+	Value    float64
+	Filename string
+}
+
+// returns the first numeric value of the tag of each entry against its InstanceNumber, sorted by instance number and
+// filename, and the number of entries lacking either
+func Trend(entries []Entry, t tag.Tag) ([]TrendPoint, int) {
+	var points []TrendPoint
+	skipped := 0
+	for i := range entries {
+		instance, err := strconv.Atoi(strings.TrimSpace(firstValue(&entries[i].Dataset, tag.InstanceNumber)))
+		var values []float64
+		if e, findErr := entries[i].Dataset.FindElementByTag(t); findErr == nil {
+			values = NumericValues(e)
+		}
+		if err != nil || len(values) == 0 {
+			skipped++
+			continue
+		}
+		points = append(points, TrendPoint{instance, values[0], entries[i].Filename})
+	}
+	slices.SortStableFunc(points, func(p, q TrendPoint) int {
+		return cmp.Or(cmp.Compare(p.Instance, q.Instance), cmp.Compare(p.Filename, q.Filename))
+	})
+	return points, skipped
+}
+
+// returns the instance numbers missing between the lowest and the highest one of the sorted points
+func MissingInstances(points []TrendPoint) []int {
+	var missing []int
+	for i := 1; i < len(points); i++ {
+		for instance := points[i-1].Instance + 1; instance < points[i].Instance; instance++ {
+			missing = append(missing, instance)
+		}
+	}
+	return missing
+}
+
+// returns the sorted points whose value doesn't move on from the previous one in the direction from the first to the
+// last value, e.g. a slice out of order or repeated. If the first and the last value are equal, every change is returned.
+func NonMonotonic(points []TrendPoint) []TrendPoint {
+	if len(points) < 2 {
+		return nil
+	}
+	direction := cmp.Compare(points[len(points)-1].Value, points[0].Value)
+	var breaks []TrendPoint
+	for i := 1; i < len(points); i++ {
+		step := cmp.Compare(points[i].Value, points[i-1].Value)
+		if (direction != 0 && step != direction) || (direction == 0 && step != 0) {
+			breaks = append(breaks, points[i])
+		}
+	}
+	return breaks
+}
@@ -0,0 +1,32 @@
+package dicomtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestTrend(t *testing.T) {
+	assert := assert.New(t)
+
+	slice := func(filename string, instance string, location float64) Entry {
+		return Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.InstanceNumber, []string{instance}),
+			mustElement(t, tag.SliceLocation, []float64{location}),
+		}}}
+	}
+	entries := []Entry{slice("c.dcm", "4", 7.5), slice("a.dcm", " 1", 0), slice("b.dcm", "2", 2.5), slice("d.dcm", "6", 5),
+		slice("e.dcm", "7", 15), newTestEntry(t, "f.dcm", "Doe^John", "CT")}
+
+	points, skipped := Trend(entries, tag.SliceLocation)
+	assert.Equal(1, skipped, "f.dcm lacks both")
+	assert.Equal([]TrendPoint{{1, 0, "a.dcm"}, {2, 2.5, "b.dcm"}, {4, 7.5, "c.dcm"}, {6, 5, "d.dcm"}, {7, 15, "e.dcm"}}, points)
+	assert.Equal([]int{3, 5}, MissingInstances(points))
+	assert.Equal([]TrendPoint{{6, 5, "d.dcm"}}, NonMonotonic(points))
+
+	constant := []TrendPoint{{1, 2, "a.dcm"}, {2, 3, "b.dcm"}, {3, 2, "c.dcm"}}
+	assert.Equal(constant[1:], NonMonotonic(constant), "every change of equal first and last values")
+	assert.Nil(NonMonotonic(points[:1]))
+}
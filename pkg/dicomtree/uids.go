@@ -0,0 +1,144 @@
+package dicomtree
+
+import (
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// short names of common transfer syntaxes and SOP classes, e.g. for annotating file nodes
+var uidShortNames = map[string]string{
+	"1.2.840.10008.1.2":       "Implicit VR LE",
+	"1.2.840.10008.1.2.1":     "Explicit VR LE",
+	"1.2.840.10008.1.2.1.99":  "Deflated LE",
+	"1.2.840.10008.1.2.2":     "Explicit VR BE",
+	"1.2.840.10008.1.2.4.50":  "JPEG Baseline",
+	"1.2.840.10008.1.2.4.51":  "JPEG Extended",
+	"1.2.840.10008.1.2.4.57":  "JPEG Lossless",
+	"1.2.840.10008.1.2.4.70":  "JPEG Lossless SV1",
+	"1.2.840.10008.1.2.4.80":  "JPEG-LS Lossless",
+	"1.2.840.10008.1.2.4.81":  "JPEG-LS Near-LL",
+	"1.2.840.10008.1.2.4.90":  "JPEG 2000 Lossless",
+	"1.2.840.10008.1.2.4.91":  "JPEG 2000",
+	"1.2.840.10008.1.2.4.100": "MPEG-2",
+	"1.2.840.10008.1.2.4.102": "MPEG-4 AVC",
+	"1.2.840.10008.1.2.4.201": "HTJ2K Lossless",
+	"1.2.840.10008.1.2.4.203": "HTJ2K",
+	"1.2.840.10008.1.2.5":     "RLE Lossless",
+
+	"1.2.840.10008.1.1":           "Verification",
+	"1.2.840.10008.5.1.4.1.2.1.1": "Patient Root FIND",
+	"1.2.840.10008.5.1.4.1.2.1.2": "Patient Root MOVE",
+	"1.2.840.10008.5.1.4.1.2.2.1": "Study Root FIND",
+	"1.2.840.10008.5.1.4.1.2.2.2": "Study Root MOVE",
+	"1.2.840.10008.5.1.4.31":      "Modality Worklist FIND",
+
+	"1.2.840.10008.5.1.4.1.1.1":      "CR Image",
+	"1.2.840.10008.5.1.4.1.1.1.1":    "DX Image",
+	"1.2.840.10008.5.1.4.1.1.1.2":    "MG Image",
+	"1.2.840.10008.5.1.4.1.1.2":      "CT Image",
+	"1.2.840.10008.5.1.4.1.1.2.1":    "Enhanced CT Image",
+	"1.2.840.10008.5.1.4.1.1.3.1":    "US Multi-frame Image",
+	"1.2.840.10008.5.1.4.1.1.4":      "MR Image",
+	"1.2.840.10008.5.1.4.1.1.4.1":    "Enhanced MR Image",
+	"1.2.840.10008.5.1.4.1.1.6.1":    "US Image",
+	"1.2.840.10008.5.1.4.1.1.7":      "Secondary Capture",
+	"1.2.840.10008.5.1.4.1.1.7.1":    "MF Single Bit SC",
+	"1.2.840.10008.5.1.4.1.1.7.2":    "MF Grayscale Byte SC",
+	"1.2.840.10008.5.1.4.1.1.7.3":    "MF Grayscale Word SC",
+	"1.2.840.10008.5.1.4.1.1.7.4":    "MF True Color SC",
+	"1.2.840.10008.5.1.4.1.1.9.1.1":  "12-lead ECG",
+	"1.2.840.10008.5.1.4.1.1.11.1":   "Grayscale PR",
+	"1.2.840.10008.5.1.4.1.1.12.1":   "XA Image",
+	"1.2.840.10008.5.1.4.1.1.20":     "NM Image",
+	"1.2.840.10008.5.1.4.1.1.66":     "Raw Data",
+	"1.2.840.10008.5.1.4.1.1.66.4":   "Segmentation",
+	"1.2.840.10008.5.1.4.1.1.77.1.6": "WSI",
+	"1.2.840.10008.5.1.4.1.1.88.11":  "Basic Text SR",
+	"1.2.840.10008.5.1.4.1.1.88.22":  "Enhanced SR",
+	"1.2.840.10008.5.1.4.1.1.88.33":  "Comprehensive SR",
+	"1.2.840.10008.5.1.4.1.1.88.67":  "X-Ray Dose SR",
+	"1.2.840.10008.5.1.4.1.1.104.1":  "Encapsulated PDF",
+	"1.2.840.10008.5.1.4.1.1.128":    "PET Image",
+	"1.2.840.10008.5.1.4.1.1.481.1":  "RT Image",
+	"1.2.840.10008.5.1.4.1.1.481.2":  "RT Dose",
+	"1.2.840.10008.5.1.4.1.1.481.3":  "RT Structure Set",
+	"1.2.840.10008.5.1.4.1.1.481.5":  "RT Plan",
+}
+
+// names of the storage SOP classes as in the standard, e.g. for summarizing file nodes
+var sopClassNames = map[string]string{
+	"1.2.840.10008.5.1.4.1.1.1":      "Computed Radiography Image Storage",
+	"1.2.840.10008.5.1.4.1.1.1.1":    "Digital X-Ray Image Storage",
+	"1.2.840.10008.5.1.4.1.1.1.2":    "Digital Mammography X-Ray Image Storage",
+	"1.2.840.10008.5.1.4.1.1.2":      "CT Image Storage",
+	"1.2.840.10008.5.1.4.1.1.2.1":    "Enhanced CT Image Storage",
+	"1.2.840.10008.5.1.4.1.1.3.1":    "Ultrasound Multi-frame Image Storage",
+	"1.2.840.10008.5.1.4.1.1.4":      "MR Image Storage",
+	"1.2.840.10008.5.1.4.1.1.4.1":    "Enhanced MR Image Storage",
+	"1.2.840.10008.5.1.4.1.1.6.1":    "Ultrasound Image Storage",
+	"1.2.840.10008.5.1.4.1.1.7":      "Secondary Capture Image Storage",
+	"1.2.840.10008.5.1.4.1.1.7.1":    "Multi-frame Single Bit Secondary Capture Image Storage",
+	"1.2.840.10008.5.1.4.1.1.7.2":    "Multi-frame Grayscale Byte Secondary Capture Image Storage",
+	"1.2.840.10008.5.1.4.1.1.7.3":    "Multi-frame Grayscale Word Secondary Capture Image Storage",
+	"1.2.840.10008.5.1.4.1.1.7.4":    "Multi-frame True Color Secondary Capture Image Storage",
+	"1.2.840.10008.5.1.4.1.1.9.1.1":  "12-lead ECG Waveform Storage",
+	"1.2.840.10008.5.1.4.1.1.11.1":   "Grayscale Softcopy Presentation State Storage",
+	"1.2.840.10008.5.1.4.1.1.12.1":   "X-Ray Angiographic Image Storage",
+	"1.2.840.10008.5.1.4.1.1.20":     "Nuclear Medicine Image Storage",
+	"1.2.840.10008.5.1.4.1.1.66":     "Raw Data Storage",
+	"1.2.840.10008.5.1.4.1.1.66.4":   "Segmentation Storage",
+	"1.2.840.10008.5.1.4.1.1.77.1.6": "VL Whole Slide Microscopy Image Storage",
+	"1.2.840.10008.5.1.4.1.1.88.11":  "Basic Text SR Storage",
+	"1.2.840.10008.5.1.4.1.1.88.22":  "Enhanced SR Storage",
+	"1.2.840.10008.5.1.4.1.1.88.33":  "Comprehensive SR Storage",
+	"1.2.840.10008.5.1.4.1.1.88.67":  "X-Ray Radiation Dose SR Storage",
+	"1.2.840.10008.5.1.4.1.1.104.1":  "Encapsulated PDF Storage",
+	"1.2.840.10008.5.1.4.1.1.128":    "Positron Emission Tomography Image Storage",
+	"1.2.840.10008.5.1.4.1.1.481.1":  "RT Image Storage",
+	"1.2.840.10008.5.1.4.1.1.481.2":  "RT Dose Storage",
+	"1.2.840.10008.5.1.4.1.1.481.3":  "RT Structure Set Storage",
+	"1.2.840.10008.5.1.4.1.1.481.5":  "RT Plan Storage",
+}
+
+// returns the name of a storage SOP class UID, e.g. "CT Image Storage", the UID if unknown
+func SOPClassName(uid string) string {
+	if name, ok := sopClassNames[uid]; ok {
+		return name
+	}
+	return uid
+}
+
+// returns what the object of the entry is, the SOP class name and modality like "CT Image Storage (CT)",
+// "" if the dataset contains neither, e.g. if not parsed yet
+func (entry *Entry) Summary() string {
+	sopClass, modality := UIDValue(&entry.Dataset, tag.SOPClassUID), ""
+	if e, err := entry.Dataset.FindElementByTag(tag.Modality); err == nil {
+		modality = strings.TrimSpace(ValueText(e, nil))
+	}
+	switch {
+	case sopClass == "":
+		return modality
+	case modality == "":
+		return SOPClassName(sopClass)
+	}
+	return SOPClassName(sopClass) + " (" + modality + ")"
+}
+
+// returns a short name of a transfer syntax or SOP class UID, e.g. "JPEG Baseline" or "CT Image", the UID if unknown
+func UIDShortName(uid string) string {
+	if name, ok := uidShortNames[uid]; ok {
+		return name
+	}
+	return uid
+}
+
+// returns the UID of the top level element without padding, "" if the dataset doesn't contain it
+func UIDValue(dataset *dicom.Dataset, t tag.Tag) string {
+	e, err := dataset.FindElementByTag(t)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(ValueText(e, nil), "\x00 ")
+}
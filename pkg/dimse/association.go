@@ -0,0 +1,363 @@
+// Package dimse is a minimal DICOM network client: it opens associations with remote application entities and runs
+// the DIMSE-C services on them, e.g. C-ECHO to test a node, C-FIND to query a modality worklist or C-GET to retrieve
+// an instance. Identifiers are exchanged in implicit VR little endian, the transfer syntax every peer accepts.
+package dimse
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/suyashkumar/dicom"
+)
+
+// time a peer may take to answer before the association is aborted
+var Timeout = 30 * time.Second
+
+// returned by receive if the peer releases the association instead of sending a message
+var errReleaseRequested = errors.New("association released by the peer")
+
+// an established association with a node, for the abstract syntaxes it accepted
+type Association struct {
+	conn      net.Conn
+	node      Node
+	contexts  map[string]byte // accepted abstract syntax to presentation context ID
+	syntaxes  map[byte]string // presentation context ID to accepted transfer syntax
+	maxLength uint32          // of the PDUs sent, as accepted by the peer
+	messageID uint16
+}
+
+// opens an association with the node proposing the abstract syntaxes, fails if the node accepts none of them
+func Associate(node Node, abstractSyntaxes ...string) (*Association, error) {
+	contexts := make([]presentationContext, 0, len(abstractSyntaxes))
+	for i, syntax := range abstractSyntaxes {
+		contexts = append(contexts, presentationContext{ID: byte(2*i + 1), AbstractSyntax: syntax, TransferSyntaxes: []string{implicitVRLittle}})
+	}
+	return associate(node, contexts, nil)
+}
+
+// opens an association proposing the presentation contexts and the SCP role for the abstract syntaxes of scpRoles
+func associate(node Node, contexts []presentationContext, scpRoles []string) (*Association, error) {
+	a := &Association{node: node, contexts: make(map[string]byte), syntaxes: make(map[byte]string)}
+	a.trace(Local, "connecting")
+	conn, err := node.dial()
+	if err != nil {
+		a.trace(Local, "connection failed: %s", err)
+		return nil, err
+	}
+	a.conn = conn
+	if err := a.negotiate(contexts, scpRoles); err != nil {
+		a.trace(Local, "association failed: %s", err)
+		conn.Close()
+		return nil, fmt.Errorf("association with %s failed: %w", node, err)
+	}
+	return a, nil
+}
+
+func (a *Association) negotiate(contexts []presentationContext, scpRoles []string) error {
+	rq := associatePDU{
+		CalledAE:           a.node.AET,
+		CallingAE:          a.node.callingAET(),
+		Contexts:           contexts,
+		MaxLength:          defaultMaxPDULength,
+		ImplementationUID:  implementationUID,
+		ImplementationName: implementationName,
+		SCPRoles:           scpRoles,
+	}
+	proposed := make(map[byte]string)
+	for _, pc := range contexts {
+		proposed[pc.ID] = pc.AbstractSyntax
+	}
+	a.conn.SetDeadline(time.Now().Add(Timeout))
+	a.traceAssociate(Sent, "A-ASSOCIATE-RQ", rq, nil)
+	if err := writePDU(a.conn, pduAssociateRQ, rq.encode(false)); err != nil {
+		return err
+	}
+	pduType, payload, err := readPDU(a.conn)
+	if err != nil {
+		return err
+	}
+	switch pduType {
+	case pduAssociateAC:
+	case pduAssociateRJ:
+		err := rejectError(payload)
+		a.trace(Received, "A-ASSOCIATE-RJ %s", err)
+		return err
+	case pduAbort:
+		a.trace(Received, "A-ABORT")
+		return errors.New("aborted by the peer")
+	default:
+		a.trace(Received, "%s", pduName(pduType))
+		return fmt.Errorf("unexpected %s", pduName(pduType))
+	}
+	ac, err := decodeAssociate(payload)
+	if err != nil {
+		return err
+	}
+	a.traceAssociate(Received, "A-ASSOCIATE-AC", ac, proposed)
+	for _, pc := range ac.Contexts {
+		if syntax, ok := proposed[pc.ID]; ok && pc.Result == 0 && len(pc.TransferSyntaxes) > 0 {
+			a.contexts[syntax] = pc.ID
+			a.syntaxes[pc.ID] = pc.TransferSyntaxes[0]
+		}
+	}
+	if len(a.contexts) == 0 {
+		return errors.New("no presentation context accepted")
+	}
+	a.maxLength = ac.MaxLength
+	if a.maxLength == 0 || a.maxLength > maxAcceptedPDULength {
+		a.maxLength = maxAcceptedPDULength
+	}
+	return nil
+}
+
+// returns the error of an A-ASSOCIATE-RJ with its reason, PS3.8 9.3.4
+func rejectError(payload []byte) error {
+	if len(payload) < 4 {
+		return errors.New("rejected")
+	}
+	reasons := map[[2]byte]string{
+		{1, 2}: "application context not supported",
+		{1, 3}: "calling AE title not recognized",
+		{1, 7}: "called AE title not recognized",
+		{3, 2}: "temporary congestion",
+		{3, 3}: "local limit exceeded",
+	}
+	if reason, ok := reasons[[2]byte{payload[2], payload[3]}]; ok {
+		return errors.New("rejected, " + reason)
+	}
+	return fmt.Errorf("rejected with source %d and reason %d", payload[2], payload[3])
+}
+
+// releases the association and closes the connection
+func (a *Association) Release() error {
+	defer a.conn.Close()
+	a.conn.SetDeadline(time.Now().Add(Timeout))
+	a.trace(Sent, "A-RELEASE-RQ")
+	if err := writePDU(a.conn, pduReleaseRQ, make([]byte, 4)); err != nil {
+		return err
+	}
+	for {
+		pduType, _, err := readPDU(a.conn)
+		if err != nil {
+			a.trace(Local, "error: %s", err)
+			return err
+		}
+		a.trace(Received, "%s", pduName(pduType))
+		if pduType == pduReleaseRP {
+			return nil
+		}
+	}
+}
+
+// aborts the association and closes the connection
+func (a *Association) Abort() error {
+	a.trace(Sent, "A-ABORT")
+	writePDU(a.conn, pduAbort, make([]byte, 4))
+	return a.conn.Close()
+}
+
+// sends the command and, if not nil, the encoded dataset on the presentation context of the abstract syntax
+func (a *Association) send(abstractSyntax string, command Command, dataset []byte) error {
+	id, ok := a.contexts[abstractSyntax]
+	if !ok {
+		return fmt.Errorf("%s is not accepted by %s", abstractSyntax, a.node)
+	}
+	command.HasDataset = dataset != nil
+	a.conn.SetDeadline(time.Now().Add(Timeout))
+	a.trace(Sent, "%s", messageText(command, dataset))
+	if err := a.sendFragments(id, true, command.encode()); err != nil {
+		return err
+	}
+	if dataset == nil {
+		return nil
+	}
+	return a.sendFragments(id, false, dataset)
+}
+
+// returns the command as text with the length of its dataset
+func messageText(command Command, dataset []byte) string {
+	if dataset == nil {
+		return command.String()
+	}
+	return fmt.Sprintf("%s of %d bytes", command, len(dataset))
+}
+
+// sends the data in P-DATA-TF PDUs of at most the accepted length, one fragment each
+func (a *Association) sendFragments(id byte, command bool, data []byte) error {
+	size := int(a.maxLength) - 6
+	for {
+		fragment := data[:min(size, len(data))]
+		data = data[len(fragment):]
+		payload := encodePDVs(pdv{ContextID: id, Command: command, Last: len(data) == 0, Data: fragment})
+		if err := writePDU(a.conn, pduData, payload); err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return nil
+		}
+	}
+}
+
+// receives the next message and the ID of its presentation context, the dataset is nil if the command has none
+func (a *Association) receive() (Command, []byte, byte, error) {
+	var commandData, dataset []byte
+	var contextID byte
+	commandDone, datasetDone := false, false
+	a.conn.SetDeadline(time.Now().Add(Timeout))
+	for {
+		pduType, payload, err := readPDU(a.conn)
+		if err != nil {
+			a.trace(Local, "error: %s", err)
+			return Command{}, nil, 0, err
+		}
+		if pduType != pduData {
+			a.trace(Received, "%s", pduName(pduType))
+		}
+		switch pduType {
+		case pduData:
+		case pduReleaseRQ:
+			return Command{}, nil, 0, errReleaseRequested
+		case pduAbort:
+			a.conn.Close()
+			return Command{}, nil, 0, fmt.Errorf("association aborted by %s", a.node)
+		default:
+			a.Abort()
+			return Command{}, nil, 0, fmt.Errorf("unexpected %s from %s", pduName(pduType), a.node)
+		}
+		pdvs, err := decodePDVs(payload)
+		if err != nil {
+			return Command{}, nil, 0, err
+		}
+		for _, v := range pdvs {
+			contextID = v.ContextID
+			if v.Command {
+				commandData = append(commandData, v.Data...)
+				commandDone = v.Last
+			} else {
+				dataset = append(dataset, v.Data...)
+				datasetDone = v.Last
+			}
+		}
+		if !commandDone {
+			continue
+		}
+		command, err := decodeCommand(commandData)
+		if err != nil {
+			return Command{}, nil, 0, err
+		}
+		if !command.HasDataset {
+			a.trace(Received, "%s", messageText(command, nil))
+			return command, nil, contextID, nil
+		}
+		if datasetDone {
+			a.trace(Received, "%s", messageText(command, dataset))
+			return command, dataset, contextID, nil
+		}
+	}
+}
+
+func (a *Association) nextMessageID() uint16 {
+	a.messageID++
+	return a.messageID
+}
+
+// verifies the node answers with a C-ECHO
+func (a *Association) Echo() error {
+	request := Command{Field: CommandEchoRQ, MessageID: a.nextMessageID(), SOPClassUID: VerificationClass}
+	if err := a.send(VerificationClass, request, nil); err != nil {
+		return err
+	}
+	response, _, _, err := a.receive()
+	if err != nil {
+		return err
+	}
+	return responseError(response)
+}
+
+// queries the node with a C-FIND of the information model and returns the matches in the order received
+func (a *Association) Find(model string, identifier []*dicom.Element) ([]dicom.Dataset, error) {
+	data, err := encodeDataset(identifier)
+	if err != nil {
+		return nil, err
+	}
+	request := Command{Field: CommandFindRQ, MessageID: a.nextMessageID(), SOPClassUID: model}
+	if err := a.send(model, request, data); err != nil {
+		return nil, err
+	}
+	var matches []dicom.Dataset
+	for {
+		response, data, _, err := a.receive()
+		if err != nil {
+			return matches, err
+		}
+		if !response.Pending() {
+			return matches, responseError(response)
+		}
+		if data == nil {
+			continue
+		}
+		elements, err := decodeDataset(data)
+		if err != nil {
+			return matches, fmt.Errorf("error decoding match %d: %w", len(matches)+1, err)
+		}
+		matches = append(matches, dicom.Dataset{Elements: elements})
+	}
+}
+
+// retrieves the instances matching the identifier with a C-GET of the information model. The node sends them in
+// C-STORE sub-operations on this association, store is called for each with the request, the transfer syntax of the
+// dataset and the dataset, an error of store fails the sub-operation and is returned unless the C-GET fails otherwise.
+func (a *Association) Get(model string, identifier []*dicom.Element, store func(request Command, transferSyntax string, dataset []byte) error) error {
+	data, err := encodeDataset(identifier)
+	if err != nil {
+		return err
+	}
+	request := Command{Field: CommandGetRQ, MessageID: a.nextMessageID(), SOPClassUID: model}
+	if err := a.send(model, request, data); err != nil {
+		return err
+	}
+	var storeErr error
+	for {
+		message, data, contextID, err := a.receive()
+		if err != nil {
+			return err
+		}
+		if message.Field == CommandStoreRQ {
+			response := Command{Field: CommandStoreRSP, RespondingTo: message.MessageID, SOPClassUID: message.SOPClassUID,
+				SOPInstanceUID: message.SOPInstanceUID}
+			if err := store(message, a.syntaxes[contextID], data); err != nil {
+				storeErr = err
+				response.Status, response.ErrorComment = StatusProcessingFailure, err.Error()
+			}
+			if err := a.send(message.SOPClassUID, response, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if message.Pending() {
+			continue
+		}
+		if message.Status == StatusWarning || message.Status == StatusSuccess && message.Failed > 0 {
+			return errors.Join(fmt.Errorf("C-GET failed for %d sub-operations", message.Failed), storeErr)
+		}
+		if err := responseError(message); err != nil {
+			return errors.Join(err, storeErr)
+		}
+		return storeErr
+	}
+}
+
+// returns nil for a successful final response, else an error with its status and comment
+func responseError(response Command) error {
+	if response.Status == StatusSuccess {
+		return nil
+	}
+	service := strings.TrimSuffix(response.Name(), "-RSP")
+	if response.ErrorComment != "" {
+		return fmt.Errorf("%s failed with status 0x%04x, %s", service, response.Status, response.ErrorComment)
+	}
+	return fmt.Errorf("%s failed with status 0x%04x", service, response.Status)
+}
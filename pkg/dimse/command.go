@@ -0,0 +1,224 @@
+package dimse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// command fields of the DIMSE-C services, PS3.7 E.1
+const (
+	CommandStoreRQ  = 0x0001
+	CommandStoreRSP = 0x8001
+	CommandGetRQ    = 0x0010
+	CommandGetRSP   = 0x8010
+	CommandFindRQ   = 0x0020
+	CommandFindRSP  = 0x8020
+	CommandMoveRQ   = 0x0021
+	CommandMoveRSP  = 0x8021
+	CommandEchoRQ   = 0x0030
+	CommandEchoRSP  = 0x8030
+	CommandCancelRQ = 0x0FFF
+)
+
+var commandNames = map[uint16]string{
+	CommandStoreRQ: "C-STORE-RQ", CommandStoreRSP: "C-STORE-RSP", CommandGetRQ: "C-GET-RQ", CommandGetRSP: "C-GET-RSP",
+	CommandFindRQ: "C-FIND-RQ", CommandFindRSP: "C-FIND-RSP", CommandMoveRQ: "C-MOVE-RQ", CommandMoveRSP: "C-MOVE-RSP",
+	CommandEchoRQ: "C-ECHO-RQ", CommandEchoRSP: "C-ECHO-RSP", CommandCancelRQ: "C-CANCEL-RQ",
+}
+
+// statuses of the responses, PS3.7 C
+const (
+	StatusSuccess           = 0x0000
+	StatusProcessingFailure = 0x0110
+	StatusUnrecognized      = 0x0211 // unrecognized operation
+	StatusWarning           = 0xB000 // sub-operations failed
+	StatusCancel            = 0xFE00
+	StatusPending           = 0xFF00
+	StatusPendingWarning    = 0xFF01
+)
+
+// CommandDataSetType of a command without dataset
+const noDataset = 0x0101
+
+// the command set of a DIMSE message, the elements of group 0000 used by the C services
+type Command struct {
+	Field                    uint16
+	MessageID                uint16
+	RespondingTo             uint16 // MessageIDBeingRespondedTo
+	SOPClassUID              string // AffectedSOPClassUID
+	SOPInstanceUID           string // AffectedSOPInstanceUID
+	MoveDestination          string
+	Priority                 uint16
+	HasDataset               bool
+	Status                   uint16
+	ErrorComment             string
+	Remaining, Completed     uint16 // sub-operations of C-GET and C-MOVE
+	Failed, Warning          uint16
+	MoveOriginatorAET        string
+	MoveOriginatorMessageID  uint16
+	hasSubOperations         bool
+	hasMoveOriginatorMessage bool
+}
+
+// returns the name of the command, e.g. "C-FIND-RSP"
+func (c Command) Name() string {
+	if name, ok := commandNames[c.Field]; ok {
+		return name
+	}
+	return fmt.Sprintf("command 0x%04x", c.Field)
+}
+
+// returns whether the status of a response is pending, so further responses follow
+func (c Command) Pending() bool {
+	return c.Status == StatusPending || c.Status == StatusPendingWarning
+}
+
+// encodes the command set in implicit VR little endian with the group length first
+func (c Command) encode() []byte {
+	var elements []byte
+	addString := func(element uint16, value string) {
+		if value == "" {
+			return
+		}
+		if len(value)%2 == 1 {
+			value += "\x00"
+		}
+		elements = appendTag(elements, commandTag(element))
+		elements = binary.LittleEndian.AppendUint32(elements, uint32(len(value)))
+		elements = append(elements, value...)
+	}
+	addUS := func(element uint16, value uint16) {
+		elements = appendTag(elements, commandTag(element))
+		elements = binary.LittleEndian.AppendUint32(elements, 2)
+		elements = binary.LittleEndian.AppendUint16(elements, value)
+	}
+	addString(0x0002, c.SOPClassUID)
+	addUS(0x0100, c.Field)
+	if c.Field&0x8000 == 0 {
+		addUS(0x0110, c.MessageID)
+	} else {
+		addUS(0x0120, c.RespondingTo)
+	}
+	addString(0x0600, c.MoveDestination)
+	if c.Field&0x8000 == 0 && c.Field != CommandEchoRQ && c.Field != CommandCancelRQ {
+		addUS(0x0700, c.Priority)
+	}
+	datasetType := uint16(noDataset)
+	if c.HasDataset {
+		datasetType = 0
+	}
+	addUS(0x0800, datasetType)
+	if c.Field&0x8000 != 0 {
+		addUS(0x0900, c.Status)
+	}
+	addString(0x0902, c.ErrorComment)
+	addString(0x1000, c.SOPInstanceUID)
+	if c.hasSubOperations {
+		addUS(0x1020, c.Remaining)
+		addUS(0x1021, c.Completed)
+		addUS(0x1022, c.Failed)
+		addUS(0x1023, c.Warning)
+	}
+	addString(0x1030, c.MoveOriginatorAET)
+	if c.hasMoveOriginatorMessage {
+		addUS(0x1031, c.MoveOriginatorMessageID)
+	}
+
+	b := appendTag(nil, commandTag(0x0000))
+	b = binary.LittleEndian.AppendUint32(b, 4)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(elements)))
+	return append(b, elements...)
+}
+
+func commandTag(element uint16) tag.Tag {
+	return tag.Tag{Group: 0x0000, Element: element}
+}
+
+// decodes a command set in implicit VR little endian, unknown elements are skipped
+func decodeCommand(data []byte) (Command, error) {
+	var c Command
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return c, fmt.Errorf("truncated command element")
+		}
+		group, element := binary.LittleEndian.Uint16(data), binary.LittleEndian.Uint16(data[2:])
+		length := binary.LittleEndian.Uint32(data[4:])
+		if uint64(len(data)) < 8+uint64(length) {
+			return c, fmt.Errorf("command element (%04x,%04x) is truncated", group, element)
+		}
+		value := data[8 : 8+length]
+		data = data[8+length:]
+		if group != 0x0000 {
+			return c, fmt.Errorf("element (%04x,%04x) in command set", group, element)
+		}
+		us := uint16(0)
+		if len(value) >= 2 {
+			us = binary.LittleEndian.Uint16(value)
+		}
+		text := strings.TrimRight(string(value), "\x00 ")
+		switch element {
+		case 0x0002:
+			c.SOPClassUID = text
+		case 0x0100:
+			c.Field = us
+		case 0x0110:
+			c.MessageID = us
+		case 0x0120:
+			c.RespondingTo = us
+		case 0x0600:
+			c.MoveDestination = text
+		case 0x0700:
+			c.Priority = us
+		case 0x0800:
+			c.HasDataset = us != noDataset
+		case 0x0900:
+			c.Status = us
+		case 0x0902:
+			c.ErrorComment = text
+		case 0x1000:
+			c.SOPInstanceUID = text
+		case 0x1020:
+			c.Remaining, c.hasSubOperations = us, true
+		case 0x1021:
+			c.Completed = us
+		case 0x1022:
+			c.Failed = us
+		case 0x1023:
+			c.Warning = us
+		case 0x1030:
+			c.MoveOriginatorAET = text
+		case 0x1031:
+			c.MoveOriginatorMessageID, c.hasMoveOriginatorMessage = us, true
+		}
+	}
+	return c, nil
+}
+
+// returns the command as one line for traces, e.g. "C-FIND-RSP, status 0xff00, responding to 1, with dataset"
+func (c Command) String() string {
+	parts := []string{c.Name()}
+	if c.Field&0x8000 != 0 {
+		parts = append(parts, fmt.Sprintf("status 0x%04x", c.Status), fmt.Sprintf("responding to %d", c.RespondingTo))
+	} else {
+		parts = append(parts, fmt.Sprintf("message %d", c.MessageID))
+	}
+	if c.SOPClassUID != "" {
+		parts = append(parts, "class "+c.SOPClassUID)
+	}
+	if c.SOPInstanceUID != "" {
+		parts = append(parts, "instance "+c.SOPInstanceUID)
+	}
+	if c.hasSubOperations {
+		parts = append(parts, fmt.Sprintf("remaining %d, completed %d, failed %d, warning %d", c.Remaining, c.Completed, c.Failed, c.Warning))
+	}
+	if c.ErrorComment != "" {
+		parts = append(parts, "'"+c.ErrorComment+"'")
+	}
+	if c.HasDataset {
+		parts = append(parts, "with dataset")
+	}
+	return strings.Join(parts, ", ")
+}
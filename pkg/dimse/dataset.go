@@ -0,0 +1,272 @@
+package dimse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const undefinedLength = 0xFFFFFFFF
+
+var (
+	itemTag                 = tag.Tag{Group: 0xFFFE, Element: 0xE000}
+	itemDelimitationTag     = tag.Tag{Group: 0xFFFE, Element: 0xE00D}
+	sequenceDelimitationTag = tag.Tag{Group: 0xFFFE, Element: 0xE0DD}
+)
+
+// returns the VR of the element, from the element if parsed with explicit VR, else from the dictionary
+func vrOf(e *dicom.Element) string {
+	if e.RawValueRepresentation != "" && e.RawValueRepresentation != "UN" {
+		return e.RawValueRepresentation
+	}
+	return dictionaryVR(e.Tag)
+}
+
+func dictionaryVR(t tag.Tag) string {
+	if info, err := tag.Find(t); err == nil && info.VR != "" {
+		vr, _, _ := strings.Cut(info.VR, " ") // e.g. "US or SS"
+		return vr
+	}
+	return "UN"
+}
+
+// encodes the elements in implicit VR little endian, sorted by tag as the standard requires. Sequences and items
+// have undefined length, so they are decoded without a dictionary.
+func encodeDataset(elements []*dicom.Element) ([]byte, error) {
+	sorted := slices.Clone(elements)
+	slices.SortFunc(sorted, func(a, b *dicom.Element) int { return int(dicomtree.TagOrder(a.Tag)) - int(dicomtree.TagOrder(b.Tag)) })
+	var b []byte
+	for _, e := range sorted {
+		value, err := encodeValue(e)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s: %w", dicomtree.FormatTag(e.Tag), err)
+		}
+		length := uint32(len(value))
+		if dicomtree.IsSequence(e) {
+			length = undefinedLength
+		}
+		b = appendTag(b, e.Tag)
+		b = binary.LittleEndian.AppendUint32(b, length)
+		b = append(b, value...)
+	}
+	return b, nil
+}
+
+func appendTag(b []byte, t tag.Tag) []byte {
+	b = binary.LittleEndian.AppendUint16(b, t.Group)
+	return binary.LittleEndian.AppendUint16(b, t.Element)
+}
+
+func encodeValue(e *dicom.Element) ([]byte, error) {
+	if e.Value == nil {
+		return nil, nil
+	}
+	vr := vrOf(e)
+	var b []byte
+	switch e.Value.ValueType() {
+	case dicom.Strings:
+		b = []byte(strings.Join(e.Value.GetValue().([]string), "\\"))
+		if len(b)%2 == 1 {
+			padding := byte(' ')
+			if vr == "UI" {
+				padding = 0
+			}
+			b = append(b, padding)
+		}
+	case dicom.Bytes:
+		b = e.Value.GetValue().([]byte)
+		if len(b)%2 == 1 {
+			b = append(b, 0)
+		}
+	case dicom.Ints:
+		for _, v := range e.Value.GetValue().([]int) {
+			switch vr {
+			case "US", "SS":
+				b = binary.LittleEndian.AppendUint16(b, uint16(v))
+			default:
+				b = binary.LittleEndian.AppendUint32(b, uint32(v))
+			}
+		}
+	case dicom.Floats:
+		for _, v := range e.Value.GetValue().([]float64) {
+			if vr == "FL" {
+				b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v)))
+			} else {
+				b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+			}
+		}
+	case dicom.Sequences:
+		for _, item := range dicomtree.SequenceItems(e) {
+			content, err := encodeDataset(item)
+			if err != nil {
+				return nil, err
+			}
+			b = appendTag(b, itemTag)
+			b = binary.LittleEndian.AppendUint32(b, undefinedLength)
+			b = append(b, content...)
+			b = appendTag(b, itemDelimitationTag)
+			b = binary.LittleEndian.AppendUint32(b, 0)
+		}
+		b = appendTag(b, sequenceDelimitationTag)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+	default:
+		return nil, fmt.Errorf("unsupported value type %v", e.Value.ValueType())
+	}
+	return b, nil
+}
+
+// decodes a dataset in implicit VR little endian, the values of binary VRs known to the dictionary are decoded as
+// numbers, all others as strings
+func decodeDataset(data []byte) ([]*dicom.Element, error) {
+	elements, rest, err := decodeElements(data, false)
+	if err == nil && len(rest) > 0 {
+		err = fmt.Errorf("%d bytes left after the dataset", len(rest))
+	}
+	return elements, err
+}
+
+// decodes elements until the end of the data or, within an item of undefined length, its delimitation item.
+// Returns the data after the elements.
+func decodeElements(data []byte, inItem bool) ([]*dicom.Element, []byte, error) {
+	var elements []*dicom.Element
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, nil, fmt.Errorf("truncated element header")
+		}
+		t := tag.Tag{Group: binary.LittleEndian.Uint16(data), Element: binary.LittleEndian.Uint16(data[2:])}
+		length := binary.LittleEndian.Uint32(data[4:])
+		data = data[8:]
+		if t == itemDelimitationTag && inItem {
+			return elements, data, nil
+		}
+		vr := dictionaryVR(t)
+		if length == undefinedLength || vr == "SQ" {
+			items, rest, err := decodeItems(data, length)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error in %s: %w", dicomtree.FormatTag(t), err)
+			}
+			value, err := dicom.NewValue(items)
+			if err != nil {
+				return nil, nil, err
+			}
+			elements = append(elements, &dicom.Element{Tag: t, RawValueRepresentation: "SQ", ValueLength: length, Value: value})
+			data = rest
+			continue
+		}
+		if uint64(length) > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("value of %s with %d bytes is truncated", dicomtree.FormatTag(t), length)
+		}
+		value, err := decodeValue(data[:length], vr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error in %s: %w", dicomtree.FormatTag(t), err)
+		}
+		elements = append(elements, &dicom.Element{Tag: t, RawValueRepresentation: vr, ValueLength: length, Value: value})
+		data = data[length:]
+	}
+	if inItem {
+		return nil, nil, fmt.Errorf("item without delimitation")
+	}
+	return elements, data, nil
+}
+
+// decodes the items of a sequence of the given length, returns the data after the sequence
+func decodeItems(data []byte, length uint32) ([][]*dicom.Element, []byte, error) {
+	var rest []byte
+	if length != undefinedLength {
+		if uint64(length) > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("sequence of %d bytes is truncated", length)
+		}
+		data, rest = data[:length], data[length:]
+	}
+	items := make([][]*dicom.Element, 0)
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, nil, fmt.Errorf("truncated item header")
+		}
+		t := tag.Tag{Group: binary.LittleEndian.Uint16(data), Element: binary.LittleEndian.Uint16(data[2:])}
+		itemLength := binary.LittleEndian.Uint32(data[4:])
+		data = data[8:]
+		if t == sequenceDelimitationTag {
+			return items, data, nil
+		}
+		if t != itemTag {
+			return nil, nil, fmt.Errorf("unexpected %s in sequence", dicomtree.FormatTag(t))
+		}
+		var item []*dicom.Element
+		var err error
+		if itemLength == undefinedLength {
+			item, data, err = decodeElements(data, true)
+		} else if uint64(itemLength) > uint64(len(data)) {
+			err = fmt.Errorf("item of %d bytes is truncated", itemLength)
+		} else {
+			item, err = decodeDataset(data[:itemLength])
+			data = data[itemLength:]
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+	}
+	if length == undefinedLength {
+		return nil, nil, fmt.Errorf("sequence without delimitation")
+	}
+	return items, rest, nil
+}
+
+func decodeValue(data []byte, vr string) (dicom.Value, error) {
+	switch vr {
+	case "US", "SS", "UL", "SL", "AT":
+		size := 4
+		if vr == "US" || vr == "SS" {
+			size = 2
+		}
+		values := make([]int, 0, len(data)/size)
+		for i := 0; i+size <= len(data); i += size {
+			switch vr {
+			case "US":
+				values = append(values, int(binary.LittleEndian.Uint16(data[i:])))
+			case "SS":
+				values = append(values, int(int16(binary.LittleEndian.Uint16(data[i:]))))
+			case "SL":
+				values = append(values, int(int32(binary.LittleEndian.Uint32(data[i:]))))
+			default:
+				values = append(values, int(binary.LittleEndian.Uint32(data[i:])))
+			}
+		}
+		return dicom.NewValue(values)
+	case "FL", "FD":
+		size := 8
+		if vr == "FL" {
+			size = 4
+		}
+		values := make([]float64, 0, len(data)/size)
+		for i := 0; i+size <= len(data); i += size {
+			if vr == "FL" {
+				values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i:]))))
+			} else {
+				values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(data[i:])))
+			}
+		}
+		return dicom.NewValue(values)
+	case "OB", "OW", "OF", "OD", "OL":
+		return dicom.NewValue(data)
+	case "UN":
+		if slices.ContainsFunc(data, func(c byte) bool { return c < ' ' && c != 0 || c > '~' }) {
+			return dicom.NewValue(data)
+		}
+	}
+	text := strings.TrimRight(string(data), "\x00 ")
+	if text == "" {
+		return dicom.NewValue([]string{})
+	}
+	if vr == "LT" || vr == "ST" || vr == "UT" {
+		return dicom.NewValue([]string{text})
+	}
+	return dicom.NewValue(strings.Split(text, "\\"))
+}
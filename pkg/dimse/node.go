@@ -0,0 +1,155 @@
+package dimse
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const VerificationClass = "1.2.840.10008.1.1"
+
+// services a node offers, configured with its capabilities
+var Capabilities = []string{"echo", "find", "move", "get", "store", "worklist", "dicomweb"}
+
+// a remote application entity reached with DIMSE, or a DICOMweb server if only the URL is set
+type Node struct {
+	Host         string   `json:"host,omitempty"`
+	Port         int      `json:"port,omitempty"`
+	AET          string   `json:"aet,omitempty"`          // called AE title
+	CallingAET   string   `json:"callingAet,omitempty"`   // "DCMTAGGER" if empty
+	TLS          bool     `json:"tls,omitempty"`          // DICOM over TLS, verified against the system roots or CAFile
+	CAFile       string   `json:"caFile,omitempty"`       // PEM certificates of the CAs trusted for TLS
+	URL          string   `json:"url,omitempty"`          // base URL of the DICOMweb services
+	Capabilities []string `json:"capabilities,omitempty"` // all services of its kind if empty
+}
+
+// parses a node "AET@host:port" or a DICOMweb base URL
+func ParseNode(spec string) (Node, error) {
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		if _, err := url.Parse(spec); err != nil {
+			return Node{}, err
+		}
+		return Node{URL: spec}, nil
+	}
+	aet, address, ok := strings.Cut(spec, "@")
+	host, portText, err := net.SplitHostPort(address)
+	if !ok || err != nil {
+		return Node{}, fmt.Errorf("invalid node '%s', use AET@host:port or a DICOMweb URL", spec)
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return Node{}, fmt.Errorf("invalid port '%s'", portText)
+	}
+	node := Node{Host: host, Port: port, AET: aet}
+	return node, node.Validate()
+}
+
+// returns an error if the node has neither host, port and AE title nor a URL, or unknown capabilities
+func (n Node) Validate() error {
+	if n.URL == "" && (n.Host == "" || n.Port <= 0 || n.Port > 65535 || n.AET == "") {
+		return errors.New("node needs host, port and aet or a url")
+	}
+	if len(n.AET) > 16 || len(n.CallingAET) > 16 {
+		return errors.New("AE titles have at most 16 characters")
+	}
+	for _, capability := range n.Capabilities {
+		if !slices.Contains(Capabilities, capability) {
+			return fmt.Errorf("unknown capability '%s', use %s", capability, strings.Join(Capabilities, ", "))
+		}
+	}
+	return nil
+}
+
+// returns whether the node offers the service, nodes without capabilities offer all DIMSE services if they have a host
+// and DICOMweb if they have a URL
+func (n Node) Can(capability string) bool {
+	if len(n.Capabilities) > 0 {
+		return slices.Contains(n.Capabilities, capability)
+	}
+	if capability == "dicomweb" {
+		return n.URL != ""
+	}
+	return n.Host != ""
+}
+
+func (n Node) address() string {
+	return net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+}
+
+func (n Node) callingAET() string {
+	if n.CallingAET == "" {
+		return "DCMTAGGER"
+	}
+	return n.CallingAET
+}
+
+// returns the node as "AET@host:port", with " (TLS)" for TLS, or its URL
+func (n Node) String() string {
+	if n.Host == "" {
+		return n.URL
+	}
+	if n.TLS {
+		return n.AET + "@" + n.address() + " (TLS)"
+	}
+	return n.AET + "@" + n.address()
+}
+
+func (n Node) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: Timeout}
+	if !n.TLS {
+		return dialer.Dial("tcp", n.address())
+	}
+	config := &tls.Config{ServerName: n.Host}
+	if n.CAFile != "" {
+		pem, err := os.ReadFile(n.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", n.CAFile)
+		}
+	}
+	return tls.DialWithDialer(dialer, "tcp", n.address(), config)
+}
+
+// tests the connectivity of the node with a C-ECHO, or for DICOMweb with a query for a study, returns the round trip
+// time
+func Ping(node Node) (time.Duration, error) {
+	start := time.Now()
+	if node.Host == "" {
+		err := pingDICOMweb(node.URL)
+		return time.Since(start), err
+	}
+	a, err := Associate(node, VerificationClass)
+	if err != nil {
+		return 0, err
+	}
+	if err := a.Echo(); err != nil {
+		a.Abort()
+		return 0, err
+	}
+	return time.Since(start), a.Release()
+}
+
+func pingDICOMweb(baseURL string) error {
+	client := http.Client{Timeout: Timeout}
+	response, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/studies?limit=1")
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("QIDO-RS query failed with %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package dimse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPing(t *testing.T) {
+	assert := assert.New(t)
+
+	scp, node := startFakeSCP(t, 0)
+	_, err := Ping(node)
+	assert.NoError(err)
+	assert.NoError(<-scp.done)
+
+	scp.listener.Close()
+	_, err = Ping(node)
+	assert.Error(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dicomweb/studies" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	_, err = Ping(Node{URL: server.URL + "/dicomweb/"})
+	assert.NoError(err)
+	_, err = Ping(Node{URL: server.URL})
+	assert.EqualError(err, "QIDO-RS query failed with 404 Not Found")
+}
+
+func TestParseNode(t *testing.T) {
+	assert := assert.New(t)
+
+	node, err := ParseNode("PACS@pacs.example.com:11112")
+	assert.NoError(err)
+	assert.Equal(Node{Host: "pacs.example.com", Port: 11112, AET: "PACS"}, node)
+	assert.Equal("PACS@pacs.example.com:11112", node.String())
+	assert.True(node.Can("move"))
+	assert.False(node.Can("dicomweb"))
+	node.Capabilities = []string{"echo", "find"}
+	assert.False(node.Can("move"))
+	node.Capabilities = []string{"teleport"}
+	assert.ErrorContains(node.Validate(), "unknown capability 'teleport'")
+
+	node, err = ParseNode("https://pacs.example.com/dicomweb")
+	assert.NoError(err)
+	assert.True(node.Can("dicomweb"))
+	assert.Equal("https://pacs.example.com/dicomweb", node.String())
+
+	for _, invalid := range []string{"pacs.example.com:104", "PACS@pacs.example.com", "PACS@host:port", "THIS_AE_TITLE_IS_TOO_LONG@host:104"} {
+		_, err := ParseNode(invalid)
+		assert.Error(err, invalid)
+	}
+}
@@ -0,0 +1,268 @@
+package dimse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PDU types of the DICOM upper layer protocol, PS3.8 9.3
+const (
+	pduAssociateRQ = 0x01
+	pduAssociateAC = 0x02
+	pduAssociateRJ = 0x03
+	pduData        = 0x04
+	pduReleaseRQ   = 0x05
+	pduReleaseRP   = 0x06
+	pduAbort       = 0x07
+)
+
+// item types of the association PDUs
+const (
+	itemApplicationContext = 0x10
+	itemPresentationRQ     = 0x20
+	itemPresentationAC     = 0x21
+	itemAbstractSyntax     = 0x30
+	itemTransferSyntax     = 0x40
+	itemUserInformation    = 0x50
+	itemMaxLength          = 0x51
+	itemImplementationUID  = 0x52
+	itemRoleSelection      = 0x54
+	itemImplementationName = 0x55
+)
+
+const (
+	applicationContext   = "1.2.840.10008.3.1.1.1"
+	implicitVRLittle     = "1.2.840.10008.1.2"
+	explicitVRLittle     = "1.2.840.10008.1.2.1"
+	implementationUID    = "1.2.826.0.1.3680043.10.1404.1"
+	implementationName   = "DCMTAGGER"
+	defaultMaxPDULength  = 16384
+	maxAcceptedPDULength = 1 << 24 // longer PDUs are treated as garbage
+)
+
+var pduNames = map[byte]string{
+	pduAssociateRQ: "A-ASSOCIATE-RQ", pduAssociateAC: "A-ASSOCIATE-AC", pduAssociateRJ: "A-ASSOCIATE-RJ",
+	pduData: "P-DATA-TF", pduReleaseRQ: "A-RELEASE-RQ", pduReleaseRP: "A-RELEASE-RP", pduAbort: "A-ABORT",
+}
+
+// returns the name of the PDU type, e.g. "A-ASSOCIATE-RQ"
+func pduName(pduType byte) string {
+	if name, ok := pduNames[pduType]; ok {
+		return name
+	}
+	return fmt.Sprintf("PDU 0x%02x", pduType)
+}
+
+// a presentation context proposed in an A-ASSOCIATE-RQ or answered in an A-ASSOCIATE-AC, where Result 0 accepts it
+type presentationContext struct {
+	ID               byte
+	Result           byte
+	AbstractSyntax   string   // empty in an A-ASSOCIATE-AC
+	TransferSyntaxes []string // the accepted one in an A-ASSOCIATE-AC
+}
+
+// content of an A-ASSOCIATE-RQ or A-ASSOCIATE-AC
+type associatePDU struct {
+	CalledAE           string
+	CallingAE          string
+	Contexts           []presentationContext
+	MaxLength          uint32
+	ImplementationUID  string
+	ImplementationName string
+	SCPRoles           []string // abstract syntaxes the requestor offers the SCP role for, e.g. storage for C-GET
+}
+
+func writePDU(w io.Writer, pduType byte, payload []byte) error {
+	header := []byte{pduType, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[2:], uint32(len(payload)))
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+func readPDU(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[2:])
+	if length > maxAcceptedPDULength {
+		return 0, nil, fmt.Errorf("PDU of %d bytes is too long", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// appends an item with 2 byte length
+func appendItem(b []byte, itemType byte, content []byte) []byte {
+	b = append(b, itemType, 0)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(content)))
+	return append(b, content...)
+}
+
+// returns the AE title padded to 16 characters
+func aeTitle(title string) []byte {
+	return []byte(fmt.Sprintf("%-16.16s", title))
+}
+
+// encodes the payload of an A-ASSOCIATE-RQ or, for accept, an A-ASSOCIATE-AC
+func (p associatePDU) encode(accept bool) []byte {
+	b := []byte{0, 1, 0, 0}
+	b = append(b, aeTitle(p.CalledAE)...)
+	b = append(b, aeTitle(p.CallingAE)...)
+	b = append(b, make([]byte, 32)...)
+	b = appendItem(b, itemApplicationContext, []byte(applicationContext))
+	for _, pc := range p.Contexts {
+		content := []byte{pc.ID, 0, pc.Result, 0}
+		itemType := byte(itemPresentationAC)
+		if !accept {
+			itemType = itemPresentationRQ
+			content[2] = 0
+			content = appendItem(content, itemAbstractSyntax, []byte(pc.AbstractSyntax))
+		}
+		for _, ts := range pc.TransferSyntaxes {
+			content = appendItem(content, itemTransferSyntax, []byte(ts))
+		}
+		b = appendItem(b, itemType, content)
+	}
+	var user []byte
+	user = appendItem(user, itemMaxLength, binary.BigEndian.AppendUint32(nil, p.MaxLength))
+	user = appendItem(user, itemImplementationUID, []byte(p.ImplementationUID))
+	user = appendItem(user, itemImplementationName, []byte(p.ImplementationName))
+	for _, syntax := range p.SCPRoles {
+		role := binary.BigEndian.AppendUint16(nil, uint16(len(syntax)))
+		role = append(append(role, syntax...), 0, 1) // no SCU role, SCP role
+		user = appendItem(user, itemRoleSelection, role)
+	}
+	return appendItem(b, itemUserInformation, user)
+}
+
+// returns the items of the data as type and content
+func items(data []byte) ([]byte, [][]byte, error) {
+	var types []byte
+	var contents [][]byte
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, nil, fmt.Errorf("truncated item")
+		}
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if len(data) < 4+length {
+			return nil, nil, fmt.Errorf("item 0x%02x of %d bytes is truncated", data[0], length)
+		}
+		types = append(types, data[0])
+		contents = append(contents, data[4:4+length])
+		data = data[4+length:]
+	}
+	return types, contents, nil
+}
+
+func trimUID(b []byte) string {
+	return strings.TrimRight(string(b), "\x00 ")
+}
+
+func decodeAssociate(payload []byte) (associatePDU, error) {
+	var p associatePDU
+	if len(payload) < 68 {
+		return p, fmt.Errorf("A-ASSOCIATE of %d bytes is truncated", len(payload))
+	}
+	p.CalledAE = strings.TrimSpace(string(payload[4:20]))
+	p.CallingAE = strings.TrimSpace(string(payload[20:36]))
+	types, contents, err := items(payload[68:])
+	if err != nil {
+		return p, err
+	}
+	for i, content := range contents {
+		switch types[i] {
+		case itemPresentationRQ, itemPresentationAC:
+			if len(content) < 4 {
+				return p, fmt.Errorf("truncated presentation context")
+			}
+			pc := presentationContext{ID: content[0], Result: content[2]}
+			subTypes, subContents, err := items(content[4:])
+			if err != nil {
+				return p, err
+			}
+			for j, sub := range subContents {
+				switch subTypes[j] {
+				case itemAbstractSyntax:
+					pc.AbstractSyntax = trimUID(sub)
+				case itemTransferSyntax:
+					pc.TransferSyntaxes = append(pc.TransferSyntaxes, trimUID(sub))
+				}
+			}
+			p.Contexts = append(p.Contexts, pc)
+		case itemUserInformation:
+			subTypes, subContents, err := items(content)
+			if err != nil {
+				return p, err
+			}
+			for j, sub := range subContents {
+				switch {
+				case subTypes[j] == itemMaxLength && len(sub) == 4:
+					p.MaxLength = binary.BigEndian.Uint32(sub)
+				case subTypes[j] == itemImplementationUID:
+					p.ImplementationUID = trimUID(sub)
+				case subTypes[j] == itemImplementationName:
+					p.ImplementationName = strings.TrimSpace(string(sub))
+				case subTypes[j] == itemRoleSelection && len(sub) >= 2:
+					n := int(binary.BigEndian.Uint16(sub))
+					if len(sub) == n+4 && sub[n+3] == 1 {
+						p.SCPRoles = append(p.SCPRoles, trimUID(sub[2:n+2]))
+					}
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// a fragment of a message in a P-DATA-TF
+type pdv struct {
+	ContextID byte
+	Command   bool
+	Last      bool
+	Data      []byte
+}
+
+func encodePDVs(pdvs ...pdv) []byte {
+	var b []byte
+	for _, v := range pdvs {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(v.Data)+2))
+		var header byte
+		if v.Command {
+			header |= 1
+		}
+		if v.Last {
+			header |= 2
+		}
+		b = append(b, v.ContextID, header)
+		b = append(b, v.Data...)
+	}
+	return b
+}
+
+func decodePDVs(payload []byte) ([]pdv, error) {
+	var pdvs []pdv
+	for len(payload) > 0 {
+		if len(payload) < 6 {
+			return nil, fmt.Errorf("truncated PDV")
+		}
+		length := int(binary.BigEndian.Uint32(payload))
+		if length < 2 || len(payload) < 4+length {
+			return nil, fmt.Errorf("PDV of %d bytes is truncated", length)
+		}
+		pdvs = append(pdvs, pdv{
+			ContextID: payload[4],
+			Command:   payload[5]&1 != 0,
+			Last:      payload[5]&2 != 0,
+			Data:      bytes.Clone(payload[6 : 4+length]),
+		})
+		payload = payload[4+length:]
+	}
+	return pdvs, nil
+}
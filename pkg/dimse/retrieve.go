@@ -0,0 +1,154 @@
+package dimse
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const GetModel = "1.2.840.10008.5.1.4.1.2.2.3" // Study Root Query/Retrieve Information Model - GET
+
+// an instance to retrieve, identified by its UIDs
+type Instance struct {
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	SOPInstanceUID    string
+	SOPClassUID       string // proposed for the C-STORE sub-operation of a C-GET
+	TransferSyntaxUID string // proposed first for the C-STORE sub-operation if set, e.g. the one of a local copy
+}
+
+// returns the identifier of a C-GET for the instance at the image level
+func (i Instance) identifier() []*dicom.Element {
+	return []*dicom.Element{
+		stringElement(tag.QueryRetrieveLevel, "CS", "IMAGE"),
+		stringElement(tag.StudyInstanceUID, "UI", i.StudyInstanceUID),
+		stringElement(tag.SeriesInstanceUID, "UI", i.SeriesInstanceUID),
+		stringElement(tag.SOPInstanceUID, "UI", i.SOPInstanceUID),
+	}
+}
+
+// retrieves the instance from the node with a C-GET, or with WADO-RS from a DICOMweb node, and returns it as DICOM
+// file in the transfer syntax the node sent
+func Retrieve(node Node, instance Instance) ([]byte, error) {
+	if node.Host == "" {
+		return retrieveDICOMweb(node.URL, instance)
+	}
+	transferSyntaxes := []string{explicitVRLittle, implicitVRLittle}
+	if ts := instance.TransferSyntaxUID; ts != "" && !slices.Contains(transferSyntaxes, ts) {
+		transferSyntaxes = append([]string{ts}, transferSyntaxes...)
+	}
+	a, err := associate(node, []presentationContext{
+		{ID: 1, AbstractSyntax: GetModel, TransferSyntaxes: []string{implicitVRLittle}},
+		{ID: 3, AbstractSyntax: instance.SOPClassUID, TransferSyntaxes: transferSyntaxes},
+	}, []string{instance.SOPClassUID})
+	if err != nil {
+		return nil, err
+	}
+	for _, syntax := range []string{GetModel, instance.SOPClassUID} {
+		if _, ok := a.contexts[syntax]; !ok {
+			a.Abort()
+			return nil, fmt.Errorf("%s is not accepted by %s", syntax, node)
+		}
+	}
+	var file []byte
+	err = a.Get(GetModel, instance.identifier(), func(request Command, transferSyntax string, dataset []byte) error {
+		if request.SOPInstanceUID != instance.SOPInstanceUID {
+			return fmt.Errorf("instance %s was not requested", request.SOPInstanceUID)
+		}
+		file = fileBytes(request.SOPClassUID, request.SOPInstanceUID, transferSyntax, dataset)
+		return nil
+	})
+	if err != nil {
+		a.Abort()
+		return nil, err
+	}
+	if err := a.Release(); err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("instance %s not found at %s", instance.SOPInstanceUID, node)
+	}
+	return file, nil
+}
+
+// retrieves the instance with WADO-RS in any transfer syntax, PS3.18 10.4
+func retrieveDICOMweb(baseURL string, instance Instance) ([]byte, error) {
+	url := fmt.Sprintf("%s/studies/%s/series/%s/instances/%s", strings.TrimSuffix(baseURL, "/"),
+		instance.StudyInstanceUID, instance.SeriesInstanceUID, instance.SOPInstanceUID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", `multipart/related; type="application/dicom"; transfer-syntax=*`)
+	client := http.Client{Timeout: Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("instance %s not found at %s", instance.SOPInstanceUID, baseURL)
+	}
+	if response.StatusCode >= 300 {
+		return nil, fmt.Errorf("WADO-RS retrieve failed with %s", response.Status)
+	}
+	mediaType, params, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WADO-RS response: %w", err)
+	}
+	if mediaType == "application/dicom" {
+		return io.ReadAll(response.Body)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("unexpected WADO-RS response of type %s", mediaType)
+	}
+	part, err := multipart.NewReader(response.Body, params["boundary"]).NextPart()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("instance %s not found at %s", instance.SOPInstanceUID, baseURL)
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid WADO-RS response: %w", err)
+	}
+	return io.ReadAll(part)
+}
+
+// returns the dataset received in the transfer syntax as DICOM file with preamble and file meta information, PS3.10 7.1
+func fileBytes(sopClass, sopInstance, transferSyntax string, dataset []byte) []byte {
+	var meta []byte
+	add := func(element uint16, vr string, value string) {
+		if len(value)%2 == 1 {
+			value += map[string]string{"UI": "\x00", "SH": " "}[vr]
+		}
+		meta = appendTag(meta, tag.Tag{Group: 0x0002, Element: element})
+		meta = append(meta, vr...)
+		if vr == "OB" {
+			meta = append(meta, 0, 0)
+			meta = binary.LittleEndian.AppendUint32(meta, uint32(len(value)))
+		} else {
+			meta = binary.LittleEndian.AppendUint16(meta, uint16(len(value)))
+		}
+		meta = append(meta, value...)
+	}
+	add(0x0001, "OB", "\x00\x01")
+	add(0x0002, "UI", sopClass)
+	add(0x0003, "UI", sopInstance)
+	add(0x0010, "UI", transferSyntax)
+	add(0x0012, "UI", implementationUID)
+	add(0x0013, "SH", implementationName)
+
+	file := append(make([]byte, 128), "DICM"...)
+	file = appendTag(file, tag.Tag{Group: 0x0002, Element: 0x0000})
+	file = append(file, "UL"...)
+	file = binary.LittleEndian.AppendUint16(file, 4)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(meta)))
+	file = append(file, meta...)
+	return append(file, dataset...)
+}
@@ -0,0 +1,93 @@
+package dimse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestRetrieve(t *testing.T) {
+	assert := assert.New(t)
+	const ctImage = "1.2.840.10008.5.1.4.1.1.2"
+	instance := Instance{StudyInstanceUID: "1.2", SeriesInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3.4",
+		SOPClassUID: ctImage, TransferSyntaxUID: "1.2.840.10008.1.2.4.90"}
+	archived := []*dicom.Element{
+		stringElement(tag.SOPInstanceUID, "UI", "1.2.3.4"),
+		stringElement(tag.PatientName, "PN", "DOE^JOHN"),
+	}
+
+	scp, node := startFakeSCP(t, 0, archived)
+	file, err := Retrieve(node, instance)
+	require.NoError(t, err)
+	require.NoError(t, <-scp.done)
+	assert.Equal([]string{ctImage}, scp.scpRoles)
+	identifier := dicom.Dataset{Elements: scp.identifier}
+	assert.Equal("1.2.3.4", dicomtree.UIDValue(&identifier, tag.SOPInstanceUID))
+	assert.Equal("1.2.3", dicomtree.UIDValue(&identifier, tag.SeriesInstanceUID))
+
+	require.Greater(t, len(file), 144)
+	assert.Equal("DICM", string(file[128:132]))
+	metaLength := int(binary.LittleEndian.Uint32(file[140:]))
+	meta := string(file[144 : 144+metaLength])
+	assert.Contains(meta, ctImage+"\x00")
+	assert.Contains(meta, implicitVRLittle+"\x00", "the transfer syntax the SCP accepted")
+	elements, err := decodeDataset(file[144+metaLength:])
+	require.NoError(t, err)
+	dataset := dicom.Dataset{Elements: elements}
+	assert.Equal("1.2.3.4", dicomtree.UIDValue(&dataset, tag.SOPInstanceUID))
+
+	other := []*dicom.Element{stringElement(tag.SOPInstanceUID, "UI", "1.2.3.5")}
+	scp, node = startFakeSCP(t, 0, other)
+	_, err = Retrieve(node, instance)
+	assert.ErrorContains(err, "C-GET failed for 1 sub-operations")
+	assert.ErrorContains(err, "instance 1.2.3.5 was not requested")
+	<-scp.done
+
+	scp, node = startFakeSCP(t, 0)
+	_, err = Retrieve(node, instance)
+	assert.ErrorContains(err, "instance 1.2.3.4 not found")
+	assert.NoError(<-scp.done)
+
+	scp, node = startFakeSCP(t, 0)
+	_, err = Retrieve(node, Instance{SOPInstanceUID: "1.2.3.4", SOPClassUID: "1.2.3"})
+	assert.ErrorContains(err, "1.2.3 is not accepted")
+	<-scp.done
+}
+
+func TestRetrieveDICOMweb(t *testing.T) {
+	assert := assert.New(t)
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dicomweb/studies/1.2/series/1.2.3/instances/1.2.3.4" {
+			http.NotFound(w, r)
+			return
+		}
+		accept = r.Header.Get("Accept")
+		var body bytes.Buffer
+		parts := multipart.NewWriter(&body)
+		part, _ := parts.CreatePart(map[string][]string{"Content-Type": {"application/dicom"}})
+		part.Write([]byte("archived file"))
+		parts.Close()
+		w.Header().Set("Content-Type", `multipart/related; type="application/dicom"; boundary=`+parts.Boundary())
+		w.Write(body.Bytes())
+	}))
+	defer server.Close()
+
+	node := Node{URL: server.URL + "/dicomweb/"}
+	file, err := Retrieve(node, Instance{StudyInstanceUID: "1.2", SeriesInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3.4"})
+	require.NoError(t, err)
+	assert.Equal("archived file", string(file))
+	assert.Contains(accept, `type="application/dicom"`)
+
+	_, err = Retrieve(node, Instance{StudyInstanceUID: "1.2", SeriesInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3.5"})
+	assert.ErrorContains(err, "instance 1.2.3.5 not found")
+}
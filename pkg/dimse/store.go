@@ -0,0 +1,233 @@
+package dimse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prefix of the storage SOP classes accepted by a Listener
+const storageClassPrefix = "1.2.840.10008.5.1.4.1.1."
+
+// the parts of a DICOM file sent in a C-STORE
+type storeFile struct {
+	SOPClassUID       string
+	SOPInstanceUID    string
+	TransferSyntaxUID string
+	Dataset           []byte // the dataset after the file meta information, in the transfer syntax
+}
+
+// splits a DICOM file with preamble into its file meta information and the dataset, PS3.10 7.1
+func parseFile(file []byte) (storeFile, error) {
+	if len(file) < 144 || string(file[128:132]) != "DICM" {
+		return storeFile{}, errors.New("not a DICOM file with preamble")
+	}
+	var f storeFile
+	meta := file[132:]
+	for len(meta) >= 8 && binary.LittleEndian.Uint16(meta) == 0x0002 {
+		element, vr := binary.LittleEndian.Uint16(meta[2:]), string(meta[4:6])
+		header, length := 8, int(binary.LittleEndian.Uint16(meta[6:]))
+		if vr == "OB" || vr == "OW" || vr == "UN" || vr == "SQ" || vr == "UT" {
+			if len(meta) < 12 {
+				break
+			}
+			header, length = 12, int(binary.LittleEndian.Uint32(meta[8:]))
+		}
+		if len(meta) < header+length {
+			return storeFile{}, errors.New("truncated file meta information")
+		}
+		value := trimUID(meta[header : header+length])
+		switch element {
+		case 0x0002:
+			f.SOPClassUID = value
+		case 0x0003:
+			f.SOPInstanceUID = value
+		case 0x0010:
+			f.TransferSyntaxUID = value
+		}
+		meta = meta[header+length:]
+	}
+	if f.SOPClassUID == "" || f.SOPInstanceUID == "" || f.TransferSyntaxUID == "" {
+		return storeFile{}, errors.New("file meta information without SOP class, instance or transfer syntax")
+	}
+	f.Dataset = meta
+	return f, nil
+}
+
+// sends the DICOM file to the node with a C-STORE in the transfer syntax of the file, or with STOW-RS to a DICOMweb
+// node
+func Store(node Node, file []byte) error {
+	if node.Host == "" {
+		return storeDICOMweb(node.URL, file)
+	}
+	f, err := parseFile(file)
+	if err != nil {
+		return err
+	}
+	a, err := associate(node, []presentationContext{
+		{ID: 1, AbstractSyntax: f.SOPClassUID, TransferSyntaxes: []string{f.TransferSyntaxUID}},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	request := Command{Field: CommandStoreRQ, MessageID: a.nextMessageID(), SOPClassUID: f.SOPClassUID,
+		SOPInstanceUID: f.SOPInstanceUID}
+	if err := a.send(f.SOPClassUID, request, f.Dataset); err != nil {
+		a.Abort()
+		return err
+	}
+	response, _, _, err := a.receive()
+	if err != nil {
+		a.Abort()
+		return err
+	}
+	if err := responseError(response); err != nil {
+		a.Abort()
+		return err
+	}
+	return a.Release()
+}
+
+// stores the file with STOW-RS, PS3.18 10.5
+func storeDICOMweb(baseURL string, file []byte) error {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	part, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/dicom"}})
+	if err != nil {
+		return err
+	}
+	part.Write(file)
+	parts.Close()
+	request, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/studies", &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", `multipart/related; type="application/dicom"; boundary=`+parts.Boundary())
+	request.Header.Set("Accept", "application/dicom+json")
+	client := http.Client{Timeout: Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("STOW-RS store failed with %s", response.Status)
+	}
+	return nil
+}
+
+// a storage SCP receiving instances with C-STORE, and answering C-ECHO
+type Listener struct {
+	AET   string // the called AE title accepted, any if empty
+	Store func(instance Instance, file []byte) error
+	wg    sync.WaitGroup
+}
+
+// accepts associations on the listener until it is closed, each on its own goroutine. Store is called with the UIDs of
+// each received instance and the instance as DICOM file in the transfer syntax it was sent, possibly from several
+// goroutines at once, an error of it fails the C-STORE. Returns after all associations ended.
+func (l *Listener) Serve(listener net.Listener) error {
+	defer l.wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			defer conn.Close()
+			l.serve(conn)
+		}()
+	}
+}
+
+// runs an association on the connection, accepting the verification and all storage SOP classes with the first
+// proposed transfer syntax
+func (l *Listener) serve(conn net.Conn) error {
+	a := &Association{conn: conn, contexts: make(map[string]byte), syntaxes: make(map[byte]string),
+		maxLength: defaultMaxPDULength}
+	if address, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		a.node.Host, a.node.Port = address.IP.String(), address.Port
+	}
+	conn.SetDeadline(time.Now().Add(Timeout))
+	pduType, payload, err := readPDU(conn)
+	if err != nil {
+		return err
+	}
+	if pduType != pduAssociateRQ {
+		a.Abort()
+		return fmt.Errorf("unexpected %s", pduName(pduType))
+	}
+	rq, err := decodeAssociate(payload)
+	if err != nil {
+		a.Abort()
+		return err
+	}
+	a.node.AET = rq.CallingAE
+	a.traceAssociate(Received, "A-ASSOCIATE-RQ", rq, nil)
+	if l.AET != "" && rq.CalledAE != l.AET {
+		a.trace(Sent, "A-ASSOCIATE-RJ called AE title not recognized")
+		return writePDU(conn, pduAssociateRJ, []byte{0, 1, 1, 7})
+	}
+	if rq.MaxLength > 0 && rq.MaxLength < maxAcceptedPDULength {
+		a.maxLength = rq.MaxLength
+	}
+	ac := associatePDU{CalledAE: rq.CalledAE, CallingAE: rq.CallingAE, MaxLength: defaultMaxPDULength,
+		ImplementationUID: implementationUID, ImplementationName: implementationName}
+	proposed := make(map[byte]string)
+	for _, pc := range rq.Contexts {
+		proposed[pc.ID] = pc.AbstractSyntax
+		accepted := presentationContext{ID: pc.ID, Result: 3, TransferSyntaxes: []string{implicitVRLittle}} // abstract syntax not supported
+		if (pc.AbstractSyntax == VerificationClass || strings.HasPrefix(pc.AbstractSyntax, storageClassPrefix)) && len(pc.TransferSyntaxes) > 0 {
+			accepted.Result, accepted.TransferSyntaxes = 0, pc.TransferSyntaxes[:1]
+			if pc.AbstractSyntax == VerificationClass && slices.Contains(pc.TransferSyntaxes, implicitVRLittle) {
+				accepted.TransferSyntaxes = []string{implicitVRLittle}
+			}
+			a.contexts[pc.AbstractSyntax] = pc.ID
+			a.syntaxes[pc.ID] = accepted.TransferSyntaxes[0]
+		}
+		ac.Contexts = append(ac.Contexts, accepted)
+	}
+	a.traceAssociate(Sent, "A-ASSOCIATE-AC", ac, proposed)
+	if err := writePDU(conn, pduAssociateAC, ac.encode(true)); err != nil {
+		return err
+	}
+	for {
+		request, data, contextID, err := a.receive()
+		if errors.Is(err, errReleaseRequested) {
+			a.trace(Sent, "A-RELEASE-RP")
+			return writePDU(conn, pduReleaseRP, make([]byte, 4))
+		} else if err != nil {
+			return err
+		}
+		response := Command{Field: request.Field | 0x8000, RespondingTo: request.MessageID, SOPClassUID: request.SOPClassUID,
+			SOPInstanceUID: request.SOPInstanceUID}
+		switch request.Field {
+		case CommandEchoRQ:
+		case CommandStoreRQ:
+			instance := Instance{SOPClassUID: request.SOPClassUID, SOPInstanceUID: request.SOPInstanceUID,
+				TransferSyntaxUID: a.syntaxes[contextID]}
+			file := fileBytes(instance.SOPClassUID, instance.SOPInstanceUID, instance.TransferSyntaxUID, data)
+			if err := l.Store(instance, file); err != nil {
+				response.Status, response.ErrorComment = StatusProcessingFailure, err.Error()
+			}
+		default:
+			response.Status = StatusUnrecognized
+		}
+		if err := a.send(request.SOPClassUID, response, nil); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package dimse
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestStore(t *testing.T) {
+	assert := assert.New(t)
+	const ctImage = "1.2.840.10008.5.1.4.1.1.2"
+	dataset, err := encodeDataset([]*dicom.Element{stringElement(tag.SOPInstanceUID, "UI", "1.2.3.4")})
+	require.NoError(t, err)
+	file := fileBytes(ctImage, "1.2.3.4", explicitVRLittle, dataset)
+
+	var mu sync.Mutex
+	var received [][]byte
+	var instance Instance
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	scp := &Listener{AET: "STORE", Store: func(i Instance, file []byte) error {
+		mu.Lock()
+		instance = i
+		defer mu.Unlock()
+		received = append(received, file)
+		if len(received) > 1 {
+			return errors.New("disk full")
+		}
+		return nil
+	}}
+	done := make(chan error, 1)
+	go func() { done <- scp.Serve(listener) }()
+	node := Node{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port, AET: "STORE"}
+
+	_, err = Ping(node)
+	assert.NoError(err)
+	require.NoError(t, Store(node, file))
+	require.Len(t, received, 1)
+	assert.Equal(file, received[0], "the transfer syntax of the file is kept")
+	assert.Equal(Instance{SOPClassUID: ctImage, SOPInstanceUID: "1.2.3.4", TransferSyntaxUID: explicitVRLittle}, instance)
+	assert.ErrorContains(Store(node, file), "C-STORE failed with status 0x0110, disk full")
+	node.AET = "OTHER"
+	assert.ErrorContains(Store(node, file), "called AE title not recognized")
+	assert.ErrorContains(Store(node, []byte("no DICOM")), "not a DICOM file")
+
+	listener.Close()
+	assert.NoError(<-done)
+}
+
+func TestStoreDICOMweb(t *testing.T) {
+	assert := assert.New(t)
+	var stored []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if r.URL.Path != "/dicomweb/studies" || err != nil || mediaType != "multipart/related" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err == nil {
+			stored, _ = io.ReadAll(part)
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, Store(Node{URL: server.URL + "/dicomweb/"}, []byte("file")))
+	assert.Equal("file", string(stored))
+	assert.ErrorContains(Store(Node{URL: server.URL + "/other"}, []byte("file")), "STOW-RS store failed with 400")
+}
@@ -0,0 +1,97 @@
+package dimse
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+)
+
+// direction of a traced event
+type Direction int
+
+const (
+	Local    Direction = iota // e.g. connecting or an error
+	Sent                      // to the peer
+	Received                  // from the peer
+)
+
+// an event of an association: a PDU, a DIMSE message or a connection state, for debugging interoperability problems
+type Event struct {
+	Time      time.Time
+	Peer      string // the node as "AET@host:port"
+	Direction Direction
+	Text      string
+}
+
+// called with the events of all associations if not nil, possibly from several goroutines at once. The events are
+// also logged at debug level.
+var Trace func(event Event)
+
+// returns the event as line, e.g. "12:00:01.123 -> MWL@ris:104 A-RELEASE-RQ"
+func (e Event) String() string {
+	arrow := "  "
+	switch e.Direction {
+	case Sent:
+		arrow = "->"
+	case Received:
+		arrow = "<-"
+	}
+	return fmt.Sprintf("%s %s %s %s", e.Time.Format("15:04:05.000"), arrow, e.Peer, e.Text)
+}
+
+func (a *Association) trace(direction Direction, format string, args ...any) {
+	event := Event{Time: time.Now(), Peer: a.node.String(), Direction: direction, Text: fmt.Sprintf(format, args...)}
+	slog.Debug("dimse", "peer", event.Peer, "direction", []string{"local", "sent", "received"}[direction], "event", event.Text)
+	if Trace != nil {
+		Trace(event)
+	}
+}
+
+// traces an A-ASSOCIATE-RQ or A-ASSOCIATE-AC with a line per presentation context, the abstract syntaxes of an
+// A-ASSOCIATE-AC are taken from the proposed ones
+func (a *Association) traceAssociate(direction Direction, name string, p associatePDU, proposed map[byte]string) {
+	maxLength := "unlimited"
+	if p.MaxLength > 0 {
+		maxLength = fmt.Sprint(p.MaxLength)
+	}
+	text := fmt.Sprintf("%s called %s, calling %s, max PDU %s", name, p.CalledAE, p.CallingAE, maxLength)
+	if p.ImplementationUID != "" {
+		text += strings.TrimSpace(fmt.Sprintf(", implementation %s %s", p.ImplementationUID, p.ImplementationName))
+	}
+	a.trace(direction, "%s", text)
+	for _, pc := range p.Contexts {
+		syntax := pc.AbstractSyntax
+		if syntax == "" {
+			syntax = proposed[pc.ID]
+		}
+		var transferSyntaxes []string
+		for _, ts := range pc.TransferSyntaxes {
+			transferSyntaxes = append(transferSyntaxes, dicomtree.UIDShortName(ts))
+		}
+		text := fmt.Sprintf("  context %d %s (%s) %v", pc.ID, dicomtree.UIDShortName(syntax), syntax, transferSyntaxes)
+		if name == "A-ASSOCIATE-AC" {
+			text += ": " + contextResult(pc.Result)
+		}
+		a.trace(direction, "%s", text)
+	}
+}
+
+// returns the result of a presentation context in an A-ASSOCIATE-AC, PS3.8 9.3.3.2
+func contextResult(result byte) string {
+	switch result {
+	case 0:
+		return "accepted"
+	case 1:
+		return "rejected by the user"
+	case 2:
+		return "rejected without reason"
+	case 3:
+		return "abstract syntax not supported"
+	case 4:
+		return "transfer syntaxes not supported"
+	}
+	return fmt.Sprintf("result %d", result)
+}
@@ -0,0 +1,44 @@
+package dimse
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	scp, node := startFakeSCP(t, 0)
+	var mu sync.Mutex
+	var lines []string
+	Trace = func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event.Peer == node.String() {
+			lines = append(lines, strings.SplitN(event.String(), " ", 2)[1]) // without time
+		}
+	}
+	t.Cleanup(func() { Trace = nil })
+
+	_, err := Ping(node)
+	require.NoError(t, err)
+	require.NoError(t, <-scp.done)
+	mu.Lock()
+	defer mu.Unlock()
+	peer := node.String()
+	assert.Equal([]string{
+		"   " + peer + " connecting",
+		"-> " + peer + " A-ASSOCIATE-RQ called MWL, calling DCMTAGGER, max PDU 16384, implementation 1.2.826.0.1.3680043.10.1404.1 DCMTAGGER",
+		"-> " + peer + "   context 1 Verification (1.2.840.10008.1.1) [Implicit VR LE]",
+		"<- " + peer + " A-ASSOCIATE-AC called MWL, calling DCMTAGGER, max PDU unlimited",
+		"<- " + peer + "   context 1 Verification (1.2.840.10008.1.1) [Implicit VR LE]: accepted",
+		"-> " + peer + " C-ECHO-RQ, message 1, class 1.2.840.10008.1.1",
+		"<- " + peer + " C-ECHO-RSP, status 0x0000, responding to 1, class 1.2.840.10008.1.1",
+		"-> " + peer + " A-RELEASE-RQ",
+		"<- " + peer + " A-RELEASE-RP",
+	}, lines)
+}
@@ -0,0 +1,77 @@
+package dimse
+
+import (
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const WorklistModel = "1.2.840.10008.5.1.4.31" // Modality Worklist Information Model - FIND
+
+// matching keys of a worklist query, empty keys match everything. Names and IDs may contain the wildcards * and ?,
+// the date is YYYYMMDD or a range YYYYMMDD-YYYYMMDD.
+type WorklistQuery struct {
+	PatientName string
+	PatientID   string
+	Date        string // scheduled procedure step start date
+	Modality    string
+	StationAET  string // scheduled station AE title
+}
+
+// return keys of a worklist query besides the matching keys, PS3.4 K.6.1.2.2
+var (
+	worklistKeys = []tag.Tag{
+		tag.SpecificCharacterSet, tag.AccessionNumber, tag.ReferringPhysicianName, tag.PatientBirthDate, tag.PatientSex,
+		tag.StudyInstanceUID, tag.RequestedProcedureDescription, tag.RequestedProcedureID,
+	}
+	stepKeys = []tag.Tag{
+		tag.ScheduledProcedureStepStartTime, tag.ScheduledPerformingPhysicianName, tag.ScheduledProcedureStepDescription,
+		tag.ScheduledStationName, tag.ScheduledProcedureStepID,
+	}
+)
+
+// returns the identifier of the query with the matching keys and the return keys of the worklist and its scheduled
+// procedure step
+func (q WorklistQuery) Identifier() []*dicom.Element {
+	step := []*dicom.Element{
+		stringElement(tag.ScheduledStationAETitle, "AE", q.StationAET),
+		stringElement(tag.ScheduledProcedureStepStartDate, "DA", q.Date),
+		stringElement(tag.Modality, "CS", q.Modality),
+	}
+	for _, t := range stepKeys {
+		step = append(step, stringElement(t, dictionaryVR(t), ""))
+	}
+	identifier := []*dicom.Element{
+		stringElement(tag.PatientName, "PN", q.PatientName),
+		stringElement(tag.PatientID, "LO", q.PatientID),
+	}
+	for _, t := range worklistKeys {
+		identifier = append(identifier, stringElement(t, dictionaryVR(t), ""))
+	}
+	value, _ := dicom.NewValue([][]*dicom.Element{step})
+	sequence := &dicom.Element{Tag: tag.ScheduledProcedureStepSequence, RawValueRepresentation: "SQ", Value: value}
+	return append(identifier, sequence)
+}
+
+// returns an element with a single string value, without value if empty
+func stringElement(t tag.Tag, vr string, value string) *dicom.Element {
+	values := []string{}
+	if value != "" {
+		values = []string{value}
+	}
+	v, _ := dicom.NewValue(values)
+	return &dicom.Element{Tag: t, RawValueRepresentation: vr, Value: v}
+}
+
+// queries the worklist of the node and returns the scheduled procedure steps matching the query
+func QueryWorklist(node Node, query WorklistQuery) ([]dicom.Dataset, error) {
+	a, err := Associate(node, WorklistModel)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := a.Find(WorklistModel, query.Identifier())
+	if err != nil {
+		a.Abort()
+		return nil, err
+	}
+	return matches, a.Release()
+}
@@ -0,0 +1,203 @@
+package dimse
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// an SCP accepting one association on a local port, answering C-ECHOs and C-FINDs with the matches and C-GETs with
+// C-STORE sub-operations of the matches, the client sends PDUs of at most maxLength bytes
+type fakeSCP struct {
+	listener   net.Listener
+	maxLength  uint32
+	matches    [][]*dicom.Element
+	calledAE   string
+	identifier []*dicom.Element // received with the C-FIND-RQ or C-GET-RQ
+	scpRoles   []string         // proposed with the A-ASSOCIATE-RQ
+	done       chan error
+}
+
+func startFakeSCP(t *testing.T, maxLength uint32, matches ...[]*dicom.Element) (*fakeSCP, Node) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	scp := &fakeSCP{listener: listener, maxLength: maxLength, matches: matches, calledAE: "MWL", done: make(chan error, 1)}
+	go func() { scp.done <- scp.serve() }()
+	address := listener.Addr().(*net.TCPAddr)
+	return scp, Node{Host: "127.0.0.1", Port: address.Port, AET: "MWL"}
+}
+
+func (s *fakeSCP) serve() error {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, payload, err := readPDU(conn)
+	if err != nil {
+		return err
+	}
+	rq, err := decodeAssociate(payload)
+	if err != nil {
+		return err
+	}
+	if rq.CalledAE != s.calledAE {
+		return writePDU(conn, pduAssociateRJ, []byte{0, 1, 1, 7})
+	}
+	ac := associatePDU{CalledAE: rq.CalledAE, CallingAE: rq.CallingAE, MaxLength: s.maxLength}
+	s.scpRoles = rq.SCPRoles
+	a := &Association{conn: conn, contexts: make(map[string]byte), maxLength: rq.MaxLength}
+	var storageClass string
+	for _, pc := range rq.Contexts {
+		result := byte(3) // abstract syntax not supported
+		switch {
+		case strings.HasPrefix(pc.AbstractSyntax, "1.2.840.10008.5.1.4.1.1."):
+			storageClass = pc.AbstractSyntax
+			fallthrough
+		case pc.AbstractSyntax == WorklistModel || pc.AbstractSyntax == VerificationClass || pc.AbstractSyntax == GetModel:
+			result = 0
+			a.contexts[pc.AbstractSyntax] = pc.ID
+		}
+		ac.Contexts = append(ac.Contexts, presentationContext{ID: pc.ID, Result: result, TransferSyntaxes: []string{implicitVRLittle}})
+	}
+	if err := writePDU(conn, pduAssociateAC, ac.encode(true)); err != nil {
+		return err
+	}
+
+	for {
+		request, data, _, err := a.receive()
+		if errors.Is(err, errReleaseRequested) {
+			return writePDU(conn, pduReleaseRP, make([]byte, 4))
+		} else if err != nil {
+			return err
+		}
+		response := Command{Field: request.Field | 0x8000, RespondingTo: request.MessageID, SOPClassUID: request.SOPClassUID}
+		if request.Field == CommandFindRQ {
+			if s.identifier, err = decodeDataset(data); err != nil {
+				return err
+			}
+			response.Status = StatusPending
+			for _, match := range s.matches {
+				encoded, err := encodeDataset(match)
+				if err != nil {
+					return err
+				}
+				if err := a.send(request.SOPClassUID, response, encoded); err != nil {
+					return err
+				}
+			}
+			response.Status = StatusSuccess
+		}
+		if request.Field == CommandGetRQ {
+			if s.identifier, err = decodeDataset(data); err != nil {
+				return err
+			}
+			response.hasSubOperations = true
+			for i, match := range s.matches {
+				encoded, err := encodeDataset(match)
+				if err != nil {
+					return err
+				}
+				instance := dicom.Dataset{Elements: match}
+				store := Command{Field: CommandStoreRQ, MessageID: uint16(i + 1), SOPClassUID: storageClass,
+					SOPInstanceUID: dicomtree.UIDValue(&instance, tag.SOPInstanceUID)}
+				if err := a.send(storageClass, store, encoded); err != nil {
+					return err
+				}
+				stored, _, _, err := a.receive()
+				if err != nil {
+					return err
+				}
+				if stored.Status == StatusSuccess {
+					response.Completed++
+				} else {
+					response.Failed++
+					response.Status = StatusWarning
+				}
+			}
+		}
+		if err := a.send(request.SOPClassUID, response, nil); err != nil {
+			return err
+		}
+	}
+}
+
+func TestQueryWorklist(t *testing.T) {
+	assert := assert.New(t)
+	value := func(elements []*dicom.Element, t tag.Tag) string {
+		dataset := dicom.Dataset{Elements: elements}
+		e, err := dataset.FindElementByTag(t)
+		if err != nil {
+			return "missing"
+		}
+		return dicomtree.ValueText(e, nil)
+	}
+
+	step := []*dicom.Element{
+		stringElement(tag.Modality, "CS", "MR"),
+		stringElement(tag.ScheduledProcedureStepStartDate, "DA", "20240102"),
+	}
+	sequence, err := dicom.NewValue([][]*dicom.Element{step})
+	require.NoError(t, err)
+	match := []*dicom.Element{
+		stringElement(tag.PatientName, "PN", "Doe^John"),
+		stringElement(tag.PatientID, "LO", "12345"),
+		stringElement(tag.AccessionNumber, "SH", strings.Repeat("A", 16)),
+		{Tag: tag.ScheduledProcedureStepSequence, RawValueRepresentation: "SQ", Value: sequence},
+	}
+	scp, node := startFakeSCP(t, 64, match, match) // fragments the messages into several PDUs
+
+	matches, err := QueryWorklist(node, WorklistQuery{PatientName: "Doe*", Date: "20240102", Modality: "MR"})
+	require.NoError(t, err)
+	require.NoError(t, <-scp.done)
+	require.Len(t, matches, 2)
+	assert.Equal("Doe^John", value(matches[0].Elements, tag.PatientName))
+	assert.Equal("12345", value(matches[1].Elements, tag.PatientID))
+	items := dicomtree.SequenceItems(matches[0].Elements[3])
+	require.Len(t, items, 1)
+	assert.Equal([]string{"MR"}, items[0][0].Value.GetValue())
+
+	assert.Equal("Doe*", value(scp.identifier, tag.PatientName))
+	assert.Equal("", value(scp.identifier, tag.PatientID))
+	identifier := dicom.Dataset{Elements: scp.identifier}
+	e, err := identifier.FindElementByTag(tag.ScheduledProcedureStepSequence)
+	require.NoError(t, err)
+	items = dicomtree.SequenceItems(e)
+	require.Len(t, items, 1)
+	assert.Equal("20240102", value(items[0], tag.ScheduledProcedureStepStartDate))
+	assert.Equal("MR", value(items[0], tag.Modality))
+
+	scp, node = startFakeSCP(t, 0)
+	node.AET = "OTHER"
+	_, err = QueryWorklist(node, WorklistQuery{})
+	assert.ErrorContains(err, "called AE title not recognized")
+	assert.NoError(<-scp.done)
+}
+
+func TestCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	request := Command{Field: CommandFindRQ, MessageID: 7, SOPClassUID: WorklistModel, HasDataset: true}
+	decoded, err := decodeCommand(request.encode())
+	require.NoError(t, err)
+	assert.Equal(request, decoded)
+	assert.Equal("C-FIND-RQ, message 7, class 1.2.840.10008.5.1.4.31, with dataset", decoded.String())
+
+	response := Command{Field: CommandFindRSP, RespondingTo: 7, Status: 0xA700, ErrorComment: "out of resources"}
+	decoded, err = decodeCommand(response.encode())
+	require.NoError(t, err)
+	assert.Equal(response, decoded)
+	assert.False(decoded.Pending())
+	assert.EqualError(responseError(decoded), "C-FIND failed with status 0xa700, out of resources")
+
+	_, err = decodeCommand([]byte{0, 0, 0, 1, 8})
+	assert.Error(err)
+}
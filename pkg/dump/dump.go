@@ -0,0 +1,194 @@
+// Package dump writes datasets as plain text lines or in the DICOM JSON model, e.g. when the output is piped.
+package dump
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+)
+
+// an element as line of Text
+type Line struct {
+	File  string `json:"file"`
+	Path  string `json:"path"` // tag with the path of its sequence and item, e.g. "(0008,1115)/1/(0020,000e)"
+	Name  string `json:"name,omitempty"`
+	VR    string `json:"vr"`
+	Value string `json:"value"`
+}
+
+// returns the line "<file> <path> <keyword> [<VR>] <value>", without the file if it is empty
+func (l Line) String() string {
+	var fields []string
+	if l.File != "" {
+		fields = append(fields, l.File)
+	}
+	fields = append(fields, l.Path)
+	if l.Name != "" {
+		fields = append(fields, l.Name)
+	}
+	return strings.Join(append(fields, "["+l.VR+"]", l.Value), " ")
+}
+
+// returns a line per element of the entry, nested elements after their sequence
+func Lines(entry *dicomtree.Entry) []Line {
+	return elementLines(entry.Filename, "", entry.Dataset.Elements, charset.FromDataset(&entry.Dataset))
+}
+
+// writes one line per element "<file> <tag> <keyword> [<VR>] <value>", nested elements with the path of their
+// sequence and item, e.g. "a.dcm (0008,1115)/1/(0020,000e) SeriesInstanceUID [UI] 1.2.3". Only lines containing
+// the search text (case insensitive) are written, all if it is empty.
+func Text(w io.Writer, entries []dicomtree.Entry, search string) error {
+	for i := range entries {
+		for _, line := range Lines(&entries[i]) {
+			if !matches(line.String(), search) {
+				continue
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func elementLines(file, prefix string, elements []*dicom.Element, charsets []string) []Line {
+	var lines []Line
+	for _, e := range elements {
+		path := prefix + dicomtree.FormatTag(e.Tag)
+		lines = append(lines, Line{file, path, dicomtree.TagName(e), e.RawValueRepresentation, ValueText(e, charsets)})
+		for i, item := range dicomtree.SequenceItems(e) {
+			lines = append(lines, elementLines(file, fmt.Sprintf("%s/%d/", path, i+1), item, charsets)...)
+		}
+	}
+	return lines
+}
+
+// returns the complete decoded value, only the size of binary values
+func ValueText(e *dicom.Element, charsets []string) string {
+	if dicomtree.IsSequence(e) {
+		return fmt.Sprintf("%d items", len(dicomtree.SequenceItems(e)))
+	}
+	if e.Value != nil {
+		switch e.Value.ValueType() {
+		case dicom.Bytes:
+			return fmt.Sprintf("<%d bytes>", e.ValueLength)
+		case dicom.PixelData:
+			return "<pixel data>"
+		}
+	}
+	return dicomtree.ValueText(e, charsets)
+}
+
+func matches(line string, search string) bool {
+	return search == "" || strings.Contains(strings.ToLower(line), strings.ToLower(search))
+}
+
+type jsonEntry struct {
+	File    string                 `json:"file"`
+	Dataset map[string]jsonElement `json:"dataset"`
+}
+
+// an attribute of the DICOM JSON model (PS3.18 F.2), pixel data is left out
+type jsonElement struct {
+	VR           string        `json:"vr"`
+	Value        []interface{} `json:"Value,omitempty"`
+	InlineBinary string        `json:"InlineBinary,omitempty"`
+}
+
+// writes the entries as JSON array of objects with the filename and the dataset in the DICOM JSON model. Only top
+// level elements with a text line (see Text) containing the search text are written, all if it is empty.
+func JSON(w io.Writer, entries []dicomtree.Entry, search string) error {
+	result := make([]jsonEntry, 0, len(entries))
+	for i := range entries {
+		charsets := charset.FromDataset(&entries[i].Dataset)
+		var elements []*dicom.Element
+		for _, e := range entries[i].Dataset.Elements {
+			for _, line := range elementLines("", "", []*dicom.Element{e}, charsets) {
+				if matches(line.String(), search) {
+					elements = append(elements, e)
+					break
+				}
+			}
+		}
+		result = append(result, jsonEntry{File: entries[i].Filename, Dataset: jsonDataset(elements, charsets)})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// returns the top level elements of the dataset in the DICOM JSON model for encoding/json, text values decoded
+func DatasetJSON(dataset *dicom.Dataset) interface{} {
+	return jsonDataset(dataset.Elements, charset.FromDataset(dataset))
+}
+
+func jsonDataset(elements []*dicom.Element, charsets []string) map[string]jsonElement {
+	dataset := make(map[string]jsonElement, len(elements))
+	for _, e := range elements {
+		dataset[fmt.Sprintf("%04X%04X", e.Tag.Group, e.Tag.Element)] = jsonValue(e, charsets)
+	}
+	return dataset
+}
+
+func jsonValue(e *dicom.Element, charsets []string) jsonElement {
+	result := jsonElement{VR: e.RawValueRepresentation}
+	if dicomtree.IsSequence(e) {
+		for _, item := range dicomtree.SequenceItems(e) {
+			result.Value = append(result.Value, jsonDataset(item, charsets))
+		}
+		return result
+	}
+	if e.Value == nil {
+		return result
+	}
+	switch e.Value.ValueType() {
+	case dicom.Strings:
+		for _, v := range dicomtree.ValueStrings(e) {
+			v = strings.TrimRight(v, "\x00 ")
+			if charset.IsText(e) {
+				v = charset.Decode(v, charsets)
+			}
+			result.Value = append(result.Value, jsonString(e.RawValueRepresentation, v))
+		}
+	case dicom.Ints:
+		for _, v := range e.Value.GetValue().([]int) {
+			result.Value = append(result.Value, v)
+		}
+	case dicom.Floats:
+		for _, v := range e.Value.GetValue().([]float64) {
+			result.Value = append(result.Value, v)
+		}
+	case dicom.Bytes:
+		result.InlineBinary = base64.StdEncoding.EncodeToString(e.Value.GetValue().([]byte))
+	}
+	return result
+}
+
+// returns a string value as JSON value: numbers for IS and DS, person names with their component groups
+func jsonString(vr string, v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	switch vr {
+	case "IS", "DS":
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f
+		}
+	case "PN":
+		name := make(map[string]string)
+		for i, group := range dicomtree.SplitPersonName(v) {
+			if group != "" {
+				name[dicomtree.PersonNameGroups[i]] = group
+			}
+		}
+		return name
+	}
+	return v
+}
@@ -0,0 +1,100 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newTestEntries(t *testing.T) []dicomtree.Entry {
+	modality := mustElement(t, tag.Modality, []string{"CT"})
+	modality.RawValueRepresentation = "CS"
+	name := mustElement(t, tag.PatientName, []string{"Doe^John"})
+	name.RawValueRepresentation = "PN"
+	rows := mustElement(t, tag.Rows, []int{512})
+	rows.RawValueRepresentation = "US"
+	series := mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"})
+	series.RawValueRepresentation = "UI"
+	sequence := mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{{series}})
+	sequence.RawValueRepresentation = "SQ"
+	return []dicomtree.Entry{{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{modality, sequence, name, rows}}}}
+}
+
+func TestText(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	assert.NoError(Text(&out, newTestEntries(t), ""))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(lines, 5)
+	assert.True(strings.HasPrefix(lines[0], "a.dcm (0008,0060) "))
+	assert.True(strings.HasSuffix(lines[0], "[CS] CT"))
+	assert.True(strings.HasSuffix(lines[1], "[SQ] 1 items"))
+	assert.True(strings.HasPrefix(lines[2], "a.dcm (0008,1115)/1/(0020,000e) "))
+	assert.True(strings.HasSuffix(lines[2], "1.2.3"))
+
+	out.Reset()
+	assert.NoError(Text(&out, newTestEntries(t), "doe^"))
+	assert.Equal(1, strings.Count(out.String(), "\n"))
+	assert.Contains(out.String(), "(0010,0010)")
+}
+
+func TestLines(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := newTestEntries(t)
+	lines := Lines(&entries[0])
+	assert.Len(lines, 5)
+	assert.Equal("a.dcm", lines[2].File)
+	assert.Equal("(0008,1115)/1/(0020,000e)", lines[2].Path)
+	assert.Equal("UI", lines[2].VR)
+	assert.Equal("1.2.3", strings.TrimRight(lines[2].Value, "\x00"))
+	lines[2].File = ""
+	assert.True(strings.HasPrefix(lines[2].String(), "(0008,1115)/1/(0020,000e) "), "without the file")
+}
+
+func TestJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	assert.NoError(JSON(&out, newTestEntries(t), ""))
+	type jsonResult []struct {
+		File    string
+		Dataset map[string]struct {
+			VR    string `json:"vr"`
+			Value []interface{}
+		}
+	}
+	var result jsonResult
+	assert.NoError(json.Unmarshal(out.Bytes(), &result))
+	assert.Len(result, 1)
+	assert.Equal("a.dcm", result[0].File)
+	dataset := result[0].Dataset
+	assert.Equal([]interface{}{"CT"}, dataset["00080060"].Value)
+	assert.Equal([]interface{}{map[string]interface{}{"Alphabetic": "Doe^John"}}, dataset["00100010"].Value)
+	assert.Equal([]interface{}{float64(512)}, dataset["00280010"].Value)
+	assert.Equal("SQ", dataset["00081115"].VR)
+	assert.Equal(map[string]interface{}{"0020000E": map[string]interface{}{"vr": "UI", "Value": []interface{}{"1.2.3"}}},
+		dataset["00081115"].Value[0])
+
+	out.Reset()
+	assert.NoError(JSON(&out, newTestEntries(t), "1.2.3"))
+	var filtered jsonResult
+	assert.NoError(json.Unmarshal(out.Bytes(), &filtered))
+	assert.Len(filtered[0].Dataset, 1, "sequence with matching nested element")
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
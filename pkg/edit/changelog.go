@@ -0,0 +1,103 @@
+package edit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// a changed top level element, the old value is empty for added and the new value for removed elements
+type Change struct {
+	Time     time.Time `json:"time"`
+	File     string    `json:"file"`
+	Tag      string    `json:"tag"`
+	Name     string    `json:"name"`
+	OldValue string    `json:"oldValue"`
+	NewValue string    `json:"newValue"`
+}
+
+// values of the top level elements of a dataset before an edit, see Changelog.Record
+type Snapshot struct {
+	values map[tag.Tag]string
+}
+
+func TakeSnapshot(dataset *dicom.Dataset) Snapshot {
+	charsets := charset.FromDataset(dataset)
+	values := make(map[tag.Tag]string, len(dataset.Elements))
+	for _, e := range dataset.Elements {
+		values[e.Tag] = dicomtree.ValueText(e, charsets)
+	}
+	return Snapshot{values}
+}
+
+// all changes done in a session in the order they were made
+type Changelog struct {
+	Changes []Change
+}
+
+// adds the differences between the snapshot and the current state of the dataset, returns the number of changes
+func (l *Changelog) Record(file string, before Snapshot, dataset *dicom.Dataset) int {
+	changes := Diff(file, before, dataset)
+	for _, c := range changes {
+		slog.Info("edited element", "file", file, "tag", c.Tag, "old", c.OldValue, "new", c.NewValue)
+	}
+	l.Changes = append(l.Changes, changes...)
+	return len(changes)
+}
+
+// returns the differences between the snapshot and the current state of the dataset without recording them, the
+// removed elements last
+func Diff(file string, before Snapshot, dataset *dicom.Dataset) []Change {
+	now := time.Now()
+	after := TakeSnapshot(dataset)
+	var changes []Change
+	add := func(t tag.Tag, oldValue, newValue string) {
+		changes = append(changes, Change{now, file, dicomtree.FormatTag(t), dicomtree.TagNameByTag(t), oldValue, newValue})
+	}
+
+	for _, e := range dataset.Elements {
+		oldValue, existed := before.values[e.Tag]
+		if newValue := after.values[e.Tag]; !existed || oldValue != newValue {
+			add(e.Tag, oldValue, newValue)
+		}
+	}
+	removed := make([]tag.Tag, 0)
+	for t := range before.values {
+		if _, ok := after.values[t]; !ok {
+			removed = append(removed, t)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return dicomtree.TagOrder(removed[i]) < dicomtree.TagOrder(removed[j]) })
+	for _, t := range removed {
+		add(t, before.values[t], "")
+	}
+	return changes
+}
+
+func (l *Changelog) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	changes := l.Changes
+	if changes == nil {
+		changes = []Change{}
+	}
+	return encoder.Encode(changes)
+}
+
+func (l *Changelog) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "file", "tag", "name", "old value", "new value"})
+	for _, c := range l.Changes {
+		writer.Write([]string{c.Time.Format(time.RFC3339), c.File, c.Tag, c.Name, c.OldValue, c.NewValue})
+	}
+	writer.Flush()
+	return writer.Error()
+}
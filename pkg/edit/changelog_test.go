@@ -0,0 +1,71 @@
+package edit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestChangelogRecord(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientID, []string{"123"}),
+		mustElement(t, tag.PatientName, []string{"Doe^John"}),
+	}}
+	var log Changelog
+	assert.Equal(0, log.Record("a.dcm", TakeSnapshot(&dataset), &dataset))
+
+	before := TakeSnapshot(&dataset)
+	_, err := Set(&dataset, tag.Modality, []string{"MR"})
+	assert.NoError(err)
+	_, err = Set(&dataset, tag.InstitutionName, []string{"Hospital"})
+	assert.NoError(err)
+	Delete(&dataset, tag.PatientID)
+	assert.Equal(3, log.Record("a.dcm", before, &dataset))
+
+	changes := log.Changes
+	assert.Equal("(0008,0060)", changes[0].Tag)
+	assert.Equal([2]string{"CT", "MR"}, [2]string{changes[0].OldValue, changes[0].NewValue})
+	assert.Equal("(0008,0080)", changes[1].Tag)
+	assert.Equal([2]string{"", "Hospital"}, [2]string{changes[1].OldValue, changes[1].NewValue})
+	assert.Equal("(0010,0020)", changes[2].Tag)
+	assert.Equal([2]string{"123", ""}, [2]string{changes[2].OldValue, changes[2].NewValue})
+	assert.Equal("a.dcm", changes[2].File)
+	assert.False(changes[2].Time.IsZero())
+}
+
+func TestChangelogExport(t *testing.T) {
+	assert := assert.New(t)
+
+	var log Changelog
+	var out bytes.Buffer
+	assert.NoError(log.WriteJSON(&out))
+	assert.Equal("[]\n", out.String())
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.Modality, []string{"CT"})}}
+	before := TakeSnapshot(&dataset)
+	_, err := Set(&dataset, tag.Modality, []string{"MR, US"})
+	assert.NoError(err)
+	log.Record("a.dcm", before, &dataset)
+
+	out.Reset()
+	assert.NoError(log.WriteJSON(&out))
+	var changes []Change
+	assert.NoError(json.Unmarshal(out.Bytes(), &changes))
+	assert.Equal("MR, US", changes[0].NewValue)
+
+	out.Reset()
+	assert.NoError(log.WriteCSV(&out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(lines, 2)
+	assert.Equal("time,file,tag,name,old value,new value", lines[0])
+	assert.Contains(lines[1], `,a.dcm,"(0008,0060)",`)
+	assert.True(strings.HasSuffix(lines[1], `,CT,"MR, US"`), lines[1])
+}
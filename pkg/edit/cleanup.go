@@ -0,0 +1,104 @@
+package edit
+
+import (
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+type CleanupOptions struct {
+	// also remove empty attributes that are not Type 1 or 2 of the IOD, only done for known SOP classes
+	RemoveEmpty bool
+}
+
+// prepares the dataset for writing: removes the retired group length elements (gggg,0000) except the file meta
+// information group length and trailing padding of string values, which is added again by the writer.
+// Returns the number of removed or changed elements.
+func Cleanup(dataset *dicom.Dataset, opts CleanupOptions) (int, error) {
+	var required map[tag.Tag]bool
+	if opts.RemoveEmpty {
+		required = validate.RequiredTags(dataset)
+	}
+	elements, changed, err := cleanupElements(dataset.Elements, required, opts.RemoveEmpty && required != nil, true)
+	dataset.Elements = elements
+	return changed, err
+}
+
+func cleanupElements(elements []*dicom.Element, required map[tag.Tag]bool, removeEmpty, topLevel bool) ([]*dicom.Element, int, error) {
+	changed := 0
+	cleaned := make([]*dicom.Element, 0, len(elements))
+	for _, e := range elements {
+		if e.Tag.Element == 0x0000 && e.Tag != tag.FileMetaInformationGroupLength {
+			changed++
+			continue
+		}
+		// attributes within sequence items are never removed, their type depends on the macro
+		if removeEmpty && topLevel && e.Tag.Group != 0x0002 && !required[e.Tag] && isEmpty(e) {
+			changed++
+			continue
+		}
+		switch {
+		case dicomtree.IsSequence(e):
+			items := dicomtree.SequenceItems(e)
+			itemsChanged := 0
+			for i, item := range items {
+				cleanedItem, n, err := cleanupElements(item, required, removeEmpty, false)
+				if err != nil {
+					return elements, changed, err
+				}
+				items[i], itemsChanged = cleanedItem, itemsChanged+n
+			}
+			if itemsChanged > 0 {
+				if err := setItems(e, items); err != nil {
+					return elements, changed, err
+				}
+				changed += itemsChanged
+			}
+		case e.Value != nil && e.Value.ValueType() == dicom.Strings:
+			values := e.Value.GetValue().([]string)
+			trimmed := make([]string, len(values))
+			padded := false
+			for i, v := range values {
+				trimmed[i] = strings.TrimRight(v, " \x00")
+				padded = padded || trimmed[i] != v
+			}
+			if padded {
+				value, err := dicom.NewValue(trimmed)
+				if err != nil {
+					return elements, changed, err
+				}
+				e.Value = value
+				changed++
+			}
+		}
+		cleaned = append(cleaned, e)
+	}
+	return cleaned, changed, nil
+}
+
+func isEmpty(e *dicom.Element) bool {
+	if e.Value == nil {
+		return true
+	}
+	switch e.Value.ValueType() {
+	case dicom.Strings:
+		for _, v := range e.Value.GetValue().([]string) {
+			if strings.TrimRight(v, " \x00") != "" {
+				return false
+			}
+		}
+		return true
+	case dicom.Bytes:
+		return len(e.Value.GetValue().([]byte)) == 0
+	case dicom.Ints:
+		return len(e.Value.GetValue().([]int)) == 0
+	case dicom.Floats:
+		return len(e.Value.GetValue().([]float64)) == 0
+	case dicom.Sequences:
+		return len(dicomtree.SequenceItems(e)) == 0
+	}
+	return false
+}
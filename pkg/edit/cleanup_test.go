@@ -0,0 +1,46 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newCleanupTestDataset(t *testing.T) dicom.Dataset {
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.FileMetaInformationGroupLength, []int{100}),
+		mustElement(t, tag.Tag{Group: 0x0008, Element: 0x0000}, []int{42}),
+		mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.7\x00"}),
+		mustElement(t, tag.AccessionNumber, []string{""}),
+		mustElement(t, tag.StudyDescription, []string{"  "}),
+		mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{{
+			mustElement(t, tag.Tag{Group: 0x0020, Element: 0x0000}, []int{8}),
+			mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"}),
+		}}),
+		mustElement(t, tag.PatientName, []string{"Doe^John "}),
+	}}
+}
+
+func TestCleanup(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newCleanupTestDataset(t)
+	changed, err := Cleanup(&dataset, CleanupOptions{})
+	assert.NoError(err)
+	assert.Equal(5, changed, "2 group lengths and 3 padded values")
+	assert.Len(dataset.Elements, 6)
+	assert.Equal(tag.FileMetaInformationGroupLength, dataset.Elements[0].Tag)
+	assert.Equal([]string{"1.2.840.10008.5.1.4.1.1.7"}, dataset.Elements[1].Value.GetValue())
+	assert.Equal("{(0020,000e)=1.2.3}", dicomtree.ValueText(dataset.Elements[4], nil))
+	assert.Equal([]string{"Doe^John"}, dataset.Elements[5].Value.GetValue())
+
+	dataset = newCleanupTestDataset(t)
+	_, err = Cleanup(&dataset, CleanupOptions{RemoveEmpty: true})
+	assert.NoError(err)
+	assert.Len(dataset.Elements, 5)
+	assert.Equal(tag.AccessionNumber, dataset.Elements[2].Tag, "Type 2 of the secondary capture IOD")
+	assert.Equal(tag.ReferencedSeriesSequence, dataset.Elements[3].Tag, "empty Type 3 attribute is removed")
+}
@@ -0,0 +1,125 @@
+// Package edit contains the mutations of datasets done by the viewer and the scripting.
+package edit
+
+import (
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// inserts the element into the top level of the dataset, keeping the elements sorted by tag
+func InsertSorted(dataset *dicom.Dataset, element *dicom.Element) {
+	dataset.Elements = insertSorted(dataset.Elements, element)
+}
+
+func insertSorted(elements []*dicom.Element, element *dicom.Element) []*dicom.Element {
+	idx := len(elements)
+	for i, e := range elements {
+		if dicomtree.TagOrder(e.Tag) > dicomtree.TagOrder(element.Tag) {
+			idx = i
+			break
+		}
+	}
+	elements = append(elements, nil)
+	copy(elements[idx+1:], elements[idx:])
+	elements[idx] = element
+	return elements
+}
+
+// sets the string values of the element, encoded with the given specific character set, fails for values the
+// character set can't represent
+func SetStrings(element *dicom.Element, values []string, charsets []string) error {
+	encoded := make([]string, 0, len(values))
+	for _, v := range values {
+		e, err := charset.Encode(v, charsets)
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, e)
+	}
+	value, err := dicom.NewValue(encoded)
+	if err != nil {
+		return err
+	}
+	element.Value = value
+	return nil
+}
+
+// sets the value of the top level element with the given tag, the element is created if not present.
+// The data has to be supported by dicom.NewValue, e.g. []string, []int or []float64.
+func Set(dataset *dicom.Dataset, t tag.Tag, data interface{}) (*dicom.Element, error) {
+	elements, e, err := setElement(dataset.Elements, t, data)
+	if err != nil {
+		return nil, err
+	}
+	dataset.Elements = elements
+	return e, nil
+}
+
+func setElement(elements []*dicom.Element, t tag.Tag, data interface{}) ([]*dicom.Element, *dicom.Element, error) {
+	for _, e := range elements {
+		if e.Tag == t {
+			value, err := dicom.NewValue(data)
+			if err != nil {
+				return elements, nil, err
+			}
+			e.Value = value
+			return elements, e, nil
+		}
+	}
+	e, err := dicom.NewElement(t, data)
+	if err != nil {
+		return elements, nil, err
+	}
+	return insertSorted(elements, e), e, nil
+}
+
+// removes the top level element with the given tag and returns whether it was present
+func Delete(dataset *dicom.Dataset, t tag.Tag) bool {
+	elements, deleted := deleteElement(dataset.Elements, t)
+	dataset.Elements = elements
+	return deleted
+}
+
+func deleteElement(elements []*dicom.Element, t tag.Tag) ([]*dicom.Element, bool) {
+	for i, e := range elements {
+		if e.Tag == t {
+			return append(elements[:i], elements[i+1:]...), true
+		}
+	}
+	return elements, false
+}
+
+// re-encodes all text values of the dataset to UTF-8 and sets the specific character set accordingly
+func ConvertToUTF8(dataset *dicom.Dataset) (int, error) {
+	charsets := charset.FromDataset(dataset)
+	converted := 0
+	for _, e := range dataset.Elements {
+		if !charset.IsText(e) {
+			continue
+		}
+		values := e.Value.GetValue().([]string)
+		decodedValues := make([]string, 0, len(values))
+		changed := false
+		for _, v := range values {
+			decoded := charset.Decode(v, charsets)
+			changed = changed || decoded != v
+			decodedValues = append(decodedValues, decoded)
+		}
+		if !changed {
+			continue
+		}
+		value, err := dicom.NewValue(decodedValues)
+		if err != nil {
+			return converted, err
+		}
+		e.Value = value
+		converted++
+	}
+
+	if _, err := Set(dataset, tag.SpecificCharacterSet, []string{charset.UTF8}); err != nil {
+		return converted, err
+	}
+	return converted, nil
+}
@@ -0,0 +1,83 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestSetAndDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientID, []string{"123"}),
+	}}
+
+	e, err := Set(&dataset, tag.PatientName, []string{"Doe^John"})
+	assert.NoError(err)
+	assert.Len(dataset.Elements, 3)
+	assert.Same(e, dataset.Elements[1], "inserted sorted by tag")
+
+	_, err = Set(&dataset, tag.Modality, []string{"MR"})
+	assert.NoError(err)
+	assert.Len(dataset.Elements, 3)
+	assert.Equal([]string{"MR"}, dataset.Elements[0].Value.GetValue())
+
+	assert.True(Delete(&dataset, tag.PatientID))
+	assert.False(Delete(&dataset, tag.PatientID))
+	assert.Len(dataset.Elements, 2)
+}
+
+func TestSetStrings(t *testing.T) {
+	assert := assert.New(t)
+
+	e := mustElement(t, tag.PatientName, []string{""})
+	assert.NoError(SetStrings(e, []string{"Jérôme"}, []string{"ISO_IR 100"}))
+	assert.Equal([]string{"J\xe9r\xf4me"}, e.Value.GetValue())
+}
+
+func TestConvertToUTF8(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SpecificCharacterSet, []string{"ISO_IR 100"}),
+		mustElement(t, tag.PatientName, []string{"Buc^J\xe9r\xf4me"}),
+	}}
+	dataset.Elements[1].RawValueRepresentation = "PN"
+
+	converted, err := ConvertToUTF8(&dataset)
+	assert.NoError(err)
+	assert.Equal(1, converted)
+	assert.Equal([]string{"Buc^Jérôme"}, dataset.Elements[1].Value.GetValue())
+	assert.Equal([]string{charset.UTF8}, charset.FromDataset(&dataset))
+}
+
+func TestSetSOPInstanceUID(t *testing.T) {
+	assert := assert.New(t)
+
+	uid := NewUID()
+	assert.Regexp(`^2\.25\.[1-9][0-9]*$`, uid)
+	assert.LessOrEqual(len(uid), 64)
+	assert.NotEqual(uid, NewUID())
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.MediaStorageSOPInstanceUID, []string{"1.2.3"}),
+		mustElement(t, tag.SOPInstanceUID, []string{"1.2.3"}),
+	}}
+	assert.NoError(SetSOPInstanceUID(&dataset, uid))
+	assert.Equal([]string{uid}, dataset.Elements[0].Value.GetValue())
+	assert.Equal([]string{uid}, dataset.Elements[1].Value.GetValue())
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
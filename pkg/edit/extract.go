@@ -0,0 +1,42 @@
+package edit
+
+import (
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// tags every extracted dataset keeps, so it is still a DICOM object with readable text values
+var ExtractKeptTags = []tag.Tag{
+	tag.SpecificCharacterSet, tag.SOPClassUID, tag.SOPInstanceUID, tag.StudyInstanceUID, tag.SeriesInstanceUID,
+}
+
+// returns a new dataset with copies of the top level elements of the dataset with the tags, the file meta
+// information and ExtractKeptTags, e.g. as minimal sample of a bug report. Tags missing in the dataset are skipped.
+func Extract(dataset *dicom.Dataset, tags []tag.Tag) (dicom.Dataset, error) {
+	wanted := make(map[tag.Tag]bool, len(tags)+len(ExtractKeptTags))
+	for _, t := range append(ExtractKeptTags, tags...) {
+		wanted[t] = true
+	}
+	var elements []*dicom.Element
+	for _, e := range dataset.Elements {
+		if e.Tag.Group == 0x0002 || wanted[e.Tag] {
+			elements = append(elements, e)
+		}
+	}
+	copies, err := copyElements(elements)
+	if err != nil {
+		return dicom.Dataset{}, err
+	}
+	return dicom.Dataset{Elements: copies}, nil
+}
+
+// returns the tags of the top level elements of the dataset without the pixel data, for a header-only stub
+func HeaderTags(dataset *dicom.Dataset) []tag.Tag {
+	var tags []tag.Tag
+	for _, e := range dataset.Elements {
+		if e.Tag.Group != 0x7fe0 {
+			tags = append(tags, e.Tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,31 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustElement(t, tag.SOPInstanceUID, []string{"1.2.3"}),
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientName, []string{"Doe^John"}),
+		mustElement(t, tag.PixelData, []int{0}),
+	}}
+	extracted, err := Extract(&dataset, []tag.Tag{tag.Modality, tag.StudyDate})
+	assert.NoError(err)
+	var tags []tag.Tag
+	for _, e := range extracted.Elements {
+		tags = append(tags, e.Tag)
+	}
+	assert.Equal([]tag.Tag{tag.TransferSyntaxUID, tag.SOPInstanceUID, tag.Modality}, tags, "file meta and UIDs kept")
+	assert.NotSame(dataset.Elements[2], extracted.Elements[2], "copies")
+
+	assert.Equal([]tag.Tag{tag.TransferSyntaxUID, tag.SOPInstanceUID, tag.Modality, tag.PatientName}, HeaderTags(&dataset))
+}
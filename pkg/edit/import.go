@@ -0,0 +1,74 @@
+package edit
+
+import (
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// tags of the modules that can be imported by name, e.g. to restore the demographics stripped by a modality
+var ImportModules = map[string][]tag.Tag{
+	"patient": {
+		tag.PatientName, tag.PatientID, tag.IssuerOfPatientID, tag.PatientBirthDate, tag.PatientBirthTime,
+		tag.PatientSex, tag.OtherPatientIDs, tag.OtherPatientNames, tag.PatientComments,
+	},
+	"study": {
+		tag.StudyDate, tag.StudyTime, tag.AccessionNumber, tag.ReferringPhysicianName, tag.StudyDescription,
+		tag.StudyInstanceUID, tag.StudyID,
+	},
+}
+
+// returns the tags of the given module names and tags (keyword or group,element), the patient module if none given
+func ImportTags(names []string) ([]tag.Tag, error) {
+	if len(names) == 0 {
+		return ImportModules["patient"], nil
+	}
+	var tags []tag.Tag
+	for _, name := range names {
+		if module, ok := ImportModules[strings.ToLower(name)]; ok {
+			tags = append(tags, module...)
+			continue
+		}
+		t, err := dicomtree.ParseTag(name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// copies the top level elements with the given tags from the source into the dataset, replacing present elements.
+// Text values are re-encoded to the specific character set of the dataset. Tags missing in the source are skipped,
+// the copied tags are returned.
+func Import(dataset *dicom.Dataset, source *dicom.Dataset, tags []tag.Tag) ([]tag.Tag, error) {
+	sourceCharsets, charsets := charset.FromDataset(source), charset.FromDataset(dataset)
+	var imported []tag.Tag
+	for _, t := range tags {
+		e, err := source.FindElementByTag(t)
+		if err != nil {
+			continue
+		}
+		copies, err := copyElements([]*dicom.Element{e})
+		if err != nil {
+			return imported, err
+		}
+		if charset.IsText(e) && strings.Join(sourceCharsets, "\\") != strings.Join(charsets, "\\") {
+			values := dicomtree.ValueStrings(e)
+			decoded := make([]string, 0, len(values))
+			for _, v := range values {
+				decoded = append(decoded, charset.Decode(v, sourceCharsets))
+			}
+			if err := SetStrings(copies[0], decoded, charsets); err != nil {
+				return imported, err
+			}
+		}
+		dataset.Elements, _ = deleteElement(dataset.Elements, t)
+		dataset.Elements = insertSorted(dataset.Elements, copies[0])
+		imported = append(imported, t)
+	}
+	return imported, nil
+}
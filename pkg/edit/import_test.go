@@ -0,0 +1,39 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestImport(t *testing.T) {
+	assert := assert.New(t)
+
+	tags, err := ImportTags([]string{"Patient", "0008,0050"})
+	assert.NoError(err)
+	assert.Equal(ImportModules["patient"], tags[:len(tags)-1])
+	assert.Equal(tag.AccessionNumber, tags[len(tags)-1])
+	_, err = ImportTags([]string{"nope"})
+	assert.Error(err)
+
+	source := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SpecificCharacterSet, []string{"ISO_IR 100"}),
+		mustElement(t, tag.PatientName, []string{"J\xe9r\xf4me"}),
+		mustElement(t, tag.PatientID, []string{"123"}),
+	}}
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SpecificCharacterSet, []string{"ISO_IR 192"}),
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientName, []string{"ANONYMOUS"}),
+	}}
+	source.Elements[1].RawValueRepresentation = "PN"
+	imported, err := Import(&dataset, &source, []tag.Tag{tag.PatientName, tag.PatientID, tag.PatientSex})
+	assert.NoError(err)
+	assert.Equal([]tag.Tag{tag.PatientName, tag.PatientID}, imported, "missing tags are skipped")
+	assert.Len(dataset.Elements, 4)
+	assert.Equal([]string{"Jérôme"}, dataset.Elements[2].Value.GetValue(), "re-encoded to UTF-8")
+	assert.Equal([]string{"123"}, dataset.Elements[3].Value.GetValue())
+	assert.NotSame(source.Elements[2], dataset.Elements[3])
+}
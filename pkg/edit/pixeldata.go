@@ -0,0 +1,138 @@
+package edit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const explicitVRLittleEndian = "1.2.840.10008.1.2.1"
+
+// transfer syntaxes with native pixel data, others are switched to explicit VR little endian by ReplacePixelData
+var nativeTransferSyntaxes = map[string]bool{
+	"1.2.840.10008.1.2":   true,
+	"1.2.840.10008.1.2.1": true,
+	"1.2.840.10008.1.2.2": true,
+}
+
+// loads a PNG or JPEG image, or a headerless RAW file (.raw) with the given size. RAW files are read as 8 bit gray,
+// 16 bit little endian gray or 8 bit RGB, depending on their length.
+func LoadImage(path string, columns, rows int) (image.Image, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".raw" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return rawImage(data, columns, rows)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+func rawImage(data []byte, columns, rows int) (image.Image, error) {
+	if columns <= 0 || rows <= 0 {
+		return nil, fmt.Errorf("missing size of RAW image")
+	}
+	rect := image.Rect(0, 0, columns, rows)
+	pixels := columns * rows
+	switch len(data) {
+	case pixels:
+		return &image.Gray{Pix: data, Stride: columns, Rect: rect}, nil
+	case 2 * pixels:
+		img := image.NewGray16(rect)
+		for i := 0; i < pixels; i++ {
+			img.SetGray16(i%columns, i/columns, color.Gray16{Y: binary.LittleEndian.Uint16(data[2*i:])})
+		}
+		return img, nil
+	case 3 * pixels:
+		img := image.NewRGBA(rect)
+		for i := 0; i < pixels; i++ {
+			img.SetRGBA(i%columns, i/columns, color.RGBA{data[3*i], data[3*i+1], data[3*i+2], 0xff})
+		}
+		return img, nil
+	}
+	return nil, fmt.Errorf("RAW image has %d bytes, expected %d, %d or %d for %dx%d pixels", len(data), pixels, 2*pixels, 3*pixels, columns, rows)
+}
+
+type pixelAttribute struct {
+	tag  tag.Tag
+	data interface{}
+}
+
+// installs the image as single frame native pixel data and updates the image pixel attributes accordingly.
+// Gray images are stored as MONOCHROME2 with 8 or 16 bits, all others as 8 bit RGB.
+func ReplacePixelData(dataset *dicom.Dataset, img image.Image) error {
+	bounds := img.Bounds()
+	columns, rows := bounds.Dx(), bounds.Dy()
+	samples, bits, photometric := 3, 8, "RGB"
+	switch img.(type) {
+	case *image.Gray:
+		samples, photometric = 1, "MONOCHROME2"
+	case *image.Gray16:
+		samples, bits, photometric = 1, 16, "MONOCHROME2"
+	}
+
+	data := make([][]int, 0, columns*rows)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			switch samples {
+			case 1:
+				gray := color.Gray16Model.Convert(img.At(x, y)).(color.Gray16)
+				data = append(data, []int{int(gray.Y) >> (16 - bits)})
+			default:
+				rgb := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				data = append(data, []int{int(rgb.R), int(rgb.G), int(rgb.B)})
+			}
+		}
+	}
+	pixelData := dicom.PixelDataInfo{Frames: []frame.Frame{{
+		NativeData: frame.NativeFrame{Data: data, Rows: rows, Cols: columns, BitsPerSample: bits},
+	}}}
+
+	attributes := []pixelAttribute{
+		{tag.SamplesPerPixel, []int{samples}},
+		{tag.PhotometricInterpretation, []string{photometric}},
+		{tag.Rows, []int{rows}},
+		{tag.Columns, []int{columns}},
+		{tag.BitsAllocated, []int{bits}},
+		{tag.BitsStored, []int{bits}},
+		{tag.HighBit, []int{bits - 1}},
+		{tag.PixelRepresentation, []int{0}},
+		{tag.PixelData, pixelData},
+	}
+	if samples == 3 {
+		attributes = append(attributes, pixelAttribute{tag.PlanarConfiguration, []int{0}})
+	} else {
+		Delete(dataset, tag.PlanarConfiguration)
+	}
+	Delete(dataset, tag.NumberOfFrames)
+	if e, err := dataset.FindElementByTag(tag.TransferSyntaxUID); err == nil {
+		if values := dicomtree.ValueStrings(e); len(values) == 0 || !nativeTransferSyntaxes[strings.TrimRight(values[0], "\x00 ")] {
+			if _, err := Set(dataset, tag.TransferSyntaxUID, []string{explicitVRLittleEndian}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, a := range attributes {
+		if _, err := Set(dataset, a.tag, a.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
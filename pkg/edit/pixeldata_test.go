@@ -0,0 +1,76 @@
+package edit
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestLoadImage(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	rawFile := filepath.Join(dir, "image.raw")
+	assert.NoError(os.WriteFile(rawFile, []byte{0, 1, 2, 3, 4, 5, 6, 7}, 0o644))
+	img, err := LoadImage(rawFile, 2, 2)
+	assert.NoError(err)
+	assert.Equal(color.Gray16{Y: 0x0302}, img.At(1, 0), "16 bit little endian")
+	_, err = LoadImage(rawFile, 3, 3)
+	assert.Error(err)
+
+	pngFile := filepath.Join(dir, "image.png")
+	f, err := os.Create(pngFile)
+	assert.NoError(err)
+	assert.NoError(png.Encode(f, image.NewRGBA(image.Rect(0, 0, 4, 3))))
+	assert.NoError(f.Close())
+	img, err = LoadImage(pngFile, 0, 0)
+	assert.NoError(err)
+	assert.Equal(image.Rect(0, 0, 4, 3), img.Bounds())
+}
+
+func TestReplacePixelData(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.4.50"}),
+		mustElement(t, tag.PlanarConfiguration, []int{0}),
+		mustElement(t, tag.NumberOfFrames, []string{"10"}),
+	}}
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.SetGray(2, 1, color.Gray{Y: 200})
+	assert.NoError(ReplacePixelData(&dataset, img))
+
+	value := func(t tag.Tag) interface{} {
+		e, err := dataset.FindElementByTag(t)
+		if !assert.NoError(err) {
+			return nil
+		}
+		return e.Value.GetValue()
+	}
+	assert.Equal([]string{explicitVRLittleEndian}, value(tag.TransferSyntaxUID), "compressed transfer syntax is replaced")
+	assert.Equal([]string{"MONOCHROME2"}, value(tag.PhotometricInterpretation))
+	assert.Equal([]int{2}, value(tag.Rows))
+	assert.Equal([]int{3}, value(tag.Columns))
+	assert.Equal([]int{8}, value(tag.BitsAllocated))
+	assert.Equal([]int{7}, value(tag.HighBit))
+	assert.Equal([]int{1}, value(tag.SamplesPerPixel))
+	_, err := dataset.FindElementByTag(tag.PlanarConfiguration)
+	assert.Error(err)
+	_, err = dataset.FindElementByTag(tag.NumberOfFrames)
+	assert.Error(err)
+	pixelData := value(tag.PixelData).(dicom.PixelDataInfo)
+	assert.Len(pixelData.Frames, 1)
+	assert.Equal([]int{200}, pixelData.Frames[0].NativeData.Data[5])
+
+	assert.NoError(ReplacePixelData(&dataset, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	assert.Equal([]string{"RGB"}, value(tag.PhotometricInterpretation))
+	assert.Equal([]int{3}, value(tag.SamplesPerPixel))
+	assert.Equal([]int{0}, value(tag.PlanarConfiguration))
+}
@@ -0,0 +1,60 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// returned by ProtectedTags.Check for protected tags
+var ErrProtected = errors.New("tag is protected")
+
+// tags that define the type and the pixel data structure of a dataset, changing them usually corrupts the file
+var DefaultProtectedTags = []tag.Tag{
+	tag.MediaStorageSOPClassUID,
+	tag.TransferSyntaxUID,
+	tag.SOPClassUID,
+	tag.SamplesPerPixel,
+	tag.PhotometricInterpretation,
+	tag.PlanarConfiguration,
+	tag.NumberOfFrames,
+	tag.Rows,
+	tag.Columns,
+	tag.BitsAllocated,
+	tag.BitsStored,
+	tag.HighBit,
+	tag.PixelRepresentation,
+	tag.PixelData,
+}
+
+// set of tags that must not be modified without explicit override
+type ProtectedTags map[tag.Tag]bool
+
+func NewProtectedTags(tags []tag.Tag) ProtectedTags {
+	protected := make(ProtectedTags, len(tags))
+	for _, t := range tags {
+		protected[t] = true
+	}
+	return protected
+}
+
+// returns an error wrapping ErrProtected if the tag is protected
+func (p ProtectedTags) Check(t tag.Tag) error {
+	if p[t] {
+		return fmt.Errorf("%s %s: %w", dicomtree.FormatTag(t), dicomtree.TagNameByTag(t), ErrProtected)
+	}
+	return nil
+}
+
+// returns the protected tags sorted by group and element
+func (p ProtectedTags) Tags() []tag.Tag {
+	tags := make([]tag.Tag, 0, len(p))
+	for t := range p {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return dicomtree.TagOrder(tags[i]) < dicomtree.TagOrder(tags[j]) })
+	return tags
+}
@@ -0,0 +1,25 @@
+package edit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestProtectedTags(t *testing.T) {
+	assert := assert.New(t)
+
+	protected := NewProtectedTags(DefaultProtectedTags)
+	err := protected.Check(tag.TransferSyntaxUID)
+	assert.True(errors.Is(err, ErrProtected))
+	assert.Contains(err.Error(), "(0002,0010)")
+	assert.NoError(protected.Check(tag.PatientName))
+
+	delete(protected, tag.TransferSyntaxUID)
+	protected[tag.PatientName] = true
+	assert.NoError(protected.Check(tag.TransferSyntaxUID))
+	assert.Error(protected.Check(tag.PatientName))
+	assert.Equal([]tag.Tag{tag.SOPClassUID, tag.PatientName}, protected.Tags()[1:3])
+}
@@ -0,0 +1,142 @@
+package edit
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// appends an empty item to the sequence element and returns its index
+func AddItem(sequence *dicom.Element) (int, error) {
+	items, err := sequenceItems(sequence)
+	if err != nil {
+		return 0, err
+	}
+	items = append(items, []*dicom.Element{})
+	return len(items) - 1, setItems(sequence, items)
+}
+
+// inserts a deep copy of the item after it and returns the index of the copy
+func DuplicateItem(sequence *dicom.Element, index int) (int, error) {
+	items, err := sequenceItem(sequence, index)
+	if err != nil {
+		return 0, err
+	}
+	duplicate, err := copyElements(items[index])
+	if err != nil {
+		return 0, err
+	}
+	items = append(items[:index+1], append([][]*dicom.Element{duplicate}, items[index+1:]...)...)
+	return index + 1, setItems(sequence, items)
+}
+
+func DeleteItem(sequence *dicom.Element, index int) error {
+	items, err := sequenceItem(sequence, index)
+	if err != nil {
+		return err
+	}
+	return setItems(sequence, append(items[:index], items[index+1:]...))
+}
+
+// sets the value of the element with the given tag in the item, the element is created if not present, see Set
+func SetInItem(sequence *dicom.Element, index int, t tag.Tag, data interface{}) (*dicom.Element, error) {
+	items, err := sequenceItem(sequence, index)
+	if err != nil {
+		return nil, err
+	}
+	elements, e, err := setElement(items[index], t, data)
+	if err != nil {
+		return nil, err
+	}
+	items[index] = elements
+	return e, setItems(sequence, items)
+}
+
+// removes the element with the given tag from the item and returns whether it was present
+func DeleteFromItem(sequence *dicom.Element, index int, t tag.Tag) (bool, error) {
+	items, err := sequenceItem(sequence, index)
+	if err != nil {
+		return false, err
+	}
+	elements, deleted := deleteElement(items[index], t)
+	if !deleted {
+		return false, nil
+	}
+	items[index] = elements
+	return true, setItems(sequence, items)
+}
+
+func sequenceItems(sequence *dicom.Element) ([][]*dicom.Element, error) {
+	if !dicomtree.IsSequence(sequence) {
+		return nil, fmt.Errorf("%s is no sequence", dicomtree.FormatTag(sequence.Tag))
+	}
+	return dicomtree.SequenceItems(sequence), nil
+}
+
+func sequenceItem(sequence *dicom.Element, index int) ([][]*dicom.Element, error) {
+	items, err := sequenceItems(sequence)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(items) {
+		return nil, fmt.Errorf("%s has no item %d", dicomtree.FormatTag(sequence.Tag), index+1)
+	}
+	return items, nil
+}
+
+func setItems(sequence *dicom.Element, items [][]*dicom.Element) error {
+	value, err := dicom.NewValue(items)
+	if err != nil {
+		return err
+	}
+	sequence.Value = value
+	return nil
+}
+
+// returns a deep copy of the dataset to try an edit on, e.g. for a preview. Pixel data is shared.
+func CopyDataset(dataset *dicom.Dataset) (dicom.Dataset, error) {
+	elements, err := copyElements(dataset.Elements)
+	return dicom.Dataset{Elements: elements}, err
+}
+
+// returns deep copies of the elements including nested sequences
+func copyElements(elements []*dicom.Element) ([]*dicom.Element, error) {
+	copies := make([]*dicom.Element, 0, len(elements))
+	for _, e := range elements {
+		c := *e
+		if e.Value != nil {
+			var data interface{}
+			switch e.Value.ValueType() {
+			case dicom.Strings:
+				data = append([]string{}, e.Value.GetValue().([]string)...)
+			case dicom.Ints:
+				data = append([]int{}, e.Value.GetValue().([]int)...)
+			case dicom.Floats:
+				data = append([]float64{}, e.Value.GetValue().([]float64)...)
+			case dicom.Bytes:
+				data = append([]byte{}, e.Value.GetValue().([]byte)...)
+			case dicom.Sequences:
+				items := make([][]*dicom.Element, 0)
+				for _, item := range dicomtree.SequenceItems(e) {
+					itemCopy, err := copyElements(item)
+					if err != nil {
+						return nil, err
+					}
+					items = append(items, itemCopy)
+				}
+				data = items
+			}
+			if data != nil {
+				value, err := dicom.NewValue(data)
+				if err != nil {
+					return nil, err
+				}
+				c.Value = value
+			}
+		}
+		copies = append(copies, &c)
+	}
+	return copies, nil
+}
@@ -0,0 +1,60 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newTestSequence(t *testing.T) *dicom.Element {
+	return mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{{
+		mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"}),
+		mustElement(t, tag.ReferencedImageSequence, [][]*dicom.Element{{
+			mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3.1"}),
+		}}),
+	}})
+}
+
+func TestSequenceItems(t *testing.T) {
+	assert := assert.New(t)
+
+	sequence := newTestSequence(t)
+	index, err := AddItem(sequence)
+	assert.NoError(err)
+	assert.Equal(1, index)
+	assert.Len(dicomtree.SequenceItems(sequence), 2)
+	assert.Empty(dicomtree.SequenceItems(sequence)[1])
+
+	e, err := SetInItem(sequence, 1, tag.SeriesInstanceUID, []string{"1.2.4"})
+	assert.NoError(err)
+	assert.Same(e, dicomtree.SequenceItems(sequence)[1][0])
+	_, err = SetInItem(sequence, 2, tag.SeriesInstanceUID, []string{"1.2.4"})
+	assert.Error(err)
+
+	index, err = DuplicateItem(sequence, 0)
+	assert.NoError(err)
+	assert.Equal(1, index)
+	items := dicomtree.SequenceItems(sequence)
+	assert.Len(items, 3)
+	assert.Equal("{(0020,000e)=1.2.3, (0008,1140)={(0008,1155)=1.2.3.1}}{(0020,000e)=1.2.3, (0008,1140)={(0008,1155)=1.2.3.1}}{(0020,000e)=1.2.4}",
+		dicomtree.ValueText(sequence, nil))
+	assert.NotSame(items[0][0], items[1][0], "deep copy")
+	nestedOriginal, nestedCopy := dicomtree.SequenceItems(items[0][1])[0][0], dicomtree.SequenceItems(items[1][1])[0][0]
+	assert.NotSame(nestedOriginal, nestedCopy, "nested sequences are copied as well")
+
+	deleted, err := DeleteFromItem(sequence, 1, tag.SeriesInstanceUID)
+	assert.NoError(err)
+	assert.True(deleted)
+	assert.Len(dicomtree.SequenceItems(sequence)[1], 1)
+	assert.Len(dicomtree.SequenceItems(sequence)[0], 2, "original item unchanged")
+
+	assert.NoError(DeleteItem(sequence, 0))
+	assert.Len(dicomtree.SequenceItems(sequence), 2)
+	assert.Equal("1.2.4", dicomtree.ValueText(dicomtree.SequenceItems(sequence)[1][0], nil))
+
+	_, err = AddItem(mustElement(t, tag.PatientName, []string{"Doe"}))
+	assert.Error(err, "no sequence")
+}
@@ -0,0 +1,126 @@
+package edit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// pixel data elements removed by StripPixelData: float, double float and integer pixel data
+var pixelDataTags = []tag.Tag{{Group: 0x7fe0, Element: 0x0008}, {Group: 0x7fe0, Element: 0x0009}, tag.PixelData}
+
+// removes the pixel data of the dataset, or with zero replaces it by native frames of zeros of the size given by the
+// image pixel attributes, switching an encapsulated transfer syntax to explicit VR little endian. Elements are
+// replaced, never changed, so a shallow copy of a dataset can be stripped. Returns whether the dataset changed.
+func StripPixelData(dataset *dicom.Dataset, zero bool) (bool, error) {
+	stripped := false
+	for _, t := range pixelDataTags {
+		if Delete(dataset, t) {
+			stripped = true
+		}
+	}
+	if !zero || !stripped {
+		return stripped, nil
+	}
+	return stripped, zeroPixelData(dataset)
+}
+
+// sets native pixel data of zeros of the size given by the image pixel attributes, see StripPixelData
+func zeroPixelData(dataset *dicom.Dataset) error {
+	number := func(t tag.Tag, defaultValue int) int {
+		e, err := dataset.FindElementByTag(t)
+		if err != nil {
+			return defaultValue
+		}
+		if values := dicomtree.ValueStrings(e); len(values) > 0 {
+			if n, err := strconv.Atoi(strings.TrimSpace(values[0])); err == nil {
+				return n
+			}
+		}
+		return defaultValue
+	}
+	rows, columns, samples := number(tag.Rows, 0), number(tag.Columns, 0), number(tag.SamplesPerPixel, 1)
+	bits, frames := number(tag.BitsAllocated, 16), number(tag.NumberOfFrames, 1)
+	if rows <= 0 || columns <= 0 {
+		return fmt.Errorf("missing rows or columns to zero the pixel data")
+	}
+	pixel := make([]int, samples) // shared by all pixels of all frames, only read when writing
+	data := make([][]int, rows*columns)
+	for i := range data {
+		data[i] = pixel
+	}
+	zeros := frame.Frame{NativeData: frame.NativeFrame{Data: data, Rows: rows, Cols: columns, BitsPerSample: bits}}
+	pixelData := dicom.PixelDataInfo{Frames: make([]frame.Frame, max(frames, 1))}
+	for i := range pixelData.Frames {
+		pixelData.Frames[i] = zeros
+	}
+	if err := replaceElement(dataset, tag.PixelData, pixelData); err != nil {
+		return err
+	}
+	if ts := dicomtree.UIDValue(dataset, tag.TransferSyntaxUID); ts != "" && !nativeTransferSyntaxes[ts] {
+		if err := replaceElement(dataset, tag.TransferSyntaxUID, []string{explicitVRLittleEndian}); err != nil {
+			return err
+		}
+		if photometric := dicomtree.UIDValue(dataset, tag.PhotometricInterpretation); strings.HasPrefix(photometric, "YBR_") {
+			// decompressed color, the YBR variants of JPEG are not valid for native pixel data
+			return replaceElement(dataset, tag.PhotometricInterpretation, []string{"RGB"})
+		}
+	}
+	return nil
+}
+
+// replaces the top level element with the tag by a new one with the data, keeping the VR of the old element
+func replaceElement(dataset *dicom.Dataset, t tag.Tag, data interface{}) error {
+	e, err := dicom.NewElement(t, data)
+	if err != nil {
+		return err
+	}
+	if old, err := dataset.FindElementByTag(t); err == nil {
+		e.RawValueRepresentation = old.RawValueRepresentation
+		Delete(dataset, t)
+	}
+	dataset.Elements = insertSorted(dataset.Elements, e)
+	return nil
+}
+
+// writes copies of the files of the entries without their pixel data, or zeroed, to the output directory, mirroring
+// their paths. Partial entries are parsed one at a time without their pixel data. Returns the written paths.
+func WriteStripped(entries []dicomtree.Entry, output string, zero bool) ([]string, error) {
+	var paths []string
+	for i := range entries {
+		entry := &entries[i]
+		dataset := dicom.Dataset{Elements: slices.Clone(entry.Dataset.Elements)}
+		skipped := false // pixel data of a partial entry, not parsed
+		if entry.Partial {
+			parsed, hasPixelData, err := dicomtree.ParseFileWithoutPixelData(entry.Path)
+			if err != nil {
+				return paths, fmt.Errorf("error parsing %s: %w", entry.Filename, err)
+			}
+			dataset, skipped = parsed, hasPixelData
+		}
+		stripped, err := StripPixelData(&dataset, zero)
+		if err == nil && zero && skipped && !stripped {
+			err = zeroPixelData(&dataset)
+		}
+		if err != nil {
+			return paths, fmt.Errorf("%s: %w", entry.Filename, err)
+		}
+		path := dicomtree.OutputPath(output, entry)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return paths, err
+		}
+		if err := dicomtree.WriteFile(dataset, path); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,81 @@
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestStripPixelData(t *testing.T) {
+	assert := assert.New(t)
+
+	newDataset := func() dicom.Dataset {
+		return dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.4.50"}),
+			mustElement(t, tag.SamplesPerPixel, []int{3}),
+			mustElement(t, tag.PhotometricInterpretation, []string{"YBR_FULL_422"}),
+			mustElement(t, tag.NumberOfFrames, []string{"2"}),
+			mustElement(t, tag.Rows, []int{2}),
+			mustElement(t, tag.Columns, []int{3}),
+			mustElement(t, tag.BitsAllocated, []int{8}),
+			mustElement(t, tag.PixelData, dicom.PixelDataInfo{IsEncapsulated: true}),
+		}}
+	}
+
+	dataset := newDataset()
+	original := dataset.Elements[len(dataset.Elements)-1]
+	stripped, err := StripPixelData(&dataset, false)
+	assert.NoError(err)
+	assert.True(stripped)
+	_, err = dataset.FindElementByTag(tag.PixelData)
+	assert.Error(err, "removed")
+	assert.Equal("1.2.840.10008.1.2.4.50", dicomtree.UIDValue(&dataset, tag.TransferSyntaxUID))
+	stripped, err = StripPixelData(&dataset, false)
+	assert.NoError(err)
+	assert.False(stripped, "nothing left to strip")
+
+	dataset = newDataset()
+	shared := dataset.Elements[0]
+	shallow := dicom.Dataset{Elements: append([]*dicom.Element(nil), dataset.Elements...)}
+	stripped, err = StripPixelData(&shallow, true)
+	assert.NoError(err)
+	assert.True(stripped)
+	e, err := shallow.FindElementByTag(tag.PixelData)
+	require.NoError(t, err)
+	info := e.Value.GetValue().(dicom.PixelDataInfo)
+	assert.Len(info.Frames, 2)
+	assert.Len(info.Frames[0].NativeData.Data, 6)
+	assert.Equal([]int{0, 0, 0}, info.Frames[0].NativeData.Data[5])
+	assert.Equal(explicitVRLittleEndian, dicomtree.UIDValue(&shallow, tag.TransferSyntaxUID))
+	assert.Equal("RGB", dicomtree.UIDValue(&shallow, tag.PhotometricInterpretation))
+	assert.Equal("1.2.840.10008.1.2.4.50", dicomtree.UIDValue(&dataset, tag.TransferSyntaxUID), "the original is unchanged")
+	assert.Same(shared, dataset.Elements[0])
+	assert.Contains(dataset.Elements, original)
+
+	noSize := dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.PixelData, dicom.PixelDataInfo{})}}
+	_, err = StripPixelData(&noSize, true)
+	assert.ErrorContains(err, "missing rows or columns")
+
+	output := t.TempDir()
+	paths, err := WriteStripped([]dicomtree.Entry{{Filename: "sub/a.dcm", Dataset: newDataset()}}, output, false)
+	assert.NoError(err)
+	assert.Equal([]string{filepath.Join(output, "sub", "a.dcm")}, paths)
+	_, err = os.Stat(paths[0])
+	assert.NoError(err)
+
+	partial := []dicomtree.Entry{{Filename: "b.dcm", Path: "../../testdata/test.dcm", Partial: true}}
+	paths, err = WriteStripped(partial, output, true)
+	require.NoError(t, err)
+	written, err := dicom.ParseFile(paths[0], nil)
+	require.NoError(t, err)
+	e, err = written.FindElementByTag(tag.PixelData)
+	if assert.NoError(err, "the pixel data not parsed is zeroed") {
+		assert.NotEmpty(e.Value.GetValue().(dicom.PixelDataInfo).Frames)
+	}
+}
@@ -0,0 +1,124 @@
+package edit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// rewrites the DT values and the dates and times of dicomtree.DateTimePairs of the dataset, also in sequence items,
+// into the timezone of the offset "&HHMM" and sets TimezoneOffsetFromUTC to it. Values without own offset are in the
+// timezone of TimezoneOffsetFromUTC and kept if the dataset has none, values without hour are always kept.
+// Returns the number of changed elements.
+func ConvertTimezone(dataset *dicom.Dataset, offset string) (int, error) {
+	zone, err := dicomtree.ParseTimezoneOffset(offset)
+	if err != nil {
+		return 0, err
+	}
+	loc, known := dicomtree.DatasetTimezone(dataset)
+	changed, err := convertTimezone(dataset.Elements, loc, zone)
+	if err != nil || !known {
+		return changed, err
+	}
+	if e, err := dataset.FindElementByTag(tag.TimezoneOffsetFromUTC); err == nil && strings.TrimSpace(dicomtree.ValueText(e, nil)) == offset {
+		return changed, nil
+	}
+	if _, err := Set(dataset, tag.TimezoneOffsetFromUTC, []string{offset}); err != nil {
+		return changed, err
+	}
+	return changed + 1, nil
+}
+
+func convertTimezone(elements []*dicom.Element, loc, zone *time.Location) (int, error) {
+	changed := 0
+	for _, e := range elements {
+		if dicomtree.IsSequence(e) {
+			for _, item := range dicomtree.SequenceItems(e) {
+				n, err := convertTimezone(item, loc, zone)
+				changed += n
+				if err != nil {
+					return changed, err
+				}
+			}
+			continue
+		}
+		if e.RawValueRepresentation != "DT" {
+			continue
+		}
+		values := dicomtree.ValueStrings(e)
+		converted := make([]string, len(values))
+		differs := false
+		for i, v := range values {
+			v = strings.TrimSpace(v)
+			converted[i] = convertDateTime(v, loc, zone)
+			differs = differs || converted[i] != v
+		}
+		if differs {
+			if err := SetStrings(e, converted, nil); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+	for _, pair := range dicomtree.DateTimePairs {
+		date, tm := dicomtree.FindItemElement(elements, pair[0]), dicomtree.FindItemElement(elements, pair[1])
+		if date == nil || tm == nil || len(dicomtree.ValueStrings(date)) != 1 || len(dicomtree.ValueStrings(tm)) != 1 {
+			continue
+		}
+		dateValue := strings.TrimSpace(dicomtree.ValueStrings(date)[0])
+		timeValue := strings.TrimSpace(dicomtree.ValueStrings(tm)[0])
+		if len(dateValue) != 8 || strings.ContainsAny(dateValue+timeValue, "+-") {
+			continue
+		}
+		converted := convertDateTime(dateValue+timeValue, loc, zone)
+		if converted == dateValue+timeValue {
+			continue
+		}
+		if converted[:8] != dateValue {
+			if err := SetStrings(date, []string{converted[:8]}, nil); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+		if err := SetStrings(tm, []string{converted[8:]}, nil); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// returns the DT value in the timezone with the precision of the value, with the offset if the value has one.
+// Values without hour, in an unknown timezone or invalid are returned unchanged.
+func convertDateTime(value string, loc, zone *time.Location) string {
+	digits, fraction, suffix := value, "", ""
+	if i := strings.IndexAny(digits, "+-"); i >= 0 {
+		digits, suffix = digits[:i], digits[i:]
+	}
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		digits, fraction = digits[:i], digits[i+1:]
+	}
+	if len(digits) < 10 || loc == nil && suffix == "" {
+		return value
+	}
+	if loc == nil {
+		loc = time.UTC // not used, the value has its own offset
+	}
+	t, err := dicomtree.ParseDateTime(value, loc)
+	if err != nil {
+		return value
+	}
+	t = t.In(zone)
+	converted := t.Format("20060102150405")[:len(digits)]
+	if fraction != "" {
+		converted += "." + fmt.Sprintf("%09d", t.Nanosecond())[:min(len(fraction), 9)]
+	}
+	if suffix != "" {
+		converted += t.Format("-0700")
+	}
+	return converted
+}
@@ -0,0 +1,55 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestConvertTimezone(t *testing.T) {
+	assert := assert.New(t)
+
+	element := func(tg tag.Tag, vr, value string) *dicom.Element {
+		e := mustElement(t, tg, []string{value})
+		e.RawValueRepresentation = vr
+		return e
+	}
+	newDataset := func() *dicom.Dataset {
+		return &dicom.Dataset{Elements: []*dicom.Element{
+			element(tag.StudyDate, "DA", "20240102"),
+			element(tag.StudyTime, "TM", "233005.25"),
+			element(tag.AcquisitionDateTime, "DT", "202401021015"),
+			element(tag.FrameAcquisitionDateTime, "DT", "20240102101500+0200"),
+			element(tag.InstanceCreationDate, "DA", "20240102"),
+			element(tag.TimezoneOffsetFromUTC, "SH", "-0500"),
+		}}
+	}
+	dataset := newDataset()
+	changed, err := ConvertTimezone(dataset, "+0100")
+	assert.NoError(err)
+	assert.Equal(5, changed)
+	assert.Equal("20240103", dicomtree.ItemValue(dataset.Elements, tag.StudyDate), "the date of the time moves along")
+	assert.Equal("053005.25", dicomtree.ItemValue(dataset.Elements, tag.StudyTime))
+	assert.Equal("202401021615", dicomtree.ItemValue(dataset.Elements, tag.AcquisitionDateTime), "same precision")
+	assert.Equal("20240102091500+0100", dicomtree.ItemValue(dataset.Elements, tag.FrameAcquisitionDateTime), "own offset")
+	assert.Equal("20240102", dicomtree.ItemValue(dataset.Elements, tag.InstanceCreationDate), "date without time")
+	assert.Equal("+0100", dicomtree.ItemValue(dataset.Elements, tag.TimezoneOffsetFromUTC))
+
+	changed, err = ConvertTimezone(dataset, "+0100")
+	assert.NoError(err)
+	assert.Zero(changed, "already in the timezone")
+
+	dataset = newDataset()
+	dataset.Elements = dataset.Elements[:5]
+	changed, err = ConvertTimezone(dataset, "+0000")
+	assert.NoError(err)
+	assert.Equal(1, changed, "only the date time with own offset without TimezoneOffsetFromUTC")
+	assert.Equal("20240102081500+0000", dicomtree.ItemValue(dataset.Elements, tag.FrameAcquisitionDateTime))
+	assert.Equal("233005.25", dicomtree.ItemValue(dataset.Elements, tag.StudyTime))
+
+	_, err = ConvertTimezone(dataset, "CET")
+	assert.Error(err)
+}
@@ -0,0 +1,103 @@
+package edit
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// returns a new UID below the 2.25 root, derived from a random 128 bit number
+func NewUID() string {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	return "2.25." + n.String()
+}
+
+// sets the SOP instance UID of the dataset and of its file meta information if present
+func SetSOPInstanceUID(dataset *dicom.Dataset, uid string) error {
+	if _, err := dataset.FindElementByTag(tag.MediaStorageSOPInstanceUID); err == nil {
+		if _, err := Set(dataset, tag.MediaStorageSOPInstanceUID, []string{uid}); err != nil {
+			return err
+		}
+	}
+	_, err := Set(dataset, tag.SOPInstanceUID, []string{uid})
+	return err
+}
+
+// tags of the UIDs replaced by ForkUIDs
+var ForkedUIDTags = []tag.Tag{tag.StudyInstanceUID, tag.SeriesInstanceUID, tag.SOPInstanceUID}
+
+// replacement of the study, series and SOP instance UIDs of a set of datasets by new ones, so they can be imported
+// again as independent copy of the study
+type UIDFork struct {
+	Mapping map[string]string // original UID to its new UID
+}
+
+// returns the fork of the datasets with a new UID for each study, series and SOP instance UID at their top levels
+func NewUIDFork(datasets []*dicom.Dataset) *UIDFork {
+	f := &UIDFork{Mapping: make(map[string]string)}
+	for _, dataset := range datasets {
+		for _, t := range ForkedUIDTags {
+			e, err := dataset.FindElementByTag(t)
+			if err != nil {
+				continue
+			}
+			for _, uid := range dicomtree.ValueStrings(e) {
+				uid = strings.TrimRight(uid, "\x00 ")
+				if _, ok := f.Mapping[uid]; !ok && uid != "" {
+					f.Mapping[uid] = NewUID()
+				}
+			}
+		}
+	}
+	return f
+}
+
+// replaces the forked UIDs wherever they occur in the dataset, also in the file meta information and the references
+// of sequence items, so references between the forked datasets are preserved. Returns the number of changed elements.
+func (f *UIDFork) Apply(dataset *dicom.Dataset) (int, error) {
+	return f.apply(dataset.Elements)
+}
+
+func (f *UIDFork) apply(elements []*dicom.Element) (int, error) {
+	changed := 0
+	for _, e := range elements {
+		if dicomtree.IsSequence(e) {
+			for _, item := range dicomtree.SequenceItems(e) {
+				n, err := f.apply(item)
+				changed += n
+				if err != nil {
+					return changed, err
+				}
+			}
+			continue
+		}
+		if e.RawValueRepresentation != "UI" {
+			continue
+		}
+		values := dicomtree.ValueStrings(e)
+		replaced := make([]string, len(values))
+		found := false
+		for i, uid := range values {
+			replaced[i] = uid
+			if newUID, ok := f.Mapping[strings.TrimRight(uid, "\x00 ")]; ok {
+				replaced[i], found = newUID, true
+			}
+		}
+		if !found {
+			continue
+		}
+		if err := SetStrings(e, replaced, nil); err != nil {
+			return changed, fmt.Errorf("error setting %s: %w", dicomtree.FormatTag(e.Tag), err)
+		}
+		changed++
+	}
+	return changed, nil
+}
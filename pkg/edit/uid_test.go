@@ -0,0 +1,59 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestUIDFork(t *testing.T) {
+	assert := assert.New(t)
+	uid := func(uidTag tag.Tag, value string) *dicom.Element {
+		v, err := dicom.NewValue([]string{value})
+		require.NoError(t, err)
+		return &dicom.Element{Tag: uidTag, RawValueRepresentation: "UI", Value: v}
+	}
+	image := dicom.Dataset{Elements: []*dicom.Element{
+		uid(tag.MediaStorageSOPInstanceUID, "1.3.1"),
+		uid(tag.SOPInstanceUID, "1.3.1"),
+		uid(tag.StudyInstanceUID, "1.1"),
+		uid(tag.SeriesInstanceUID, "1.2.1"),
+		uid(tag.FrameOfReferenceUID, "1.4"),
+	}}
+	reference, err := dicom.NewValue([][]*dicom.Element{{
+		uid(tag.ReferencedSOPInstanceUID, "1.3.1"),
+		uid(tag.ReferencedSOPClassUID, "1.2.840.10008.5.1.4.1.1.2"),
+	}})
+	require.NoError(t, err)
+	report := dicom.Dataset{Elements: []*dicom.Element{
+		uid(tag.SOPInstanceUID, "1.3.2"),
+		uid(tag.StudyInstanceUID, "1.1"),
+		uid(tag.SeriesInstanceUID, "1.2.2"),
+		{Tag: tag.ReferencedImageSequence, RawValueRepresentation: "SQ", Value: reference},
+	}}
+
+	fork := NewUIDFork([]*dicom.Dataset{&image, &report})
+	assert.Len(fork.Mapping, 5, "study, 2 series and 2 instances")
+	n, err := fork.Apply(&image)
+	require.NoError(t, err)
+	assert.Equal(4, n)
+	n, err = fork.Apply(&report)
+	require.NoError(t, err)
+	assert.Equal(4, n)
+
+	newUID := dicomtree.UIDValue
+	assert.True(strings.HasPrefix(newUID(&image, tag.SOPInstanceUID), "2.25."))
+	assert.Equal(fork.Mapping["1.3.1"], newUID(&image, tag.SOPInstanceUID))
+	assert.Equal(newUID(&image, tag.SOPInstanceUID), newUID(&image, tag.MediaStorageSOPInstanceUID))
+	assert.Equal(newUID(&image, tag.StudyInstanceUID), newUID(&report, tag.StudyInstanceUID))
+	assert.NotEqual(newUID(&image, tag.SeriesInstanceUID), newUID(&report, tag.SeriesInstanceUID))
+	assert.Equal("1.4", newUID(&image, tag.FrameOfReferenceUID), "not forked")
+	item := dicomtree.SequenceItems(report.Elements[3])[0]
+	assert.Equal([]string{newUID(&image, tag.SOPInstanceUID)}, item[0].Value.GetValue(), "reference preserved")
+	assert.Equal([]string{"1.2.840.10008.5.1.4.1.1.2"}, item[1].Value.GetValue())
+}
@@ -0,0 +1,114 @@
+package edit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// an element read with VR UN whose tag the dictionary knows with another VR
+type UnknownVR struct {
+	Element *dicom.Element
+	VR      string // the VR of the dictionary, the first one if it allows several
+}
+
+// returns the top level elements with VR UN whose VR the dictionary knows
+func FindUnknownVRs(dataset *dicom.Dataset) []UnknownVR {
+	var unknown []UnknownVR
+	for _, e := range dataset.Elements {
+		if e.RawValueRepresentation != "UN" {
+			continue
+		}
+		if info, err := tag.Find(e.Tag); err == nil {
+			if vr := strings.Split(info.VR, " or ")[0]; vr != "UN" && vr != "" {
+				unknown = append(unknown, UnknownVR{e, vr})
+			}
+		}
+	}
+	return unknown
+}
+
+// returns the value bytes of an element read with VR UN, nil if the parser trimmed padding off them when reading them
+// as strings, see dicomtree.Entry.ReadValueBytes
+func UnknownValueBytes(e *dicom.Element) []byte {
+	if e.Value == nil {
+		return nil
+	}
+	switch e.Value.ValueType() {
+	case dicom.Bytes:
+		return e.Value.GetValue().([]byte)
+	case dicom.Strings:
+		value := strings.Join(e.Value.GetValue().([]string), "\\")
+		if uint32(len(value)) == e.ValueLength {
+			return []byte(value)
+		}
+	}
+	return nil
+}
+
+// decodes the little endian value bytes of an element read with VR UN like the parser decodes values of the VR,
+// PS3.5 6.2.2. Sequences are not decoded.
+func DecodeUnknownValue(value []byte, vr string) (dicom.Value, error) {
+	size := map[string]int{"US": 2, "SS": 2, "AT": 2, "UL": 4, "SL": 4, "FL": 4, "FD": 8}[vr]
+	if size > 0 && len(value)%size != 0 {
+		return nil, fmt.Errorf("%d bytes are no %s values", len(value), vr)
+	}
+	switch vr {
+	case "SQ":
+		return nil, fmt.Errorf("sequences with VR UN are not decoded")
+	case "OB", "OW":
+		return dicom.NewValue(append([]byte{}, value...))
+	case "US", "AT", "SS", "UL", "SL":
+		ints := make([]int, 0, len(value)/size)
+		for i := 0; i < len(value); i += size {
+			switch vr {
+			case "US", "AT":
+				ints = append(ints, int(binary.LittleEndian.Uint16(value[i:])))
+			case "SS":
+				ints = append(ints, int(int16(binary.LittleEndian.Uint16(value[i:]))))
+			case "UL":
+				ints = append(ints, int(binary.LittleEndian.Uint32(value[i:])))
+			case "SL":
+				ints = append(ints, int(int32(binary.LittleEndian.Uint32(value[i:]))))
+			}
+		}
+		return dicom.NewValue(ints)
+	case "FL", "FD":
+		floats := make([]float64, 0, len(value)/size)
+		for i := 0; i < len(value); i += size {
+			if vr == "FL" {
+				floats = append(floats, float64(math.Float32frombits(binary.LittleEndian.Uint32(value[i:]))))
+			} else {
+				floats = append(floats, math.Float64frombits(binary.LittleEndian.Uint64(value[i:])))
+			}
+		}
+		return dicom.NewValue(floats)
+	}
+	text := string(value)
+	if strings.TrimSpace(text) != "" {
+		text = strings.Trim(text, " \x00")
+	}
+	if vr == "DA" {
+		return dicom.NewValue([]string{text})
+	}
+	return dicom.NewValue(strings.Split(text, "\\"))
+}
+
+// sets the VR of the dictionary and the value decoded from its value bytes for an element read with VR UN, so it is
+// displayed and written with its VR
+func ReinterpretUnknown(unknown UnknownVR, value []byte) error {
+	decoded, err := DecodeUnknownValue(value, unknown.VR)
+	if err != nil {
+		return fmt.Errorf("%s: %w", dicomtree.FormatTag(unknown.Element.Tag), err)
+	}
+	e := unknown.Element
+	e.RawValueRepresentation = unknown.VR
+	e.ValueRepresentation = tag.GetVRKind(e.Tag, unknown.VR)
+	e.Value = decoded
+	return nil
+}
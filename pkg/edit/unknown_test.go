@@ -0,0 +1,53 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestDecodeUnknownValue(t *testing.T) {
+	assert := assert.New(t)
+
+	value, err := DecodeUnknownValue([]byte("1.25\\2.5 "), "DS")
+	require.NoError(t, err)
+	assert.Equal([]string{"1.25", "2.5"}, value.GetValue())
+	value, err = DecodeUnknownValue([]byte{0x00, 0x02, 0xff, 0xff}, "US")
+	require.NoError(t, err)
+	assert.Equal([]int{512, 65535}, value.GetValue())
+	value, err = DecodeUnknownValue([]byte{0xff, 0xff}, "SS")
+	require.NoError(t, err)
+	assert.Equal([]int{-1}, value.GetValue())
+	value, err = DecodeUnknownValue([]byte{0x00, 0x00, 0x80, 0x3f}, "FL")
+	require.NoError(t, err)
+	assert.Equal([]float64{1}, value.GetValue())
+	value, err = DecodeUnknownValue([]byte{0x20, 0x00}, "OB")
+	require.NoError(t, err)
+	assert.Equal([]byte{0x20, 0x00}, value.GetValue())
+
+	_, err = DecodeUnknownValue([]byte{1, 2, 3}, "UL")
+	assert.EqualError(err, "3 bytes are no UL values")
+	_, err = DecodeUnknownValue(nil, "SQ")
+	assert.Error(err)
+}
+
+func TestReinterpretUnknown(t *testing.T) {
+	assert := assert.New(t)
+
+	rows := mustElement(t, tag.Rows, []string{"\x00\x02"})
+	rows.RawValueRepresentation = "UN"
+	rows.ValueLength = 2
+	assert.Equal([]byte{0x00, 0x02}, UnknownValueBytes(rows))
+	require.NoError(t, ReinterpretUnknown(UnknownVR{rows, "US"}, UnknownValueBytes(rows)))
+	assert.Equal("US", rows.RawValueRepresentation)
+	assert.Equal([]int{512}, rows.Value.GetValue())
+
+	trimmed := mustElement(t, tag.Columns, []string{"\x02"})
+	trimmed.RawValueRepresentation = "UN"
+	trimmed.ValueLength = 2
+	assert.Nil(UnknownValueBytes(trimmed), "the parser trimmed the null byte")
+	assert.Error(ReinterpretUnknown(UnknownVR{trimmed, "US"}, []byte{2}))
+	assert.Equal("UN", trimmed.RawValueRepresentation)
+}
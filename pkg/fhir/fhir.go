@@ -0,0 +1,292 @@
+// Package fhir maps the patient, study, series and instance attributes of datasets to FHIR R4 ImagingStudy and
+// Patient resources in a collection bundle.
+package fhir
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const (
+	dicomSystem    = "http://dicom.nema.org/resources/ontology/DCM" // modality codes
+	uriSystem      = "urn:ietf:rfc:3986"                            // SOP class UIDs and study instance UID identifiers
+	dicomUIDSystem = "urn:dicom:uid"
+)
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+type BundleEntry struct {
+	FullURL  string      `json:"fullUrl"`
+	Resource interface{} `json:"resource"` // *Patient or *ImagingStudy
+}
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference"`
+}
+
+type HumanName struct {
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+	Prefix []string `json:"prefix,omitempty"`
+	Suffix []string `json:"suffix,omitempty"`
+}
+
+type Patient struct {
+	ResourceType string       `json:"resourceType"`
+	ID           string       `json:"id"`
+	Identifier   []Identifier `json:"identifier,omitempty"`
+	Name         []HumanName  `json:"name,omitempty"`
+	Gender       string       `json:"gender,omitempty"`
+	BirthDate    string       `json:"birthDate,omitempty"`
+}
+
+type ImagingStudy struct {
+	ResourceType      string        `json:"resourceType"`
+	ID                string        `json:"id"`
+	Identifier        []Identifier  `json:"identifier"`
+	Status            string        `json:"status"`
+	Modality          []Coding      `json:"modality,omitempty"`
+	Subject           Reference     `json:"subject"`
+	Started           string        `json:"started,omitempty"`
+	NumberOfSeries    int           `json:"numberOfSeries"`
+	NumberOfInstances int           `json:"numberOfInstances"`
+	Description       string        `json:"description,omitempty"`
+	Series            []StudySeries `json:"series"`
+}
+
+type StudySeries struct {
+	UID               string          `json:"uid"`
+	Number            *int            `json:"number,omitempty"`
+	Modality          Coding          `json:"modality"`
+	Description       string          `json:"description,omitempty"`
+	NumberOfInstances int             `json:"numberOfInstances"`
+	BodySite          *Coding         `json:"bodySite,omitempty"`
+	Started           string          `json:"started,omitempty"`
+	Instance          []StudyInstance `json:"instance"`
+}
+
+type StudyInstance struct {
+	UID      string `json:"uid"`
+	SOPClass Coding `json:"sopClass"`
+	Number   *int   `json:"number,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+// returns the bundle with a Patient per PatientID and PatientName and an ImagingStudy per StudyInstanceUID in the
+// order of their first entry, entries without study or SOP instance UID are skipped. Resource ids are derived from
+// the identifiers, so exporting the same study again yields the same ids.
+func NewBundle(entries []dicomtree.Entry) Bundle {
+	bundle := Bundle{ResourceType: "Bundle", Type: "collection", Entry: make([]BundleEntry, 0)}
+	patients := make(map[string]*Patient)
+	studies := make(map[string]*ImagingStudy)
+	for i := range entries {
+		charsets := charset.FromDataset(&entries[i].Dataset)
+		value := func(t tag.Tag) string {
+			e, err := entries[i].Dataset.FindElementByTag(t)
+			if err != nil {
+				return ""
+			}
+			return strings.TrimRight(dicomtree.ValueText(e, charsets), "\x00 ")
+		}
+		studyUID, instanceUID := value(tag.StudyInstanceUID), value(tag.SOPInstanceUID)
+		if studyUID == "" || instanceUID == "" {
+			continue
+		}
+
+		patientKey := value(tag.PatientID) + "\x00" + value(tag.PatientName)
+		patient, ok := patients[patientKey]
+		if !ok {
+			patient = newPatient(patientKey, value)
+			patients[patientKey] = patient
+			bundle.Entry = append(bundle.Entry, BundleEntry{"urn:uuid:" + patient.ID, patient})
+		}
+		study, ok := studies[studyUID]
+		if !ok {
+			study = &ImagingStudy{
+				ResourceType: "ImagingStudy",
+				ID:           uuid("study\x00" + studyUID),
+				Identifier:   []Identifier{{System: uriSystem, Value: "urn:oid:" + studyUID}},
+				Status:       "available",
+				Subject:      Reference{"urn:uuid:" + patient.ID},
+				Started:      dateTime(value(tag.StudyDate), value(tag.StudyTime), value(tag.TimezoneOffsetFromUTC)),
+				Description:  value(tag.StudyDescription),
+				Series:       make([]StudySeries, 0),
+			}
+			if accession := value(tag.AccessionNumber); accession != "" {
+				study.Identifier = append(study.Identifier, Identifier{Value: accession})
+			}
+			studies[studyUID] = study
+			bundle.Entry = append(bundle.Entry, BundleEntry{"urn:uuid:" + study.ID, study})
+		}
+
+		seriesUID := value(tag.SeriesInstanceUID)
+		s := -1
+		for j := range study.Series {
+			if study.Series[j].UID == seriesUID {
+				s = j
+			}
+		}
+		if s < 0 {
+			series := StudySeries{
+				UID:         seriesUID,
+				Number:      number(value(tag.SeriesNumber)),
+				Modality:    Coding{System: dicomSystem, Code: value(tag.Modality)},
+				Description: value(tag.SeriesDescription),
+				Started:     dateTime(value(tag.SeriesDate), value(tag.SeriesTime), value(tag.TimezoneOffsetFromUTC)),
+				Instance:    make([]StudyInstance, 0),
+			}
+			if bodyPart := value(tag.BodyPartExamined); bodyPart != "" {
+				series.BodySite = &Coding{Display: bodyPart, Code: bodyPart}
+			}
+			study.Series = append(study.Series, series)
+			s = len(study.Series) - 1
+			if modality := value(tag.Modality); modality != "" && !hasModality(study.Modality, modality) {
+				study.Modality = append(study.Modality, Coding{System: dicomSystem, Code: modality})
+			}
+		}
+		sopClass := value(tag.SOPClassUID)
+		study.Series[s].Instance = append(study.Series[s].Instance, StudyInstance{
+			UID:      instanceUID,
+			SOPClass: Coding{System: uriSystem, Code: "urn:oid:" + sopClass, Display: dicomtree.SOPClassName(sopClass)},
+			Number:   number(value(tag.InstanceNumber)),
+		})
+		study.Series[s].NumberOfInstances++
+		study.NumberOfSeries = len(study.Series)
+		study.NumberOfInstances++
+	}
+	return bundle
+}
+
+// writes the bundle of the entries as indented JSON
+func Write(w io.Writer, entries []dicomtree.Entry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewBundle(entries))
+}
+
+func newPatient(key string, value func(t tag.Tag) string) *Patient {
+	patient := &Patient{ResourceType: "Patient", ID: uuid("patient\x00" + key)}
+	if id := value(tag.PatientID); id != "" {
+		patient.Identifier = []Identifier{{Value: id}}
+	}
+	if name := humanName(value(tag.PatientName)); name != nil {
+		patient.Name = []HumanName{*name}
+	}
+	switch value(tag.PatientSex) {
+	case "M":
+		patient.Gender = "male"
+	case "F":
+		patient.Gender = "female"
+	case "O":
+		patient.Gender = "other"
+	case "":
+	default:
+		patient.Gender = "unknown"
+	}
+	patient.BirthDate = date(value(tag.PatientBirthDate))
+	return patient
+}
+
+// returns the name of the alphabetic group of a person name "family^given^middle^prefix^suffix", nil if empty
+func humanName(pn string) *HumanName {
+	alphabetic, _, _ := strings.Cut(pn, "=")
+	if strings.Trim(alphabetic, "^ ") == "" {
+		return nil
+	}
+	parts := strings.Split(alphabetic, "^")
+	part := func(i int) string {
+		if i < len(parts) {
+			return strings.TrimSpace(parts[i])
+		}
+		return ""
+	}
+	name := &HumanName{Family: part(0)}
+	for _, given := range []string{part(1), part(2)} {
+		if given != "" {
+			name.Given = append(name.Given, given)
+		}
+	}
+	if prefix := part(3); prefix != "" {
+		name.Prefix = []string{prefix}
+	}
+	if suffix := part(4); suffix != "" {
+		name.Suffix = []string{suffix}
+	}
+	text := append(append(append([]string{}, name.Prefix...), name.Given...), name.Family)
+	name.Text = strings.TrimSpace(strings.Join(append(text, name.Suffix...), " "))
+	return name
+}
+
+// returns the FHIR date of a DA value YYYYMMDD, "" if it isn't one
+func date(da string) string {
+	if len(da) != 8 {
+		return ""
+	}
+	if _, err := strconv.Atoi(da); err != nil {
+		return ""
+	}
+	return da[:4] + "-" + da[4:6] + "-" + da[6:]
+}
+
+// returns the FHIR dateTime of a DA and TM value with the time zone of a TimezoneOffsetFromUTC value like "+0100".
+// FHIR requires a time zone with a time, so without it only the date is returned, "" without date.
+func dateTime(da, tm, tz string) string {
+	d := date(da)
+	tm = strings.TrimSpace(strings.ReplaceAll(tm, ":", ""))
+	if d == "" || len(tm) < 6 || len(tz) != 5 || (tz[0] != '+' && tz[0] != '-') {
+		return d
+	}
+	if _, err := strconv.Atoi(tm[:6]); err != nil {
+		return d
+	}
+	return d + "T" + tm[:2] + ":" + tm[2:4] + ":" + tm[4:6] + tz[:3] + ":" + tz[3:]
+}
+
+func number(is string) *int {
+	n, err := strconv.Atoi(strings.TrimSpace(is))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func hasModality(codings []Coding, modality string) bool {
+	for _, c := range codings {
+		if c.Code == modality {
+			return true
+		}
+	}
+	return false
+}
+
+// returns a name-based UUID (version 5 layout) of the text, used as resource id and in the urn:uuid: full URLs
+func uuid(text string) string {
+	sum := sha1.Sum([]byte(text))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,83 @@
+package fhir
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newInstance(t *testing.T, series, instance, number string) dicomtree.Entry {
+	return dicomtree.Entry{Filename: instance + ".dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+		mustElement(t, tag.SOPInstanceUID, []string{instance}),
+		mustElement(t, tag.StudyDate, []string{"20240131"}),
+		mustElement(t, tag.StudyTime, []string{"134500.25"}),
+		mustElement(t, tag.TimezoneOffsetFromUTC, []string{"+0100"}),
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientName, []string{"Doe^John^^Dr"}),
+		mustElement(t, tag.PatientID, []string{"42"}),
+		mustElement(t, tag.PatientBirthDate, []string{"19700101"}),
+		mustElement(t, tag.PatientSex, []string{"M"}),
+		mustElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+		mustElement(t, tag.SeriesInstanceUID, []string{series}),
+		mustElement(t, tag.SeriesNumber, []string{series[len(series)-1:]}),
+		mustElement(t, tag.InstanceNumber, []string{number}),
+	}}}
+}
+
+func TestNewBundle(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []dicomtree.Entry{
+		newInstance(t, "1.2.3.1", "1.2.3.1.1", "1"),
+		newInstance(t, "1.2.3.1", "1.2.3.1.2", "2"),
+		newInstance(t, "1.2.3.2", "1.2.3.2.1", "1"),
+		{Filename: "no-uids.dcm"},
+	}
+	bundle := NewBundle(entries)
+	require.Len(t, bundle.Entry, 2)
+	patient := bundle.Entry[0].Resource.(*Patient)
+	assert.Equal([]HumanName{{Text: "Dr John Doe", Family: "Doe", Given: []string{"John"}, Prefix: []string{"Dr"}}}, patient.Name)
+	assert.Equal("male", patient.Gender)
+	assert.Equal("1970-01-01", patient.BirthDate)
+	assert.Equal("urn:uuid:"+patient.ID, bundle.Entry[0].FullURL)
+
+	study := bundle.Entry[1].Resource.(*ImagingStudy)
+	assert.Equal("urn:uuid:"+patient.ID, study.Subject.Reference)
+	assert.Equal([]Identifier{{System: "urn:ietf:rfc:3986", Value: "urn:oid:1.2.3"}}, study.Identifier)
+	assert.Equal("2024-01-31T13:45:00+01:00", study.Started)
+	assert.Equal(2, study.NumberOfSeries)
+	assert.Equal(3, study.NumberOfInstances)
+	assert.Equal([]Coding{{System: "http://dicom.nema.org/resources/ontology/DCM", Code: "CT"}}, study.Modality)
+	assert.Equal(2, study.Series[0].NumberOfInstances)
+	assert.Equal(2, *study.Series[0].Instance[1].Number)
+	assert.Equal("urn:oid:1.2.840.10008.5.1.4.1.1.2", study.Series[0].Instance[0].SOPClass.Code)
+
+	assert.Equal(bundle.Entry[1].FullURL, NewBundle(entries).Entry[1].FullURL, "same ids for the same study")
+	assert.Regexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, patient.ID)
+
+	var json strings.Builder
+	require.NoError(t, Write(&json, entries))
+	assert.Contains(json.String(), `"resourceType": "ImagingStudy"`)
+}
+
+func TestDateTime(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("2024-01-31", dateTime("20240131", "1345", "+0100"))
+	assert.Equal("2024-01-31", dateTime("20240131", "134500", ""), "no time without time zone")
+	assert.Equal("", dateTime("2024", "134500", "+0100"))
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
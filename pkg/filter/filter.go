@@ -0,0 +1,227 @@
+// Package filter parses filter expressions like 'Modality=CT and PatientName~doe' and evaluates them
+// against datasets in memory or translates them to SQL for the index.
+package filter
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// comparison operators of a condition
+const (
+	OpEqual    = "="
+	OpNotEqual = "!="
+	OpContains = "~" // case-insensitive substring
+	OpLess     = "<"
+	OpGreater  = ">"
+)
+
+// VRs whose values are compared as numbers by < and >, others as text, which orders DA, DT and TM values too
+var numericVRs = map[string]bool{
+	"DS": true, "FD": true, "FL": true, "IS": true, "SL": true, "SS": true, "SV": true, "UL": true, "US": true, "UV": true,
+}
+
+// condition on the value text of a top level element, see dicomtree.ValueText
+type Condition struct {
+	Tag   tag.Tag
+	Op    string
+	Value string
+}
+
+// disjunction of conjunctions of conditions, 'and' binds stronger than 'or'
+type Expr struct {
+	Or [][]Condition
+}
+
+// parses an expression of conditions '<tag><op><value>' combined with 'and' and 'or'.
+// Tags are keywords or group and element, values can be quoted with ' or ".
+func Parse(text string) (*Expr, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+
+	expr := &Expr{Or: [][]Condition{{}}}
+	for i := 0; i < len(tokens); {
+		if i+3 > len(tokens) || tokens[i].isOp || !tokens[i+1].isOp || tokens[i+2].isOp {
+			return nil, fmt.Errorf("expected condition '<tag><op><value>' at '%s'", joinTokens(tokens[i:]))
+		}
+		t, err := dicomtree.ParseTag(tokens[i].text)
+		if err != nil {
+			return nil, err
+		}
+		and := &expr.Or[len(expr.Or)-1]
+		*and = append(*and, Condition{t, tokens[i+1].text, tokens[i+2].text})
+		i += 3
+
+		if i == len(tokens) {
+			break
+		}
+		switch strings.ToLower(tokens[i].text) {
+		case "and":
+		case "or":
+			expr.Or = append(expr.Or, []Condition{})
+		default:
+			return nil, fmt.Errorf("expected 'and' or 'or' at '%s'", joinTokens(tokens[i:]))
+		}
+		i++
+		if i == len(tokens) {
+			return nil, fmt.Errorf("missing condition after '%s'", tokens[i-1].text)
+		}
+	}
+	return expr, nil
+}
+
+// reports whether the top level elements of the dataset fulfill the expression
+func (expr *Expr) Match(dataset *dicom.Dataset) bool {
+	charsets := charset.FromDataset(dataset)
+	for _, conditions := range expr.Or {
+		matches := true
+		for _, condition := range conditions {
+			if !condition.match(dataset, charsets) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) match(dataset *dicom.Dataset, charsets []string) bool {
+	e, err := dataset.FindElementByTag(c.Tag)
+	if err != nil {
+		return c.Op == OpNotEqual // a missing element is different from every value
+	}
+	value := dicomtree.ValueText(e, charsets)
+	switch c.Op {
+	case OpEqual:
+		return value == c.Value
+	case OpNotEqual:
+		return value != c.Value
+	case OpContains:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(c.Value))
+	case OpLess:
+		return compareValues(e.RawValueRepresentation, value, c.Value) < 0
+	case OpGreater:
+		return compareValues(e.RawValueRepresentation, value, c.Value) > 0
+	}
+	return false
+}
+
+// compares the value text with the value of a condition, by the first value as number for numeric VRs if both are
+// numbers, else as text
+func compareValues(vr, value, conditionValue string) int {
+	if number, ok := parseNumber(conditionValue); ok && numericVRs[vr] {
+		if first, ok := parseNumber(strings.SplitN(value, "\\", 2)[0]); ok {
+			return cmp.Compare(first, number)
+		}
+	}
+	return strings.Compare(value, conditionValue)
+}
+
+func parseNumber(text string) (float64, bool) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	return number, err == nil
+}
+
+// returns the expression as SQL condition for the path of the files table of the index, with its arguments
+func (expr *Expr) SQL() (string, []interface{}) {
+	args := make([]interface{}, 0)
+	ors := make([]string, 0, len(expr.Or))
+	for _, conditions := range expr.Or {
+		ands := make([]string, 0, len(conditions))
+		for _, c := range conditions {
+			tagValue := int64(dicomtree.TagOrder(c.Tag))
+			switch c.Op {
+			case OpEqual:
+				ands = append(ands, "path IN (SELECT path FROM elements WHERE tag = ? AND value = ?)")
+			case OpNotEqual:
+				ands = append(ands, "path NOT IN (SELECT path FROM elements WHERE tag = ? AND value = ?)")
+			case OpContains:
+				ands = append(ands, "path IN (SELECT path FROM elements WHERE tag = ? AND instr(lower(value), lower(?)) > 0)")
+			case OpLess, OpGreater:
+				if number, ok := parseNumber(c.Value); ok {
+					ands = append(ands, fmt.Sprintf("path IN (SELECT path FROM elements WHERE tag = ? AND CASE WHEN vr IN (%s)"+
+						" THEN CAST(value AS REAL) %s ? ELSE value %s ? END)", numericVRList(), c.Op, c.Op))
+					args = append(args, tagValue, number, c.Value)
+					continue
+				}
+				ands = append(ands, fmt.Sprintf("path IN (SELECT path FROM elements WHERE tag = ? AND value %s ?)", c.Op))
+			}
+			args = append(args, tagValue, c.Value)
+		}
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), args
+}
+
+// returns the numericVRs as SQL list, sorted for a stable statement
+func numericVRList() string {
+	vrs := make([]string, 0, len(numericVRs))
+	for vr := range numericVRs {
+		vrs = append(vrs, "'"+vr+"'")
+	}
+	slices.Sort(vrs)
+	return strings.Join(vrs, ", ")
+}
+
+type token struct {
+	text string
+	isOp bool
+}
+
+func tokenize(text string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(text[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing quote %c", c)
+			}
+			tokens = append(tokens, token{text[i+1 : i+1+end], false})
+			i += end + 2
+		case strings.HasPrefix(text[i:], OpNotEqual):
+			tokens = append(tokens, token{OpNotEqual, true})
+			i += len(OpNotEqual)
+		case c == '!':
+			return nil, fmt.Errorf("unexpected '!' at '%s', use != for not equal", text[i:])
+		case strings.IndexByte("=~<>", c) >= 0:
+			tokens = append(tokens, token{string(c), true})
+			i++
+		default:
+			end := strings.IndexAny(text[i:], " \t\"'=!~<>")
+			if end < 0 {
+				end = len(text) - i
+			}
+			tokens = append(tokens, token{text[i : i+end], false})
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+func joinTokens(tokens []token) string {
+	texts := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		texts = append(texts, t.text)
+	}
+	return strings.Join(texts, " ")
+}
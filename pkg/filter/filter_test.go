@@ -0,0 +1,79 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newTestDataset(t *testing.T, patientName string, modality string) *dicom.Dataset {
+	return &dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{modality}),
+		mustElement(t, tag.PatientName, []string{patientName}),
+	}}
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, err := Parse("0008,0060=CT and 0010,0010 ~ 'doe^j' or (0008,0060)!=\"MR\"")
+	assert.NoError(err)
+	assert.Equal([][]Condition{
+		{{tag.Modality, OpEqual, "CT"}, {tag.PatientName, OpContains, "doe^j"}},
+		{{tag.Modality, OpNotEqual, "MR"}},
+	}, expr.Or)
+
+	for _, text := range []string{"", "0008,0060", "0008,0060=", "0008,0060=CT and", "0008,0060=CT xor 0010,0010=x", "0008,0060='CT", "=CT", "PatientName!Doe", "0008,0060=CT!"} {
+		_, err := Parse(text)
+		assert.Error(err, text)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	ct, mr := newTestDataset(t, "Doe^John", "CT"), newTestDataset(t, "Roe^Jane", "MR")
+	ct.Elements = append(ct.Elements, mustElement(t, tag.SeriesNumber, []string{"9"}))
+	mr.Elements = append(mr.Elements, mustElement(t, tag.SeriesNumber, []string{"10"}))
+	for text, expected := range map[string][2]bool{
+		"0008,0060=CT":                         {true, false},
+		"0008,0060!=CT":                        {false, true},
+		"0010,0010~doe":                        {true, false},
+		"0010,0010>M":                          {false, true},
+		"0010,0010<M":                          {true, false},
+		"SeriesNumber<10":                      {true, false},
+		"SeriesNumber>9.5":                     {false, true},
+		"0008,0060=MR or 0010,0010~john":       {true, true},
+		"0008,0060=CT and 0010,0010~jane":      {false, false},
+		"0008,0020=20240101":                   {false, false},
+		"0008,0020!=20240101 and 0008,0060=MR": {false, true},
+	} {
+		expr, err := Parse(text)
+		assert.NoError(err, text)
+		assert.Equal(expected[0], expr.Match(ct), text)
+		assert.Equal(expected[1], expr.Match(mr), text)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, err := Parse("0008,0060=CT and 0010,0010~doe or 0008,0060!=MR")
+	assert.NoError(err)
+	condition, args := expr.SQL()
+	assert.Equal("(path IN (SELECT path FROM elements WHERE tag = ? AND value = ?)"+
+		" AND path IN (SELECT path FROM elements WHERE tag = ? AND instr(lower(value), lower(?)) > 0))"+
+		" OR (path NOT IN (SELECT path FROM elements WHERE tag = ? AND value = ?))", condition)
+	assert.Equal([]interface{}{int64(0x00080060), "CT", int64(0x00100010), "doe", int64(0x00080060), "MR"}, args)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
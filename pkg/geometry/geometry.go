@@ -0,0 +1,232 @@
+// Package geometry checks the slice geometry of series derived from the image position and orientation, e.g. whether
+// the slices are evenly spaced as needed for a 3D reconstruction.
+package geometry
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const (
+	tolerance    = 0.01 // mm, differences below are rounding of the decimal strings
+	gapFactor    = 1.5  // spacing of a gap relative to the median spacing
+	angleEpsilon = 1e-4 // difference of the direction cosines of parallel slices
+)
+
+// deviation of the geometry of a series, with the file it was found at
+type Problem struct {
+	Text  string
+	Entry *dicomtree.Entry
+}
+
+type Slice struct {
+	Entry    *dicomtree.Entry
+	Position [3]float64 // image position (patient) in mm
+	Distance float64    // along the slice normal of the series in mm
+}
+
+type Series struct {
+	UID              string
+	Number           string
+	Description      string
+	FrameOfReference string
+	Orientation      []float64 // direction cosines of rows and columns of the first slice, nil if missing
+	Rows             int
+	Columns          int
+	PixelSpacing     []float64 // row and column spacing in mm
+	SliceThickness   float64
+	Slices           []Slice // slices with position and orientation sorted along the normal
+	Spacing          float64 // median distance of adjacent slices in mm, 0 for less than two slices
+	MinSpacing       float64
+	MaxSpacing       float64
+	Extent           [3]float64 // along rows, columns and the normal in mm
+	Problems         []Problem
+}
+
+// returns whether the series can be reconstructed as volume, evenly spaced slices of the same orientation
+func (s Series) Regular() bool {
+	return len(s.Problems) == 0
+}
+
+// returns the geometry of each series of the entries with at least one image position, in the order of their first
+// file
+func Analyze(entries []dicomtree.Entry) []Series {
+	indexByUID := make(map[string]int)
+	series := make([]Series, 0)
+	var entriesBySeries [][]*dicomtree.Entry
+	for i := range entries {
+		entry := &entries[i]
+		if dicomtree.FindItemElement(entry.Dataset.Elements, tag.ImagePositionPatient) == nil {
+			continue
+		}
+		uid := dicomtree.UIDValue(&entry.Dataset, tag.SeriesInstanceUID)
+		index, ok := indexByUID[uid]
+		if !ok {
+			index = len(series)
+			indexByUID[uid] = index
+			series = append(series, Series{
+				UID:              uid,
+				Number:           dicomtree.ItemValue(entry.Dataset.Elements, tag.SeriesNumber),
+				Description:      dicomtree.ItemValue(entry.Dataset.Elements, tag.SeriesDescription),
+				FrameOfReference: dicomtree.UIDValue(&entry.Dataset, tag.FrameOfReferenceUID),
+			})
+			entriesBySeries = append(entriesBySeries, nil)
+		}
+		entriesBySeries[index] = append(entriesBySeries[index], entry)
+	}
+	for i := range series {
+		series[i].analyze(entriesBySeries[i])
+	}
+	return series
+}
+
+func numbers(entry *dicomtree.Entry, t tag.Tag) []float64 {
+	if e := dicomtree.FindItemElement(entry.Dataset.Elements, t); e != nil {
+		return dicomtree.NumericValues(e)
+	}
+	return nil
+}
+
+func (s *Series) analyze(entries []*dicomtree.Entry) {
+	first := entries[0]
+	s.Orientation = numbers(first, tag.ImageOrientationPatient)
+	s.PixelSpacing = numbers(first, tag.PixelSpacing)
+	s.Rows, _ = strconv.Atoi(dicomtree.ItemValue(first.Dataset.Elements, tag.Rows))
+	s.Columns, _ = strconv.Atoi(dicomtree.ItemValue(first.Dataset.Elements, tag.Columns))
+	if thickness := numbers(first, tag.SliceThickness); len(thickness) == 1 {
+		s.SliceThickness = thickness[0]
+	}
+	if len(s.Orientation) != 6 {
+		s.Orientation = nil
+		s.Problems = append(s.Problems, Problem{"no valid image orientation", first})
+		return
+	}
+	row, column := [3]float64(s.Orientation[:3]), [3]float64(s.Orientation[3:])
+	normal := cross(row, column)
+
+	for _, entry := range entries {
+		position := numbers(entry, tag.ImagePositionPatient)
+		if len(position) != 3 {
+			s.Problems = append(s.Problems, Problem{"no valid image position", entry})
+			continue
+		}
+		orientation := numbers(entry, tag.ImageOrientationPatient)
+		if len(orientation) != 6 || !parallel(orientation, s.Orientation) {
+			s.Problems = append(s.Problems, Problem{"orientation differs from the first file " + first.Filename, entry})
+			continue
+		}
+		p := [3]float64(position)
+		s.Slices = append(s.Slices, Slice{Entry: entry, Position: p, Distance: dot(p, normal)})
+	}
+	slices.SortStableFunc(s.Slices, func(a, b Slice) int { return cmp.Compare(a.Distance, b.Distance) })
+	if len(s.PixelSpacing) == 2 {
+		s.Extent[0], s.Extent[1] = float64(s.Columns)*s.PixelSpacing[1], float64(s.Rows)*s.PixelSpacing[0]
+	}
+	if len(s.Slices) < 2 {
+		return
+	}
+	s.Extent[2] = s.Slices[len(s.Slices)-1].Distance - s.Slices[0].Distance
+
+	spacings := make([]float64, len(s.Slices)-1)
+	for i := range spacings {
+		spacings[i] = s.Slices[i+1].Distance - s.Slices[i].Distance
+	}
+	sorted := slices.Clone(spacings)
+	slices.Sort(sorted)
+	s.Spacing, s.MinSpacing, s.MaxSpacing = sorted[len(sorted)/2], sorted[0], sorted[len(sorted)-1]
+
+	for i, spacing := range spacings {
+		previous, next := s.Slices[i], s.Slices[i+1]
+		switch {
+		case spacing < tolerance:
+			s.Problems = append(s.Problems, Problem{fmt.Sprintf("same position as %s", previous.Entry.Filename), next.Entry})
+		case spacing > s.Spacing*gapFactor:
+			s.Problems = append(s.Problems, Problem{fmt.Sprintf("gap of %s mm after %s, about %d missing slices", format(spacing),
+				previous.Entry.Filename, int(math.Round(spacing/s.Spacing))-1), next.Entry})
+		case math.Abs(spacing-s.Spacing) > max(tolerance, s.Spacing*0.01):
+			s.Problems = append(s.Problems, Problem{fmt.Sprintf("irregular spacing of %s mm after %s instead of %s mm", format(spacing),
+				previous.Entry.Filename, format(s.Spacing)), next.Entry})
+		}
+	}
+	// the offset within the slice plane changes from slice to slice for tilted gantries
+	first2D := [2]float64{dot(s.Slices[0].Position, row), dot(s.Slices[0].Position, column)}
+	for _, slice := range s.Slices[1:] {
+		if math.Abs(dot(slice.Position, row)-first2D[0]) > tolerance || math.Abs(dot(slice.Position, column)-first2D[1]) > tolerance {
+			s.Problems = append(s.Problems, Problem{"position is not on the slice normal through the first slice, e.g. gantry tilt",
+				slice.Entry})
+			break
+		}
+	}
+}
+
+// returns a line with the number of slices, spacing, extent and orientation of the series
+func (s Series) Summary() string {
+	text := fmt.Sprintf("%s: %d slices", seriesName(s.Number, s.Description), len(s.Slices))
+	if len(s.Slices) > 1 {
+		text += fmt.Sprintf(", spacing %s mm", format(s.Spacing))
+		if s.MaxSpacing-s.MinSpacing > tolerance {
+			text += fmt.Sprintf(" (%s..%s)", format(s.MinSpacing), format(s.MaxSpacing))
+		}
+	}
+	if s.SliceThickness > 0 {
+		text += fmt.Sprintf(", thickness %s mm", format(s.SliceThickness))
+	}
+	text += fmt.Sprintf(", extent %s x %s x %s mm", format(s.Extent[0]), format(s.Extent[1]), format(s.Extent[2]))
+	if s.Orientation != nil {
+		text += ", " + OrientationName(s.Orientation)
+	}
+	if s.Regular() {
+		return text + ", regular"
+	}
+	return text + fmt.Sprintf(", %d problems", len(s.Problems))
+}
+
+func seriesName(number, description string) string {
+	if description != "" {
+		return "Series " + number + " " + description
+	}
+	return "Series " + number
+}
+
+// returns the plane of the orientation, axial, coronal or sagittal, oblique if it deviates from them
+func OrientationName(orientation []float64) string {
+	normal := cross([3]float64(orientation[:3]), [3]float64(orientation[3:]))
+	axis, largest := 0, 0.0
+	for i, v := range normal {
+		if math.Abs(v) > largest {
+			axis, largest = i, math.Abs(v)
+		}
+	}
+	name := [3]string{"sagittal", "coronal", "axial"}[axis]
+	if largest < 1-angleEpsilon {
+		return "oblique " + name
+	}
+	return name
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func parallel(a, b []float64) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > angleEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
+func cross(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
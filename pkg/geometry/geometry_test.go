@@ -0,0 +1,90 @@
+package geometry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+var (
+	axial   = []string{"1", "0", "0", "0", "1", "0"}
+	coronal = []string{"1", "0", "0", "0", "0", "-1"}
+)
+
+func newSlice(t *testing.T, filename, series string, z float64, orientation []string) dicomtree.Entry {
+	ds := func(tg tag.Tag, values ...string) *dicom.Element {
+		e := mustElement(t, tg, values)
+		e.RawValueRepresentation = "DS"
+		return e
+	}
+	return dicomtree.Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SeriesInstanceUID, []string{series}),
+		mustElement(t, tag.SeriesNumber, []string{series[len(series)-1:]}),
+		ds(tag.SliceThickness, "2.5"),
+		ds(tag.ImagePositionPatient, "-128", "-128", fmt.Sprint(z)),
+		ds(tag.ImageOrientationPatient, orientation...),
+		mustElement(t, tag.Rows, []int{512}),
+		mustElement(t, tag.Columns, []int{512}),
+		ds(tag.PixelSpacing, "0.5", "0.5"),
+	}}}
+}
+
+func TestAnalyze(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []dicomtree.Entry{
+		newSlice(t, "a.dcm", "1.1", 0, axial),
+		newSlice(t, "b.dcm", "1.1", 2, axial),
+		newSlice(t, "c.dcm", "1.1", 1, axial),
+		newSlice(t, "d.dcm", "1.1", 4, axial),
+		newSlice(t, "e.dcm", "1.1", 5, coronal),
+		newSlice(t, "f.dcm", "1.2", 0, axial),
+		newSlice(t, "g.dcm", "1.2", 2.5, axial),
+		newSlice(t, "h.dcm", "1.2", 5, axial),
+		{Filename: "report.dcm"},
+	}
+	series := Analyze(entries)
+	require.Len(t, series, 2)
+
+	assert.False(series[0].Regular())
+	assert.Equal([]string{"a.dcm", "c.dcm", "b.dcm", "d.dcm"}, []string{series[0].Slices[0].Entry.Filename, series[0].Slices[1].Entry.Filename,
+		series[0].Slices[2].Entry.Filename, series[0].Slices[3].Entry.Filename}, "sorted along the normal")
+	assert.Equal([]Problem{
+		{"orientation differs from the first file a.dcm", &entries[4]},
+		{"gap of 2 mm after b.dcm, about 1 missing slices", &entries[3]},
+	}, series[0].Problems)
+	assert.Equal("Series 1: 4 slices, spacing 1 mm (1..2), thickness 2.5 mm, extent 256 x 256 x 4 mm, axial, 2 problems", series[0].Summary())
+
+	assert.True(series[1].Regular())
+	assert.Equal(2.5, series[1].Spacing)
+	assert.Equal("Series 2: 3 slices, spacing 2.5 mm, thickness 2.5 mm, extent 256 x 256 x 5 mm, axial, regular", series[1].Summary())
+
+	entries = []dicomtree.Entry{newSlice(t, "a.dcm", "1.1", 0, axial), newSlice(t, "b.dcm", "1.1", 0, axial), newSlice(t, "c.dcm", "1.1", 1.2, axial)}
+	entries[2].Dataset.Elements[3].Value = mustElement(t, tag.ImagePositionPatient, []string{"-120", "-128", "1.2"}).Value
+	assert.Equal([]Problem{
+		{"same position as a.dcm", &entries[1]},
+		{"position is not on the slice normal through the first slice, e.g. gantry tilt", &entries[2]},
+	}, Analyze(entries)[0].Problems)
+}
+
+func TestOrientationName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("axial", OrientationName([]float64{1, 0, 0, 0, 1, 0}))
+	assert.Equal("coronal", OrientationName([]float64{1, 0, 0, 0, 0, -1}))
+	assert.Equal("sagittal", OrientationName([]float64{0, 1, 0, 0, 0, -1}))
+	assert.Equal("oblique axial", OrientationName([]float64{1, 0, 0, 0, 0.98, -0.2}))
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
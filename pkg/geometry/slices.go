@@ -0,0 +1,178 @@
+package geometry
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// the suspect instances of a series: missing and duplicate instance numbers, duplicate positions and irregular
+// spacing of the slice locations
+type SliceCheck struct {
+	UID           string
+	Number        string
+	Description   string
+	Files         int
+	FirstInstance int // lowest instance number, 0 if no file has one
+	LastInstance  int
+	Problems      []Problem
+}
+
+// checks the instance numbers, image positions and slice locations of each series of the entries with any of them, in
+// the order of their first file
+func CheckSlices(entries []dicomtree.Entry) []SliceCheck {
+	indexByUID := make(map[string]int)
+	checks := make([]SliceCheck, 0)
+	var entriesBySeries [][]*dicomtree.Entry
+	for i := range entries {
+		entry := &entries[i]
+		if dicomtree.FindItemElement(entry.Dataset.Elements, tag.InstanceNumber) == nil &&
+			dicomtree.FindItemElement(entry.Dataset.Elements, tag.SliceLocation) == nil &&
+			dicomtree.FindItemElement(entry.Dataset.Elements, tag.ImagePositionPatient) == nil {
+			continue
+		}
+		uid := dicomtree.UIDValue(&entry.Dataset, tag.SeriesInstanceUID)
+		index, ok := indexByUID[uid]
+		if !ok {
+			index = len(checks)
+			indexByUID[uid] = index
+			checks = append(checks, SliceCheck{
+				UID:         uid,
+				Number:      dicomtree.ItemValue(entry.Dataset.Elements, tag.SeriesNumber),
+				Description: dicomtree.ItemValue(entry.Dataset.Elements, tag.SeriesDescription),
+			})
+			entriesBySeries = append(entriesBySeries, nil)
+		}
+		entriesBySeries[index] = append(entriesBySeries[index], entry)
+	}
+	for i := range checks {
+		checks[i].check(entriesBySeries[i])
+	}
+	return checks
+}
+
+func (c *SliceCheck) check(entries []*dicomtree.Entry) {
+	c.Files = len(entries)
+	c.checkInstances(entries)
+	samePosition := c.checkPositions(entries)
+	c.checkLocations(entries, samePosition)
+}
+
+func (c *SliceCheck) checkInstances(entries []*dicomtree.Entry) {
+	type instance struct {
+		number int
+		entry  *dicomtree.Entry
+	}
+	var instances []instance
+	for _, entry := range entries {
+		number, err := strconv.Atoi(strings.TrimSpace(dicomtree.ItemValue(entry.Dataset.Elements, tag.InstanceNumber)))
+		if err != nil {
+			c.Problems = append(c.Problems, Problem{"no valid instance number", entry})
+			continue
+		}
+		instances = append(instances, instance{number, entry})
+	}
+	if len(instances) == 0 {
+		return
+	}
+	slices.SortStableFunc(instances, func(a, b instance) int { return cmp.Compare(a.number, b.number) })
+	c.FirstInstance, c.LastInstance = instances[0].number, instances[len(instances)-1].number
+	for i := 1; i < len(instances); i++ {
+		previous, current := instances[i-1], instances[i]
+		switch {
+		case current.number == previous.number:
+			c.Problems = append(c.Problems, Problem{fmt.Sprintf("instance number %d also in %s", current.number,
+				previous.entry.Filename), current.entry})
+		case current.number == previous.number+2:
+			c.Problems = append(c.Problems, Problem{fmt.Sprintf("instance number %d missing before", previous.number+1),
+				current.entry})
+		case current.number > previous.number+2:
+			c.Problems = append(c.Problems, Problem{fmt.Sprintf("instance numbers %d-%d missing before", previous.number+1,
+				current.number-1), current.entry})
+		}
+	}
+}
+
+// reports files with the position of another file and returns them
+func (c *SliceCheck) checkPositions(entries []*dicomtree.Entry) map[*dicomtree.Entry]bool {
+	samePosition := make(map[*dicomtree.Entry]bool)
+	firstByPosition := make(map[[3]float64]*dicomtree.Entry)
+	for _, entry := range entries {
+		position := numbers(entry, tag.ImagePositionPatient)
+		if len(position) != 3 {
+			continue
+		}
+		var key [3]float64 // rounded to the tolerance
+		for i, v := range position {
+			key[i] = math.Round(v / tolerance)
+		}
+		if first, ok := firstByPosition[key]; ok {
+			c.Problems = append(c.Problems, Problem{"same position as " + first.Filename, entry})
+			samePosition[entry] = true
+			continue
+		}
+		firstByPosition[key] = entry
+	}
+	return samePosition
+}
+
+// reports duplicate slice locations of files not reported for their position, gaps and irregular spacing
+func (c *SliceCheck) checkLocations(entries []*dicomtree.Entry, samePosition map[*dicomtree.Entry]bool) {
+	type location struct {
+		value float64
+		entry *dicomtree.Entry
+	}
+	var locations []location
+	for _, entry := range entries {
+		if values := numbers(entry, tag.SliceLocation); len(values) == 1 {
+			locations = append(locations, location{values[0], entry})
+		}
+	}
+	slices.SortStableFunc(locations, func(a, b location) int { return cmp.Compare(a.value, b.value) })
+	var spacings []float64
+	for i := 1; i < len(locations); i++ {
+		if spacing := locations[i].value - locations[i-1].value; spacing >= tolerance {
+			spacings = append(spacings, spacing)
+		}
+	}
+	median := 0.0
+	if len(spacings) > 0 {
+		sorted := slices.Clone(spacings)
+		slices.Sort(sorted)
+		median = sorted[len(sorted)/2]
+	}
+	for i := 1; i < len(locations); i++ {
+		previous, current := locations[i-1], locations[i]
+		spacing := current.value - previous.value
+		switch {
+		case spacing < tolerance:
+			if !samePosition[current.entry] {
+				c.Problems = append(c.Problems, Problem{"same slice location as " + previous.entry.Filename, current.entry})
+			}
+		case spacing > median*gapFactor:
+			c.Problems = append(c.Problems, Problem{fmt.Sprintf("slice location gap of %s mm after %s, about %d missing slices",
+				format(spacing), previous.entry.Filename, int(math.Round(spacing/median))-1), current.entry})
+		case math.Abs(spacing-median) > max(tolerance, median*0.01):
+			c.Problems = append(c.Problems, Problem{fmt.Sprintf("irregular slice location spacing of %s mm after %s instead of %s mm",
+				format(spacing), previous.entry.Filename, format(median)), current.entry})
+		}
+	}
+}
+
+// returns a line with the number of files, the range of the instance numbers and the number of problems
+func (c SliceCheck) Summary() string {
+	text := fmt.Sprintf("%s: %d files", seriesName(c.Number, c.Description), c.Files)
+	if c.LastInstance > 0 {
+		text += fmt.Sprintf(", instances %d..%d", c.FirstInstance, c.LastInstance)
+	}
+	if len(c.Problems) == 0 {
+		return text + ", no suspect instances"
+	}
+	return text + fmt.Sprintf(", %d suspect instances", len(c.Problems))
+}
@@ -0,0 +1,50 @@
+package geometry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestCheckSlices(t *testing.T) {
+	assert := assert.New(t)
+
+	slice := func(filename string, instance int, z float64) dicomtree.Entry {
+		entry := newSlice(t, filename, "1.1", z, axial)
+		location := mustElement(t, tag.SliceLocation, []string{fmt.Sprint(z)})
+		location.RawValueRepresentation = "DS"
+		entry.Dataset.Elements = append(entry.Dataset.Elements, mustElement(t, tag.InstanceNumber, []string{fmt.Sprint(instance)}), location)
+		return entry
+	}
+	entries := []dicomtree.Entry{
+		slice("a.dcm", 1, 0),
+		slice("b.dcm", 2, 2),
+		slice("c.dcm", 2, 4),
+		slice("d.dcm", 6, 4),
+		slice("e.dcm", 7, 10),
+		slice("f.dcm", 8, 11),
+		slice("g.dcm", 9, 13),
+		{Filename: "report.dcm"},
+	}
+	entries[3].Dataset.Elements[3].Value = mustElement(t, tag.ImagePositionPatient, []string{"-120", "-128", "4"}).Value
+	checks := CheckSlices(entries)
+	require.Len(t, checks, 1)
+	assert.Equal([]Problem{
+		{"instance number 2 also in b.dcm", &entries[2]},
+		{"instance numbers 3-5 missing before", &entries[3]},
+		{"same slice location as c.dcm", &entries[3]},
+		{"slice location gap of 6 mm after d.dcm, about 2 missing slices", &entries[4]},
+		{"irregular slice location spacing of 1 mm after e.dcm instead of 2 mm", &entries[5]},
+	}, checks[0].Problems)
+	assert.Equal("Series 1: 7 files, instances 1..9, 5 suspect instances", checks[0].Summary())
+
+	entries = []dicomtree.Entry{slice("a.dcm", 1, 0), slice("b.dcm", 3, 0)}
+	assert.Equal([]Problem{
+		{"instance number 2 missing before", &entries[1]},
+		{"same position as a.dcm", &entries[1]},
+	}, CheckSlices(entries)[0].Problems, "the slice location of a file with the same position isn't reported again")
+}
@@ -0,0 +1,172 @@
+// Package hook runs the commands configured for events of files, e.g. an external validator before a file is written
+// or a script filling in InstitutionName after a file is loaded. A command gets the dataset in the DICOM JSON model on
+// stdin and may print attributes to change on stdout.
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dump"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/drcynic/dcmtagger/pkg/tool"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// events commands can be configured for
+const (
+	Load = "load" // after a file is parsed
+	Save = "save" // before a file is written, a failing command prevents the write
+)
+
+// the JSON object on stdin of a command
+type input struct {
+	Event   string      `json:"event"`
+	File    string      `json:"file"`
+	Dataset interface{} `json:"dataset"`
+}
+
+// an attribute of the DICOM JSON model printed by a command
+type attribute struct {
+	VR    string            `json:"vr"`
+	Value []json.RawMessage `json:"Value"`
+}
+
+// runs the command with the shell (see tool.Command) for the event of the file, with the event and the dataset on
+// stdin as JSON object {"event": ..., "file": ..., "dataset": {...}}. The path is also passed in DCMTAGGER_FILE and
+// the event in DCMTAGGER_EVENT. A command exiting with an error fails with its stderr. Its stdout may be empty or a
+// JSON object of attributes in the DICOM JSON model, which replace the top level elements of the dataset, all others
+// are kept. Attributes of protected tags fail the command.
+func Run(command, event, path string, dataset *dicom.Dataset, protected edit.ProtectedTags) error {
+	stdin, err := json.Marshal(input{Event: event, File: path, Dataset: dump.DatasetJSON(dataset)})
+	if err != nil {
+		return err
+	}
+	cmd := tool.Command(command)
+	cmd.Env = append(os.Environ(), "DCMTAGGER_EVENT="+event, tool.FileVariable+"="+path)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%s: %s", err.Error(), message)
+		}
+		return err
+	}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil
+	}
+	return Apply(dataset, stdout.Bytes(), protected)
+}
+
+// runs the commands of the event for the file of the entry in their order, see Run, the first failing one stops the
+// others
+func RunAll(commands []string, event string, entry *dicomtree.Entry, protected edit.ProtectedTags) error {
+	for _, command := range commands {
+		if err := Run(command, event, entry.Path, &entry.Dataset, protected); err != nil {
+			return fmt.Errorf("%s hook '%s' failed for %s: %w", event, command, entry.Filename, err)
+		}
+	}
+	return nil
+}
+
+// sets the attributes of the JSON object in the DICOM JSON model in the dataset. Text values are encoded with the
+// specific character set of the dataset, sequences and binary values are not supported. Nothing is set if one of the
+// tags is protected.
+func Apply(dataset *dicom.Dataset, data []byte, protected edit.ProtectedTags) error {
+	var attributes map[string]attribute
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return fmt.Errorf("invalid output, expected DICOM JSON attributes: %w", err)
+	}
+	tags := make(map[string]tag.Tag, len(attributes))
+	for key := range attributes {
+		t, err := dicomtree.ParseTag(key)
+		if err != nil {
+			return fmt.Errorf("invalid output: %w", err)
+		}
+		if err := protected.Check(t); err != nil {
+			return fmt.Errorf("invalid output: %w", err)
+		}
+		tags[key] = t
+	}
+	charsets := charset.FromDataset(dataset)
+	for key, a := range attributes {
+		t := tags[key]
+		if err := set(dataset, t, a, charsets); err != nil {
+			return fmt.Errorf("invalid output for %s: %w", dicomtree.FormatTag(t), err)
+		}
+	}
+	return nil
+}
+
+func set(dataset *dicom.Dataset, t tag.Tag, a attribute, charsets []string) error {
+	switch a.VR {
+	case "SQ", "OB", "OD", "OF", "OL", "OV", "OW", "UN":
+		return fmt.Errorf("VR %s is not supported", a.VR)
+	case "US", "SS", "UL", "SL", "UV", "SV":
+		ints := make([]int, 0, len(a.Value))
+		for _, v := range a.Value {
+			var n int
+			if err := json.Unmarshal(v, &n); err != nil {
+				return err
+			}
+			ints = append(ints, n)
+		}
+		_, err := edit.Set(dataset, t, ints)
+		return err
+	case "FL", "FD":
+		floats := make([]float64, 0, len(a.Value))
+		for _, v := range a.Value {
+			var f float64
+			if err := json.Unmarshal(v, &f); err != nil {
+				return err
+			}
+			floats = append(floats, f)
+		}
+		_, err := edit.Set(dataset, t, floats)
+		return err
+	}
+	values := make([]string, 0, len(a.Value))
+	for _, v := range a.Value {
+		text, err := stringValue(v)
+		if err != nil {
+			return err
+		}
+		values = append(values, text)
+	}
+	e, err := edit.Set(dataset, t, values)
+	if err != nil {
+		return err
+	}
+	return edit.SetStrings(e, values, charsets)
+}
+
+// returns a string, number, null or person name value as text, person names with their component groups "A=I=P"
+func stringValue(v json.RawMessage) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(v, &value); err != nil {
+		return "", err
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case map[string]interface{}:
+		var groups [3]string
+		for i, name := range dicomtree.PersonNameGroups {
+			groups[i], _ = value[name].(string)
+		}
+		return dicomtree.JoinPersonName(groups), nil
+	}
+	return "", fmt.Errorf("unsupported value %s", string(v))
+}
@@ -0,0 +1,107 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/backup"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands use sh")
+	}
+	assert := assert.New(t)
+
+	dataset := &dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.PatientName, []string{"Doe^John"})}}
+	fill := `case "$(cat)" in *'"event":"load"'*Doe^John*) ;; *) exit 1 ;; esac; echo '{"00080080": {"vr": "LO", "Value": ["Clinic"]}}'`
+	require.NoError(t, Run(fill, Load, "a.dcm", dataset, nil))
+	assert.Equal("Clinic", dicomtree.ItemValue(dataset.Elements, tag.InstitutionName))
+
+	assert.NoError(Run(`test "$DCMTAGGER_EVENT $DCMTAGGER_FILE" = "save a.dcm"`, Save, "a.dcm", dataset, nil))
+	assert.EqualError(Run("echo 'missing PatientID' >&2; exit 3", Save, "a.dcm", dataset, nil), "exit status 3: missing PatientID")
+	assert.ErrorContains(Run("echo nonsense", Save, "a.dcm", dataset, nil), "invalid output")
+}
+
+func TestApply(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := &dicom.Dataset{}
+	require.NoError(t, Apply(dataset, []byte(`{
+		"00100010": {"vr": "PN", "Value": [{"Alphabetic": "Yamada^Tarou", "Ideographic": "山田^太郎"}]},
+		"00200013": {"vr": "IS", "Value": [7]},
+		"00280010": {"vr": "US", "Value": [512]},
+		"00081030": {"vr": "LO"}
+	}`), nil))
+	assert.Equal("Yamada^Tarou=山田^太郎", dicomtree.ItemValue(dataset.Elements, tag.PatientName))
+	assert.Equal("7", dicomtree.ItemValue(dataset.Elements, tag.InstanceNumber))
+	rows, err := dataset.FindElementByTag(tag.Rows)
+	require.NoError(t, err)
+	assert.Equal([]int{512}, rows.Value.GetValue())
+	description, err := dataset.FindElementByTag(tag.StudyDescription)
+	require.NoError(t, err)
+	assert.Empty(dicomtree.ValueStrings(description))
+
+	assert.ErrorContains(Apply(dataset, []byte(`{"00081115": {"vr": "SQ", "Value": []}}`), nil), "VR SQ is not supported")
+	assert.ErrorContains(Apply(dataset, []byte(`{"nonsense": {"vr": "PN"}}`), nil), "invalid output")
+
+	protected := edit.NewProtectedTags([]tag.Tag{tag.SOPClassUID})
+	err = Apply(dataset, []byte(`{"00080016": {"vr": "UI", "Value": ["1.2.3"]}, "00080080": {"vr": "LO", "Value": ["Clinic"]}}`), protected)
+	assert.ErrorIs(err, edit.ErrProtected)
+	assert.Empty(dicomtree.ItemValue(dataset.Elements, tag.InstitutionName), "nothing is set")
+}
+
+func TestWriter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands use sh")
+	}
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	data, err := os.ReadFile("../../testdata/test.dcm")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "a.dcm")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	dataset, err := dicom.ParseFile(path, nil)
+	require.NoError(t, err)
+	entry := &dicomtree.Entry{Filename: "a.dcm", Path: path, Dataset: dataset}
+
+	w := &Writer{Commands: []string{"exit 1"}, OutputDir: filepath.Join(dir, "out")}
+	_, err = w.Write(entry)
+	assert.EqualError(err, "save hook 'exit 1' failed for a.dcm: exit status 1")
+	assert.NoDirExists(filepath.Join(dir, "out"), "the hook aborts the write")
+	_, err = w.Save(entry, filepath.Join(dir, "copy.dcm"))
+	assert.Error(err)
+	assert.NoFileExists(filepath.Join(dir, "copy.dcm"), "the hook aborts save() of scripts")
+
+	w.Commands = []string{`echo '{"00080080": {"vr": "LO", "Value": ["Clinic"]}}'`}
+	written, err := w.Write(entry)
+	assert.NoError(err)
+	assert.Equal(filepath.Join(dir, "out", "a.dcm"), written)
+	assert.Equal("Clinic", dicomtree.ItemValue(entry.Dataset.Elements, tag.InstitutionName))
+
+	w.OutputDir = ""
+	w.Commands = []string{`echo '{"00080016": {"vr": "UI", "Value": ["1.2.3"]}}'`}
+	w.Protected = edit.NewProtectedTags(edit.DefaultProtectedTags)
+	_, err = w.Write(entry)
+	assert.ErrorIs(err, edit.ErrProtected)
+	backups, err := backup.List(path)
+	assert.NoError(err)
+	assert.Empty(backups, "the file is not overwritten")
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
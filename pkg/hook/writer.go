@@ -0,0 +1,61 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/drcynic/dcmtagger/pkg/backup"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+)
+
+// writes edited datasets after the save commands succeeded, shared by :w of the UI, save() of scripts and the
+// headless commands
+type Writer struct {
+	Commands  []string           // commands of the save event, run in their order
+	Protected edit.ProtectedTags // tags the commands must not change
+	OutputDir string             // files are written to this directory mirroring the input instead of in place if set
+}
+
+// writes the dataset of the entry to the output directory if set, else overwrites the file keeping the previous
+// version in the backup directory. Returns the written path.
+func (w *Writer) Write(entry *dicomtree.Entry) (string, error) {
+	if w.OutputDir == "" {
+		if err := entry.CheckOverwrite(); err != nil {
+			return entry.Path, err
+		}
+	}
+	if err := RunAll(w.Commands, Save, entry, w.Protected); err != nil {
+		return entry.Path, err
+	}
+	if w.OutputDir == "" {
+		return entry.Path, backup.WriteFile(entry.Dataset, entry.Path)
+	}
+	path := dicomtree.OutputPath(w.OutputDir, entry)
+	if sameFile(path, entry.Path) {
+		return path, fmt.Errorf("%s is the input file, choose another output directory", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return path, err
+	}
+	return path, dicomtree.WriteFile(entry.Dataset, path)
+}
+
+// writes the dataset of the entry for save() of scripts, to the path if it is another file than the one of the entry,
+// else like Write. A script.SaveFunc.
+func (w *Writer) Save(entry *dicomtree.Entry, path string) (string, error) {
+	if path == "" || sameFile(path, entry.Path) {
+		return w.Write(entry)
+	}
+	if err := RunAll(w.Commands, Save, entry, w.Protected); err != nil {
+		return path, err
+	}
+	return path, dicomtree.WriteFile(entry.Dataset, path)
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,336 @@
+// Package index caches the top level values of all files of a directory in a SQLite database, so that later
+// starts only parse changed files and tag statistics and filters are computed by queries.
+package index
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/filter"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// increase on every schema change, outdated indexes are recreated
+const schemaVersion = 1
+
+const schema = `
+CREATE TABLE files (
+	path     TEXT PRIMARY KEY,
+	filename TEXT NOT NULL,
+	size     INTEGER NOT NULL,
+	mtime    INTEGER NOT NULL
+);
+CREATE TABLE elements (
+	path       TEXT NOT NULL REFERENCES files(path) ON DELETE CASCADE,
+	tag        INTEGER NOT NULL,
+	vr         TEXT NOT NULL,
+	length     INTEGER NOT NULL,
+	value_type INTEGER NOT NULL,
+	value      TEXT NOT NULL,
+	data       BLOB NOT NULL,
+	PRIMARY KEY (path, tag)
+);
+CREATE INDEX elements_tag_value ON elements(tag, value);
+`
+
+// index of one directory, created with Open
+type Index struct {
+	db     *sql.DB
+	dir    string
+	filter dicomtree.PathFilter
+}
+
+// returns the file of the index of the given directory in the user cache directory
+func Path(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "dcmtagger", fmt.Sprintf("index-%x.sqlite", sha1.Sum([]byte(absDir)))), nil
+}
+
+// opens or creates the index of the given directory, call Update to bring it up to date
+func Open(dir string) (*Index, error) {
+	dbPath, err := Path(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if version != schemaVersion {
+		if err := createSchema(db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("creating index %s: %w", dbPath, err)
+		}
+	}
+	return &Index{db: db, dir: dir}, nil
+}
+
+func createSchema(db *sql.DB) error {
+	if _, err := db.Exec("DROP TABLE IF EXISTS elements; DROP TABLE IF EXISTS files"); err != nil {
+		return err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
+	return err
+}
+
+// restricts the indexed files to the ones matching the filter, files no longer matching are removed by Update
+func (ix *Index) SetPathFilter(filter dicomtree.PathFilter) {
+	ix.filter = filter
+}
+
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// parses the headers of the new and changed files of the directory (not recursive) and removes deleted ones, returns
+// the number of parsed files and the errors of the files that failed to parse, which are skipped and not indexed
+func (ix *Index) Update() (int, []error, error) {
+	files, err := os.ReadDir(ix.dir)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	type fileState struct{ size, mtime int64 }
+	indexed := make(map[string]fileState)
+	rows, err := ix.db.Query("SELECT path, size, mtime FROM files")
+	if err != nil {
+		return 0, nil, err
+	}
+	for rows.Next() {
+		var path string
+		var state fileState
+		if err := rows.Scan(&path, &state.size, &state.mtime); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		indexed[path] = state
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	tx, err := ix.db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	parsed := 0
+	var skipped []error
+	for _, f := range files {
+		path := filepath.Join(ix.dir, f.Name())
+		if f.IsDir() || !ix.filter.Match(path) {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return parsed, skipped, err
+		}
+		state, ok := indexed[path]
+		if ok && state.size == info.Size() && state.mtime == info.ModTime().UnixNano() {
+			delete(indexed, path)
+			continue
+		}
+
+		dataset, _, err := dicomtree.ParseFileWithoutPixelData(path)
+		if err != nil {
+			slog.Warn("error parsing file for the index", "path", path, "error", err)
+			skipped = append(skipped, fmt.Errorf("%s: %w", f.Name(), err))
+			continue // removed from the index below if it was indexed before
+		}
+		delete(indexed, path)
+		if err := insertDataset(tx, path, f.Name(), info.Size(), info.ModTime().UnixNano(), &dataset); err != nil {
+			return parsed, skipped, err
+		}
+		parsed++
+	}
+	for path := range indexed {
+		if _, err := tx.Exec("DELETE FROM files WHERE path = ?", path); err != nil {
+			return parsed, skipped, err
+		}
+	}
+
+	return parsed, skipped, tx.Commit()
+}
+
+// replaces the indexed values of the file by the top level elements with string, int or float values of the dataset
+func insertDataset(tx *sql.Tx, path, filename string, size, mtime int64, dataset *dicom.Dataset) error {
+	if _, err := tx.Exec("DELETE FROM files WHERE path = ?", path); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO files (path, filename, size, mtime) VALUES (?, ?, ?, ?)", path, filename, size, mtime); err != nil {
+		return err
+	}
+
+	charsets := charset.FromDataset(dataset)
+	for _, e := range dataset.Elements {
+		if e.Value == nil {
+			continue
+		}
+		switch e.Value.ValueType() {
+		case dicom.Strings, dicom.Ints, dicom.Floats:
+		default:
+			continue // binary data and sequences are only available after loading the file
+		}
+		var data bytes.Buffer
+		if err := gob.NewEncoder(&data).Encode(e.Value.GetValue()); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO elements (path, tag, vr, length, value_type, value, data) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			path, int64(dicomtree.TagOrder(e.Tag)), e.RawValueRepresentation, int64(e.ValueLength), int(e.Value.ValueType()),
+			dicomtree.ValueText(e, charsets), data.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns partial entries of all indexed files sorted by filename, see dicomtree.Entry.Load
+func (ix *Index) Entries() ([]dicomtree.Entry, error) {
+	rows, err := ix.db.Query(`
+		SELECT f.path, f.filename, e.tag, e.vr, e.length, e.value_type, e.data
+		FROM files f LEFT JOIN elements e ON e.path = f.path
+		ORDER BY f.filename, f.path, e.tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]dicomtree.Entry, 0)
+	for rows.Next() {
+		var path, filename string
+		var tagValue, length, valueType sql.NullInt64
+		var vr sql.NullString
+		var data []byte
+		if err := rows.Scan(&path, &filename, &tagValue, &vr, &length, &valueType, &data); err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 || entries[len(entries)-1].Path != path {
+			entries = append(entries, dicomtree.Entry{Filename: filename, Path: path, Partial: true})
+		}
+		if !tagValue.Valid {
+			continue // file without indexed elements
+		}
+
+		value, err := decodeValue(dicom.ValueType(valueType.Int64), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		entry := &entries[len(entries)-1]
+		entry.Dataset.Elements = append(entry.Dataset.Elements, &dicom.Element{
+			Tag:                    tag.Tag{Group: uint16(tagValue.Int64 >> 16), Element: uint16(tagValue.Int64)},
+			RawValueRepresentation: vr.String,
+			ValueLength:            uint32(length.Int64),
+			Value:                  value,
+		})
+	}
+	return entries, rows.Err()
+}
+
+func decodeValue(valueType dicom.ValueType, data []byte) (dicom.Value, error) {
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	switch valueType {
+	case dicom.Ints:
+		var values []int
+		if err := decoder.Decode(&values); err != nil {
+			return nil, err
+		}
+		return dicom.NewValue(values)
+	case dicom.Floats:
+		var values []float64
+		if err := decoder.Decode(&values); err != nil {
+			return nil, err
+		}
+		return dicom.NewValue(values)
+	default:
+		var values []string
+		if err := decoder.Decode(&values); err != nil {
+			return nil, err
+		}
+		return dicom.NewValue(values)
+	}
+}
+
+// returns the number of different values and value lengths per tag over all indexed files.
+// The ranges only cover numeric elements with a single value.
+func (ix *Index) TagStats() (dicomtree.TagStats, error) {
+	stats := dicomtree.NewTagStats()
+	if err := ix.db.QueryRow("SELECT COUNT(*) FROM files").Scan(&stats.FileCount); err != nil {
+		return stats, err
+	}
+	numeric := "(value_type IN (?, ?) OR vr IN ('DS', 'IS')) AND value != '' AND instr(value, '\\') = 0"
+	rows, err := ix.db.Query("SELECT tag, COUNT(DISTINCT value), COUNT(DISTINCT length), COUNT(DISTINCT path), "+
+		"MIN(CASE WHEN "+numeric+" THEN CAST(value AS REAL) END), MAX(CASE WHEN "+numeric+" THEN CAST(value AS REAL) END) "+
+		"FROM elements GROUP BY tag", int(dicom.Ints), int(dicom.Floats), int(dicom.Ints), int(dicom.Floats))
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tagValue int64
+		var valueCount, lengthCount, fileCount int
+		var minValue, maxValue sql.NullFloat64
+		if err := rows.Scan(&tagValue, &valueCount, &lengthCount, &fileCount, &minValue, &maxValue); err != nil {
+			return stats, err
+		}
+		t := tag.Tag{Group: uint16(tagValue >> 16), Element: uint16(tagValue)}
+		stats.ValueCounts[t] = valueCount
+		stats.LengthCounts[t] = lengthCount
+		stats.FileCounts[t] = fileCount
+		if minValue.Valid && maxValue.Valid {
+			stats.Ranges[t] = dicomtree.ValueRange{Min: minValue.Float64, Max: maxValue.Float64}
+		}
+	}
+	return stats, rows.Err()
+}
+
+// returns the paths of all indexed files matching the filter expression, sorted by filename
+func (ix *Index) Filter(expr *filter.Expr) ([]string, error) {
+	condition, args := expr.SQL()
+	rows, err := ix.db.Query("SELECT path FROM files WHERE "+condition+" ORDER BY filename, path", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
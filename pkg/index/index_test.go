@@ -0,0 +1,169 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// opens an index of an empty temporary directory with the cache in another temporary directory
+func openTestIndex(t *testing.T) *Index {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	ix, err := Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { ix.Close() })
+	return ix
+}
+
+func insertTestDataset(t *testing.T, ix *Index, filename string, patientName string, modality string, rows int) {
+	dataset := &dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{modality}),
+		mustElement(t, tag.PatientName, []string{patientName}),
+		mustElement(t, tag.Rows, []int{rows}),
+		mustElement(t, tag.PixelData, []byte{1, 2, 3, 4}),
+	}}
+	dataset.Elements[0].ValueLength = uint32(len(modality))
+	dataset.Elements[1].ValueLength = uint32(len(patientName))
+	tx, err := ix.db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, insertDataset(tx, filepath.Join(ix.dir, filename), filename, 0, 0, dataset))
+	require.NoError(t, tx.Commit())
+}
+
+func TestEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	ix := openTestIndex(t)
+	insertTestDataset(t, ix, "b.dcm", "Roe^Jane", "MR", 256)
+	insertTestDataset(t, ix, "a.dcm", "Doe^John", "CT", 512)
+
+	entries, err := ix.Entries()
+	assert.NoError(err)
+	assert.Len(entries, 2)
+	assert.Equal("a.dcm", entries[0].Filename)
+	assert.Equal(filepath.Join(ix.dir, "a.dcm"), entries[0].Path)
+	assert.True(entries[0].Partial)
+	assert.Len(entries[0].Dataset.Elements, 3, "pixel data is not indexed")
+
+	e, err := entries[1].Dataset.FindElementByTag(tag.PatientName)
+	assert.NoError(err)
+	assert.Equal([]string{"Roe^Jane"}, dicom.MustGetStrings(e.Value))
+	assert.Equal(uint32(8), e.ValueLength)
+	e, err = entries[1].Dataset.FindElementByTag(tag.Rows)
+	assert.NoError(err)
+	assert.Equal([]int{256}, dicom.MustGetInts(e.Value))
+}
+
+func TestTagStats(t *testing.T) {
+	assert := assert.New(t)
+
+	ix := openTestIndex(t)
+	insertTestDataset(t, ix, "a.dcm", "Doe^John", "CT", 512)
+	insertTestDataset(t, ix, "b.dcm", "Roe^Jane", "CT", 256)
+
+	stats, err := ix.TagStats()
+	assert.NoError(err)
+	assert.Equal(2, stats.FileCount)
+	assert.Equal(2, stats.FileCounts[tag.PatientName])
+	assert.Equal(dicomtree.ValueRange{Min: 256, Max: 512}, stats.Ranges[tag.Rows])
+	assert.NotContains(stats.Ranges, tag.PatientName)
+	assert.Equal(1, stats.ValueCounts[tag.Modality])
+	assert.Equal(2, stats.ValueCounts[tag.PatientName])
+	assert.Equal(1, stats.LengthCounts[tag.PatientName])
+	assert.Equal(0, stats.ValueCounts[tag.PixelData])
+}
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	ix := openTestIndex(t)
+	insertTestDataset(t, ix, "a.dcm", "Doe^John", "CT", 512)
+	insertTestDataset(t, ix, "b.dcm", "Roe^Jane", "MR", 256)
+	insertTestDataset(t, ix, "c.dcm", "Doe^Jane", "MR", 256)
+
+	for text, expected := range map[string][]string{
+		"0008,0060=MR":                       {"b.dcm", "c.dcm"},
+		"0010,0010~DOE":                      {"a.dcm", "c.dcm"},
+		"0008,0060!=CT and 0010,0010~jane":   {"b.dcm", "c.dcm"},
+		"0008,0060=CT or 0010,0010=Roe^Jane": {"a.dcm", "b.dcm"},
+		"0008,0060=US":                       {},
+		"Rows<300":                           {"b.dcm", "c.dcm"},
+		"Rows>1000":                          {},
+		"0010,0010<M":                        {"a.dcm", "c.dcm"},
+	} {
+		expr, err := filter.Parse(text)
+		assert.NoError(err)
+		paths, err := ix.Filter(expr)
+		assert.NoError(err, text)
+		filenames := make([]string, 0)
+		for _, path := range paths {
+			filenames = append(filenames, filepath.Base(path))
+		}
+		assert.Equal(expected, filenames, text)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	ix := openTestIndex(t)
+	data, err := os.ReadFile("../../testdata/test.dcm")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(ix.dir, "a.dcm"), data, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(ix.dir, "b.dcm"), data, 0o644))
+
+	require.NoError(t, os.WriteFile(filepath.Join(ix.dir, "c.dcm"), data[:200], 0o644))
+
+	parsed, skipped, err := ix.Update()
+	assert.NoError(err)
+	assert.Equal(2, parsed)
+	if assert.Len(skipped, 1, "the truncated file is skipped") {
+		assert.Contains(skipped[0].Error(), "c.dcm: ")
+	}
+	parsed, _, err = ix.Update()
+	assert.NoError(err)
+	assert.Equal(0, parsed, "unchanged files are not parsed again")
+
+	require.NoError(t, os.Remove(filepath.Join(ix.dir, "a.dcm")))
+	require.NoError(t, os.Remove(filepath.Join(ix.dir, "c.dcm")))
+	parsed, _, err = ix.Update()
+	assert.NoError(err)
+	assert.Equal(0, parsed)
+	entries, err := ix.Entries()
+	assert.NoError(err)
+	assert.Len(entries, 1)
+	assert.Equal("b.dcm", entries[0].Filename)
+}
+
+func TestOpenKeepsIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	ix, err := Open(dir)
+	require.NoError(t, err)
+	insertTestDataset(t, ix, "a.dcm", "Doe^John", "CT", 512)
+	ix.Close()
+
+	ix, err = Open(dir)
+	require.NoError(t, err)
+	defer ix.Close()
+	entries, err := ix.Entries()
+	assert.NoError(err)
+	assert.Len(entries, 1)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
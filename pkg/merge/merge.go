@@ -0,0 +1,150 @@
+// Package merge combines several exports of the same studies, e.g. two CDs of a patient, into one directory with each
+// instance only once.
+package merge
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/checksum"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// an instance found in several inputs with different content, only the first file is copied
+type Conflict struct {
+	SOPInstanceUID string
+	Kept           string // path of the copied file
+	Skipped        string
+}
+
+// outcome of Write
+type Result struct {
+	Written    []string // paths of the copied files
+	Duplicates int      // files skipped as identical to a copied one
+	Conflicts  []Conflict
+}
+
+// a file of an input that is not merged
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// media storage SOP class of DICOMDIRs, which only index the files of their own export
+const directoryStorageClass = "1.2.840.10008.1.3.10"
+
+// returns the files below the input directory, recursively, or the input file, parsed up to their pixel data with
+// their slash separated path relative to the input as filename. Files failing to parse, e.g. a README, and DICOMDIRs
+// are skipped.
+func Load(input string) ([]dicomtree.Entry, []Skipped, error) {
+	var entries []dicomtree.Entry
+	var skipped []Skipped
+	err := filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(input, path)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			name = d.Name()
+		}
+		dataset, _, err := dicomtree.ParseFileWithoutPixelData(path)
+		if err != nil {
+			skipped = append(skipped, Skipped{path, "no DICOM file: " + err.Error()})
+			return nil
+		}
+		if dicomtree.UIDValue(&dataset, tag.MediaStorageSOPClassUID) == directoryStorageClass {
+			skipped = append(skipped, Skipped{path, "DICOMDIR of the input"})
+			return nil
+		}
+		entries = append(entries, dicomtree.Entry{Filename: filepath.ToSlash(name), Path: path, Dataset: dataset})
+		return nil
+	})
+	return entries, skipped, err
+}
+
+type keptFile struct {
+	path   string
+	digest string
+}
+
+// copies the files of the inputs in order into the output directory, created if needed, to the path of their filename
+// relative to it, see Load, skipping files of an instance already copied. Files are the same instance if they have the
+// same SOP instance UID, or without UID the same content. Instances with the same UID but different content, e.g. of
+// another transfer syntax, are reported as conflicts. Paths taken by a file of another input get a suffix, e.g.
+// "DICOM/IM0001_2.dcm".
+func Write(inputs [][]dicomtree.Entry, output string) (Result, error) {
+	var result Result
+	if err := os.MkdirAll(output, 0o755); err != nil {
+		return result, err
+	}
+	kept := make(map[string]keptFile)
+	names := make(map[string]bool)
+	for _, entries := range inputs {
+		for i := range entries {
+			entry := &entries[i]
+			digest, err := checksum.File(entry.Path)
+			if err != nil {
+				return result, err
+			}
+			uid := dicomtree.UIDValue(&entry.Dataset, tag.SOPInstanceUID)
+			key := uid
+			if key == "" {
+				key = "sha256:" + digest
+			}
+			if first, ok := kept[key]; ok {
+				if first.digest == digest {
+					result.Duplicates++
+				} else {
+					result.Conflicts = append(result.Conflicts, Conflict{uid, first.path, entry.Path})
+				}
+				continue
+			}
+			path := dicomtree.OutputPath(output, &dicomtree.Entry{Filename: uniqueName(names, entry.Filename)})
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return result, err
+			}
+			if err := copyFile(entry.Path, path); err != nil {
+				return result, fmt.Errorf("error copying %s: %w", entry.Path, err)
+			}
+			kept[key] = keptFile{entry.Path, digest}
+			result.Written = append(result.Written, path)
+		}
+	}
+	return result, nil
+}
+
+// returns the name, or if already used the first free one with a suffix _2, _3 and so on, and marks it used
+func uniqueName(used map[string]bool, name string) string {
+	ext := filepath.Ext(name)
+	unique := name
+	for i := 2; used[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,99 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestWrite(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	entry := func(input, name, uid, content string) dicomtree.Entry {
+		path := filepath.Join(dir, input, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		var elements []*dicom.Element
+		if uid != "" {
+			elements = append(elements, mustElement(t, tag.SOPInstanceUID, []string{uid}))
+		}
+		return dicomtree.Entry{Filename: name, Path: path, Dataset: dicom.Dataset{Elements: elements}}
+	}
+	first := []dicomtree.Entry{
+		entry("cd1", "IM1", "1.1", "one"),
+		entry("cd1", "IM2", "1.2", "two"),
+		entry("cd1", "README", "", "readme"),
+	}
+	second := []dicomtree.Entry{
+		entry("cd2", "IM1", "1.2", "two"),          // same instance under another name
+		entry("cd2", "IM2", "1.3", "three"),        // another instance under a taken name
+		entry("cd2", "IM3", "1.1", "one, changed"), // same UID, other content
+		entry("cd2", "readme", "", "readme"),
+		entry("cd2", "sub/IM4", "1.4", "four"),
+	}
+
+	output := filepath.Join(dir, "merged")
+	result, err := Write([][]dicomtree.Entry{first, second}, output)
+	require.NoError(t, err)
+	assert.Equal([]string{filepath.Join(output, "IM1"), filepath.Join(output, "IM2"), filepath.Join(output, "README"),
+		filepath.Join(output, "IM2_2"), filepath.Join(output, "sub", "IM4")}, result.Written)
+	assert.Equal(2, result.Duplicates)
+	assert.Equal([]Conflict{{"1.1", first[0].Path, second[2].Path}}, result.Conflicts)
+	content, err := os.ReadFile(filepath.Join(output, "IM2_2"))
+	require.NoError(t, err)
+	assert.Equal("three", string(content))
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	data, err := os.ReadFile("../../testdata/test.dcm")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "DICOM", "ST1"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "DICOM", "ST1", "IM1"), data, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("no DICOM"), 0o644))
+	dicomdir, err := dicom.ParseFile("../../testdata/test.dcm", nil)
+	require.NoError(t, err)
+	_, err = edit.Set(&dicomdir, tag.MediaStorageSOPClassUID, []string{directoryStorageClass})
+	require.NoError(t, err)
+	require.NoError(t, dicomtree.WriteFile(dicomdir, filepath.Join(dir, "DICOMDIR")))
+
+	entries, skipped, err := Load(dir)
+	assert.NoError(err)
+	if assert.Len(entries, 1) {
+		assert.Equal("DICOM/ST1/IM1", entries[0].Filename)
+		assert.Equal(filepath.Join(dir, "DICOM", "ST1", "IM1"), entries[0].Path)
+	}
+	if assert.Len(skipped, 2) {
+		assert.Equal(Skipped{filepath.Join(dir, "DICOMDIR"), "DICOMDIR of the input"}, skipped[0])
+		assert.Equal(filepath.Join(dir, "README"), skipped[1].Path)
+	}
+
+	entries, _, err = Load(filepath.Join(dir, "DICOM", "ST1", "IM1"))
+	assert.NoError(err)
+	if assert.Len(entries, 1) {
+		assert.Equal("IM1", entries[0].Filename)
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	used := make(map[string]bool)
+	assert.Equal(t, "a.dcm", uniqueName(used, "a.dcm"))
+	assert.Equal(t, "A_2.dcm", uniqueName(used, "A.dcm"))
+	assert.Equal(t, "a_3.dcm", uniqueName(used, "a.dcm"))
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
@@ -0,0 +1,354 @@
+package multiframe
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// single-frame SOP classes of the enhanced and legacy converted enhanced multi-frame SOP classes
+var singleFrameSOPClasses = map[string]string{
+	"1.2.840.10008.5.1.4.1.1.2.1":   "1.2.840.10008.5.1.4.1.1.2",   // Enhanced CT
+	"1.2.840.10008.5.1.4.1.1.2.2":   "1.2.840.10008.5.1.4.1.1.2",   // Legacy Converted Enhanced CT
+	"1.2.840.10008.5.1.4.1.1.4.1":   "1.2.840.10008.5.1.4.1.1.4",   // Enhanced MR
+	"1.2.840.10008.5.1.4.1.1.4.4":   "1.2.840.10008.5.1.4.1.1.4",   // Legacy Converted Enhanced MR
+	"1.2.840.10008.5.1.4.1.1.128.1": "1.2.840.10008.5.1.4.1.1.128", // Legacy Converted Enhanced PET
+	"1.2.840.10008.5.1.4.1.1.130":   "1.2.840.10008.5.1.4.1.1.128", // Enhanced PET
+}
+
+// legacy converted enhanced SOP classes the single-frame SOP classes are assembled to
+var legacyConvertedSOPClasses = map[string]string{
+	"1.2.840.10008.5.1.4.1.1.2":   "1.2.840.10008.5.1.4.1.1.2.2",
+	"1.2.840.10008.5.1.4.1.1.4":   "1.2.840.10008.5.1.4.1.1.4.4",
+	"1.2.840.10008.5.1.4.1.1.128": "1.2.840.10008.5.1.4.1.1.128.1",
+}
+
+// functional group macros the attributes of single frames are moved to when assembling, shared if equal for all
+// frames
+var functionalGroupMacros = []struct {
+	sequence tag.Tag
+	tags     []tag.Tag
+}{
+	{tag.PixelMeasuresSequence, []tag.Tag{tag.SliceThickness, tag.PixelSpacing}},
+	{tag.PlanePositionSequence, []tag.Tag{tag.ImagePositionPatient}},
+	{tag.PlaneOrientationSequence, []tag.Tag{tag.ImageOrientationPatient}},
+	{tag.FrameVOILUTSequence, []tag.Tag{tag.WindowCenter, tag.WindowWidth}},
+	{tag.PixelValueTransformationSequence, []tag.Tag{tag.RescaleIntercept, tag.RescaleSlope, tag.RescaleType}},
+}
+
+// attributes describing a frame within the multi-frame object, dropped when splitting
+var frameContentTags = map[tag.Tag]bool{
+	tag.StackID:                true,
+	tag.InStackPositionNumber:  true,
+	tag.TemporalPositionIndex:  true,
+	tag.DimensionIndexValues:   true,
+	tag.FrameAcquisitionNumber: true,
+}
+
+// attributes of the multi-frame object that single frames don't have
+var multiFrameTags = map[tag.Tag]bool{
+	tag.SharedFunctionalGroupsSequence:   true,
+	tag.PerFrameFunctionalGroupsSequence: true,
+	tag.NumberOfFrames:                   true,
+	tag.PixelData:                        true,
+}
+
+// returns a single-frame dataset per frame of the enhanced multi-frame dataset with new SOP instance UIDs. The
+// attributes of the shared and per-frame functional groups are moved to the top level.
+func Split(dataset *dicom.Dataset) ([]dicom.Dataset, error) {
+	sopClass, ok := singleFrameSOPClasses[dicomtree.UIDValue(dataset, tag.SOPClassUID)]
+	if !ok {
+		return nil, fmt.Errorf("no single-frame SOP class for %s", dicomtree.SOPClassName(dicomtree.UIDValue(dataset, tag.SOPClassUID)))
+	}
+	e, err := dataset.FindElementByTag(tag.PixelData)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+		return nil, errors.New("no pixel data")
+	}
+	info := dicom.MustGetPixelDataInfo(e.Value)
+	var shared []*dicom.Element
+	if e, err := dataset.FindElementByTag(tag.SharedFunctionalGroupsSequence); err == nil {
+		if items := dicomtree.SequenceItems(e); len(items) > 0 {
+			shared = items[0]
+		}
+	}
+	var perFrame [][]*dicom.Element
+	if e, err := dataset.FindElementByTag(tag.PerFrameFunctionalGroupsSequence); err == nil {
+		perFrame = dicomtree.SequenceItems(e)
+	}
+	if len(perFrame) != 0 && len(perFrame) != len(info.Frames) {
+		return nil, fmt.Errorf("%d per-frame functional groups for %d frames", len(perFrame), len(info.Frames))
+	}
+
+	datasets := make([]dicom.Dataset, 0, len(info.Frames))
+	for i, f := range info.Frames {
+		single := dicom.Dataset{Elements: make([]*dicom.Element, 0, len(dataset.Elements))}
+		for _, e := range dataset.Elements {
+			if !multiFrameTags[e.Tag] {
+				single.Elements = append(single.Elements, e)
+			}
+		}
+		groups := [][]*dicom.Element{shared}
+		if len(perFrame) > 0 {
+			groups = append(groups, perFrame[i])
+		}
+		for _, group := range groups {
+			for _, macro := range group {
+				for _, item := range dicomtree.SequenceItems(macro) {
+					for _, e := range item {
+						if !dicomtree.IsSequence(e) && !frameContentTags[e.Tag] {
+							replace(&single, e)
+						}
+					}
+				}
+			}
+		}
+		uid := edit.NewUID()
+		for _, value := range []struct {
+			tag  tag.Tag
+			data interface{}
+		}{
+			{tag.SOPClassUID, []string{sopClass}},
+			{tag.SOPInstanceUID, []string{uid}},
+			{tag.InstanceNumber, []string{strconv.Itoa(i + 1)}},
+			{tag.PixelData, dicom.PixelDataInfo{Frames: []frame.Frame{f}, IsEncapsulated: info.IsEncapsulated}},
+		} {
+			if err := set(&single, value.tag, value.data); err != nil {
+				return nil, err
+			}
+		}
+		if err := updateFileMeta(&single, sopClass, uid); err != nil {
+			return nil, err
+		}
+		datasets = append(datasets, single)
+	}
+	return datasets, nil
+}
+
+// returns a legacy converted enhanced multi-frame dataset of the single-frame datasets of one series, ordered by
+// instance number. Attributes of the functional group macros go to the shared functional groups if they are equal
+// for all frames, else to the per-frame functional groups, which also reference the source instance of each frame.
+func Assemble(datasets []*dicom.Dataset) (dicom.Dataset, error) {
+	if len(datasets) == 0 {
+		return dicom.Dataset{}, errors.New("no datasets")
+	}
+	sorted := slices.Clone(datasets)
+	instanceNumber := func(dataset *dicom.Dataset) int {
+		n, _ := strconv.Atoi(dicomtree.UIDValue(dataset, tag.InstanceNumber))
+		return n
+	}
+	slices.SortStableFunc(sorted, func(a, b *dicom.Dataset) int { return cmp.Compare(instanceNumber(a), instanceNumber(b)) })
+	first := sorted[0]
+	sopClass, ok := legacyConvertedSOPClasses[dicomtree.UIDValue(first, tag.SOPClassUID)]
+	if !ok {
+		return dicom.Dataset{}, fmt.Errorf("no multi-frame SOP class for %s", dicomtree.SOPClassName(dicomtree.UIDValue(first, tag.SOPClassUID)))
+	}
+
+	var frames []frame.Frame
+	encapsulated := false
+	for i, dataset := range sorted {
+		for _, t := range []tag.Tag{tag.SOPClassUID, tag.SeriesInstanceUID, tag.Rows, tag.Columns, tag.BitsAllocated, tag.TransferSyntaxUID} {
+			if dicomtree.UIDValue(dataset, t) != dicomtree.UIDValue(first, t) {
+				return dicom.Dataset{}, fmt.Errorf("dataset %d differs in %s from the first", i+1, dicomtree.FormatTag(t))
+			}
+		}
+		e, err := dataset.FindElementByTag(tag.PixelData)
+		if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+			return dicom.Dataset{}, fmt.Errorf("dataset %d has no pixel data", i+1)
+		}
+		info := dicom.MustGetPixelDataInfo(e.Value)
+		frames, encapsulated = append(frames, info.Frames...), info.IsEncapsulated
+	}
+
+	macroTags := make(map[tag.Tag]bool)
+	shared, perFrame := []*dicom.Element{}, make([][]*dicom.Element, len(sorted))
+	for _, macro := range functionalGroupMacros {
+		var values []*dicom.Element
+		equal := true
+		for _, dataset := range sorted {
+			var item []*dicom.Element
+			for _, t := range macro.tags {
+				macroTags[t] = true
+				if e, err := dataset.FindElementByTag(t); err == nil {
+					item = append(item, e)
+				}
+			}
+			if len(item) > 0 && values == nil {
+				values = item
+			} else if len(item) > 0 && itemText(item) != itemText(values) {
+				equal = false
+			}
+		}
+		if values == nil {
+			continue
+		}
+		if equal {
+			shared = append(shared, sequenceElement(macro.sequence, values))
+			continue
+		}
+		for i, dataset := range sorted {
+			var item []*dicom.Element
+			for _, t := range macro.tags {
+				if e, err := dataset.FindElementByTag(t); err == nil {
+					item = append(item, e)
+				}
+			}
+			perFrame[i] = append(perFrame[i], sequenceElement(macro.sequence, item))
+		}
+	}
+	for i, dataset := range sorted {
+		source := []*dicom.Element{
+			uidElement(tag.ReferencedSOPClassUID, dicomtree.UIDValue(dataset, tag.SOPClassUID)),
+			uidElement(tag.ReferencedSOPInstanceUID, dicomtree.UIDValue(dataset, tag.SOPInstanceUID)),
+		}
+		derivation := []*dicom.Element{sequenceElement(tag.SourceImageSequence, source)}
+		perFrame[i] = append(perFrame[i], sequenceElement(tag.DerivationImageSequence, derivation))
+	}
+
+	assembled := dicom.Dataset{Elements: make([]*dicom.Element, 0, len(first.Elements))}
+	for _, e := range first.Elements {
+		if !macroTags[e.Tag] && e.Tag != tag.PixelData {
+			assembled.Elements = append(assembled.Elements, e)
+		}
+	}
+	uid := edit.NewUID()
+	for _, value := range []struct {
+		tag  tag.Tag
+		data interface{}
+	}{
+		{tag.SOPClassUID, []string{sopClass}},
+		{tag.SOPInstanceUID, []string{uid}},
+		{tag.InstanceNumber, []string{"1"}},
+		{tag.NumberOfFrames, []string{strconv.Itoa(len(frames))}},
+		{tag.SharedFunctionalGroupsSequence, [][]*dicom.Element{shared}},
+		{tag.PerFrameFunctionalGroupsSequence, perFrame},
+		{tag.PixelData, dicom.PixelDataInfo{Frames: frames, IsEncapsulated: encapsulated}},
+	} {
+		if err := set(&assembled, value.tag, value.data); err != nil {
+			return dicom.Dataset{}, err
+		}
+	}
+	return assembled, updateFileMeta(&assembled, sopClass, uid)
+}
+
+// returns the values of the elements for comparing items
+func itemText(elements []*dicom.Element) string {
+	text := ""
+	for _, e := range elements {
+		text += dicomtree.FormatTag(e.Tag) + "=" + dicomtree.ValueText(e, nil) + "\x00"
+	}
+	return text
+}
+
+// replaces or inserts the element without changing the element it replaces, which other datasets may share
+func replace(dataset *dicom.Dataset, e *dicom.Element) {
+	edit.Delete(dataset, e.Tag)
+	edit.InsertSorted(dataset, e)
+}
+
+// sets a new element with the data like replace
+func set(dataset *dicom.Dataset, t tag.Tag, data interface{}) error {
+	e, err := dicom.NewElement(t, data)
+	if err != nil {
+		return fmt.Errorf("error setting %s: %w", dicomtree.FormatTag(t), err)
+	}
+	replace(dataset, e)
+	return nil
+}
+
+// returns a sequence element with a single item
+func sequenceElement(t tag.Tag, item []*dicom.Element) *dicom.Element {
+	value, _ := dicom.NewValue([][]*dicom.Element{item})
+	return &dicom.Element{Tag: t, ValueRepresentation: tag.VRSequence, RawValueRepresentation: "SQ", Value: value}
+}
+
+// returns a UI element with the UID
+func uidElement(t tag.Tag, uid string) *dicom.Element {
+	value, _ := dicom.NewValue([]string{uid})
+	return &dicom.Element{Tag: t, ValueRepresentation: tag.VRStringList, RawValueRepresentation: "UI", Value: value}
+}
+
+// sets SOP class and instance of the file meta information if the dataset has one
+func updateFileMeta(dataset *dicom.Dataset, sopClass, uid string) error {
+	if _, err := dataset.FindElementByTag(tag.MediaStorageSOPInstanceUID); err != nil {
+		return nil
+	}
+	if err := set(dataset, tag.MediaStorageSOPClassUID, []string{sopClass}); err != nil {
+		return err
+	}
+	return set(dataset, tag.MediaStorageSOPInstanceUID, []string{uid})
+}
+
+// splits the enhanced multi-frame entries into single-frame files in the directory, created if needed, named like the
+// file with the frame number appended. Returns the written paths, other entries are skipped.
+func WriteSplit(entries []dicomtree.Entry, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for i := range entries {
+		if !IsMultiFrame(&entries[i].Dataset) {
+			continue
+		}
+		datasets, err := Split(&entries[i].Dataset)
+		if err != nil {
+			return paths, fmt.Errorf("%s: %w", entries[i].Filename, err)
+		}
+		base := strings.TrimSuffix(filepath.Base(entries[i].Filename), filepath.Ext(entries[i].Filename))
+		for f, dataset := range datasets {
+			path := filepath.Join(dir, fmt.Sprintf("%s_%04d.dcm", base, f+1))
+			if err := dicomtree.WriteFile(dataset, path); err != nil {
+				return paths, fmt.Errorf("error writing %s: %w", path, err)
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// assembles the single-frame CT, MR and PET entries of each series to a multi-frame file in the directory, created if
+// needed, named like the first file of the series. Returns the written paths, other entries are skipped.
+func WriteAssembled(entries []dicomtree.Entry, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var series []string
+	bySeries := make(map[string][]*dicom.Dataset)
+	firstFile := make(map[string]string)
+	for i := range entries {
+		dataset := &entries[i].Dataset
+		if _, ok := legacyConvertedSOPClasses[dicomtree.UIDValue(dataset, tag.SOPClassUID)]; !ok {
+			continue
+		}
+		uid := dicomtree.UIDValue(dataset, tag.SeriesInstanceUID)
+		if _, ok := bySeries[uid]; !ok {
+			series = append(series, uid)
+			firstFile[uid] = entries[i].Filename
+		}
+		bySeries[uid] = append(bySeries[uid], dataset)
+	}
+	var paths []string
+	for _, uid := range series {
+		assembled, err := Assemble(bySeries[uid])
+		if err != nil {
+			return paths, fmt.Errorf("series %s: %w", uid, err)
+		}
+		base := strings.TrimSuffix(filepath.Base(firstFile[uid]), filepath.Ext(firstFile[uid]))
+		path := filepath.Join(dir, base+"_multiframe.dcm")
+		if err := dicomtree.WriteFile(assembled, path); err != nil {
+			return paths, fmt.Errorf("error writing %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
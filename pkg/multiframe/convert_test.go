@@ -0,0 +1,80 @@
+package multiframe
+
+import (
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func nativeFrame(value int) frame.Frame {
+	return frame.Frame{NativeData: frame.NativeFrame{Data: [][]int{{value}, {value}, {value}, {value}}, Rows: 2, Cols: 2, BitsPerSample: 16}}
+}
+
+func TestSplitAndAssemble(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newEnhancedCT(t)
+	dataset.Elements = append(dataset.Elements,
+		mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2.1"}),
+		mustElement(t, tag.SOPInstanceUID, []string{"1.2.3"}),
+		mustElement(t, tag.SeriesInstanceUID, []string{"1.2.4"}),
+		mustElement(t, tag.Rows, []int{2}),
+		mustElement(t, tag.Columns, []int{2}),
+		mustElement(t, tag.NumberOfFrames, []string{"2"}),
+		mustElement(t, tag.PixelData, dicom.PixelDataInfo{Frames: []frame.Frame{nativeFrame(1), nativeFrame(2)}}),
+	)
+	singles, err := Split(&dataset)
+	require.NoError(t, err)
+	require.Len(t, singles, 2)
+	for i, single := range singles {
+		assert.Equal("1.2.840.10008.5.1.4.1.1.2", dicomtree.UIDValue(&single, tag.SOPClassUID))
+		assert.NotEqual("1.2.3", dicomtree.UIDValue(&single, tag.SOPInstanceUID))
+		assert.Equal([]string{"1", "2"}[i], dicomtree.UIDValue(&single, tag.InstanceNumber))
+		assert.Equal("1.5", dicomtree.UIDValue(&single, tag.SliceThickness))
+		assert.False(IsMultiFrame(&single))
+		_, err := single.FindElementByTag(tag.InStackPositionNumber)
+		assert.Error(err, "frame content is dropped")
+		e, err := single.FindElementByTag(tag.PixelData)
+		require.NoError(t, err)
+		assert.Equal([]frame.Frame{nativeFrame(i + 1)}, dicom.MustGetPixelDataInfo(e.Value).Frames)
+	}
+	assert.Equal("0\\0\\1.5", dicomtree.UIDValue(&singles[1], tag.ImagePositionPatient))
+	assert.Equal("1.2.840.10008.5.1.4.1.1.2.1", dicomtree.UIDValue(&dataset, tag.SOPClassUID), "the input is unchanged")
+
+	assembled, err := Assemble([]*dicom.Dataset{&singles[1], &singles[0]})
+	require.NoError(t, err)
+	assert.Equal("1.2.840.10008.5.1.4.1.1.2.2", dicomtree.UIDValue(&assembled, tag.SOPClassUID))
+	assert.Equal("2", dicomtree.UIDValue(&assembled, tag.NumberOfFrames))
+	_, err = assembled.FindElementByTag(tag.ImagePositionPatient)
+	assert.Error(err, "moved to the functional groups")
+	table := FunctionalGroupsTable(&assembled, []tag.Tag{tag.ImagePositionPatient, tag.SliceThickness, tag.SourceImageSequence})
+	require.Len(t, table.Rows, 2)
+	assert.Equal("0\\0\\0", table.Rows[0][0].Value)
+	assert.Equal("0\\0\\1.5", table.Rows[1][0].Value)
+	assert.False(table.Rows[1][0].Shared)
+	assert.True(table.Rows[1][1].Shared)
+	source := dicomtree.SequenceItems(table.Rows[1][2].Element)
+	require.Len(t, source, 1)
+	assert.Equal(dicomtree.UIDValue(&singles[1], tag.SOPInstanceUID), dicomtree.ItemValue(source[0], tag.ReferencedSOPInstanceUID), "source instance of the frame")
+	e, err := assembled.FindElementByTag(tag.PixelData)
+	require.NoError(t, err)
+	assert.Equal([]frame.Frame{nativeFrame(1), nativeFrame(2)}, dicom.MustGetPixelDataInfo(e.Value).Frames, "ordered by instance number")
+
+	_, err = Split(&dicom.Dataset{Elements: []*dicom.Element{mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.7"})}})
+	assert.Error(err)
+	_, err = Assemble([]*dicom.Dataset{&assembled})
+	assert.Error(err, "no single-frame SOP class")
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
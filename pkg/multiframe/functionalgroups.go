@@ -0,0 +1,109 @@
+// Package multiframe flattens the functional groups of enhanced multi-frame objects into a table with a row per frame.
+package multiframe
+
+import (
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// attributes of the functional groups shown by default, those varying per frame in enhanced CT and MR images
+var DefaultTags = []tag.Tag{
+	tag.InStackPositionNumber,
+	tag.TemporalPositionIndex,
+	tag.DimensionIndexValues,
+	tag.FrameAcquisitionDateTime,
+	tag.ImagePositionPatient,
+	tag.ImageOrientationPatient,
+	tag.SliceThickness,
+	tag.PixelSpacing,
+	tag.WindowCenter,
+	tag.WindowWidth,
+	tag.RescaleIntercept,
+	tag.RescaleSlope,
+}
+
+type Cell struct {
+	Element *dicom.Element // nil if neither the frame nor the shared functional groups contain the attribute
+	Value   string
+	Shared  bool // from the shared functional groups
+}
+
+// attributes of the functional groups of all frames, Rows[frame][column] for the tags
+type Table struct {
+	Tags []tag.Tag
+	Rows [][]Cell
+}
+
+func IsMultiFrame(dataset *dicom.Dataset) bool {
+	_, err := dataset.FindElementByTag(tag.PerFrameFunctionalGroupsSequence)
+	return err == nil
+}
+
+// returns the table of the attributes with the tags for each item of the per-frame functional groups, attributes
+// missing for a frame are taken from the shared functional groups
+func FunctionalGroupsTable(dataset *dicom.Dataset, tags []tag.Tag) Table {
+	var shared []*dicom.Element
+	if e, err := dataset.FindElementByTag(tag.SharedFunctionalGroupsSequence); err == nil {
+		if items := dicomtree.SequenceItems(e); len(items) > 0 {
+			shared = items[0]
+		}
+	}
+	table := Table{Tags: tags, Rows: make([][]Cell, 0)}
+	e, err := dataset.FindElementByTag(tag.PerFrameFunctionalGroupsSequence)
+	if err != nil {
+		return table
+	}
+	for _, frame := range dicomtree.SequenceItems(e) {
+		row := make([]Cell, len(tags))
+		for i, t := range tags {
+			if found := FindInFunctionalGroups(frame, t); found != nil {
+				row[i] = Cell{Element: found, Value: dicomtree.ValueText(found, nil)}
+			} else if found := FindInFunctionalGroups(shared, t); found != nil {
+				row[i] = Cell{Element: found, Value: dicomtree.ValueText(found, nil), Shared: true}
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// returns the element with the tag within the functional group sequences of a frame or the shared item, nil if none
+// of them contains it
+func FindInFunctionalGroups(groups []*dicom.Element, t tag.Tag) *dicom.Element {
+	for _, group := range groups {
+		if group.Tag == t {
+			return group
+		}
+		for _, item := range dicomtree.SequenceItems(group) {
+			if e := dicomtree.FindItemElement(item, t); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// returns the table without the columns of attributes no frame has, e.g. of the default tags
+func (table Table) WithoutEmptyColumns() Table {
+	var columns []int
+	for i := range table.Tags {
+		for _, row := range table.Rows {
+			if row[i].Element != nil {
+				columns = append(columns, i)
+				break
+			}
+		}
+	}
+	result := Table{Tags: make([]tag.Tag, 0, len(columns)), Rows: make([][]Cell, len(table.Rows))}
+	for _, i := range columns {
+		result.Tags = append(result.Tags, table.Tags[i])
+	}
+	for r, row := range table.Rows {
+		result.Rows[r] = make([]Cell, 0, len(columns))
+		for _, i := range columns {
+			result.Rows[r] = append(result.Rows[r], row[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package multiframe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func sequence(t *testing.T, tg tag.Tag, items ...[]*dicom.Element) *dicom.Element {
+	return mustElement(t, tg, items)
+}
+
+// returns an enhanced CT of two frames with the slice thickness shared and the positions per frame
+func newEnhancedCT(t *testing.T) dicom.Dataset {
+	frame := func(position int, z string) []*dicom.Element {
+		return []*dicom.Element{
+			sequence(t, tag.FrameContentSequence, []*dicom.Element{mustElement(t, tag.InStackPositionNumber, []int{position})}),
+			sequence(t, tag.PlanePositionSequence, []*dicom.Element{mustElement(t, tag.ImagePositionPatient, []string{"0", "0", z})}),
+		}
+	}
+	return dicom.Dataset{Elements: []*dicom.Element{
+		sequence(t, tag.SharedFunctionalGroupsSequence, []*dicom.Element{
+			sequence(t, tag.PixelMeasuresSequence, []*dicom.Element{mustElement(t, tag.SliceThickness, []string{"1.5"})}),
+		}),
+		sequence(t, tag.PerFrameFunctionalGroupsSequence, frame(1, "0"), frame(2, "1.5")),
+	}}
+}
+
+func TestFunctionalGroupsTable(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newEnhancedCT(t)
+	assert.True(IsMultiFrame(&dataset))
+	table := FunctionalGroupsTable(&dataset, DefaultTags).WithoutEmptyColumns()
+	assert.Equal([]tag.Tag{tag.InStackPositionNumber, tag.ImagePositionPatient, tag.SliceThickness}, table.Tags)
+	require.Len(t, table.Rows, 2)
+	values := func(row []Cell) []string {
+		texts := make([]string, 0, len(row))
+		for _, cell := range row {
+			texts = append(texts, cell.Value)
+		}
+		return texts
+	}
+	assert.Equal([]string{"1", "0\\0\\0", "1.5"}, values(table.Rows[0]))
+	assert.Equal([]string{"2", "0\\0\\1.5", "1.5"}, values(table.Rows[1]))
+	assert.False(table.Rows[1][1].Shared)
+	assert.True(table.Rows[1][2].Shared)
+	assert.Same(table.Rows[0][2].Element, table.Rows[1][2].Element)
+
+	table = FunctionalGroupsTable(&dataset, []tag.Tag{tag.WindowCenter})
+	assert.Equal([][]Cell{{{}}, {{}}}, table.Rows, "missing attributes are empty cells")
+	assert.False(IsMultiFrame(&dicom.Dataset{}))
+}
@@ -0,0 +1,144 @@
+// Package notes keeps free-text notes on files and elements in a JSON sidecar file, e.g. to review a study with a
+// colleague.
+package notes
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// name of the sidecar file in an input directory
+const SidecarName = "dcmtagger-notes.json"
+
+// a note on a file or a top level element of it
+type Note struct {
+	File string    `json:"file"`
+	Tag  string    `json:"tag,omitempty"` // "(gggg,eeee)" of the element, empty for a note on the file
+	Name string    `json:"name,omitempty"`
+	Text string    `json:"text"`
+	Time time.Time `json:"time"`
+}
+
+// the notes of a sidecar file, sorted by file and tag
+type Notes struct {
+	path string
+	All  []Note `json:"notes"`
+}
+
+// returns the sidecar of an input: dcmtagger-notes.json in a directory, <file>.notes.json next to a file and "" for
+// other inputs like URLs
+func DefaultPath(input string) string {
+	info, err := os.Stat(input)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return filepath.Join(input, SidecarName)
+	}
+	return input + ".notes.json"
+}
+
+// loads the sidecar file, no notes if it doesn't exist yet
+func Load(path string) (*Notes, error) {
+	n := &Notes{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return n, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	n.sort()
+	return n, nil
+}
+
+// writes the notes to the sidecar file, which is removed if there are none
+func (n *Notes) Save() error {
+	if len(n.All) == 0 {
+		if err := os.Remove(n.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(n.path, append(data, '\n'), 0o644)
+}
+
+// returns the path of the sidecar file
+func (n *Notes) Path() string {
+	return n.path
+}
+
+// returns the note on the file or on its element with the tag, tag "" for the file
+func (n *Notes) Find(file, tag string) (Note, bool) {
+	i := slices.IndexFunc(n.All, func(note Note) bool { return note.File == file && note.Tag == tag })
+	if i < 0 {
+		return Note{}, false
+	}
+	return n.All[i], true
+}
+
+// returns whether the file or any of its elements has a note
+func (n *Notes) HasFile(file string) bool {
+	return slices.ContainsFunc(n.All, func(note Note) bool { return note.File == file })
+}
+
+// adds the note or replaces the existing one on the same file and tag, a note without text is removed. Returns
+// whether the notes changed.
+func (n *Notes) Set(note Note) bool {
+	i := slices.IndexFunc(n.All, func(existing Note) bool { return existing.File == note.File && existing.Tag == note.Tag })
+	switch {
+	case i < 0 && note.Text == "":
+		return false
+	case i < 0:
+		n.All = append(n.All, note)
+	case note.Text == "":
+		n.All = slices.Delete(n.All, i, i+1)
+	default:
+		n.All[i] = note
+	}
+	n.sort()
+	return true
+}
+
+func (n *Notes) sort() {
+	slices.SortStableFunc(n.All, func(a, b Note) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+}
+
+func (n *Notes) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	all := n.All
+	if all == nil {
+		all = []Note{}
+	}
+	return encoder.Encode(all)
+}
+
+func (n *Notes) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "file", "tag", "name", "note"})
+	for _, note := range n.All {
+		writer.Write([]string{note.Time.Format(time.RFC3339), note.File, note.Tag, note.Name, note.Text})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,60 @@
+package notes
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotes(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, SidecarName)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	n, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(n.All, "no sidecar yet")
+	assert.True(n.Set(Note{File: "b.dcm", Tag: "(0010,0010)", Name: "PatientName", Text: "misspelled", Time: now}))
+	assert.True(n.Set(Note{File: "a.dcm", Text: "wrong series", Time: now}))
+	assert.False(n.Set(Note{File: "c.dcm"}), "removing a missing note")
+	require.NoError(t, n.Save())
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(n.All, loaded.All)
+	assert.Equal("a.dcm", loaded.All[0].File, "sorted by file")
+	note, ok := loaded.Find("b.dcm", "(0010,0010)")
+	assert.True(ok)
+	assert.Equal("misspelled", note.Text)
+	_, ok = loaded.Find("b.dcm", "")
+	assert.False(ok)
+	assert.True(loaded.HasFile("b.dcm"))
+	assert.False(loaded.HasFile("c.dcm"))
+
+	var csv bytes.Buffer
+	require.NoError(t, loaded.WriteCSV(&csv))
+	assert.Equal("time,file,tag,name,note\n2024-03-01T12:00:00Z,a.dcm,,,wrong series\n"+
+		"2024-03-01T12:00:00Z,b.dcm,\"(0010,0010)\",PatientName,misspelled\n", csv.String())
+
+	assert.True(loaded.Set(Note{File: "a.dcm"}))
+	assert.True(loaded.Set(Note{File: "b.dcm", Tag: "(0010,0010)"}))
+	require.NoError(t, loaded.Save())
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err), "the sidecar of no notes is removed")
+
+	assert.Equal(filepath.Join(dir, SidecarName), DefaultPath(dir))
+	file := filepath.Join(dir, "a.dcm")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	assert.Equal(file+".notes.json", DefaultPath(file))
+	assert.Equal("", DefaultPath("https://example.org/study/"))
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err = Load(path)
+	assert.Error(err)
+}
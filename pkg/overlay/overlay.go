@@ -0,0 +1,278 @@
+// Package overlay decodes the overlay planes of the repeating groups 60xx and composites them onto the first frame of
+// the pixel data.
+package overlay
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// elements of the overlay plane module, the group is one of 6000 to 601e
+const (
+	rowsElement          = 0x0010
+	columnsElement       = 0x0011
+	framesElement        = 0x0015
+	descriptionElement   = 0x0022
+	typeElement          = 0x0040
+	originElement        = 0x0050
+	bitsAllocatedElement = 0x0100
+	bitPositionElement   = 0x0102
+	labelElement         = 0x1500
+	dataElement          = 0x3000
+)
+
+// value representations of the elements of the overlay plane module, the dictionary has no entries for the
+// repeating groups
+var elementVRs = map[uint16]string{
+	rowsElement:          "US",
+	columnsElement:       "US",
+	framesElement:        "IS",
+	descriptionElement:   "LO",
+	typeElement:          "CS",
+	originElement:        "SS",
+	bitsAllocatedElement: "US",
+	bitPositionElement:   "US",
+	labelElement:         "LO",
+	dataElement:          "OW",
+}
+
+var ErrNoPixelData = errors.New("no native or decodable pixel data")
+
+type Overlay struct {
+	Group       uint16
+	Rows        int
+	Columns     int
+	Origin      [2]int // row and column of the first overlay pixel in the image, 1 based
+	Type        string // G for graphics, R for ROI
+	Label       string
+	Description string
+	Frames      int
+	Embedded    bool   // stored in the unused high bits of the pixel data instead of overlay data
+	Bits        []bool // pixels of the first frame, row by row
+}
+
+// returns the number of set pixels of the first frame
+func (o Overlay) Count() int {
+	count := 0
+	for _, bit := range o.Bits {
+		if bit {
+			count++
+		}
+	}
+	return count
+}
+
+func IsOverlayGroup(group uint16) bool {
+	return group >= 0x6000 && group <= 0x601e && group%2 == 0
+}
+
+// returns the VR of an element of the overlay plane module, UN for other elements
+func VR(t tag.Tag) string {
+	if vr, ok := elementVRs[t.Element]; ok && IsOverlayGroup(t.Group) {
+		return vr
+	}
+	return "UN"
+}
+
+// returns a new element of the overlay plane module with its VR
+func NewElement(t tag.Tag, data interface{}) (*dicom.Element, error) {
+	vr := VR(t)
+	if vr == "UN" {
+		return nil, fmt.Errorf("%s is no element of the overlay plane module", dicomtree.FormatTag(t))
+	}
+	value, err := dicom.NewValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return &dicom.Element{Tag: t, ValueRepresentation: tag.GetVRKind(t, vr), RawValueRepresentation: vr, Value: value}, nil
+}
+
+// returns the element with the value decoded with the VR of the overlay plane module if it was read with VR UN from
+// an implicit VR file, else the element
+func withVR(e *dicom.Element) *dicom.Element {
+	vr := VR(e.Tag)
+	if e.RawValueRepresentation != "UN" || vr == "UN" {
+		return e
+	}
+	value := edit.UnknownValueBytes(e)
+	if value == nil {
+		return e
+	}
+	decoded, err := edit.DecodeUnknownValue(value, vr)
+	if err != nil {
+		return e
+	}
+	return &dicom.Element{Tag: e.Tag, ValueRepresentation: tag.GetVRKind(e.Tag, vr), RawValueRepresentation: vr, Value: decoded}
+}
+
+// decodes the overlay planes of the dataset in the order of their groups, elements read with VR UN with the VR of the
+// overlay plane module. Overlays embedded in the pixel data (retired) are read from the bit position of the samples
+// of the first frame.
+func Decode(dataset *dicom.Dataset) ([]Overlay, error) {
+	elements := make(map[uint16]map[uint16]*dicom.Element)
+	var groups []uint16
+	for _, e := range dataset.Elements {
+		if !IsOverlayGroup(e.Tag.Group) {
+			continue
+		}
+		if _, ok := elements[e.Tag.Group]; !ok {
+			elements[e.Tag.Group] = make(map[uint16]*dicom.Element)
+			groups = append(groups, e.Tag.Group)
+		}
+		elements[e.Tag.Group][e.Tag.Element] = withVR(e)
+	}
+
+	overlays := make([]Overlay, 0, len(groups))
+	for _, group := range groups {
+		text := func(element uint16) string {
+			if e, ok := elements[group][element]; ok {
+				return strings.TrimRight(dicomtree.ValueText(e, nil), "\x00 ")
+			}
+			return ""
+		}
+		number := func(element uint16, fallback int) int {
+			values := strings.Split(text(element), "\\")
+			n, err := strconv.Atoi(strings.TrimSpace(values[0]))
+			if err != nil {
+				return fallback
+			}
+			return n
+		}
+		o := Overlay{
+			Group:       group,
+			Rows:        number(rowsElement, 0),
+			Columns:     number(columnsElement, 0),
+			Origin:      [2]int{1, 1},
+			Type:        text(typeElement),
+			Label:       text(labelElement),
+			Description: text(descriptionElement),
+			Frames:      number(framesElement, 1),
+		}
+		if origin := strings.Split(text(originElement), "\\"); len(origin) == 2 {
+			o.Origin[0], _ = strconv.Atoi(strings.TrimSpace(origin[0]))
+			o.Origin[1], _ = strconv.Atoi(strings.TrimSpace(origin[1]))
+		}
+		if o.Rows <= 0 || o.Columns <= 0 {
+			return overlays, fmt.Errorf("overlay %04x has no size", group)
+		}
+
+		pixels := o.Rows * o.Columns
+		if data, ok := elements[group][dataElement]; ok && data.Value != nil && data.Value.ValueType() == dicom.Bytes {
+			bytes := dicom.MustGetBytes(data.Value)
+			if len(bytes)*8 < pixels {
+				return overlays, fmt.Errorf("overlay %04x has %d bytes of data for %dx%d pixels", group, len(bytes), o.Columns, o.Rows)
+			}
+			o.Bits = make([]bool, pixels)
+			for i := range o.Bits {
+				o.Bits[i] = bytes[i/8]&(1<<(i%8)) != 0
+			}
+		} else if number(bitsAllocatedElement, 1) > 1 {
+			samples, _, _, err := firstFrame(dataset)
+			if err != nil || len(samples) < pixels {
+				return overlays, fmt.Errorf("overlay %04x is embedded in pixel data that can't be read", group)
+			}
+			position := number(bitPositionElement, 0)
+			o.Embedded, o.Bits = true, make([]bool, pixels)
+			for i := range o.Bits {
+				o.Bits[i] = samples[i]&(1<<position) != 0
+			}
+		} else {
+			return overlays, fmt.Errorf("overlay %04x has no overlay data", group)
+		}
+		overlays = append(overlays, o)
+	}
+	return overlays, nil
+}
+
+// returns the first sample of each pixel of the first native frame with the frame size
+func firstFrame(dataset *dicom.Dataset) ([]int, int, int, error) {
+	e, err := dataset.FindElementByTag(tag.PixelData)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+		return nil, 0, 0, ErrNoPixelData
+	}
+	info := dicom.MustGetPixelDataInfo(e.Value)
+	if len(info.Frames) == 0 || info.Frames[0].IsEncapsulated() {
+		return nil, 0, 0, ErrNoPixelData
+	}
+	native, err := info.Frames[0].GetNativeFrame()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	samples := make([]int, len(native.Data))
+	for i, pixel := range native.Data {
+		if len(pixel) > 0 {
+			samples[i] = pixel[0]
+		}
+	}
+	return samples, native.Cols, native.Rows, nil
+}
+
+// returns the first frame of the native pixel data as gray image scaled from its minimum to maximum value, with the
+// pixels of the overlays in white. Without pixel data the overlays are drawn on black of the size of the first overlay.
+func Composite(dataset *dicom.Dataset, overlays []Overlay) (*image.Gray, error) {
+	samples, columns, rows, err := firstFrame(dataset)
+	if errors.Is(err, ErrNoPixelData) && len(overlays) > 0 {
+		samples, columns, rows, err = nil, overlays[0].Columns+overlays[0].Origin[1]-1, overlays[0].Rows+overlays[0].Origin[0]-1, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewGray(image.Rect(0, 0, columns, rows))
+	if len(samples) >= columns*rows && columns*rows > 0 {
+		low, high := samples[0], samples[0]
+		for _, s := range samples {
+			low, high = min(low, s), max(high, s)
+		}
+		for i := 0; i < columns*rows; i++ {
+			if high > low {
+				img.Pix[i] = uint8((samples[i] - low) * 255 / (high - low))
+			}
+		}
+	}
+	for _, o := range overlays {
+		for i, bit := range o.Bits {
+			x, y := o.Origin[1]-1+i%o.Columns, o.Origin[0]-1+i/o.Columns
+			if bit && image.Pt(x, y).In(img.Rect) {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return img, nil
+}
+
+// braille dots of a cell by column and row from the top
+var brailleDots = [2][4]rune{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+
+// renders the first frame of the overlay with braille characters of at most the given width, each dot covers a
+// square block of pixels and is set if any pixel of the block is set
+func (o Overlay) Preview(width int) []string {
+	if o.Columns <= 0 || width <= 0 {
+		return nil
+	}
+	scale := (o.Columns + 2*width - 1) / (2 * width)
+	dotColumns, dotRows := (o.Columns+scale-1)/scale, (o.Rows+scale-1)/scale
+	cells := make([][]rune, (dotRows+3)/4)
+	for i := range cells {
+		cells[i] = []rune(strings.Repeat("⠀", (dotColumns+1)/2))
+	}
+	for i, bit := range o.Bits {
+		if bit {
+			x, y := i%o.Columns/scale, i/o.Columns/scale
+			cells[y/4][x/2] |= brailleDots[x%2][y%4]
+		}
+	}
+	lines := make([]string, len(cells))
+	for i, cell := range cells {
+		lines[i] = string(cell)
+	}
+	return lines
+}
@@ -0,0 +1,91 @@
+package overlay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	element := func(group, element uint16, data interface{}) *dicom.Element {
+		e, err := NewElement(tag.Tag{Group: group, Element: element}, data)
+		require.NoError(t, err)
+		return e
+	}
+	pixelData := dicom.PixelDataInfo{Frames: []frame.Frame{
+		{NativeData: frame.NativeFrame{Data: [][]int{{0x1000}, {0}, {0}, {0x1005}}, Rows: 2, Cols: 2, BitsPerSample: 16}},
+	}}
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		element(0x6000, rowsElement, []int{4}),
+		element(0x6000, columnsElement, []int{4}),
+		element(0x6000, descriptionElement, []string{"diagonal"}),
+		element(0x6000, typeElement, []string{"G"}),
+		element(0x6000, originElement, []int{2, 2}),
+		element(0x6000, bitsAllocatedElement, []int{1}),
+		element(0x6000, dataElement, []byte{0x21, 0x84}), // pixels 0, 5, 10 and 15
+		element(0x6002, rowsElement, []int{2}),
+		element(0x6002, columnsElement, []int{2}),
+		element(0x6002, bitsAllocatedElement, []int{16}),
+		element(0x6002, bitPositionElement, []int{12}),
+		mustElement(t, tag.PixelData, pixelData),
+	}}
+	overlays, err := Decode(&dataset)
+	require.NoError(t, err)
+	require.Len(t, overlays, 2)
+	assert.Equal(Overlay{Group: 0x6000, Rows: 4, Columns: 4, Origin: [2]int{2, 2}, Type: "G", Description: "diagonal", Frames: 1,
+		Bits: []bool{true, false, false, false, false, true, false, false, false, false, true, false, false, false, false, true}}, overlays[0])
+	assert.Equal(4, overlays[0].Count())
+	assert.Equal([]string{"⠑⢄"}, overlays[0].Preview(10))
+	assert.True(overlays[1].Embedded)
+	assert.Equal([]bool{true, false, false, true}, overlays[1].Bits)
+
+	img, err := Composite(&dataset, overlays[1:])
+	require.NoError(t, err)
+	assert.Equal([]uint8{255, 0, 0, 255}, img.Pix)
+
+	dataset.Elements = dataset.Elements[:7]
+	img, err = Composite(&dataset, overlays[:1])
+	require.NoError(t, err)
+	assert.Equal(5, img.Rect.Dx(), "size of the overlay at its origin without pixel data")
+	assert.Equal(uint8(255), img.GrayAt(1, 1).Y)
+	assert.Equal(uint8(0), img.GrayAt(0, 0).Y)
+
+	_, err = Decode(&dicom.Dataset{Elements: []*dicom.Element{element(0x6000, rowsElement, []int{4})}})
+	assert.Error(err)
+
+	unknown := func(element uint16, data []byte) *dicom.Element {
+		value, err := dicom.NewValue(data)
+		require.NoError(t, err)
+		return &dicom.Element{Tag: tag.Tag{Group: 0x6000, Element: element}, RawValueRepresentation: "UN", Value: value, ValueLength: uint32(len(data))}
+	}
+	overlays, err = Decode(&dicom.Dataset{Elements: []*dicom.Element{
+		unknown(rowsElement, []byte{0x02, 0x00}),
+		unknown(columnsElement, []byte{0x04, 0x00}),
+		unknown(typeElement, []byte("R ")),
+		unknown(originElement, []byte{0x01, 0x00, 0x03, 0x00}),
+		unknown(dataElement, []byte{0x0f}),
+	}})
+	require.NoError(t, err, "elements of implicit VR files are decoded with the VR of the module")
+	require.Len(t, overlays, 1)
+	assert.Equal(Overlay{Group: 0x6000, Rows: 2, Columns: 4, Origin: [2]int{1, 3}, Type: "R", Frames: 1,
+		Bits: []bool{true, true, true, true, false, false, false, false}}, overlays[0])
+
+	_, err = NewElement(tag.Tag{Group: 0x6000, Element: 0x0001}, []int{1})
+	assert.Error(err)
+	assert.Equal("US", VR(tag.Tag{Group: 0x601e, Element: rowsElement}))
+	assert.Equal("UN", VR(tag.Tag{Group: 0x6020, Element: rowsElement}))
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
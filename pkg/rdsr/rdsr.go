@@ -0,0 +1,207 @@
+// Package rdsr extracts the dose metrics of X-Ray Radiation Dose SR objects, e.g. CTDIvol and DLP of the irradiation
+// events of a CT (TID 10011) or the dose area product of projection X-ray (TID 10001), and exports them as CSV.
+package rdsr
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const (
+	SOPClassUID         = "1.2.840.10008.5.1.4.1.1.88.67"
+	EnhancedSOPClassUID = "1.2.840.10008.5.1.4.1.1.88.76"
+)
+
+// concept name codes (DCM) of the containers of irradiation events and accumulated doses
+var (
+	eventContainers       = map[string]bool{"113819": true, "113706": true} // CT Acquisition, Irradiation Event X-Ray Data
+	accumulatedContainers = map[string]bool{"113811": true, "113702": true} // CT Accumulated Dose Data, Accumulated X-Ray Dose Data
+)
+
+// key dose metrics of an irradiation event, the columns of the table and the CSV if any event has them
+var Metrics = []Metric{
+	{"113830", "CTDIvol"},
+	{"113838", "DLP"},
+	{"113835", "Phantom"},
+	{"122130", "DAP"},
+	{"113738", "Dose (RP)"},
+	{"113824", "Exposure time"},
+	{"113825", "Scanning length"},
+}
+
+type Metric struct {
+	Code string // concept name code value (DCM)
+	Name string
+}
+
+// a numeric, coded, text or UID value of a content item
+type Value struct {
+	Name  string // the meaning of the concept name
+	Value string
+	Unit  string // UCUM code of numeric values, e.g. mGy.cm
+}
+
+// an irradiation event, its values by the code value of their concept name
+type Event struct {
+	Values map[string]Value
+	Item   int // index of the item of the event in the content sequence
+}
+
+// the irradiation events and accumulated doses of a dose report
+type Report struct {
+	Events  []Event
+	Totals  []Value        // numeric values of the accumulated dose containers
+	Content *dicom.Element // the content sequence holding the events
+}
+
+func IsDoseReport(dataset *dicom.Dataset) bool {
+	uid := dicomtree.UIDValue(dataset, tag.SOPClassUID)
+	return uid == SOPClassUID || uid == EnhancedSOPClassUID
+}
+
+// returns the irradiation events and accumulated doses of the content tree of a dose report
+func Extract(dataset *dicom.Dataset) Report {
+	report := Report{Content: dicomtree.FindItemElement(dataset.Elements, tag.ContentSequence)}
+	for i, item := range items(dataset.Elements, tag.ContentSequence) {
+		code := conceptCode(item, tag.ConceptNameCodeSequence)
+		switch {
+		case eventContainers[code]:
+			event := Event{Values: make(map[string]Value), Item: i}
+			collect(item, func(code string, value Value) {
+				if _, ok := event.Values[code]; !ok {
+					event.Values[code] = value
+				}
+			})
+			report.Events = append(report.Events, event)
+		case accumulatedContainers[code]:
+			collect(item, func(code string, value Value) {
+				if value.Unit != "" {
+					report.Totals = append(report.Totals, value)
+				}
+			})
+		}
+	}
+	return report
+}
+
+// calls add with the values of the content items below the container, depth first
+func collect(container []*dicom.Element, add func(code string, value Value)) {
+	for _, item := range items(container, tag.ContentSequence) {
+		code := conceptCode(item, tag.ConceptNameCodeSequence)
+		value := Value{Name: conceptMeaning(item, tag.ConceptNameCodeSequence)}
+		switch dicomtree.ItemValue(item, tag.ValueType) {
+		case "NUM":
+			if measured := items(item, tag.MeasuredValueSequence); len(measured) > 0 {
+				value.Value = dicomtree.ItemValue(measured[0], tag.NumericValue)
+				value.Unit = conceptCode(measured[0], tag.MeasurementUnitsCodeSequence)
+			}
+		case "CODE":
+			value.Value = conceptMeaning(item, tag.ConceptCodeSequence)
+		case "TEXT":
+			value.Value = dicomtree.ItemValue(item, tag.TextValue)
+		case "UIDREF":
+			value.Value = dicomtree.ItemValue(item, tag.UID)
+		case "DATETIME":
+			value.Value = dicomtree.ItemValue(item, tag.DateTime)
+		case "CONTAINER":
+			collect(item, add)
+			continue
+		default:
+			continue
+		}
+		add(code, value)
+	}
+}
+
+// returns the metrics any event has
+func (r Report) Metrics() []Metric {
+	var present []Metric
+	for _, metric := range Metrics {
+		for _, event := range r.Events {
+			if _, ok := event.Values[metric.Code]; ok {
+				present = append(present, metric)
+				break
+			}
+		}
+	}
+	return present
+}
+
+// returns the header of the event table: number, protocol, type, target region and the present metrics with the unit
+// of the first event having them
+func (r Report) Header() []string {
+	header := []string{"Event", "Protocol", "Type", "Target"}
+	for _, metric := range r.Metrics() {
+		name := metric.Name
+		for _, event := range r.Events {
+			if value, ok := event.Values[metric.Code]; ok {
+				if value.Unit != "" {
+					name += " [" + value.Unit + "]"
+				}
+				break
+			}
+		}
+		header = append(header, name)
+	}
+	return header
+}
+
+// returns a row per event with the columns of the header, "" for missing values
+func (r Report) Rows() [][]string {
+	metrics := r.Metrics()
+	rows := make([][]string, 0, len(r.Events))
+	for i, event := range r.Events {
+		protocol := event.Values["125203"]  // Acquisition Protocol
+		eventType := event.Values["113820"] // CT Acquisition Type
+		if eventType.Value == "" {
+			eventType = event.Values["113721"] // Irradiation Event Type
+		}
+		target := event.Values["123014"] // Target Region
+		row := []string{strconv.Itoa(i + 1), protocol.Value, eventType.Value, target.Value}
+		for _, metric := range metrics {
+			row = append(row, event.Values[metric.Code].Value)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// writes the event table with the header as first line
+func WriteCSV(w io.Writer, report Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(report.Header()); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(report.Rows()); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func items(elements []*dicom.Element, t tag.Tag) [][]*dicom.Element {
+	if e := dicomtree.FindItemElement(elements, t); e != nil {
+		return dicomtree.SequenceItems(e)
+	}
+	return nil
+}
+
+// returns the code value of the first item of the code sequence
+func conceptCode(elements []*dicom.Element, t tag.Tag) string {
+	if codes := items(elements, t); len(codes) > 0 {
+		return dicomtree.ItemValue(codes[0], tag.CodeValue)
+	}
+	return ""
+}
+
+// returns the code meaning of the first item of the code sequence
+func conceptMeaning(elements []*dicom.Element, t tag.Tag) string {
+	if codes := items(elements, t); len(codes) > 0 {
+		return dicomtree.ItemValue(codes[0], tag.CodeMeaning)
+	}
+	return ""
+}
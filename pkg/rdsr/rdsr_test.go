@@ -0,0 +1,98 @@
+package rdsr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func code(t *testing.T, tg tag.Tag, value, meaning string) *dicom.Element {
+	return mustElement(t, tg, [][]*dicom.Element{{
+		mustElement(t, tag.CodeValue, []string{value}),
+		mustElement(t, tag.CodingSchemeDesignator, []string{"DCM"}),
+		mustElement(t, tag.CodeMeaning, []string{meaning}),
+	}})
+}
+
+func contentItem(t *testing.T, valueType, concept, meaning string, value ...*dicom.Element) []*dicom.Element {
+	return append([]*dicom.Element{
+		mustElement(t, tag.RelationshipType, []string{"CONTAINS"}),
+		mustElement(t, tag.ValueType, []string{valueType}),
+		code(t, tag.ConceptNameCodeSequence, concept, meaning),
+	}, value...)
+}
+
+func num(t *testing.T, concept, meaning, value, unit string) []*dicom.Element {
+	return contentItem(t, "NUM", concept, meaning, mustElement(t, tag.MeasuredValueSequence, [][]*dicom.Element{{
+		code(t, tag.MeasurementUnitsCodeSequence, unit, unit),
+		mustElement(t, tag.NumericValue, []string{value}),
+	}}))
+}
+
+func container(t *testing.T, concept, meaning string, children ...[]*dicom.Element) []*dicom.Element {
+	return contentItem(t, "CONTAINER", concept, meaning, mustElement(t, tag.ContentSequence, children))
+}
+
+func newCTDoseReport(t *testing.T) dicom.Dataset {
+	acquisition := func(protocol, acquisitionType, ctdi, dlp string) []*dicom.Element {
+		return container(t, "113819", "CT Acquisition",
+			contentItem(t, "TEXT", "125203", "Acquisition Protocol", mustElement(t, tag.TextValue, []string{protocol})),
+			contentItem(t, "CODE", "113820", "CT Acquisition Type", code(t, tag.ConceptCodeSequence, "113804", acquisitionType)),
+			container(t, "113829", "CT Dose",
+				num(t, "113830", "Mean CTDIvol", ctdi, "mGy"),
+				num(t, "113838", "DLP", dlp, "mGy.cm"),
+			),
+		)
+	}
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{SOPClassUID}),
+		mustElement(t, tag.ContentSequence, [][]*dicom.Element{
+			container(t, "113811", "CT Accumulated Dose Data",
+				num(t, "113812", "Total Number of Irradiation Events", "2", "{events}"),
+				num(t, "113813", "CT Dose Length Product Total", "512.5", "mGy.cm"),
+			),
+			acquisition("Scout", "Constant Angle Acquisition", "0.1", "2.5"),
+			acquisition("Abdomen", "Spiral Acquisition", "10.2", "510"),
+		}),
+	}}
+}
+
+func TestExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newCTDoseReport(t)
+	assert.True(IsDoseReport(&dataset))
+	report := Extract(&dataset)
+	require.Len(t, report.Events, 2)
+	assert.Equal(Value{"Mean CTDIvol", "10.2", "mGy"}, report.Events[1].Values["113830"])
+	assert.Equal(2, report.Events[1].Item)
+	assert.Equal([]Value{{"Total Number of Irradiation Events", "2", "{events}"}, {"CT Dose Length Product Total", "512.5", "mGy.cm"}},
+		report.Totals)
+	assert.Same(dataset.Elements[1], report.Content)
+
+	assert.Equal([]string{"Event", "Protocol", "Type", "Target", "CTDIvol [mGy]", "DLP [mGy.cm]"}, report.Header())
+	assert.Equal([][]string{
+		{"1", "Scout", "Constant Angle Acquisition", "", "0.1", "2.5"},
+		{"2", "Abdomen", "Spiral Acquisition", "", "10.2", "510"},
+	}, report.Rows())
+
+	var csv strings.Builder
+	require.NoError(t, WriteCSV(&csv, report))
+	assert.Equal("Event,Protocol,Type,Target,CTDIvol [mGy],DLP [mGy.cm]\n1,Scout,Constant Angle Acquisition,,0.1,2.5\n"+
+		"2,Abdomen,Spiral Acquisition,,10.2,510\n", csv.String())
+
+	assert.False(IsDoseReport(&dicom.Dataset{}))
+	assert.Empty(Extract(&dicom.Dataset{}).Events)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
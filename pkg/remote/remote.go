@@ -0,0 +1,242 @@
+// Package remote downloads http(s):// and s3:// inputs into a cache directory, so that they are loaded like local
+// files. A URL ending with / is a listing: the objects of an S3 prefix or the files linked by a directory index page.
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// returns whether the input is a URL handled by Fetch
+func IsURL(input string) bool {
+	for _, scheme := range []string{"http://", "https://", "s3://"} {
+		if strings.HasPrefix(strings.ToLower(input), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// downloads the object or listing of the URL into its cache directory and returns the path of the file or, for a
+// listing, of the directory. Files are only downloaded again if they changed since the last download.
+func Fetch(rawURL string) (string, error) {
+	return FetchWithProgress(rawURL, nil)
+}
+
+// like Fetch, calls progress if not nil after each downloaded file of a listing with the files done and their total
+func FetchWithProgress(rawURL string, progress func(done, total int)) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	dir, err := cacheDir(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	var objects []object
+	switch strings.ToLower(u.Scheme) {
+	case "s3":
+		objects, err = s3Objects(u)
+	default:
+		objects, err = httpObjects(u)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", fmt.Errorf("no objects found at %s", rawURL)
+	}
+	if err := prune(dir, objects); err != nil {
+		return "", err
+	}
+	for i, o := range objects {
+		if err := o.download(filepath.Join(dir, objectName(o.url))); err != nil {
+			return "", err
+		}
+		if progress != nil {
+			progress(i+1, len(objects))
+		}
+	}
+	if !isListing(u) {
+		return filepath.Join(dir, objectName(objects[0].url)), nil
+	}
+	return dir, nil
+}
+
+// returns the directory the downloads of the URL are cached in, below the user cache directory
+func cacheDir(rawURL string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(cacheDir, "dcmtagger", "remote", hex.EncodeToString(hash[:8])), nil
+}
+
+// removes the files of the cache directory that are not objects of the URL anymore, e.g. objects deleted from a listing
+// or downloads interrupted before they were complete
+func prune(dir string, objects []object) error {
+	names := make(map[string]bool, len(objects))
+	for _, o := range objects {
+		names[objectName(o.url)] = true
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if names[f.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, f.Name())); err != nil {
+			return err
+		}
+		slog.Debug("removed stale cached file", "path", filepath.Join(dir, f.Name()))
+	}
+	return nil
+}
+
+func isListing(u *url.URL) bool {
+	if u.Scheme == "s3" {
+		return strings.TrimPrefix(u.Path, "/") == "" || strings.HasSuffix(u.Path, "/")
+	}
+	return strings.HasSuffix(u.Path, "/")
+}
+
+func objectName(u *url.URL) string {
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		return "object.dcm"
+	}
+	return name
+}
+
+// a file to download, requests are signed by sign if not nil
+type object struct {
+	url  *url.URL
+	sign func(req *http.Request)
+}
+
+// returns the object or all files linked by the directory index page of a listing
+func httpObjects(u *url.URL) ([]object, error) {
+	if !isListing(u) {
+		return []object{{url: u}}, nil
+	}
+	page, err := object{url: u}.get()
+	if err != nil {
+		return nil, err
+	}
+	var objects []object
+	for _, link := range linkedFiles(u, string(page)) {
+		objects = append(objects, object{url: link})
+	}
+	return objects, nil
+}
+
+var hrefPattern = regexp.MustCompile(`href="([^"?#]+)"`)
+
+// returns the links of the page to files directly in the directory of the listing URL
+func linkedFiles(listing *url.URL, page string) []*url.URL {
+	var files []*url.URL
+	seen := make(map[string]bool)
+	for _, match := range hrefPattern.FindAllStringSubmatch(page, -1) {
+		link, err := listing.Parse(match[1])
+		if err != nil || link.Host != listing.Host || strings.HasSuffix(link.Path, "/") ||
+			path.Dir(link.Path)+"/" != listing.Path || seen[link.String()] {
+			continue
+		}
+		seen[link.String()] = true
+		files = append(files, link)
+	}
+	return files
+}
+
+func (o object) do(header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, o.url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if o.sign != nil {
+		o.sign(req)
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.Warn("http request failed", "method", req.Method, "url", o.url.String(), "error", err)
+		return nil, err
+	}
+	slog.Debug("http request", "method", req.Method, "url", o.url.String(), "status", resp.StatusCode,
+		"signed", o.sign != nil, "duration", time.Since(start))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error fetching %s: %s", o.url, resp.Status)
+	}
+	return resp, nil
+}
+
+// returns the content of the object, e.g. a listing
+func (o object) get() ([]byte, error) {
+	resp, err := o.do(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// downloads the object to the file, unless the file is present and the object is not modified since. The
+// modification time of the file is set to the one of the object.
+func (o object) download(filename string) error {
+	header := make(http.Header)
+	if info, err := os.Stat(filename); err == nil {
+		header.Set("If-Modified-Since", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+	resp, err := o.do(header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	tmp := filename + ".download"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error fetching %s: %w", o.url, err)
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		os.Chtimes(tmp, time.Now(), modified)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package remote
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// access to S3 from the environment: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN for signed
+// requests (anonymous without), AWS_REGION and AWS_ENDPOINT_URL for S3 compatible stores like MinIO
+type s3Config struct {
+	endpoint     *url.URL // path style requests to this endpoint, virtual hosted AWS requests if nil
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func s3ConfigFromEnv() (s3Config, error) {
+	c := s3Config{
+		region:       os.Getenv("AWS_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if c.region == "" {
+		c.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if c.region == "" {
+		c.region = "us-east-1"
+	}
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return c, fmt.Errorf("invalid AWS_ENDPOINT_URL: %w", err)
+		}
+		c.endpoint = u
+	}
+	return c, nil
+}
+
+func (c s3Config) url(bucket, key string, query url.Values) *url.URL {
+	u := &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, c.region), Path: "/" + key}
+	if c.endpoint != nil {
+		u = &url.URL{Scheme: c.endpoint.Scheme, Host: c.endpoint.Host, Path: strings.TrimSuffix(c.endpoint.Path, "/") + "/" + bucket + "/" + key}
+	}
+	u.RawQuery = query.Encode()
+	return u
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// returns the object of the URL s3://bucket/key or the objects directly below the prefix of s3://bucket/prefix/
+func s3Objects(u *url.URL) ([]object, error) {
+	c, err := s3ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if !isListing(u) {
+		return []object{{url: c.url(bucket, key, nil), sign: c.sign}}, nil
+	}
+
+	var objects []object
+	query := url.Values{"list-type": {"2"}, "prefix": {key}, "delimiter": {"/"}}
+	for {
+		page, err := object{url: c.url(bucket, "", query), sign: c.sign}.get()
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		if err := xml.Unmarshal(page, &result); err != nil {
+			return nil, fmt.Errorf("error reading listing of %s: %w", u, err)
+		}
+		for _, content := range result.Contents {
+			if !strings.HasSuffix(content.Key, "/") {
+				objects = append(objects, object{url: c.url(bucket, content.Key, nil), sign: c.sign})
+			}
+		}
+		if !result.IsTruncated {
+			return objects, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// signs the request with AWS signature version 4, requests stay anonymous without credentials
+func (c s3Config) sign(req *http.Request) {
+	if c.accessKey == "" {
+		return
+	}
+	c.signAt(req, time.Now())
+}
+
+func (c s3Config) signAt(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := amzDate[:8] + "/" + c.region + "/s3/aws4_request"
+	req.Header.Set("X-Amz-Date", amzDate)
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	}
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			canonicalQuery = append(canonicalQuery, awsEscape(key, true)+"="+awsEscape(value, true))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, awsEscape(req.URL.Path, false), strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(), signedHeaders, req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + c.secretKey)
+	for _, part := range []string{amzDate[:8], c.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapes all but the unreserved characters as required for signing, slashes are kept in paths
+func awsEscape(s string, escapeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '.', b == '_', b == '~',
+			b == '/' && !escapeSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,268 @@
+// Package render converts frames of the pixel data to images by their photometric interpretation, the YBR color spaces
+// to RGB, and writes them as PNG with the embedded ICC profile of the dataset.
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+var ErrNoPixelData = errors.New("no pixel data")
+
+// the pixel attributes of a dataset
+type Format struct {
+	Columns       int
+	Rows          int
+	Frames        int
+	Samples       int    // SamplesPerPixel
+	Photometric   string // PhotometricInterpretation
+	Planar        bool   // color-by-plane, PlanarConfiguration 1
+	BitsStored    int
+	Signed        bool
+	Encapsulated  bool
+	ICCProfileLen int // size of the ICC profile, 0 without one
+}
+
+func ReadFormat(dataset *dicom.Dataset) Format {
+	number := func(t tag.Tag, fallback int) int {
+		value, err := strconv.Atoi(strings.TrimSpace(dicomtree.ItemValue(dataset.Elements, t)))
+		if err != nil {
+			return fallback
+		}
+		return value
+	}
+	format := Format{
+		Columns:       number(tag.Columns, 0),
+		Rows:          number(tag.Rows, 0),
+		Frames:        max(number(tag.NumberOfFrames, 1), 1),
+		Samples:       number(tag.SamplesPerPixel, 1),
+		Photometric:   strings.TrimSpace(dicomtree.ItemValue(dataset.Elements, tag.PhotometricInterpretation)),
+		Planar:        number(tag.PlanarConfiguration, 0) == 1,
+		BitsStored:    number(tag.BitsStored, number(tag.BitsAllocated, 8)),
+		Signed:        number(tag.PixelRepresentation, 0) == 1,
+		ICCProfileLen: len(ICCProfile(dataset)),
+	}
+	if e, err := dataset.FindElementByTag(tag.PixelData); err == nil && e.Value != nil && e.Value.ValueType() == dicom.PixelData {
+		format.Encapsulated = dicom.MustGetPixelDataInfo(e.Value).IsEncapsulated
+	}
+	return format
+}
+
+// returns a line like "512x512 YBR_FULL_422, 3 frames, color-by-plane, ICC profile"
+func (f Format) String() string {
+	text := fmt.Sprintf("%dx%d %s", f.Columns, f.Rows, f.Photometric)
+	if f.Frames > 1 {
+		text += fmt.Sprintf(", %d frames", f.Frames)
+	}
+	if f.Samples > 1 && f.Planar {
+		text += ", color-by-plane"
+	}
+	if f.Encapsulated {
+		text += ", encapsulated"
+	}
+	if f.ICCProfileLen > 0 {
+		text += fmt.Sprintf(", ICC profile of %d bytes", f.ICCProfileLen)
+	}
+	return text
+}
+
+// returns the ICC profile of the dataset, else the one of the first optical path having one, nil without one
+func ICCProfile(dataset *dicom.Dataset) []byte {
+	if profile := profileOf(dataset.Elements); profile != nil {
+		return profile
+	}
+	if e := dicomtree.FindItemElement(dataset.Elements, tag.OpticalPathSequence); e != nil {
+		for _, item := range dicomtree.SequenceItems(e) {
+			if profile := profileOf(item); profile != nil {
+				return profile
+			}
+		}
+	}
+	return nil
+}
+
+func profileOf(elements []*dicom.Element) []byte {
+	for _, e := range elements {
+		if e.Tag == tag.ICCProfile && e.Value != nil && e.Value.ValueType() == dicom.Bytes {
+			if profile := dicom.MustGetBytes(e.Value); len(profile) > 0 {
+				return profile
+			}
+		}
+	}
+	return nil
+}
+
+// returns the frame (0 based) as image: monochrome frames as gray scaled from their minimum to maximum value,
+// MONOCHROME1 inverted, color frames as RGB converted from their YBR color space and planar configuration.
+// Encapsulated frames are decoded as JPEG, whose decoder already converts YCbCr to RGB.
+func Frame(dataset *dicom.Dataset, index int) (image.Image, error) {
+	e, err := dataset.FindElementByTag(tag.PixelData)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+		return nil, ErrNoPixelData
+	}
+	info := dicom.MustGetPixelDataInfo(e.Value)
+	if index < 0 || index >= len(info.Frames) {
+		return nil, fmt.Errorf("frame %d out of range, %d frames", index+1, len(info.Frames))
+	}
+	f := info.Frames[index]
+	if f.IsEncapsulated() {
+		encapsulated, err := f.GetEncapsulatedFrame()
+		if err != nil {
+			return nil, err
+		}
+		img, err := jpeg.Decode(bytes.NewReader(encapsulated.Data))
+		if err != nil {
+			return nil, fmt.Errorf("can't decode the frame, only JPEG baseline is supported: %w", err)
+		}
+		return img, nil
+	}
+	native, err := f.GetNativeFrame()
+	if err != nil {
+		return nil, err
+	}
+	format := ReadFormat(dataset)
+	format.Columns, format.Rows = native.Cols, native.Rows
+	if format.BitsStored <= 0 {
+		format.BitsStored = native.BitsPerSample
+	}
+	samples := make([]int, 0, len(native.Data)*max(format.Samples, 1))
+	for _, pixel := range native.Data {
+		samples = append(samples, pixel...)
+	}
+	switch format.Photometric {
+	case "MONOCHROME1", "MONOCHROME2", "":
+		return gray(samples, format)
+	case "RGB", "YBR_FULL", "YBR_FULL_422", "YBR_PARTIAL_422":
+		return rgb(samples, format)
+	}
+	return nil, fmt.Errorf("unsupported photometric interpretation %s", format.Photometric)
+}
+
+func gray(samples []int, format Format) (image.Image, error) {
+	pixels := format.Columns * format.Rows
+	if len(samples) < pixels*max(format.Samples, 1) {
+		return nil, fmt.Errorf("frame has %d samples for %dx%d pixels", len(samples), format.Columns, format.Rows)
+	}
+	values := make([]int, pixels)
+	for i := range values {
+		values[i] = samples[i*max(format.Samples, 1)]
+		if format.Signed && format.BitsStored > 0 && values[i]&(1<<(format.BitsStored-1)) != 0 {
+			values[i] |= -1 << format.BitsStored
+		}
+	}
+	img := image.NewGray(image.Rect(0, 0, format.Columns, format.Rows))
+	if pixels == 0 {
+		return img, nil
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low, high = min(low, v), max(high, v)
+	}
+	for i, v := range values {
+		if high > low {
+			img.Pix[i] = uint8((v - low) * 255 / (high - low))
+		}
+		if format.Photometric == "MONOCHROME1" {
+			img.Pix[i] = 255 - img.Pix[i]
+		}
+	}
+	return img, nil
+}
+
+func rgb(samples []int, format Format) (image.Image, error) {
+	pixels := format.Columns * format.Rows
+	needed := 3 * pixels
+	if strings.HasSuffix(format.Photometric, "_422") {
+		needed = 2 * pixels // two Y followed by Cb and Cr per pair of pixels of a row
+	}
+	if format.Samples != 3 || len(samples) < needed {
+		return nil, fmt.Errorf("frame has %d samples for %dx%d pixels of %d samples", len(samples), format.Columns,
+			format.Rows, format.Samples)
+	}
+	shift := max(format.BitsStored-8, 0)
+	sample := func(pixel, component int) int {
+		switch {
+		case strings.HasSuffix(format.Photometric, "_422"):
+			pair := pixel / 2 * 4
+			if component == 0 {
+				return samples[pair+pixel%2] >> shift
+			}
+			return samples[pair+1+component] >> shift
+		case format.Planar:
+			return samples[component*pixels+pixel] >> shift
+		}
+		return samples[3*pixel+component] >> shift
+	}
+	img := image.NewRGBA(image.Rect(0, 0, format.Columns, format.Rows))
+	for i := 0; i < pixels; i++ {
+		c0, c1, c2 := sample(i, 0), sample(i, 1), sample(i, 2)
+		var r, g, b float64
+		switch format.Photometric {
+		case "RGB":
+			r, g, b = float64(c0), float64(c1), float64(c2)
+		case "YBR_PARTIAL_422":
+			y, cb, cr := 1.1644*float64(c0-16), float64(c1-128), float64(c2-128)
+			r, g, b = y+1.5960*cr, y-0.3918*cb-0.8130*cr, y+2.0172*cb
+		default:
+			y, cb, cr := float64(c0), float64(c1-128), float64(c2-128)
+			r, g, b = y+1.402*cr, y-0.344136*cb-0.714136*cr, y+1.772*cb
+		}
+		img.SetRGBA(i%format.Columns, i/format.Columns, color.RGBA{clamp(r), clamp(g), clamp(b), 0xff})
+	}
+	return img, nil
+}
+
+func clamp(v float64) uint8 {
+	return uint8(min(max(v+0.5, 0), 255))
+}
+
+// encodes the image as PNG with the ICC profile as iCCP chunk if any, so viewers display the colors as intended
+func EncodePNG(w io.Writer, img image.Image, profile []byte) error {
+	if len(profile) == 0 {
+		return png.Encode(w, img)
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return err
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(profile); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	data := append([]byte("ICC profile\x00\x00"), compressed.Bytes()...) // name, separator and compression method 0
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], "iCCP")
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	const afterHeader = 8 + 12 + 13 // signature and IHDR chunk, which the iCCP chunk has to follow
+	out := encoded.Bytes()
+	if _, err := w.Write(out[:afterHeader]); err != nil {
+		return err
+	}
+	if _, err := w.Write(chunk); err != nil {
+		return err
+	}
+	_, err := w.Write(out[afterHeader:])
+	return err
+}
@@ -0,0 +1,116 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func colorDataset(t *testing.T, photometric string, planar int, data [][]int) *dicom.Dataset {
+	pixelData := dicom.PixelDataInfo{Frames: []frame.Frame{
+		{NativeData: frame.NativeFrame{Data: data, Rows: 1, Cols: 2, BitsPerSample: 8}},
+	}}
+	return &dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SamplesPerPixel, []int{3}),
+		mustElement(t, tag.PhotometricInterpretation, []string{photometric}),
+		mustElement(t, tag.PlanarConfiguration, []int{planar}),
+		mustElement(t, tag.Rows, []int{1}),
+		mustElement(t, tag.Columns, []int{2}),
+		mustElement(t, tag.BitsStored, []int{8}),
+		mustElement(t, tag.PixelData, pixelData),
+	}}
+}
+
+func TestFrame(t *testing.T) {
+	assert := assert.New(t)
+
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	img, err := Frame(colorDataset(t, "RGB", 0, [][]int{{255, 0, 0}, {0, 0, 255}}), 0)
+	require.NoError(t, err)
+	assert.Equal(red, img.At(0, 0))
+	assert.Equal(blue, img.At(1, 0))
+
+	// color-by-plane is read by the parser as if it were color-by-pixel
+	img, err = Frame(colorDataset(t, "RGB", 1, [][]int{{255, 0, 0}, {0, 0, 0}}), 0)
+	require.NoError(t, err)
+	assert.Equal(red, img.At(0, 0))
+	assert.Equal(color.RGBA{0, 0, 0, 255}, img.At(1, 0))
+
+	img, err = Frame(colorDataset(t, "YBR_FULL", 0, [][]int{{76, 85, 255}, {29, 255, 107}}), 0)
+	require.NoError(t, err)
+	assert.InDelta(255, img.(*image.RGBA).RGBAAt(0, 0).R, 1)
+	assert.InDelta(0, img.(*image.RGBA).RGBAAt(0, 0).B, 1)
+	assert.InDelta(255, img.(*image.RGBA).RGBAAt(1, 0).B, 1)
+	assert.InDelta(0, img.(*image.RGBA).RGBAAt(1, 0).R, 1)
+
+	// both pixels share Cb and Cr
+	img, err = Frame(colorDataset(t, "YBR_FULL_422", 0, [][]int{{0, 255, 128}, {128, 0, 0}}), 0)
+	require.NoError(t, err)
+	assert.Equal(color.RGBA{0, 0, 0, 255}, img.At(0, 0))
+	assert.Equal(color.RGBA{255, 255, 255, 255}, img.At(1, 0))
+
+	_, err = Frame(colorDataset(t, "PALETTE COLOR", 0, [][]int{{1}, {2}}), 0)
+	assert.ErrorContains(err, "unsupported photometric interpretation PALETTE COLOR")
+	_, err = Frame(colorDataset(t, "RGB", 0, [][]int{{1}, {2}}), 1)
+	assert.ErrorContains(err, "frame 2 out of range")
+	_, err = Frame(&dicom.Dataset{}, 0)
+	assert.ErrorIs(err, ErrNoPixelData)
+
+	monochrome := colorDataset(t, "MONOCHROME1", 0, [][]int{{0xfff0}, {10}})
+	monochrome.Elements[0] = mustElement(t, tag.SamplesPerPixel, []int{1})
+	monochrome.Elements[5] = mustElement(t, tag.BitsStored, []int{16})
+	monochrome.Elements = append(monochrome.Elements, mustElement(t, tag.PixelRepresentation, []int{1}))
+	img, err = Frame(monochrome, 0)
+	require.NoError(t, err)
+	assert.Equal([]uint8{255, 0}, img.(*image.Gray).Pix, "negative value is the lowest, inverted to white")
+}
+
+func TestEncodePNG(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := colorDataset(t, "RGB", 0, [][]int{{255, 0, 0}, {0, 0, 255}})
+	assert.Nil(ICCProfile(dataset))
+	profile := []byte("profile data")
+	dataset.Elements = append(dataset.Elements, mustElement(t, tag.OpticalPathSequence, [][]*dicom.Element{
+		{mustElement(t, tag.ICCProfile, profile)},
+	}))
+	assert.Equal(profile, ICCProfile(dataset))
+	assert.Equal("2x1 RGB, ICC profile of 12 bytes", ReadFormat(dataset).String())
+
+	img, err := Frame(dataset, 0)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, EncodePNG(&buf, img, profile))
+	encoded := buf.Bytes()
+	assert.Equal("iCCP", string(encoded[37:41]), "first chunk after the header")
+	data := encoded[41:]
+	data = data[len("ICC profile\x00\x00"):]
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(profile, decompressed)
+
+	decoded, err := png.Decode(bytes.NewReader(encoded))
+	require.NoError(t, err, "valid CRC of the chunk")
+	r, g, b, _ := decoded.At(1, 0).RGBA()
+	assert.Equal([]uint32{0, 0, 0xffff}, []uint32{r, g, b})
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
@@ -0,0 +1,244 @@
+// Package report renders datasets, the differences between files and validation findings as HTML or Markdown
+// documents with Go templates, e.g. for attaching to a ticket or signing off a de-identification.
+package report
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/anon"
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/dump"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/suyashkumar/dicom"
+)
+
+//go:embed templates
+var templates embed.FS
+
+type Kind string
+
+const (
+	KindDataset    Kind = "dataset"
+	KindDiff       Kind = "diff"
+	KindValidation Kind = "validation"
+	KindAudit      Kind = "audit"
+)
+
+// element of a dataset, nested elements have the path of their sequence and item, e.g. "(0008,1115)/1/(0020,000e)"
+type Element struct {
+	Path  string
+	Depth int // 0 for top-level elements
+	Name  string
+	VR    string
+	Value string
+}
+
+type File struct {
+	Filename string
+	Elements []Element
+}
+
+// attribute with different values in the files, Values[i] is the value of Files[i], "" if the file hasn't it
+type Difference struct {
+	Path   string
+	Name   string
+	Values []string
+}
+
+type Finding struct {
+	Severity string
+	Tag      string
+	Message  string
+}
+
+type Validation struct {
+	Filename string
+	IOD      string
+	Findings []Finding
+}
+
+// attributes of a file to review before release, see anon.Audit
+type Audit struct {
+	Filename string
+	Risks    []Risk
+}
+
+type Risk struct {
+	Category string
+	Path     string
+	Name     string
+	Value    string
+}
+
+// data of the templates, only the fields of the kind are set
+type Report struct {
+	Kind        Kind
+	Title       string
+	Generated   string
+	Files       []File       // the datasets of KindDataset, the files compared by KindDiff without elements
+	Differences []Difference // KindDiff
+	Validations []Validation // KindValidation
+	Audits      []Audit      // KindAudit
+}
+
+// returns the report of all elements of the entries
+func Datasets(entries []dicomtree.Entry) Report {
+	report := newReport(KindDataset, entries)
+	for i := range entries {
+		report.Files[i].Elements = elements("", 0, entries[i].Dataset.Elements, charset.FromDataset(&entries[i].Dataset))
+	}
+	return report
+}
+
+// returns the report of the attributes with different values in the entries, nested elements included
+func Diff(entries []dicomtree.Entry) Report {
+	report := newReport(KindDiff, entries)
+	var paths []string
+	names := make(map[string]string)
+	values := make(map[string][]string)
+	for i := range entries {
+		for _, e := range elements("", 0, entries[i].Dataset.Elements, charset.FromDataset(&entries[i].Dataset)) {
+			if _, ok := values[e.Path]; !ok {
+				paths = append(paths, e.Path)
+				names[e.Path] = e.Name
+				values[e.Path] = make([]string, len(entries))
+			}
+			values[e.Path][i] = e.Value
+		}
+	}
+	for _, path := range paths {
+		for _, value := range values[path][1:] {
+			if value != values[path][0] {
+				report.Differences = append(report.Differences, Difference{path, names[path], values[path]})
+				break
+			}
+		}
+	}
+	return report
+}
+
+// returns the report of the validation of each entry against its IOD
+func Validations(entries []dicomtree.Entry) Report {
+	report := newReport(KindValidation, entries)
+	for i := range entries {
+		iod, findings := validate.Dataset(&entries[i].Dataset)
+		validation := Validation{Filename: entries[i].Filename, IOD: iod, Findings: make([]Finding, 0, len(findings))}
+		for _, f := range findings {
+			validation.Findings = append(validation.Findings, Finding{f.Severity.String(), dicomtree.FormatTag(f.Tag), f.Message})
+		}
+		report.Validations = append(report.Validations, validation)
+	}
+	return report
+}
+
+// returns the report of the de-identification audit of each entry, for signing off the release
+func Audits(entries []dicomtree.Entry) Report {
+	report := newReport(KindAudit, entries)
+	for i := range entries {
+		audit := Audit{Filename: entries[i].Filename, Risks: make([]Risk, 0)}
+		for _, r := range anon.Audit(&entries[i].Dataset) {
+			name := ""
+			if r.Element != nil {
+				name = dicomtree.TagName(r.Element)
+			}
+			audit.Risks = append(audit.Risks, Risk{string(r.Category), r.Path, name, r.Value})
+		}
+		report.Audits = append(report.Audits, audit)
+	}
+	return report
+}
+
+func newReport(kind Kind, entries []dicomtree.Entry) Report {
+	report := Report{Kind: kind, Generated: time.Now().Format(time.DateTime), Files: make([]File, len(entries))}
+	for i := range entries {
+		report.Files[i].Filename = entries[i].Filename
+	}
+	switch {
+	case len(entries) == 1:
+		report.Title = fmt.Sprintf("%s of %s", kindTitle(kind), entries[0].Filename)
+	default:
+		report.Title = fmt.Sprintf("%s of %d files", kindTitle(kind), len(entries))
+	}
+	return report
+}
+
+func kindTitle(kind Kind) string {
+	switch kind {
+	case KindDiff:
+		return "Differences"
+	case KindValidation:
+		return "Validation"
+	case KindAudit:
+		return "De-identification audit"
+	}
+	return "Dataset"
+}
+
+func elements(prefix string, depth int, dataset []*dicom.Element, charsets []string) []Element {
+	var result []Element
+	for _, e := range dataset {
+		path := prefix + dicomtree.FormatTag(e.Tag)
+		result = append(result, Element{path, depth, dicomtree.TagName(e), e.RawValueRepresentation, dump.ValueText(e, charsets)})
+		for i, item := range dicomtree.SequenceItems(e) {
+			result = append(result, elements(fmt.Sprintf("%s/%d/", path, i+1), depth+1, item, charsets)...)
+		}
+	}
+	return result
+}
+
+// returns "html" or "md" for the extension of the filename, an error for other extensions
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm":
+		return "html", nil
+	case ".md", ".markdown":
+		return "md", nil
+	}
+	return "", fmt.Errorf("unknown report format of %s, use .html or .md", filename)
+}
+
+// writes the report in the format with the template file, the built-in template of the format if templateFile is empty.
+// HTML templates escape the values, Markdown templates have the function md escaping a value for a table cell.
+func Write(w io.Writer, report Report, format, templateFile string) error {
+	var text []byte
+	var err error
+	if templateFile != "" {
+		text, err = os.ReadFile(templateFile)
+	} else {
+		text, err = templates.ReadFile("templates/report." + format + ".tmpl")
+	}
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "html":
+		t, err := htmltemplate.New("report").Parse(string(text))
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, report)
+	case "md":
+		t, err := texttemplate.New("report").Funcs(texttemplate.FuncMap{"md": markdownCell}).Parse(string(text))
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, report)
+	}
+	return fmt.Errorf("unknown report format '%s', use html or md", format)
+}
+
+var markdownReplacer = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "", "*", "\\*", "_", "\\_", "`", "\\`", "<", "&lt;")
+
+// returns the value escaped for a cell of a Markdown table
+func markdownCell(value string) string {
+	return markdownReplacer.Replace(strings.TrimRight(value, "\x00 "))
+}
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func newEntries(t *testing.T) []dicomtree.Entry {
+	entry := func(filename, name string) dicomtree.Entry {
+		return dicomtree.Entry{Filename: filename, Dataset: dicom.Dataset{Elements: []*dicom.Element{
+			mustElement(t, tag.Modality, []string{"CT"}),
+			mustElement(t, tag.PatientName, []string{name}),
+		}}}
+	}
+	return []dicomtree.Entry{entry("a.dcm", "Doe^John"), entry("b.dcm", "Doe|Jane")}
+}
+
+func TestDiff(t *testing.T) {
+	r := Diff(newEntries(t))
+	assert.Equal(t, "Differences of 2 files", r.Title)
+	assert.Equal(t, []Difference{{"(0010,0010)", dicomtree.TagName(mustElement(t, tag.PatientName, []string{""})), []string{"Doe^John", "Doe|Jane"}}}, r.Differences)
+}
+
+func TestWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	var md strings.Builder
+	require.NoError(t, Write(&md, Diff(newEntries(t)), "md", ""))
+	assert.True(strings.HasPrefix(md.String(), "# Differences of 2 files\n"))
+	assert.Contains(md.String(), "| Tag | Name | a.dcm | b.dcm |\n")
+	assert.Contains(md.String(), " | Doe^John | Doe\\|Jane |\n", "cells are escaped")
+
+	var html strings.Builder
+	entries := newEntries(t)
+	entries[0].Dataset.Elements[1] = mustElement(t, tag.PatientName, []string{"<script>"})
+	require.NoError(t, Write(&html, Datasets(entries[:1]), "html", ""))
+	assert.Contains(html.String(), "<title>Dataset of a.dcm</title>")
+	assert.Contains(html.String(), "<td>&lt;script&gt;</td>")
+
+	templateFile := filepath.Join(t.TempDir(), "own.tmpl")
+	require.NoError(t, os.WriteFile(templateFile, []byte("{{range .Validations}}{{.Filename}}: {{len .Findings}}{{end}}"), 0o644))
+	var own strings.Builder
+	require.NoError(t, Write(&own, Validations(entries[:1]), "md", templateFile))
+	assert.Regexp(`^a\.dcm: \d+$`, own.String())
+
+	_, err := FormatOf("report.pdf")
+	assert.Error(err)
+	format, err := FormatOf("report.HTML")
+	assert.NoError(err)
+	assert.Equal("html", format)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
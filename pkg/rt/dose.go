@@ -0,0 +1,131 @@
+package rt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// the dose grid geometry and maximum dose of an RT dose
+type Dose struct {
+	Units         string // GY or RELATIVE
+	Type          string // PHYSICAL, EFFECTIVE or ERROR
+	SummationType string // PLAN, BEAM, FRACTION, ...
+	Rows          int
+	Columns       int
+	Frames        int
+	PixelSpacing  []float64 // row and column spacing in mm
+	FrameOffsets  []float64 // offsets of the frames along the normal in mm
+	Position      []float64 // position of the first voxel in mm
+	Scaling       float64   // factor of the stored values to the dose in the units
+	Max           float64   // maximum dose in the units, -1 if the pixel data can't be read
+
+	elements []*dicom.Element
+}
+
+func IsDose(dataset *dicom.Dataset) bool {
+	return dicomtree.UIDValue(dataset, tag.SOPClassUID) == DoseSOPClassUID
+}
+
+// returns the grid geometry of an RT dose and its maximum dose, computed from the native pixel data and dose grid scaling
+func DoseSummary(dataset *dicom.Dataset) Dose {
+	number := func(t tag.Tag) int {
+		value, _ := strconv.Atoi(dicomtree.ItemValue(dataset.Elements, t))
+		return value
+	}
+	numbers := func(t tag.Tag) []float64 {
+		if e := dicomtree.FindItemElement(dataset.Elements, t); e != nil {
+			return dicomtree.NumericValues(e)
+		}
+		return nil
+	}
+	dose := Dose{
+		Units:         dicomtree.ItemValue(dataset.Elements, tag.DoseUnits),
+		Type:          dicomtree.ItemValue(dataset.Elements, tag.DoseType),
+		SummationType: dicomtree.ItemValue(dataset.Elements, tag.DoseSummationType),
+		Rows:          number(tag.Rows),
+		Columns:       number(tag.Columns),
+		Frames:        max(number(tag.NumberOfFrames), 1),
+		PixelSpacing:  numbers(tag.PixelSpacing),
+		FrameOffsets:  numbers(tag.GridFrameOffsetVector),
+		Position:      numbers(tag.ImagePositionPatient),
+		Scaling:       1,
+		Max:           -1,
+		elements:      dataset.Elements,
+	}
+	if scaling := numbers(tag.DoseGridScaling); len(scaling) == 1 {
+		dose.Scaling = scaling[0]
+	}
+	if maxValue, ok := maxPixelValue(dataset); ok {
+		dose.Max = float64(maxValue) * dose.Scaling
+	}
+	return dose
+}
+
+// returns the largest sample of the native pixel data, false for missing or encapsulated pixel data
+func maxPixelValue(dataset *dicom.Dataset) (int, bool) {
+	e, err := dataset.FindElementByTag(tag.PixelData)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+		return 0, false
+	}
+	info := dicom.MustGetPixelDataInfo(e.Value)
+	found, maxValue := false, 0
+	for i := range info.Frames {
+		if info.Frames[i].IsEncapsulated() {
+			return 0, false
+		}
+		native, err := info.Frames[i].GetNativeFrame()
+		if err != nil {
+			return 0, false
+		}
+		for _, pixel := range native.Data {
+			for _, sample := range pixel {
+				if !found || sample > maxValue {
+					found, maxValue = true, sample
+				}
+			}
+		}
+	}
+	return maxValue, found
+}
+
+// returns the lines of the dose summary, linked to the elements they are derived from
+func (dose Dose) Lines() []Line {
+	element := func(t tag.Tag) *dicom.Element {
+		return dicomtree.FindItemElement(dose.elements, t)
+	}
+	lines := []Line{
+		{fmt.Sprintf("Dose %s %s, summed per %s", orDash(dose.Type), orDash(dose.Units), orDash(dose.SummationType)), element(tag.DoseType), -1},
+		{fmt.Sprintf("Grid %d x %d x %d voxels", dose.Columns, dose.Rows, dose.Frames), element(tag.Rows), -1},
+		{"Spacing " + formatNumbers(dose.PixelSpacing, " x ") + " mm, frame offsets " + formatRange(dose.FrameOffsets) + " mm", element(tag.PixelSpacing), -1},
+		{"Position (" + formatNumbers(dose.Position, ", ") + ") mm", element(tag.ImagePositionPatient), -1},
+	}
+	maxText := "Max dose unknown, no native pixel data"
+	if dose.Max >= 0 {
+		maxText = fmt.Sprintf("Max dose %s %s (scaling %s)", strconv.FormatFloat(dose.Max, 'f', 3, 64), orDash(dose.Units),
+			strconv.FormatFloat(dose.Scaling, 'g', -1, 64))
+	}
+	return append(lines, Line{maxText, element(tag.PixelData), -1})
+}
+
+func formatNumbers(values []float64, separator string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	texts := make([]string, 0, len(values))
+	for _, v := range values {
+		texts = append(texts, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return strings.Join(texts, separator)
+}
+
+func formatRange(values []float64) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(values[0], 'g', -1, 64) + ".." + strconv.FormatFloat(values[len(values)-1], 'g', -1, 64)
+}
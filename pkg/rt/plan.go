@@ -0,0 +1,130 @@
+package rt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const (
+	PlanSOPClassUID = "1.2.840.10008.5.1.4.1.1.481.5"
+	DoseSOPClassUID = "1.2.840.10008.5.1.4.1.1.481.2"
+)
+
+// line of a plan or dose summary with the element it is derived from, to jump to it in the tree
+type Line struct {
+	Text    string
+	Element *dicom.Element // nil for lines without element, the sequence for lines of an item
+	Item    int            // index of the item of the sequence element, -1 for the element itself
+}
+
+type Beam struct {
+	Number        string
+	Name          string
+	Type          string // STATIC or DYNAMIC
+	RadiationType string
+	Machine       string
+	Energy        string // nominal energy of the first control point
+	ControlPoints int
+	Meterset      string // monitor units of the beam from the fraction group
+	Item          int    // index of the item of the beam in the beam sequence
+}
+
+type FractionGroup struct {
+	Number    string
+	Fractions string // number of fractions planned
+	Beams     string // number of beams
+	Item      int    // index of the item of the group in the fraction group sequence
+}
+
+// the beams and fraction scheme of an RT plan
+type Plan struct {
+	Label          string
+	PrescribedDose string // target prescription dose of the first dose reference in Gy
+	FractionGroups []FractionGroup
+	Beams          []Beam
+
+	beamSequence, fractionGroupSequence *dicom.Element
+}
+
+func IsPlan(dataset *dicom.Dataset) bool {
+	return dicomtree.UIDValue(dataset, tag.SOPClassUID) == PlanSOPClassUID
+}
+
+// returns the beams and fraction groups of an RT plan in the order of their sequences
+func PlanSummary(dataset *dicom.Dataset) Plan {
+	plan := Plan{Label: dicomtree.ItemValue(dataset.Elements, tag.RTPlanLabel)}
+	for _, reference := range items(dataset.Elements, tag.DoseReferenceSequence) {
+		if dose := dicomtree.ItemValue(reference, tag.TargetPrescriptionDose); dose != "" {
+			plan.PrescribedDose = dose
+			break
+		}
+	}
+
+	metersets := make(map[string]string)
+	plan.fractionGroupSequence = dicomtree.FindItemElement(dataset.Elements, tag.FractionGroupSequence)
+	for i, item := range items(dataset.Elements, tag.FractionGroupSequence) {
+		plan.FractionGroups = append(plan.FractionGroups, FractionGroup{
+			Number:    dicomtree.ItemValue(item, tag.FractionGroupNumber),
+			Fractions: dicomtree.ItemValue(item, tag.NumberOfFractionsPlanned),
+			Beams:     dicomtree.ItemValue(item, tag.NumberOfBeams),
+			Item:      i,
+		})
+		for _, beam := range items(item, tag.ReferencedBeamSequence) {
+			metersets[dicomtree.ItemValue(beam, tag.ReferencedBeamNumber)] = dicomtree.ItemValue(beam, tag.BeamMeterset)
+		}
+	}
+
+	plan.beamSequence = dicomtree.FindItemElement(dataset.Elements, tag.BeamSequence)
+	for i, item := range items(dataset.Elements, tag.BeamSequence) {
+		beam := Beam{
+			Number:        dicomtree.ItemValue(item, tag.BeamNumber),
+			Name:          dicomtree.ItemValue(item, tag.BeamName),
+			Type:          dicomtree.ItemValue(item, tag.BeamType),
+			RadiationType: dicomtree.ItemValue(item, tag.RadiationType),
+			Machine:       dicomtree.ItemValue(item, tag.TreatmentMachineName),
+			ControlPoints: len(items(item, tag.ControlPointSequence)),
+			Item:          i,
+		}
+		if controlPoints := items(item, tag.ControlPointSequence); len(controlPoints) > 0 {
+			beam.Energy = dicomtree.ItemValue(controlPoints[0], tag.NominalBeamEnergy)
+		}
+		beam.Meterset = metersets[beam.Number]
+		plan.Beams = append(plan.Beams, beam)
+	}
+	return plan
+}
+
+// returns the lines of the plan summary, the fraction groups and beams link to their items
+func (plan Plan) Lines() []Line {
+	lines := []Line{{Text: fmt.Sprintf("Plan %s, prescribed dose %s Gy", orDash(plan.Label), orDash(plan.PrescribedDose)), Item: -1}}
+	for _, group := range plan.FractionGroups {
+		lines = append(lines, Line{fmt.Sprintf("Fraction group %s: %s fractions, %s beams", orDash(group.Number), orDash(group.Fractions),
+			orDash(group.Beams)), plan.fractionGroupSequence, group.Item})
+	}
+	total := 0.0
+	for _, beam := range plan.Beams {
+		mu, err := strconv.ParseFloat(strings.TrimSpace(beam.Meterset), 64)
+		if err == nil {
+			total += mu
+		}
+		lines = append(lines, Line{fmt.Sprintf("Beam %s %s: %s %s %s MV, %d control points, %s MU on %s", orDash(beam.Number),
+			orDash(beam.Name), orDash(beam.Type), orDash(beam.RadiationType), orDash(beam.Energy), beam.ControlPoints,
+			orDash(beam.Meterset), orDash(beam.Machine)), plan.beamSequence, beam.Item})
+	}
+	if len(plan.Beams) > 0 {
+		lines = append(lines, Line{Text: fmt.Sprintf("Total %s MU per fraction in %d beams", strconv.FormatFloat(total, 'f', -1, 64), len(plan.Beams)), Item: -1})
+	}
+	return lines
+}
+
+func orDash(text string) string {
+	if text == "" {
+		return "-"
+	}
+	return text
+}
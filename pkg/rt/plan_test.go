@@ -0,0 +1,116 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestPlanSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	beam := func(number, name string) []*dicom.Element {
+		return []*dicom.Element{
+			mustElement(t, tag.TreatmentMachineName, []string{"Linac1"}),
+			mustElement(t, tag.BeamNumber, []string{number}),
+			mustElement(t, tag.BeamName, []string{name}),
+			mustElement(t, tag.BeamType, []string{"STATIC"}),
+			mustElement(t, tag.RadiationType, []string{"PHOTON"}),
+			sequence(t, tag.ControlPointSequence, []*dicom.Element{mustElement(t, tag.NominalBeamEnergy, []string{"6"})}, nil),
+		}
+	}
+	referencedBeam := func(number, meterset string) []*dicom.Element {
+		return []*dicom.Element{
+			mustElement(t, tag.BeamMeterset, []string{meterset}),
+			mustElement(t, tag.ReferencedBeamNumber, []string{number}),
+		}
+	}
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{PlanSOPClassUID}),
+		mustElement(t, tag.RTPlanLabel, []string{"Prostate"}),
+		sequence(t, tag.DoseReferenceSequence, []*dicom.Element{mustElement(t, tag.TargetPrescriptionDose, []string{"78"})}),
+		sequence(t, tag.FractionGroupSequence, []*dicom.Element{
+			mustElement(t, tag.FractionGroupNumber, []string{"1"}),
+			mustElement(t, tag.NumberOfFractionsPlanned, []string{"39"}),
+			mustElement(t, tag.NumberOfBeams, []string{"2"}),
+			sequence(t, tag.ReferencedBeamSequence, referencedBeam("1", "100.5"), referencedBeam("2", "99.5")),
+		}),
+		sequence(t, tag.BeamSequence, beam("1", "AP"), beam("2", "PA")),
+	}}
+	assert.True(IsPlan(&dataset))
+	plan := PlanSummary(&dataset)
+	assert.Equal("Prostate", plan.Label)
+	assert.Equal("78", plan.PrescribedDose)
+	assert.Equal([]FractionGroup{{Number: "1", Fractions: "39", Beams: "2"}}, plan.FractionGroups)
+	assert.Equal(Beam{Number: "2", Name: "PA", Type: "STATIC", RadiationType: "PHOTON", Machine: "Linac1", Energy: "6", ControlPoints: 2,
+		Meterset: "99.5", Item: 1}, plan.Beams[1])
+
+	lines := plan.Lines()
+	assert.Len(lines, 5)
+	assert.Equal("Plan Prostate, prescribed dose 78 Gy", lines[0].Text)
+	assert.Nil(lines[0].Element)
+	assert.Equal("Fraction group 1: 39 fractions, 2 beams", lines[1].Text)
+	assert.Equal("Beam 1 AP: STATIC PHOTON 6 MV, 2 control points, 100.5 MU on Linac1", lines[2].Text)
+	assert.Same(dataset.Elements[4], lines[3].Element)
+	assert.Equal(1, lines[3].Item)
+	assert.Equal("Total 200 MU per fraction in 2 beams", lines[4].Text)
+}
+
+func TestDoseSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	pixelData := dicom.PixelDataInfo{Frames: []frame.Frame{
+		{NativeData: frame.NativeFrame{Data: [][]int{{0}, {10}, {400}, {20}}, Rows: 2, Cols: 2, BitsPerSample: 16}},
+		{NativeData: frame.NativeFrame{Data: [][]int{{0}, {1000}, {40}, {20}}, Rows: 2, Cols: 2, BitsPerSample: 16}},
+	}}
+	numbers := func(tg tag.Tag, values ...string) *dicom.Element {
+		e := mustElement(t, tg, values)
+		e.RawValueRepresentation = "DS"
+		return e
+	}
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{DoseSOPClassUID}),
+		numbers(tag.ImagePositionPatient, "-10", "-20", "5"),
+		mustElement(t, tag.NumberOfFrames, []string{"2"}),
+		mustElement(t, tag.Rows, []int{2}),
+		mustElement(t, tag.Columns, []int{2}),
+		numbers(tag.PixelSpacing, "2.5", "2.5"),
+		mustElement(t, tag.DoseUnits, []string{"GY"}),
+		mustElement(t, tag.DoseType, []string{"PHYSICAL"}),
+		mustElement(t, tag.DoseSummationType, []string{"PLAN"}),
+		numbers(tag.GridFrameOffsetVector, "0", "3"),
+		numbers(tag.DoseGridScaling, "0.001"),
+		mustElement(t, tag.PixelData, pixelData),
+	}}
+	assert.True(IsDose(&dataset))
+	dose := DoseSummary(&dataset)
+	assert.Equal(2, dose.Frames)
+	assert.InDelta(1.0, dose.Max, 1e-9)
+
+	var texts []string
+	for _, line := range dose.Lines() {
+		texts = append(texts, line.Text)
+	}
+	assert.Equal([]string{
+		"Dose PHYSICAL GY, summed per PLAN",
+		"Grid 2 x 2 x 2 voxels",
+		"Spacing 2.5 x 2.5 mm, frame offsets 0..3 mm",
+		"Position (-10, -20, 5) mm",
+		"Max dose 1.000 GY (scaling 0.001)",
+	}, texts)
+	assert.Same(dataset.Elements[11], dose.Lines()[4].Element)
+
+	assert.Equal(-1.0, DoseSummary(&dicom.Dataset{}).Max, "no pixel data")
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
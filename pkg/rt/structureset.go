@@ -0,0 +1,118 @@
+// Package rt summarizes radiotherapy objects, structure sets, plans and doses, so their content can be reviewed
+// without walking the deeply nested sequences.
+package rt
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const StructureSetSOPClassUID = "1.2.840.10008.5.1.4.1.1.481.3"
+
+// region of interest of a structure set with the values spread over the ROI, contour and observation sequences
+type ROI struct {
+	Number           string
+	Name             string
+	Color            string // display color as hex, e.g. "#ff0000", "" if not set
+	Type             string // interpreted type of the observations, e.g. ORGAN or PTV
+	ContourCount     int
+	PointCount       int
+	FrameOfReference string
+	ReferencedSeries []string // series instance UIDs the frame of reference of the ROI refers to
+	Item             int      // index of the item of the ROI in the structure set ROI sequence
+}
+
+func IsStructureSet(dataset *dicom.Dataset) bool {
+	return dicomtree.UIDValue(dataset, tag.SOPClassUID) == StructureSetSOPClassUID
+}
+
+// returns the ROIs of a structure set in the order of the structure set ROI sequence
+func StructureSetROIs(dataset *dicom.Dataset) []ROI {
+	seriesByFrameOfReference := make(map[string][]string)
+	for _, frameOfReference := range items(dataset.Elements, tag.ReferencedFrameOfReferenceSequence) {
+		uid := dicomtree.ItemValue(frameOfReference, tag.FrameOfReferenceUID)
+		for _, study := range items(frameOfReference, tag.RTReferencedStudySequence) {
+			for _, series := range items(study, tag.RTReferencedSeriesSequence) {
+				if seriesUID := dicomtree.ItemValue(series, tag.SeriesInstanceUID); seriesUID != "" {
+					seriesByFrameOfReference[uid] = append(seriesByFrameOfReference[uid], seriesUID)
+				}
+			}
+		}
+	}
+
+	rois := make([]ROI, 0)
+	indexByNumber := make(map[string]int)
+	for i, item := range items(dataset.Elements, tag.StructureSetROISequence) {
+		roi := ROI{
+			Number:           dicomtree.ItemValue(item, tag.ROINumber),
+			Name:             dicomtree.ItemValue(item, tag.ROIName),
+			FrameOfReference: dicomtree.ItemValue(item, tag.ReferencedFrameOfReferenceUID),
+			Item:             i,
+		}
+		roi.ReferencedSeries = seriesByFrameOfReference[roi.FrameOfReference]
+		indexByNumber[roi.Number] = len(rois)
+		rois = append(rois, roi)
+	}
+
+	for _, item := range items(dataset.Elements, tag.ROIContourSequence) {
+		i, ok := indexByNumber[dicomtree.ItemValue(item, tag.ReferencedROINumber)]
+		if !ok {
+			continue
+		}
+		if e := dicomtree.FindItemElement(item, tag.ROIDisplayColor); e != nil {
+			rois[i].Color = hexColor(dicomtree.NumericValues(e))
+		}
+		for _, contour := range items(item, tag.ContourSequence) {
+			rois[i].ContourCount++
+			points, _ := strconv.Atoi(dicomtree.ItemValue(contour, tag.NumberOfContourPoints))
+			rois[i].PointCount += points
+		}
+	}
+
+	for _, item := range items(dataset.Elements, tag.RTROIObservationsSequence) {
+		if i, ok := indexByNumber[dicomtree.ItemValue(item, tag.ReferencedROINumber)]; ok {
+			rois[i].Type = dicomtree.ItemValue(item, tag.RTROIInterpretedType)
+		}
+	}
+	return rois
+}
+
+// returns the referenced series of all ROIs, sorted and without duplicates
+func ReferencedSeries(rois []ROI) []string {
+	seen := make(map[string]bool)
+	series := make([]string, 0)
+	for _, roi := range rois {
+		for _, uid := range roi.ReferencedSeries {
+			if !seen[uid] {
+				seen[uid] = true
+				series = append(series, uid)
+			}
+		}
+	}
+	sort.Strings(series)
+	return series
+}
+
+// returns the items of the sequence with the tag among the elements, nil if there is none
+func items(elements []*dicom.Element, t tag.Tag) [][]*dicom.Element {
+	if e := dicomtree.FindItemElement(elements, t); e != nil {
+		return dicomtree.SequenceItems(e)
+	}
+	return nil
+}
+
+func hexColor(rgb []float64) string {
+	if len(rgb) != 3 {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", clamp(rgb[0]), clamp(rgb[1]), clamp(rgb[2]))
+}
+
+func clamp(v float64) int {
+	return int(min(max(v, 0), 255))
+}
@@ -0,0 +1,65 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func sequence(t *testing.T, tg tag.Tag, items ...[]*dicom.Element) *dicom.Element {
+	return mustElement(t, tg, items)
+}
+
+func newStructureSet(t *testing.T) dicom.Dataset {
+	color := mustElement(t, tag.ROIDisplayColor, []string{"255", "128", "0"})
+	color.RawValueRepresentation = "IS"
+	contour := func(points string) []*dicom.Element {
+		return []*dicom.Element{mustElement(t, tag.NumberOfContourPoints, []string{points})}
+	}
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{StructureSetSOPClassUID}),
+		sequence(t, tag.ReferencedFrameOfReferenceSequence, []*dicom.Element{
+			mustElement(t, tag.FrameOfReferenceUID, []string{"1.2.3"}),
+			sequence(t, tag.RTReferencedStudySequence, []*dicom.Element{
+				sequence(t, tag.RTReferencedSeriesSequence, []*dicom.Element{mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3.4"})}),
+			}),
+		}),
+		sequence(t, tag.StructureSetROISequence,
+			[]*dicom.Element{
+				mustElement(t, tag.ROINumber, []string{"1"}),
+				mustElement(t, tag.ReferencedFrameOfReferenceUID, []string{"1.2.3"}),
+				mustElement(t, tag.ROIName, []string{"Body"}),
+			},
+			[]*dicom.Element{
+				mustElement(t, tag.ROINumber, []string{"2"}),
+				mustElement(t, tag.ROIName, []string{"PTV"}),
+			}),
+		sequence(t, tag.ROIContourSequence, []*dicom.Element{
+			color,
+			sequence(t, tag.ContourSequence, contour("4"), contour("6")),
+			mustElement(t, tag.ReferencedROINumber, []string{"1"}),
+		}),
+		sequence(t, tag.RTROIObservationsSequence, []*dicom.Element{
+			mustElement(t, tag.ReferencedROINumber, []string{"2"}),
+			mustElement(t, tag.RTROIInterpretedType, []string{"PTV"}),
+		}),
+	}}
+}
+
+func TestStructureSetROIs(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newStructureSet(t)
+	assert.True(IsStructureSet(&dataset))
+	rois := StructureSetROIs(&dataset)
+	assert.Equal([]ROI{
+		{Number: "1", Name: "Body", Color: "#ff8000", ContourCount: 2, PointCount: 10, FrameOfReference: "1.2.3", ReferencedSeries: []string{"1.2.3.4"}},
+		{Number: "2", Name: "PTV", Type: "PTV", Item: 1},
+	}, rois)
+	assert.Equal([]string{"1.2.3.4"}, ReferencedSeries(rois))
+
+	assert.Empty(StructureSetROIs(&dicom.Dataset{}))
+	assert.False(IsStructureSet(&dicom.Dataset{}))
+}
@@ -0,0 +1,346 @@
+// Package script runs Starlark scripts against loaded datasets.
+package script
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/drcynic/dcmtagger/pkg/backup"
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+	"go.starlark.net/starlark"
+)
+
+// writes the dataset of the entry for save(), to the path if not empty, and returns the written path
+type SaveFunc func(entry *dicomtree.Entry, path string) (string, error)
+
+// Starlark wrapper of a loaded dataset, exposed to scripts as 'dataset'
+type scriptDataset struct {
+	entry  *dicomtree.Entry
+	save   SaveFunc
+	dryRun bool // save does not write, see Preview
+}
+
+// Starlark wrapper of a top level element, exposed to scripts as 'element'
+type scriptElement struct {
+	element  *dicom.Element
+	charsets []string
+}
+
+var scriptDatasetMethods = map[string]func(d *scriptDataset, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error){
+	"get":    (*scriptDataset).get,
+	"set":    (*scriptDataset).set,
+	"delete": (*scriptDataset).delete,
+	"save":   (*scriptDataset).saveDataset,
+}
+
+func (d *scriptDataset) String() string        { return fmt.Sprintf("<dataset %s>", d.entry.Filename) }
+func (d *scriptDataset) Type() string          { return "dataset" }
+func (d *scriptDataset) Freeze()               {}
+func (d *scriptDataset) Truth() starlark.Bool  { return starlark.True }
+func (d *scriptDataset) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: dataset") }
+
+func (d *scriptDataset) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "filename":
+		return starlark.String(d.entry.Filename), nil
+	case "path":
+		return starlark.String(d.entry.Path), nil
+	case "elements":
+		charsets := charset.FromDataset(&d.entry.Dataset)
+		elements := make([]starlark.Value, 0, len(d.entry.Dataset.Elements))
+		for _, e := range d.entry.Dataset.Elements {
+			elements = append(elements, &scriptElement{e, charsets})
+		}
+		return starlark.NewList(elements), nil
+	}
+	if method, ok := scriptDatasetMethods[name]; ok {
+		return starlark.NewBuiltin(name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			return method(b.Receiver().(*scriptDataset), args, kwargs)
+		}).BindReceiver(d), nil
+	}
+	return nil, nil
+}
+
+func (d *scriptDataset) AttrNames() []string {
+	names := []string{"elements", "filename", "path"}
+	for name := range scriptDatasetMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// get(tag) returns the value of the element or None if not present
+func (d *scriptDataset) get(args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var tagText string
+	if err := starlark.UnpackPositionalArgs("get", args, kwargs, 1, &tagText); err != nil {
+		return nil, err
+	}
+	t, err := dicomtree.ParseTag(tagText)
+	if err != nil {
+		return nil, err
+	}
+	e, err := d.entry.Dataset.FindElementByTag(t)
+	if err != nil {
+		return starlark.None, nil
+	}
+	return toStarlarkValue(e, charset.FromDataset(&d.entry.Dataset)), nil
+}
+
+// set(tag, value) sets the value of the element, creating it if not present
+func (d *scriptDataset) set(args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var tagText string
+	var value starlark.Value
+	if err := starlark.UnpackPositionalArgs("set", args, kwargs, 2, &tagText, &value); err != nil {
+		return nil, err
+	}
+	t, err := dicomtree.ParseTag(tagText)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []starlark.Value{value}
+	if list, ok := value.(*starlark.List); ok {
+		items = make([]starlark.Value, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			items = append(items, list.Index(i))
+		}
+	}
+
+	valueType := dicom.ValueType(-1)
+	if e, err := d.entry.Dataset.FindElementByTag(t); err == nil {
+		valueType = e.Value.ValueType()
+	}
+	data, err := fromStarlarkValues(items, valueType, charset.FromDataset(&d.entry.Dataset))
+	if err != nil {
+		return nil, err
+	}
+	_, err = edit.Set(&d.entry.Dataset, t, data)
+	return starlark.None, err
+}
+
+// delete(tag) removes the element and returns whether it was present
+func (d *scriptDataset) delete(args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var tagText string
+	if err := starlark.UnpackPositionalArgs("delete", args, kwargs, 1, &tagText); err != nil {
+		return nil, err
+	}
+	t, err := dicomtree.ParseTag(tagText)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Bool(edit.Delete(&d.entry.Dataset, t)), nil
+}
+
+// save(path=None) writes the dataset with the SaveFunc of Run, see WriteEntry
+func (d *scriptDataset) saveDataset(args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackArgs("save", args, kwargs, "path?", &path); err != nil {
+		return nil, err
+	}
+	if d.dryRun {
+		return starlark.None, nil
+	}
+	_, err := d.save(d.entry, path)
+	return starlark.None, err
+}
+
+// writes the dataset of the entry to the path, in place without path keeping the previous version in the backup
+// directory. The SaveFunc of Run without another one.
+func WriteEntry(entry *dicomtree.Entry, path string) (string, error) {
+	if path == "" || path == entry.Path {
+		if err := entry.CheckOverwrite(); err != nil {
+			return entry.Path, err
+		}
+		return entry.Path, backup.WriteFile(entry.Dataset, entry.Path)
+	}
+	return path, dicomtree.WriteFile(entry.Dataset, path)
+}
+
+func (e *scriptElement) String() string {
+	return fmt.Sprintf("<element %s %s>", dicomtree.FormatTag(e.element.Tag), dicomtree.TagName(e.element))
+}
+func (e *scriptElement) Type() string          { return "element" }
+func (e *scriptElement) Freeze()               {}
+func (e *scriptElement) Truth() starlark.Bool  { return starlark.True }
+func (e *scriptElement) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: element") }
+
+func (e *scriptElement) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "tag":
+		return starlark.String(dicomtree.FormatTag(e.element.Tag)), nil
+	case "group":
+		return starlark.MakeInt(int(e.element.Tag.Group)), nil
+	case "element":
+		return starlark.MakeInt(int(e.element.Tag.Element)), nil
+	case "name":
+		return starlark.String(dicomtree.TagName(e.element)), nil
+	case "vr":
+		return starlark.String(e.element.RawValueRepresentation), nil
+	case "value":
+		return toStarlarkValue(e.element, e.charsets), nil
+	}
+	return nil, nil
+}
+
+func (e *scriptElement) AttrNames() []string {
+	return []string{"element", "group", "name", "tag", "value", "vr"}
+}
+
+// converts the element value into a starlark value, a single value is returned directly, multiple ones as list
+func toStarlarkValue(e *dicom.Element, charsets []string) starlark.Value {
+	values := make([]starlark.Value, 0)
+	switch e.Value.ValueType() {
+	case dicom.Strings:
+		for _, v := range e.Value.GetValue().([]string) {
+			if charset.IsText(e) {
+				v = charset.Decode(v, charsets)
+			}
+			values = append(values, starlark.String(v))
+		}
+	case dicom.Ints:
+		for _, v := range e.Value.GetValue().([]int) {
+			values = append(values, starlark.MakeInt(v))
+		}
+	case dicom.Floats:
+		for _, v := range e.Value.GetValue().([]float64) {
+			values = append(values, starlark.Float(v))
+		}
+	default:
+		return starlark.String(e.Value.String())
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	return starlark.NewList(values)
+}
+
+// converts starlark values to the data for dicom.NewValue, using the given value type or the type of the first item if
+// negative, fails for strings the character set can't represent
+func fromStarlarkValues(items []starlark.Value, valueType dicom.ValueType, charsets []string) (interface{}, error) {
+	if valueType < 0 && len(items) > 0 {
+		switch items[0].(type) {
+		case starlark.Int:
+			valueType = dicom.Ints
+		case starlark.Float:
+			valueType = dicom.Floats
+		}
+	}
+
+	switch valueType {
+	case dicom.Ints:
+		ints := make([]int, 0, len(items))
+		for _, item := range items {
+			if i, err := starlark.AsInt32(item); err == nil {
+				ints = append(ints, i)
+			}
+		}
+		return ints, nil
+	case dicom.Floats:
+		floats := make([]float64, 0, len(items))
+		for _, item := range items {
+			if f, ok := starlark.AsFloat(item); ok {
+				floats = append(floats, f)
+			}
+		}
+		return floats, nil
+	}
+
+	strs := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := starlark.AsString(item)
+		if !ok {
+			s = item.String()
+		}
+		encoded, err := charset.Encode(s, charsets)
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, encoded)
+	}
+	return strs, nil
+}
+
+func openDatasetBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackPositionalArgs("open_dataset", args, kwargs, 1, &path); err != nil {
+		return nil, err
+	}
+	entries, err := dicomtree.ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	datasets := make([]starlark.Value, 0, len(entries))
+	for i := range entries {
+		datasets = append(datasets, &scriptDataset{&entries[i], thread.Local(saveKey).(SaveFunc), thread.Local(dryRunKey) == true})
+	}
+	if len(datasets) == 1 {
+		return datasets[0], nil
+	}
+	return starlark.NewList(datasets), nil
+}
+
+// thread locals of the SaveFunc and of Preview
+const (
+	saveKey   = "save"
+	dryRunKey = "dryRun"
+)
+
+// runs the Starlark script with the given entries predeclared as 'datasets', print output goes to output. save()
+// writes with the SaveFunc, WriteEntry if nil.
+func Run(filename string, entries []dicomtree.Entry, output io.Writer, save SaveFunc) error {
+	if save == nil {
+		save = WriteEntry
+	}
+	return run(filename, entries, output, save, false)
+}
+
+// runs the Starlark script on copies of the entries without writing files and returns the changes it would make.
+// Print output is discarded.
+func Preview(filename string, entries []dicomtree.Entry) ([]edit.Change, error) {
+	copies := make([]dicomtree.Entry, len(entries))
+	for i, entry := range entries {
+		dataset, err := edit.CopyDataset(&entry.Dataset)
+		if err != nil {
+			return nil, err
+		}
+		entry.Dataset = dataset
+		copies[i] = entry
+	}
+	if err := run(filename, copies, io.Discard, WriteEntry, true); err != nil {
+		return nil, err
+	}
+	var changes []edit.Change
+	for i := range entries {
+		changes = append(changes, edit.Diff(entries[i].Filename, edit.TakeSnapshot(&entries[i].Dataset), &copies[i].Dataset)...)
+	}
+	return changes, nil
+}
+
+func run(filename string, entries []dicomtree.Entry, output io.Writer, save SaveFunc, dryRun bool) error {
+	datasets := make([]starlark.Value, 0, len(entries))
+	for i := range entries {
+		datasets = append(datasets, &scriptDataset{&entries[i], save, dryRun})
+	}
+	predeclared := starlark.StringDict{
+		"datasets":     starlark.NewList(datasets),
+		"open_dataset": starlark.NewBuiltin("open_dataset", openDatasetBuiltin),
+	}
+	thread := &starlark.Thread{
+		Name: filename,
+		Print: func(thread *starlark.Thread, msg string) {
+			fmt.Fprintln(output, msg)
+		},
+	}
+	thread.SetLocal(saveKey, save)
+	thread.SetLocal(dryRunKey, dryRun)
+	_, err := starlark.ExecFile(thread, filename, nil, predeclared)
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		return fmt.Errorf("%s", evalErr.Backtrace())
+	}
+	return err
+}
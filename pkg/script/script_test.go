@@ -0,0 +1,77 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	scriptFile := filepath.Join(t.TempDir(), "test.star")
+	assert.NoError(os.WriteFile(scriptFile, []byte("print(len(datasets))\nprint('done')\n"), 0o644))
+	var output strings.Builder
+	assert.NoError(Run(scriptFile, []dicomtree.Entry{}, &output, nil))
+	assert.Equal("0\ndone\n", output.String())
+
+	assert.NoError(os.WriteFile(scriptFile, []byte("fail('broken')\n"), 0o644))
+	err := Run(scriptFile, []dicomtree.Entry{}, &output, nil)
+	assert.Error(err)
+	assert.Contains(err.Error(), "broken")
+}
+
+func TestRunEditsDatasets(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []dicomtree.Entry{{Filename: "a.dcm", Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientID, []string{"123"}),
+	}}}}
+	scriptFile := filepath.Join(t.TempDir(), "test.star")
+	src := `ds = datasets[0]
+ds.set("0008,0060", "MR")
+print(ds.filename, ds.get("(0008,0060)"), ds.delete("00100020"), ds.get("00100020"))
+`
+	assert.NoError(os.WriteFile(scriptFile, []byte(src), 0o644))
+	var output strings.Builder
+	assert.NoError(Run(scriptFile, entries, &output, nil))
+	assert.Equal("a.dcm MR True None\n", output.String())
+	assert.Len(entries[0].Dataset.Elements, 1)
+	assert.Equal([]string{"MR"}, entries[0].Dataset.Elements[0].Value.GetValue())
+}
+
+func TestPreview(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	entries := []dicomtree.Entry{{Filename: "a.dcm", Path: filepath.Join(dir, "a.dcm"), Dataset: dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.Modality, []string{"CT"}),
+	}}}}
+	scriptFile := filepath.Join(dir, "test.star")
+	assert.NoError(os.WriteFile(scriptFile, []byte("datasets[0].set('0008,0060', 'MR')\ndatasets[0].save()\n"), 0o644))
+	changes, err := Preview(scriptFile, entries)
+	assert.NoError(err)
+	if assert.Len(changes, 1) {
+		assert.Equal("a.dcm", changes[0].File)
+		assert.Equal("CT", changes[0].OldValue)
+		assert.Equal("MR", changes[0].NewValue)
+	}
+	assert.Equal([]string{"CT"}, entries[0].Dataset.Elements[0].Value.GetValue(), "entries unchanged")
+	assert.NoFileExists(entries[0].Path, "save does not write")
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
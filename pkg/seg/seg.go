@@ -0,0 +1,142 @@
+// Package seg summarizes the segments of segmentation objects and extracts the binary masks of their frames.
+package seg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const SOPClassUID = "1.2.840.10008.5.1.4.1.1.66.4"
+
+var ErrNoPixelData = errors.New("no native pixel data")
+
+type Segment struct {
+	Number        string
+	Label         string
+	Category      string // code meaning of the segmented property category, e.g. "Tissue"
+	Type          string // code meaning of the segmented property type, e.g. "Liver"
+	AlgorithmType string // AUTOMATIC, SEMIAUTOMATIC or MANUAL
+	AlgorithmName string
+	Frames        []int    // indices of the frames of the segment, 0 based
+	Instances     []string // SOP instance UIDs of the source images of the frames in frame order, "" if unknown
+	Item          int      // index of the item of the segment in the segment sequence
+}
+
+func IsSegmentation(dataset *dicom.Dataset) bool {
+	return dicomtree.UIDValue(dataset, tag.SOPClassUID) == SOPClassUID
+}
+
+// returns the segments in the order of the segment sequence with the frames assigned by the per-frame functional
+// groups
+func Segments(dataset *dicom.Dataset) []Segment {
+	segments := make([]Segment, 0)
+	indexByNumber := make(map[string]int)
+	for i, item := range items(dataset.Elements, tag.SegmentSequence) {
+		segment := Segment{
+			Number:        dicomtree.ItemValue(item, tag.SegmentNumber),
+			Label:         dicomtree.ItemValue(item, tag.SegmentLabel),
+			Category:      codeMeaning(item, tag.SegmentedPropertyCategoryCodeSequence),
+			Type:          codeMeaning(item, tag.SegmentedPropertyTypeCodeSequence),
+			AlgorithmType: dicomtree.ItemValue(item, tag.SegmentAlgorithmType),
+			AlgorithmName: dicomtree.ItemValue(item, tag.SegmentAlgorithmName),
+			Item:          i,
+		}
+		indexByNumber[segment.Number] = len(segments)
+		segments = append(segments, segment)
+	}
+
+	for f, frame := range items(dataset.Elements, tag.PerFrameFunctionalGroupsSequence) {
+		identification := items(frame, tag.SegmentIdentificationSequence)
+		if len(identification) == 0 {
+			continue
+		}
+		i, ok := indexByNumber[dicomtree.ItemValue(identification[0], tag.ReferencedSegmentNumber)]
+		if !ok {
+			continue
+		}
+		instance := ""
+		for _, derivation := range items(frame, tag.DerivationImageSequence) {
+			for _, source := range items(derivation, tag.SourceImageSequence) {
+				if instance == "" {
+					instance = dicomtree.ItemValue(source, tag.ReferencedSOPInstanceUID)
+				}
+			}
+		}
+		segments[i].Frames = append(segments[i].Frames, f)
+		segments[i].Instances = append(segments[i].Instances, instance)
+	}
+	return segments
+}
+
+// returns the code meaning of the first item of the code sequence, the code value if there is no meaning
+func codeMeaning(elements []*dicom.Element, t tag.Tag) string {
+	codes := items(elements, t)
+	if len(codes) == 0 {
+		return ""
+	}
+	if meaning := dicomtree.ItemValue(codes[0], tag.CodeMeaning); meaning != "" {
+		return meaning
+	}
+	return dicomtree.ItemValue(codes[0], tag.CodeValue)
+}
+
+// returns the items of the sequence with the tag among the elements, nil if there is none
+func items(elements []*dicom.Element, t tag.Tag) [][]*dicom.Element {
+	if e := dicomtree.FindItemElement(elements, t); e != nil {
+		return dicomtree.SequenceItems(e)
+	}
+	return nil
+}
+
+// returns the first sample of each pixel of the native frame with the index together with the frame size
+func Frame(dataset *dicom.Dataset, index int) ([]int, int, int, error) {
+	e, err := dataset.FindElementByTag(tag.PixelData)
+	if err != nil || e.Value == nil || e.Value.ValueType() != dicom.PixelData {
+		return nil, 0, 0, ErrNoPixelData
+	}
+	info := dicom.MustGetPixelDataInfo(e.Value)
+	if index < 0 || index >= len(info.Frames) {
+		return nil, 0, 0, fmt.Errorf("no frame %d in %d frames", index+1, len(info.Frames))
+	}
+	if info.Frames[index].IsEncapsulated() {
+		return nil, 0, 0, ErrNoPixelData
+	}
+	native, err := info.Frames[index].GetNativeFrame()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	samples := make([]int, len(native.Data))
+	for i, pixel := range native.Data {
+		if len(pixel) > 0 {
+			samples[i] = pixel[0]
+		}
+	}
+	return samples, native.Cols, native.Rows, nil
+}
+
+// returns the mask of the frame, a pixel is set if its value is not 0, for fractional segmentations if it is at least
+// half of the maximum fractional value
+func Mask(dataset *dicom.Dataset, index int) ([]bool, int, int, error) {
+	samples, columns, rows, err := Frame(dataset, index)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	threshold := 1
+	if strings.EqualFold(dicomtree.ItemValue(dataset.Elements, tag.SegmentationType), "FRACTIONAL") {
+		maximum, err := strconv.Atoi(dicomtree.ItemValue(dataset.Elements, tag.MaximumFractionalValue))
+		if err == nil && maximum > 1 {
+			threshold = (maximum + 1) / 2
+		}
+	}
+	mask := make([]bool, len(samples))
+	for i, s := range samples {
+		mask[i] = s >= threshold
+	}
+	return mask, columns, rows, nil
+}
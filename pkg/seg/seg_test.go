@@ -0,0 +1,103 @@
+package seg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func sequence(t *testing.T, tg tag.Tag, items ...[]*dicom.Element) *dicom.Element {
+	return mustElement(t, tg, items)
+}
+
+// returns a segmentation of two 2x2 frames of the first of two segments, both derived from the instance 1.2.3
+func newSegmentation(t *testing.T, segmentationType string, frames ...[]int) dicom.Dataset {
+	code := func(meaning string) []*dicom.Element {
+		return []*dicom.Element{mustElement(t, tag.CodeValue, []string{"T-1"}), mustElement(t, tag.CodeMeaning, []string{meaning})}
+	}
+	perFrame := func() []*dicom.Element {
+		return []*dicom.Element{
+			sequence(t, tag.DerivationImageSequence, []*dicom.Element{
+				sequence(t, tag.SourceImageSequence, []*dicom.Element{mustElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3"})}),
+			}),
+			sequence(t, tag.SegmentIdentificationSequence, []*dicom.Element{mustElement(t, tag.ReferencedSegmentNumber, []int{1})}),
+		}
+	}
+	pixelData := dicom.PixelDataInfo{}
+	for _, samples := range frames {
+		data := make([][]int, len(samples))
+		for i, s := range samples {
+			data[i] = []int{s}
+		}
+		pixelData.Frames = append(pixelData.Frames, frame.Frame{NativeData: frame.NativeFrame{Data: data, Rows: 2, Cols: 2, BitsPerSample: 8}})
+	}
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{SOPClassUID}),
+		mustElement(t, tag.SegmentationType, []string{segmentationType}),
+		mustElement(t, tag.MaximumFractionalValue, []int{255}),
+		sequence(t, tag.SegmentSequence,
+			[]*dicom.Element{
+				mustElement(t, tag.SegmentNumber, []int{1}),
+				mustElement(t, tag.SegmentLabel, []string{"Liver"}),
+				sequence(t, tag.SegmentedPropertyCategoryCodeSequence, code("Tissue")),
+				sequence(t, tag.SegmentedPropertyTypeCodeSequence, code("Liver")),
+				mustElement(t, tag.SegmentAlgorithmType, []string{"AUTOMATIC"}),
+				mustElement(t, tag.SegmentAlgorithmName, []string{"net"}),
+			},
+			[]*dicom.Element{
+				mustElement(t, tag.SegmentNumber, []int{2}),
+				mustElement(t, tag.SegmentLabel, []string{"Tumor"}),
+				sequence(t, tag.SegmentedPropertyTypeCodeSequence, []*dicom.Element{mustElement(t, tag.CodeValue, []string{"M-8000"})}),
+				mustElement(t, tag.SegmentAlgorithmType, []string{"MANUAL"}),
+			}),
+		sequence(t, tag.PerFrameFunctionalGroupsSequence, perFrame(), perFrame()),
+		mustElement(t, tag.PixelData, pixelData),
+	}}
+}
+
+func TestSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newSegmentation(t, "BINARY", []int{1, 0, 0, 1}, []int{0, 0, 0, 0})
+	assert.True(IsSegmentation(&dataset))
+	assert.Equal([]Segment{
+		{Number: "1", Label: "Liver", Category: "Tissue", Type: "Liver", AlgorithmType: "AUTOMATIC", AlgorithmName: "net", Frames: []int{0, 1},
+			Instances: []string{"1.2.3", "1.2.3"}},
+		{Number: "2", Label: "Tumor", Type: "M-8000", AlgorithmType: "MANUAL", Item: 1},
+	}, Segments(&dataset))
+	assert.False(IsSegmentation(&dicom.Dataset{}))
+	assert.Empty(Segments(&dicom.Dataset{}))
+}
+
+func TestMask(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newSegmentation(t, "BINARY", []int{1, 0, 0, 1})
+	mask, columns, rows, err := Mask(&dataset, 0)
+	require.NoError(t, err)
+	assert.Equal([]bool{true, false, false, true}, mask)
+	assert.Equal(2, columns)
+	assert.Equal(2, rows)
+	_, _, _, err = Mask(&dataset, 1)
+	assert.Error(err)
+
+	dataset = newSegmentation(t, "FRACTIONAL", []int{255, 127, 128, 1})
+	mask, _, _, err = Mask(&dataset, 0)
+	require.NoError(t, err)
+	assert.Equal([]bool{true, false, true, false}, mask, "fractional values from half of the maximum")
+
+	_, _, _, err = Frame(&dicom.Dataset{}, 0)
+	assert.ErrorIs(err, ErrNoPixelData)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
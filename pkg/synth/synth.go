@@ -0,0 +1,192 @@
+// Package synth generates synthetic DICOM studies with coherent UIDs and gradient pixel data, e.g. as test fixtures.
+package synth
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/edit"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+type Options struct {
+	Modality  string // CT, MR, PT, CR, US or OT (secondary capture)
+	Series    int
+	Instances int // per series
+	Rows      int
+	Columns   int
+	Time      time.Time // study date and time, now if zero
+}
+
+type attribute struct {
+	tag  tag.Tag
+	data interface{}
+}
+
+type modalityDefinition struct {
+	sopClassUID string
+	bits        int         // bits allocated of the gray pixel data
+	attributes  []attribute // modality specific attributes of the IOD
+}
+
+var modalities = map[string]modalityDefinition{
+	"CT": {"1.2.840.10008.5.1.4.1.1.2", 16, []attribute{
+		{tag.ImageType, []string{"ORIGINAL", "PRIMARY", "AXIAL"}},
+		{tag.RescaleIntercept, []string{"-1024"}},
+		{tag.RescaleSlope, []string{"1"}},
+		{tag.KVP, []string{"120"}},
+		{tag.AcquisitionNumber, []string{"1"}},
+	}},
+	"MR": {"1.2.840.10008.5.1.4.1.1.4", 16, []attribute{
+		{tag.ImageType, []string{"ORIGINAL", "PRIMARY", "M"}},
+		{tag.ScanningSequence, []string{"SE"}},
+		{tag.SequenceVariant, []string{"NONE"}},
+		{tag.ScanOptions, []string{""}},
+		{tag.MRAcquisitionType, []string{"2D"}},
+		{tag.EchoTime, []string{"10"}},
+		{tag.EchoTrainLength, []string{"1"}},
+	}},
+	"PT": {"1.2.840.10008.5.1.4.1.1.128", 16, nil},
+	"CR": {"1.2.840.10008.5.1.4.1.1.1", 8, []attribute{
+		{tag.BodyPartExamined, []string{"CHEST"}},
+		{tag.ViewPosition, []string{"PA"}},
+	}},
+	"US": {"1.2.840.10008.5.1.4.1.1.6.1", 8, nil},
+	"OT": {"1.2.840.10008.5.1.4.1.1.7", 8, []attribute{
+		{tag.ConversionType, []string{"SYN"}},
+	}},
+}
+
+// returns the supported modalities sorted by name
+func Modalities() []string {
+	names := make([]string, 0, len(modalities))
+	for name := range modalities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// generates the instances of a study, all series share the study and patient, the instances of a series the
+// frame of reference. The entries are named S<series>_I<instance>.dcm.
+func Study(opts Options) ([]dicomtree.Entry, error) {
+	definition, ok := modalities[strings.ToUpper(opts.Modality)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported modality '%s', use one of %s", opts.Modality, strings.Join(Modalities(), ", "))
+	}
+	if opts.Series <= 0 || opts.Instances <= 0 || opts.Rows <= 0 || opts.Columns <= 0 {
+		return nil, fmt.Errorf("series, instances, rows and columns have to be positive")
+	}
+	studyTime := opts.Time
+	if studyTime.IsZero() {
+		studyTime = time.Now()
+	}
+
+	studyUID := edit.NewUID()
+	study := []attribute{
+		{tag.StudyDate, []string{studyTime.Format("20060102")}},
+		{tag.StudyTime, []string{studyTime.Format("150405")}},
+		{tag.AccessionNumber, []string{""}},
+		{tag.Modality, []string{strings.ToUpper(opts.Modality)}},
+		{tag.Manufacturer, []string{"dcmtagger"}},
+		{tag.ReferringPhysicianName, []string{""}},
+		{tag.PatientName, []string{"SYNTHETIC^PATIENT"}},
+		{tag.PatientID, []string{"SYNTH-" + studyTime.Format("20060102150405")}},
+		{tag.PatientBirthDate, []string{""}},
+		{tag.PatientSex, []string{"O"}},
+		{tag.StudyInstanceUID, []string{studyUID}},
+		{tag.StudyID, []string{"1"}},
+	}
+	study = append(study, definition.attributes...)
+
+	entries := make([]dicomtree.Entry, 0, opts.Series*opts.Instances)
+	for s := 1; s <= opts.Series; s++ {
+		series := []attribute{
+			{tag.SeriesDescription, []string{fmt.Sprintf("synthetic series %d", s)}},
+			{tag.SeriesInstanceUID, []string{edit.NewUID()}},
+			{tag.SeriesNumber, []string{fmt.Sprint(s)}},
+			{tag.FrameOfReferenceUID, []string{edit.NewUID()}},
+			{tag.PositionReferenceIndicator, []string{""}},
+		}
+		for i := 1; i <= opts.Instances; i++ {
+			sopInstanceUID := edit.NewUID()
+			instance := []attribute{
+				{tag.MediaStorageSOPClassUID, []string{definition.sopClassUID}},
+				{tag.MediaStorageSOPInstanceUID, []string{sopInstanceUID}},
+				{tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}},
+				{tag.SOPClassUID, []string{definition.sopClassUID}},
+				{tag.SOPInstanceUID, []string{sopInstanceUID}},
+				{tag.InstanceNumber, []string{fmt.Sprint(i)}},
+				{tag.ImagePositionPatient, []string{"0", "0", fmt.Sprint(i - 1)}},
+				{tag.ImageOrientationPatient, []string{"1", "0", "0", "0", "1", "0"}},
+				{tag.PixelSpacing, []string{"1", "1"}},
+				{tag.SliceThickness, []string{"1"}},
+			}
+			var dataset dicom.Dataset
+			for _, attributes := range [][]attribute{study, series, instance} {
+				for _, a := range attributes {
+					if _, err := edit.Set(&dataset, a.tag, a.data); err != nil {
+						return nil, fmt.Errorf("error setting %s: %w", dicomtree.FormatTag(a.tag), err)
+					}
+				}
+			}
+			if err := edit.ReplacePixelData(&dataset, gradient(opts.Columns, opts.Rows, definition.bits, float64(i-1)/float64(opts.Instances))); err != nil {
+				return nil, err
+			}
+			entries = append(entries, dicomtree.Entry{Filename: fmt.Sprintf("S%03d_I%04d.dcm", s, i), Dataset: dataset})
+		}
+	}
+	return entries, nil
+}
+
+// returns a diagonal gray gradient, shifted by the offset (0 to 1) so that the instances of a series differ
+func gradient(columns, rows, bits int, offset float64) image.Image {
+	maxValue := float64(int(1)<<bits - 1)
+	value := func(x, y int) float64 {
+		v := float64(x)/float64(columns)/2 + float64(y)/float64(rows)/2 + offset
+		return (v - float64(int(v))) * maxValue
+	}
+	rect := image.Rect(0, 0, columns, rows)
+	if bits == 8 {
+		img := image.NewGray(rect)
+		for y := 0; y < rows; y++ {
+			for x := 0; x < columns; x++ {
+				img.SetGray(x, y, color.Gray{Y: uint8(value(x, y))})
+			}
+		}
+		return img
+	}
+	img := image.NewGray16(rect)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < columns; x++ {
+			img.SetGray16(x, y, color.Gray16{Y: uint16(value(x, y))})
+		}
+	}
+	return img
+}
+
+// generates a study and writes its instances to the directory, which is created if needed
+func Write(dir string, opts Options) ([]dicomtree.Entry, error) {
+	entries, err := Study(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Path = filepath.Join(dir, entries[i].Filename)
+		if err := dicomtree.WriteFile(entries[i].Dataset, entries[i].Path); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", entries[i].Path, err)
+		}
+	}
+	return entries, nil
+}
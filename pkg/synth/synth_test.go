@@ -0,0 +1,51 @@
+package synth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/drcynic/dcmtagger/pkg/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestStudy(t *testing.T) {
+	assert := assert.New(t)
+
+	entries, err := Study(Options{Modality: "ct", Series: 2, Instances: 3, Rows: 4, Columns: 5, Time: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)})
+	assert.NoError(err)
+	assert.Len(entries, 6)
+	assert.Equal("S002_I0001.dcm", entries[3].Filename)
+
+	value := func(dataset dicom.Dataset, t tag.Tag) string {
+		e, err := dataset.FindElementByTag(t)
+		if !assert.NoError(err) {
+			return ""
+		}
+		return dicomtree.ValueText(e, nil)
+	}
+	first, second, otherSeries := entries[0].Dataset, entries[1].Dataset, entries[3].Dataset
+	assert.Equal(value(first, tag.StudyInstanceUID), value(otherSeries, tag.StudyInstanceUID))
+	assert.Equal(value(first, tag.SeriesInstanceUID), value(second, tag.SeriesInstanceUID))
+	assert.NotEqual(value(first, tag.SeriesInstanceUID), value(otherSeries, tag.SeriesInstanceUID))
+	assert.NotEqual(value(first, tag.SOPInstanceUID), value(second, tag.SOPInstanceUID))
+	assert.Equal(value(first, tag.SOPInstanceUID), value(first, tag.MediaStorageSOPInstanceUID))
+	assert.Regexp(`^2\.25\.[1-9][0-9]*$`, value(first, tag.SOPInstanceUID))
+	assert.Equal("20240506", value(first, tag.StudyDate))
+	assert.Equal("2", value(second, tag.InstanceNumber))
+	assert.Equal("4", value(first, tag.Rows))
+	assert.Equal("16", value(first, tag.BitsAllocated))
+
+	iodName, findings := validate.Dataset(&first)
+	assert.Equal("CT Image", iodName)
+	for _, finding := range findings {
+		assert.NotNil(finding.Element, finding.Message)
+	}
+
+	_, err = Study(Options{Modality: "XA", Series: 1, Instances: 1, Rows: 1, Columns: 1})
+	assert.ErrorContains(err, "use one of CR, CT, MR, OT, PT, US")
+	_, err = Study(Options{Modality: "CT"})
+	assert.Error(err)
+}
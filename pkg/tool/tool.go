@@ -0,0 +1,51 @@
+// Package tool runs external commands with the shell, e.g. dcmdump, storescu or a viewer on the current file, with
+// the placeholders {file} and {tag} substituted.
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// returns the command running the line with the shell, sh or cmd on Windows
+func Command(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command("sh", "-c", line)
+}
+
+// environment variable with the path of the file passed by Run
+const FileVariable = "DCMTAGGER_FILE"
+
+// substitutes {file} by the quoted path and {tag} by the tag like 0010,0010, fails for {tag} without one
+func Expand(command, file, tag string) (string, error) {
+	if strings.Contains(command, "{tag}") && tag == "" {
+		return "", fmt.Errorf("no element selected for {tag}")
+	}
+	return strings.NewReplacer("{file}", quotePath(file), "{tag}", tag).Replace(command), nil
+}
+
+// quotes the path for the shell of Command. cmd on Windows expands % even in quotes and has no escape for it there,
+// so the path is referenced by FileVariable instead, whose value is not parsed again.
+func quotePath(path string) string {
+	if runtime.GOOS == "windows" {
+		return `"%` + FileVariable + `%"`
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// runs the line with the shell, with the path of the file in FileVariable, and returns its stdout and stderr
+// interleaved, a command exiting with an error fails with its output
+func Run(line, file string) (string, error) {
+	var output bytes.Buffer
+	cmd := Command(line)
+	cmd.Env = append(os.Environ(), FileVariable+"="+file)
+	cmd.Stdout, cmd.Stderr = &output, &output
+	err := cmd.Run()
+	return output.String(), err
+}
@@ -0,0 +1,32 @@
+package tool
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandAndRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands use sh")
+	}
+	assert := assert.New(t)
+
+	line, err := Expand("echo {file} {tag}", "dir/it's.dcm", "0010,0010")
+	assert.NoError(err)
+	assert.Equal(`echo 'dir/it'\''s.dcm' 0010,0010`, line)
+	output, err := Run(line, "dir/it's.dcm")
+	assert.NoError(err)
+	assert.Equal("dir/it's.dcm 0010,0010\n", output)
+	output, err = Run(`echo "$DCMTAGGER_FILE"`, "dir/100% it's.dcm")
+	assert.NoError(err)
+	assert.Equal("dir/100% it's.dcm\n", output)
+
+	_, err = Expand("dcmdump +P {tag} {file}", "a.dcm", "")
+	assert.EqualError(err, "no element selected for {tag}")
+
+	output, err = Run("echo out; echo err >&2; exit 2", "a.dcm")
+	assert.EqualError(err, "exit status 2")
+	assert.Equal("out\nerr\n", output)
+}
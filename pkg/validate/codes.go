@@ -0,0 +1,38 @@
+package validate
+
+import (
+	"fmt"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// checks the items of code sequences at any depth against the Code Sequence Macro, PS3.3 8.8: a code needs its
+// CodeMeaning and, unless it is a URN code, its CodingSchemeDesignator
+func Codes(dataset *dicom.Dataset) []Finding {
+	return checkCodes(dataset.Elements)
+}
+
+func checkCodes(elements []*dicom.Element) []Finding {
+	findings := make([]Finding, 0)
+	for _, e := range elements {
+		for i, item := range dicomtree.SequenceItems(e) {
+			if code, ok := dicomtree.ItemCode(item); ok {
+				itemText := fmt.Sprintf("%s %s item %d code %s", dicomtree.FormatTag(e.Tag), dicomtree.TagName(e), i+1, code.Value)
+				if code.Scheme == "" && dicomtree.ItemValue(item, dicomtree.URNCodeValue) == "" {
+					findings = append(findings, Finding{SeverityError, e.Tag, e, itemText + " has no CodingSchemeDesignator"})
+				}
+				if dicomtree.ItemValue(item, tag.CodeMeaning) == "" {
+					message := itemText + " has no CodeMeaning"
+					if code.Known {
+						message += ", known as " + code.Meaning
+					}
+					findings = append(findings, Finding{SeverityError, e.Tag, e, message})
+				}
+			}
+			findings = append(findings, checkCodes(item)...)
+		}
+	}
+	return findings
+}
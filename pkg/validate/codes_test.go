@@ -0,0 +1,30 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	code := func(elements ...*dicom.Element) []*dicom.Element { return elements }
+	dataset := &dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.ProcedureCodeSequence, [][]*dicom.Element{
+			code(mustElement(t, tag.CodeValue, []string{"818981001"}), mustElement(t, tag.CodingSchemeDesignator, []string{"SCT"}),
+				mustElement(t, tag.CodeMeaning, []string{"Abdomen"})),
+			code(mustElement(t, tag.CodeValue, []string{"113830"}), mustElement(t, tag.CodingSchemeDesignator, []string{"DCM"})),
+			code(mustElement(t, tag.CodeValue, []string{"42"}), mustElement(t, tag.CodeMeaning, []string{"Answer"})),
+		}),
+	}}
+	findings := Codes(dataset)
+	if assert.Len(findings, 2) {
+		assert.Contains(findings[0].Message, "item 2 code 113830 has no CodeMeaning, known as Mean CTDIvol")
+		assert.Contains(findings[1].Message, "item 3 code 42 has no CodingSchemeDesignator")
+		assert.Same(dataset.Elements[0], findings[1].Element)
+	}
+	assert.Empty(Codes(&dicom.Dataset{Elements: dataset.Elements[:0]}))
+}
@@ -0,0 +1,111 @@
+package validate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/checksum"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+)
+
+// writes the dataset to a temporary file, parses it again and returns a finding per element the writer dropped,
+// changed or added, see CompareElements
+func RoundTrip(dataset *dicom.Dataset) ([]Finding, error) {
+	dir, err := os.MkdirTemp("", "dcmtagger-roundtrip-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "roundtrip.dcm")
+	if err := dicomtree.WriteFile(*dataset, path); err != nil {
+		return nil, fmt.Errorf("error writing the dataset: %w", err)
+	}
+	written, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the written dataset: %w", err)
+	}
+	return CompareElements(dataset.Elements, written.Elements), nil
+}
+
+// returns an error per element of before that is missing or has another value in after, a warning per element
+// with another VR or only present in after. Sequences are compared item by item, pixel data by its decoded samples.
+// Group lengths are skipped, as writers recompute them, and so is trailing padding of string values.
+func CompareElements(before, after []*dicom.Element) []Finding {
+	return compareElements("", before, after)
+}
+
+func compareElements(prefix string, before, after []*dicom.Element) []Finding {
+	findings := make([]Finding, 0)
+	written := make(map[uint32]*dicom.Element, len(after))
+	for _, e := range after {
+		written[dicomtree.TagOrder(e.Tag)] = e
+	}
+	for _, e := range before {
+		if e.Tag.Element == 0 {
+			continue
+		}
+		path := prefix + dicomtree.FormatTag(e.Tag)
+		tagText := strings.TrimSpace(path + " " + dicomtree.TagName(e))
+		w, ok := written[dicomtree.TagOrder(e.Tag)]
+		delete(written, dicomtree.TagOrder(e.Tag))
+		if !ok {
+			findings = append(findings, Finding{SeverityError, e.Tag, e, tagText + " was dropped"})
+			continue
+		}
+		if e.RawValueRepresentation != w.RawValueRepresentation {
+			findings = append(findings, Finding{SeverityWarning, e.Tag, e,
+				fmt.Sprintf("%s changed VR from %s to %s", tagText, e.RawValueRepresentation, w.RawValueRepresentation)})
+		}
+		if dicomtree.IsSequence(e) || dicomtree.IsSequence(w) {
+			items, writtenItems := dicomtree.SequenceItems(e), dicomtree.SequenceItems(w)
+			if len(items) != len(writtenItems) {
+				findings = append(findings, Finding{SeverityError, e.Tag, e,
+					fmt.Sprintf("%s changed from %d to %d items", tagText, len(items), len(writtenItems))})
+				continue
+			}
+			for i := range items {
+				findings = append(findings, compareElements(fmt.Sprintf("%s/%d/", path, i+1), items[i], writtenItems[i])...)
+			}
+			continue
+		}
+		if comparableValue(e) != comparableValue(w) {
+			findings = append(findings, Finding{SeverityError, e.Tag, e, fmt.Sprintf("%s changed value from '%s' to '%s'", tagText,
+				dicomtree.ValueString(e, nil, dicomtree.DisplayOptions{}), dicomtree.ValueString(w, nil, dicomtree.DisplayOptions{}))})
+		}
+	}
+	for _, e := range after {
+		if _, added := written[dicomtree.TagOrder(e.Tag)]; added && e.Tag.Element != 0 {
+			findings = append(findings, Finding{SeverityWarning, e.Tag, nil,
+				strings.TrimSpace(prefix+dicomtree.FormatTag(e.Tag)+" "+dicomtree.TagName(e)) + " was added"})
+		}
+	}
+	return findings
+}
+
+// returns the value of the element in a form that doesn't depend on its encoding
+func comparableValue(e *dicom.Element) string {
+	if e.Value == nil {
+		return ""
+	}
+	switch e.Value.ValueType() {
+	case dicom.Bytes:
+		return hex.EncodeToString(dicom.MustGetBytes(e.Value))
+	case dicom.PixelData:
+		digest, err := checksum.PixelData(&dicom.Dataset{Elements: []*dicom.Element{e}})
+		if err != nil {
+			return err.Error()
+		}
+		return digest
+	case dicom.Strings:
+		values := make([]string, 0)
+		for _, v := range dicom.MustGetStrings(e.Value) {
+			values = append(values, strings.TrimRight(v, "\x00 "))
+		}
+		return strings.Join(values, "\\")
+	}
+	return fmt.Sprint(e.Value.GetValue())
+}
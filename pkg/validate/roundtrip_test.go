@@ -0,0 +1,50 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestCompareElements(t *testing.T) {
+	assert := assert.New(t)
+
+	before := []*dicom.Element{
+		mustElement(t, tag.FileMetaInformationGroupLength, []int{100}),
+		mustElement(t, tag.Modality, []string{"CT"}),
+		mustElement(t, tag.PatientName, []string{"Doe^John"}),
+		mustElement(t, tag.PatientID, []string{"123"}),
+		mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{
+			{mustElement(t, tag.SeriesInstanceUID, []string{"1.2.3"})},
+		}),
+	}
+	after := []*dicom.Element{
+		mustElement(t, tag.FileMetaInformationGroupLength, []int{104}),
+		mustElement(t, tag.Modality, []string{"CT "}),
+		mustElement(t, tag.PatientName, []string{"Doe^Jane"}),
+		mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{
+			{mustElement(t, tag.SeriesInstanceUID, []string{"1.2.4"})},
+		}),
+		mustElement(t, tag.PatientSex, []string{"M"}),
+	}
+	assert.Empty(CompareElements(before, before))
+
+	findings := CompareElements(before, after)
+	if assert.Len(findings, 4, "group length and padding are skipped") {
+		assert.Equal(SeverityError, findings[0].Severity)
+		assert.Same(before[2], findings[0].Element)
+		assert.Contains(findings[0].Message, "changed value from 'Doe^John' to 'Doe^Jane'")
+		assert.Contains(findings[1].Message, "(0010,0020)")
+		assert.Contains(findings[1].Message, "was dropped")
+		assert.Contains(findings[2].Message, "(0008,1115)/1/(0020,000e)")
+		assert.Equal(SeverityWarning, findings[3].Severity)
+		assert.Nil(findings[3].Element)
+		assert.Contains(findings[3].Message, "(0010,0040)")
+		assert.Contains(findings[3].Message, "was added")
+	}
+
+	after[3] = mustElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{})
+	assert.Contains(CompareElements(before[4:], after[3:4])[0].Message, "changed from 1 to 0 items")
+}
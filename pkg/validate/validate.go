@@ -0,0 +1,305 @@
+// Package validate checks datasets against the required attributes of their IOD and the encoding rules of the standard, and
+// verifies that writing a dataset keeps all elements.
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "Warning"
+	}
+	return "Error"
+}
+
+type Finding struct {
+	Severity Severity
+	Tag      tag.Tag
+	Element  *dicom.Element // nil if the attribute is missing
+	Message  string
+}
+
+type attributeRequirement struct {
+	tag      tag.Tag
+	attrType string // "1" must be present with value, "2" must be present but may be empty
+}
+
+type moduleDefinition struct {
+	name       string
+	attributes []attributeRequirement
+}
+
+type iodDefinition struct {
+	name    string
+	modules []moduleDefinition
+}
+
+var patientModule = moduleDefinition{"Patient", []attributeRequirement{
+	{tag.PatientName, "2"},
+	{tag.PatientID, "2"},
+	{tag.PatientBirthDate, "2"},
+	{tag.PatientSex, "2"},
+}}
+
+var generalStudyModule = moduleDefinition{"General Study", []attributeRequirement{
+	{tag.StudyInstanceUID, "1"},
+	{tag.StudyDate, "2"},
+	{tag.StudyTime, "2"},
+	{tag.ReferringPhysicianName, "2"},
+	{tag.StudyID, "2"},
+	{tag.AccessionNumber, "2"},
+}}
+
+var generalSeriesModule = moduleDefinition{"General Series", []attributeRequirement{
+	{tag.Modality, "1"},
+	{tag.SeriesInstanceUID, "1"},
+	{tag.SeriesNumber, "2"},
+}}
+
+var frameOfReferenceModule = moduleDefinition{"Frame of Reference", []attributeRequirement{
+	{tag.FrameOfReferenceUID, "1"},
+	{tag.PositionReferenceIndicator, "2"},
+}}
+
+var generalEquipmentModule = moduleDefinition{"General Equipment", []attributeRequirement{
+	{tag.Manufacturer, "2"},
+}}
+
+var generalImageModule = moduleDefinition{"General Image", []attributeRequirement{
+	{tag.InstanceNumber, "2"},
+}}
+
+var imagePlaneModule = moduleDefinition{"Image Plane", []attributeRequirement{
+	{tag.PixelSpacing, "1"},
+	{tag.ImageOrientationPatient, "1"},
+	{tag.ImagePositionPatient, "1"},
+	{tag.SliceThickness, "2"},
+}}
+
+var imagePixelModule = moduleDefinition{"Image Pixel", []attributeRequirement{
+	{tag.SamplesPerPixel, "1"},
+	{tag.PhotometricInterpretation, "1"},
+	{tag.Rows, "1"},
+	{tag.Columns, "1"},
+	{tag.BitsAllocated, "1"},
+	{tag.BitsStored, "1"},
+	{tag.HighBit, "1"},
+	{tag.PixelRepresentation, "1"},
+	{tag.PixelData, "1"},
+}}
+
+var ctImageModule = moduleDefinition{"CT Image", []attributeRequirement{
+	{tag.ImageType, "1"},
+	{tag.RescaleIntercept, "1"},
+	{tag.RescaleSlope, "1"},
+	{tag.KVP, "2"},
+	{tag.AcquisitionNumber, "2"},
+}}
+
+var mrImageModule = moduleDefinition{"MR Image", []attributeRequirement{
+	{tag.ImageType, "1"},
+	{tag.ScanningSequence, "1"},
+	{tag.SequenceVariant, "1"},
+	{tag.ScanOptions, "2"},
+	{tag.MRAcquisitionType, "2"},
+	{tag.EchoTime, "2"},
+	{tag.EchoTrainLength, "2"},
+}}
+
+var crImageModule = moduleDefinition{"CR Image", []attributeRequirement{
+	{tag.BodyPartExamined, "2"},
+	{tag.ViewPosition, "2"},
+}}
+
+var scEquipmentModule = moduleDefinition{"SC Equipment", []attributeRequirement{
+	{tag.ConversionType, "1"},
+}}
+
+var sopCommonModule = moduleDefinition{"SOP Common", []attributeRequirement{
+	{tag.SOPClassUID, "1"},
+	{tag.SOPInstanceUID, "1"},
+}}
+
+var iodsBySOPClassUID = map[string]iodDefinition{
+	"1.2.840.10008.5.1.4.1.1.2": {"CT Image", []moduleDefinition{
+		patientModule, generalStudyModule, generalSeriesModule, frameOfReferenceModule, generalEquipmentModule,
+		generalImageModule, imagePlaneModule, imagePixelModule, ctImageModule, sopCommonModule}},
+	"1.2.840.10008.5.1.4.1.1.4": {"MR Image", []moduleDefinition{
+		patientModule, generalStudyModule, generalSeriesModule, frameOfReferenceModule, generalEquipmentModule,
+		generalImageModule, imagePlaneModule, imagePixelModule, mrImageModule, sopCommonModule}},
+	"1.2.840.10008.5.1.4.1.1.1": {"Computed Radiography Image", []moduleDefinition{
+		patientModule, generalStudyModule, generalSeriesModule, generalEquipmentModule,
+		generalImageModule, imagePixelModule, crImageModule, sopCommonModule}},
+	"1.2.840.10008.5.1.4.1.1.7": {"Secondary Capture Image", []moduleDefinition{
+		patientModule, generalStudyModule, generalSeriesModule, scEquipmentModule,
+		generalImageModule, imagePixelModule, sopCommonModule}},
+	"1.2.840.10008.5.1.4.1.1.6.1": {"Ultrasound Image", []moduleDefinition{
+		patientModule, generalStudyModule, generalSeriesModule, generalEquipmentModule,
+		generalImageModule, imagePixelModule, sopCommonModule}},
+	"1.2.840.10008.5.1.4.1.1.128": {"PET Image", []moduleDefinition{
+		patientModule, generalStudyModule, generalSeriesModule, frameOfReferenceModule, generalEquipmentModule,
+		generalImageModule, imagePlaneModule, imagePixelModule, sopCommonModule}},
+}
+
+var enumeratedValuesByTag = map[tag.Tag][]string{
+	tag.PatientSex:                {"M", "F", "O"},
+	tag.PhotometricInterpretation: {"MONOCHROME1", "MONOCHROME2", "PALETTE COLOR", "RGB", "YBR_FULL", "YBR_FULL_422", "YBR_PARTIAL_420", "YBR_ICT", "YBR_RCT"},
+	tag.PixelRepresentation:       {"0", "1"},
+	tag.PlanarConfiguration:       {"0", "1"},
+	tag.ConversionType:            {"DV", "DI", "DF", "WSD", "SD", "SI", "DRW", "SYN"},
+	tag.BurnedInAnnotation:        {"YES", "NO"},
+	tag.LossyImageCompression:     {"00", "01"},
+	tag.PatientIdentityRemoved:    {"YES", "NO"},
+}
+
+var uidPattern = regexp.MustCompile(`^(0|[1-9][0-9]*)(\.(0|[1-9][0-9]*))*$`)
+
+// returns the name of the IOD the dataset was checked against and all findings
+func Dataset(dataset *dicom.Dataset) (string, []Finding) {
+	findings := make([]Finding, 0)
+
+	iodName := "unknown IOD"
+	sopClassUID := ""
+	if e, err := dataset.FindElementByTag(tag.SOPClassUID); err == nil {
+		if values := dicomtree.ValueStrings(e); len(values) > 0 {
+			sopClassUID = strings.TrimRight(values[0], "\x00 ")
+		}
+	}
+	iod, ok := iodsBySOPClassUID[sopClassUID]
+	if ok {
+		iodName = iod.name
+		findings = append(findings, checkModules(dataset, iod.modules)...)
+	} else {
+		findings = append(findings, Finding{SeverityWarning, tag.SOPClassUID, nil,
+			fmt.Sprintf("no IOD definition for SOP Class UID '%s', only checking attribute encoding", sopClassUID)})
+	}
+
+	for _, e := range dataset.Elements {
+		findings = append(findings, checkElement(e)...)
+	}
+	findings = append(findings, Codes(dataset)...)
+
+	return iodName, findings
+}
+
+// returns the Type 1 and Type 2 attributes of the IOD of the dataset, nil if there is no definition for its SOP class
+func RequiredTags(dataset *dicom.Dataset) map[tag.Tag]bool {
+	e, err := dataset.FindElementByTag(tag.SOPClassUID)
+	if err != nil {
+		return nil
+	}
+	values := dicomtree.ValueStrings(e)
+	if len(values) == 0 {
+		return nil
+	}
+	iod, ok := iodsBySOPClassUID[strings.TrimRight(values[0], "\x00 ")]
+	if !ok {
+		return nil
+	}
+	required := make(map[tag.Tag]bool)
+	for _, module := range iod.modules {
+		for _, attr := range module.attributes {
+			required[attr.tag] = true
+		}
+	}
+	return required
+}
+
+func checkModules(dataset *dicom.Dataset, modules []moduleDefinition) []Finding {
+	findings := make([]Finding, 0)
+	for _, module := range modules {
+		for _, attr := range module.attributes {
+			e, err := dataset.FindElementByTag(attr.tag)
+			if err != nil {
+				findings = append(findings, Finding{SeverityError, attr.tag, nil,
+					fmt.Sprintf("missing Type %s attribute %s %s from %s module", attr.attrType, dicomtree.FormatTag(attr.tag), dicomtree.TagNameByTag(attr.tag), module.name)})
+				continue
+			}
+			if attr.attrType == "1" && isEmptyElement(e) {
+				findings = append(findings, Finding{SeverityError, attr.tag, e,
+					fmt.Sprintf("empty Type 1 attribute %s %s from %s module", dicomtree.FormatTag(attr.tag), dicomtree.TagNameByTag(attr.tag), module.name)})
+			}
+		}
+	}
+	return findings
+}
+
+func checkElement(e *dicom.Element) []Finding {
+	findings := make([]Finding, 0)
+	if e.Tag.Group%2 == 1 || e.Tag.Group == 0xfffe {
+		return findings // private or delimiter tags are not in the dictionary
+	}
+
+	tagText := fmt.Sprintf("%s %s", dicomtree.FormatTag(e.Tag), dicomtree.TagName(e))
+	if tagInfo, err := tag.Find(e.Tag); err == nil && e.RawValueRepresentation != "" && !isAllowedVR(tagInfo.VR, e.RawValueRepresentation) {
+		findings = append(findings, Finding{SeverityError, e.Tag, e,
+			fmt.Sprintf("%s has VR %s, expected %s", tagText, e.RawValueRepresentation, tagInfo.VR)})
+	}
+
+	values := dicomtree.ValueStrings(e)
+	if allowed, ok := enumeratedValuesByTag[e.Tag]; ok {
+		for _, v := range values {
+			v = strings.TrimSpace(v)
+			if v != "" && !containsString(allowed, v) {
+				findings = append(findings, Finding{SeverityError, e.Tag, e,
+					fmt.Sprintf("%s has invalid enumerated value '%s', expected one of %s", tagText, v, strings.Join(allowed, ", "))})
+			}
+		}
+	}
+
+	if e.RawValueRepresentation == "UI" {
+		for _, v := range values {
+			v = strings.TrimRight(v, "\x00 ")
+			if v != "" && (len(v) > 64 || !uidPattern.MatchString(v)) {
+				findings = append(findings, Finding{SeverityError, e.Tag, e,
+					fmt.Sprintf("%s has invalid UID '%s'", tagText, v)})
+			}
+		}
+	}
+
+	return findings
+}
+
+func isAllowedVR(dictVR string, vr string) bool {
+	for _, allowed := range strings.Split(dictVR, " or ") {
+		if strings.TrimSpace(allowed) == vr {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyElement(e *dicom.Element) bool {
+	if e.Value == nil || e.ValueLength == 0 {
+		return true
+	}
+	for _, v := range dicomtree.ValueStrings(e) {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return e.Value.ValueType() == dicom.Strings
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestUIDPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(uidPattern.MatchString("1.2.840.10008.5.1.4.1.1.2"))
+	assert.True(uidPattern.MatchString("1.2.0.3"))
+	assert.False(uidPattern.MatchString("1.2.03"), "leading zero in component")
+	assert.False(uidPattern.MatchString("1..2"), "empty component")
+	assert.False(uidPattern.MatchString("1.2.a"), "non-numeric component")
+}
+
+func TestIsAllowedVR(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isAllowedVR("US", "US"))
+	assert.True(isAllowedVR("US or SS", "SS"))
+	assert.False(isAllowedVR("DA", "DT"))
+}
+
+func TestDataset(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+		mustElement(t, tag.SOPInstanceUID, []string{"1.2.03"}),
+	}}
+	dataset.Elements[1].RawValueRepresentation = "UI"
+	iodName, findings := Dataset(&dataset)
+	assert.Equal("CT Image", iodName)
+
+	missingPatientName, invalidUID := false, false
+	for _, finding := range findings {
+		missingPatientName = missingPatientName || (finding.Tag == tag.PatientName && finding.Element == nil)
+		invalidUID = invalidUID || (finding.Tag == tag.SOPInstanceUID && finding.Element != nil)
+	}
+	assert.True(missingPatientName)
+	assert.True(invalidUID)
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}
@@ -0,0 +1,212 @@
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drcynic/dcmtagger/pkg/charset"
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// maximum number of characters of a value per VR, PS3.5 6.2. PN limits each component group.
+var maxValueLengths = map[string]int{
+	"AE": 16, "AS": 4, "CS": 16, "DA": 8, "DS": 16, "DT": 26, "IS": 12, "LO": 64, "LT": 10240, "PN": 64, "SH": 16,
+	"ST": 1024, "TM": 14, "UI": 64,
+}
+
+// VRs whose values have exactly their maximum length
+var fixedLengthVRs = map[string]bool{"AS": true, "DA": true}
+
+// VRs holding a single value that may contain backslashes, which the parser splits nevertheless
+var singleValueVRs = map[string]bool{"LT": true, "ST": true, "UT": true, "UR": true}
+
+// VRs whose leading spaces are insignificant, trailing spaces are insignificant for all string VRs
+var leadingSpaceVRs = map[string]bool{
+	"AE": true, "AS": true, "CS": true, "DA": true, "DS": true, "DT": true, "IS": true, "LO": true, "SH": true,
+	"TM": true, "UI": true,
+}
+
+var (
+	separatedDatePattern = regexp.MustCompile(`^(\d{4})[.-](\d{2})[.-](\d{2})$`)
+	separatedTimePattern = regexp.MustCompile(`^(\d{2}):(\d{2})(?::(\d{2})(\.\d+)?)?$`)
+	longTimePattern      = regexp.MustCompile(`^\d{6}\.\d{7,}$`)
+)
+
+// checks the string values of all elements including nested ones against the length rules of their VR, for padding
+// kept inside of values and for odd value lengths. Findings that FixValues repairs say so in their message.
+func Values(dataset *dicom.Dataset) []Finding {
+	return checkValues(dataset.Elements, charset.FromDataset(dataset), false)
+}
+
+func checkValues(elements []*dicom.Element, charsets []string, nested bool) []Finding {
+	findings := make([]Finding, 0)
+	for _, e := range elements {
+		if e.Value == nil {
+			continue
+		}
+		if e.Value.ValueType() == dicom.Sequences {
+			for _, item := range e.Value.GetValue().([]*dicom.SequenceItemValue) {
+				findings = append(findings, checkValues(item.GetValue().([]*dicom.Element), charsets, true)...)
+			}
+			continue
+		}
+		tagText := fmt.Sprintf("%s %s", dicomtree.FormatTag(e.Tag), dicomtree.TagName(e))
+		if e.ValueLength%2 == 1 && e.ValueLength != tag.VLUndefinedLength {
+			findings = append(findings, Finding{SeverityWarning, e.Tag, e,
+				fmt.Sprintf("%s has odd value length %d, writing pads it to %d", tagText, e.ValueLength, e.ValueLength+1)})
+		}
+		vr := elementVR(e)
+		if e.Value.ValueType() != dicom.Strings || vr == "" {
+			continue
+		}
+		for _, v := range elementValues(e, vr) {
+			problem, _, fixable := checkValue(vr, v, charsets)
+			if problem == "" {
+				continue
+			}
+			severity := SeverityError
+			if fixable {
+				severity = SeverityWarning
+				if !nested {
+					problem += ", fixable"
+				}
+			}
+			findings = append(findings, Finding{severity, e.Tag, e, fmt.Sprintf("%s %s value '%s' %s", tagText, vr, v, problem)})
+		}
+	}
+	return findings
+}
+
+// fixes the values of the top level elements that Values reports as fixable without changing their meaning: it removes
+// padding inside of values and separators of dates and times and shortens decimal strings and fractions of seconds
+// that are too long. Returns the number of changed elements.
+func FixValues(dataset *dicom.Dataset) int {
+	charsets := charset.FromDataset(dataset)
+	changed := 0
+	for _, e := range dataset.Elements {
+		vr := elementVR(e)
+		if e.Value == nil || e.Value.ValueType() != dicom.Strings || vr == "" || singleValueVRs[vr] {
+			continue
+		}
+		values := e.Value.GetValue().([]string)
+		fixed := make([]string, 0, len(values))
+		modified := false
+		for _, v := range values {
+			if problem, fixedValue, fixable := checkValue(vr, v, charsets); problem != "" && fixable {
+				v, modified = fixedValue, true
+			}
+			fixed = append(fixed, v)
+		}
+		if !modified {
+			continue
+		}
+		value, err := dicom.NewValue(fixed)
+		if err != nil {
+			continue
+		}
+		e.Value = value
+		changed++
+	}
+	return changed
+}
+
+// returns the VR of the element, the one of the dictionary if it was read without
+func elementVR(e *dicom.Element) string {
+	if e.RawValueRepresentation != "" {
+		return e.RawValueRepresentation
+	}
+	if info, err := tag.Find(e.Tag); err == nil {
+		return strings.Split(info.VR, " or ")[0]
+	}
+	return ""
+}
+
+func elementValues(e *dicom.Element, vr string) []string {
+	values := e.Value.GetValue().([]string)
+	if singleValueVRs[vr] {
+		return []string{strings.Join(values, "\\")}
+	}
+	return values
+}
+
+// returns the problem of the value, "" if there is none, and whether the returned fixed value repairs it
+func checkValue(vr string, value string, charsets []string) (string, string, bool) {
+	trimmed := strings.TrimRight(value, " \x00")
+	if leadingSpaceVRs[vr] {
+		trimmed = strings.TrimLeft(trimmed, " ")
+	}
+	if trimmed != value && !singleValueVRs[vr] {
+		problem, fixed, fixable := checkValue(vr, trimmed, charsets)
+		if problem == "" {
+			return "has padding inside the value", trimmed, true
+		}
+		return "has padding inside the value and " + problem, fixed, fixable
+	}
+	if vr == "UI" && strings.ContainsRune(value, 0) {
+		return "contains a null character inside the value", "", false
+	}
+	switch vr {
+	case "DA":
+		if m := separatedDatePattern.FindStringSubmatch(value); m != nil {
+			return "has separators, which DA values do not have", m[1] + m[2] + m[3], true
+		}
+	case "TM":
+		if m := separatedTimePattern.FindStringSubmatch(value); m != nil {
+			return "has separators, which TM values do not have", m[1] + m[2] + m[3] + m[4], true
+		}
+		if longTimePattern.MatchString(value) {
+			return "has more than 6 digits of fractional seconds", value[:13], true
+		}
+	}
+	problem := lengthProblem(vr, value, charsets)
+	if problem == "" {
+		return "", value, false
+	}
+	if vr == "DS" {
+		if fixed, ok := shortDecimalString(value); ok {
+			return problem, fixed, true
+		}
+	}
+	return problem, "", false
+}
+
+// returns the length violation of the value, "" if there is none
+func lengthProblem(vr string, value string, charsets []string) string {
+	maxLength, ok := maxValueLengths[vr]
+	if !ok {
+		return ""
+	}
+	components := []string{value}
+	if vr == "PN" {
+		components = strings.Split(value, "=")
+	}
+	for _, component := range components {
+		length := utf8.RuneCountInString(charset.Decode(component, charsets))
+		if fixedLengthVRs[vr] && length != maxLength && length > 0 {
+			return fmt.Sprintf("has %d characters, %s values have %d", length, vr, maxLength)
+		}
+		if length > maxLength {
+			return fmt.Sprintf("has %d characters, %s allows %d", length, vr, maxLength)
+		}
+	}
+	return ""
+}
+
+// returns the decimal string with as many significant digits as fit into 16 characters
+func shortDecimalString(value string) (string, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return "", false
+	}
+	for precision := 16; precision > 0; precision-- {
+		if s := strconv.FormatFloat(f, 'g', precision, 64); len(s) <= maxValueLengths["DS"] {
+			return s, true
+		}
+	}
+	return "", false
+}
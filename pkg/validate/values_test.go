@@ -0,0 +1,74 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestCheckValue(t *testing.T) {
+	assert := assert.New(t)
+
+	problem, _, _ := checkValue("SH", "0123456789abcdef", nil)
+	assert.Empty(problem)
+	problem, _, fixable := checkValue("SH", "0123456789abcdefg", nil)
+	assert.Equal("has 17 characters, SH allows 16", problem)
+	assert.False(fixable)
+	problem, _, _ = checkValue("PN", "DOE^JOHN=ドウ^ジョン", nil)
+	assert.Empty(problem, "PN limits each component group")
+	problem, _, _ = checkValue("AS", "45Y", nil)
+	assert.Equal("has 3 characters, AS values have 4", problem)
+
+	problem, fixed, fixable := checkValue("UI", "1.2.3 ", nil)
+	assert.Equal("has padding inside the value", problem)
+	assert.Equal("1.2.3", fixed)
+	assert.True(fixable)
+	_, _, fixable = checkValue("UI", "1.2\x003", nil)
+	assert.False(fixable)
+	problem, fixed, _ = checkValue("LO", " SITE ", nil)
+	assert.Equal("SITE", fixed)
+	problem, fixed, fixable = checkValue("DA", "2024-01-31", nil)
+	assert.Equal("has separators, which DA values do not have", problem)
+	assert.Equal("20240131", fixed)
+	assert.True(fixable)
+	_, fixed, _ = checkValue("TM", "12:30:05", nil)
+	assert.Equal("123005", fixed)
+	_, fixed, _ = checkValue("TM", "123005.1234567", nil)
+	assert.Equal("123005.123456", fixed)
+	_, fixed, fixable = checkValue("DS", "0.123456789012345678", nil)
+	assert.Equal("0.12345678901235", fixed)
+	assert.True(fixable)
+	_, _, fixable = checkValue("DS", "not a number too long", nil)
+	assert.False(fixable)
+}
+
+func TestValues(t *testing.T) {
+	assert := assert.New(t)
+
+	description := mustElement(t, tag.StudyDescription, []string{"a description that is far too long for SH"})
+	description.RawValueRepresentation = "SH"
+	date := mustElement(t, tag.StudyDate, []string{"2024.01.31"})
+	date.RawValueRepresentation = "DA"
+	uids := mustElement(t, tag.Tag{Group: 0x0008, Element: 0x1150}, []string{"1.2.3 ", "1.2.4"})
+	uids.RawValueRepresentation = "UI"
+	uids.ValueLength = 13
+	dataset := dicom.Dataset{Elements: []*dicom.Element{date, description, uids}}
+
+	findings := Values(&dataset)
+	assert.Len(findings, 4)
+	messages := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		messages = append(messages, finding.Message)
+	}
+	assert.Contains(messages[0], "'2024.01.31' has separators, which DA values do not have, fixable")
+	assert.Contains(messages[1], "has 41 characters, SH allows 16")
+	assert.Contains(messages[2], "odd value length 13, writing pads it to 14")
+	assert.Contains(messages[3], "'1.2.3 ' has padding inside the value, fixable")
+
+	assert.Equal(2, FixValues(&dataset))
+	assert.Equal([]string{"20240131"}, date.Value.GetValue())
+	assert.Equal([]string{"1.2.3", "1.2.4"}, uids.Value.GetValue())
+	assert.Len(Values(&dataset), 2, "too long SH and odd length are not fixed")
+}
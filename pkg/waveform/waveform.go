@@ -0,0 +1,225 @@
+// Package waveform decodes the multiplex groups of waveform objects like ECGs, renders their channels as braille plots
+// and exports the samples as CSV.
+package waveform
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/drcynic/dcmtagger/pkg/dicomtree"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+var ErrNoWaveform = errors.New("no waveform sequence")
+
+type Channel struct {
+	Label   string    // channel label or the code meaning of the channel source, e.g. "Lead I"
+	Unit    string    // code value of the sensitivity units, e.g. "uV", "" if not given
+	Samples []float64 // sensitivity corrected values including the baseline
+}
+
+// multiplex group of channels sampled at the same frequency
+type Group struct {
+	Label     string
+	Frequency float64 // in Hz
+	Channels  []Channel
+}
+
+// decodes the channels of all multiplex groups of the waveform sequence
+func Decode(dataset *dicom.Dataset) ([]Group, error) {
+	e, err := dataset.FindElementByTag(tag.WaveformSequence)
+	if err != nil {
+		return nil, ErrNoWaveform
+	}
+	groups := make([]Group, 0)
+	for i, item := range dicomtree.SequenceItems(e) {
+		group, err := decodeGroup(item)
+		if err != nil {
+			return groups, fmt.Errorf("multiplex group %d: %w", i+1, err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func decodeGroup(item []*dicom.Element) (Group, error) {
+	number := func(elements []*dicom.Element, t tag.Tag, fallback float64) float64 {
+		value, err := strconv.ParseFloat(dicomtree.ItemValue(elements, t), 64)
+		if err != nil {
+			return fallback
+		}
+		return value
+	}
+	group := Group{Label: dicomtree.ItemValue(item, tag.MultiplexGroupLabel), Frequency: number(item, tag.SamplingFrequency, 0)}
+	channelCount := int(number(item, tag.NumberOfWaveformChannels, 0))
+	sampleCount := int(number(item, tag.NumberOfWaveformSamples, 0))
+	bits := int(number(item, tag.WaveformBitsAllocated, 16))
+	interpretation := dicomtree.ItemValue(item, tag.WaveformSampleInterpretation)
+	data := dicomtree.FindItemElement(item, tag.WaveformData)
+	if data == nil || data.Value == nil || data.Value.ValueType() != dicom.Bytes {
+		return group, errors.New("no waveform data")
+	}
+	raw, err := rawSamples(dicom.MustGetBytes(data.Value), bits, interpretation)
+	if err != nil {
+		return group, err
+	}
+	if channelCount <= 0 || len(raw) < channelCount*sampleCount {
+		return group, fmt.Errorf("%d samples of waveform data for %d channels of %d samples", len(raw), channelCount, sampleCount)
+	}
+
+	definitions := dicomtree.FindItemElement(item, tag.ChannelDefinitionSequence)
+	var definitionItems [][]*dicom.Element
+	if definitions != nil {
+		definitionItems = dicomtree.SequenceItems(definitions)
+	}
+	for c := 0; c < channelCount; c++ {
+		channel := Channel{Label: fmt.Sprintf("Channel %d", c+1), Samples: make([]float64, sampleCount)}
+		sensitivity, correction, baseline := 1.0, 1.0, 0.0
+		if c < len(definitionItems) {
+			definition := definitionItems[c]
+			if label := dicomtree.ItemValue(definition, tag.ChannelLabel); label != "" {
+				channel.Label = label
+			} else if sources := dicomtree.FindItemElement(definition, tag.ChannelSourceSequence); sources != nil {
+				if items := dicomtree.SequenceItems(sources); len(items) > 0 && dicomtree.ItemValue(items[0], tag.CodeMeaning) != "" {
+					channel.Label = dicomtree.ItemValue(items[0], tag.CodeMeaning)
+				}
+			}
+			if units := dicomtree.FindItemElement(definition, tag.ChannelSensitivityUnitsSequence); units != nil {
+				if items := dicomtree.SequenceItems(units); len(items) > 0 {
+					channel.Unit = dicomtree.ItemValue(items[0], tag.CodeValue)
+				}
+			}
+			sensitivity = number(definition, tag.ChannelSensitivity, 1)
+			correction = number(definition, tag.ChannelSensitivityCorrectionFactor, 1)
+			baseline = number(definition, tag.ChannelBaseline, 0)
+		}
+		for s := 0; s < sampleCount; s++ {
+			channel.Samples[s] = float64(raw[s*channelCount+c])*sensitivity*correction + baseline*correction
+		}
+		group.Channels = append(group.Channels, channel)
+	}
+	return group, nil
+}
+
+// returns the little endian samples of the waveform data, channels interleaved
+func rawSamples(data []byte, bits int, interpretation string) ([]int, error) {
+	var samples []int
+	switch {
+	case bits == 8 && (interpretation == "SB" || interpretation == "UB"):
+		samples = make([]int, len(data))
+		for i, b := range data {
+			samples[i] = int(b)
+			if interpretation == "SB" {
+				samples[i] = int(int8(b))
+			}
+		}
+	case bits == 16 && (interpretation == "SS" || interpretation == "US"):
+		samples = make([]int, len(data)/2)
+		for i := range samples {
+			v := binary.LittleEndian.Uint16(data[2*i:])
+			samples[i] = int(v)
+			if interpretation == "SS" {
+				samples[i] = int(int16(v))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sample interpretation %s with %d bits", interpretation, bits)
+	}
+	return samples, nil
+}
+
+// writes the samples of the group with the time in seconds as first column and a column per channel
+func WriteCSV(w io.Writer, group Group) error {
+	writer := csv.NewWriter(w)
+	header := []string{"time"}
+	for _, channel := range group.Channels {
+		label := channel.Label
+		if channel.Unit != "" {
+			label += " [" + channel.Unit + "]"
+		}
+		header = append(header, label)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for s := 0; len(group.Channels) > 0 && s < len(group.Channels[0].Samples); s++ {
+		time := 0.0
+		if group.Frequency > 0 {
+			time = float64(s) / group.Frequency
+		}
+		record := []string{strconv.FormatFloat(time, 'f', -1, 64)}
+		for _, channel := range group.Channels {
+			record = append(record, strconv.FormatFloat(channel.Samples[s], 'f', -1, 64))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// braille dots of a cell by column and row from the top
+var brailleDots = [2][4]rune{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+
+// renders the samples as braille plot of the given number of text lines, each dot column covers step samples.
+// The range of the samples fills the height, consecutive values are connected, NaN samples leave a gap.
+func Plot(samples []float64, lines, step int) []string {
+	if len(samples) == 0 || lines <= 0 {
+		return nil
+	}
+	step = max(step, 1)
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range samples {
+		if !math.IsNaN(v) {
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+	}
+	dotRows := lines * 4
+	row := func(v float64) int {
+		if high == low {
+			return dotRows / 2
+		}
+		return int(math.Round((high - v) / (high - low) * float64(dotRows-1)))
+	}
+
+	columns := (len(samples) + step - 1) / step
+	cells := make([][]rune, lines)
+	for i := range cells {
+		cells[i] = make([]rune, (columns+1)/2)
+		for j := range cells[i] {
+			cells[i][j] = 0x2800
+		}
+	}
+	previous := -1 // dot row of the previous sample, -1 after a gap
+	for c := 0; c < columns; c++ {
+		top, bottom := previous, previous
+		sampled := false
+		for _, v := range samples[c*step : min((c+1)*step, len(samples))] {
+			if math.IsNaN(v) {
+				previous = -1
+				continue
+			}
+			r := row(v)
+			if top < 0 {
+				top, bottom = r, r
+			}
+			top, bottom = min(top, r), max(bottom, r)
+			previous, sampled = r, true
+		}
+		for r := top; sampled && r <= bottom; r++ {
+			cells[r/4][c/2] |= brailleDots[c%2][r%4]
+		}
+	}
+	text := make([]string, lines)
+	for i, cell := range cells {
+		text[i] = string(cell)
+	}
+	return text
+}
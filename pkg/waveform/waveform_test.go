@@ -0,0 +1,76 @@
+package waveform
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// returns a dataset with one multiplex group of two channels with three samples, 16 bit signed
+func newECG(t *testing.T) dicom.Dataset {
+	channel := func(label, sensitivity, baseline string) []*dicom.Element {
+		return []*dicom.Element{
+			mustElement(t, tag.ChannelSourceSequence, [][]*dicom.Element{{mustElement(t, tag.CodeMeaning, []string{label})}}),
+			mustElement(t, tag.ChannelSensitivity, []string{sensitivity}),
+			mustElement(t, tag.ChannelSensitivityUnitsSequence, [][]*dicom.Element{{mustElement(t, tag.CodeValue, []string{"uV"})}}),
+			mustElement(t, tag.ChannelBaseline, []string{baseline}),
+		}
+	}
+	// samples interleaved by channel: (1, -1), (2, -2), (3, 100)
+	data := []byte{1, 0, 0xff, 0xff, 2, 0, 0xfe, 0xff, 3, 0, 100, 0}
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustElement(t, tag.WaveformSequence, [][]*dicom.Element{{
+			mustElement(t, tag.NumberOfWaveformChannels, []int{2}),
+			mustElement(t, tag.NumberOfWaveformSamples, []int{3}),
+			mustElement(t, tag.SamplingFrequency, []string{"500"}),
+			mustElement(t, tag.ChannelDefinitionSequence, [][]*dicom.Element{channel("Lead I", "2", "0"), channel("Lead II", "1", "10")}),
+			mustElement(t, tag.WaveformBitsAllocated, []int{16}),
+			mustElement(t, tag.WaveformSampleInterpretation, []string{"SS"}),
+			mustElement(t, tag.WaveformData, data),
+		}}),
+	}}
+}
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	dataset := newECG(t)
+	groups, err := Decode(&dataset)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(500.0, groups[0].Frequency)
+	assert.Equal([]Channel{
+		{Label: "Lead I", Unit: "uV", Samples: []float64{2, 4, 6}},
+		{Label: "Lead II", Unit: "uV", Samples: []float64{9, 8, 110}},
+	}, groups[0].Channels)
+
+	var csv bytes.Buffer
+	assert.NoError(WriteCSV(&csv, groups[0]))
+	assert.Equal("time,Lead I [uV],Lead II [uV]\n0,2,9\n0.002,4,8\n0.004,6,110\n", csv.String())
+
+	_, err = Decode(&dicom.Dataset{})
+	assert.ErrorIs(err, ErrNoWaveform)
+}
+
+func TestPlot(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"⠙⢦"}, Plot([]float64{3, 2, 1, 0}, 1, 1), "falling line over the four dot rows, connected")
+	assert.Equal([]string{"⡇", "⡇"}, Plot([]float64{1, 1, 0, 1}, 2, 4), "one column with the range of four samples")
+	assert.Equal([]string{"⠤⠄"}, Plot([]float64{5, 5, 5}, 1, 1), "constant values in the middle")
+	assert.Equal([]string{"⠁⠀⡀"}, Plot([]float64{3, math.NaN(), math.NaN(), math.NaN(), 0}, 1, 1), "gap not connected")
+	assert.Nil(Plot(nil, 2, 1))
+}
+
+// returns a new element with the VR of the dictionary
+func mustElement(t *testing.T, tg tag.Tag, data interface{}) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	require.NoError(t, err)
+	return e
+}